@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+)
+
+const (
+	abuseMessageWindowSeconds = 60  // sliding window for counting messages
+	abuseMaxMessagesInWindow  = 20  // more messages than this in the window will be throttled
+	abuseMaxThrottles         = 3   // throttled more than this in a row will be banned
+	abuseMaxNLUErrors         = 5   // consecutive api.ai errors caused by the user more than this will be banned
+	abuseBaseBanMinutes       = 10  // duration of the first ban
+	abuseBanMultiplier        = 4   // each following ban will be this times longer
+	abuseMaxBanHours          = 168 // bans will not be longer than this
+
+	banReasonTooManyMessages = "too many messages"
+	banReasonTooManyErrors   = "too many api.ai errors"
+
+	apiaiErrorTypeBadRequest = "bad_request" // (api.ai could not process the query itself, eg. malformed or too long)
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageThrottled         = "메시지를 너무 많이 보내셨습니다. 잠시 후 다시 시도해 주세요."
+	messageBannedFormat      = "2006.1.2 15:04까지 이용이 제한되었습니다."
+	messageAdminBannedFormat = "[관리자] 사용자 %s(%d)를 %s까지 차단했습니다. (%d회째, 사유: %s)"
+)
+
+// abuse tracker for counting messages and errors of each user
+type abuseTracker struct {
+	sync.Mutex
+
+	messages  map[int64][]time.Time
+	throttles map[int64]int
+	nluErrors map[int64]int
+}
+
+var _abuse = abuseTracker{
+	messages:  map[int64][]time.Time{},
+	throttles: map[int64]int{},
+	nluErrors: map[int64]int{},
+}
+
+// check if given user is banned or sending too many messages
+//
+// returns false if the message should not be processed
+func checkAbuse(b *bot.Bot, userID int64, username string, chatID int64) bool {
+	if ban, exists := db.Ban(userID); exists && ban.IsActive() {
+		if _isVerbose {
+			log.Printf("Ignoring message from banned user: %s (%d)", username, userID)
+		}
+
+		return false
+	}
+
+	_abuse.Lock()
+
+	now := time.Now()
+	since := now.Add(-abuseMessageWindowSeconds * time.Second)
+
+	// leave only the messages in the window
+	recent := []time.Time{}
+	for _, t := range _abuse.messages[userID] {
+		if t.After(since) {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now)
+	_abuse.messages[userID] = recent
+
+	throttled := len(recent) > abuseMaxMessagesInWindow
+	shouldWarn, shouldBan := false, false
+	if throttled {
+		// warn only once for each throttling
+		if len(recent) == abuseMaxMessagesInWindow+1 {
+			_abuse.throttles[userID]++
+
+			shouldWarn = true
+			shouldBan = _abuse.throttles[userID] > abuseMaxThrottles
+		}
+	} else if len(recent) == 1 {
+		// calmed down
+		_abuse.throttles[userID] = 0
+	}
+
+	_abuse.Unlock()
+
+	if shouldBan {
+		banUser(b, userID, username, chatID, banReasonTooManyMessages)
+	} else if shouldWarn {
+		if sent := b.SendMessage(chatID, messageThrottled, map[string]interface{}{}); !sent.Ok {
+			log.Printf("*** failed to send throttle message: %s", *sent.Description)
+		}
+	}
+
+	return !throttled
+}
+
+// check if given error type of api.ai is caused by the query of the user
+//
+// (transport errors and other errors of api.ai are not the user's fault, so they are not counted)
+func isUserCausedNLUError(errorType string) bool {
+	return errorType == apiaiErrorTypeBadRequest
+}
+
+// count an api.ai error caused by given user, and ban him if there were too many
+func recordNLUError(b *bot.Bot, userID int64, username string, chatID int64) {
+	_abuse.Lock()
+	_abuse.nluErrors[userID]++
+	shouldBan := _abuse.nluErrors[userID] > abuseMaxNLUErrors
+	_abuse.Unlock()
+
+	if shouldBan {
+		banUser(b, userID, username, chatID, banReasonTooManyErrors)
+	}
+}
+
+// reset the api.ai error count of given user
+func recordNLUSuccess(userID int64) {
+	_abuse.Lock()
+	delete(_abuse.nluErrors, userID)
+	_abuse.Unlock()
+}
+
+// ban given user with escalating cooloff, and notify him and the admins
+func banUser(b *bot.Bot, userID int64, username string, chatID int64, reason string) {
+	numBans := 0
+	if ban, exists := db.Ban(userID); exists {
+		numBans = ban.NumBans
+	}
+
+	minutes := float64(abuseBaseBanMinutes) * math.Pow(abuseBanMultiplier, float64(numBans))
+	if minutes > abuseMaxBanHours*60 {
+		minutes = abuseMaxBanHours * 60
+	}
+	until := time.Now().Add(time.Duration(minutes) * time.Minute)
+
+	if !db.SaveBan(userID, username, reason, until) {
+		log.Printf("*** failed to save ban of user: %s (%d)", username, userID)
+		return
+	}
+
+	// reset counters
+	_abuse.Lock()
+	delete(_abuse.messages, userID)
+	delete(_abuse.throttles, userID)
+	delete(_abuse.nluErrors, userID)
+	_abuse.Unlock()
+
+	log.Printf("Banned user: %s (%d) until %s (%s)", username, userID, until.Format("2006.1.2 15:04"), reason)
+
+	db.Log(fmt.Sprintf("banned user: %s (%d) until %s (%s)", username, userID, until.Format("2006.1.2 15:04"), reason))
+
+	if sent := b.SendMessage(chatID, until.Format(messageBannedFormat), map[string]interface{}{}); !sent.Ok {
+		log.Printf("*** failed to send ban message: %s", *sent.Description)
+	}
+
+	notifyAdmins(b, fmt.Sprintf(messageAdminBannedFormat, userDisplayName(userID), userID, until.Format("2006.1.2 15:04"), numBans+1, reason))
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	callbackAck = "/ack"
+
+	groupNagIntervalMinutes = 30  // nag again after this while a group reminder is not acknowledged
+	groupNagMaxMinutes      = 120 // stop nagging after this from the delivery
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageAckButton          = "✔ 완료"
+	messageAssigneeFormat     = "담당: @%s"
+	messageGroupNagFormat     = "⏰ 아직 완료되지 않았습니다: %s"
+	messageAckedFormat        = "✔ %s님이 완료했습니다. (%s)"
+	messageAlreadyAckedFormat = "이미 %s님이 완료했습니다."
+)
+
+var assigneeRegex = regexp.MustCompile(`@([A-Za-z][A-Za-z0-9_]{4,31})`)
+
+// username of the assignee mentioned in given reminder message (without '@')
+func assigneeFromMessage(message string) string {
+	if matches := assigneeRegex.FindStringSubmatch(message); matches != nil {
+		return matches[1]
+	}
+
+	return ""
+}
+
+// check if given queue item should be acknowledged (by members of its group, or for triggering its chains)
+func needsAck(q dbhelper.QueueItem) bool {
+	if q.Broadcast || (q.Kind != dbhelper.QueueKindReminder && q.Kind != dbhelper.QueueKindGroupNag) {
+		return false
+	}
+
+	// (private ones are acknowledged by replying '완료' in the private chat, not to be nagged in the group)
+	if isPrivateInGroup(q) {
+		return false
+	}
+
+	return isGroupChatID(q.ChatID) || hasPendingChains(q)
+}
+
+// message and keyboard for delivering group reminders
+func ackDeliveryMessage(q dbhelper.QueueItem) (message string, keyboard interface{}) {
+	queueID := q.ID
+	if q.Kind == dbhelper.QueueKindGroupNag {
+		queueID = q.RefID
+	}
+
+	message = q.Message
+	if q.Assignee != "" {
+		message += "\n" + fmt.Sprintf(messageAssigneeFormat, q.Assignee)
+	}
+
+	return message, ackKeyboard(queueID)
+}
+
+// inline keyboard for acknowledging given queue item
+func ackKeyboard(queueID int64) bot.InlineKeyboardMarkup {
+	ack := fmt.Sprintf("%s %d", callbackAck, queueID)
+
+	return bot.InlineKeyboardMarkup{
+		InlineKeyboard: [][]bot.InlineKeyboardButton{
+			[]bot.InlineKeyboardButton{
+				bot.InlineKeyboardButton{Text: messageAckButton, CallbackData: &ack},
+			},
+		},
+	}
+}
+
+// schedule a nag for given group reminder (or nag) until it is acknowledged
+func scheduleGroupNag(q dbhelper.QueueItem) {
+	reminder := q
+	if q.Kind == dbhelper.QueueKindGroupNag {
+		var exists bool
+		if reminder, exists = db.QueueItem(q.ChatID, q.RefID); !exists {
+			return
+		}
+	}
+	if reminder.AckedBy != 0 {
+		return
+	}
+
+	fireOn := time.Now().Add(groupNagIntervalMinutes * time.Minute)
+	if fireOn.After(reminder.FireOn.Add(groupNagMaxMinutes * time.Minute)) {
+		return
+	}
+
+	if _, enqueued := db.EnqueueItem(dbhelper.QueueItem{
+		ChatID:   reminder.ChatID,
+		UserID:   reminder.UserID,
+		Message:  fmt.Sprintf(messageGroupNagFormat, reminder.Message),
+		FireOn:   fireOn,
+		Kind:     dbhelper.QueueKindGroupNag,
+		RefID:    reminder.ID,
+		Assignee: reminder.Assignee,
+	}); !enqueued {
+		log.Printf("*** failed to enqueue nag for queue item %d", reminder.ID)
+	}
+}
+
+// process callback query for acknowledging group reminders
+func processAckCallback(query bot.CallbackQuery, txt string) string {
+	queueID, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(txt, callbackAck)), 10, 64)
+	if err != nil {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+
+	chatID := query.Message.Chat.ID
+	userID := int64(query.From.ID)
+
+	text := ""
+	if query.Message.Text != nil {
+		text = *query.Message.Text
+	}
+
+	if !db.AckQueueItem(chatID, queueID, userID) {
+		if q, exists := db.QueueItem(chatID, queueID); exists && q.AckedBy != 0 {
+			return text + "\n" + fmt.Sprintf(messageAlreadyAckedFormat, userDisplayName(q.AckedBy))
+		}
+		return messageError
+	}
+
+	// stop nagging
+	db.DeleteQueueItemsByRef(chatID, dbhelper.QueueKindGroupNag, queueID)
+
+	if q, exists := db.QueueItem(chatID, queueID); exists {
+		completeIntegrationTasks(q)
+	}
+
+	message := text + "\n" + fmt.Sprintf(messageAckedFormat, userDisplayName(userID), clockOf(chatID, time.Now()))
+
+	// schedule chained reminders
+	if chained := triggerChains(chatID, queueID); chained != "" {
+		message += "\n" + chained
+	}
+
+	return message
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+)
+
+// runtime variables exposed through /debug/vars
+var (
+	_varNumDeliveriesInFlight = expvar.NewInt("deliveries_in_flight")
+	_varNumDelivered          = expvar.NewInt("deliveries_succeeded")
+	_varNumDeliveryFailures   = expvar.NewInt("deliveries_failed")
+)
+
+func init() {
+	expvar.Publish("goroutines", expvar.Func(func() interface{} {
+		return runtime.NumGoroutine()
+	}))
+	expvar.Publish("db", expvar.Func(func() interface{} {
+		return db.Stats()
+	}))
+	expvar.Publish("db_queries", expvar.Func(func() interface{} {
+		return db.Metrics()
+	}))
+}
+
+// start admin HTTP server for diagnostics
+//
+// (only with a token, as profiles, runtime variables, and the dashboard should not be open to anyone)
+func startAdminServer(addr, token string) {
+	if token == "" {
+		log.Printf("*** admin server is not started without admin_server_token: %s", addr)
+		return
+	}
+
+	mux := http.NewServeMux()
+
+	// pprof
+	mux.HandleFunc("/debug/pprof/", adminOnly(token, pprof.Index))
+	mux.HandleFunc("/debug/pprof/cmdline", adminOnly(token, pprof.Cmdline))
+	mux.HandleFunc("/debug/pprof/profile", adminOnly(token, pprof.Profile))
+	mux.HandleFunc("/debug/pprof/symbol", adminOnly(token, pprof.Symbol))
+	mux.HandleFunc("/debug/pprof/trace", adminOnly(token, pprof.Trace))
+
+	// expvar
+	mux.HandleFunc("/debug/vars", adminOnly(token, expvar.Handler().ServeHTTP))
+
+	// dashboard
+	handleDashboard(mux, token)
+
+	log.Printf("> Starting admin server on: %s", addr)
+
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Printf("*** admin server stopped: %s", err)
+	}
+}
+
+// wrap given handler with admin token check (rejects all requests if token is empty)
+//
+// token can be given as a 'token' query parameter (for 'go tool pprof') or a bearer token
+func adminOnly(token string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" ||
+			!isAdminToken(token, r.URL.Query().Get("token")) &&
+				!isAdminToken(token, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// check if given token matches the admin token (in constant time)
+func isAdminToken(token, given string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(given)) == 1
+}
@@ -16,11 +16,39 @@ const (
 	IntentNameMessageConfirmedNo  = "message-confirm-no"
 
 	ContextLifespan = 1
+
+	EntityNameAnchor = "anchor"
+)
+
+// values of anchor entity (recurrence rules, except AnchorPayday which depends on the config)
+const (
+	AnchorLastDayOfMonth  = "monthly:last"
+	AnchorFirstDayOfMonth = "monthly:1"
+	AnchorBiweekly        = "biweekly"
+	AnchorPayday          = "payday"
+	AnchorLunar           = "lunar"        // the date is a lunar date
+	AnchorLunarYearly     = "lunar-yearly" // the date is a lunar date, every year
 )
 
 // setup agent
 func SetupAgent(ai *apiai.Client, db *dbhelper.Database) {
-	var existsMessage, existsConfirmYes, existsConfirmNo bool
+	var existsAnchor, existsMessage, existsConfirmYes, existsConfirmNo bool
+
+	// check existence of entities
+	if entities, err := ai.AllEntities(); err == nil {
+		for _, entity := range entities {
+			if entity.Name == EntityNameAnchor {
+				existsAnchor = true
+			}
+		}
+	}
+
+	// create entities (before intents which use them)
+	if existsAnchor { // entity: anchor
+		log.Printf("Entity '%s' already exists", EntityNameAnchor)
+	} else {
+		createAnchorEntity(ai, db)
+	}
 
 	// check existence of intents
 	if intents, err := ai.AllIntents(); err == nil {
@@ -53,6 +81,46 @@ func SetupAgent(ai *apiai.Client, db *dbhelper.Database) {
 	}
 }
 
+func createAnchorEntity(ai *apiai.Client, db *dbhelper.Database) {
+	if res, err := ai.CreateEntity(apiai.EntityObject{
+		Name: EntityNameAnchor,
+		Entries: []apiai.EntityEntryObject{
+			apiai.EntityEntryObject{
+				Value:    AnchorLastDayOfMonth,
+				Synonyms: []string{"말일", "매월 말일", "매달 말일", "월말", "매월 마지막 날", "매달 마지막 날"},
+			},
+			apiai.EntityEntryObject{
+				Value:    AnchorFirstDayOfMonth,
+				Synonyms: []string{"월초", "매월 초", "매달 초", "매월 1일", "매달 1일", "초하루"},
+			},
+			apiai.EntityEntryObject{
+				Value:    AnchorBiweekly,
+				Synonyms: []string{"격주", "격주로", "2주마다", "이주마다", "2주에 한 번"},
+			},
+			apiai.EntityEntryObject{
+				Value:    AnchorPayday,
+				Synonyms: []string{"월급날", "매월 월급날", "매달 월급날", "급여일"},
+			},
+			apiai.EntityEntryObject{
+				Value:    AnchorLunar,
+				Synonyms: []string{"음력"},
+			},
+			apiai.EntityEntryObject{
+				Value:    AnchorLunarYearly,
+				Synonyms: []string{"매년 음력", "해마다 음력"},
+			},
+		},
+	}); err != nil {
+		log.Printf("*** Failed to create entity %s: %s", EntityNameAnchor, err)
+
+		db.LogError(fmt.Sprintf("failed to create entity %s: %s", EntityNameAnchor, err))
+	} else if res.Status.Code != 200 {
+		log.Printf("*** Failed to create entity %s: %s", EntityNameAnchor, res.Status.ErrorDetails)
+
+		db.LogError(fmt.Sprintf("failed to create entity %s: %s", EntityNameAnchor, res.Status.ErrorDetails))
+	}
+}
+
 func createMessageIntent(ai *apiai.Client, db *dbhelper.Database) {
 	if res, err := ai.CreateIntent(apiai.IntentObject{
 		Name:     IntentNameMessage,
@@ -287,6 +355,99 @@ func createMessageIntent(ai *apiai.Client, db *dbhelper.Database) {
 					},
 				},
 			},
+			apiai.UserSays{
+				Data: []apiai.UserSaysData{
+					apiai.UserSaysData{
+						Text:  "매월 말일",
+						Meta:  "@" + EntityNameAnchor,
+						Alias: "anchor",
+					},
+					apiai.UserSaysData{
+						Text: " ",
+					},
+					apiai.UserSaysData{
+						Text:  "09시",
+						Meta:  "@sys.time",
+						Alias: "time",
+					},
+					apiai.UserSaysData{
+						Text: "에 ",
+					},
+					apiai.UserSaysData{
+						Text:  "카드값 내라고",
+						Meta:  "@sys.any",
+						Alias: "message",
+					},
+					apiai.UserSaysData{
+						Text: " ",
+					},
+					apiai.UserSaysData{
+						Text: "알려줘",
+					},
+				},
+			},
+			apiai.UserSays{
+				Data: []apiai.UserSaysData{
+					apiai.UserSaysData{
+						Text:  "격주",
+						Meta:  "@" + EntityNameAnchor,
+						Alias: "anchor",
+					},
+					apiai.UserSaysData{
+						Text: " ",
+					},
+					apiai.UserSaysData{
+						Text:  "18시",
+						Meta:  "@sys.time",
+						Alias: "time",
+					},
+					apiai.UserSaysData{
+						Text: "에 ",
+					},
+					apiai.UserSaysData{
+						Text:  "분리수거 하라고",
+						Meta:  "@sys.any",
+						Alias: "message",
+					},
+					apiai.UserSaysData{
+						Text: " ",
+					},
+					apiai.UserSaysData{
+						Text: "알려줘",
+					},
+				},
+			},
+			apiai.UserSays{
+				Data: []apiai.UserSaysData{
+					apiai.UserSaysData{
+						Text:  "월급날",
+						Meta:  "@" + EntityNameAnchor,
+						Alias: "anchor",
+					},
+					apiai.UserSaysData{
+						Text: " ",
+					},
+					apiai.UserSaysData{
+						Text:  "10시",
+						Meta:  "@sys.time",
+						Alias: "time",
+					},
+					apiai.UserSaysData{
+						Text: "에 ",
+					},
+					apiai.UserSaysData{
+						Text:  "적금 넣으라고",
+						Meta:  "@sys.any",
+						Alias: "message",
+					},
+					apiai.UserSaysData{
+						Text: " ",
+					},
+					apiai.UserSaysData{
+						Text: "보내줘",
+					},
+				},
+			},
 		},
 		Responses: []apiai.IntentResponse{
 			apiai.IntentResponse{
@@ -311,14 +472,12 @@ func createMessageIntent(ai *apiai.Client, db *dbhelper.Database) {
 					apiai.IntentResponseParameter{
 						Name:     "date",
 						Value:    "$date",
-						Required: true,
-						DataType: "@sys.date",
-						Prompts: []string{
-							"어느 날짜에 보내드릴까요?",
-							"어느 날짜에 알려드릴까요?",
-							"몇 월 몇 일에 보내드릴까요?",
-							"몇 월 몇 일에 알려드릴까요?",
-						},
+						DataType: "@sys.date", // (not required: today/tomorrow or anchor)
+					},
+					apiai.IntentResponseParameter{
+						Name:     "anchor",
+						Value:    "$anchor",
+						DataType: "@" + EntityNameAnchor,
 					},
 					apiai.IntentResponseParameter{
 						Name:     "time",
@@ -411,6 +570,10 @@ func createConfirmYesIntent(ai *apiai.Client, db *dbhelper.Database) {
 						Name:  "message",
 						Value: "#message.message",
 					},
+					apiai.IntentResponseParameter{
+						Name:  "anchor",
+						Value: "#message.anchor",
+					},
 				},
 				Messages: []apiai.Message{
 					apiai.TextResponseMessage("", []string{
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	commandAlias = "/alias"
+
+	aliasMaxLength = 20
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageAliasUsage         = "사용법:\n/alias ㄹ /list : 'ㄹ'을 /list 로 쓰기\n/alias ㄹ : 'ㄹ' 지우기\n/alias : 목록 보기"
+	messageAliasSavedFormat   = "이제 '%s'(은)는 %s 명령으로 처리됩니다."
+	messageAliasDeletedFormat = "'%s'(을)를 지웠습니다."
+	messageAliasNotFound      = "그런 단축어가 없습니다."
+	messageAliasInvalid       = "단축어는 띄어쓰기 없이 20자 이내여야 하고, 명령은 /로 시작해야 합니다."
+	messageAliasNone          = "등록된 단축어가 없습니다.\n\n" + "사용 예: /alias ㄹ /list"
+	messageAliasItemFormat    = "%s → %s"
+	messageAliasGlobalSuffix  = " (공통)"
+	messageAliasListTitle     = "단축어 목록:"
+)
+
+// process /alias command: list, save, or delete personal aliases of commands
+func processAliasCommand(chatID int64, txt string) string {
+	params := strings.Fields(strings.TrimPrefix(txt, commandAlias))
+
+	switch len(params) {
+	case 0:
+		return listAliases(chatID)
+	case 1:
+		if db.DeleteAlias(chatID, params[0]) {
+			return fmt.Sprintf(messageAliasDeletedFormat, params[0])
+		}
+		return messageAliasNotFound
+	}
+
+	alias, command := params[0], strings.Join(params[1:], " ")
+	if utf8.RuneCountInString(alias) > aliasMaxLength ||
+		!strings.HasPrefix(command, "/") ||
+		alias == commandAlias || strings.HasPrefix(command, commandAlias) { // (not to be locked out)
+		return messageAliasInvalid + "\n\n" + messageAliasUsage
+	}
+
+	if !db.SaveAlias(chatID, alias, command) {
+		return messageSaveFailed
+	}
+
+	return fmt.Sprintf(messageAliasSavedFormat, alias, command)
+}
+
+// list personal and global aliases of given chat
+func listAliases(chatID int64) string {
+	lines := []string{}
+	for _, a := range db.Aliases(chatID) {
+		lines = append(lines, fmt.Sprintf(messageAliasItemFormat, a.Alias, a.Command))
+	}
+	for alias, command := range _conf.Aliases {
+		lines = append(lines, fmt.Sprintf(messageAliasItemFormat, alias, command)+messageAliasGlobalSuffix)
+	}
+	if len(lines) <= 0 {
+		return messageAliasNone
+	}
+
+	return messageAliasListTitle + "\n" + strings.Join(lines, "\n")
+}
+
+// expand the alias at the beginning of given text (personal ones first, then global ones)
+//
+// (eg. "ㄹ" => "/list", "취소" => "/cancel")
+func expandAlias(chatID int64, txt string) string {
+	trimmed := strings.TrimSpace(txt)
+	if trimmed == "" || strings.HasPrefix(trimmed, commandAlias) {
+		return txt
+	}
+
+	first, rest := trimmed, ""
+	if i := strings.IndexAny(trimmed, " \n"); i > 0 {
+		first, rest = trimmed[:i], trimmed[i:]
+	}
+
+	for _, a := range db.Aliases(chatID) {
+		if a.Alias == first {
+			return a.Command + rest
+		}
+	}
+	if command, exists := _conf.Aliases[first]; exists {
+		return command + rest
+	}
+
+	return txt
+}
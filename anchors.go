@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	aihelper "github.com/meinside/telegram-bot-reminder-api.ai/ai"
+	"github.com/meinside/telegram-bot-reminder-api.ai/lunar"
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageReminderScheduledFormat = "2006.1.2 15:04에 \"%s\"라고 알려드리겠습니다."
+)
+
+// recurrence rule for given anchor (value of anchor entity)
+func recurrenceForAnchor(anchor string) string {
+	switch anchor {
+	case "":
+		return ""
+	case aihelper.AnchorPayday:
+		return fmt.Sprintf("%s:%d", recurrenceMonthly, _paydayOfMonth)
+	}
+
+	return anchor
+}
+
+// resolve the (first) fire time and recurrence rule from given date, time, and anchor
+//
+// without date, it will be today (or tomorrow if the time is already past);
+// with anchor, it will be the first occurrence of the anchor from the date
+func resolveSchedule(date, clock, anchor string) (when time.Time, recurrence string, err error) {
+	dateGiven := date != ""
+	if !dateGiven {
+		date = time.Now().In(_location).Format("2006-01-02")
+	}
+
+	if when, err = time.ParseInLocation("2006-01-02 15:04:05", fmt.Sprintf("%s %s", date, clock), _location); err != nil {
+		return when, "", err
+	}
+
+	// lunar dates
+	if anchor == aihelper.AnchorLunar || anchor == aihelper.AnchorLunarYearly {
+		return resolveLunarSchedule(when, anchor == aihelper.AnchorLunarYearly)
+	}
+
+	recurrence = recurrenceForAnchor(anchor)
+	if recurrence == recurrenceMonthly { // (on the day of the date)
+		recurrence = monthlyRecurrence(when)
+	}
+	if recurrence == "" {
+		if !dateGiven && when.Before(time.Now()) {
+			when = when.AddDate(0, 0, 1)
+		}
+
+		return when, "", nil
+	}
+
+	// move to the anchored day of the month
+	if name, param := splitRecurrence(recurrence); name == recurrenceMonthly {
+		day, err := dayOfMonthParam(param)
+		if err != nil {
+			return when, "", err
+		}
+		when = dayOfMonthClamped(when.Year(), when.Month(), day, when)
+	}
+
+	if when.Before(time.Now()) {
+		if when, err = nextOccurrenceAfterNow(recurrence, when); err != nil {
+			return when, "", err
+		}
+	}
+
+	return when, recurrence, nil
+}
+
+// resolve the first fire time of given lunar date (given as a solar time) from now
+//
+// (the year is ignored: it will be the nearest upcoming one)
+func resolveLunarSchedule(when time.Time, yearly bool) (time.Time, string, error) {
+	month, day := int(when.Month()), when.Day()
+
+	year, _, _, _, err := lunar.FromSolar(time.Now().In(_location))
+	if err != nil {
+		return when, "", err
+	}
+
+	if when, err = lunarDateClamped(year, month, day, when); err != nil {
+		return when, "", err
+	}
+	if when.Before(time.Now()) {
+		if when, err = lunarDateClamped(year+1, month, day, when); err != nil {
+			return when, "", err
+		}
+	}
+
+	if yearly {
+		return when, lunarRecurrence(month, day), nil
+	}
+
+	return when, "", nil
+}
+
+// confirmation message for newly scheduled reminder
+func confirmationMessage(chatID int64, message string, when time.Time, recurrence string) string {
+	if recurrence != "" {
+		return fmt.Sprintf(messageRecurrenceCreatedFormat, describeRecurrence(chatID, recurrence, when), message)
+	}
+
+	return fmt.Sprintf(formatTimeFor(chatID, when, messageReminderScheduledFormat), message)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	commandAnnounce = "/announce"
+
+	announceTimeFormat = "2006-01-02 15:04"
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageAnnouncementFormat      = "[공지] %s"
+	messageAnnounceUsage           = "사용법: /announce 2006-01-02 15:04 공지할 내용"
+	messageAnnounceScheduledFormat = "2006.1.2 15:04에 모든 채팅으로 공지를 보냅니다."
+	messageAnnounceAdminOnly       = "관리자만 사용할 수 있는 명령입니다."
+	messageAnnounceDoneFormat      = "[관리자] 공지 발송 완료: 성공 %d, 실패 %d"
+	messageAnnounceTimeParseError  = "공지 시각이 올바르지 않습니다 (형식: 2006-01-02 15:04)"
+)
+
+// check if given user is an admin
+func isAdmin(userID int64) bool {
+	for _, id := range _adminUserIds {
+		if id == userID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// process /announce command: schedule a broadcast to all chats
+func processAnnounceCommand(chatID, userID int64, txt string) string {
+	if !isAdmin(userID) {
+		return messageAnnounceAdminOnly
+	}
+
+	params := strings.Fields(strings.TrimPrefix(txt, commandAnnounce))
+	if len(params) < 3 {
+		return messageAnnounceUsage
+	}
+
+	when, err := time.ParseInLocation(announceTimeFormat, params[0]+" "+params[1], _location)
+	if err != nil {
+		return messageAnnounceTimeParseError
+	}
+	if when.Before(time.Now()) {
+		return when.Format(messageTimeIsPastFormat)
+	}
+
+	// text after date and time (keeping line breaks)
+	text := strings.TrimSpace(txt[strings.Index(txt, params[1])+len(params[1]):])
+
+	if _, enqueued := db.EnqueueItem(dbhelper.QueueItem{
+		ChatID:    chatID,
+		UserID:    userID,
+		Message:   text,
+		FireOn:    when,
+		Broadcast: true,
+	}); !enqueued {
+		return messageSaveFailed
+	}
+
+	return when.Format(messageAnnounceScheduledFormat)
+}
+
+// fan out given broadcast item to all active chats (with the broadcast engine)
+//
+// (it is marked as delivered when its broadcast is created, not to be picked up again while broadcasting)
+func broadcastQueueItem(client *bot.Bot, q dbhelper.QueueItem) {
+	// (posts to topics are sent to their subscribers only)
+	if q.Kind == dbhelper.QueueKindTopicPost {
+		broadcastTopicPost(client, q)
+		return
+	}
+
+	chatIDs := []int64{}
+	for _, chat := range db.ActiveChats() {
+		chatIDs = append(chatIDs, chat.ChatID)
+	}
+
+	if !startBroadcast(client, q, fmt.Sprintf(messageAnnouncementFormat, q.Message), chatIDs) {
+		log.Printf("*** failed to start broadcasting announcement %d", q.ID)
+
+		// (retried in the next check, until the max number of tries)
+		db.IncreaseNumTries(q.ChatID, q.ID)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	apiKeyLength = 32 // in bytes
+
+	apiPathReminders = "/api/reminders"
+
+	apiKeyParamRevoke = "revoke"
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageAPIDisabled       = "REST API가 활성화되어 있지 않습니다."
+	messageAPIKeyPrivateOnly = "API 키는 개인 대화에서만 발급할 수 있습니다."
+	messageAPIKeyRevoked     = "API 키가 폐기 되었습니다."
+	messageAPIKeyNotFound    = "발급된 API 키가 없습니다."
+	messageAPIKeyFormat      = `API 키가 발급 되었습니다. (이전 키는 더 이상 사용할 수 없습니다)
+
+%s
+
+요청시 'Authorization: Bearer <키>' 헤더를 포함해 주세요.
+폐기하려면: /apikey revoke`
+)
+
+// response of REST API
+type apiResponse struct {
+	Ok     bool        `json:"ok"`
+	Error  string      `json:"error,omitempty"`
+	Result interface{} `json:"result,omitempty"`
+}
+
+// request body for creating a new reminder
+type apiReminderRequest struct {
+	Message string    `json:"message"`
+	FireOn  time.Time `json:"fire_on"`
+}
+
+// process /apikey command
+func processAPIKeyCommand(chatID int64, isPrivate bool, param string) string {
+	if _conf.APIServerPort <= 0 {
+		return messageAPIDisabled
+	}
+
+	if param == apiKeyParamRevoke {
+		if db.DeleteAPIKey(chatID) {
+			return messageAPIKeyRevoked
+		}
+		return messageAPIKeyNotFound
+	}
+
+	if !isPrivate {
+		return messageAPIKeyPrivateOnly
+	}
+
+	token, err := generateAPIKey()
+	if err != nil {
+		log.Printf("*** failed to generate api key: %s", err)
+		return messageError
+	}
+	if !db.SaveAPIKey(chatID, token) {
+		return messageError
+	}
+
+	return fmt.Sprintf(messageAPIKeyFormat, token)
+}
+
+// generate a new random api key
+func generateAPIKey() (string, error) {
+	bytes := make([]byte, apiKeyLength)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(bytes), nil
+}
+
+// start REST API server
+func startAPIServer(port int) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(apiPathReminders, authorized(handleReminders))
+	mux.HandleFunc(apiPathReminders+"/", authorized(handleReminder))
+	mux.HandleFunc(apiPathEvents, authorized(handleEvent))
+	mux.HandleFunc(apiPathIFTTTTrigger, authorizedWith(handleIFTTTTrigger, writeIFTTTError))
+	mux.HandleFunc(apiPathIFTTTAction, authorizedWith(handleIFTTTAction, writeIFTTTError))
+	mux.HandleFunc(apiPathZapierTrigger, authorized(handleZapierTrigger))
+	mux.HandleFunc(apiPathZapierAction, authorized(handleZapierAction))
+	mux.HandleFunc(apiPathPushTokens, authorized(handlePushTokens))
+	mux.HandleFunc(apiPathPushTokens+"/", authorized(handlePushToken))
+	if _conf.Integrations != nil {
+		mux.HandleFunc(apiPathOAuthCallbackPrefix, handleOAuthCallback)
+	}
+
+	log.Printf("> Starting REST API server on port: %d", port)
+
+	if err := http.ListenAndServe(fmt.Sprintf(":%d", port), mux); err != nil {
+		log.Printf("*** REST API server stopped: %s", err)
+	}
+}
+
+// wrap given handler with authorization, passing the chat id of the api key
+func authorized(handler func(w http.ResponseWriter, r *http.Request, chatID int64)) http.HandlerFunc {
+	return authorizedWith(handler, func(w http.ResponseWriter, status int, message string) {
+		writeAPIResponse(w, status, apiResponse{Error: message})
+	})
+}
+
+// wrap given handler with authorization, writing errors with given function (eg. in the shape of IFTTT)
+func authorizedWith(handler func(w http.ResponseWriter, r *http.Request, chatID int64), writeError func(w http.ResponseWriter, status int, message string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := apiKeyFromRequest(r)
+		if key == "" {
+			writeError(w, http.StatusUnauthorized, "no api key")
+			return
+		}
+
+		if chatID, exists := db.ChatIDForAPIKey(key); exists {
+			handler(w, r, chatID)
+		} else {
+			writeError(w, http.StatusUnauthorized, "invalid api key")
+		}
+	}
+}
+
+// GET: list reminders, POST: create a reminder
+func handleReminders(w http.ResponseWriter, r *http.Request, chatID int64) {
+	switch r.Method {
+	case http.MethodGet:
+		writeAPIResponse(w, http.StatusOK, apiResponse{Ok: true, Result: db.UndeliveredQueueItems(chatID)})
+	case http.MethodPost:
+		var req apiReminderRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeAPIResponse(w, http.StatusBadRequest, apiResponse{Error: fmt.Sprintf("invalid request body: %s", err)})
+			return
+		}
+		if len(strings.TrimSpace(req.Message)) <= 0 {
+			writeAPIResponse(w, http.StatusBadRequest, apiResponse{Error: "message is empty"})
+			return
+		}
+		message, err := sanitizeReminderText(chatID, req.Message)
+		if err != nil {
+			writeAPIResponse(w, http.StatusBadRequest, apiResponse{Error: fmt.Sprintf("message is rejected: %s", err)})
+			return
+		}
+		if req.FireOn.Before(time.Now()) {
+			writeAPIResponse(w, http.StatusBadRequest, apiResponse{Error: "fire_on is in the past"})
+			return
+		}
+
+		item := dbhelper.QueueItem{
+			ChatID:  chatID,
+			Message: message,
+			FireOn:  req.FireOn,
+		}
+		if queueID, enqueued := db.EnqueueItem(item); enqueued {
+			item.ID = queueID
+			fireWebhooks(webhookEventCreated, item, "")
+			flagQueueItemIfNeeded(item)
+
+			writeAPIResponse(w, http.StatusCreated, apiResponse{Ok: true})
+		} else {
+			writeAPIResponse(w, http.StatusInternalServerError, apiResponse{Error: "failed to save reminder"})
+		}
+	default:
+		writeAPIResponse(w, http.StatusMethodNotAllowed, apiResponse{Error: "method not allowed"})
+	}
+}
+
+// GET: get a reminder, DELETE: cancel a reminder
+func handleReminder(w http.ResponseWriter, r *http.Request, chatID int64) {
+	queueID, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, apiPathReminders+"/"), 10, 64)
+	if err != nil {
+		writeAPIResponse(w, http.StatusBadRequest, apiResponse{Error: "invalid reminder id"})
+		return
+	}
+
+	// find the reminder among the chat's own ones
+	var reminder *dbhelper.QueueItem
+	for _, q := range db.UndeliveredQueueItems(chatID) {
+		if q.ID == queueID {
+			reminder = &q
+			break
+		}
+	}
+	if reminder == nil {
+		writeAPIResponse(w, http.StatusNotFound, apiResponse{Error: "no such reminder"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeAPIResponse(w, http.StatusOK, apiResponse{Ok: true, Result: reminder})
+	case http.MethodDelete:
+		if db.DeleteQueueItem(chatID, queueID) {
+			fireWebhooks(webhookEventCanceled, *reminder, "")
+
+			writeAPIResponse(w, http.StatusOK, apiResponse{Ok: true})
+		} else {
+			writeAPIResponse(w, http.StatusInternalServerError, apiResponse{Error: "failed to delete reminder"})
+		}
+	default:
+		writeAPIResponse(w, http.StatusMethodNotAllowed, apiResponse{Error: "method not allowed"})
+	}
+}
+
+// write given response as json
+func writeAPIResponse(w http.ResponseWriter, status int, response apiResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("*** failed to write api response: %s", err)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// endpoints in the shapes of IFTTT and Zapier
+//
+// (api keys can also be given with 'X-API-Key' header or 'key' parameter, for services which only take urls)
+const (
+	apiPathIFTTTTrigger  = "/api/ifttt/triggers/delivered"
+	apiPathIFTTTAction   = "/api/ifttt/actions/create_reminder"
+	apiPathZapierTrigger = "/api/zapier/triggers/delivered"
+	apiPathZapierAction  = "/api/zapier/actions/create_reminder"
+
+	automationDefaultLimit = 50
+	automationMaxLimit     = 100
+	automationTimeFormat   = "2006-01-02 15:04"
+)
+
+// delivered reminder for triggers
+type automationReminder struct {
+	ID          string    `json:"id"`
+	Message     string    `json:"message"`
+	FireOn      time.Time `json:"fire_on"`
+	DeliveredOn time.Time `json:"delivered_on"`
+
+	Meta *iftttMeta `json:"meta,omitempty"` // (only for IFTTT)
+}
+
+type iftttMeta struct {
+	ID        string `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// request body of IFTTT triggers
+type iftttTriggerRequest struct {
+	Limit *int `json:"limit"`
+}
+
+// request body of IFTTT actions
+type iftttActionRequest struct {
+	ActionFields automationActionFields `json:"actionFields"`
+}
+
+// fields for creating a reminder
+type automationActionFields struct {
+	Message string `json:"message"`
+	When    string `json:"when"` // "2006-01-02 15:04", RFC3339, or duration like "10m" (now if empty)
+}
+
+// api key from given request
+func apiKeyFromRequest(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+
+	return r.URL.Query().Get("key")
+}
+
+// POST: IFTTT polling trigger returning delivered reminders (newest first)
+func handleIFTTTTrigger(w http.ResponseWriter, r *http.Request, chatID int64) {
+	if r.Method != http.MethodPost {
+		writeIFTTTError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	limit := automationDefaultLimit
+	var req iftttTriggerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err == nil && req.Limit != nil {
+		limit = *req.Limit
+	}
+
+	reminders := deliveredReminders(chatID, limit)
+	for i := range reminders {
+		reminders[i].Meta = &iftttMeta{
+			ID:        reminders[i].ID,
+			Timestamp: reminders[i].DeliveredOn.Unix(),
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": reminders})
+}
+
+// POST: IFTTT action creating a reminder
+func handleIFTTTAction(w http.ResponseWriter, r *http.Request, chatID int64) {
+	if r.Method != http.MethodPost {
+		writeIFTTTError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req iftttActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeIFTTTError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %s", err))
+		return
+	}
+
+	item, err := createAutomationReminder(chatID, req.ActionFields)
+	if err != nil {
+		writeIFTTTError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": []map[string]string{
+			map[string]string{"id": strconv.FormatInt(item.ID, 10)},
+		},
+	})
+}
+
+// GET: Zapier polling trigger returning delivered reminders (newest first)
+func handleZapierTrigger(w http.ResponseWriter, r *http.Request, chatID int64) {
+	if r.Method != http.MethodGet {
+		writeAPIResponse(w, http.StatusMethodNotAllowed, apiResponse{Error: "method not allowed"})
+		return
+	}
+
+	limit := automationDefaultLimit
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil {
+		limit = l
+	}
+
+	writeJSON(w, http.StatusOK, deliveredReminders(chatID, limit))
+}
+
+// POST: Zapier action creating a reminder
+func handleZapierAction(w http.ResponseWriter, r *http.Request, chatID int64) {
+	if r.Method != http.MethodPost {
+		writeAPIResponse(w, http.StatusMethodNotAllowed, apiResponse{Error: "method not allowed"})
+		return
+	}
+
+	var fields automationActionFields
+	if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+		writeAPIResponse(w, http.StatusBadRequest, apiResponse{Error: fmt.Sprintf("invalid request body: %s", err)})
+		return
+	}
+
+	item, err := createAutomationReminder(chatID, fields)
+	if err != nil {
+		writeAPIResponse(w, http.StatusBadRequest, apiResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, automationReminder{
+		ID:      strconv.FormatInt(item.ID, 10),
+		Message: item.Message,
+		FireOn:  item.FireOn,
+	})
+}
+
+// delivered reminders of given chat, newest first
+//
+// (none for limit 0, as IFTTT expects an empty array for it)
+func deliveredReminders(chatID int64, limit int) []automationReminder {
+	reminders := []automationReminder{}
+	if limit == 0 {
+		return reminders
+	}
+	if limit < 0 || limit > automationMaxLimit {
+		limit = automationDefaultLimit
+	}
+
+	for _, q := range db.SearchQueueItems(dbhelper.QueueFilter{ChatID: chatID, Delivered: true, Limit: limit}) {
+		if q.Kind != dbhelper.QueueKindReminder {
+			continue
+		}
+
+		reminders = append(reminders, automationReminder{
+			ID:          strconv.FormatInt(q.ID, 10),
+			Message:     q.Message,
+			FireOn:      q.FireOn,
+			DeliveredOn: q.DeliveredOn,
+		})
+	}
+
+	return reminders
+}
+
+// create a reminder with given fields
+func createAutomationReminder(chatID int64, fields automationActionFields) (item dbhelper.QueueItem, err error) {
+	if strings.TrimSpace(fields.Message) == "" {
+		return item, fmt.Errorf("message is empty")
+	}
+	message, err := sanitizeReminderText(chatID, fields.Message)
+	if err != nil {
+		return item, err
+	}
+
+	fireOn := time.Now()
+	if when := strings.TrimSpace(fields.When); when != "" {
+		if t, err := time.Parse(time.RFC3339, when); err == nil {
+			fireOn = t
+		} else if t, err := time.ParseInLocation(automationTimeFormat, when, _location); err == nil {
+			fireOn = t
+		} else if d, err := time.ParseDuration(when); err == nil && d >= 0 {
+			fireOn = fireOn.Add(d)
+		} else {
+			return item, fmt.Errorf("when is malformed (should look like '%s', RFC3339, or '10m'): %s", automationTimeFormat, when)
+		}
+	}
+	if fireOn.Before(time.Now().Add(-time.Minute)) {
+		return item, fmt.Errorf("when is in the past")
+	}
+
+	item = dbhelper.QueueItem{
+		ChatID:  chatID,
+		Message: message,
+		FireOn:  fireOn,
+	}
+	queueID, enqueued := db.EnqueueItem(item)
+	if !enqueued {
+		return item, fmt.Errorf("failed to save reminder")
+	}
+	item.ID = queueID
+
+	fireWebhooks(webhookEventCreated, item, "")
+
+	return item, nil
+}
+
+// write an error in the shape of IFTTT
+func writeIFTTTError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"errors": []map[string]string{
+			map[string]string{"message": message},
+		},
+	})
+}
+
+// write given value as json
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	backupFilenamePrefix    = "db-"
+	backupFilenameSuffix    = ".sqlite"
+	backupTimestampFormat   = "20060102-150405"
+	defaultNumBackupsToKeep = 7
+)
+
+func monitorBackup(monitor *time.Ticker) {
+	for {
+		select {
+		case <-monitor.C:
+			if path, err := backupDatabase(); err != nil {
+				log.Printf("*** failed to backup database: %s", err)
+
+				db.LogError(fmt.Sprintf("failed to backup database: %s", err))
+			} else if _conf.RemoteBackup != nil {
+				if err := uploadBackup(*_conf.RemoteBackup, path); err != nil {
+					log.Printf("*** failed to upload database backup: %s", err)
+
+					db.LogError(fmt.Sprintf("failed to upload database backup: %s", err))
+				}
+			}
+		}
+	}
+}
+
+// save a snapshot of the database in the backup directory, and remove old ones
+func backupDatabase() (path string, err error) {
+	path = filepath.Join(_backupDir, backupFilenamePrefix+time.Now().Format(backupTimestampFormat)+backupFilenameSuffix)
+
+	if !db.Backup(path) {
+		return "", fmt.Errorf("failed to save snapshot: %s", path)
+	}
+
+	if _isVerbose {
+		log.Printf("Saved database backup: %s", path)
+	}
+
+	pruneBackups()
+
+	return path, nil
+}
+
+// list backup files in the backup directory, newest first
+func backupFiles() []string {
+	files := []string{}
+
+	if infos, err := ioutil.ReadDir(_backupDir); err == nil {
+		for _, info := range infos {
+			if !info.IsDir() &&
+				strings.HasPrefix(info.Name(), backupFilenamePrefix) &&
+				strings.HasSuffix(info.Name(), backupFilenameSuffix) {
+				files = append(files, filepath.Join(_backupDir, info.Name()))
+			}
+		}
+	} else {
+		log.Printf("*** failed to read backup directory: %s", err)
+	}
+
+	// (timestamps in filenames are sortable)
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+
+	return files
+}
+
+// remove backups older than the configured number
+func pruneBackups() {
+	numToKeep := _conf.NumBackupsToKeep
+	if numToKeep <= 0 {
+		numToKeep = defaultNumBackupsToKeep
+	}
+
+	files := backupFiles()
+	if len(files) <= numToKeep {
+		return
+	}
+
+	for _, file := range files[numToKeep:] {
+		if err := os.Remove(file); err != nil {
+			log.Printf("*** failed to remove old backup: %s", err)
+		}
+	}
+}
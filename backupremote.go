@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// remote backups: snapshots of the database are encrypted and uploaded to an S3-compatible bucket or a WebDAV server
+// (after each local backup), and only the newest ones are kept there
+//
+//	$ reminderbot restore -list
+//	$ reminderbot restore [-name db-20240101-030000.sqlite.enc]
+
+const (
+	subcommandRestore = "restore"
+
+	remoteBackupFilenameSuffix    = backupFilenameSuffix + ".enc"
+	remoteBackupMagic             = "RBK1" // (format of encrypted snapshots: magic + salt + nonce + sealed)
+	remoteBackupMaxBytes          = 1024 * 1024 * 1024
+	defaultNumRemoteBackupsToKeep = 30
+
+	restoredFileSuffixFormat = ".before-restore-20060102-150405"
+)
+
+// config for remote backups
+type remoteBackupConfig struct {
+	S3         *s3Config     `json:"s3,omitempty"`
+	WebDAV     *webdavConfig `json:"webdav,omitempty"`
+	Passphrase string        `json:"passphrase"` // for encrypting snapshots (can be given like other secrets, eg. "env:BACKUP_PASSPHRASE")
+	NumToKeep  int           `json:"num_to_keep,omitempty"`
+}
+
+// validate remote backup config values
+func (c remoteBackupConfig) validate(backupIntervalHours int) (problems []string) {
+	if (c.S3 == nil) == (c.WebDAV == nil) {
+		problems = append(problems, "one of s3 or webdav of remote_backup should be configured")
+	}
+	if c.S3 != nil {
+		problems = append(problems, c.S3.validate("remote_backup.s3")...)
+	}
+	if c.WebDAV != nil && !strings.HasPrefix(c.WebDAV.URL, "https://") && !strings.HasPrefix(c.WebDAV.URL, "http://") {
+		problems = append(problems, fmt.Sprintf("url of remote_backup.webdav should be a http(s) url: '%s'", c.WebDAV.URL))
+	}
+	if c.Passphrase == "" {
+		problems = append(problems, "passphrase of remote_backup should be given")
+	}
+	if c.NumToKeep < 0 {
+		problems = append(problems, fmt.Sprintf("num_to_keep of remote_backup should not be negative: %d", c.NumToKeep))
+	}
+	if backupIntervalHours <= 0 {
+		problems = append(problems, "remote_backup needs backup_interval_hours")
+	}
+
+	return problems
+}
+
+// store of remote backups with given config
+func remoteBackupStore(c remoteBackupConfig) blobStore {
+	if c.S3 != nil {
+		return s3BlobStore{conf: *c.S3, maxBytes: remoteBackupMaxBytes}
+	}
+	return webdavBlobStore{conf: *c.WebDAV, maxBytes: remoteBackupMaxBytes}
+}
+
+// encrypt given snapshot with AES-GCM
+func encryptBackup(data []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, secretSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := backupCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	encrypted := append([]byte(remoteBackupMagic), salt...)
+	encrypted = append(encrypted, nonce...)
+
+	return gcm.Seal(encrypted, nonce, data, []byte(remoteBackupMagic)), nil
+}
+
+// decrypt given snapshot encrypted with encryptBackup
+func decryptBackup(data []byte, passphrase string) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(remoteBackupMagic)) {
+		return nil, fmt.Errorf("not an encrypted snapshot")
+	}
+	data = data[len(remoteBackupMagic):]
+	if len(data) < secretSaltLength {
+		return nil, fmt.Errorf("encrypted snapshot is too short")
+	}
+
+	gcm, err := backupCipher(passphrase, data[:secretSaltLength])
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[secretSaltLength:]
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted snapshot is too short")
+	}
+
+	decrypted, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(remoteBackupMagic))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt (wrong passphrase?): %s", err)
+	}
+	return decrypted, nil
+}
+
+// AES-GCM cipher with the key derived from given passphrase and salt
+func backupCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt and upload given local backup file, then remove old remote backups
+func uploadBackup(c remoteBackupConfig, path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := encryptBackup(data, c.Passphrase)
+	if err != nil {
+		return err
+	}
+
+	store := remoteBackupStore(c)
+	name := strings.TrimSuffix(filepath.Base(path), backupFilenameSuffix) + remoteBackupFilenameSuffix
+	if err := store.put(name, encrypted); err != nil {
+		return err
+	}
+
+	if _isVerbose {
+		log.Printf("Uploaded database backup: %s", name)
+	}
+
+	return pruneRemoteBackups(c, store)
+}
+
+// list remote backups, newest first
+func remoteBackups(store blobStore) ([]string, error) {
+	blobs, err := store.list(backupFilenamePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, blob := range blobs {
+		if strings.HasSuffix(blob.key, remoteBackupFilenameSuffix) && !strings.Contains(blob.key, "/") {
+			names = append(names, blob.key)
+		}
+	}
+
+	// (timestamps in names are sortable)
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+
+	return names, nil
+}
+
+// remove remote backups older than the configured number
+func pruneRemoteBackups(c remoteBackupConfig, store blobStore) error {
+	numToKeep := c.NumToKeep
+	if numToKeep <= 0 {
+		numToKeep = defaultNumRemoteBackupsToKeep
+	}
+
+	names, err := remoteBackups(store)
+	if err != nil {
+		return err
+	}
+	if len(names) <= numToKeep {
+		return nil
+	}
+
+	for _, name := range names[numToKeep:] {
+		if err := store.remove(name); err != nil {
+			log.Printf("*** failed to remove old remote backup %s: %s", name, err)
+		}
+	}
+
+	return nil
+}
+
+// run `restore` subcommand: download a remote backup and replace the database with it, then exit
+//
+// (the bot should not be running, and the current database is kept with a suffix)
+func runRestore(args []string) {
+	flags := flag.NewFlagSet(subcommandRestore, flag.ExitOnError)
+	list := flags.Bool("list", false, "list remote backups, newest first")
+	name := flags.String("name", "", "name of the remote backup to restore (default: the newest one)")
+	flags.Parse(args)
+
+	// (the database is not opened, as it will be replaced)
+	conf, err := openConfig()
+	if err == nil {
+		err = conf.resolveSecrets()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "*** failed to read config: %s\n", err)
+		os.Exit(1)
+	}
+	if conf.RemoteBackup == nil {
+		fmt.Fprintf(os.Stderr, "*** remote_backup is not configured: %s\n", *_configFilepath)
+		os.Exit(1)
+	}
+	if problems := conf.RemoteBackup.validate(conf.BackupIntervalHours); len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "*** invalid remote_backup: %s\n", strings.Join(problems, "; "))
+		os.Exit(1)
+	}
+
+	store := remoteBackupStore(*conf.RemoteBackup)
+
+	names, err := remoteBackups(store)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "*** failed to list remote backups: %s\n", err)
+		os.Exit(1)
+	}
+	if *list {
+		for _, n := range names {
+			fmt.Println(n)
+		}
+		return
+	}
+
+	target := *name
+	if target == "" {
+		if len(names) <= 0 {
+			fmt.Fprintf(os.Stderr, "*** no remote backups\n")
+			os.Exit(1)
+		}
+		target = names[0]
+	}
+
+	_dataDir = dataDirOf(conf)
+	dbFilepath := dataPath(dbFilename)
+	if err := lockDatabaseFile(dbFilepath); err != nil {
+		fmt.Fprintf(os.Stderr, "*** %s\n", err)
+		os.Exit(1)
+	}
+
+	encrypted, err := store.get(target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "*** failed to download %s: %s\n", target, err)
+		os.Exit(1)
+	}
+	data, err := decryptBackup(encrypted, conf.RemoteBackup.Passphrase)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "*** %s: %s\n", target, err)
+		os.Exit(1)
+	}
+
+	// keep the current database (and its journals, which should not be applied to the restored one)
+	kept := dbFilepath + time.Now().Format(restoredFileSuffixFormat)
+	if _, err := os.Stat(dbFilepath); err == nil {
+		if err := os.Rename(dbFilepath, kept); err != nil {
+			fmt.Fprintf(os.Stderr, "*** failed to keep the current database: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Kept the current database: %s\n", kept)
+	}
+	for _, suffix := range databaseFileSuffixes {
+		if _, err := os.Stat(dbFilepath + suffix); err == nil {
+			if err := os.Rename(dbFilepath+suffix, kept+suffix); err != nil {
+				fmt.Fprintf(os.Stderr, "*** failed to keep %s: %s\n", dbFilepath+suffix, err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	if err := ioutil.WriteFile(dbFilepath, data, 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "*** failed to write the database: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Restored %s to: %s\n", target, dbFilepath)
+}
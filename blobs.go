@@ -0,0 +1,280 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// blob storage: files of reminders (photos) and exports are kept outside of the database,
+// and only their keys are saved in queue items;
+// blobs which are not referenced anymore are garbage-collected periodically
+
+const (
+	blobDefaultDir = "blobs" // (relative to the data directory)
+
+	blobPrefixAttachments = "attachments/"
+	blobPrefixExports     = "exports/"
+
+	attachmentMaxBytes         = 20 * 1024 * 1024 // (limit of downloads from telegram)
+	attachmentMaxCaptionLength = 1024             // (limit of telegram)
+	attachmentPendingMinutes   = 30               // photos are kept this long while api.ai asks for the rest
+	attachmentRetentionDays    = 30               // attachments of delivered reminders are kept this long
+
+	attachmentIndicator = "📎 "
+
+	blobGCIntervalMinutes = 60
+	blobGCGraceMinutes    = 60 // (newer blobs are not collected, as they can be pending yet)
+	blobExportRetention   = 7 * 24 * time.Hour
+)
+
+// config for blob storage (a local directory if not configured)
+type blobStorageConfig struct {
+	Dir string    `json:"dir,omitempty"` // local directory (default: "blobs" in the data directory)
+	S3  *s3Config `json:"s3,omitempty"`  // S3-compatible storage (instead of the local directory)
+}
+
+// messages (can be overridden with messages.json)
+var (
+	messageAttachmentCaptionNeeded = "사진에 알림 내용을 함께 적어 보내 주세요. (예: 사진과 함께 '내일 아침 9시에 이 영수증 정리')"
+	messageAttachmentSaveFailed    = "사진을 저장하지 못했습니다."
+	messageAttachmentMissing       = "(첨부된 사진을 찾을 수 없습니다)"
+)
+
+// a blob in the storage
+type blobInfo struct {
+	key        string
+	modifiedOn time.Time
+}
+
+// storage of blobs
+type blobStore interface {
+	put(key string, data []byte) error
+	get(key string) ([]byte, error)
+	remove(key string) error
+	list(prefix string) ([]blobInfo, error)
+}
+
+var _blobStore blobStore
+
+// photos waiting for their reminders to be confirmed
+type pendingAttachment struct {
+	key       string
+	expiresOn time.Time
+}
+
+// photos of reminders not confirmed yet (key: chat id/user id)
+var _pendingAttachments = struct {
+	sync.Mutex
+	attachments map[string]pendingAttachment
+}{
+	attachments: map[string]pendingAttachment{},
+}
+
+// validate blob storage config values
+func (c blobStorageConfig) validate() (problems []string) {
+	if c.S3 != nil {
+		if c.Dir != "" {
+			problems = append(problems, "only one of dir and s3 of blob_storage should be configured")
+		}
+		problems = append(problems, c.S3.validate("blob_storage.s3")...)
+	}
+
+	return problems
+}
+
+// validate S3 config values (named with given key)
+func (c s3Config) validate(name string) (problems []string) {
+	if !strings.HasPrefix(c.Endpoint, "https://") && !strings.HasPrefix(c.Endpoint, "http://") {
+		problems = append(problems, fmt.Sprintf("endpoint of %s should be a http(s) url: '%s'", name, c.Endpoint))
+	}
+	if c.Region == "" || c.Bucket == "" {
+		problems = append(problems, fmt.Sprintf("region and bucket of %s should be given", name))
+	}
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		problems = append(problems, fmt.Sprintf("access_key_id and secret_access_key of %s should be given", name))
+	}
+
+	return problems
+}
+
+// setup the blob store with given config (can be nil)
+func setupBlobStore(c *blobStorageConfig) {
+	if c != nil && c.S3 != nil {
+		_blobStore = s3BlobStore{conf: *c.S3}
+		return
+	}
+
+	dir := blobDefaultDir
+	if c != nil && c.Dir != "" {
+		dir = c.Dir
+	}
+	_blobStore = localBlobStore{dir: dataPath(dir)}
+}
+
+// generate a new key with given prefix and extension
+func newBlobKey(prefix, ext string) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return fmt.Sprintf("%s%s/%s%s", prefix, time.Now().Format("20060102"), hex.EncodeToString(b), ext)
+}
+
+// check if given message is a photo
+func isPhotoMessage(message *bot.Message) bool {
+	return len(message.Photo) > 0
+}
+
+// check if given message is a photo with a caption (which can be a reminder with the photo attached)
+func isCaptionedPhotoMessage(message *bot.Message) bool {
+	return isPhotoMessage(message) && message.Caption != nil && strings.TrimSpace(*message.Caption) != ""
+}
+
+// save the photo of given message, and keep it until the reminder in its caption is confirmed by given user
+func processPhotoMessage(b *bot.Bot, chatID, userID int64, message *bot.Message) (caption string, errorMessage string) {
+	if message.Caption == nil || strings.TrimSpace(*message.Caption) == "" {
+		return "", messageAttachmentCaptionNeeded
+	}
+
+	// (the last one is the biggest)
+	photo := message.Photo[len(message.Photo)-1]
+
+	data, err := downloadFile(b, photo.FileID, attachmentMaxBytes)
+	if err != nil {
+		log.Printf("*** failed to download photo for attachment: %s", err)
+		return "", messageAttachmentSaveFailed
+	}
+
+	key := newBlobKey(blobPrefixAttachments, ".jpg")
+	if err := _blobStore.put(key, data); err != nil {
+		log.Printf("*** failed to save attachment %s: %s", key, err)
+		return "", messageAttachmentSaveFailed
+	}
+
+	_pendingAttachments.Lock()
+	_pendingAttachments.attachments[pendingKey(chatID, userID)] = pendingAttachment{
+		key:       key,
+		expiresOn: time.Now().Add(attachmentPendingMinutes * time.Minute),
+	}
+	_pendingAttachments.Unlock()
+
+	return *message.Caption, ""
+}
+
+// attach the pending photo of its creator in its chat to given queue item
+func attachPendingAttachment(item *dbhelper.QueueItem) {
+	key := pendingKey(item.ChatID, item.UserID)
+
+	_pendingAttachments.Lock()
+	defer _pendingAttachments.Unlock()
+
+	pending, exists := _pendingAttachments.attachments[key]
+	if !exists {
+		return
+	}
+	delete(_pendingAttachments.attachments, key)
+
+	if time.Now().Before(pending.expiresOn) {
+		item.Attachment = pending.key
+	}
+}
+
+// send given reminder with its attached photo
+func sendAttachment(client *bot.Bot, q dbhelper.QueueItem) bot.APIResponseMessage {
+	message, options := deliveryMessage(client, q)
+	chatID := deliveryChatID(q, options)
+
+	data, err := _blobStore.get(q.Attachment)
+	if err != nil {
+		log.Printf("*** failed to load attachment %s of queue item %d: %s", q.Attachment, q.ID, err)
+
+		return client.SendMessage(chatID, message+"\n\n"+messageAttachmentMissing, options)
+	}
+
+	// (sent separately, when the text is too long for a caption)
+	if utf8.RuneCountInString(message) > attachmentMaxCaptionLength {
+		if sent := client.SendPhoto(chatID, bot.InputFileFromBytes(data), map[string]interface{}{}); !sent.Ok {
+			return sent
+		}
+		return client.SendMessage(chatID, message, options)
+	}
+
+	delete(options, "disable_web_page_preview")
+	options["caption"] = message
+
+	return client.SendPhoto(chatID, bot.InputFileFromBytes(data), options)
+}
+
+// keep a copy of given export file (for blobExportRetention)
+func keepExport(name string, data []byte) {
+	key := newBlobKey(blobPrefixExports, "-"+name)
+	if err := _blobStore.put(key, data); err != nil {
+		log.Printf("*** failed to keep export %s: %s", key, err)
+	}
+}
+
+// collect garbage blobs periodically
+func monitorBlobs(ticker *time.Ticker) {
+	for range ticker.C {
+		collectBlobs()
+	}
+}
+
+// delete attachments which are not referenced by any (recent) queue item, and exports which are too old
+func collectBlobs() {
+	now := time.Now()
+	numDeleted := 0
+
+	// (attachments are not collected at all when the referenced ones are unknown)
+	if keys, loaded := db.QueueAttachments(now.AddDate(0, 0, -attachmentRetentionDays)); !loaded {
+		log.Printf("*** failed to load referenced attachments")
+	} else if blobs, err := _blobStore.list(blobPrefixAttachments); err != nil {
+		log.Printf("*** failed to list attachments: %s", err)
+	} else {
+		referenced := map[string]bool{}
+		for _, key := range keys {
+			referenced[key] = true
+		}
+
+		for _, blob := range blobs {
+			if referenced[blob.key] || now.Sub(blob.modifiedOn) < blobGCGraceMinutes*time.Minute {
+				continue
+			}
+
+			if err := _blobStore.remove(blob.key); err != nil {
+				log.Printf("*** failed to delete attachment %s: %s", blob.key, err)
+			} else {
+				numDeleted++
+			}
+		}
+	}
+
+	if blobs, err := _blobStore.list(blobPrefixExports); err != nil {
+		log.Printf("*** failed to list exports: %s", err)
+	} else {
+		for _, blob := range blobs {
+			if now.Sub(blob.modifiedOn) < blobExportRetention {
+				continue
+			}
+
+			if err := _blobStore.remove(blob.key); err != nil {
+				log.Printf("*** failed to delete export %s: %s", blob.key, err)
+			} else {
+				numDeleted++
+			}
+		}
+	}
+
+	if numDeleted > 0 {
+		db.Log(fmt.Sprintf("deleted %d garbage blobs", numDeleted))
+	}
+}
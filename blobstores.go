@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// stores of blobs (in a local directory, an S3-compatible storage, or a WebDAV server)
+
+const (
+	blobTimeoutSeconds = 300 // (long enough for uploading snapshots of the database)
+
+	s3Service        = "s3"
+	s3Algorithm      = "AWS4-HMAC-SHA256"
+	s3DateTimeFormat = "20060102T150405Z"
+	s3DateFormat     = "20060102"
+)
+
+// config for S3-compatible storages (AWS S3, MinIO, Cloudflare R2, ...)
+type s3Config struct {
+	Endpoint        string `json:"endpoint"` // eg. "https://s3.ap-northeast-2.amazonaws.com", "http://localhost:9000"
+	Region          string `json:"region"`
+	Bucket          string `json:"bucket"`
+	Prefix          string `json:"prefix,omitempty"` // prepended to all keys (eg. "reminder-bot/")
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+}
+
+// config for WebDAV servers (Nextcloud, ownCloud, ...)
+type webdavConfig struct {
+	URL      string `json:"url"` // url of the collection (eg. "https://cloud.example.com/remote.php/dav/files/me/backups/")
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+var errBlobNotFound = errors.New("blob not found")
+
+var _blobClient = &http.Client{Timeout: blobTimeoutSeconds * time.Second}
+
+// check if given key is safe to be used as a path
+func isValidBlobKey(key string) bool {
+	return key != "" && !strings.HasPrefix(key, "/") && !strings.Contains(key, "..")
+}
+
+// blobs in a local directory
+type localBlobStore struct {
+	dir string
+}
+
+func (s localBlobStore) put(key string, data []byte) error {
+	if !isValidBlobKey(key) {
+		return fmt.Errorf("invalid key: %s", key)
+	}
+
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	// (written to a temporary file first, not to leave a partial one)
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s localBlobStore) get(key string) ([]byte, error) {
+	if !isValidBlobKey(key) {
+		return nil, fmt.Errorf("invalid key: %s", key)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return nil, errBlobNotFound
+	}
+	return data, err
+}
+
+func (s localBlobStore) remove(key string) error {
+	if !isValidBlobKey(key) {
+		return fmt.Errorf("invalid key: %s", key)
+	}
+
+	if err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(key))); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s localBlobStore) list(prefix string) ([]blobInfo, error) {
+	blobs := []blobInfo{}
+
+	root := filepath.Join(s.dir, filepath.FromSlash(prefix))
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return blobs, nil
+	}
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		blobs = append(blobs, blobInfo{key: filepath.ToSlash(rel), modifiedOn: info.ModTime()})
+
+		return nil
+	})
+
+	return blobs, err
+}
+
+// blobs in an S3-compatible storage (with path-style urls and signature v4)
+type s3BlobStore struct {
+	conf     s3Config
+	maxBytes int64 // of responses (attachmentMaxBytes if 0)
+}
+
+// response of ListObjectsV2
+type s3ListResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+func (s s3BlobStore) put(key string, data []byte) error {
+	if !isValidBlobKey(key) {
+		return fmt.Errorf("invalid key: %s", key)
+	}
+
+	_, err := s.do("PUT", s.conf.Prefix+key, nil, data)
+	return err
+}
+
+func (s s3BlobStore) get(key string) ([]byte, error) {
+	if !isValidBlobKey(key) {
+		return nil, fmt.Errorf("invalid key: %s", key)
+	}
+
+	return s.do("GET", s.conf.Prefix+key, nil, nil)
+}
+
+func (s s3BlobStore) remove(key string) error {
+	if !isValidBlobKey(key) {
+		return fmt.Errorf("invalid key: %s", key)
+	}
+
+	if _, err := s.do("DELETE", s.conf.Prefix+key, nil, nil); err != nil && err != errBlobNotFound {
+		return err
+	}
+	return nil
+}
+
+func (s s3BlobStore) list(prefix string) ([]blobInfo, error) {
+	blobs := []blobInfo{}
+
+	token := ""
+	for {
+		query := map[string]string{
+			"list-type": "2",
+			"prefix":    s.conf.Prefix + prefix,
+		}
+		if token != "" {
+			query["continuation-token"] = token
+		}
+
+		body, err := s.do("GET", "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result s3ListResult
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, err
+		}
+		for _, c := range result.Contents {
+			blobs = append(blobs, blobInfo{key: strings.TrimPrefix(c.Key, s.conf.Prefix), modifiedOn: c.LastModified})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	return blobs, nil
+}
+
+// send a signed request for given object key (or the bucket itself, if empty)
+func (s s3BlobStore) do(method, key string, query map[string]string, data []byte) ([]byte, error) {
+	endpoint, err := url.Parse(s.conf.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	path := "/" + s.conf.Bucket
+	if key != "" {
+		path += "/" + s3Escape(key, false)
+	}
+	canonicalQuery := s3CanonicalQuery(query)
+
+	target := fmt.Sprintf("%s://%s%s", endpoint.Scheme, endpoint.Host, path)
+	if canonicalQuery != "" {
+		target += "?" + canonicalQuery
+	}
+
+	req, err := http.NewRequest(method, target, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, path, canonicalQuery, data, time.Now().UTC())
+
+	return doBlobRequest(req, s.maxBytes)
+}
+
+// send given request, and return its response body (errBlobNotFound for 404)
+func doBlobRequest(req *http.Request, maxBytes int64) ([]byte, error) {
+	if maxBytes <= 0 {
+		maxBytes = attachmentMaxBytes
+	}
+
+	resp, err := _blobClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, errBlobNotFound
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, fmt.Errorf("http status: %s (%s)", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return body, nil
+}
+
+// sign given request with AWS signature version 4
+func (s s3BlobStore) sign(req *http.Request, path, canonicalQuery string, data []byte, now time.Time) {
+	payloadHash := sha256Hex(data)
+	amzDate := now.Format(s3DateTimeFormat)
+	scope := strings.Join([]string{now.Format(s3DateFormat), s.conf.Region, s3Service, "aws4_request"}, "/")
+
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	req.Header.Set("x-amz-date", amzDate)
+
+	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", req.URL.Host, payloadHash, amzDate)
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+
+	canonicalRequest := strings.Join([]string{req.Method, path, canonicalQuery, canonicalHeaders, signedHeaders, payloadHash}, "\n")
+	stringToSign := strings.Join([]string{s3Algorithm, amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.conf.SecretAccessKey), now.Format(s3DateFormat))
+	for _, part := range []string{s.conf.Region, s3Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s", s3Algorithm, s.conf.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// query string sorted by keys, with values escaped for signature v4
+func s3CanonicalQuery(query map[string]string) string {
+	keys := []string{}
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	params := []string{}
+	for _, k := range keys {
+		params = append(params, s3Escape(k, true)+"="+s3Escape(query[k], true))
+	}
+	return strings.Join(params, "&")
+}
+
+// escape given string for signature v4 (slashes are kept in paths)
+func s3Escape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !escapeSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// blobs in a WebDAV collection
+//
+// (keys are listed only in the collection itself, not in its sub-collections)
+type webdavBlobStore struct {
+	conf     webdavConfig
+	maxBytes int64 // of responses (attachmentMaxBytes if 0)
+}
+
+// response of PROPFIND
+type webdavMultistatus struct {
+	Responses []struct {
+		Href         string    `xml:"href"`
+		LastModified string    `xml:"propstat>prop>getlastmodified"`
+		Collection   *struct{} `xml:"propstat>prop>resourcetype>collection"`
+	} `xml:"response"`
+}
+
+func (s webdavBlobStore) put(key string, data []byte) error {
+	if !isValidBlobKey(key) {
+		return fmt.Errorf("invalid key: %s", key)
+	}
+
+	_, err := s.do("PUT", key, nil, data)
+	if err != nil && strings.Contains(key, "/") {
+		// (parent collections may not exist yet)
+		parts := strings.Split(key, "/")
+		for i := 1; i < len(parts); i++ {
+			s.do("MKCOL", strings.Join(parts[:i], "/")+"/", nil, nil)
+		}
+		_, err = s.do("PUT", key, nil, data)
+	}
+	return err
+}
+
+func (s webdavBlobStore) get(key string) ([]byte, error) {
+	if !isValidBlobKey(key) {
+		return nil, fmt.Errorf("invalid key: %s", key)
+	}
+
+	return s.do("GET", key, nil, nil)
+}
+
+func (s webdavBlobStore) remove(key string) error {
+	if !isValidBlobKey(key) {
+		return fmt.Errorf("invalid key: %s", key)
+	}
+
+	if _, err := s.do("DELETE", key, nil, nil); err != nil && err != errBlobNotFound {
+		return err
+	}
+	return nil
+}
+
+func (s webdavBlobStore) list(prefix string) ([]blobInfo, error) {
+	base, err := url.Parse(s.conf.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := s.do("PROPFIND", "", map[string]string{"Depth": "1"}, []byte(`<?xml version="1.0"?><propfind xmlns="DAV:"><prop><getlastmodified/><resourcetype/></prop></propfind>`))
+	if err != nil {
+		return nil, err
+	}
+
+	var result webdavMultistatus
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	blobs := []blobInfo{}
+	for _, r := range result.Responses {
+		if r.Collection != nil {
+			continue
+		}
+
+		href, err := base.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+		key := strings.TrimPrefix(href.Path, strings.TrimSuffix(base.Path, "/")+"/")
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		modifiedOn, _ := time.Parse(time.RFC1123, r.LastModified)
+		blobs = append(blobs, blobInfo{key: key, modifiedOn: modifiedOn})
+	}
+
+	return blobs, nil
+}
+
+// send a request for given key (or the collection itself, if empty)
+func (s webdavBlobStore) do(method, key string, headers map[string]string, data []byte) ([]byte, error) {
+	target := strings.TrimSuffix(s.conf.URL, "/") + "/" + s3Escape(key, false)
+
+	req, err := http.NewRequest(method, target, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if s.conf.Username != "" {
+		req.SetBasicAuth(s.conf.Username, s.conf.Password)
+	}
+	if method == "PROPFIND" {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	return doBlobRequest(req, s.maxBytes)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
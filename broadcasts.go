@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// broadcast engine: messages for many chats (eg. announcements) are saved with their recipients,
+// then sent in chunks within the global rate limit, honoring 'retry after' of telegram,
+// and resumed from the pending recipients after restarts; admins get the stats when finished
+
+const (
+	broadcastChunkSize                = 100 // recipients loaded at once
+	broadcastMaxTries                 = 3   // for each recipient (with transient errors)
+	broadcastDefaultMessagesPerSecond = 25  // (telegram allows about 30 messages per second in total)
+)
+
+// limiter shared by all broadcasts
+var _broadcastLimiter struct {
+	sync.Once
+	ticker *time.Ticker
+}
+
+// broadcasts being sent now (not to be run twice)
+var _runningBroadcasts = struct {
+	sync.Mutex
+	ids map[int64]bool
+}{
+	ids: map[int64]bool{},
+}
+
+// wait for the global rate limit of broadcasts
+func waitBroadcastLimit() {
+	_broadcastLimiter.Do(func() {
+		perSecond := _conf.BroadcastMessagesPerSecond
+		if perSecond <= 0 {
+			perSecond = broadcastDefaultMessagesPerSecond
+		}
+		_broadcastLimiter.ticker = time.NewTicker(time.Duration(float64(time.Second) / perSecond))
+	})
+
+	<-_broadcastLimiter.ticker.C
+}
+
+// start broadcasting given message to given chats (in background), marking given queue item as delivered
+func startBroadcast(client *bot.Bot, q dbhelper.QueueItem, message string, chatIDs []int64) bool {
+	broadcastID, created := db.CreateBroadcast(q.ChatID, q.ID, message, chatIDs)
+	if !created {
+		return false
+	}
+
+	go runBroadcast(client, dbhelper.Broadcast{
+		ID:          broadcastID,
+		QueueChatID: q.ChatID,
+		QueueID:     q.ID,
+		Message:     message,
+		Status:      dbhelper.BroadcastStatusRunning,
+		CreatedOn:   time.Now(),
+	})
+
+	return true
+}
+
+// resume broadcasts which were not finished (eg. stopped by a crash)
+func resumeBroadcasts(client *bot.Bot) {
+	for _, b := range db.UnfinishedBroadcasts() {
+		log.Printf("> Resuming broadcast %d...", b.ID)
+
+		go runBroadcast(client, b)
+	}
+}
+
+// send given broadcast to its pending recipients, then report the stats to admins (or to the publisher, for topic posts)
+func runBroadcast(client *bot.Bot, b dbhelper.Broadcast) {
+	_runningBroadcasts.Lock()
+	if _runningBroadcasts.ids[b.ID] {
+		_runningBroadcasts.Unlock()
+		return
+	}
+	_runningBroadcasts.ids[b.ID] = true
+	_runningBroadcasts.Unlock()
+
+	defer func() {
+		_runningBroadcasts.Lock()
+		delete(_runningBroadcasts.ids, b.ID)
+		_runningBroadcasts.Unlock()
+	}()
+
+	for {
+		chatIDs := db.PendingBroadcastRecipients(b.ID, broadcastChunkSize)
+		if len(chatIDs) <= 0 {
+			break
+		}
+
+		for _, chatID := range chatIDs {
+			sendBroadcastTo(client, b, chatID)
+		}
+	}
+
+	db.FinishBroadcast(b.ID)
+
+	stats := db.BroadcastStatsOf(b.ID)
+
+	log.Printf("Broadcasted %d: %d succeeded, %d failed (in %s)", b.ID, stats.NumSent, stats.NumFailed, time.Since(b.CreatedOn))
+
+	db.Log(fmt.Sprintf("broadcasted %d (queue item %d): %d succeeded, %d failed", b.ID, b.QueueID, stats.NumSent, stats.NumFailed))
+
+	if q, exists := db.QueueItem(b.QueueChatID, b.QueueID); exists && q.Kind == dbhelper.QueueKindTopicPost {
+		reportTopicPost(client, q, stats)
+	} else {
+		notifyAdmins(client, fmt.Sprintf(messageAnnounceDoneFormat, stats.NumSent, stats.NumFailed))
+	}
+}
+
+// send given broadcast to given chat, and save the result
+//
+// (the recipient is left pending for transient errors, to be tried again in the next chunk)
+func sendBroadcastTo(client *bot.Bot, b dbhelper.Broadcast, chatID int64) {
+	for {
+		waitBroadcastLimit()
+
+		sent := client.SendMessage(chatID, b.Message, map[string]interface{}{})
+		if sent.Ok {
+			db.SetBroadcastRecipientStatus(b.ID, chatID, dbhelper.RecipientStatusSent, "")
+			return
+		}
+
+		err := telegramError(*sent.Description)
+
+		log.Printf("*** failed to send broadcast %d to chat %d (%s): %s", b.ID, chatID, telegramErrorKind(err), *sent.Description)
+
+		if e, ok := err.(ErrRateLimited); ok {
+			// (not counted as a try)
+			time.Sleep(e.RetryAfter)
+			continue
+		}
+
+		status := dbhelper.RecipientStatusPending
+		if isPermanentTelegramError(err) || db.NumBroadcastTries(b.ID, chatID)+1 >= broadcastMaxTries {
+			status = dbhelper.RecipientStatusFailed
+		}
+		db.SetBroadcastRecipientStatus(b.ID, chatID, status, *sent.Description)
+
+		return
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	apiai "github.com/meinside/api.ai-go"
+	bot "github.com/meinside/telegram-bot-go"
+
+	aihelper "github.com/meinside/telegram-bot-reminder-api.ai/ai"
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// telegram business: the bot can be attached to business accounts (Settings > Telegram Business > Chatbots),
+// then their owners can schedule reminders in customer chats with '/remind ...', which are sent on their behalf
+//
+// (owners allow it per connection with /business, and results are reported in their private chats, not to the customers)
+
+const (
+	commandBusiness       = "/business"
+	commandBusinessRemind = "/remind"
+
+	businessParamOn  = "on"
+	businessParamOff = "off"
+
+	businessDisabledError = "Forbidden: business connection is disabled or not allowed"
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageBusinessConnectedFormat = "비즈니스 계정에 연결되었습니다. (답장 권한: %s)\n고객 대화에서 '/remind 내일 오후 3시에 예약 확인 부탁드립니다'처럼 보내면 그 대화에 알림을 보내드립니다.\n아래 버튼으로 허용해 주세요."
+	messageBusinessDisconnected    = "비즈니스 계정 연결이 해제되었습니다."
+	messageBusinessNone            = "연결된 비즈니스 계정이 없습니다. (설정 > Telegram Business > 챗봇에서 이 봇을 연결할 수 있습니다)"
+	messageBusinessListTitle       = "연결된 비즈니스 계정:"
+	messageBusinessLineFormat      = "➤ %s (연결: %s, 답장 권한: %s, 알림 예약: %s)"
+	messageBusinessAllow           = "알림 예약 허용"
+	messageBusinessDisallow        = "알림 예약 막기"
+	messageBusinessAllowed         = "이제 고객 대화에서 알림을 예약할 수 있습니다."
+	messageBusinessDisallowed      = "이제 고객 대화에서 알림을 예약할 수 없습니다."
+	messageBusinessNotAllowed      = "고객 대화에서의 알림 예약이 허용되지 않았습니다. (/business에서 허용할 수 있습니다)"
+	messageBusinessCannotReply     = "비즈니스 계정 설정에서 봇의 답장 권한을 켜 주세요."
+	messageBusinessRemindUsage     = "고객 대화에서 '/remind 내일 오후 3시에 예약 확인 부탁드립니다'처럼 보내 주세요."
+	messageBusinessScheduledFormat = "고객 대화(%s)에 '%s'(을)를 %s에 보내도록 예약했습니다."
+	messageBusinessUnknownCustomer = "알 수 없는 고객"
+	messageBusinessTimeFormat      = "1월 2일 15:04"
+)
+
+// process updates of business connections: save them, and tell their owners
+func processBusinessConnection(b *bot.Bot, connection bot.BusinessConnection) {
+	c := dbhelper.BusinessConnection{
+		ID:         connection.ID,
+		UserID:     int64(connection.User.ID),
+		UserChatID: connection.UserChatID,
+		CanReply:   connection.CanReply,
+		IsEnabled:  connection.IsEnabled,
+	}
+	if !db.SaveBusinessConnection(c) {
+		return
+	}
+
+	saveUser(&connection.User)
+
+	message := messageBusinessDisconnected
+	options := map[string]interface{}{}
+	if c.IsEnabled {
+		message = fmt.Sprintf(messageBusinessConnectedFormat, onOff(c.CanReply))
+		if saved, exists := db.BusinessConnection(c.ID); exists {
+			options["reply_markup"] = businessKeyboard(saved)
+		}
+	}
+
+	if sent := b.SendMessage(c.UserChatID, message, options); !sent.Ok {
+		log.Printf("*** failed to notify business connection to user %d: %s", c.UserID, *sent.Description)
+	}
+}
+
+// process messages of business chats: only '/remind ...' from the owners are handled
+//
+// (messages from customers are ignored, not to answer them on behalf of the owners)
+func processBusinessMessage(b *bot.Bot, message *bot.Message) {
+	if message.BusinessConnectionID == nil || message.From == nil || !message.HasText() {
+		return
+	}
+
+	c, exists := db.BusinessConnection(*message.BusinessConnectionID)
+	if !exists || int64(message.From.ID) != c.UserID {
+		return
+	}
+
+	txt := strings.TrimSpace(*message.Text)
+	if !strings.HasPrefix(txt, commandBusinessRemind) {
+		return
+	}
+
+	result := scheduleBusinessReminder(c, message.Chat, strings.TrimSpace(strings.TrimPrefix(txt, commandBusinessRemind)))
+	if sent := b.SendMessage(c.UserChatID, result, map[string]interface{}{}); !sent.Ok {
+		log.Printf("*** failed to send result of business reminder to user %d: %s", c.UserID, *sent.Description)
+	}
+}
+
+// schedule a reminder in given business chat with given text (eg. "내일 오후 3시에 예약 확인 부탁드립니다")
+//
+// (there is no confirmation step, as it would be shown to the customer)
+func scheduleBusinessReminder(c dbhelper.BusinessConnection, chat bot.Chat, txt string) string {
+	if !c.IsEnabled || !c.CanSchedule {
+		return messageBusinessNotAllowed
+	}
+	if !c.CanReply {
+		return messageBusinessCannotReply
+	}
+	if txt == "" {
+		return messageBusinessRemindUsage
+	}
+
+	response, err := ai.QueryText(apiai.QueryRequest{
+		Query:         []string{txt},
+		SessionId:     fmt.Sprintf("bz_%d", chat.ID),
+		Language:      apiai.Korean,
+		ResetContexts: true,
+	})
+	if err != nil || response.Status.ErrorType != apiai.Success {
+		log.Printf("*** failed to query business reminder: %v", err)
+		return messageError
+	}
+	if response.Result.ActionIncomplete || response.Result.Metadata.IntentName != aihelper.IntentNameMessage {
+		return messageBusinessRemindUsage
+	}
+
+	params := response.Result.Parameters
+	msg, hasMessage := params["message"]
+	tm, hasTime := params["time"]
+	if !hasMessage || !hasTime {
+		return messageBusinessRemindUsage
+	}
+	dt, _ := params["date"].(string)
+	anchor, _ := params["anchor"].(string)
+
+	sanitized, err := sanitizeReminderText(chat.ID, fmt.Sprintf("%s", msg))
+	if err != nil {
+		return err.Error()
+	}
+
+	when, recurrence, err := resolveSchedule(dt, fmt.Sprintf("%s", tm), anchor)
+	if err != nil {
+		return messageTimeParseError
+	}
+	if when.Before(time.Now()) {
+		return formatTimeFor(chat.ID, when, messageTimeIsPastFormat)
+	}
+
+	if _, enqueued := db.EnqueueItem(dbhelper.QueueItem{
+		ChatID:               chat.ID,
+		UserID:               c.UserID,
+		Message:              sanitized,
+		FireOn:               when,
+		Recurrence:           recurrence,
+		BusinessConnectionID: c.ID,
+	}); !enqueued {
+		return messageSaveFailed
+	}
+
+	return fmt.Sprintf(messageBusinessScheduledFormat, businessChatName(chat), sanitized, when.Format(messageBusinessTimeFormat))
+}
+
+// displayable name of given business chat
+func businessChatName(chat bot.Chat) string {
+	if chat.FirstName != nil {
+		return *chat.FirstName
+	}
+	if chat.Username != nil {
+		return "@" + *chat.Username
+	}
+	return messageBusinessUnknownCustomer
+}
+
+// process /business command: list business connections of the user with buttons for allowing reminders
+func processBusinessCommand(userID int64, options map[string]interface{}) string {
+	connections := db.BusinessConnectionsOfUser(userID)
+	if len(connections) <= 0 {
+		return messageBusinessNone
+	}
+
+	lines := []string{messageBusinessListTitle}
+	buttons := [][]bot.InlineKeyboardButton{}
+	for _, c := range connections {
+		lines = append(lines, fmt.Sprintf(messageBusinessLineFormat, c.ID, onOff(c.IsEnabled), onOff(c.CanReply), onOff(c.CanSchedule)))
+		buttons = append(buttons, businessKeyboard(c).InlineKeyboard...)
+	}
+	options["reply_markup"] = bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+
+	return strings.Join(lines, "\n")
+}
+
+// inline keyboard for allowing/disallowing reminders in the business chats of given connection
+func businessKeyboard(c dbhelper.BusinessConnection) bot.InlineKeyboardMarkup {
+	text, data := messageBusinessAllow, fmt.Sprintf("%s %s %s", commandBusiness, c.ID, businessParamOn)
+	if c.CanSchedule {
+		text, data = messageBusinessDisallow, fmt.Sprintf("%s %s %s", commandBusiness, c.ID, businessParamOff)
+	}
+
+	return bot.InlineKeyboardMarkup{
+		InlineKeyboard: [][]bot.InlineKeyboardButton{
+			[]bot.InlineKeyboardButton{
+				bot.InlineKeyboardButton{Text: text, CallbackData: &data},
+			},
+		},
+	}
+}
+
+// process callback query for allowing reminders: '/business <connection id> <on|off>'
+func processBusinessCallback(query bot.CallbackQuery, txt string) (message string, keyboard interface{}) {
+	params := strings.Fields(strings.TrimPrefix(txt, commandBusiness))
+	if len(params) != 2 || (params[1] != businessParamOn && params[1] != businessParamOff) {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError, nil
+	}
+
+	allowed := params[1] == businessParamOn
+	if !db.SetBusinessConnectionSchedulable(params[0], int64(query.From.ID), allowed) {
+		return messageError, nil
+	}
+
+	if c, exists := db.BusinessConnection(params[0]); exists {
+		keyboard = businessKeyboard(c)
+	}
+	if allowed {
+		return messageBusinessAllowed, keyboard
+	}
+	return messageBusinessDisallowed, keyboard
+}
+
+// deliver given reminder to its business chat on behalf of the owner of its connection
+func sendBusinessReminder(client *bot.Bot, q dbhelper.QueueItem) (sent bot.APIResponseMessage) {
+	if c, exists := db.BusinessConnection(q.BusinessConnectionID); !exists || !c.IsEnabled || !c.CanReply || !c.CanSchedule {
+		description := businessDisabledError
+		sent.Description = &description
+		return sent
+	}
+
+	message, options := deliveryMessage(client, q)
+
+	// (customers should not see the controls of the bot)
+	delete(options, "reply_markup")
+	options["business_connection_id"] = q.BusinessConnectionID
+
+	return client.SendMessage(q.ChatID, message, options)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	cancelMatchConfident = 0.8 // cancel right away if the best match is this similar,
+	cancelMatchMargin    = 0.2 // and this more similar than the second one
+	cancelMatchMinimum   = 0.3 // matches less similar than this will not be shown
+	cancelMaxCandidates  = 3
+)
+
+// eg. "회의 알림 지워줘", "내일 병원 예약 알림 취소해 주세요"
+var cancelTextRegex = regexp.MustCompile(`^(.+?)\s*(?:알림|리마인더)?\s*(?:을|를)?\s*(?:지워|취소해|삭제해|없애)\s*(?:줘|주세요|줄래|줄래요)?[.!~?]*$`)
+
+// messages (can be overridden with messages.json)
+var (
+	messageCancelNoMatchFormat   = "'%s'와(과) 비슷한 알림이 없습니다."
+	messageCancelCandidates      = "어떤 알림을 취소할까요?"
+	messageCancelMatchedFormat   = "\"%s\" 알림을 취소했습니다."
+	messageCancelCandidateFormat = "%s (%.0f%%)"
+)
+
+// reminder with its similarity to the query
+type cancelCandidate struct {
+	reminder   dbhelper.QueueItem
+	similarity float64
+}
+
+// check if given text is a request for canceling a reminder by its text
+func isCancelText(txt string) bool {
+	return cancelTextRegex.MatchString(strings.TrimSpace(txt))
+}
+
+// cancel the reminder which matches given text best,
+// or show the best matches as buttons when it is not certain
+//
+// (private ones of other members are not matched)
+func processCancelText(chatID, userID int64, txt string, options map[string]interface{}) string {
+	matches := cancelTextRegex.FindStringSubmatch(strings.TrimSpace(txt))
+	if matches == nil {
+		return messageError
+	}
+	query := strings.TrimSpace(matches[1])
+
+	candidates := []cancelCandidate{}
+	for _, r := range db.UndeliveredQueueItems(chatID) {
+		if r.Kind != dbhelper.QueueKindReminder || isOthersPrivate(r, userID) {
+			continue
+		}
+		if similarity := textSimilarity(query, r.Message); similarity >= cancelMatchMinimum {
+			candidates = append(candidates, cancelCandidate{reminder: r, similarity: similarity})
+		}
+	}
+	if len(candidates) <= 0 {
+		return fmt.Sprintf(messageCancelNoMatchFormat, query)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].similarity > candidates[j].similarity
+	})
+
+	// confident: cancel it right away
+	best := candidates[0]
+	if best.similarity >= cancelMatchConfident &&
+		(len(candidates) == 1 || best.similarity-candidates[1].similarity >= cancelMatchMargin) {
+		if db.DeleteQueueItem(chatID, best.reminder.ID) {
+			fireWebhooks(webhookEventCanceled, best.reminder, "")
+
+			return fmt.Sprintf(messageCancelMatchedFormat, visibleReminderText(best.reminder))
+		}
+		return messageError
+	}
+
+	// not confident: let the user choose
+	if len(candidates) > cancelMaxCandidates {
+		candidates = candidates[:cancelMaxCandidates]
+	}
+	buttons := [][]bot.InlineKeyboardButton{}
+	for _, c := range candidates {
+		data := fmt.Sprintf("%s %d", commandCancel, c.reminder.ID)
+		buttons = append(buttons, []bot.InlineKeyboardButton{
+			bot.InlineKeyboardButton{
+				Text:         fmt.Sprintf(messageCancelCandidateFormat, formatReminder(c.reminder), c.similarity*100),
+				CallbackData: &data,
+			},
+		})
+	}
+	cancel := commandCancel
+	buttons = append(buttons, []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{
+			Text:         messageCancel,
+			CallbackData: &cancel,
+		},
+	})
+	options["reply_markup"] = bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+
+	return messageCancelCandidates
+}
+
+// similarity of given query and text (0.0 ~ 1.0)
+//
+// (the bigger one of normalized levenshtein similarity and token overlap)
+func textSimilarity(query, text string) float64 {
+	q, t := normalizeForMatching(query), normalizeForMatching(text)
+	if len(q) <= 0 || len(t) <= 0 {
+		return 0
+	}
+
+	// levenshtein (without spaces)
+	qr, tr := []rune(strings.Join(q, "")), []rune(strings.Join(t, ""))
+	longer := len(qr)
+	if len(tr) > longer {
+		longer = len(tr)
+	}
+	similarity := 1 - float64(levenshtein(qr, tr))/float64(longer)
+
+	// tokens of the query found in the text
+	found := 0
+	for _, qt := range q {
+		for _, tt := range t {
+			if strings.Contains(tt, qt) || strings.Contains(qt, tt) {
+				found++
+				break
+			}
+		}
+	}
+	if overlap := float64(found) / float64(len(q)); overlap > similarity {
+		similarity = overlap
+	}
+
+	return similarity
+}
+
+// lowercased tokens of given text without punctuations
+func normalizeForMatching(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// edit distance of given runes
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
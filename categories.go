@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// categories of reminders: hashtags in reminders (eg. #업무) can be routed to other chats
+// which the bot shares with the user (eg. a work group), configured with '/settings 분류 #업무'
+
+const (
+	callbackCategory = "/category"
+
+	settingParamCategory = "분류"
+
+	categoryRemove = "0" // (target chat id for removing the route)
+
+	categoryMaxBytes = 30 // (callback data can be 64 bytes at most)
+)
+
+var categoryRegex = regexp.MustCompile(`#([\p{L}\p{N}_]+)`)
+
+// messages (can be overridden with messages.json)
+var (
+	messageCategoryUsage       = "'/settings 분류 #업무'처럼 분류(해시태그)를 입력하면, 그 분류의 알림을 보낼 대화를 고를 수 있습니다."
+	messageCategoryNoRoutes    = "(분류별로 다른 대화에 보내는 알림이 없습니다)"
+	messageCategoryRouteFormat = "#%s → %s"
+	messageCategoryWhereFormat = "#%s 알림을 어느 대화로 보낼까요?"
+	messageCategoryThisChat    = "이 대화 (기본값)"
+	messageCategorySavedFormat = "이제 #%s 알림은 '%s'(으)로 보내집니다."
+	messageCategoryRemoved     = "이제 #%s 알림은 이 대화로 보내집니다."
+	messageCategoryTooLong     = "분류 이름이 너무 깁니다."
+	messageCategoryNotShared   = "그 대화에는 알림을 보낼 수 없습니다. (봇과 함께 있는 대화만 고를 수 있습니다)"
+)
+
+// category (hashtag without '#') from given parameter
+func parseCategory(param string) (category string, ok bool) {
+	if matches := categoryRegex.FindStringSubmatch(strings.TrimSpace(param)); len(matches) > 1 {
+		return strings.ToLower(matches[1]), true
+	}
+	return "", false
+}
+
+// categories (hashtags) in given reminder text
+func categoriesInMessage(message string) []string {
+	categories := []string{}
+	for _, matches := range categoryRegex.FindAllStringSubmatch(message, -1) {
+		categories = append(categories, strings.ToLower(matches[1]))
+	}
+	return categories
+}
+
+// displayable name of the chat with given id
+func chatNameOf(chatID int64) string {
+	for _, chat := range db.ActiveChats() {
+		if chat.ChatID == chatID {
+			return chatName(chat)
+		}
+	}
+	return strconv.FormatInt(chatID, 10)
+}
+
+// process '/settings 분류 [#category]': list the routes, or show chats for routing given category
+func processCategorySetting(client *bot.Bot, chatID, userID int64, param string, options map[string]interface{}) string {
+	if strings.TrimSpace(param) == "" {
+		lines := []string{messageCategoryUsage}
+		routes := db.CategoryRoutes(chatID)
+		if len(routes) <= 0 {
+			lines = append(lines, messageCategoryNoRoutes)
+		}
+		for _, r := range routes {
+			lines = append(lines, fmt.Sprintf(messageCategoryRouteFormat, r.Category, chatNameOf(r.TargetChatID)))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	category, ok := parseCategory(param)
+	if !ok {
+		return messageCategoryUsage
+	}
+	if len(category) > categoryMaxBytes {
+		return messageCategoryTooLong
+	}
+
+	remove := fmt.Sprintf("%s %s %s", callbackCategory, category, categoryRemove)
+	buttons := [][]bot.InlineKeyboardButton{
+		[]bot.InlineKeyboardButton{
+			bot.InlineKeyboardButton{Text: messageCategoryThisChat, CallbackData: &remove},
+		},
+	}
+	for _, chat := range sharedChats(client, userID, chatID) {
+		data := fmt.Sprintf("%s %s %d", callbackCategory, category, chat.ChatID)
+		buttons = append(buttons, []bot.InlineKeyboardButton{
+			bot.InlineKeyboardButton{Text: chatName(chat), CallbackData: &data},
+		})
+	}
+	options["reply_markup"] = bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+
+	return fmt.Sprintf(messageCategoryWhereFormat, category)
+}
+
+// process callback query for routing a category: '/category <category> <target chat id>'
+func processCategoryCallback(client *bot.Bot, query bot.CallbackQuery, txt string) string {
+	chatID := query.Message.Chat.ID
+	userID := int64(query.From.ID)
+
+	params := strings.Fields(strings.TrimPrefix(txt, callbackCategory))
+	if len(params) != 2 {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+	category := params[0]
+
+	if params[1] == categoryRemove {
+		if !db.DeleteCategoryRoute(chatID, category) {
+			return messageError
+		}
+		return fmt.Sprintf(messageCategoryRemoved, category)
+	}
+
+	targetChatID, err := strconv.ParseInt(params[1], 10, 64)
+	if err != nil {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+
+	// (the user should still share the chat with the bot)
+	for _, chat := range sharedChats(client, userID, chatID) {
+		if chat.ChatID == targetChatID {
+			if !db.SaveCategoryRoute(chatID, category, targetChatID) {
+				return messageError
+			}
+			return fmt.Sprintf(messageCategorySavedFormat, category, chatName(chat))
+		}
+	}
+
+	return messageCategoryNotShared
+}
+
+// chat where given queue item should be delivered (consulting the routes of its categories)
+//
+// (only plain reminders without inline keyboards are routed, as their callbacks work in their own chats)
+func deliveryChatID(q dbhelper.QueueItem, options map[string]interface{}) int64 {
+	if q.Kind != dbhelper.QueueKindReminder || q.Broadcast {
+		return q.ChatID
+	}
+	if _, hasKeyboard := options["reply_markup"]; hasKeyboard {
+		return q.ChatID
+	}
+
+	categories := categoriesInMessage(q.Message)
+	if len(categories) <= 0 {
+		return q.ChatID
+	}
+
+	routes := map[string]int64{}
+	for _, r := range db.CategoryRoutes(q.ChatID) {
+		routes[r.Category] = r.TargetChatID
+	}
+	for _, category := range categories {
+		if target, exists := routes[category]; exists {
+			return target
+		}
+	}
+
+	return q.ChatID
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageChainSavedFormat     = "'%s' 알림을 완료하면, %s 뒤에 '%s' 알려드리겠습니다."
+	messageChainNoParent        = "먼저 이어서 알려드릴 알림을 등록해 주세요."
+	messageChainTriggeredFormat = "⛓ %s 뒤에 '%s' 알려드리겠습니다."
+)
+
+// eg. "끝나면 30분 뒤에 정리하라고 알려줘", "그거 끝나고 1시간 후에 보고서 제출"
+var chainTextRegex = regexp.MustCompile(`^(?:그거|그게|이거|이게)?\s*(?:끝나면|끝나고|완료하면|다 하면)\s*(\d+)\s*(분|시간)\s*(?:뒤|후)에?\s*(.+)$`)
+
+// trailing words to be removed from chained messages
+var chainMessageSuffixes = []string{"알려 줘", "알려줘", "말해줘", "하라고", "라고"}
+
+// check if given text is a chaining request
+func isChainText(txt string) bool {
+	return chainTextRegex.MatchString(strings.TrimSpace(txt))
+}
+
+// process chaining request: the reminder will be scheduled after the user's latest reminder is acknowledged
+func processChainText(chatID, userID int64, txt string) string {
+	matches := chainTextRegex.FindStringSubmatch(strings.TrimSpace(txt))
+	if matches == nil {
+		return messageError
+	}
+
+	delay, _ := strconv.Atoi(matches[1])
+	if matches[2] == "시간" {
+		delay *= 60
+	}
+
+	message := strings.TrimSpace(matches[3])
+	for _, suffix := range chainMessageSuffixes {
+		message = strings.TrimSpace(strings.TrimSuffix(message, suffix))
+	}
+
+	// parent: the latest pending reminder of the user
+	var parent *dbhelper.QueueItem
+	for _, q := range db.UndeliveredQueueItems(chatID) {
+		if q.UserID == userID && q.Kind == dbhelper.QueueKindReminder {
+			parent = &q
+			break
+		}
+	}
+	if parent == nil {
+		return messageChainNoParent
+	}
+
+	if _, saved := db.SaveChain(dbhelper.Chain{
+		ParentID:     parent.ID,
+		ChatID:       chatID,
+		UserID:       userID,
+		Message:      message,
+		DelayMinutes: delay,
+	}); !saved {
+		return messageSaveFailed
+	}
+
+	return fmt.Sprintf(messageChainSavedFormat, parent.Message, formatTimerDuration(time.Duration(delay)*time.Minute), message)
+}
+
+// check if given reminder has chained reminders
+func hasPendingChains(q dbhelper.QueueItem) bool {
+	return len(db.PendingChains(q.ChatID, q.ID)) > 0
+}
+
+// schedule chained reminders of given acknowledged reminder, returning a message for them
+func triggerChains(chatID, parentID int64) string {
+	lines := []string{}
+	for _, c := range db.PendingChains(chatID, parentID) {
+		if !db.MarkChainTriggered(c.ID) {
+			continue
+		}
+
+		delay := time.Duration(c.DelayMinutes) * time.Minute
+		item := dbhelper.QueueItem{
+			ChatID:  c.ChatID,
+			UserID:  c.UserID,
+			Message: c.Message,
+			FireOn:  time.Now().Add(delay),
+		}
+		queueID, enqueued := db.EnqueueItem(item)
+		if !enqueued {
+			log.Printf("*** failed to enqueue chained reminder %d", c.ID)
+			continue
+		}
+		item.ID = queueID
+		fireWebhooks(webhookEventCreated, item, "")
+
+		lines = append(lines, fmt.Sprintf(messageChainTriggeredFormat, formatTimerDuration(delay), c.Message))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// cloning reminders: re-create one with the same text, only asking for a new time
+// (without another round trip to api.ai)
+
+const (
+	callbackClone = "/clone"
+
+	clonePendingMinutes = 10 // typed time for cloning will be waited for this long
+
+	cloneOffsetDay   = "1d"
+	cloneOffsetWeek  = "1w"
+	cloneOffsetMonth = "1m"
+)
+
+// formats of typed times for cloning
+var cloneTimeFormats = []string{
+	"2006.1.2 15:04",
+	"1.2 15:04",
+	"15:04",
+}
+
+// messages (can be overridden with messages.json)
+var (
+	messageClone           = "복제"
+	messageCloneWhat       = "어떤 알림을 복제할까요?"
+	messageCloneWhenFormat = "'%s'(을)를 언제 다시 알려드릴까요?\n(아래에서 고르거나, '%s'처럼 시각을 입력해 주세요)"
+	messageCloneNextDay    = "다음 날 같은 시각"
+	messageCloneNextWeek   = "다음 주 같은 시각"
+	messageCloneNextMonth  = "다음 달 같은 날"
+	messageCloneGone       = "이미 없어진 알림입니다. 다시 /list 해 주세요."
+	messageCloneTimeIsPast = "이미 지난 시각입니다. 다시 입력해 주세요."
+)
+
+// reminder waiting for a typed time to be cloned
+type pendingClone struct {
+	queueID   int64
+	expiresOn time.Time
+}
+
+// (key: chat id/user id)
+var _pendingClones = struct {
+	sync.Mutex
+	clones map[string]pendingClone
+}{
+	clones: map[string]pendingClone{},
+}
+
+// add a button for cloning reminders to the inline keyboard of /list
+func addCloneButton(options map[string]interface{}) {
+	data := callbackClone
+	row := []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{
+			Text:         messageClone,
+			CallbackData: &data,
+		},
+	}
+
+	if markup, ok := options["reply_markup"].(bot.InlineKeyboardMarkup); ok {
+		markup.InlineKeyboard = append(markup.InlineKeyboard, row)
+		options["reply_markup"] = markup
+	} else {
+		options["reply_markup"] = bot.InlineKeyboardMarkup{InlineKeyboard: [][]bot.InlineKeyboardButton{row}}
+	}
+}
+
+// process callback query for cloning reminders
+//
+// "/clone" => select one, "/clone <id>" => select a new time, "/clone <id> <offset>" => clone it
+func processCloneCallback(query bot.CallbackQuery, txt string) (message string, keyboard interface{}) {
+	chatID := query.Message.Chat.ID
+	userID := int64(query.From.ID)
+
+	params := strings.Fields(strings.TrimPrefix(txt, callbackClone))
+	if len(params) <= 0 {
+		reminders := db.UndeliveredQueueItems(chatID)
+		if len(reminders) <= 0 {
+			return messageNoReminders, nil
+		}
+		return messageCloneWhat, reminderSelectionKeyboard(reminders, callbackClone)
+	}
+
+	queueID, err := strconv.ParseInt(params[0], 10, 64)
+	if err != nil {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError, nil
+	}
+	q, exists := db.QueueItem(chatID, queueID)
+	if !exists {
+		return messageCloneGone, nil
+	}
+
+	if len(params) < 2 {
+		setPendingClone(chatID, userID, queueID)
+
+		example := time.Now().Add(24 * time.Hour).Format(cloneTimeFormats[0])
+		return fmt.Sprintf(messageCloneWhenFormat, q.Message, example), cloneTimeKeyboard(queueID)
+	}
+
+	var when time.Time
+	switch params[1] {
+	case cloneOffsetDay:
+		when = q.FireOn.AddDate(0, 0, 1)
+	case cloneOffsetWeek:
+		when = q.FireOn.AddDate(0, 0, 7)
+	case cloneOffsetMonth:
+		when = q.FireOn.AddDate(0, 1, 0)
+	default:
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError, nil
+	}
+	takePendingClone(chatID, userID)
+
+	return cloneQueueItem(q, userID, when), nil
+}
+
+// inline keyboard for selecting the time of the clone
+func cloneTimeKeyboard(queueID int64) bot.InlineKeyboardMarkup {
+	buttons := [][]bot.InlineKeyboardButton{}
+	for _, offset := range []struct {
+		title  string
+		offset string
+	}{
+		{messageCloneNextDay, cloneOffsetDay},
+		{messageCloneNextWeek, cloneOffsetWeek},
+		{messageCloneNextMonth, cloneOffsetMonth},
+	} {
+		data := fmt.Sprintf("%s %d %s", callbackClone, queueID, offset.offset)
+		buttons = append(buttons, []bot.InlineKeyboardButton{
+			bot.InlineKeyboardButton{
+				Text:         offset.title,
+				CallbackData: &data,
+			},
+		})
+	}
+
+	return bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// keep given reminder until a time for cloning it is typed by given user
+func setPendingClone(chatID, userID, queueID int64) {
+	_pendingClones.Lock()
+	_pendingClones.clones[pendingKey(chatID, userID)] = pendingClone{
+		queueID:   queueID,
+		expiresOn: time.Now().Add(clonePendingMinutes * time.Minute),
+	}
+	_pendingClones.Unlock()
+}
+
+// take the reminder waiting to be cloned by given user in given chat
+func takePendingClone(chatID, userID int64) (queueID int64, exists bool) {
+	_pendingClones.Lock()
+	defer _pendingClones.Unlock()
+
+	key := pendingKey(chatID, userID)
+	pending, exists := _pendingClones.clones[key]
+	if !exists {
+		return 0, false
+	}
+	delete(_pendingClones.clones, key)
+
+	if time.Now().After(pending.expiresOn) {
+		return 0, false
+	}
+
+	return pending.queueID, true
+}
+
+// check if given text is a time for the reminder waiting to be cloned by given user in given chat
+func isCloneTimeText(chatID, userID int64, txt string) bool {
+	_pendingClones.Lock()
+	pending, exists := _pendingClones.clones[pendingKey(chatID, userID)]
+	_pendingClones.Unlock()
+
+	if !exists || time.Now().After(pending.expiresOn) {
+		return false
+	}
+
+	_, ok := parseCloneTime(txt)
+	return ok
+}
+
+// parse typed time for cloning (missing year or date is filled with the current/next one)
+func parseCloneTime(txt string) (when time.Time, ok bool) {
+	txt = strings.TrimSpace(txt)
+	now := time.Now()
+
+	for _, format := range cloneTimeFormats {
+		t, err := time.ParseInLocation(format, txt, time.Local)
+		if err != nil {
+			continue
+		}
+
+		switch format {
+		case "1.2 15:04":
+			when = time.Date(now.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.Local)
+			if when.Before(now) {
+				when = when.AddDate(1, 0, 0)
+			}
+		case "15:04":
+			when = nextTimeOfDay(t.Hour(), t.Minute())
+		default:
+			when = t
+		}
+
+		return when, true
+	}
+
+	return when, false
+}
+
+// process typed time for cloning the pending reminder of given user in given chat
+func processCloneTimeText(chatID, userID int64, txt string) string {
+	queueID, exists := takePendingClone(chatID, userID)
+	if !exists {
+		return messageCloneGone
+	}
+	q, exists := db.QueueItem(chatID, queueID)
+	if !exists {
+		return messageCloneGone
+	}
+
+	when, _ := parseCloneTime(txt)
+
+	return cloneQueueItem(q, userID, when)
+}
+
+// create a new reminder with the text of given one, at given time
+func cloneQueueItem(q dbhelper.QueueItem, userID int64, when time.Time) string {
+	if when.Before(time.Now()) {
+		return messageCloneTimeIsPast
+	}
+
+	item := dbhelper.QueueItem{
+		ChatID:   q.ChatID,
+		UserID:   userID,
+		Message:  q.Message,
+		FireOn:   when,
+		Assignee: q.Assignee,
+		Private:  q.Private && userID == q.UserID, // (only the creator's own copy)
+
+		Attachment: q.Attachment,
+	}
+	queueID, enqueued := db.EnqueueItem(item)
+	if !enqueued {
+		return messageSaveFailed
+	}
+	item.ID = queueID
+	fireWebhooks(webhookEventCreated, item, "")
+
+	return confirmationMessage(item.ChatID, item.Message, when, "")
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// collision avoidance: reminders of a chat with the same fire time (in minutes) are merged into one message,
+// or staggered with some spacing, not to pile notifications on top of each other
+
+const (
+	collisionPolicyMerge   = "merge"
+	collisionPolicyStagger = "stagger"
+
+	collisionDefaultSpacingSeconds = 30
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageMergedReminderFormat = "• %s"
+)
+
+// items merged into other ones (key: id of the item which is delivered in place of them)
+var _mergedItems = struct {
+	sync.Mutex
+	items map[int64][]dbhelper.QueueItem
+}{
+	items: map[int64][]dbhelper.QueueItem{},
+}
+
+// check if given item can be merged with others, or staggered
+//
+// (polls, acknowledgments, and special kinds have their own messages and keyboards)
+func isCollidable(q dbhelper.QueueItem) bool {
+	return q.Kind == dbhelper.QueueKindReminder && !q.Broadcast && !needsAck(q)
+}
+
+// merge or stagger given items colliding with each other, with the configured policy
+//
+// returns the items to be delivered now (merged ones are replaced with one item)
+func avoidCollisions(items []dbhelper.QueueItem) []dbhelper.QueueItem {
+	policy := _conf.CollisionPolicy
+	if policy != collisionPolicyMerge && policy != collisionPolicyStagger {
+		return items
+	}
+
+	// group by chat and minute
+	groups := map[string][]dbhelper.QueueItem{}
+	keys := []string{}
+	result := []dbhelper.QueueItem{}
+	for _, q := range items {
+		if !isCollidable(q) {
+			result = append(result, q)
+			continue
+		}
+
+		key := fmt.Sprintf("%d/%d", q.ChatID, q.FireOn.Unix()/60)
+		if _, exists := groups[key]; !exists {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], q)
+	}
+
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) == 1 {
+			result = append(result, group[0])
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool { return group[i].ID < group[j].ID })
+
+		switch policy {
+		case collisionPolicyMerge:
+			result = append(result, mergeQueueItems(group))
+		case collisionPolicyStagger:
+			result = append(result, group[0])
+			staggerQueueItems(group[1:], group[0].FireOn)
+		}
+	}
+
+	return result
+}
+
+// merge given items into the first one (the rest will be delivered in its message, and marked as delivered with it)
+func mergeQueueItems(group []dbhelper.QueueItem) dbhelper.QueueItem {
+	_mergedItems.Lock()
+	_mergedItems.items[group[0].ID] = group[1:]
+	_mergedItems.Unlock()
+
+	return group[0]
+}
+
+// message of given item including the texts of items merged into it
+func mergedMessage(q dbhelper.QueueItem, message string) string {
+	_mergedItems.Lock()
+	merged := _mergedItems.items[q.ID]
+	_mergedItems.Unlock()
+
+	if len(merged) <= 0 {
+		return message
+	}
+
+	lines := []string{fmt.Sprintf(messageMergedReminderFormat, message)}
+	for _, m := range merged {
+		lines = append(lines, fmt.Sprintf(messageMergedReminderFormat, reminderText(m.ChatID, sanitizedDeliveryText(m.ChatID, m.Message))))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// postpone given items with the configured spacing from given time
+func staggerQueueItems(items []dbhelper.QueueItem, from time.Time) {
+	spacing := time.Duration(_conf.CollisionSpacingSeconds) * time.Second
+	if spacing <= 0 {
+		spacing = collisionDefaultSpacingSeconds * time.Second
+	}
+
+	for i, q := range items {
+		if !db.RescheduleQueueItem(q.ChatID, q.ID, from.Add(time.Duration(i+1)*spacing)) {
+			log.Printf("*** failed to stagger chat id: %d, queue id: %d", q.ChatID, q.ID)
+		}
+	}
+}
+
+// mark items merged into given (delivered) item as delivered
+func markMergedQueueItems(client *bot.Bot, q dbhelper.QueueItem) {
+	_mergedItems.Lock()
+	merged := _mergedItems.items[q.ID]
+	delete(_mergedItems.items, q.ID)
+	_mergedItems.Unlock()
+
+	for _, m := range merged {
+		if !db.MarkQueueItemAsDelivered(m.ChatID, m.ID) {
+			log.Printf("*** failed to mark merged chat id: %d, queue id: %d", m.ChatID, m.ID)
+			continue
+		}
+		recordDeliveryLatency(client, m)
+		fireWebhooks(webhookEventDelivered, m, "")
+
+		if m.Recurrence != "" {
+			scheduleNextOccurrence(m)
+		}
+	}
+}
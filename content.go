@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// content policy: operators can block reminders matching their rules,
+// or flag them (they are still saved) for admins to review with /review
+
+const (
+	commandReview = "/review"
+
+	reviewParamApprove = "ok"
+	reviewParamRemove  = "rm"
+
+	reviewMaxItems = 10
+
+	contentActionBlock = "block"
+	contentActionFlag  = "flag"
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageContentRejected       = "죄송하지만, 운영 정책상 이 내용의 알림은 등록할 수 없습니다."
+	messageReviewEmpty           = "[관리자] 검토할 알림이 없습니다."
+	messageReviewMoreFormat      = "(검토할 알림이 %d건 더 있음)"
+	messageReviewApproved        = "[관리자] 알림을 그대로 두었습니다."
+	messageReviewRemoved         = "[관리자] 알림을 삭제했습니다."
+	messageReviewAlreadyReviewed = "[관리자] 이미 검토한 알림입니다."
+	messageReviewApproveFormat   = "✓ #%d"
+	messageReviewRemoveFormat    = "✕ #%d"
+)
+
+// content rule of the config
+type contentRule struct {
+	Name    string `json:"name,omitempty"`    // name of the rule (for admins)
+	Keyword string `json:"keyword,omitempty"` // case-insensitive keyword
+	Regex   string `json:"regex,omitempty"`   // regular expression (one of keyword or regex)
+	Action  string `json:"action"`            // "block" or "flag"
+}
+
+// validate content rule
+func (r contentRule) validate() (problems []string) {
+	if (r.Keyword == "") == (r.Regex == "") {
+		problems = append(problems, fmt.Sprintf("content rule '%s' should have one of keyword or regex", r.displayName()))
+	}
+	if r.Regex != "" {
+		if _, err := regexp.Compile(r.Regex); err != nil {
+			problems = append(problems, fmt.Sprintf("malformed regex of content rule '%s': %s", r.displayName(), err))
+		}
+	}
+	if r.Action != contentActionBlock && r.Action != contentActionFlag {
+		problems = append(problems, fmt.Sprintf("action of content rule '%s' should be '%s' or '%s': '%s'", r.displayName(), contentActionBlock, contentActionFlag, r.Action))
+	}
+
+	return problems
+}
+
+// name of the rule for displaying
+func (r contentRule) displayName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	if r.Keyword != "" {
+		return r.Keyword
+	}
+	return r.Regex
+}
+
+var _contentRegexes = struct {
+	sync.Mutex
+	compiled map[string]*regexp.Regexp
+}{
+	compiled: map[string]*regexp.Regexp{},
+}
+
+// check if given text matches the rule
+func (r contentRule) matches(text string) bool {
+	if r.Keyword != "" {
+		return strings.Contains(strings.ToLower(text), strings.ToLower(r.Keyword))
+	}
+
+	_contentRegexes.Lock()
+	re, exists := _contentRegexes.compiled[r.Regex]
+	if !exists {
+		var err error
+		if re, err = regexp.Compile(r.Regex); err != nil {
+			log.Printf("*** Failed to compile regex of content rule '%s': %s", r.displayName(), err)
+		}
+		_contentRegexes.compiled[r.Regex] = re
+	}
+	_contentRegexes.Unlock()
+
+	return re != nil && re.MatchString(text)
+}
+
+// first content rule with given action which matches given text
+func matchingContentRule(text, action string) (rule contentRule, matched bool) {
+	for _, r := range _conf.ContentRules {
+		if r.Action == action && r.matches(text) {
+			return r, true
+		}
+	}
+	return rule, false
+}
+
+// flag given (saved) queue item for review if it matches any flagging rule
+func flagQueueItemIfNeeded(q dbhelper.QueueItem) {
+	if rule, matched := matchingContentRule(q.Message, contentActionFlag); matched {
+		if _, saved := db.SaveFlag(q, rule.displayName()); saved {
+			db.Log(fmt.Sprintf("queue item %d of chat %d was flagged by content rule '%s'", q.ID, q.ChatID, rule.displayName()))
+		}
+	}
+}
+
+// process /review command: show flagged reminders for admins
+func processReviewCommand(userID int64, options map[string]interface{}) string {
+	if !isAdmin(userID) {
+		return messageAnnounceAdminOnly
+	}
+
+	flags := db.UnreviewedFlags(reviewMaxItems)
+	if len(flags) <= 0 {
+		return messageReviewEmpty
+	}
+
+	lines := []string{}
+	buttons := [][]bot.InlineKeyboardButton{}
+	for _, f := range flags {
+		lines = append(lines, fmt.Sprintf("#%d chat:%d user:%d rule:%s (%s)", f.ID, f.ChatID, f.UserID, f.Rule, f.FlaggedOn.Format(reminderTimeFormat)))
+		lines = append(lines, "  "+f.Message)
+
+		approve := fmt.Sprintf("%s %s %d", commandReview, reviewParamApprove, f.ID)
+		remove := fmt.Sprintf("%s %s %d", commandReview, reviewParamRemove, f.ID)
+		buttons = append(buttons, []bot.InlineKeyboardButton{
+			bot.InlineKeyboardButton{Text: fmt.Sprintf(messageReviewApproveFormat, f.ID), CallbackData: &approve},
+			bot.InlineKeyboardButton{Text: fmt.Sprintf(messageReviewRemoveFormat, f.ID), CallbackData: &remove},
+		})
+	}
+	if remaining := db.NumUnreviewedFlags() - len(flags); remaining > 0 {
+		lines = append(lines, fmt.Sprintf(messageReviewMoreFormat, remaining))
+	}
+
+	options["reply_markup"] = bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+
+	return strings.Join(lines, "\n")
+}
+
+// process callback query of /review actions
+func processReviewCallback(query bot.CallbackQuery, txt string) string {
+	if !isAdmin(int64(query.From.ID)) {
+		return messageAnnounceAdminOnly
+	}
+
+	params := strings.Fields(strings.TrimPrefix(txt, commandReview))
+	if len(params) != 2 {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+	flagID, err := strconv.ParseInt(params[1], 10, 64)
+	if err != nil || (params[0] != reviewParamApprove && params[0] != reviewParamRemove) {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+
+	flag, reviewed := db.ReviewFlag(flagID)
+	if !reviewed {
+		return messageReviewAlreadyReviewed
+	}
+
+	if params[0] == reviewParamApprove {
+		db.Log(fmt.Sprintf("admin %d approved flagged queue item %d", query.From.ID, flag.QueueID))
+
+		return messageReviewApproved
+	}
+
+	q, exists := db.QueueItem(flag.ChatID, flag.QueueID)
+	if exists && db.DeleteQueueItem(flag.ChatID, flag.QueueID) {
+		fireWebhooks(webhookEventCanceled, q, "")
+	}
+	db.Log(fmt.Sprintf("admin %d removed flagged queue item %d", query.From.ID, flag.QueueID))
+
+	return messageReviewRemoved
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// cron schedules: reminders with cron expressions (without api.ai), saved as "cron:<spec>" recurrences
+// and rescheduled to the next matching time after each delivery
+//
+//	/remind cron "0 9 * * 1-5" 출근 준비 : every weekday at 9:00
+//
+// (5 fields: minute hour day-of-month month day-of-week, in the time zone of the bot;
+// like other crons, a day matches either of day-of-month and day-of-week when both are restricted)
+
+const (
+	commandRemind = "/remind"
+
+	cronMaxYears = 5 // specs which don't match in this period (eg. "0 0 31 2 *") are rejected
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageCronUsage           = "사용법: /remind cron \"0 9 * * 1-5\" 출근 준비\n(분 시 일 월 요일, 요일은 0-6 또는 sun-sat, 일요일은 7도 가능)"
+	messageCronInvalidFormat   = "cron 표현식이 올바르지 않습니다: %s"
+	messageCronScheduledFormat = "cron '%s'에 맞춰 \"%s\" 알림을 보내드리겠습니다. (다음: %s)"
+)
+
+var cronCommandRegex = regexp.MustCompile(`(?s)^cron\s+["“”']([^"“”']+)["“”']\s+(.+)$`)
+
+// a field of cron specs (range of values and their names)
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+// fields of cron specs: minute, hour, day of month, month, and day of week (0 and 7 are sunday)
+var cronFields = []cronField{
+	{min: 0, max: 59},
+	{min: 0, max: 23},
+	{min: 1, max: 31},
+	{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// parsed cron spec (bits of matching values of each field)
+type cronSpec struct {
+	minutes, hours, days, months, weekdays uint64
+
+	daysRestricted, weekdaysRestricted bool
+}
+
+// parse given cron spec (eg. "0 9 * * 1-5", "*/15 9-18 * * mon-fri")
+func parseCronSpec(spec string) (c cronSpec, err error) {
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return c, fmt.Errorf("cron spec should have %d fields: '%s'", len(cronFields), spec)
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, field := range fields {
+		if bits[i], err = parseCronField(field, cronFields[i]); err != nil {
+			return c, err
+		}
+	}
+
+	c = cronSpec{
+		minutes:            bits[0],
+		hours:              bits[1],
+		days:               bits[2],
+		months:             bits[3],
+		weekdays:           bits[4],
+		daysRestricted:     fields[2] != "*",
+		weekdaysRestricted: fields[4] != "*",
+	}
+	if c.weekdays&(1<<7) != 0 { // (7 => 0)
+		c.weekdays |= 1
+	}
+
+	return c, nil
+}
+
+// parse a field of cron specs into bits of matching values (eg. "1-5", "*/15", "0,30", "mon-fri")
+func parseCronField(field string, f cronField) (bits uint64, err error) {
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step: '%s'", part)
+			}
+			part = part[:i]
+		}
+
+		from, to := f.min, f.max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			if from, err = cronValue(bounds[0], f); err != nil {
+				return 0, err
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = cronValue(bounds[1], f); err != nil {
+					return 0, err
+				}
+			} else if step > 1 { // (eg. "5/15" => from 5 to the max)
+				to = f.max
+			}
+			if from > to {
+				return 0, fmt.Errorf("invalid range: '%s'", part)
+			}
+		}
+
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+// value of a field (number or name)
+func cronValue(s string, f cronField) (int, error) {
+	if v, exists := f.names[strings.ToLower(s)]; exists {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value: '%s'", s)
+	}
+	return v, nil
+}
+
+// check if given day matches
+func (c cronSpec) matchesDay(t time.Time) bool {
+	day := c.days&(1<<uint(t.Day())) != 0
+	weekday := c.weekdays&(1<<uint(t.Weekday())) != 0
+
+	if c.daysRestricted && c.weekdaysRestricted {
+		return day || weekday
+	}
+	return day && weekday
+}
+
+// the first matching time after given time (in the time zone of the bot)
+func (c cronSpec) next(after time.Time) (time.Time, error) {
+	t := after.In(_location).Truncate(time.Minute).Add(time.Minute)
+	until := t.AddDate(cronMaxYears, 0, 0)
+
+	for t.Before(until) {
+		if c.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t, nil
+	}
+
+	return after, fmt.Errorf("cron spec does not match in %d years", cronMaxYears)
+}
+
+// recurrence rule of given cron spec
+func cronRecurrence(spec string) string {
+	return fmt.Sprintf("%s:%s", recurrenceCron, strings.Join(strings.Fields(spec), " "))
+}
+
+// process /remind command: schedule a reminder with a cron spec
+//
+//	"/remind cron \"<spec>\" <message>"
+func processRemindCommand(chatID, userID int64, txt string) string {
+	matches := cronCommandRegex.FindStringSubmatch(strings.TrimSpace(strings.TrimPrefix(txt, commandRemind)))
+	if matches == nil {
+		return messageCronUsage
+	}
+
+	c, err := parseCronSpec(matches[1])
+	if err != nil {
+		return fmt.Sprintf(messageCronInvalidFormat, err)
+	}
+	when, err := c.next(time.Now())
+	if err != nil {
+		return fmt.Sprintf(messageCronInvalidFormat, err)
+	}
+
+	msg, err := sanitizeReminderText(chatID, matches[2])
+	if err != nil {
+		return err.Error()
+	}
+
+	recurrence := cronRecurrence(matches[1])
+	if _, enqueued := db.EnqueueItem(dbhelper.QueueItem{
+		ChatID:     chatID,
+		UserID:     userID,
+		Message:    msg,
+		FireOn:     when,
+		Recurrence: recurrence,
+	}); !enqueued {
+		return messageSaveFailed
+	}
+
+	_, spec := splitRecurrence(recurrence)
+
+	return fmt.Sprintf(messageCronScheduledFormat, spec, msg, formatTimeFor(chatID, when, reminderTimeFormat))
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronSpec(t *testing.T) {
+	for _, test := range []struct {
+		spec  string
+		valid bool
+	}{
+		{"0 9 * * 1-5", true},
+		{"*/15 9-18 * * mon-fri", true},
+		{"0,30 * 1,15 jan-jun sun", true},
+		{"0 0 * * 7", true},
+		{"0 0 31 2 *", true}, // (valid, but never matches)
+		{"0 9 * *", false},
+		{"0 9 * * * *", false},
+		{"60 * * * *", false},
+		{"* 24 * * *", false},
+		{"* * 0 * *", false},
+		{"* * * 13 *", false},
+		{"* * * * 8", false},
+		{"*/0 * * * *", false},
+		{"5-1 * * * *", false},
+		{"* * * * someday", false},
+	} {
+		if _, err := parseCronSpec(test.spec); (err == nil) != test.valid {
+			t.Errorf("parsing '%s': expected valid = %t, got error: %v", test.spec, test.valid, err)
+		}
+	}
+}
+
+func TestCronSpecNext(t *testing.T) {
+	_location = time.FixedZone("KST", 9*60*60)
+
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, _location)
+	}
+
+	for _, test := range []struct {
+		spec     string
+		after    time.Time
+		expected time.Time
+	}{
+		// every weekday (2023.1.7 is a saturday)
+		{"0 9 * * 1-5", at(2023, 1, 7, 10, 0), at(2023, 1, 9, 9, 0)},
+		{"0 9 * * mon-fri", at(2023, 1, 9, 8, 59), at(2023, 1, 9, 9, 0)},
+		{"0 9 * * 1-5", at(2023, 1, 9, 9, 0), at(2023, 1, 10, 9, 0)},
+
+		// day of month or day of week, when both are restricted (2023.1.1 is a sunday)
+		{"0 0 1 * 1", at(2023, 1, 1, 0, 0), at(2023, 1, 2, 0, 0)},
+		{"0 0 1 * 1", at(2023, 1, 2, 0, 0), at(2023, 1, 9, 0, 0)},
+		{"0 0 1 * 1", at(2023, 1, 30, 0, 0), at(2023, 2, 1, 0, 0)},
+		{"0 9 13 * fri", at(2023, 1, 7, 0, 0), at(2023, 1, 13, 9, 0)},
+		{"0 9 13 * fri", at(2023, 1, 13, 9, 0), at(2023, 1, 20, 9, 0)},
+
+		// day of month only
+		{"0 0 1 * *", at(2023, 1, 2, 0, 0), at(2023, 2, 1, 0, 0)},
+
+		// 7 is sunday too
+		{"30 8 * * 7", at(2023, 1, 2, 0, 0), at(2023, 1, 8, 8, 30)},
+		{"30 8 * * 0", at(2023, 1, 2, 0, 0), at(2023, 1, 8, 8, 30)},
+		{"30 8 * * sun", at(2023, 1, 2, 0, 0), at(2023, 1, 8, 8, 30)},
+
+		// steps
+		{"*/15 * * * *", at(2023, 1, 1, 10, 7), at(2023, 1, 1, 10, 15)},
+		{"*/15 * * * *", at(2023, 1, 1, 10, 45), at(2023, 1, 1, 11, 0)},
+		{"*/15 * * * *", at(2023, 12, 31, 23, 50), at(2024, 1, 1, 0, 0)},
+		{"5/20 * * * *", at(2023, 1, 1, 10, 26), at(2023, 1, 1, 10, 45)},
+
+		// leap day
+		{"0 12 29 2 *", at(2023, 1, 1, 0, 0), at(2024, 2, 29, 12, 0)},
+	} {
+		c, err := parseCronSpec(test.spec)
+		if err != nil {
+			t.Errorf("failed to parse '%s': %s", test.spec, err)
+			continue
+		}
+
+		if next, err := c.next(test.after); err != nil {
+			t.Errorf("next of '%s' after %s: %s", test.spec, test.after, err)
+		} else if !next.Equal(test.expected) {
+			t.Errorf("next of '%s' after %s: expected %s, got %s", test.spec, test.after, test.expected, next)
+		}
+	}
+}
+
+func TestCronSpecNeverMatches(t *testing.T) {
+	_location = time.FixedZone("KST", 9*60*60)
+
+	for _, spec := range []string{"0 0 31 2 *", "0 0 30 2 *", "0 0 31 4,6,9,11 *"} {
+		c, err := parseCronSpec(spec)
+		if err != nil {
+			t.Errorf("failed to parse '%s': %s", spec, err)
+			continue
+		}
+
+		if next, err := c.next(time.Now()); err == nil {
+			t.Errorf("'%s' should not match, but got %s", spec, next)
+		}
+	}
+}
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	dashboardPath        = "/dashboard/"
+	dashboardActionsPath = "/dashboard/actions/"
+	dashboardMaxItems    = 200
+	dashboardNumLogs     = 500
+	dashboardTimeFormat  = "2006-01-02 15:04:05"
+
+	dashboardInputTimeFormat = "2006-01-02T15:04" // (of datetime-local inputs)
+)
+
+// data for rendering dashboard pages
+type dashboardPage struct {
+	Title  string
+	Path   string
+	Token  string
+	ChatID string
+	Search string
+
+	History bool // (queue items are delivered ones)
+	Queue   []dbhelper.QueueItem
+	Logs    []dbhelper.Log
+	Stats   []dbhelper.ChatStat
+	Audits  []dbhelper.AuditLog
+
+	Settings *dbhelper.ChatSettings // (of the chat filtered on the chats page)
+
+	MaxNumTries int
+}
+
+// link to given dashboard page, keeping the token and filters
+func (p dashboardPage) Link(path string, chatID int64) string {
+	params := url.Values{}
+	if p.Token != "" {
+		params.Set("token", p.Token)
+	}
+	if chatID != 0 {
+		params.Set("chat", strconv.FormatInt(chatID, 10))
+	}
+
+	return dashboardPath + path + "?" + params.Encode()
+}
+
+// url of given dashboard action, with the token
+func (p dashboardPage) Action(action string) string {
+	params := url.Values{}
+	if p.Token != "" {
+		params.Set("token", p.Token)
+	}
+
+	return dashboardActionsPath + action + "?" + params.Encode()
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"time": func(t interface{ Format(string) string }) string {
+		return t.Format(dashboardTimeFormat)
+	},
+	"dateFormats":   func() []string { return settingDateFormats },
+	"dateLayout":    func(format string) string { return dateLayouts[format].full },
+	"roundingUnits": func() []int { return settingRoundingUnits },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} - reminder bot</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; margin: 1em; }
+nav a { margin-right: 1em; }
+table { border-collapse: collapse; width: 100%; margin-top: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #eee; }
+.err { color: #c00; }
+.muted { color: #888; }
+form.inline { display: inline; }
+</style>
+</head>
+<body>
+<nav>
+<a href="{{.Link "queue" 0}}">Queue</a>
+<a href="{{.Link "history" 0}}">History</a>
+<a href="{{.Link "logs" 0}}">Logs</a>
+<a href="{{.Link "chats" 0}}">Chats</a>
+<a href="{{.Link "audit" 0}}">Audit</a>
+</nav>
+<h1>{{.Title}}</h1>
+<form method="get" action="{{.Path}}">
+{{if .Token}}<input type="hidden" name="token" value="{{.Token}}">{{end}}
+{{if and (ne .Path "/dashboard/logs") (ne .Path "/dashboard/audit")}}<input type="text" name="chat" placeholder="chat id" value="{{.ChatID}}">{{end}}
+<input type="text" name="q" placeholder="search" value="{{.Search}}">
+<input type="submit" value="Filter">
+</form>
+{{if .Stats}}
+<table>
+<tr><th>chat</th><th>title</th><th>active</th><th>pending</th><th>delivered</th><th>failed</th><th>last delivered</th><th>settings</th></tr>
+{{range .Stats}}
+<tr{{if not .IsActive}} class="muted"{{end}}>
+<td><a href="{{$.Link "queue" .ChatID}}">{{.ChatID}}</a></td>
+<td>{{.Title}}</td>
+<td>{{.IsActive}}</td>
+<td>{{.NumPending}}</td>
+<td><a href="{{$.Link "history" .ChatID}}">{{.NumDelivered}}</a></td>
+<td{{if .NumFailed}} class="err"{{end}}>{{.NumFailed}}</td>
+<td>{{if not .LastDeliveredOn.IsZero}}{{time .LastDeliveredOn}}{{end}}</td>
+<td>
+<form class="inline" method="post" action="{{$.Action "chat"}}"><input type="hidden" name="chat" value="{{.ChatID}}"><input type="hidden" name="setting" value="{{if .IsActive}}deactivate{{else}}activate{{end}}"><input type="submit" value="{{if .IsActive}}deactivate{{else}}activate{{end}}"></form>
+<form class="inline" method="post" action="{{$.Action "chat"}}"><input type="hidden" name="chat" value="{{.ChatID}}"><input type="hidden" name="setting" value="revoke-api-key"><input type="submit" value="revoke api key"></form>
+<form class="inline" method="post" action="{{$.Action "chat"}}"><input type="hidden" name="chat" value="{{.ChatID}}"><input type="hidden" name="setting" value="clear-vacations"><input type="submit" value="clear vacations"></form>
+<a href="{{$.Link "chats" .ChatID}}">edit settings</a>
+</td>
+</tr>
+{{end}}
+</table>
+{{with .Settings}}
+<h2>Settings of {{.ChatID}}</h2>
+<form method="post" action="{{$.Action "chat"}}">
+<input type="hidden" name="chat" value="{{.ChatID}}"><input type="hidden" name="setting" value="settings">
+<p><label><input type="checkbox" name="link_preview"{{if .LinkPreview}} checked{{end}}> link preview</label></p>
+<p><label><input type="checkbox" name="summarize"{{if .Summarize}} checked{{end}}> link summaries</label></p>
+<p><label>greeting <input type="text" name="greeting" value="{{.Greeting}}"></label></p>
+<p><label>sign-off <input type="text" name="sign_off" value="{{.SignOff}}"></label></p>
+<p><label><input type="checkbox" name="simple_mode"{{if .SimpleMode}} checked{{end}}> simple mode</label></p>
+<p><label>date format <select name="date_format">{{$format := .DateFormat}}{{range dateFormats}}<option value="{{.}}"{{if eq . $format}} selected{{end}}>{{dateLayout .}}</option>{{end}}</select></label></p>
+<p><label><input type="checkbox" name="hour12"{{if .Hour12}} checked{{end}}> 12-hour clock</label></p>
+<p><label>rounding <select name="round_minutes">{{$minutes := .RoundMinutes}}{{range roundingUnits}}<option value="{{.}}"{{if eq . $minutes}} selected{{end}}>{{if .}}{{.}} minutes{{else}}none{{end}}</option>{{end}}</select></label></p>
+<input type="submit" value="save">
+</form>
+{{end}}
+{{else if .Audits}}
+<table>
+<tr><th>time</th><th>actor</th><th>action</th><th>chat</th><th>target</th><th>detail</th></tr>
+{{range .Audits}}
+<tr><td>{{time .Time}}</td><td>{{.Actor}}</td><td>{{.Action}}</td><td>{{if .ChatID}}{{.ChatID}}{{end}}</td><td>{{.Target}}</td><td>{{.Detail}}</td></tr>
+{{end}}
+</table>
+{{else if .Logs}}
+<table>
+<tr><th>time</th><th>type</th><th>message</th></tr>
+{{range .Logs}}
+<tr{{if eq .Type "err"}} class="err"{{end}}><td>{{time .Time}}</td><td>{{.Type}}</td><td>{{.Message}}</td></tr>
+{{end}}
+</table>
+{{else if .Queue}}
+<table>
+<tr><th>id</th><th>chat</th><th>user</th><th>message</th><th>fire on</th><th>delivered on</th><th>tries</th><th>kind</th><th>recurrence</th><th>last error</th><th>actions</th></tr>
+{{range .Queue}}
+<tr{{if ge .NumTries $.MaxNumTries}} class="err"{{else if .Paused}} class="muted"{{end}}>
+<td>{{.ID}}</td>
+<td><a href="{{$.Link "queue" .ChatID}}">{{.ChatID}}</a></td>
+<td>{{if .UserID}}{{.UserID}}{{end}}</td>
+<td>{{.Message}}</td>
+<td>{{time .FireOn}}</td>
+<td>{{if gt .DeliveredOn.Unix 0}}{{time .DeliveredOn}}{{end}}</td>
+<td>{{.NumTries}}</td>
+<td>{{.Kind}}</td>
+<td>{{.Recurrence}}</td>
+<td>{{if .LastError}}<span class="err">{{if .LastErrorCode}}[{{.LastErrorCode}}] {{end}}{{.LastError}}</span>{{if gt .LastErrorOn.Unix 0}}<br><span class="muted">{{time .LastErrorOn}}</span>{{end}}{{end}}</td>
+<td>
+<form class="inline" method="post" action="{{$.Action "resend"}}"><input type="hidden" name="chat" value="{{.ChatID}}"><input type="hidden" name="id" value="{{.ID}}"><input type="submit" value="resend"></form>
+{{if not $.History}}
+<form class="inline" method="post" action="{{$.Action "cancel"}}"><input type="hidden" name="chat" value="{{.ChatID}}"><input type="hidden" name="id" value="{{.ID}}"><input type="submit" value="cancel"></form>
+<form class="inline" method="post" action="{{$.Action "reschedule"}}"><input type="hidden" name="chat" value="{{.ChatID}}"><input type="hidden" name="id" value="{{.ID}}"><input type="datetime-local" name="fire_on"><input type="submit" value="reschedule"></form>
+{{end}}
+</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p class="muted">(nothing to show)</p>
+{{end}}
+</body>
+</html>
+`))
+
+// add dashboard handlers to given admin server mux
+func handleDashboard(mux *http.ServeMux, token string) {
+	mux.HandleFunc(dashboardActionsPath, adminOnly(token, handleDashboardAction))
+
+	mux.HandleFunc(dashboardPath, adminOnly(token, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		page := dashboardPage{
+			Path:        strings.TrimSuffix(r.URL.Path, "/"),
+			Token:       query.Get("token"),
+			ChatID:      query.Get("chat"),
+			Search:      strings.TrimSpace(query.Get("q")),
+			MaxNumTries: _maxNumTries,
+		}
+		if page.MaxNumTries <= 0 {
+			page.MaxNumTries = dbhelper.DefaultMaxNumTries
+		}
+
+		var chatID int64
+		if page.ChatID != "" {
+			var err error
+			if chatID, err = strconv.ParseInt(page.ChatID, 10, 64); err != nil {
+				http.Error(w, "malformed chat id", http.StatusBadRequest)
+				return
+			}
+		}
+
+		switch strings.TrimPrefix(r.URL.Path, dashboardPath) {
+		case "", "queue":
+			page.Title = "Queue"
+			page.Queue = db.SearchQueueItems(dbhelper.QueueFilter{ChatID: chatID, Search: page.Search, Limit: dashboardMaxItems})
+		case "history":
+			page.Title = "History"
+			page.History = true
+			page.Queue = db.SearchQueueItems(dbhelper.QueueFilter{ChatID: chatID, Delivered: true, Search: page.Search, Limit: dashboardMaxItems})
+		case "logs":
+			page.Title = "Logs"
+			for _, l := range db.GetLogs(dashboardNumLogs) {
+				if page.Search == "" || strings.Contains(l.Message, page.Search) {
+					page.Logs = append(page.Logs, l)
+				}
+			}
+		case "chats":
+			page.Title = "Chats"
+			for _, s := range db.ChatStats(page.MaxNumTries) {
+				if (chatID == 0 || s.ChatID == chatID) && (page.Search == "" || strings.Contains(s.Title, page.Search)) {
+					page.Stats = append(page.Stats, s)
+				}
+			}
+			if chatID != 0 && len(page.Stats) > 0 {
+				s := db.ChatSettings(chatID)
+				page.Settings = &s
+			}
+		case "audit":
+			page.Title = "Audit"
+			for _, a := range db.AuditLogs(dashboardNumLogs) {
+				if page.Search == "" || strings.Contains(a.Action+" "+a.Target+" "+a.Detail, page.Search) {
+					page.Audits = append(page.Audits, a)
+				}
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := dashboardTemplate.Execute(w, page); err != nil {
+			log.Printf("*** failed to render dashboard: %s", err)
+		}
+	}))
+}
+
+// handle actions of operators on the dashboard (recorded in the audit log)
+func handleDashboardAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	chatID, err := strconv.ParseInt(r.FormValue("chat"), 10, 64)
+	if err != nil {
+		http.Error(w, "malformed chat id", http.StatusBadRequest)
+		return
+	}
+
+	audit := dbhelper.AuditLog{
+		Actor:  r.RemoteAddr,
+		Action: strings.TrimPrefix(r.URL.Path, dashboardActionsPath),
+		ChatID: chatID,
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		audit.Actor = host
+	}
+
+	var ok bool
+	switch audit.Action {
+	case "cancel", "reschedule", "resend":
+		queueID, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "malformed queue id", http.StatusBadRequest)
+			return
+		}
+		audit.Target = fmt.Sprintf("queue:%d", queueID)
+
+		q, exists := db.QueueItem(chatID, queueID)
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch audit.Action {
+		case "cancel":
+			audit.Detail = q.Message
+			if ok = db.DeleteQueueItem(chatID, queueID); ok {
+				fireWebhooks(webhookEventCanceled, q, "")
+			}
+		case "reschedule":
+			fireOn, err := time.ParseInLocation(dashboardInputTimeFormat, r.FormValue("fire_on"), _location)
+			if err != nil {
+				http.Error(w, "malformed time", http.StatusBadRequest)
+				return
+			}
+			audit.Detail = fmt.Sprintf("%s -> %s", q.FireOn.Format(dashboardTimeFormat), fireOn.Format(dashboardTimeFormat))
+			ok = db.ScheduleQueueItem(chatID, queueID, fireOn)
+		case "resend":
+			if q.DeliveredOn.Unix() > 0 {
+				// send a copy of the delivered one
+				var newID int64
+				newID, ok = db.EnqueueItem(dbhelper.QueueItem{
+					ChatID:   q.ChatID,
+					UserID:   q.UserID,
+					Message:  q.Message,
+					FireOn:   time.Now(),
+					Kind:     q.Kind,
+					RefID:    q.RefID,
+					Assignee: q.Assignee,
+					Private:  q.Private,
+				})
+				audit.Detail = fmt.Sprintf("copied to queue:%d", newID)
+			} else {
+				// retry the stuck one from now on
+				ok = db.RetryQueueItem(chatID, queueID, time.Now()) && db.SetQueueItemPaused(chatID, queueID, dbhelper.NotPaused)
+				audit.Detail = fmt.Sprintf("retried after %d tries", q.NumTries)
+			}
+		}
+	case "chat":
+		audit.Target = r.FormValue("setting")
+
+		switch audit.Target {
+		case "activate", "deactivate":
+			ok = db.SetChatActive(chatID, audit.Target == "activate")
+		case "revoke-api-key":
+			ok = db.DeleteAPIKey(chatID)
+		case "clear-vacations":
+			num := db.DeleteSuppressions(chatID, dbhelper.SuppressionKindVacation)
+			audit.Detail = fmt.Sprintf("%d deleted", num)
+			ok = true
+		case "settings":
+			s, err := chatSettingsFromForm(r, db.ChatSettings(chatID))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			audit.Detail = chatSettingsChanges(db.ChatSettings(chatID), s)
+			ok = db.SaveChatSettings(s)
+		default:
+			http.Error(w, "unknown setting", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if !ok {
+		audit.Detail = strings.TrimSpace(audit.Detail + " (failed)")
+	}
+	db.SaveAuditLog(audit)
+
+	log.Printf("Dashboard action by %s: %s %s (chat: %d, ok: %t)", audit.Actor, audit.Action, audit.Target, chatID, ok)
+
+	if !ok {
+		http.Error(w, "failed to "+audit.Action, http.StatusInternalServerError)
+		return
+	}
+
+	// back to the page
+	back := r.Referer()
+	if back == "" {
+		back = dashboardPath
+	}
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// apply the values of the settings form on the dashboard to given settings
+func chatSettingsFromForm(r *http.Request, s dbhelper.ChatSettings) (dbhelper.ChatSettings, error) {
+	// (unchecked ones are not posted)
+	s.LinkPreview = r.FormValue("link_preview") != ""
+	s.Summarize = r.FormValue("summarize") != ""
+	s.SimpleMode = r.FormValue("simple_mode") != ""
+	s.Hour12 = r.FormValue("hour12") != ""
+
+	var errMessage string
+	if s.Greeting, errMessage = sanitizeDecoration(r.FormValue("greeting")); errMessage != "" {
+		return s, fmt.Errorf("invalid greeting: %s", errMessage)
+	}
+	if s.SignOff, errMessage = sanitizeDecoration(r.FormValue("sign_off")); errMessage != "" {
+		return s, fmt.Errorf("invalid sign-off: %s", errMessage)
+	}
+
+	s.DateFormat = r.FormValue("date_format")
+	if _, exists := dateLayouts[s.DateFormat]; !exists {
+		return s, fmt.Errorf("unknown date format: %s", s.DateFormat)
+	}
+
+	minutes, err := strconv.Atoi(r.FormValue("round_minutes"))
+	if err != nil {
+		return s, fmt.Errorf("malformed rounding: %s", r.FormValue("round_minutes"))
+	}
+	for _, unit := range settingRoundingUnits {
+		if unit == minutes {
+			s.RoundMinutes = minutes
+			return s, nil
+		}
+	}
+	return s, fmt.Errorf("unsupported rounding: %d", minutes)
+}
+
+// changes between given settings (for the audit log)
+func chatSettingsChanges(before, after dbhelper.ChatSettings) string {
+	changes := []string{}
+	for _, c := range []struct {
+		name     string
+		from, to interface{}
+	}{
+		{"link_preview", before.LinkPreview, after.LinkPreview},
+		{"summarize", before.Summarize, after.Summarize},
+		{"greeting", strconv.Quote(before.Greeting), strconv.Quote(after.Greeting)},
+		{"sign_off", strconv.Quote(before.SignOff), strconv.Quote(after.SignOff)},
+		{"simple_mode", before.SimpleMode, after.SimpleMode},
+		{"date_format", strconv.Quote(before.DateFormat), strconv.Quote(after.DateFormat)},
+		{"hour12", before.Hour12, after.Hour12},
+		{"round_minutes", before.RoundMinutes, after.RoundMinutes},
+	} {
+		if c.from != c.to {
+			changes = append(changes, fmt.Sprintf("%s: %v -> %v", c.name, c.from, c.to))
+		}
+	}
+
+	if len(changes) <= 0 {
+		return "(no changes)"
+	}
+	return strings.Join(changes, ", ")
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// per-chat date/time formats and week starts: layouts of times shown to users are written with the default layouts
+// (eg. "2006.1.2 15:04에 ..."), which are replaced with the chosen ones of each chat when formatted
+
+const (
+	dateFormatDot   = ""      // 2006.1.2 (default)
+	dateFormatSlash = "slash" // 1/2/2006
+	dateFormatISO   = "iso"   // 2006-01-02
+
+	defaultDateLayout      = "2006.1.2"
+	defaultShortDateLayout = "1.2"
+	defaultClockLayout     = "15:04"
+	clockLayout12          = "PM 3:04" // (AM/PM are replaced with messageTimeAM/PM)
+)
+
+// date layouts of each format
+var dateLayouts = map[string]struct {
+	full  string
+	short string // (without year)
+}{
+	dateFormatDot:   {full: defaultDateLayout, short: defaultShortDateLayout},
+	dateFormatSlash: {full: "1/2/2006", short: "1/2"},
+	dateFormatISO:   {full: "2006-01-02", short: "01-02"},
+}
+
+// date formats and week starts, in the order of cycling with the buttons
+var (
+	settingDateFormats = []string{dateFormatDot, dateFormatSlash, dateFormatISO}
+	settingWeekStarts  = []time.Weekday{time.Monday, time.Sunday, time.Saturday}
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageDateFormatTitle = "날짜 형식"
+	messageWeekStartTitle  = "한 주의 시작"
+	messageWeekStartFormat = "%s요일"
+	messageHour12Title     = "시각 표시"
+	messageHour12          = "12시간 (오후 3:04)"
+	messageHour24          = "24시간 (15:04)"
+	messageTimeAM          = "오전" // (eg. "AM" for english)
+	messageTimePM          = "오후" // (eg. "PM" for english)
+)
+
+// given layout with the date/time format of given settings
+func chatLayout(s dbhelper.ChatSettings, layout string) string {
+	if l, exists := dateLayouts[s.DateFormat]; exists {
+		layout = strings.Replace(layout, defaultDateLayout, l.full, 1)
+	}
+	if s.Hour12 {
+		layout = strings.Replace(layout, defaultClockLayout, clockLayout12, 1)
+	}
+	return layout
+}
+
+// format given time with given layout in the date/time format of given settings
+func formatTimeWith(s dbhelper.ChatSettings, t time.Time, layout string) string {
+	formatted := t.Format(chatLayout(s, layout))
+	if s.Hour12 {
+		formatted = strings.NewReplacer("AM", messageTimeAM, "PM", messageTimePM).Replace(formatted)
+	}
+	return formatted
+}
+
+// format given time with given layout in the date/time format of given chat
+//
+// (eg. formatTimeFor(chatID, t, reminderTimeFormat) => "2017-12-31 오후 11:00" for a chat with ISO dates and 12-hour clock)
+func formatTimeFor(chatID int64, t time.Time, layout string) string {
+	return formatTimeWith(db.ChatSettings(chatID), t, layout)
+}
+
+// format given time of day in the time format of given chat (eg. "15:04" or "오후 3:04")
+func clockOf(chatID int64, t time.Time) string {
+	return formatTimeFor(chatID, t, defaultClockLayout)
+}
+
+// short date (without year) of given time in the date format of given settings
+func shortDateOf(s dbhelper.ChatSettings, t time.Time) string {
+	if l, exists := dateLayouts[s.DateFormat]; exists {
+		return t.Format(l.short)
+	}
+	return t.Format(defaultShortDateLayout)
+}
+
+// displayable name of given date format (an example date)
+func describeDateFormat(format string) string {
+	if l, exists := dateLayouts[format]; exists {
+		return time.Date(2017, 12, 31, 0, 0, 0, 0, time.Local).Format(l.full)
+	}
+	return defaultDateLayout
+}
+
+// displayable name of given time format
+func describeHour12(hour12 bool) string {
+	if hour12 {
+		return messageHour12
+	}
+	return messageHour24
+}
+
+// next date format (for cycling with the button)
+func nextDateFormat(format string) string {
+	for i, f := range settingDateFormats {
+		if f == format && i+1 < len(settingDateFormats) {
+			return settingDateFormats[i+1]
+		}
+	}
+	return settingDateFormats[0]
+}
+
+// next week start (for cycling with the button)
+func nextWeekStart(weekday time.Weekday) time.Weekday {
+	for i, w := range settingWeekStarts {
+		if w == weekday && i+1 < len(settingWeekStarts) {
+			return settingWeekStarts[i+1]
+		}
+	}
+	return settingWeekStarts[0]
+}
+
+// last day of the week of given chat (weekly summaries and reports are sent on it)
+func lastWeekdayOf(chatID int64) time.Weekday {
+	return time.Weekday((db.ChatSettings(chatID).WeekStart + 6) % 7)
+}
+
+// move weekly summaries and reports of given chat to the last day of its week
+func rescheduleWeeklyReports(chatID int64) {
+	for _, q := range db.UndeliveredQueueItems(chatID) {
+		switch q.Kind {
+		case dbhelper.QueueKindHabitSummary:
+			db.ScheduleQueueItem(chatID, q.ID, nextTimeOfWeekday(lastWeekdayOf(chatID), habitSummaryHour))
+		case dbhelper.QueueKindMedicationReport:
+			db.ScheduleQueueItem(chatID, q.ID, nextTimeOfWeekday(lastWeekdayOf(chatID), medicationReportHour))
+		}
+	}
+}
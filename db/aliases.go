@@ -0,0 +1,92 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+)
+
+// Alias struct (personal shortcut of a command in a chat)
+type Alias struct {
+	ChatID  int64  `json:"chat_id"`
+	Alias   string `json:"alias"`
+	Command string `json:"command"`
+}
+
+// SaveAlias saves (or replaces) an alias of given chat
+func (d *Database) SaveAlias(chatID int64, alias, command string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or replace into aliases(chat_id, alias, command) values(?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(chatID, alias, command); err != nil {
+			log.Printf("*** Failed to save alias into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// DeleteAlias deletes an alias of given chat
+func (d *Database) DeleteAlias(chatID int64, alias string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`delete from aliases where chat_id = ? and alias = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(chatID, alias); err != nil {
+			log.Printf("*** Failed to delete alias from local database: %s\n", err.Error())
+		} else {
+			if num, _ := res.RowsAffected(); num > 0 {
+				result = true
+			}
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// Aliases returns all aliases of given chat
+func (d *Database) Aliases(chatID int64) []Alias {
+	aliases := []Alias{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select chat_id, alias, command from aliases where chat_id = ? order by alias`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID); err != nil {
+			log.Printf("*** Failed to select aliases from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var a Alias
+			for rows.Next() {
+				if err := rows.Scan(&a.ChatID, &a.Alias, &a.Command); err == nil {
+					aliases = append(aliases, a)
+				}
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return aliases
+}
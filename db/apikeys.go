@@ -0,0 +1,85 @@
+package db
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"log"
+)
+
+// hash given api token for storing/comparing
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// SaveAPIKey saves (the hash of) given api token for given chat, replacing the previous one
+func (d *Database) SaveAPIKey(chatID int64, token string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or replace into api_keys(chat_id, token_hash) values(?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(chatID, hashToken(token)); err != nil {
+			log.Printf("*** Failed to save api key into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// DeleteAPIKey revokes the api token of given chat
+func (d *Database) DeleteAPIKey(chatID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`delete from api_keys where chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(chatID); err != nil {
+			log.Printf("*** Failed to delete api key from local database: %s\n", err.Error())
+		} else {
+			if num, _ := res.RowsAffected(); num > 0 {
+				result = true
+			}
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// ChatIDForAPIKey returns the chat id which owns given api token
+func (d *Database) ChatIDForAPIKey(token string) (chatID int64, exists bool) {
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select chat_id from api_keys where token_hash = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if err := stmt.QueryRow(hashToken(token)).Scan(&chatID); err == nil {
+			exists = true
+		} else if err != sql.ErrNoRows {
+			log.Printf("*** Failed to select api key from local database: %s\n", err.Error())
+		}
+	}
+
+	d.RUnlock()
+
+	return chatID, exists
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"log"
+	"time"
+)
+
+// AuditLog struct (an action of an operator)
+type AuditLog struct {
+	Actor  string    `json:"actor"`
+	Action string    `json:"action"`
+	ChatID int64     `json:"chat_id,omitempty"`
+	Target string    `json:"target,omitempty"`
+	Detail string    `json:"detail,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// SaveAuditLog saves an action of an operator
+func (d *Database) SaveAuditLog(a AuditLog) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into audit_logs(actor, action, chat_id, target, detail) values(?, ?, ?, nullif(?, ''), nullif(?, ''))`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(a.Actor, a.Action, a.ChatID, a.Target, a.Detail); err != nil {
+			log.Printf("*** Failed to save audit log into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// AuditLogs returns latest N audit logs
+func (d *Database) AuditLogs(latestN int) []AuditLog {
+	logs := []AuditLog{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select actor, action, chat_id, ifnull(target, ''), ifnull(detail, ''), time
+		from audit_logs
+		order by id desc
+		limit ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(latestN); err != nil {
+			log.Printf("*** Failed to select audit logs from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var a AuditLog
+			var tm int64
+			for rows.Next() {
+				if err := rows.Scan(&a.Actor, &a.Action, &a.ChatID, &a.Target, &a.Detail, &tm); err != nil {
+					log.Printf("*** Failed to scan audit log: %s\n", err.Error())
+					continue
+				}
+				a.Time = time.Unix(tm, 0)
+
+				logs = append(logs, a)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return logs
+}
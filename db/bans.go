@@ -0,0 +1,91 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Ban struct
+type Ban struct {
+	UserID      int64     `json:"user_id"`
+	Username    string    `json:"username"`
+	Reason      string    `json:"reason"`
+	NumBans     int       `json:"num_bans"`
+	BannedUntil time.Time `json:"banned_until"`
+	BannedOn    time.Time `json:"banned_on"`
+}
+
+// IsActive checks if the ban is still in effect
+func (b Ban) IsActive() bool {
+	return b.BannedUntil.After(time.Now())
+}
+
+// Ban returns the (latest) ban of given user, or false if there was none
+func (d *Database) Ban(userID int64) (ban Ban, exists bool) {
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select
+		user_id,
+		ifnull(username, '') as username,
+		ifnull(reason, '') as reason,
+		num_bans,
+		banned_until,
+		banned_on
+		from bans
+		where user_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var username, reason string
+		var numBans int
+		var bannedUntil, bannedOn int64
+		if err := stmt.QueryRow(userID).Scan(&userID, &username, &reason, &numBans, &bannedUntil, &bannedOn); err == nil {
+			ban = Ban{
+				UserID:      userID,
+				Username:    username,
+				Reason:      reason,
+				NumBans:     numBans,
+				BannedUntil: time.Unix(bannedUntil, 0),
+				BannedOn:    time.Unix(bannedOn, 0),
+			}
+			exists = true
+		} else if err != sql.ErrNoRows {
+			log.Printf("*** Failed to select ban from local database: %s\n", err.Error())
+		}
+	}
+
+	d.RUnlock()
+
+	return ban, exists
+}
+
+// SaveBan bans given user until given time, increasing the number of bans
+func (d *Database) SaveBan(userID int64, username, reason string, until time.Time) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into bans(user_id, username, reason, num_bans, banned_until) values(?, ?, ?, 1, ?)
+		on conflict(user_id) do update set
+			username = excluded.username,
+			reason = excluded.reason,
+			num_bans = num_bans + 1,
+			banned_until = excluded.banned_until,
+			banned_on = strftime('%s', 'now')`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(userID, username, reason, until.Unix()); err != nil {
+			log.Printf("*** Failed to save ban into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
@@ -0,0 +1,261 @@
+package db
+
+import (
+	"log"
+	"time"
+)
+
+// statuses of broadcasts and their recipients
+const (
+	BroadcastStatusRunning = "running"
+	BroadcastStatusDone    = "done"
+
+	RecipientStatusPending = "pending"
+	RecipientStatusSent    = "sent"
+	RecipientStatusFailed  = "failed"
+)
+
+// Broadcast struct (a message being sent to many chats)
+type Broadcast struct {
+	ID          int64     `json:"id"`
+	QueueChatID int64     `json:"queue_chat_id"` // queue item which started it
+	QueueID     int64     `json:"queue_id"`
+	Message     string    `json:"message"`
+	Status      string    `json:"status"`
+	CreatedOn   time.Time `json:"created_on"`
+	FinishedOn  time.Time `json:"finished_on,omitempty"`
+}
+
+// BroadcastStats struct (numbers of recipients by status)
+type BroadcastStats struct {
+	NumSent    int `json:"num_sent"`
+	NumFailed  int `json:"num_failed"`
+	NumPending int `json:"num_pending"`
+}
+
+// CreateBroadcast saves a broadcast of given message with its recipients (all pending),
+// and marks its queue item as delivered in the same transaction
+func (d *Database) CreateBroadcast(queueChatID, queueID int64, message string, chatIDs []int64) (broadcastID int64, result bool) {
+	d.Lock()
+
+	if tx, err := d.db.Begin(); err != nil {
+		log.Printf("*** Failed to begin a transaction: %s\n", err.Error())
+	} else {
+		if res, err := tx.Exec(`insert into broadcasts(queue_chat_id, queue_id, message, status) values(?, ?, ?, ?)`, queueChatID, queueID, message, BroadcastStatusRunning); err != nil {
+			log.Printf("*** Failed to save broadcast into local database: %s\n", err.Error())
+		} else {
+			broadcastID, _ = res.LastInsertId()
+
+			result = true
+			for _, chatID := range chatIDs {
+				if _, err := tx.Exec(`insert or ignore into broadcast_recipients(broadcast_id, chat_id, status) values(?, ?, ?)`, broadcastID, chatID, RecipientStatusPending); err != nil {
+					log.Printf("*** Failed to save broadcast recipient into local database: %s\n", err.Error())
+					result = false
+					break
+				}
+			}
+
+			if result {
+				if res, err := tx.Exec(`update queue set delivered_on = ? where id = ? and chat_id = ? and delivered_on is null`, time.Now().Unix(), queueID, queueChatID); err != nil {
+					log.Printf("*** Failed to mark delivered_on in local database: %s\n", err.Error())
+					result = false
+				} else if num, _ := res.RowsAffected(); num <= 0 {
+					log.Printf("*** Failed to mark delivered_on for id: %d, chat_id: %d\n", queueID, queueChatID)
+					result = false
+				}
+			}
+		}
+
+		if result {
+			if err := tx.Commit(); err != nil {
+				log.Printf("*** Failed to commit a transaction: %s\n", err.Error())
+				result = false
+			}
+		} else {
+			tx.Rollback()
+		}
+	}
+
+	d.Unlock()
+
+	return broadcastID, result
+}
+
+// UnfinishedBroadcasts returns broadcasts which are still running (eg. for resuming them after a restart)
+func (d *Database) UnfinishedBroadcasts() []Broadcast {
+	broadcasts := []Broadcast{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select id, queue_chat_id, queue_id, message, status, created_on
+		from broadcasts
+		where status = ?
+		order by id`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(BroadcastStatusRunning); err != nil {
+			log.Printf("*** Failed to select broadcasts from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var b Broadcast
+			var createdOn int64
+			for rows.Next() {
+				if err := rows.Scan(&b.ID, &b.QueueChatID, &b.QueueID, &b.Message, &b.Status, &createdOn); err != nil {
+					log.Printf("*** Failed to scan broadcast: %s\n", err.Error())
+					continue
+				}
+				b.CreatedOn = time.Unix(createdOn, 0)
+
+				broadcasts = append(broadcasts, b)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return broadcasts
+}
+
+// PendingBroadcastRecipients returns (at most given number of) chats which are not sent given broadcast yet
+func (d *Database) PendingBroadcastRecipients(broadcastID int64, limit int) []int64 {
+	chatIDs := []int64{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select chat_id from broadcast_recipients
+		where broadcast_id = ? and status = ?
+		order by chat_id
+		limit ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(broadcastID, RecipientStatusPending, limit); err != nil {
+			log.Printf("*** Failed to select broadcast recipients from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var chatID int64
+			for rows.Next() {
+				if err := rows.Scan(&chatID); err != nil {
+					log.Printf("*** Failed to scan broadcast recipient: %s\n", err.Error())
+					continue
+				}
+				chatIDs = append(chatIDs, chatID)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return chatIDs
+}
+
+// SetBroadcastRecipientStatus saves the result of sending given broadcast to given chat
+func (d *Database) SetBroadcastRecipientStatus(broadcastID, chatID int64, status, lastError string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update broadcast_recipients
+		set status = ?, last_error = nullif(?, ''), num_tries = num_tries + 1, updated_on = strftime('%s', 'now')
+		where broadcast_id = ? and chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(status, lastError, broadcastID, chatID); err != nil {
+			log.Printf("*** Failed to update broadcast recipient in local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// NumBroadcastTries returns the number of tries of sending given broadcast to given chat
+func (d *Database) NumBroadcastTries(broadcastID, chatID int64) (numTries int) {
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select num_tries from broadcast_recipients where broadcast_id = ? and chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if err := stmt.QueryRow(broadcastID, chatID).Scan(&numTries); err != nil {
+			log.Printf("*** Failed to select broadcast recipient from local database: %s\n", err.Error())
+		}
+	}
+
+	d.RUnlock()
+
+	return numTries
+}
+
+// FinishBroadcast marks given broadcast as done
+func (d *Database) FinishBroadcast(broadcastID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update broadcasts set status = ?, finished_on = strftime('%s', 'now') where id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(BroadcastStatusDone, broadcastID); err != nil {
+			log.Printf("*** Failed to update broadcast in local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// BroadcastStatsOf returns the numbers of recipients of given broadcast by status
+func (d *Database) BroadcastStatsOf(broadcastID int64) (stats BroadcastStats) {
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select status, count(*) from broadcast_recipients where broadcast_id = ? group by status`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(broadcastID); err != nil {
+			log.Printf("*** Failed to select broadcast stats from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var status string
+			var count int
+			for rows.Next() {
+				if err := rows.Scan(&status, &count); err != nil {
+					log.Printf("*** Failed to scan broadcast stats: %s\n", err.Error())
+					continue
+				}
+
+				switch status {
+				case RecipientStatusSent:
+					stats.NumSent = count
+				case RecipientStatusFailed:
+					stats.NumFailed = count
+				case RecipientStatusPending:
+					stats.NumPending = count
+				}
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return stats
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// BusinessConnection struct (a telegram business account which the bot is attached to)
+type BusinessConnection struct {
+	ID          string    `json:"id"`
+	UserID      int64     `json:"user_id"`      // owner of the business account
+	UserChatID  int64     `json:"user_chat_id"` // private chat with the owner
+	CanReply    bool      `json:"can_reply"`    // (given by telegram) the bot can send messages in the business chats
+	IsEnabled   bool      `json:"is_enabled"`   // (given by telegram) the connection is active
+	CanSchedule bool      `json:"can_schedule"` // (given by the owner) reminders can be scheduled in the business chats
+	CreatedOn   time.Time `json:"created_on"`
+	UpdatedOn   time.Time `json:"updated_on"`
+}
+
+// SaveBusinessConnection saves (or updates) given business connection
+//
+// (permissions given by the owner are kept as they are)
+func (d *Database) SaveBusinessConnection(c BusinessConnection) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into business_connections(id, user_id, user_chat_id, can_reply, is_enabled) values(?, ?, ?, ?, ?)
+		on conflict(id) do update set
+			user_id = excluded.user_id,
+			user_chat_id = excluded.user_chat_id,
+			can_reply = excluded.can_reply,
+			is_enabled = excluded.is_enabled,
+			updated_on = strftime('%s', 'now')`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(c.ID, c.UserID, c.UserChatID, c.CanReply, c.IsEnabled); err != nil {
+			log.Printf("*** Failed to save business connection into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// SetBusinessConnectionSchedulable sets the permission for scheduling reminders in the business chats of given connection
+func (d *Database) SetBusinessConnectionSchedulable(connectionID string, userID int64, canSchedule bool) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update business_connections set can_schedule = ?, updated_on = strftime('%s', 'now') where id = ? and user_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(canSchedule, connectionID, userID); err != nil {
+			log.Printf("*** Failed to update business connection in local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// BusinessConnection returns the business connection with given id
+func (d *Database) BusinessConnection(connectionID string) (c BusinessConnection, exists bool) {
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select ` + businessConnectionColumns + ` from business_connections where id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(connectionID); err != nil {
+			log.Printf("*** Failed to select business connection from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			if connections := scanBusinessConnections(rows); len(connections) > 0 {
+				c, exists = connections[0], true
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return c, exists
+}
+
+// BusinessConnectionsOfUser returns the business connections owned by given user
+func (d *Database) BusinessConnectionsOfUser(userID int64) []BusinessConnection {
+	connections := []BusinessConnection{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select ` + businessConnectionColumns + ` from business_connections where user_id = ? order by created_on`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(userID); err != nil {
+			log.Printf("*** Failed to select business connections from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			connections = scanBusinessConnections(rows)
+		}
+	}
+
+	d.RUnlock()
+
+	return connections
+}
+
+// columns for selecting business connections (should match scanBusinessConnections)
+const businessConnectionColumns = `id, user_id, user_chat_id, can_reply, is_enabled, can_schedule, created_on, updated_on`
+
+// scan rows selected with businessConnectionColumns
+func scanBusinessConnections(rows *sql.Rows) []BusinessConnection {
+	connections := []BusinessConnection{}
+
+	var c BusinessConnection
+	var createdOn, updatedOn int64
+	for rows.Next() {
+		if err := rows.Scan(&c.ID, &c.UserID, &c.UserChatID, &c.CanReply, &c.IsEnabled, &c.CanSchedule, &createdOn, &updatedOn); err != nil {
+			log.Printf("*** Failed to scan business connection: %s\n", err.Error())
+			continue
+		}
+		c.CreatedOn = time.Unix(createdOn, 0)
+		c.UpdatedOn = time.Unix(updatedOn, 0)
+
+		connections = append(connections, c)
+	}
+
+	return connections
+}
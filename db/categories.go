@@ -0,0 +1,88 @@
+package db
+
+import (
+	"log"
+)
+
+// CategoryRoute struct (reminders of a category are delivered to the target chat)
+type CategoryRoute struct {
+	ChatID       int64  `json:"chat_id"`
+	Category     string `json:"category"`
+	TargetChatID int64  `json:"target_chat_id"`
+}
+
+// SaveCategoryRoute saves (or replaces) the target chat of given category
+func (d *Database) SaveCategoryRoute(chatID int64, category string, targetChatID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or replace into category_routes(chat_id, category, target_chat_id) values(?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(chatID, category, targetChatID); err != nil {
+			log.Printf("*** Failed to save category route into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// DeleteCategoryRoute deletes the target chat of given category
+func (d *Database) DeleteCategoryRoute(chatID int64, category string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`delete from category_routes where chat_id = ? and category = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(chatID, category); err != nil {
+			log.Printf("*** Failed to delete category route from local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// CategoryRoutes returns all category routes of given chat
+func (d *Database) CategoryRoutes(chatID int64) []CategoryRoute {
+	routes := []CategoryRoute{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select chat_id, category, target_chat_id from category_routes where chat_id = ? order by category`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID); err != nil {
+			log.Printf("*** Failed to select category routes from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var r CategoryRoute
+			for rows.Next() {
+				if err := rows.Scan(&r.ChatID, &r.Category, &r.TargetChatID); err == nil {
+					routes = append(routes, r)
+				}
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return routes
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"log"
+	"time"
+)
+
+// Chain struct (a reminder to be scheduled after its parent reminder is acknowledged)
+type Chain struct {
+	ID           int64     `json:"id"`
+	ParentID     int64     `json:"parent_id"` // queue id of the parent reminder
+	ChatID       int64     `json:"chat_id"`
+	UserID       int64     `json:"user_id,omitempty"`
+	Message      string    `json:"message"`
+	DelayMinutes int       `json:"delay_minutes"`
+	CreatedOn    time.Time `json:"created_on"`
+}
+
+// SaveChain saves a new chain
+func (d *Database) SaveChain(c Chain) (chainID int64, result bool) {
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into chains(parent_id, chat_id, user_id, message, delay_minutes) values(?, ?, ?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(c.ParentID, c.ChatID, c.UserID, c.Message, c.DelayMinutes); err != nil {
+			log.Printf("*** Failed to save chain into local database: %s\n", err.Error())
+		} else {
+			chainID, _ = res.LastInsertId()
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return chainID, result
+}
+
+// PendingChains returns chains of given parent reminder which were not triggered yet
+func (d *Database) PendingChains(chatID, parentID int64) []Chain {
+	chains := []Chain{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select id, parent_id, chat_id, user_id, message, delay_minutes, created_on
+		from chains
+		where chat_id = ? and parent_id = ? and triggered_on is null
+		order by id`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID, parentID); err != nil {
+			log.Printf("*** Failed to select chains from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var c Chain
+			var createdOn int64
+			for rows.Next() {
+				if err := rows.Scan(&c.ID, &c.ParentID, &c.ChatID, &c.UserID, &c.Message, &c.DelayMinutes, &createdOn); err != nil {
+					log.Printf("*** Failed to scan chain: %s\n", err.Error())
+					continue
+				}
+				c.CreatedOn = time.Unix(createdOn, 0)
+
+				chains = append(chains, c)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return chains
+}
+
+// MarkChainTriggered marks given chain as triggered (only once)
+func (d *Database) MarkChainTriggered(chainID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update chains set triggered_on = ? where id = ? and triggered_on is null`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(time.Now().Unix(), chainID); err != nil {
+			log.Printf("*** Failed to update chain in local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
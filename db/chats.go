@@ -0,0 +1,158 @@
+package db
+
+import (
+	"log"
+	"time"
+)
+
+// Chat struct
+type Chat struct {
+	ChatID      int64     `json:"chat_id"`
+	Type        string    `json:"type"`
+	Title       string    `json:"title,omitempty"`
+	IsActive    bool      `json:"is_active"`
+	FirstSeenOn time.Time `json:"first_seen_on"`
+	LastSeenOn  time.Time `json:"last_seen_on"`
+}
+
+// SaveChat saves given chat as an active one
+func (d *Database) SaveChat(chatID int64, typ, title string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into chats(chat_id, type, title) values(?, ?, nullif(?, ''))
+		on conflict(chat_id) do update set
+			type = excluded.type,
+			title = excluded.title,
+			is_active = 1,
+			last_seen_on = strftime('%s', 'now')`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(chatID, typ, title); err != nil {
+			log.Printf("*** Failed to save chat into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// SetChatActive marks given chat as (in)active (eg. the bot was removed from it)
+func (d *Database) SetChatActive(chatID int64, active bool) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update chats set is_active = ? where chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(active, chatID); err != nil {
+			log.Printf("*** Failed to update chat in local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// ActiveChats returns all the chats where the bot is active
+func (d *Database) ActiveChats() []Chat {
+	chats := []Chat{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select
+		chat_id,
+		type,
+		ifnull(title, '') as title,
+		is_active,
+		first_seen_on,
+		last_seen_on
+		from chats
+		where is_active = 1
+		order by chat_id`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(); err != nil {
+			log.Printf("*** Failed to select chats from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var chat Chat
+			var firstSeenOn, lastSeenOn int64
+			for rows.Next() {
+				if err := rows.Scan(&chat.ChatID, &chat.Type, &chat.Title, &chat.IsActive, &firstSeenOn, &lastSeenOn); err != nil {
+					log.Printf("*** Failed to scan chat: %s\n", err.Error())
+					continue
+				}
+				chat.FirstSeenOn = time.Unix(firstSeenOn, 0)
+				chat.LastSeenOn = time.Unix(lastSeenOn, 0)
+
+				chats = append(chats, chat)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return chats
+}
+
+// ActiveChatsSeenWith returns the chats where the bot is active, and given user has been seen
+// (the user's private chat, and groups where the user has created reminders)
+func (d *Database) ActiveChatsSeenWith(userID int64) []Chat {
+	chats := []Chat{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select
+		chat_id,
+		type,
+		ifnull(title, '') as title,
+		is_active,
+		first_seen_on,
+		last_seen_on
+		from chats
+		where is_active = 1 and (chat_id = ? or chat_id in (select distinct chat_id from queue where user_id = ?))
+		order by chat_id`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(userID, userID); err != nil {
+			log.Printf("*** Failed to select chats from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var chat Chat
+			var firstSeenOn, lastSeenOn int64
+			for rows.Next() {
+				if err := rows.Scan(&chat.ChatID, &chat.Type, &chat.Title, &chat.IsActive, &firstSeenOn, &lastSeenOn); err != nil {
+					log.Printf("*** Failed to scan chat: %s\n", err.Error())
+					continue
+				}
+				chat.FirstSeenOn = time.Unix(firstSeenOn, 0)
+				chat.LastSeenOn = time.Unix(lastSeenOn, 0)
+
+				chats = append(chats, chat)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return chats
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+)
+
+// ChatSettings struct (per-chat options)
+type ChatSettings struct {
+	ChatID       int64  `json:"chat_id"`
+	LinkPreview  bool   `json:"link_preview"`            // show previews of links in delivered reminders
+	Summarize    bool   `json:"summarize"`               // append summaries of linked pages to delivered reminders
+	FollowUp     bool   `json:"follow_up"`               // ask whether delivered reminders were done
+	Greeting     string `json:"greeting,omitempty"`      // prepended to delivered reminders
+	SignOff      string `json:"sign_off,omitempty"`      // appended to delivered reminders
+	RoundMinutes int    `json:"round_minutes,omitempty"` // requested times are rounded to this unit (0 for not rounding)
+	Voice        bool   `json:"voice,omitempty"`         // also deliver reminders as voice notes
+	SimpleMode   bool   `json:"simple_mode,omitempty"`   // numbered options instead of inline keyboards, and shorter messages
+	QuietFrom    int    `json:"quiet_from,omitempty"`    // start hour of quiet hours (for delivery windows)
+	QuietTo      int    `json:"quiet_to,omitempty"`      // end hour of quiet hours (the same as QuietFrom for no quiet hours)
+	TidyText     bool   `json:"tidy_text"`               // tidy up trailing imperative particles of reminder texts when displayed
+	GroupReport  bool   `json:"group_report,omitempty"`  // send daily reports of the group's reminders to its admins
+	DateFormat   string `json:"date_format,omitempty"`   // format of dates shown to users ("" for 2006.1.2, "slash" for 1/2/2006, "iso" for 2006-01-02)
+	WeekStart    int    `json:"week_start"`              // first day of weeks (0 for sunday, 1 for monday, ...)
+	Hour12       bool   `json:"hour12,omitempty"`        // show times in 12-hour clock (with AM/PM)
+	PushOnly     bool   `json:"push_only,omitempty"`     // deliver reminders only to the companion apps (when any of them receives the push)
+}
+
+// DefaultChatSettings returns the default settings of given chat
+func DefaultChatSettings(chatID int64) ChatSettings {
+	return ChatSettings{
+		ChatID:      chatID,
+		LinkPreview: true,
+		TidyText:    true,
+		WeekStart:   1, // (monday)
+	}
+}
+
+// SaveChatSettings saves (or replaces) settings of a chat
+func (d *Database) SaveChatSettings(s ChatSettings) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or replace into chat_settings(chat_id, link_preview, summarize, follow_up, greeting, sign_off, round_minutes, voice, simple_mode, tidy_text, quiet_from, quiet_to, group_report, date_format, week_start, hour12, push_only) values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(s.ChatID, s.LinkPreview, s.Summarize, s.FollowUp, s.Greeting, s.SignOff, s.RoundMinutes, s.Voice, s.SimpleMode, s.TidyText, s.QuietFrom, s.QuietTo, s.GroupReport, s.DateFormat, s.WeekStart, s.Hour12, s.PushOnly); err != nil {
+			log.Printf("*** Failed to save chat settings into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// ChatSettings returns settings of given chat (or the default ones if not saved yet)
+func (d *Database) ChatSettings(chatID int64) ChatSettings {
+	s := DefaultChatSettings(chatID)
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select chat_id, link_preview, summarize, follow_up, ifnull(greeting, ''), ifnull(sign_off, ''), ifnull(round_minutes, 0), ifnull(voice, 0), ifnull(simple_mode, 0), ifnull(tidy_text, 1), ifnull(quiet_from, 0), ifnull(quiet_to, 0), ifnull(group_report, 0), ifnull(date_format, ''), ifnull(week_start, 1), ifnull(hour12, 0), ifnull(push_only, 0) from chat_settings where chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if err := stmt.QueryRow(chatID).Scan(&s.ChatID, &s.LinkPreview, &s.Summarize, &s.FollowUp, &s.Greeting, &s.SignOff, &s.RoundMinutes, &s.Voice, &s.SimpleMode, &s.TidyText, &s.QuietFrom, &s.QuietTo, &s.GroupReport, &s.DateFormat, &s.WeekStart, &s.Hour12, &s.PushOnly); err != nil && err != sql.ErrNoRows {
+			log.Printf("*** Failed to select chat settings from local database: %s\n", err.Error())
+		}
+	}
+
+	d.RUnlock()
+
+	return s
+}
+
+// LinkSummary returns the cached summary of given url
+func (d *Database) LinkSummary(url string) (summary string, exists bool) {
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select summary from link_summaries where url = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if err := stmt.QueryRow(url).Scan(&summary); err == nil {
+			exists = true
+		} else if err != sql.ErrNoRows {
+			log.Printf("*** Failed to select link summary from local database: %s\n", err.Error())
+		}
+	}
+
+	d.RUnlock()
+
+	return summary, exists
+}
+
+// SaveLinkSummary caches the summary of given url
+func (d *Database) SaveLinkSummary(url, summary string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or replace into link_summaries(url, summary) values(?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(url, summary); err != nil {
+			log.Printf("*** Failed to save link summary into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
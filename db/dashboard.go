@@ -0,0 +1,129 @@
+package db
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// QueueFilter struct (for searching queue items)
+type QueueFilter struct {
+	ChatID    int64  // 0 for all chats
+	Delivered bool   // delivered items (history) or not
+	Search    string // substring of messages
+	Limit     int
+}
+
+// ChatStat struct (numbers of queue items of a chat)
+type ChatStat struct {
+	ChatID          int64     `json:"chat_id"`
+	Title           string    `json:"title,omitempty"`
+	IsActive        bool      `json:"is_active"`
+	NumPending      int       `json:"num_pending"`
+	NumDelivered    int       `json:"num_delivered"`
+	NumFailed       int       `json:"num_failed"`
+	LastDeliveredOn time.Time `json:"last_delivered_on,omitempty"`
+}
+
+// SearchQueueItems returns queue items matching given filter
+func (d *Database) SearchQueueItems(filter QueueFilter) []QueueItem {
+	queue := []QueueItem{}
+
+	conditions := []string{"delivered_on is null"}
+	order := "fire_on"
+	if filter.Delivered {
+		conditions = []string{"delivered_on is not null"}
+		order = "delivered_on desc"
+	}
+	args := []interface{}{}
+	if filter.ChatID != 0 {
+		conditions = append(conditions, "chat_id = ?")
+		args = append(args, filter.ChatID)
+	}
+	if filter.Search != "" {
+		conditions = append(conditions, "message like ?")
+		args = append(args, "%"+filter.Search+"%")
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 100
+	}
+	args = append(args, filter.Limit)
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select ` + queueItemColumns + `
+		from queue
+		where ` + strings.Join(conditions, " and ") + `
+		order by ` + order + `
+		limit ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(args...); err != nil {
+			log.Printf("*** Failed to select queue items from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			queue = scanQueueItems(rows)
+		}
+	}
+
+	d.RUnlock()
+
+	return queue
+}
+
+// ChatStats returns numbers of queue items of each chat
+//
+// (items which were tried maxNumTries times without success are counted as failed)
+func (d *Database) ChatStats(maxNumTries int) []ChatStat {
+	stats := []ChatStat{}
+	if maxNumTries <= 0 {
+		maxNumTries = DefaultMaxNumTries
+	}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select
+		queue.chat_id,
+		ifnull(chats.title, '') as title,
+		ifnull(chats.is_active, 0) as is_active,
+		sum(case when delivered_on is null and num_tries < ? then 1 else 0 end) as num_pending,
+		sum(case when delivered_on is not null then 1 else 0 end) as num_delivered,
+		sum(case when delivered_on is null and num_tries >= ? then 1 else 0 end) as num_failed,
+		ifnull(max(delivered_on), 0) as last_delivered_on
+		from queue
+		left join chats on chats.chat_id = queue.chat_id
+		group by queue.chat_id
+		order by last_delivered_on desc`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(maxNumTries, maxNumTries); err != nil {
+			log.Printf("*** Failed to select chat stats from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var stat ChatStat
+			var lastDeliveredOn int64
+			for rows.Next() {
+				if err := rows.Scan(&stat.ChatID, &stat.Title, &stat.IsActive, &stat.NumPending, &stat.NumDelivered, &stat.NumFailed, &lastDeliveredOn); err != nil {
+					log.Printf("*** Failed to scan chat stat: %s\n", err.Error())
+					continue
+				}
+				stat.LastDeliveredOn = time.Time{}
+				if lastDeliveredOn > 0 {
+					stat.LastDeliveredOn = time.Unix(lastDeliveredOn, 0)
+				}
+
+				stats = append(stats, stat)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return stats
+}
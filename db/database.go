@@ -2,7 +2,9 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,7 +12,46 @@ import (
 )
 
 const (
-	defaultMaxNumTries = 10
+	DefaultMaxNumTries = 10
+)
+
+// kinds of queue items which need special handling on delivery
+const (
+	QueueKindReminder = ""         // ordinary reminder
+	QueueKindPomodoro = "pomodoro" // end of a pomodoro phase
+
+	QueueKindMedication       = "medication"        // dose time of a medication (ref_id: medication id)
+	QueueKindMedicationNag    = "medication-nag"    // nagging for a dose not taken yet (ref_id: dose id)
+	QueueKindMedicationReport = "medication-report" // weekly adherence report
+
+	QueueKindHabit        = "habit"         // daily prompt of a habit (ref_id: habit id)
+	QueueKindHabitSummary = "habit-summary" // weekly streak summary
+
+	QueueKindGroupNag = "group-nag" // nagging for a group reminder not acknowledged yet (ref_id: queue id of the reminder)
+
+	QueueKindPoll       = "poll"        // native poll instead of a message (ref_id: poll id)
+	QueueKindPollResult = "poll-result" // closing a poll and reporting its results (ref_id: poll id)
+
+	QueueKindFocusDigest = "focus-digest" // end of a focus session, with reminders deferred during it (ref_id: suppression id)
+
+	QueueKindGroupReport = "group-report" // daily report of a group's reminders, sent to the group's admins
+
+	QueueKindTopicPost = "topic-post" // message published to a topic, broadcasted to its subscribers (ref_id: topic id)
+)
+
+// policies for queue items which failed to be delivered after all retries
+const (
+	FailurePolicyGiveUp       = ""         // give up silently
+	FailurePolicyRetryNextDay = "retry"    // retry once more on the next day
+	FailurePolicyEscalate     = "escalate" // notify through alternate channels (email/webhook)
+	FailurePolicySMS          = "sms"      // send the reminder as an SMS to the verified phone number of the chat
+)
+
+// reasons of pausing queue items
+const (
+	NotPaused          = 0
+	PausedByUser       = 1
+	PausedByBotRemoval = 2 // the bot was removed from the group
 )
 
 // Database struct
@@ -28,77 +69,752 @@ type Log struct {
 
 // QueueItem struct
 type QueueItem struct {
-	ID          int64     `json:"id"`
-	ChatID      int64     `json:"chat_id"`
-	Message     string    `json:"message"`
-	EnqueuedOn  time.Time `json:"enqueued_on"`
-	FireOn      time.Time `json:"fire_on"`
-	DeliveredOn time.Time `json:"delivered_on,omitempty"`
-	NumTries    int       `json:"num_tries"`
+	ID            int64     `json:"id"`
+	ChatID        int64     `json:"chat_id"`
+	UserID        int64     `json:"user_id,omitempty"`
+	Message       string    `json:"message"`
+	EnqueuedOn    time.Time `json:"enqueued_on"`
+	FireOn        time.Time `json:"fire_on"`
+	DeliveredOn   time.Time `json:"delivered_on,omitempty"`
+	NumTries      int       `json:"num_tries"`
+	Paused        int       `json:"paused,omitempty"`
+	Broadcast     bool      `json:"broadcast,omitempty"`
+	Recurrence    string    `json:"recurrence,omitempty"`
+	FailurePolicy string    `json:"failure_policy,omitempty"`
+	Kind          string    `json:"kind,omitempty"`
+	RefID         int64     `json:"ref_id,omitempty"`   // id of the related object (by kind)
+	Assignee      string    `json:"assignee,omitempty"` // username of the member in charge (in groups)
+	AckedBy       int64     `json:"acked_by,omitempty"` // id of the member who marked it as done (in groups)
+	AckedOn       time.Time `json:"acked_on,omitempty"`
+	LastError     string    `json:"last_error,omitempty"`      // error of the last failed delivery
+	LastErrorCode int       `json:"last_error_code,omitempty"` // http status code of the last failed delivery
+	LastErrorOn   time.Time `json:"last_error_on,omitempty"`
+	MessageChatID int64     `json:"message_chat_id,omitempty"` // chat of the delivered message (can differ from ChatID, eg. routed by category)
+	MessageID     int64     `json:"message_id,omitempty"`      // id of the delivered message
+	Private       bool      `json:"private,omitempty"`         // content is delivered to the creator's private chat (in groups)
+
+	BusinessConnectionID string `json:"business_connection_id,omitempty"` // delivered on behalf of the owner of this business connection
+	Attachment           string `json:"attachment,omitempty"`             // key of the attached file in the blob storage
+
+	ScheduledOn time.Time `json:"scheduled_on,omitempty"` // nominal fire time (kept when fire_on is deferred, eg. by vacations or retries)
 }
 
 var _db *Database = nil
 
 func OpenDb(filepath string) *Database {
 	if _db == nil {
-		if db, err := sql.Open("sqlite3", filepath); err != nil {
+		if db, err := sql.Open(instrumentedDriverName, filepath); err != nil {
 			panic("Failed to open database: " + err.Error())
 		} else {
 			_db = &Database{
 				db: db,
 			}
 
-			// logs table
-			if _, err := db.Exec(`create table if not exists logs(
-				id integer primary key autoincrement,
-				type text default null,
-				message text not null,
-				time integer default (strftime('%s', 'now'))
-			)`); err != nil {
-				panic("Failed to create logs table: " + err.Error())
-			}
+			createTables(db)
+		}
+	}
 
-			// queue table
-			if _, err := db.Exec(`create table if not exists queue(
-				id integer primary key autoincrement,
-				chat_id integer not null,
-				message text not null,
-				enqueued_on integer default (strftime('%s', 'now')),
-				fire_on integer not null,
-				delivered_on integer default null,
-				num_tries integer default 0
-			)`); err != nil {
-				panic("Failed to create queue table: " + err.Error())
-			}
-			if _, err := db.Exec(`create index if not exists idx_queue1 on queue(
-				chat_id, delivered_on
-			)`); err != nil {
-				panic("Failed to create idx_queue1: " + err.Error())
-			}
-			if _, err := db.Exec(`create index if not exists idx_queue2 on queue(
-				enqueued_on, delivered_on
-			)`); err != nil {
-				panic("Failed to create idx_queue2: " + err.Error())
-			}
-			if _, err := db.Exec(`create index if not exists idx_queue3 on queue(
-				enqueued_on, delivered_on, num_tries
-			)`); err != nil {
-				panic("Failed to create idx_queue3: " + err.Error())
-			}
-			if _, err := db.Exec(`create index if not exists idx_queue4 on queue(
-				chat_id, delivered_on, enqueued_on
-			)`); err != nil {
-				panic("Failed to create idx_queue4: " + err.Error())
+	return _db
+}
+
+// create tables (and add missing columns) in given database
+func createTables(db *sql.DB) {
+	// logs table
+	if _, err := db.Exec(`create table if not exists logs(
+		id integer primary key autoincrement,
+		type text default null,
+		message text not null,
+		time integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create logs table: " + err.Error())
+	}
+
+	// queue table
+	if _, err := db.Exec(`create table if not exists queue(
+		id integer primary key autoincrement,
+		chat_id integer not null,
+		message text not null,
+		enqueued_on integer default (strftime('%s', 'now')),
+		fire_on integer not null,
+		delivered_on integer default null,
+		num_tries integer default 0
+	)`); err != nil {
+		panic("Failed to create queue table: " + err.Error())
+	}
+	if _, err := db.Exec(`create index if not exists idx_queue1 on queue(
+		chat_id, delivered_on
+	)`); err != nil {
+		panic("Failed to create idx_queue1: " + err.Error())
+	}
+	if _, err := db.Exec(`create index if not exists idx_queue2 on queue(
+		enqueued_on, delivered_on
+	)`); err != nil {
+		panic("Failed to create idx_queue2: " + err.Error())
+	}
+	if _, err := db.Exec(`create index if not exists idx_queue3 on queue(
+		enqueued_on, delivered_on, num_tries
+	)`); err != nil {
+		panic("Failed to create idx_queue3: " + err.Error())
+	}
+	if _, err := db.Exec(`create index if not exists idx_queue4 on queue(
+		chat_id, delivered_on, enqueued_on
+	)`); err != nil {
+		panic("Failed to create idx_queue4: " + err.Error())
+	}
+	if _, err := db.Exec(`create index if not exists idx_queue5 on queue(
+		enqueued_on, delivered_on, num_tries, fire_on
+	)`); err != nil {
+		panic("Failed to create idx_queue5: " + err.Error())
+	}
+
+	// columns added to queue table later
+	addColumnIfMissing(db, "queue", "user_id", "integer default null")
+	addColumnIfMissing(db, "queue", "paused", "integer default 0")
+	addColumnIfMissing(db, "queue", "broadcast", "integer default 0")
+	addColumnIfMissing(db, "queue", "recurrence", "text default null")
+	addColumnIfMissing(db, "queue", "failure_policy", "text default null")
+	addColumnIfMissing(db, "queue", "kind", "text default null")
+	addColumnIfMissing(db, "queue", "ref_id", "integer default 0")
+	addColumnIfMissing(db, "queue", "assignee", "text default null")
+	addColumnIfMissing(db, "queue", "acked_by", "integer default 0")
+	addColumnIfMissing(db, "queue", "acked_on", "integer default null")
+	addColumnIfMissing(db, "queue", "last_error", "text default null")
+	addColumnIfMissing(db, "queue", "last_error_code", "integer default 0")
+	addColumnIfMissing(db, "queue", "last_error_on", "integer default null")
+	addColumnIfMissing(db, "queue", "delivery_latency_ms", "integer default null")
+	addColumnIfMissing(db, "queue", "message_chat_id", "integer default 0")
+	addColumnIfMissing(db, "queue", "message_id", "integer default 0")
+	addColumnIfMissing(db, "queue", "private", "integer default 0")
+	addColumnIfMissing(db, "queue", "business_connection_id", "text default null")
+	addColumnIfMissing(db, "queue", "attachment", "text default null")
+	addColumnIfMissing(db, "queue", "scheduled_on", "integer default null")
+
+	// bans table
+	if _, err := db.Exec(`create table if not exists bans(
+		user_id integer primary key,
+		username text default null,
+		reason text default null,
+		num_bans integer default 0,
+		banned_until integer not null,
+		banned_on integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create bans table: " + err.Error())
+	}
+
+	// api keys table
+	if _, err := db.Exec(`create table if not exists api_keys(
+		chat_id integer primary key,
+		token_hash text not null unique,
+		created_on integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create api_keys table: " + err.Error())
+	}
+
+	// aliases table (personal shortcuts of commands)
+	if _, err := db.Exec(`create table if not exists aliases(
+		chat_id integer not null,
+		alias text not null,
+		command text not null,
+		primary key(chat_id, alias)
+	)`); err != nil {
+		panic("Failed to create aliases table: " + err.Error())
+	}
+
+	// users table
+	if _, err := db.Exec(`create table if not exists users(
+		user_id integer primary key,
+		username text default null,
+		first_name text not null,
+		last_name text default null,
+		allowed_as text default null,
+		first_seen_on integer default (strftime('%s', 'now')),
+		last_seen_on integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create users table: " + err.Error())
+	}
+	if _, err := db.Exec(`create index if not exists idx_users1 on users(
+		username
+	)`); err != nil {
+		panic("Failed to create idx_users1: " + err.Error())
+	}
+
+	// chats table
+	if _, err := db.Exec(`create table if not exists chats(
+		chat_id integer primary key,
+		type text not null,
+		title text default null,
+		is_active integer default 1,
+		first_seen_on integer default (strftime('%s', 'now')),
+		last_seen_on integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create chats table: " + err.Error())
+	}
+
+	// suppressions table
+	if _, err := db.Exec(`create table if not exists suppressions(
+		id integer primary key autoincrement,
+		chat_id integer not null,
+		kind text not null,
+		mode text not null,
+		starts_on integer not null,
+		ends_on integer not null,
+		created_on integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create suppressions table: " + err.Error())
+	}
+	if _, err := db.Exec(`create index if not exists idx_suppressions1 on suppressions(
+		chat_id, starts_on, ends_on
+	)`); err != nil {
+		panic("Failed to create idx_suppressions1: " + err.Error())
+	}
+
+	// suggestions table
+	if _, err := db.Exec(`create table if not exists suggestions(
+		id integer primary key autoincrement,
+		chat_id integer not null,
+		message text not null,
+		suggested_on integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create suggestions table: " + err.Error())
+	}
+	if _, err := db.Exec(`create index if not exists idx_suggestions1 on suggestions(
+		chat_id, message, suggested_on
+	)`); err != nil {
+		panic("Failed to create idx_suggestions1: " + err.Error())
+	}
+
+	// ddays table
+	if _, err := db.Exec(`create table if not exists ddays(
+		id integer primary key autoincrement,
+		chat_id integer not null,
+		user_id integer default 0,
+		name text not null,
+		event_on integer not null,
+		created_on integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create ddays table: " + err.Error())
+	}
+	if _, err := db.Exec(`create index if not exists idx_ddays1 on ddays(
+		chat_id, event_on
+	)`); err != nil {
+		panic("Failed to create idx_ddays1: " + err.Error())
+	}
+
+	// event_templates table (for webhook events)
+	if _, err := db.Exec(`create table if not exists event_templates(
+		chat_id integer not null,
+		name text not null,
+		template text not null,
+		created_on integer default (strftime('%s', 'now')),
+		primary key(chat_id, name)
+	)`); err != nil {
+		panic("Failed to create event_templates table: " + err.Error())
+	}
+
+	// feeds table
+	if _, err := db.Exec(`create table if not exists feeds(
+		id integer primary key autoincrement,
+		chat_id integer not null,
+		url text not null,
+		title text default null,
+		added_on integer default (strftime('%s', 'now')),
+		checked_on integer default null,
+		unique(chat_id, url)
+	)`); err != nil {
+		panic("Failed to create feeds table: " + err.Error())
+	}
+
+	// feed_items table (for deduplicating entries of feeds)
+	if _, err := db.Exec(`create table if not exists feed_items(
+		feed_id integer not null,
+		guid text not null,
+		seen_on integer default (strftime('%s', 'now')),
+		primary key(feed_id, guid)
+	)`); err != nil {
+		panic("Failed to create feed_items table: " + err.Error())
+	}
+
+	// pomodoros table (current pomodoro session of each chat)
+	if _, err := db.Exec(`create table if not exists pomodoros(
+		chat_id integer primary key,
+		user_id integer default 0,
+		work_minutes integer not null,
+		break_minutes integer not null,
+		phase text not null,
+		paused integer default 0,
+		queue_id integer default 0,
+		remaining_seconds integer default 0,
+		started_on integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create pomodoros table: " + err.Error())
+	}
+
+	// pomodoro_logs table (completed pomodoros)
+	if _, err := db.Exec(`create table if not exists pomodoro_logs(
+		id integer primary key autoincrement,
+		chat_id integer not null,
+		completed_on integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create pomodoro_logs table: " + err.Error())
+	}
+	if _, err := db.Exec(`create index if not exists idx_pomodoro_logs1 on pomodoro_logs(
+		chat_id, completed_on
+	)`); err != nil {
+		panic("Failed to create idx_pomodoro_logs1: " + err.Error())
+	}
+
+	// medications table
+	if _, err := db.Exec(`create table if not exists medications(
+		id integer primary key autoincrement,
+		chat_id integer not null,
+		user_id integer default 0,
+		name text not null,
+		times text not null,
+		starts_on integer default (strftime('%s', 'now')),
+		ends_on integer default null,
+		is_active integer default 1
+	)`); err != nil {
+		panic("Failed to create medications table: " + err.Error())
+	}
+
+	// medication_doses table (for tracking adherence)
+	if _, err := db.Exec(`create table if not exists medication_doses(
+		id integer primary key autoincrement,
+		medication_id integer not null,
+		chat_id integer not null,
+		due_on integer not null,
+		taken_on integer default null,
+		unique(medication_id, due_on)
+	)`); err != nil {
+		panic("Failed to create medication_doses table: " + err.Error())
+	}
+
+	// habits table
+	if _, err := db.Exec(`create table if not exists habits(
+		id integer primary key autoincrement,
+		chat_id integer not null,
+		user_id integer default 0,
+		name text not null,
+		time text not null,
+		is_active integer default 1,
+		created_on integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create habits table: " + err.Error())
+	}
+
+	// habit_checks table (done or not, of each member for each day)
+	if _, err := db.Exec(`create table if not exists habit_checks(
+		habit_id integer not null,
+		user_id integer not null,
+		day text not null,
+		done integer not null,
+		checked_on integer default (strftime('%s', 'now')),
+		primary key(habit_id, user_id, day)
+	)`); err != nil {
+		panic("Failed to create habit_checks table: " + err.Error())
+	}
+
+	// habit_leaderboards table (members of group chats who opted in to the leaderboard)
+	if _, err := db.Exec(`create table if not exists habit_leaderboards(
+		chat_id integer not null,
+		user_id integer not null,
+		joined_on integer default (strftime('%s', 'now')),
+		primary key(chat_id, user_id)
+	)`); err != nil {
+		panic("Failed to create habit_leaderboards table: " + err.Error())
+	}
+
+	// audit_logs table (actions of operators)
+	if _, err := db.Exec(`create table if not exists audit_logs(
+		id integer primary key autoincrement,
+		actor text not null,
+		action text not null,
+		chat_id integer default 0,
+		target text default null,
+		detail text default null,
+		time integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create audit_logs table: " + err.Error())
+	}
+
+	// chat_settings table (per-chat options)
+	if _, err := db.Exec(`create table if not exists chat_settings(
+		chat_id integer primary key,
+		link_preview integer default 1
+	)`); err != nil {
+		panic("Failed to create chat_settings table: " + err.Error())
+	}
+	addColumnIfMissing(db, "chat_settings", "summarize", "integer default 0")
+	addColumnIfMissing(db, "chat_settings", "follow_up", "integer default 0")
+	addColumnIfMissing(db, "chat_settings", "greeting", "text default ''")
+	addColumnIfMissing(db, "chat_settings", "sign_off", "text default ''")
+	addColumnIfMissing(db, "chat_settings", "round_minutes", "integer default 0")
+	addColumnIfMissing(db, "chat_settings", "voice", "integer default 0")
+	addColumnIfMissing(db, "chat_settings", "simple_mode", "integer default 0")
+	addColumnIfMissing(db, "chat_settings", "tidy_text", "integer default 1")
+	addColumnIfMissing(db, "chat_settings", "quiet_from", "integer default 0")
+	addColumnIfMissing(db, "chat_settings", "quiet_to", "integer default 0")
+	addColumnIfMissing(db, "chat_settings", "group_report", "integer default 0")
+	addColumnIfMissing(db, "chat_settings", "date_format", "text default ''")
+	addColumnIfMissing(db, "chat_settings", "week_start", "integer default 1")
+	addColumnIfMissing(db, "chat_settings", "hour12", "integer default 0")
+	addColumnIfMissing(db, "chat_settings", "push_only", "integer default 0")
+
+	// link_summaries table (cache of summaries of linked pages)
+	if _, err := db.Exec(`create table if not exists link_summaries(
+		url text primary key,
+		summary text not null,
+		created_on integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create link_summaries table: " + err.Error())
+	}
+
+	// polls table (options of polls sent instead of reminder messages)
+	if _, err := db.Exec(`create table if not exists polls(
+		id integer primary key autoincrement,
+		chat_id integer not null,
+		question text not null,
+		options text not null,
+		message_id integer default 0,
+		closes_on integer default null
+	)`); err != nil {
+		panic("Failed to create polls table: " + err.Error())
+	}
+
+	// chains table (reminders scheduled after their parent reminders are acknowledged)
+	if _, err := db.Exec(`create table if not exists chains(
+		id integer primary key autoincrement,
+		parent_id integer not null,
+		chat_id integer not null,
+		user_id integer default 0,
+		message text not null,
+		delay_minutes integer default 0,
+		created_on integer default (strftime('%s', 'now')),
+		triggered_on integer default null
+	)`); err != nil {
+		panic("Failed to create chains table: " + err.Error())
+	}
+	if _, err := db.Exec(`create index if not exists idx_chains1 on chains(
+		chat_id, parent_id
+	)`); err != nil {
+		panic("Failed to create idx_chains1: " + err.Error())
+	}
+
+	// follow_ups table (answers to follow-up questions after delivery)
+	if _, err := db.Exec(`create table if not exists follow_ups(
+		id integer primary key autoincrement,
+		queue_id integer not null,
+		chat_id integer not null,
+		user_id integer default 0,
+		message text not null,
+		done integer not null,
+		answered_on integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create follow_ups table: " + err.Error())
+	}
+	if _, err := db.Exec(`create index if not exists idx_follow_ups1 on follow_ups(
+		chat_id, message
+	)`); err != nil {
+		panic("Failed to create idx_follow_ups1: " + err.Error())
+	}
+
+	// flags table (reminders flagged by content rules, for admins' review)
+	if _, err := db.Exec(`create table if not exists flags(
+		id integer primary key autoincrement,
+		queue_id integer not null,
+		chat_id integer not null,
+		user_id integer default 0,
+		message text not null,
+		rule text not null,
+		flagged_on integer default (strftime('%s', 'now')),
+		reviewed_on integer default null
+	)`); err != nil {
+		panic("Failed to create flags table: " + err.Error())
+	}
+
+	// feedbacks table (feedbacks of users, and their forwarded messages in admins' chats)
+	if _, err := db.Exec(`create table if not exists feedbacks(
+		id integer primary key autoincrement,
+		chat_id integer not null,
+		user_id integer not null,
+		username text default null,
+		message text not null,
+		created_on integer default (strftime('%s', 'now')),
+		answered_on integer default null
+	)`); err != nil {
+		panic("Failed to create feedbacks table: " + err.Error())
+	}
+	if _, err := db.Exec(`create table if not exists feedback_forwards(
+		admin_chat_id integer not null,
+		message_id integer not null,
+		feedback_id integer not null,
+		primary key(admin_chat_id, message_id)
+	)`); err != nil {
+		panic("Failed to create feedback_forwards table: " + err.Error())
+	}
+
+	// training_data table (misparsed queries reported by users, for improving the agent)
+	if _, err := db.Exec(`create table if not exists training_data(
+		id integer primary key autoincrement,
+		chat_id integer not null,
+		user_id integer not null,
+		query text not null,
+		response text not null,
+		correction text default null,
+		created_on integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create training_data table: " + err.Error())
+	}
+	addColumnIfMissing(db, "training_data", "source", "text default 'misparse'")
+	addColumnIfMissing(db, "training_data", "score", "real default null")
+
+	// category_routes table (chats where reminders of categories are delivered)
+	if _, err := db.Exec(`create table if not exists category_routes(
+		chat_id integer not null,
+		category text not null,
+		target_chat_id integer not null,
+		created_on integer default (strftime('%s', 'now')),
+		primary key(chat_id, category)
+	)`); err != nil {
+		panic("Failed to create category_routes table: " + err.Error())
+	}
+
+	// delivered_messages table (messages of delivered queue items, for resolving replies, reactions, edits, and pins)
+	if _, err := db.Exec(`create table if not exists delivered_messages(
+		chat_id integer not null,
+		message_id integer not null,
+		queue_chat_id integer not null,
+		queue_id integer not null,
+		delivered_on integer default (strftime('%s', 'now')),
+		primary key(chat_id, message_id)
+	)`); err != nil {
+		panic("Failed to create delivered_messages table: " + err.Error())
+	}
+	if _, err := db.Exec(`create index if not exists idx_delivered_messages1 on delivered_messages(
+		queue_chat_id, queue_id
+	)`); err != nil {
+		panic("Failed to create idx_delivered_messages1: " + err.Error())
+	}
+
+	// business_connections table (telegram business accounts the bot is attached to, with permissions given by their owners)
+	if _, err := db.Exec(`create table if not exists business_connections(
+		id text primary key,
+		user_id integer not null,
+		user_chat_id integer not null,
+		can_reply integer default 0,
+		is_enabled integer default 0,
+		can_schedule integer default 0,
+		created_on integer default (strftime('%s', 'now')),
+		updated_on integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create business_connections table: " + err.Error())
+	}
+
+	// broadcasts table (messages sent to many chats, with their recipients for resuming after restarts)
+	if _, err := db.Exec(`create table if not exists broadcasts(
+		id integer primary key autoincrement,
+		queue_chat_id integer not null,
+		queue_id integer not null,
+		message text not null,
+		status text not null,
+		created_on integer default (strftime('%s', 'now')),
+		finished_on integer default null
+	)`); err != nil {
+		panic("Failed to create broadcasts table: " + err.Error())
+	}
+	if _, err := db.Exec(`create table if not exists broadcast_recipients(
+		broadcast_id integer not null,
+		chat_id integer not null,
+		status text not null,
+		num_tries integer default 0,
+		last_error text default null,
+		updated_on integer default (strftime('%s', 'now')),
+		primary key(broadcast_id, chat_id)
+	)`); err != nil {
+		panic("Failed to create broadcast_recipients table: " + err.Error())
+	}
+
+	// integrations table (task apps which reminders are mirrored to, with encrypted tokens)
+	if _, err := db.Exec(`create table if not exists integrations(
+		chat_id integer not null,
+		provider text not null,
+		user_id integer not null,
+		token text not null,
+		created_on integer default (strftime('%s', 'now')),
+		primary key(chat_id, provider)
+	)`); err != nil {
+		panic("Failed to create integrations table: " + err.Error())
+	}
+	if _, err := db.Exec(`create table if not exists integration_tasks(
+		queue_chat_id integer not null,
+		queue_id integer not null,
+		provider text not null,
+		task_id text not null,
+		created_on integer default (strftime('%s', 'now')),
+		primary key(queue_chat_id, queue_id, provider)
+	)`); err != nil {
+		panic("Failed to create integration_tasks table: " + err.Error())
+	}
+
+	// phone_numbers table (for SMS fallbacks of failed reminders)
+	if _, err := db.Exec(`create table if not exists phone_numbers(
+		chat_id integer primary key,
+		user_id integer not null,
+		number text not null,
+		is_verified integer default 0,
+		code_hash text default null,
+		code_sent_on integer default (strftime('%s', 'now')),
+		num_attempts integer default 0,
+		registered_on integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create phone_numbers table: " + err.Error())
+	}
+
+	// push_tokens table (devices of companion apps)
+	if _, err := db.Exec(`create table if not exists push_tokens(
+		id integer primary key autoincrement,
+		chat_id integer not null,
+		platform text not null,
+		token text not null,
+		name text default null,
+		registered_on integer default (strftime('%s', 'now')),
+		unique(platform, token)
+	)`); err != nil {
+		panic("Failed to create push_tokens table: " + err.Error())
+	}
+	if _, err := db.Exec(`create index if not exists idx_push_tokens1 on push_tokens(
+		chat_id
+	)`); err != nil {
+		panic("Failed to create idx_push_tokens1: " + err.Error())
+	}
+
+	// page_watches table (web pages watched for changes)
+	if _, err := db.Exec(`create table if not exists page_watches(
+		id integer primary key autoincrement,
+		chat_id integer not null,
+		url text not null,
+		selector text not null default '',
+		title text default null,
+		interval_minutes integer not null,
+		content_hash text default null,
+		num_failures integer default 0,
+		next_check_on integer not null,
+		changed_on integer default null,
+		added_on integer default (strftime('%s', 'now')),
+		unique(chat_id, url, selector)
+	)`); err != nil {
+		panic("Failed to create page_watches table: " + err.Error())
+	}
+	if _, err := db.Exec(`create index if not exists idx_page_watches1 on page_watches(
+		next_check_on
+	)`); err != nil {
+		panic("Failed to create idx_page_watches1: " + err.Error())
+	}
+
+	// price_alerts table (threshold alerts of prices)
+	if _, err := db.Exec(`create table if not exists price_alerts(
+		id integer primary key autoincrement,
+		chat_id integer not null,
+		user_id integer not null,
+		source text not null,
+		symbol text not null,
+		name text not null,
+		above integer not null,
+		threshold real not null,
+		last_value real not null,
+		added_on integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create price_alerts table: " + err.Error())
+	}
+
+	// public_events table (operator-curated events which chats can subscribe to)
+	if _, err := db.Exec(`create table if not exists public_events(
+		id integer primary key autoincrement,
+		name text not null,
+		event_on integer not null,
+		offsets text not null,
+		created_by integer not null,
+		created_on integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create public_events table: " + err.Error())
+	}
+
+	// public_event_subscriptions table
+	if _, err := db.Exec(`create table if not exists public_event_subscriptions(
+		event_id integer not null,
+		chat_id integer not null,
+		user_id integer not null,
+		subscribed_on integer default (strftime('%s', 'now')),
+		primary key(event_id, chat_id)
+	)`); err != nil {
+		panic("Failed to create public_event_subscriptions table: " + err.Error())
+	}
+
+	// public_event_fanouts table (for not fanning out countdowns of public events twice)
+	if _, err := db.Exec(`create table if not exists public_event_fanouts(
+		event_id integer not null,
+		days integer not null,
+		fanned_out_on integer default (strftime('%s', 'now')),
+		primary key(event_id, days)
+	)`); err != nil {
+		panic("Failed to create public_event_fanouts table: " + err.Error())
+	}
+
+	// topics table (named channels which chats subscribe to, for messages published by admins or authorized publishers)
+	if _, err := db.Exec(`create table if not exists topics(
+		id integer primary key autoincrement,
+		name text not null unique,
+		created_by integer not null,
+		created_on integer default (strftime('%s', 'now'))
+	)`); err != nil {
+		panic("Failed to create topics table: " + err.Error())
+	}
+
+	// topic_publishers table (users who can publish to topics, other than admins)
+	if _, err := db.Exec(`create table if not exists topic_publishers(
+		topic_id integer not null,
+		user_id integer not null,
+		added_on integer default (strftime('%s', 'now')),
+		primary key(topic_id, user_id)
+	)`); err != nil {
+		panic("Failed to create topic_publishers table: " + err.Error())
+	}
+
+	// topic_subscriptions table
+	if _, err := db.Exec(`create table if not exists topic_subscriptions(
+		topic_id integer not null,
+		chat_id integer not null,
+		user_id integer not null,
+		subscribed_on integer default (strftime('%s', 'now')),
+		primary key(topic_id, chat_id)
+	)`); err != nil {
+		panic("Failed to create topic_subscriptions table: " + err.Error())
+	}
+}
+
+// add a column to given table if it does not exist yet (for databases created with older versions)
+func addColumnIfMissing(db *sql.DB, table, column, definition string) {
+	if rows, err := db.Query(fmt.Sprintf(`pragma table_info(%s)`, table)); err != nil {
+		panic("Failed to get table info of " + table + ": " + err.Error())
+	} else {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+
+		exists := false
+		for rows.Next() {
+			if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err == nil && name == column {
+				exists = true
 			}
-			if _, err := db.Exec(`create index if not exists idx_queue5 on queue(
-				enqueued_on, delivered_on, num_tries, fire_on
-			)`); err != nil {
-				panic("Failed to create idx_queue5: " + err.Error())
+		}
+		rows.Close()
+
+		if !exists {
+			if _, err := db.Exec(fmt.Sprintf(`alter table %s add column %s %s`, table, column, definition)); err != nil {
+				panic("Failed to add column " + column + " to " + table + ": " + err.Error())
 			}
 		}
 	}
-
-	return _db
 }
 
 func CloseDb() {
@@ -165,70 +881,151 @@ func (d *Database) GetLogs(latestN int) []Log {
 	return logs
 }
 
+// Enqueue saves a new reminder
 func (d *Database) Enqueue(chatID int64, message string, fireOn time.Time) bool {
-	result := false
+	_, result := d.EnqueueItem(QueueItem{
+		ChatID:  chatID,
+		Message: message,
+		FireOn:  fireOn,
+	})
+
+	return result
+}
 
+// EnqueueItem saves a new reminder with the values of given item, and returns its id
+func (d *Database) EnqueueItem(item QueueItem) (queueID int64, result bool) {
 	d.Lock()
 
-	if stmt, err := d.db.Prepare(`insert or ignore into queue(chat_id, message, fire_on) values(?, ?, ?)`); err != nil {
+	if stmt, err := d.db.Prepare(`insert or ignore into queue(chat_id, user_id, message, fire_on, broadcast, recurrence, failure_policy, kind, ref_id, assignee, private, business_connection_id, attachment, scheduled_on) values(?, nullif(?, 0), ?, ?, ?, nullif(?, ''), nullif(?, ''), nullif(?, ''), ?, nullif(?, ''), ?, nullif(?, ''), nullif(?, ''), nullif(?, 0))`); err != nil {
 		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
 	} else {
 		defer stmt.Close()
 
-		if _, err = stmt.Exec(chatID, message, fireOn.Unix()); err != nil {
+		// (null when not given: falls back to fire_on)
+		var scheduledOn int64
+		if !item.ScheduledOn.IsZero() {
+			scheduledOn = item.ScheduledOn.Unix()
+		}
+
+		var res sql.Result
+		if res, err = stmt.Exec(item.ChatID, item.UserID, item.Message, item.FireOn.Unix(), item.Broadcast, item.Recurrence, item.FailurePolicy, item.Kind, item.RefID, item.Assignee, item.Private, item.BusinessConnectionID, item.Attachment, scheduledOn); err != nil {
 			log.Printf("*** Failed to save queue item into local database: %s\n", err.Error())
 		} else {
+			queueID, _ = res.LastInsertId()
 			result = true
 		}
 	}
 
 	d.Unlock()
 
-	return result
+	return queueID, result
+}
+
+// columns for selecting queue items (should match scanQueueItems)
+const queueItemColumns = `id,
+		chat_id,
+		ifnull(user_id, 0) as user_id,
+		message,
+		enqueued_on,
+		fire_on,
+		ifnull(delivered_on, 0) as delivered_on,
+		num_tries,
+		paused,
+		broadcast,
+		ifnull(recurrence, '') as recurrence,
+		ifnull(failure_policy, '') as failure_policy,
+		ifnull(kind, '') as kind,
+		ifnull(ref_id, 0) as ref_id,
+		ifnull(assignee, '') as assignee,
+		ifnull(acked_by, 0) as acked_by,
+		ifnull(acked_on, 0) as acked_on,
+		ifnull(last_error, '') as last_error,
+		ifnull(last_error_code, 0) as last_error_code,
+		ifnull(last_error_on, 0) as last_error_on,
+		ifnull(message_chat_id, 0) as message_chat_id,
+		ifnull(message_id, 0) as message_id,
+		ifnull(private, 0) as private,
+		ifnull(business_connection_id, '') as business_connection_id,
+		ifnull(attachment, '') as attachment,
+		ifnull(scheduled_on, fire_on) as scheduled_on`
+
+// scan rows selected with queueItemColumns
+func scanQueueItems(rows *sql.Rows) []QueueItem {
+	queue := []QueueItem{}
+
+	var id, chatID, userID, refID, ackedBy, messageChatID, messageID int64
+	var message, recurrence, failurePolicy, kind, assignee, lastError, businessConnectionID, attachment string
+	var enqueuedOn, fireOn, deliveredOn, ackedOn, lastErrorOn, scheduledOn int64
+	var numTries, paused, lastErrorCode int
+	var broadcast, private bool
+	for rows.Next() {
+		if err := rows.Scan(&id, &chatID, &userID, &message, &enqueuedOn, &fireOn, &deliveredOn, &numTries, &paused, &broadcast, &recurrence, &failurePolicy, &kind, &refID, &assignee, &ackedBy, &ackedOn, &lastError, &lastErrorCode, &lastErrorOn, &messageChatID, &messageID, &private, &businessConnectionID, &attachment, &scheduledOn); err != nil {
+			log.Printf("*** Failed to scan queue item: %s\n", err.Error())
+			continue
+		}
+
+		queue = append(queue, QueueItem{
+			ID:            id,
+			ChatID:        chatID,
+			UserID:        userID,
+			Message:       message,
+			EnqueuedOn:    time.Unix(enqueuedOn, 0),
+			FireOn:        time.Unix(fireOn, 0),
+			DeliveredOn:   time.Unix(deliveredOn, 0),
+			NumTries:      numTries,
+			Paused:        paused,
+			Broadcast:     broadcast,
+			Recurrence:    recurrence,
+			FailurePolicy: failurePolicy,
+			Kind:          kind,
+			RefID:         refID,
+			Assignee:      assignee,
+			AckedBy:       ackedBy,
+			AckedOn:       time.Unix(ackedOn, 0),
+			LastError:     lastError,
+			LastErrorCode: lastErrorCode,
+			LastErrorOn:   time.Unix(lastErrorOn, 0),
+			MessageChatID: messageChatID,
+			MessageID:     messageID,
+			Private:       private,
+
+			BusinessConnectionID: businessConnectionID,
+			Attachment:           attachment,
+
+			ScheduledOn: time.Unix(scheduledOn, 0),
+		})
+	}
+
+	return queue
 }
 
 func (d *Database) DeliverableQueueItems(maxNumTries int) []QueueItem {
+	return d.DeliverableQueueItemsUntil(maxNumTries, time.Now())
+}
+
+// DeliverableQueueItemsUntil returns queue items which will be deliverable until given time
+func (d *Database) DeliverableQueueItemsUntil(maxNumTries int, until time.Time) []QueueItem {
 	queue := []QueueItem{}
 	if maxNumTries <= 0 {
-		maxNumTries = defaultMaxNumTries
+		maxNumTries = DefaultMaxNumTries
 	}
 
 	d.RLock()
 
-	if stmt, err := d.db.Prepare(`select 
-		id,
-		chat_id, 
-		message, 
-		enqueued_on,
-		fire_on,
-		ifnull(delivered_on, 0) as delivered_on
+	if stmt, err := d.db.Prepare(`select ` + queueItemColumns + `
 		from queue
-		where delivered_on is null and num_tries < ? and fire_on <= ?
+		where delivered_on is null and paused = 0 and num_tries < ? and fire_on <= ?
 		order by enqueued_on desc`); err != nil {
 		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
 	} else {
 		defer stmt.Close()
 
-		if rows, err := stmt.Query(maxNumTries, time.Now().Unix()); err != nil {
+		if rows, err := stmt.Query(maxNumTries, until.Unix()); err != nil {
 			log.Printf("*** Failed to select queue items from local database: %s\n", err.Error())
 		} else {
 			defer rows.Close()
 
-			var id, chatID int64
-			var message string
-			var enqueuedOn, fireOn, deliveredOn int64
-			for rows.Next() {
-				rows.Scan(&id, &chatID, &message, &enqueuedOn, &fireOn, &deliveredOn)
-
-				queue = append(queue, QueueItem{
-					ID:          id,
-					ChatID:      chatID,
-					Message:     message,
-					EnqueuedOn:  time.Unix(enqueuedOn, 0),
-					FireOn:      time.Unix(fireOn, 0),
-					DeliveredOn: time.Unix(deliveredOn, 0),
-				})
-			}
+			queue = scanQueueItems(rows)
 		}
 	}
 
@@ -242,13 +1039,7 @@ func (d *Database) UndeliveredQueueItems(chatID int64) []QueueItem {
 
 	d.RLock()
 
-	if stmt, err := d.db.Prepare(`select 
-		id,
-		chat_id, 
-		message, 
-		enqueued_on,
-		fire_on,
-		ifnull(delivered_on, 0) as delivered_on
+	if stmt, err := d.db.Prepare(`select ` + queueItemColumns + `
 		from queue
 		where chat_id = ? and delivered_on is null
 		order by enqueued_on desc`); err != nil {
@@ -261,21 +1052,63 @@ func (d *Database) UndeliveredQueueItems(chatID int64) []QueueItem {
 		} else {
 			defer rows.Close()
 
-			var id, chatID int64
-			var message string
-			var enqueuedOn, fireOn, deliveredOn int64
-			for rows.Next() {
-				rows.Scan(&id, &chatID, &message, &enqueuedOn, &fireOn, &deliveredOn)
-
-				queue = append(queue, QueueItem{
-					ID:          id,
-					ChatID:      chatID,
-					Message:     message,
-					EnqueuedOn:  time.Unix(enqueuedOn, 0),
-					FireOn:      time.Unix(fireOn, 0),
-					DeliveredOn: time.Unix(deliveredOn, 0),
-				})
-			}
+			queue = scanQueueItems(rows)
+		}
+	}
+
+	d.RUnlock()
+
+	return queue
+}
+
+// UndeliveredQueueItemsOfUser returns undelivered queue items created by given user (or in the private chat of the user)
+func (d *Database) UndeliveredQueueItemsOfUser(userID int64) []QueueItem {
+	queue := []QueueItem{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select ` + queueItemColumns + `
+		from queue
+		where (user_id = ? or chat_id = ?) and delivered_on is null and broadcast = 0
+		order by fire_on asc`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(userID, userID); err != nil {
+			log.Printf("*** Failed to select queue items from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			queue = scanQueueItems(rows)
+		}
+	}
+
+	d.RUnlock()
+
+	return queue
+}
+
+// ScheduledQueueItems returns the reminders of given chat which are scheduled between given times (delivered or not)
+func (d *Database) ScheduledQueueItems(chatID int64, from, until time.Time) []QueueItem {
+	queue := []QueueItem{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select ` + queueItemColumns + `
+		from queue
+		where chat_id = ? and ifnull(kind, '') = ? and fire_on >= ? and fire_on < ?
+		order by fire_on`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID, QueueKindReminder, from.Unix(), until.Unix()); err != nil {
+			log.Printf("*** Failed to select queue items from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			queue = scanQueueItems(rows)
 		}
 	}
 
@@ -360,3 +1193,533 @@ func (d *Database) MarkQueueItemAsDelivered(chatID, queueID int64) bool {
 
 	return result
 }
+
+// Stats returns the statistics of the underlying database connections
+func (d *Database) Stats() sql.DBStats {
+	return d.db.Stats()
+}
+
+// Backup saves a snapshot of the database to given filepath
+func (d *Database) Backup(filepath string) bool {
+	result := false
+
+	d.RLock()
+
+	if _, err := d.db.Exec(`vacuum into ?`, filepath); err != nil {
+		log.Printf("*** Failed to backup local database: %s\n", err.Error())
+	} else {
+		result = true
+	}
+
+	d.RUnlock()
+
+	return result
+}
+
+// PauseQueueItems pauses all undelivered queue items of given chat with given reason
+func (d *Database) PauseQueueItems(chatID int64, reason int) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update queue set paused = ? where chat_id = ? and delivered_on is null and paused = 0`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(reason, chatID); err != nil {
+			log.Printf("*** Failed to pause queue items in local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// ResumeQueueItems resumes all undelivered queue items of given chat which were paused with given reason
+func (d *Database) ResumeQueueItems(chatID int64, reason int) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update queue set paused = 0 where chat_id = ? and delivered_on is null and paused = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(chatID, reason); err != nil {
+			log.Printf("*** Failed to resume queue items in local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// TransferPausedQueueItems moves given user's queue items, paused with given reason, to another chat and resumes them
+//
+// returns the number of moved items
+func (d *Database) TransferPausedQueueItems(fromChatID, userID, toChatID int64, reason int) (num int64) {
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update queue set chat_id = ?, paused = 0
+		where chat_id = ? and user_id = ? and delivered_on is null and paused = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(toChatID, fromChatID, userID, reason); err != nil {
+			log.Printf("*** Failed to transfer queue items in local database: %s\n", err.Error())
+		} else {
+			num, _ = res.RowsAffected()
+		}
+	}
+
+	d.Unlock()
+
+	return num
+}
+
+// MoveQueueItem moves given user's undelivered queue item in given chat to another chat
+func (d *Database) MoveQueueItem(fromChatID, queueID, userID, toChatID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update queue set chat_id = ?
+		where id = ? and chat_id = ? and user_id = ? and delivered_on is null`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(toChatID, queueID, fromChatID, userID); err != nil {
+			log.Printf("*** Failed to move queue item in local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// DeletePausedQueueItems deletes given user's queue items in given chat, paused with given reason
+func (d *Database) DeletePausedQueueItems(chatID, userID int64, reason int) (num int64) {
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`delete from queue
+		where chat_id = ? and user_id = ? and delivered_on is null and paused = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(chatID, userID, reason); err != nil {
+			log.Printf("*** Failed to delete queue items from local database: %s\n", err.Error())
+		} else {
+			num, _ = res.RowsAffected()
+		}
+	}
+
+	d.Unlock()
+
+	return num
+}
+
+// SetQueueItemPaused pauses (with given reason) or resumes (with NotPaused) given queue item
+func (d *Database) SetQueueItemPaused(chatID, queueID int64, reason int) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update queue set paused = ? where id = ? and chat_id = ? and delivered_on is null`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(reason, queueID, chatID); err != nil {
+			log.Printf("*** Failed to update paused in local database: %s\n", err.Error())
+		} else {
+			if num, _ := res.RowsAffected(); num <= 0 {
+				log.Printf("*** Failed to update paused for id: %d, chat_id: %d\n", queueID, chatID)
+			} else {
+				result = true
+			}
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// RescheduleQueueItem changes the fire time of given queue item
+// (defers it: its scheduled time, from which next occurrences are calculated, is kept)
+func (d *Database) RescheduleQueueItem(chatID, queueID int64, fireOn time.Time) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update queue set fire_on = ? where id = ? and chat_id = ? and delivered_on is null`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(fireOn.Unix(), queueID, chatID); err != nil {
+			log.Printf("*** Failed to update fire_on in local database: %s\n", err.Error())
+		} else {
+			if num, _ := res.RowsAffected(); num <= 0 {
+				log.Printf("*** Failed to update fire_on for id: %d, chat_id: %d\n", queueID, chatID)
+			} else {
+				result = true
+			}
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// ScheduleQueueItem changes both the fire time and the scheduled time of given queue item
+func (d *Database) ScheduleQueueItem(chatID, queueID int64, fireOn time.Time) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update queue set fire_on = ?, scheduled_on = ? where id = ? and chat_id = ? and delivered_on is null`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(fireOn.Unix(), fireOn.Unix(), queueID, chatID); err != nil {
+			log.Printf("*** Failed to update fire_on/scheduled_on in local database: %s\n", err.Error())
+		} else {
+			if num, _ := res.RowsAffected(); num <= 0 {
+				log.Printf("*** Failed to update fire_on/scheduled_on for id: %d, chat_id: %d\n", queueID, chatID)
+			} else {
+				result = true
+			}
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// SetQueueItemNumTries sets the number of tries of a queue item (eg. for giving up retries)
+func (d *Database) SetQueueItemNumTries(chatID, queueID int64, numTries int) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update queue set num_tries = ? where id = ? and chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(numTries, queueID, chatID); err != nil {
+			log.Printf("*** Failed to update num_tries in local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// DeliveredQueueItemsWithMessage returns delivered queue items of given chat with given message, fired after given time
+func (d *Database) DeliveredQueueItemsWithMessage(chatID int64, message string, since time.Time) []QueueItem {
+	queue := []QueueItem{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select ` + queueItemColumns + `
+		from queue
+		where chat_id = ? and message = ? and delivered_on is not null and fire_on >= ?
+		order by fire_on desc`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID, message, since.Unix()); err != nil {
+			log.Printf("*** Failed to select queue items from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			queue = scanQueueItems(rows)
+		}
+	}
+
+	d.RUnlock()
+
+	return queue
+}
+
+// QueueItem returns the queue item with given id in given chat
+func (d *Database) QueueItem(chatID, queueID int64) (item QueueItem, exists bool) {
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select ` + queueItemColumns + `
+		from queue
+		where id = ? and chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(queueID, chatID); err != nil {
+			log.Printf("*** Failed to select queue item from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			if queue := scanQueueItems(rows); len(queue) > 0 {
+				item, exists = queue[0], true
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return item, exists
+}
+
+// QueueAttachments returns keys of attachments referenced by queue items which are not delivered yet or delivered after given time
+func (d *Database) QueueAttachments(deliveredAfter time.Time) (keys []string, result bool) {
+	keys = []string{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select distinct attachment from queue
+		where attachment is not null and (delivered_on is null or delivered_on >= ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(deliveredAfter.Unix()); err != nil {
+			log.Printf("*** Failed to select attachments from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var key string
+			for rows.Next() {
+				if err := rows.Scan(&key); err != nil {
+					log.Printf("*** Failed to scan attachment: %s\n", err.Error())
+					continue
+				}
+				keys = append(keys, key)
+			}
+			result = rows.Err() == nil
+		}
+	}
+
+	d.RUnlock()
+
+	return keys, result
+}
+
+// SetQueueItemFailurePolicy sets the failure policy of given (undelivered) queue item
+func (d *Database) SetQueueItemFailurePolicy(chatID, queueID int64, policy string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update queue set failure_policy = nullif(?, '') where id = ? and chat_id = ? and delivered_on is null`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(policy, queueID, chatID); err != nil {
+			log.Printf("*** Failed to update failure_policy in local database: %s\n", err.Error())
+		} else {
+			if num, _ := res.RowsAffected(); num <= 0 {
+				log.Printf("*** Failed to update failure_policy for id: %d, chat_id: %d\n", queueID, chatID)
+			} else {
+				result = true
+			}
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// RetryQueueItem reschedules given failed queue item with its tries reset
+//
+// (its failure policy is also reset, not to be retried endlessly)
+func (d *Database) RetryQueueItem(chatID, queueID int64, fireOn time.Time) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update queue set fire_on = ?, num_tries = 0, failure_policy = null where id = ? and chat_id = ? and delivered_on is null`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(fireOn.Unix(), queueID, chatID); err != nil {
+			log.Printf("*** Failed to retry queue item in local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// SetQueueItemError saves the error (and its http status code) of the last failed delivery of given queue item
+func (d *Database) SetQueueItemError(chatID, queueID int64, code int, lastError string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update queue set last_error = ?, last_error_code = ?, last_error_on = ? where id = ? and chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(lastError, code, time.Now().Unix(), queueID, chatID); err != nil {
+			log.Printf("*** Failed to update queue item in local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// FailedQueueItems returns undelivered (and not paused) queue items which ran out of tries,
+// in given chat (0 for all chats) and scheduled in given range of time (zero times for no limits)
+func (d *Database) FailedQueueItems(maxNumTries int, chatID int64, from, until time.Time) []QueueItem {
+	queue := []QueueItem{}
+
+	conditions := []string{"delivered_on is null", "paused = 0", "num_tries >= ?"}
+	args := []interface{}{maxNumTries}
+	if chatID != 0 {
+		conditions = append(conditions, "chat_id = ?")
+		args = append(args, chatID)
+	}
+	if !from.IsZero() {
+		conditions = append(conditions, "fire_on >= ?")
+		args = append(args, from.Unix())
+	}
+	if !until.IsZero() {
+		conditions = append(conditions, "fire_on < ?")
+		args = append(args, until.Unix())
+	}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select ` + queueItemColumns + `
+		from queue
+		where ` + strings.Join(conditions, " and ") + `
+		order by fire_on`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(args...); err != nil {
+			log.Printf("*** Failed to select queue items from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			queue = scanQueueItems(rows)
+		}
+	}
+
+	d.RUnlock()
+
+	return queue
+}
+
+// QueueItemsWithErrors returns latest N queue items of given chat which failed with errors (latest first)
+func (d *Database) QueueItemsWithErrors(chatID int64, latestN int) []QueueItem {
+	queue := []QueueItem{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select ` + queueItemColumns + `
+		from queue
+		where chat_id = ? and last_error_on is not null
+		order by last_error_on desc
+		limit ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID, latestN); err != nil {
+			log.Printf("*** Failed to select queue items from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			queue = scanQueueItems(rows)
+		}
+	}
+
+	d.RUnlock()
+
+	return queue
+}
+
+// AckQueueItem marks given delivered queue item as done by given user (only if nobody did it yet)
+func (d *Database) AckQueueItem(chatID, queueID, userID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update queue set acked_by = ?, acked_on = ? where id = ? and chat_id = ? and ifnull(acked_by, 0) = 0`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(userID, time.Now().Unix(), queueID, chatID); err != nil {
+			log.Printf("*** Failed to update queue item in local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// DeleteQueueItemsByRef deletes undelivered queue items of given kind and reference in given chat
+func (d *Database) DeleteQueueItemsByRef(chatID int64, kind string, refID int64) int64 {
+	var num int64
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`delete from queue where chat_id = ? and kind = ? and ref_id = ? and delivered_on is null`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(chatID, kind, refID); err != nil {
+			log.Printf("*** Failed to delete queue items from local database: %s\n", err.Error())
+		} else {
+			num, _ = res.RowsAffected()
+		}
+	}
+
+	d.Unlock()
+
+	return num
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"log"
+	"time"
+)
+
+// DDay struct
+type DDay struct {
+	ID        int64     `json:"id"`
+	ChatID    int64     `json:"chat_id"`
+	UserID    int64     `json:"user_id,omitempty"`
+	Name      string    `json:"name"`
+	EventOn   time.Time `json:"event_on"`
+	CreatedOn time.Time `json:"created_on"`
+}
+
+// SaveDDay saves a D-day event of given chat
+func (d *Database) SaveDDay(chatID, userID int64, name string, eventOn time.Time) (ddayID int64, result bool) {
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into ddays(chat_id, user_id, name, event_on) values(?, ?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(chatID, userID, name, eventOn.Unix()); err != nil {
+			log.Printf("*** Failed to save D-day into local database: %s\n", err.Error())
+		} else {
+			ddayID, _ = res.LastInsertId()
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return ddayID, result
+}
+
+// UpcomingDDays returns D-day events of given chat which are not past given time
+func (d *Database) UpcomingDDays(chatID int64, since time.Time) []DDay {
+	ddays := []DDay{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select
+		id,
+		chat_id,
+		user_id,
+		name,
+		event_on,
+		created_on
+		from ddays
+		where chat_id = ? and event_on >= ?
+		order by event_on`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID, since.Unix()); err != nil {
+			log.Printf("*** Failed to select D-days from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var dday DDay
+			var eventOn, createdOn int64
+			for rows.Next() {
+				if err := rows.Scan(&dday.ID, &dday.ChatID, &dday.UserID, &dday.Name, &eventOn, &createdOn); err != nil {
+					log.Printf("*** Failed to scan D-day: %s\n", err.Error())
+					continue
+				}
+				dday.EventOn = time.Unix(eventOn, 0)
+				dday.CreatedOn = time.Unix(createdOn, 0)
+
+				ddays = append(ddays, dday)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return ddays
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// DeliveredMessage is a struct for a message of a delivered queue item
+type DeliveredMessage struct {
+	ChatID      int64     `json:"chat_id"`
+	MessageID   int64     `json:"message_id"`
+	QueueChatID int64     `json:"queue_chat_id"`
+	QueueID     int64     `json:"queue_id"`
+	DeliveredOn time.Time `json:"delivered_on"`
+}
+
+// SaveDeliveredMessage remembers the message of a delivered queue item (on the item itself, and in the mapping table)
+//
+// (the message can be in another chat than the queue item's, eg. routed by its category)
+func (d *Database) SaveDeliveredMessage(chatID, messageID, queueChatID, queueID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if tx, err := d.db.Begin(); err != nil {
+		log.Printf("*** Failed to begin a transaction: %s\n", err.Error())
+	} else {
+		if _, err := tx.Exec(`insert or replace into delivered_messages(chat_id, message_id, queue_chat_id, queue_id) values(?, ?, ?, ?)`, chatID, messageID, queueChatID, queueID); err != nil {
+			log.Printf("*** Failed to save delivered message into local database: %s\n", err.Error())
+		} else if _, err := tx.Exec(`update queue set message_chat_id = ?, message_id = ? where id = ? and chat_id = ?`, chatID, messageID, queueID, queueChatID); err != nil {
+			log.Printf("*** Failed to save message id of queue item into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+
+		if result {
+			if err := tx.Commit(); err != nil {
+				log.Printf("*** Failed to commit a transaction: %s\n", err.Error())
+				result = false
+			}
+		} else {
+			tx.Rollback()
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// DeliveredQueueItem returns the queue item which was delivered as given message
+func (d *Database) DeliveredQueueItem(chatID, messageID int64) (item QueueItem, exists bool) {
+	var queueChatID, queueID int64
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select queue_chat_id, queue_id from delivered_messages where chat_id = ? and message_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if err := stmt.QueryRow(chatID, messageID).Scan(&queueChatID, &queueID); err == nil {
+			exists = true
+		} else if err != sql.ErrNoRows {
+			log.Printf("*** Failed to select delivered message from local database: %s\n", err.Error())
+		}
+	}
+
+	d.RUnlock()
+
+	if !exists {
+		return item, false
+	}
+
+	return d.QueueItem(queueChatID, queueID)
+}
+
+// DeliveredMessages returns the messages which given queue item was delivered as (eg. for editing or pinning them)
+func (d *Database) DeliveredMessages(queueChatID, queueID int64) []DeliveredMessage {
+	messages := []DeliveredMessage{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select chat_id, message_id, queue_chat_id, queue_id, delivered_on
+		from delivered_messages
+		where queue_chat_id = ? and queue_id = ?
+		order by delivered_on`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(queueChatID, queueID); err != nil {
+			log.Printf("*** Failed to select delivered messages from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var m DeliveredMessage
+			var deliveredOn int64
+			for rows.Next() {
+				if err := rows.Scan(&m.ChatID, &m.MessageID, &m.QueueChatID, &m.QueueID, &deliveredOn); err != nil {
+					log.Printf("*** Failed to scan delivered message: %s\n", err.Error())
+					continue
+				}
+				m.DeliveredOn = time.Unix(deliveredOn, 0)
+
+				messages = append(messages, m)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return messages
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// MissingSchema returns tables, columns, and indexes of the current schema which are missing
+// in the database file at given path (before it is opened and migrated with OpenDb)
+//
+// (eg. "queue", "queue.last_error_code", "idx_queue1"; the database is opened read-only)
+func MissingSchema(filepath string) (missing []string, err error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro", filepath))
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	// reference database with the current schema
+	reference, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	defer reference.Close()
+	reference.SetMaxOpenConns(1) // (each connection has its own in-memory database)
+
+	err = func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%v", r)
+			}
+		}()
+		createTables(reference)
+		return nil
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+
+	tables, err := tableNames(ctx, reference, "main")
+	if err != nil {
+		return nil, err
+	}
+	existing, err := tableNames(ctx, db, "main")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, table := range tables {
+		if !containsName(existing, table) {
+			missing = append(missing, table)
+			continue
+		}
+
+		columns, err := columnNames(ctx, reference, "main", table)
+		if err != nil {
+			return nil, err
+		}
+		existingColumns, err := columnNames(ctx, db, "main", table)
+		if err != nil {
+			return nil, err
+		}
+		for _, column := range columns {
+			if !containsName(existingColumns, column) {
+				missing = append(missing, table+"."+column)
+			}
+		}
+	}
+
+	indexes, err := indexNames(ctx, reference)
+	if err != nil {
+		return nil, err
+	}
+	existingIndexes, err := indexNames(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		if !containsName(existingIndexes, index) {
+			missing = append(missing, index)
+		}
+	}
+
+	return missing, nil
+}
+
+// names of (explicitly created) indexes in given database
+func indexNames(ctx context.Context, q querier) (names []string, err error) {
+	rows, err := q.QueryContext(ctx, `select name from main.sqlite_master where type = 'index' and sql is not null order by name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var name string
+	for rows.Next() {
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// check if given names contain given name
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
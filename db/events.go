@@ -0,0 +1,116 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+)
+
+// EventTemplate struct (message template for webhook events)
+type EventTemplate struct {
+	ChatID   int64  `json:"chat_id"`
+	Name     string `json:"name"`
+	Template string `json:"template"`
+}
+
+// SaveEventTemplate saves the message template of given event for given chat
+func (d *Database) SaveEventTemplate(chatID int64, name, template string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or replace into event_templates(chat_id, name, template) values(?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(chatID, name, template); err != nil {
+			log.Printf("*** Failed to save event template into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// DeleteEventTemplate deletes the message template of given event for given chat
+func (d *Database) DeleteEventTemplate(chatID int64, name string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`delete from event_templates where chat_id = ? and name = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(chatID, name); err != nil {
+			log.Printf("*** Failed to delete event template from local database: %s\n", err.Error())
+		} else {
+			if num, _ := res.RowsAffected(); num > 0 {
+				result = true
+			}
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// EventTemplates returns all the event templates of given chat
+func (d *Database) EventTemplates(chatID int64) []EventTemplate {
+	templates := []EventTemplate{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select chat_id, name, template from event_templates where chat_id = ? order by name`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID); err != nil {
+			log.Printf("*** Failed to select event templates from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var t EventTemplate
+			for rows.Next() {
+				if err := rows.Scan(&t.ChatID, &t.Name, &t.Template); err != nil {
+					log.Printf("*** Failed to scan event template: %s\n", err.Error())
+					continue
+				}
+
+				templates = append(templates, t)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return templates
+}
+
+// EventTemplateFor returns the message template of given event for given chat
+func (d *Database) EventTemplateFor(chatID int64, name string) (template string, exists bool) {
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select template from event_templates where chat_id = ? and name = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if err := stmt.QueryRow(chatID, name).Scan(&template); err == nil {
+			exists = true
+		} else if err != sql.ErrNoRows {
+			log.Printf("*** Failed to select event template from local database: %s\n", err.Error())
+		}
+	}
+
+	d.RUnlock()
+
+	return template, exists
+}
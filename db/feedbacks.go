@@ -0,0 +1,123 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Feedback struct
+type Feedback struct {
+	ID         int64     `json:"id"`
+	ChatID     int64     `json:"chat_id"`
+	UserID     int64     `json:"user_id"`
+	Username   string    `json:"username,omitempty"`
+	Message    string    `json:"message"`
+	CreatedOn  time.Time `json:"created_on"`
+	AnsweredOn time.Time `json:"answered_on,omitempty"`
+}
+
+// SaveFeedback saves a feedback of given user
+func (d *Database) SaveFeedback(chatID, userID int64, username, message string) (id int64, result bool) {
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into feedbacks(chat_id, user_id, username, message) values(?, ?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(chatID, userID, username, message); err != nil {
+			log.Printf("*** Failed to save feedback into local database: %s\n", err.Error())
+		} else {
+			id, _ = res.LastInsertId()
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return id, result
+}
+
+// SaveFeedbackForward remembers the message of given feedback forwarded to an admin's chat
+func (d *Database) SaveFeedbackForward(feedbackID, adminChatID, messageID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or replace into feedback_forwards(admin_chat_id, message_id, feedback_id) values(?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(adminChatID, messageID, feedbackID); err != nil {
+			log.Printf("*** Failed to save feedback forward into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// ForwardedFeedback returns the feedback which was forwarded as given message of an admin's chat
+func (d *Database) ForwardedFeedback(adminChatID, messageID int64) (feedback Feedback, exists bool) {
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select
+		f.id,
+		f.chat_id,
+		f.user_id,
+		ifnull(f.username, '') as username,
+		f.message,
+		f.created_on,
+		ifnull(f.answered_on, 0) as answered_on
+		from feedbacks f
+		join feedback_forwards w on w.feedback_id = f.id
+		where w.admin_chat_id = ? and w.message_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var createdOn, answeredOn int64
+		if err := stmt.QueryRow(adminChatID, messageID).Scan(&feedback.ID, &feedback.ChatID, &feedback.UserID, &feedback.Username, &feedback.Message, &createdOn, &answeredOn); err == nil {
+			feedback.CreatedOn = time.Unix(createdOn, 0)
+			if answeredOn > 0 {
+				feedback.AnsweredOn = time.Unix(answeredOn, 0)
+			}
+			exists = true
+		} else if err != sql.ErrNoRows {
+			log.Printf("*** Failed to select feedback from local database: %s\n", err.Error())
+		}
+	}
+
+	d.RUnlock()
+
+	return feedback, exists
+}
+
+// MarkFeedbackAsAnswered marks given feedback as answered
+func (d *Database) MarkFeedbackAsAnswered(feedbackID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update feedbacks set answered_on = strftime('%s', 'now') where id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(feedbackID); err != nil {
+			log.Printf("*** Failed to update feedback in local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
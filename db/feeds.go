@@ -0,0 +1,166 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Feed struct
+type Feed struct {
+	ID        int64     `json:"id"`
+	ChatID    int64     `json:"chat_id"`
+	URL       string    `json:"url"`
+	Title     string    `json:"title,omitempty"`
+	AddedOn   time.Time `json:"added_on"`
+	CheckedOn time.Time `json:"checked_on,omitempty"`
+}
+
+// SaveFeed saves given feed url for given chat
+func (d *Database) SaveFeed(chatID int64, url, title string) (feedID int64, result bool) {
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or ignore into feeds(chat_id, url, title) values(?, ?, nullif(?, ''))`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(chatID, url, title); err != nil {
+			log.Printf("*** Failed to save feed into local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			feedID, _ = res.LastInsertId()
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return feedID, result
+}
+
+// DeleteFeed deletes given feed of given chat (with its seen items)
+func (d *Database) DeleteFeed(chatID, feedID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if tx, err := d.db.Begin(); err != nil {
+		log.Printf("*** Failed to begin a transaction: %s\n", err.Error())
+	} else {
+		if res, err := tx.Exec(`delete from feeds where id = ? and chat_id = ?`, feedID, chatID); err != nil {
+			log.Printf("*** Failed to delete feed from local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			if _, err := tx.Exec(`delete from feed_items where feed_id = ?`, feedID); err != nil {
+				log.Printf("*** Failed to delete feed items from local database: %s\n", err.Error())
+			} else {
+				result = true
+			}
+		}
+
+		if result {
+			if err := tx.Commit(); err != nil {
+				log.Printf("*** Failed to commit a transaction: %s\n", err.Error())
+				result = false
+			}
+		} else {
+			tx.Rollback()
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// Feeds returns feeds of given chat (or all feeds if chatID is 0)
+func (d *Database) Feeds(chatID int64) []Feed {
+	feeds := []Feed{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select
+		id,
+		chat_id,
+		url,
+		ifnull(title, '') as title,
+		added_on,
+		ifnull(checked_on, 0) as checked_on
+		from feeds
+		where ? = 0 or chat_id = ?
+		order by id`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID, chatID); err != nil {
+			log.Printf("*** Failed to select feeds from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var feed Feed
+			var addedOn, checkedOn int64
+			for rows.Next() {
+				if err := rows.Scan(&feed.ID, &feed.ChatID, &feed.URL, &feed.Title, &addedOn, &checkedOn); err != nil {
+					log.Printf("*** Failed to scan feed: %s\n", err.Error())
+					continue
+				}
+				feed.AddedOn = time.Unix(addedOn, 0)
+				feed.CheckedOn = time.Unix(checkedOn, 0)
+
+				feeds = append(feeds, feed)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return feeds
+}
+
+// MarkFeedChecked updates the last checked time of given feed
+func (d *Database) MarkFeedChecked(feedID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update feeds set checked_on = strftime('%s', 'now') where id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(feedID); err != nil {
+			log.Printf("*** Failed to update feed in local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// SaveFeedItem marks given item of given feed as seen, returns false if it was seen already
+func (d *Database) SaveFeedItem(feedID int64, guid string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or ignore into feed_items(feed_id, guid) values(?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(feedID, guid); err != nil {
+			log.Printf("*** Failed to save feed item into local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Flag struct (a reminder flagged by a content rule)
+type Flag struct {
+	ID        int64     `json:"id"`
+	QueueID   int64     `json:"queue_id"`
+	ChatID    int64     `json:"chat_id"`
+	UserID    int64     `json:"user_id"`
+	Message   string    `json:"message"`
+	Rule      string    `json:"rule"`
+	FlaggedOn time.Time `json:"flagged_on"`
+}
+
+// SaveFlag saves a flag of given queue item for admins' review
+func (d *Database) SaveFlag(q QueueItem, rule string) (id int64, result bool) {
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into flags(queue_id, chat_id, user_id, message, rule) values(?, ?, ?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(q.ID, q.ChatID, q.UserID, q.Message, rule); err != nil {
+			log.Printf("*** Failed to save flag into local database: %s\n", err.Error())
+		} else {
+			id, _ = res.LastInsertId()
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return id, result
+}
+
+// UnreviewedFlags returns flags which are not reviewed yet (oldest first)
+func (d *Database) UnreviewedFlags(limit int) []Flag {
+	flags := []Flag{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select
+		id,
+		queue_id,
+		chat_id,
+		user_id,
+		message,
+		rule,
+		flagged_on
+		from flags
+		where reviewed_on is null
+		order by id
+		limit ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(limit); err != nil {
+			log.Printf("*** Failed to select flags from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var id, queueID, chatID, userID, flaggedOn int64
+			var message, rule string
+			for rows.Next() {
+				if err := rows.Scan(&id, &queueID, &chatID, &userID, &message, &rule, &flaggedOn); err != nil {
+					log.Printf("*** Failed to scan flag: %s\n", err.Error())
+					continue
+				}
+				flags = append(flags, Flag{
+					ID:        id,
+					QueueID:   queueID,
+					ChatID:    chatID,
+					UserID:    userID,
+					Message:   message,
+					Rule:      rule,
+					FlaggedOn: time.Unix(flaggedOn, 0),
+				})
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return flags
+}
+
+// ReviewFlag marks given flag as reviewed, returning the flag (or false if it was already reviewed)
+func (d *Database) ReviewFlag(id int64) (flag Flag, result bool) {
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`select queue_id, chat_id, user_id, message, rule from flags where id = ? and reviewed_on is null`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		flag.ID = id
+		if err := stmt.QueryRow(id).Scan(&flag.QueueID, &flag.ChatID, &flag.UserID, &flag.Message, &flag.Rule); err != nil {
+			if err != sql.ErrNoRows {
+				log.Printf("*** Failed to select flag from local database: %s\n", err.Error())
+			}
+		} else if _, err := d.db.Exec(`update flags set reviewed_on = strftime('%s', 'now') where id = ?`, id); err != nil {
+			log.Printf("*** Failed to update flag in local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return flag, result
+}
+
+// NumUnreviewedFlags returns the number of flags which are not reviewed yet
+func (d *Database) NumUnreviewedFlags() (count int) {
+	d.RLock()
+
+	if err := d.db.QueryRow(`select count(id) from flags where reviewed_on is null`).Scan(&count); err != nil {
+		log.Printf("*** Failed to count flags in local database: %s\n", err.Error())
+	}
+
+	d.RUnlock()
+
+	return count
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"log"
+)
+
+// SaveFollowUp saves an answer to the follow-up question of a delivered reminder
+func (d *Database) SaveFollowUp(queueID, chatID, userID int64, message string, done bool) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into follow_ups(queue_id, chat_id, user_id, message, done) values(?, ?, ?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(queueID, chatID, userID, message, done); err != nil {
+			log.Printf("*** Failed to save follow-up into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// FollowUpStreak returns the number of latest consecutive 'done' answers for given message in given chat
+func (d *Database) FollowUpStreak(chatID int64, message string) int {
+	streak := 0
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select done from follow_ups where chat_id = ? and message = ? order by id desc`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID, message); err != nil {
+			log.Printf("*** Failed to select follow-ups from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var done bool
+			for rows.Next() {
+				if err := rows.Scan(&done); err != nil || !done {
+					break
+				}
+				streak++
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return streak
+}
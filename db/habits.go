@@ -0,0 +1,242 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Habit struct
+type Habit struct {
+	ID        int64     `json:"id"`
+	ChatID    int64     `json:"chat_id"`
+	UserID    int64     `json:"user_id,omitempty"`
+	Name      string    `json:"name"`
+	Time      string    `json:"time"` // time of the daily prompt (eg. "20:00")
+	IsActive  bool      `json:"is_active"`
+	CreatedOn time.Time `json:"created_on"`
+}
+
+// SaveHabit saves a new habit
+func (d *Database) SaveHabit(h Habit) (habitID int64, result bool) {
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into habits(chat_id, user_id, name, time) values(?, ?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(h.ChatID, h.UserID, h.Name, h.Time); err != nil {
+			log.Printf("*** Failed to save habit into local database: %s\n", err.Error())
+		} else {
+			habitID, _ = res.LastInsertId()
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return habitID, result
+}
+
+// DeactivateHabit marks given habit of given chat as inactive
+func (d *Database) DeactivateHabit(chatID, habitID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update habits set is_active = 0 where id = ? and chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(habitID, chatID); err != nil {
+			log.Printf("*** Failed to update habit in local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// Habits returns active habits of given chat
+func (d *Database) Habits(chatID int64) []Habit {
+	habits := []Habit{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select id, chat_id, user_id, name, time, is_active, created_on
+		from habits
+		where chat_id = ? and is_active = 1
+		order by id`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID); err != nil {
+			log.Printf("*** Failed to select habits from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var h Habit
+			var createdOn int64
+			for rows.Next() {
+				if err := rows.Scan(&h.ID, &h.ChatID, &h.UserID, &h.Name, &h.Time, &h.IsActive, &createdOn); err != nil {
+					log.Printf("*** Failed to scan habit: %s\n", err.Error())
+					continue
+				}
+				h.CreatedOn = time.Unix(createdOn, 0)
+
+				habits = append(habits, h)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return habits
+}
+
+// Habit returns the habit with given id
+func (d *Database) Habit(habitID int64) (h Habit, exists bool) {
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select id, chat_id, user_id, name, time, is_active, created_on
+		from habits
+		where id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var createdOn int64
+		if err := stmt.QueryRow(habitID).Scan(&h.ID, &h.ChatID, &h.UserID, &h.Name, &h.Time, &h.IsActive, &createdOn); err == nil {
+			h.CreatedOn = time.Unix(createdOn, 0)
+			exists = true
+		} else if err != sql.ErrNoRows {
+			log.Printf("*** Failed to select habit from local database: %s\n", err.Error())
+		}
+	}
+
+	d.RUnlock()
+
+	return h, exists
+}
+
+// SaveHabitCheck saves (or replaces) whether given user did given habit on given day (eg. "2017-12-31")
+func (d *Database) SaveHabitCheck(habitID, userID int64, day string, done bool) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or replace into habit_checks(habit_id, user_id, day, done) values(?, ?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(habitID, userID, day, done); err != nil {
+			log.Printf("*** Failed to save habit check into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// HabitChecks returns whether given user did given habit, by day (eg. "2017-12-31")
+func (d *Database) HabitChecks(habitID, userID int64) map[string]bool {
+	checks := map[string]bool{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select day, done from habit_checks where habit_id = ? and user_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(habitID, userID); err != nil {
+			log.Printf("*** Failed to select habit checks from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var day string
+			var done bool
+			for rows.Next() {
+				if err := rows.Scan(&day, &done); err != nil {
+					log.Printf("*** Failed to scan habit check: %s\n", err.Error())
+					continue
+				}
+				checks[day] = done
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return checks
+}
+
+// SetHabitLeaderboardMember adds (or removes) given user to (or from) the habit leaderboard of given chat
+func (d *Database) SetHabitLeaderboardMember(chatID, userID int64, join bool) bool {
+	result := false
+
+	query := `insert or ignore into habit_leaderboards(chat_id, user_id) values(?, ?)`
+	if !join {
+		query = `delete from habit_leaderboards where chat_id = ? and user_id = ?`
+	}
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(query); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(chatID, userID); err != nil {
+			log.Printf("*** Failed to save habit leaderboard member into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// HabitLeaderboardMembers returns ids of users who opted in to the habit leaderboard of given chat
+func (d *Database) HabitLeaderboardMembers(chatID int64) []int64 {
+	userIDs := []int64{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select user_id from habit_leaderboards where chat_id = ? order by joined_on`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID); err != nil {
+			log.Printf("*** Failed to select habit leaderboard members from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var userID int64
+			for rows.Next() {
+				if err := rows.Scan(&userID); err != nil {
+					log.Printf("*** Failed to scan habit leaderboard member: %s\n", err.Error())
+					continue
+				}
+				userIDs = append(userIDs, userID)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return userIDs
+}
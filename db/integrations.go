@@ -0,0 +1,181 @@
+package db
+
+import (
+	"log"
+	"time"
+)
+
+// Integration struct (a task app connected to a chat)
+type Integration struct {
+	ChatID    int64     `json:"chat_id"`
+	Provider  string    `json:"provider"` // eg. "todoist"
+	UserID    int64     `json:"user_id"`  // user who connected it
+	Token     string    `json:"-"`        // (encrypted)
+	CreatedOn time.Time `json:"created_on"`
+}
+
+// IntegrationTask struct (a task mirrored from a queue item)
+type IntegrationTask struct {
+	Provider string `json:"provider"`
+	TaskID   string `json:"task_id"`
+}
+
+// SaveIntegration saves (or replaces) given integration
+func (d *Database) SaveIntegration(i Integration) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or replace into integrations(chat_id, provider, user_id, token) values(?, ?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(i.ChatID, i.Provider, i.UserID, i.Token); err != nil {
+			log.Printf("*** Failed to save integration into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// DeleteIntegration disconnects given provider from given chat
+func (d *Database) DeleteIntegration(chatID int64, provider string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`delete from integrations where chat_id = ? and provider = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(chatID, provider); err != nil {
+			log.Printf("*** Failed to delete integration from local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// Integrations returns the integrations of given chat
+func (d *Database) Integrations(chatID int64) []Integration {
+	integrations := []Integration{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select chat_id, provider, user_id, token, created_on from integrations where chat_id = ? order by provider`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID); err != nil {
+			log.Printf("*** Failed to select integrations from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var i Integration
+			var createdOn int64
+			for rows.Next() {
+				if err := rows.Scan(&i.ChatID, &i.Provider, &i.UserID, &i.Token, &createdOn); err != nil {
+					log.Printf("*** Failed to scan integration: %s\n", err.Error())
+					continue
+				}
+				i.CreatedOn = time.Unix(createdOn, 0)
+
+				integrations = append(integrations, i)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return integrations
+}
+
+// SaveIntegrationTask saves the id of the task mirrored from given queue item
+func (d *Database) SaveIntegrationTask(queueChatID, queueID int64, provider, taskID string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or replace into integration_tasks(queue_chat_id, queue_id, provider, task_id) values(?, ?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(queueChatID, queueID, provider, taskID); err != nil {
+			log.Printf("*** Failed to save integration task into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// IntegrationTasks returns the tasks mirrored from given queue item
+func (d *Database) IntegrationTasks(queueChatID, queueID int64) []IntegrationTask {
+	tasks := []IntegrationTask{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select provider, task_id from integration_tasks where queue_chat_id = ? and queue_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(queueChatID, queueID); err != nil {
+			log.Printf("*** Failed to select integration tasks from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var t IntegrationTask
+			for rows.Next() {
+				if err := rows.Scan(&t.Provider, &t.TaskID); err != nil {
+					log.Printf("*** Failed to scan integration task: %s\n", err.Error())
+					continue
+				}
+
+				tasks = append(tasks, t)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return tasks
+}
+
+// DeleteIntegrationTasks forgets the tasks mirrored from given queue item
+func (d *Database) DeleteIntegrationTasks(queueChatID, queueID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`delete from integration_tasks where queue_chat_id = ? and queue_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(queueChatID, queueID); err != nil {
+			log.Printf("*** Failed to delete integration tasks from local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
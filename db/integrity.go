@@ -0,0 +1,47 @@
+package db
+
+import (
+	"database/sql"
+)
+
+// CheckIntegrity runs integrity check on the database file at given path (before it is opened with OpenDb),
+// and returns the problems found in it (empty if intact)
+//
+// (an error is returned when it could not be checked at all, eg. it is not a database)
+func CheckIntegrity(filepath string) (problems []string, err error) {
+	db, err := sql.Open("sqlite3", filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`pragma integrity_check`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result string
+	for rows.Next() {
+		if err := rows.Scan(&result); err != nil {
+			return nil, err
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+
+	return problems, rows.Err()
+}
+
+// Reindex rebuilds all indexes of the database file at given path (before it is opened with OpenDb)
+func Reindex(filepath string) error {
+	db, err := sql.Open("sqlite3", filepath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`reindex`)
+	return err
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"log"
+	"time"
+)
+
+// SetQueueItemLatency records the gap between the fire time and the actual delivery of a queue item
+func (d *Database) SetQueueItemLatency(chatID, queueID int64, latency time.Duration) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update queue set delivery_latency_ms = ? where id = ? and chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(latency.Nanoseconds()/int64(time.Millisecond), queueID, chatID); err != nil {
+			log.Printf("*** Failed to save delivery latency into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// DeliveryLatencies returns latencies of queue items delivered since given time (in no particular order)
+func (d *Database) DeliveryLatencies(since time.Time) []time.Duration {
+	latencies := []time.Duration{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select delivery_latency_ms from queue
+		where delivered_on >= ? and delivery_latency_ms is not null`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(since.Unix()); err != nil {
+			log.Printf("*** Failed to select delivery latencies from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var ms int64
+			for rows.Next() {
+				if err := rows.Scan(&ms); err != nil {
+					log.Printf("*** Failed to scan delivery latency: %s\n", err.Error())
+					continue
+				}
+				latencies = append(latencies, time.Duration(ms)*time.Millisecond)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return latencies
+}
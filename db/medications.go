@@ -0,0 +1,261 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+)
+
+// Medication struct
+type Medication struct {
+	ID       int64     `json:"id"`
+	ChatID   int64     `json:"chat_id"`
+	UserID   int64     `json:"user_id,omitempty"`
+	Name     string    `json:"name"`
+	Times    []string  `json:"times"` // dose times of a day (eg. "08:00")
+	StartsOn time.Time `json:"starts_on"`
+	EndsOn   time.Time `json:"ends_on,omitempty"` // zero if it has no end
+	IsActive bool      `json:"is_active"`
+}
+
+// Dose struct
+type Dose struct {
+	ID           int64     `json:"id"`
+	MedicationID int64     `json:"medication_id"`
+	ChatID       int64     `json:"chat_id"`
+	DueOn        time.Time `json:"due_on"`
+	TakenOn      time.Time `json:"taken_on,omitempty"` // zero if not taken
+}
+
+// Adherence struct (doses taken of a medication in a period)
+type Adherence struct {
+	MedicationID int64  `json:"medication_id"`
+	Name         string `json:"name"`
+	NumDoses     int    `json:"num_doses"`
+	NumTaken     int    `json:"num_taken"`
+}
+
+// SaveMedication saves a new medication
+func (d *Database) SaveMedication(m Medication) (medicationID int64, result bool) {
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into medications(chat_id, user_id, name, times, ends_on) values(?, ?, ?, ?, nullif(?, 0))`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var endsOn int64
+		if !m.EndsOn.IsZero() {
+			endsOn = m.EndsOn.Unix()
+		}
+
+		if res, err := stmt.Exec(m.ChatID, m.UserID, m.Name, strings.Join(m.Times, ","), endsOn); err != nil {
+			log.Printf("*** Failed to save medication into local database: %s\n", err.Error())
+		} else {
+			medicationID, _ = res.LastInsertId()
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return medicationID, result
+}
+
+// DeactivateMedication marks given medication of given chat as inactive
+func (d *Database) DeactivateMedication(chatID, medicationID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update medications set is_active = 0 where id = ? and chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(medicationID, chatID); err != nil {
+			log.Printf("*** Failed to update medication in local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// Medications returns active medications of given chat
+func (d *Database) Medications(chatID int64) []Medication {
+	medications := []Medication{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select id, chat_id, user_id, name, times, starts_on, ifnull(ends_on, 0), is_active
+		from medications
+		where chat_id = ? and is_active = 1
+		order by id`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID); err != nil {
+			log.Printf("*** Failed to select medications from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var m Medication
+			var times string
+			var startsOn, endsOn int64
+			for rows.Next() {
+				if err := rows.Scan(&m.ID, &m.ChatID, &m.UserID, &m.Name, &times, &startsOn, &endsOn, &m.IsActive); err != nil {
+					log.Printf("*** Failed to scan medication: %s\n", err.Error())
+					continue
+				}
+				m.Times = strings.Split(times, ",")
+				m.StartsOn = time.Unix(startsOn, 0)
+				m.EndsOn = time.Time{}
+				if endsOn > 0 {
+					m.EndsOn = time.Unix(endsOn, 0)
+				}
+
+				medications = append(medications, m)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return medications
+}
+
+// Medication returns the medication with given id
+func (d *Database) Medication(medicationID int64) (m Medication, exists bool) {
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select id, chat_id, user_id, name, times, starts_on, ifnull(ends_on, 0), is_active
+		from medications
+		where id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var times string
+		var startsOn, endsOn int64
+		if err := stmt.QueryRow(medicationID).Scan(&m.ID, &m.ChatID, &m.UserID, &m.Name, &times, &startsOn, &endsOn, &m.IsActive); err == nil {
+			m.Times = strings.Split(times, ",")
+			m.StartsOn = time.Unix(startsOn, 0)
+			if endsOn > 0 {
+				m.EndsOn = time.Unix(endsOn, 0)
+			}
+			exists = true
+		} else if err != sql.ErrNoRows {
+			log.Printf("*** Failed to select medication from local database: %s\n", err.Error())
+		}
+	}
+
+	d.RUnlock()
+
+	return m, exists
+}
+
+// SaveDose saves a dose of given medication due on given time (if not saved yet), and returns its id
+func (d *Database) SaveDose(medicationID, chatID int64, dueOn time.Time) (doseID int64, result bool) {
+	d.Lock()
+
+	if _, err := d.db.Exec(`insert or ignore into medication_doses(medication_id, chat_id, due_on) values(?, ?, ?)`, medicationID, chatID, dueOn.Unix()); err != nil {
+		log.Printf("*** Failed to save dose into local database: %s\n", err.Error())
+	} else if err := d.db.QueryRow(`select id from medication_doses where medication_id = ? and due_on = ?`, medicationID, dueOn.Unix()).Scan(&doseID); err != nil {
+		log.Printf("*** Failed to select dose from local database: %s\n", err.Error())
+	} else {
+		result = true
+	}
+
+	d.Unlock()
+
+	return doseID, result
+}
+
+// Dose returns the dose with given id in given chat
+func (d *Database) Dose(chatID, doseID int64) (dose Dose, exists bool) {
+	d.RLock()
+
+	var dueOn, takenOn int64
+	if err := d.db.QueryRow(`select id, medication_id, chat_id, due_on, ifnull(taken_on, 0)
+		from medication_doses
+		where id = ? and chat_id = ?`, doseID, chatID).Scan(&dose.ID, &dose.MedicationID, &dose.ChatID, &dueOn, &takenOn); err == nil {
+		dose.DueOn = time.Unix(dueOn, 0)
+		if takenOn > 0 {
+			dose.TakenOn = time.Unix(takenOn, 0)
+		}
+		exists = true
+	} else if err != sql.ErrNoRows {
+		log.Printf("*** Failed to select dose from local database: %s\n", err.Error())
+	}
+
+	d.RUnlock()
+
+	return dose, exists
+}
+
+// TakeDose marks given dose as taken
+func (d *Database) TakeDose(chatID, doseID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update medication_doses set taken_on = strftime('%s', 'now') where id = ? and chat_id = ? and taken_on is null`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(doseID, chatID); err != nil {
+			log.Printf("*** Failed to update dose in local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// Adherences returns the numbers of due and taken doses of each medication of given chat since given time
+func (d *Database) Adherences(chatID int64, since time.Time) []Adherence {
+	adherences := []Adherence{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select m.id, m.name, count(d.id), count(d.taken_on)
+		from medications m
+		inner join medication_doses d on d.medication_id = m.id
+		where m.chat_id = ? and d.due_on >= ? and d.due_on <= strftime('%s', 'now')
+		group by m.id
+		order by m.id`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID, since.Unix()); err != nil {
+			log.Printf("*** Failed to select adherences from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var a Adherence
+			for rows.Next() {
+				if err := rows.Scan(&a.MedicationID, &a.Name, &a.NumDoses, &a.NumTaken); err != nil {
+					log.Printf("*** Failed to scan adherence: %s\n", err.Error())
+					continue
+				}
+
+				adherences = append(adherences, a)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return adherences
+}
@@ -0,0 +1,175 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// instrumentation of queries: every statement executed through the database is timed with a wrapped driver,
+// so that slow queries and lock contentions of sqlite can be caught early
+
+const (
+	instrumentedDriverName = "sqlite3-instrumented"
+)
+
+// QueryMetrics struct (numbers and durations of executed queries)
+type QueryMetrics struct {
+	NumQueries     int64         `json:"num_queries"`
+	NumSlowQueries int64         `json:"num_slow_queries"`
+	NumLockWaits   int64         `json:"num_lock_waits"` // queries which failed as the database was locked (busy)
+	TotalDuration  time.Duration `json:"total_duration_ns"`
+	MaxDuration    time.Duration `json:"max_duration_ns"`
+}
+
+var (
+	_numQueries     int64
+	_numSlowQueries int64
+	_numLockWaits   int64
+	_totalDuration  int64 // (nanoseconds)
+	_maxDuration    int64 // (nanoseconds)
+
+	_slowQueryThreshold int64 // (nanoseconds, 0 for not logging slow queries)
+)
+
+func init() {
+	// wrap the sqlite3 driver
+	if probe, err := sql.Open("sqlite3", ":memory:"); err == nil {
+		sql.Register(instrumentedDriverName, instrumentedDriver{driver: probe.Driver()})
+		probe.Close()
+	} else {
+		panic("Failed to get sqlite3 driver: " + err.Error())
+	}
+}
+
+// SetSlowQueryThreshold sets the threshold of logging slow queries (0 for not logging them)
+func SetSlowQueryThreshold(threshold time.Duration) {
+	atomic.StoreInt64(&_slowQueryThreshold, int64(threshold))
+}
+
+// Metrics returns the metrics of queries executed so far
+func (d *Database) Metrics() QueryMetrics {
+	return QueryMetrics{
+		NumQueries:     atomic.LoadInt64(&_numQueries),
+		NumSlowQueries: atomic.LoadInt64(&_numSlowQueries),
+		NumLockWaits:   atomic.LoadInt64(&_numLockWaits),
+		TotalDuration:  time.Duration(atomic.LoadInt64(&_totalDuration)),
+		MaxDuration:    time.Duration(atomic.LoadInt64(&_maxDuration)),
+	}
+}
+
+// record the duration (and the error) of given query
+func recordQuery(query string, started time.Time, err error) {
+	duration := time.Since(started)
+
+	atomic.AddInt64(&_numQueries, 1)
+	atomic.AddInt64(&_totalDuration, int64(duration))
+	for {
+		max := atomic.LoadInt64(&_maxDuration)
+		if int64(duration) <= max || atomic.CompareAndSwapInt64(&_maxDuration, max, int64(duration)) {
+			break
+		}
+	}
+
+	if err != nil && isLockError(err) {
+		atomic.AddInt64(&_numLockWaits, 1)
+	}
+
+	if threshold := atomic.LoadInt64(&_slowQueryThreshold); threshold > 0 && int64(duration) >= threshold {
+		atomic.AddInt64(&_numSlowQueries, 1)
+
+		log.Printf("*** Slow query (%s): %s\n", duration, strings.Join(strings.Fields(query), " "))
+	}
+}
+
+// check if given error is from a locked (busy) database
+func isLockError(err error) bool {
+	message := err.Error()
+	return strings.Contains(message, "database is locked") || strings.Contains(message, "database table is locked") || strings.Contains(message, "SQLITE_BUSY")
+}
+
+// driver which wraps statements for timing them
+type instrumentedDriver struct {
+	driver driver.Driver
+}
+
+func (d instrumentedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return instrumentedConn{Conn: conn}, nil
+}
+
+type instrumentedConn struct {
+	driver.Conn
+}
+
+func (c instrumentedConn) Prepare(query string) (driver.Stmt, error) {
+	started := time.Now()
+	stmt, err := c.Conn.Prepare(query)
+	if err != nil {
+		recordQuery(query, started, err)
+		return nil, err
+	}
+	return instrumentedStmt{Stmt: stmt, query: query}, nil
+}
+
+func (c instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+type instrumentedStmt struct {
+	driver.Stmt
+	query string
+}
+
+func (s instrumentedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	started := time.Now()
+	result, err := s.Stmt.Exec(args)
+	recordQuery(s.query, started, err)
+	return result, err
+}
+
+func (s instrumentedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	started := time.Now()
+	rows, err := s.Stmt.Query(args)
+	recordQuery(s.query, started, err)
+	return rows, err
+}
+
+func (s instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		started := time.Now()
+		result, err := execer.ExecContext(ctx, args)
+		recordQuery(s.query, started, err)
+		return result, err
+	}
+	return s.Exec(namedValuesToValues(args))
+}
+
+func (s instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		started := time.Now()
+		rows, err := queryer.QueryContext(ctx, args)
+		recordQuery(s.query, started, err)
+		return rows, err
+	}
+	return s.Query(namedValuesToValues(args))
+}
+
+// (for drivers without context support)
+func namedValuesToValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
@@ -0,0 +1,472 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// MigrationCount struct (numbers of rows of a table before and after migration)
+type MigrationCount struct {
+	Table     string
+	NumSource int
+	NumTarget int
+}
+
+// MigrateTo copies all rows of the database into a new sqlite database at given path,
+// which is created with the current schema
+//
+// returns the numbers of rows of each table in both databases for verification
+func (d *Database) MigrateTo(filepath string) (counts []MigrationCount, err error) {
+	if _, err := os.Stat(filepath); err == nil {
+		return nil, fmt.Errorf("target database already exists: %s", filepath)
+	}
+
+	// create the target database with the current schema
+	target, err := sql.Open("sqlite3", filepath)
+	if err != nil {
+		return nil, err
+	}
+	err = func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%v", r)
+			}
+		}()
+		createTables(target)
+		return nil
+	}()
+	target.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tables in target database: %s", err)
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	// (attached databases are per connection)
+	ctx := context.Background()
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `attach database ? as target`, filepath); err != nil {
+		return nil, fmt.Errorf("failed to attach target database: %s", err)
+	}
+	defer conn.ExecContext(ctx, `detach database target`)
+
+	tables, err := tableNames(ctx, conn, "main")
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		sourceColumns, err := columnNames(ctx, tx, "main", table)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		targetColumns, err := columnNames(ctx, tx, "target", table)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		// copy only the columns which exist in both of them
+		columns := []string{}
+		for _, column := range sourceColumns {
+			for _, c := range targetColumns {
+				if c == column {
+					columns = append(columns, column)
+					break
+				}
+			}
+		}
+		if len(columns) <= 0 {
+			continue
+		}
+
+		joined := strings.Join(columns, ", ")
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`insert into target.%s(%s) select %s from main.%s`, table, joined, joined, table)); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to copy table %s: %s", table, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// count rows for verification
+	for _, table := range tables {
+		count := MigrationCount{Table: table, NumTarget: -1}
+		if err := conn.QueryRowContext(ctx, fmt.Sprintf(`select count(*) from main.%s`, table)).Scan(&count.NumSource); err != nil {
+			return nil, err
+		}
+		conn.QueryRowContext(ctx, fmt.Sprintf(`select count(*) from target.%s`, table)).Scan(&count.NumTarget)
+
+		counts = append(counts, count)
+	}
+
+	return counts, nil
+}
+
+// MigrateToPostgres copies all rows of the database into a postgres database at given url,
+// where tables and indexes of the current schema are created
+//
+// (the target database should not have any of the tables yet: nothing is created or copied if it has one)
+//
+// returns the numbers of rows of each table in both databases for verification
+func (d *Database) MigrateToPostgres(url string) (counts []MigrationCount, err error) {
+	target, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+	defer target.Close()
+
+	ctx := context.Background()
+	if err := target.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to connect to target database: %s", err)
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	tables, err := tableNames(ctx, conn, "main")
+	if err != nil {
+		return nil, err
+	}
+
+	// (schema changes of postgres are transactional too)
+	tx, err := target.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		if err := copyTableToPostgres(ctx, conn, tx, table); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// count rows for verification
+	for _, table := range tables {
+		count := MigrationCount{Table: table, NumTarget: -1}
+		if err := conn.QueryRowContext(ctx, fmt.Sprintf(`select count(*) from main.%s`, table)).Scan(&count.NumSource); err != nil {
+			return nil, err
+		}
+		target.QueryRowContext(ctx, fmt.Sprintf(`select count(*) from %s`, pq.QuoteIdentifier(table))).Scan(&count.NumTarget)
+
+		counts = append(counts, count)
+	}
+
+	return counts, nil
+}
+
+// create given table (with its indexes) in the postgres transaction, then copy all rows of it
+func copyTableToPostgres(ctx context.Context, conn *sql.Conn, tx *sql.Tx, table string) error {
+	columns, err := tableColumns(ctx, conn, "main", table)
+	if err != nil {
+		return err
+	}
+	if len(columns) <= 0 {
+		return nil
+	}
+
+	var schema string
+	if err := conn.QueryRowContext(ctx, `select sql from main.sqlite_master where type = 'table' and name = ?`, table).Scan(&schema); err != nil {
+		return err
+	}
+
+	// primary key (in its order)
+	primaryKey := []string{}
+	for i := 1; i <= len(columns); i++ {
+		for _, c := range columns {
+			if c.pk == i {
+				primaryKey = append(primaryKey, pq.QuoteIdentifier(c.name))
+			}
+		}
+	}
+
+	// (`integer primary key autoincrement` becomes an identity column)
+	identity := ""
+	if len(primaryKey) == 1 && strings.Contains(strings.ToLower(schema), "autoincrement") {
+		for _, c := range columns {
+			if c.pk == 1 && postgresType(c.typ) == "bigint" {
+				identity = c.name
+			}
+		}
+	}
+
+	// create table
+	definitions, names, placeholders := []string{}, []string{}, []string{}
+	for i, c := range columns {
+		definition := fmt.Sprintf("%s %s", pq.QuoteIdentifier(c.name), postgresType(c.typ))
+		if c.name == identity {
+			definition += " generated by default as identity"
+		} else if c.dflt.Valid {
+			definition += " default " + postgresDefault(c.dflt.String)
+		}
+		if c.notNull {
+			definition += " not null"
+		}
+		definitions = append(definitions, definition)
+
+		names = append(names, pq.QuoteIdentifier(c.name))
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+	}
+	if len(primaryKey) > 0 {
+		definitions = append(definitions, fmt.Sprintf("primary key(%s)", strings.Join(primaryKey, ", ")))
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`create table %s(%s)`, pq.QuoteIdentifier(table), strings.Join(definitions, ", "))); err != nil {
+		return fmt.Errorf("failed to create table %s in target database: %s", table, err)
+	}
+
+	// create indexes (including the ones of unique constraints)
+	indexes, err := tableIndexes(ctx, conn, table)
+	if err != nil {
+		return err
+	}
+	for i, index := range indexes {
+		if index.partial {
+			return fmt.Errorf("partial index %s is not supported", index.name)
+		}
+
+		name := index.name
+		if index.origin == "u" {
+			name = fmt.Sprintf("%s_unique%d", table, i+1) // (instead of sqlite_autoindex_*)
+		}
+		unique := ""
+		if index.unique {
+			unique = "unique "
+		}
+		quoted := []string{}
+		for _, column := range index.columns {
+			quoted = append(quoted, pq.QuoteIdentifier(column))
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`create %sindex %s on %s(%s)`, unique, pq.QuoteIdentifier(name), pq.QuoteIdentifier(table), strings.Join(quoted, ", "))); err != nil {
+			return fmt.Errorf("failed to create index %s in target database: %s", name, err)
+		}
+	}
+
+	// copy rows
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`insert into %s(%s) values(%s)`, pq.QuoteIdentifier(table), strings.Join(names, ", "), strings.Join(placeholders, ", ")))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(`select %s from main.%s`, strings.Join(names, ", "), table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	values, pointers := make([]interface{}, len(columns)), make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+
+		// (texts can be scanned as bytes)
+		for i, value := range values {
+			if bytes, isBytes := value.([]byte); isBytes && postgresType(columns[i].typ) != "bytea" {
+				values[i] = string(bytes)
+			}
+		}
+
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return fmt.Errorf("failed to copy table %s: %s", table, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// continue the identity from the copied ones
+	if identity != "" {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`select setval(pg_get_serial_sequence(%s, %s), coalesce(max(%s), 0) + 1, false) from %s`, pq.QuoteLiteral(pq.QuoteIdentifier(table)), pq.QuoteLiteral(identity), pq.QuoteIdentifier(identity), pq.QuoteIdentifier(table))); err != nil {
+			return fmt.Errorf("failed to reset identity of table %s: %s", table, err)
+		}
+	}
+
+	return nil
+}
+
+// postgres type for given type of sqlite column (by the affinity rules of sqlite)
+func postgresType(typ string) string {
+	t := strings.ToUpper(typ)
+	switch {
+	case strings.Contains(t, "INT"):
+		return "bigint"
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		return "text"
+	case t == "", strings.Contains(t, "BLOB"):
+		return "bytea"
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return "double precision"
+	}
+	return "numeric"
+}
+
+// postgres expression for given default value of sqlite column
+func postgresDefault(dflt string) string {
+	if strings.HasPrefix(dflt, "strftime('%s'") { // (current unix time)
+		return "(extract(epoch from now())::bigint)"
+	}
+	return dflt
+}
+
+// querier for both connections and transactions
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// names of user tables in given schema
+func tableNames(ctx context.Context, q querier, schema string) (names []string, err error) {
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(`select name from %s.sqlite_master where type = 'table' and name not like 'sqlite_%%' order by name`, schema))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var name string
+	for rows.Next() {
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// names of columns of given table in given schema
+func columnNames(ctx context.Context, q querier, schema, table string) (names []string, err error) {
+	columns, err := tableColumns(ctx, q, schema, table)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, column := range columns {
+		names = append(names, column.name)
+	}
+
+	return names, nil
+}
+
+// column of a table (from `pragma table_info`)
+type tableColumn struct {
+	name    string
+	typ     string
+	notNull bool
+	dflt    sql.NullString
+	pk      int // position in the primary key (0 if not a part of it)
+}
+
+// columns of given table in given schema
+func tableColumns(ctx context.Context, q querier, schema, table string) (columns []tableColumn, err error) {
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(`pragma %s.table_info(%s)`, schema, table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cid int
+	for rows.Next() {
+		var column tableColumn
+		if err := rows.Scan(&cid, &column.name, &column.typ, &column.notNull, &column.dflt, &column.pk); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, rows.Err()
+}
+
+// index of a table (from `pragma index_list` and `pragma index_info`)
+type tableIndex struct {
+	name    string
+	unique  bool
+	origin  string // 'c': created explicitly, 'u': unique constraint, 'pk': primary key
+	partial bool
+	columns []string
+}
+
+// indexes of given table in the main schema (except the ones of primary keys)
+func tableIndexes(ctx context.Context, q querier, table string) (indexes []tableIndex, err error) {
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(`pragma main.index_list(%s)`, table))
+	if err != nil {
+		return nil, err
+	}
+
+	var seq int
+	for rows.Next() {
+		var index tableIndex
+		if err := rows.Scan(&seq, &index.name, &index.unique, &index.origin, &index.partial); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if index.origin != "pk" {
+			indexes = append(indexes, index)
+		}
+	}
+	rows.Close()
+
+	for i, index := range indexes {
+		if indexes[i].columns, err = indexColumns(ctx, q, index.name); err != nil {
+			return nil, err
+		}
+	}
+
+	// (in the order of creation)
+	for i, j := 0, len(indexes)-1; i < j; i, j = i+1, j-1 {
+		indexes[i], indexes[j] = indexes[j], indexes[i]
+	}
+
+	return indexes, nil
+}
+
+// names of columns of given index in the main schema
+func indexColumns(ctx context.Context, q querier, index string) (names []string, err error) {
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(`pragma main.index_info(%s)`, index))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var seqno, cid int
+	var name sql.NullString
+	for rows.Next() {
+		if err := rows.Scan(&seqno, &cid, &name); err != nil {
+			return nil, err
+		}
+		if !name.Valid {
+			return nil, fmt.Errorf("index on expressions is not supported: %s", index)
+		}
+		names = append(names, name.String)
+	}
+
+	return names, rows.Err()
+}
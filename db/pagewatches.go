@@ -0,0 +1,180 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// PageWatch struct (a web page watched for changes of its content)
+type PageWatch struct {
+	ID              int64     `json:"id"`
+	ChatID          int64     `json:"chat_id"`
+	URL             string    `json:"url"`
+	Selector        string    `json:"selector,omitempty"` // CSS selector of the watched part (whole page if empty)
+	Title           string    `json:"title,omitempty"`
+	IntervalMinutes int       `json:"interval_minutes"`
+	ContentHash     string    `json:"-"`
+	NumFailures     int       `json:"num_failures"` // consecutive failures of checking
+	NextCheckOn     time.Time `json:"next_check_on"`
+	ChangedOn       time.Time `json:"changed_on,omitempty"`
+	AddedOn         time.Time `json:"added_on"`
+}
+
+// SavePageWatch saves a page watch for given chat
+func (d *Database) SavePageWatch(w PageWatch) (watchID int64, result bool) {
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or ignore into page_watches(chat_id, url, selector, title, interval_minutes, content_hash, next_check_on)
+		values(?, ?, ?, nullif(?, ''), ?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(w.ChatID, w.URL, w.Selector, w.Title, w.IntervalMinutes, w.ContentHash, w.NextCheckOn.Unix()); err != nil {
+			log.Printf("*** Failed to save page watch into local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			watchID, _ = res.LastInsertId()
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return watchID, result
+}
+
+// DeletePageWatch deletes given page watch of given chat
+func (d *Database) DeletePageWatch(chatID, watchID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`delete from page_watches where id = ? and chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(watchID, chatID); err != nil {
+			log.Printf("*** Failed to delete page watch from local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// PageWatches returns page watches of given chat
+func (d *Database) PageWatches(chatID int64) []PageWatch {
+	return d.pageWatches(`where chat_id = ?`, chatID)
+}
+
+// DuePageWatches returns page watches which should be checked at given time
+func (d *Database) DuePageWatches(now time.Time) []PageWatch {
+	return d.pageWatches(`where next_check_on <= ?`, now.Unix())
+}
+
+func (d *Database) pageWatches(condition string, args ...interface{}) []PageWatch {
+	watches := []PageWatch{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select
+		id,
+		chat_id,
+		url,
+		selector,
+		ifnull(title, '') as title,
+		interval_minutes,
+		ifnull(content_hash, '') as content_hash,
+		num_failures,
+		next_check_on,
+		ifnull(changed_on, 0) as changed_on,
+		added_on
+		from page_watches
+		` + condition + `
+		order by id`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(args...); err != nil {
+			log.Printf("*** Failed to select page watches from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var w PageWatch
+			var nextCheckOn, changedOn, addedOn int64
+			for rows.Next() {
+				if err := rows.Scan(&w.ID, &w.ChatID, &w.URL, &w.Selector, &w.Title, &w.IntervalMinutes, &w.ContentHash, &w.NumFailures, &nextCheckOn, &changedOn, &addedOn); err != nil {
+					log.Printf("*** Failed to scan page watch: %s\n", err.Error())
+					continue
+				}
+				w.NextCheckOn = time.Unix(nextCheckOn, 0)
+				w.ChangedOn = time.Unix(changedOn, 0)
+				w.AddedOn = time.Unix(addedOn, 0)
+
+				watches = append(watches, w)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return watches
+}
+
+// UpdatePageWatchChecked updates the content hash (changed_on too, if given) and the next check time of given page watch after a successful check
+func (d *Database) UpdatePageWatchChecked(watchID int64, contentHash string, changed bool, nextCheckOn time.Time) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update page_watches
+		set content_hash = ?,
+			num_failures = 0,
+			next_check_on = ?,
+			changed_on = case when ? then strftime('%s', 'now') else changed_on end
+		where id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(contentHash, nextCheckOn.Unix(), changed, watchID); err != nil {
+			log.Printf("*** Failed to update page watch in local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// UpdatePageWatchFailed increases the number of consecutive failures and updates the next check time of given page watch
+func (d *Database) UpdatePageWatchFailed(watchID int64, nextCheckOn time.Time) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update page_watches set num_failures = num_failures + 1, next_check_on = ? where id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(nextCheckOn.Unix(), watchID); err != nil {
+			log.Printf("*** Failed to update page watch in local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
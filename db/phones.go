@@ -0,0 +1,137 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// PhoneNumber struct (a phone number registered for receiving SMS fallbacks of a chat)
+type PhoneNumber struct {
+	ChatID       int64     `json:"chat_id"`
+	UserID       int64     `json:"user_id"` // user who registered it
+	Number       string    `json:"number"`  // E.164 (eg. "+821012345678")
+	IsVerified   bool      `json:"is_verified"`
+	CodeHash     string    `json:"-"` // (hash of the verification code)
+	CodeSentOn   time.Time `json:"-"`
+	NumAttempts  int       `json:"-"` // (failed attempts of verification)
+	RegisteredOn time.Time `json:"registered_on"`
+}
+
+// SavePhoneNumber saves (or replaces) the unverified phone number of given chat with the hash of its verification code
+func (d *Database) SavePhoneNumber(chatID, userID int64, number, codeHash string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or replace into phone_numbers(chat_id, user_id, number, is_verified, code_hash, code_sent_on, num_attempts)
+		values(?, ?, ?, 0, ?, strftime('%s', 'now'), 0)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(chatID, userID, number, codeHash); err != nil {
+			log.Printf("*** Failed to save phone number into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// PhoneNumber returns the phone number of given chat
+func (d *Database) PhoneNumber(chatID int64) (p PhoneNumber, exists bool) {
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select chat_id, user_id, number, is_verified, ifnull(code_hash, ''), code_sent_on, num_attempts, registered_on from phone_numbers where chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var codeSentOn, registeredOn int64
+		if err := stmt.QueryRow(chatID).Scan(&p.ChatID, &p.UserID, &p.Number, &p.IsVerified, &p.CodeHash, &codeSentOn, &p.NumAttempts, &registeredOn); err == nil {
+			p.CodeSentOn = time.Unix(codeSentOn, 0)
+			p.RegisteredOn = time.Unix(registeredOn, 0)
+
+			exists = true
+		} else if err != sql.ErrNoRows {
+			log.Printf("*** Failed to select phone number from local database: %s\n", err.Error())
+		}
+	}
+
+	d.RUnlock()
+
+	return p, exists
+}
+
+// VerifyPhoneNumber marks the phone number of given chat as verified (and forgets its verification code)
+func (d *Database) VerifyPhoneNumber(chatID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update phone_numbers set is_verified = 1, code_hash = null, registered_on = strftime('%s', 'now') where chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(chatID); err != nil {
+			log.Printf("*** Failed to update phone number in local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// IncreasePhoneVerificationAttempts increases the number of failed verifications of the phone number of given chat
+func (d *Database) IncreasePhoneVerificationAttempts(chatID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update phone_numbers set num_attempts = num_attempts + 1 where chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(chatID); err != nil {
+			log.Printf("*** Failed to update phone number in local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// DeletePhoneNumber deletes the phone number of given chat
+func (d *Database) DeletePhoneNumber(chatID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`delete from phone_numbers where chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(chatID); err != nil {
+			log.Printf("*** Failed to delete phone number from local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
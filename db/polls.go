@@ -0,0 +1,92 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+)
+
+// Poll struct
+type Poll struct {
+	ID        int64     `json:"id"`
+	ChatID    int64     `json:"chat_id"`
+	Question  string    `json:"question"`
+	Options   []string  `json:"options"`
+	MessageID int       `json:"message_id,omitempty"` // message of the sent poll
+	ClosesOn  time.Time `json:"closes_on,omitempty"`
+}
+
+// SavePoll saves a new poll
+func (d *Database) SavePoll(p Poll) (pollID int64, result bool) {
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into polls(chat_id, question, options) values(?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(p.ChatID, p.Question, strings.Join(p.Options, "\n")); err != nil {
+			log.Printf("*** Failed to save poll into local database: %s\n", err.Error())
+		} else {
+			pollID, _ = res.LastInsertId()
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return pollID, result
+}
+
+// SetPollSent saves the message and closing time of given sent poll
+func (d *Database) SetPollSent(pollID int64, messageID int, closesOn time.Time) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update polls set message_id = ?, closes_on = ? where id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(messageID, closesOn.Unix(), pollID); err != nil {
+			log.Printf("*** Failed to update poll in local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// Poll returns the poll with given id
+func (d *Database) Poll(pollID int64) (p Poll, exists bool) {
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select id, chat_id, question, options, message_id, ifnull(closes_on, 0)
+		from polls
+		where id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var options string
+		var closesOn int64
+		if err := stmt.QueryRow(pollID).Scan(&p.ID, &p.ChatID, &p.Question, &options, &p.MessageID, &closesOn); err == nil {
+			p.Options = strings.Split(options, "\n")
+			if closesOn > 0 {
+				p.ClosesOn = time.Unix(closesOn, 0)
+			}
+			exists = true
+		} else if err != sql.ErrNoRows {
+			log.Printf("*** Failed to select poll from local database: %s\n", err.Error())
+		}
+	}
+
+	d.RUnlock()
+
+	return p, exists
+}
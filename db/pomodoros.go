@@ -0,0 +1,161 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// phases of pomodoros
+const (
+	PomodoroPhaseWork  = "work"
+	PomodoroPhaseBreak = "break"
+)
+
+// Pomodoro struct (current pomodoro session of a chat)
+type Pomodoro struct {
+	ChatID           int64     `json:"chat_id"`
+	UserID           int64     `json:"user_id,omitempty"`
+	WorkMinutes      int       `json:"work_minutes"`
+	BreakMinutes     int       `json:"break_minutes"`
+	Phase            string    `json:"phase"`
+	Paused           bool      `json:"paused"`
+	QueueID          int64     `json:"queue_id"`          // queue item for the end of current phase
+	RemainingSeconds int       `json:"remaining_seconds"` // remaining time of current phase (when paused)
+	StartedOn        time.Time `json:"started_on"`
+}
+
+// SavePomodoro saves (or replaces) the pomodoro session of its chat
+func (d *Database) SavePomodoro(p Pomodoro) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or replace into pomodoros(chat_id, user_id, work_minutes, break_minutes, phase, paused, queue_id, remaining_seconds, started_on)
+		values(?, ?, ?, ?, ?, ?, ?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if p.StartedOn.IsZero() {
+			p.StartedOn = time.Now()
+		}
+
+		if _, err = stmt.Exec(p.ChatID, p.UserID, p.WorkMinutes, p.BreakMinutes, p.Phase, p.Paused, p.QueueID, p.RemainingSeconds, p.StartedOn.Unix()); err != nil {
+			log.Printf("*** Failed to save pomodoro into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// Pomodoro returns the pomodoro session of given chat
+func (d *Database) Pomodoro(chatID int64) (p Pomodoro, exists bool) {
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select chat_id, user_id, work_minutes, break_minutes, phase, paused, queue_id, remaining_seconds, started_on
+		from pomodoros
+		where chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var startedOn int64
+		if err := stmt.QueryRow(chatID).Scan(&p.ChatID, &p.UserID, &p.WorkMinutes, &p.BreakMinutes, &p.Phase, &p.Paused, &p.QueueID, &p.RemainingSeconds, &startedOn); err == nil {
+			p.StartedOn = time.Unix(startedOn, 0)
+			exists = true
+		} else if err != sql.ErrNoRows {
+			log.Printf("*** Failed to select pomodoro from local database: %s\n", err.Error())
+		}
+	}
+
+	d.RUnlock()
+
+	return p, exists
+}
+
+// DeletePomodoro deletes the pomodoro session of given chat
+func (d *Database) DeletePomodoro(chatID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`delete from pomodoros where chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(chatID); err != nil {
+			log.Printf("*** Failed to delete pomodoro from local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// SavePomodoroCompletion records a completed pomodoro of given chat
+func (d *Database) SavePomodoroCompletion(chatID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into pomodoro_logs(chat_id) values(?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(chatID); err != nil {
+			log.Printf("*** Failed to save pomodoro log into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// PomodoroCompletionsByDay returns the numbers of completed pomodoros of given chat by day (in local time, eg. "2017-12-31")
+func (d *Database) PomodoroCompletionsByDay(chatID int64, since time.Time) map[string]int {
+	counts := map[string]int{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select strftime('%Y-%m-%d', completed_on, 'unixepoch', 'localtime') as day, count(*)
+		from pomodoro_logs
+		where chat_id = ? and completed_on >= ?
+		group by day`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID, since.Unix()); err != nil {
+			log.Printf("*** Failed to select pomodoro logs from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var day string
+			var count int
+			for rows.Next() {
+				if err := rows.Scan(&day, &count); err != nil {
+					log.Printf("*** Failed to scan pomodoro log: %s\n", err.Error())
+					continue
+				}
+				counts[day] = count
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return counts
+}
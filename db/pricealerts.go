@@ -0,0 +1,138 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// PriceAlert struct (a threshold alert of a price, like exchange rates, crypto, or stocks)
+type PriceAlert struct {
+	ID        int64     `json:"id"`
+	ChatID    int64     `json:"chat_id"`
+	UserID    int64     `json:"user_id"`
+	Source    string    `json:"source"` // name of the data source (eg. "fx", "crypto", "stock")
+	Symbol    string    `json:"symbol"` // symbol in the data source (eg. "USD/KRW", "BTC", "AAPL")
+	Name      string    `json:"name"`   // displayed name (eg. "달러 환율")
+	Above     bool      `json:"above"`  // alert when the price goes above (or below, if false) the threshold
+	Threshold float64   `json:"threshold"`
+	LastValue float64   `json:"last_value"` // (for alerting only when the price crosses the threshold)
+	AddedOn   time.Time `json:"added_on"`
+}
+
+// SavePriceAlert saves a price alert
+func (d *Database) SavePriceAlert(a PriceAlert) (alertID int64, result bool) {
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into price_alerts(chat_id, user_id, source, symbol, name, above, threshold, last_value) values(?, ?, ?, ?, ?, ?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(a.ChatID, a.UserID, a.Source, a.Symbol, a.Name, a.Above, a.Threshold, a.LastValue); err != nil {
+			log.Printf("*** Failed to save price alert into local database: %s\n", err.Error())
+		} else {
+			alertID, _ = res.LastInsertId()
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return alertID, result
+}
+
+// DeletePriceAlert deletes given price alert of given chat
+func (d *Database) DeletePriceAlert(chatID, alertID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`delete from price_alerts where id = ? and chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(alertID, chatID); err != nil {
+			log.Printf("*** Failed to delete price alert from local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// PriceAlerts returns price alerts of given chat (or all price alerts if chatID is 0)
+func (d *Database) PriceAlerts(chatID int64) []PriceAlert {
+	alerts := []PriceAlert{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select
+		id,
+		chat_id,
+		user_id,
+		source,
+		symbol,
+		name,
+		above,
+		threshold,
+		last_value,
+		added_on
+		from price_alerts
+		where ? = 0 or chat_id = ?
+		order by id`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID, chatID); err != nil {
+			log.Printf("*** Failed to select price alerts from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var a PriceAlert
+			var addedOn int64
+			for rows.Next() {
+				if err := rows.Scan(&a.ID, &a.ChatID, &a.UserID, &a.Source, &a.Symbol, &a.Name, &a.Above, &a.Threshold, &a.LastValue, &addedOn); err != nil {
+					log.Printf("*** Failed to scan price alert: %s\n", err.Error())
+					continue
+				}
+				a.AddedOn = time.Unix(addedOn, 0)
+
+				alerts = append(alerts, a)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return alerts
+}
+
+// UpdatePriceAlertValue updates the last seen price of given price alert
+func (d *Database) UpdatePriceAlertValue(alertID int64, value float64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update price_alerts set last_value = ? where id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(value, alertID); err != nil {
+			log.Printf("*** Failed to update price alert in local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
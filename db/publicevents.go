@@ -0,0 +1,241 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PublicEvent struct (an operator-curated event, like holidays or releases, which chats can subscribe to)
+type PublicEvent struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	EventOn   time.Time `json:"event_on"`   // (midnight of the day)
+	Offsets   []int     `json:"offsets"`    // days before the event when countdowns are sent (eg. [7, 1, 0])
+	CreatedBy int64     `json:"created_by"` // user id of the admin
+	CreatedOn time.Time `json:"created_on"`
+}
+
+// PublicEventSubscription struct
+type PublicEventSubscription struct {
+	EventID      int64     `json:"event_id"`
+	ChatID       int64     `json:"chat_id"`
+	UserID       int64     `json:"user_id"` // user who subscribed
+	SubscribedOn time.Time `json:"subscribed_on"`
+}
+
+// SavePublicEvent saves a public event
+func (d *Database) SavePublicEvent(e PublicEvent) (eventID int64, result bool) {
+	offsets := []string{}
+	for _, days := range e.Offsets {
+		offsets = append(offsets, strconv.Itoa(days))
+	}
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into public_events(name, event_on, offsets, created_by) values(?, ?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(e.Name, e.EventOn.Unix(), strings.Join(offsets, ","), e.CreatedBy); err != nil {
+			log.Printf("*** Failed to save public event into local database: %s\n", err.Error())
+		} else {
+			eventID, _ = res.LastInsertId()
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return eventID, result
+}
+
+// DeletePublicEvent deletes given public event (with its subscriptions)
+func (d *Database) DeletePublicEvent(eventID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if tx, err := d.db.Begin(); err != nil {
+		log.Printf("*** Failed to begin a transaction: %s\n", err.Error())
+	} else {
+		if res, err := tx.Exec(`delete from public_events where id = ?`, eventID); err != nil {
+			log.Printf("*** Failed to delete public event from local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			if _, err := tx.Exec(`delete from public_event_subscriptions where event_id = ?`, eventID); err != nil {
+				log.Printf("*** Failed to delete public event subscriptions from local database: %s\n", err.Error())
+			} else if _, err := tx.Exec(`delete from public_event_fanouts where event_id = ?`, eventID); err != nil {
+				log.Printf("*** Failed to delete public event fanouts from local database: %s\n", err.Error())
+			} else {
+				result = true
+			}
+		}
+
+		if result {
+			if err := tx.Commit(); err != nil {
+				log.Printf("*** Failed to commit a transaction: %s\n", err.Error())
+				result = false
+			}
+		} else {
+			tx.Rollback()
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// PublicEvents returns public events on or after given day
+func (d *Database) PublicEvents(from time.Time) []PublicEvent {
+	events := []PublicEvent{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select id, name, event_on, offsets, created_by, created_on from public_events where event_on >= ? order by event_on, id`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(from.Unix()); err != nil {
+			log.Printf("*** Failed to select public events from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var e PublicEvent
+			var eventOn, createdOn int64
+			var offsets string
+			for rows.Next() {
+				if err := rows.Scan(&e.ID, &e.Name, &eventOn, &offsets, &e.CreatedBy, &createdOn); err != nil {
+					log.Printf("*** Failed to scan public event: %s\n", err.Error())
+					continue
+				}
+				e.EventOn = time.Unix(eventOn, 0)
+				e.CreatedOn = time.Unix(createdOn, 0)
+				e.Offsets = []int{}
+				for _, offset := range strings.Split(offsets, ",") {
+					if days, err := strconv.Atoi(offset); err == nil {
+						e.Offsets = append(e.Offsets, days)
+					}
+				}
+
+				events = append(events, e)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return events
+}
+
+// SubscribePublicEvent subscribes given chat to given public event
+func (d *Database) SubscribePublicEvent(eventID, chatID, userID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or replace into public_event_subscriptions(event_id, chat_id, user_id) values(?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(eventID, chatID, userID); err != nil {
+			log.Printf("*** Failed to save public event subscription into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// UnsubscribePublicEvent unsubscribes given chat from given public event
+func (d *Database) UnsubscribePublicEvent(eventID, chatID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`delete from public_event_subscriptions where event_id = ? and chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(eventID, chatID); err != nil {
+			log.Printf("*** Failed to delete public event subscription from local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// PublicEventSubscriptions returns subscriptions of given public event and/or chat (0 for any)
+func (d *Database) PublicEventSubscriptions(eventID, chatID int64) []PublicEventSubscription {
+	subscriptions := []PublicEventSubscription{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select event_id, chat_id, user_id, subscribed_on from public_event_subscriptions
+		where (? = 0 or event_id = ?) and (? = 0 or chat_id = ?)
+		order by event_id, chat_id`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(eventID, eventID, chatID, chatID); err != nil {
+			log.Printf("*** Failed to select public event subscriptions from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var s PublicEventSubscription
+			var subscribedOn int64
+			for rows.Next() {
+				if err := rows.Scan(&s.EventID, &s.ChatID, &s.UserID, &subscribedOn); err != nil {
+					log.Printf("*** Failed to scan public event subscription: %s\n", err.Error())
+					continue
+				}
+				s.SubscribedOn = time.Unix(subscribedOn, 0)
+
+				subscriptions = append(subscriptions, s)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return subscriptions
+}
+
+// MarkPublicEventFannedOut marks the countdown of given public event (days before it) as fanned out, returns false if it was marked already
+func (d *Database) MarkPublicEventFannedOut(eventID int64, days int) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or ignore into public_event_fanouts(event_id, days) values(?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(eventID, days); err != nil {
+			log.Printf("*** Failed to save public event fanout into local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"log"
+	"time"
+)
+
+// push platforms
+const (
+	PushPlatformFCM  = "fcm"  // Firebase Cloud Messaging (Android, web/PWA)
+	PushPlatformAPNs = "apns" // Apple Push Notification service (iOS)
+)
+
+// PushToken struct (a device of a companion app which receives pushes of reminders)
+type PushToken struct {
+	ID           int64     `json:"id"`
+	ChatID       int64     `json:"chat_id"`
+	Platform     string    `json:"platform"`
+	Token        string    `json:"token"`
+	Name         string    `json:"name,omitempty"` // (eg. device name)
+	RegisteredOn time.Time `json:"registered_on"`
+}
+
+// SavePushToken saves (or moves to given chat) a push token
+func (d *Database) SavePushToken(chatID int64, platform, token, name string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or replace into push_tokens(chat_id, platform, token, name) values(?, ?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(chatID, platform, token, name); err != nil {
+			log.Printf("*** Failed to save push token into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// PushTokens returns push tokens of given chat
+func (d *Database) PushTokens(chatID int64) []PushToken {
+	tokens := []PushToken{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select id, chat_id, platform, token, ifnull(name, ''), registered_on from push_tokens where chat_id = ? order by id`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID); err != nil {
+			log.Printf("*** Failed to select push tokens from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var t PushToken
+			var registeredOn int64
+			for rows.Next() {
+				if err := rows.Scan(&t.ID, &t.ChatID, &t.Platform, &t.Token, &t.Name, &registeredOn); err != nil {
+					log.Printf("*** Failed to scan push token: %s\n", err.Error())
+					continue
+				}
+				t.RegisteredOn = time.Unix(registeredOn, 0)
+
+				tokens = append(tokens, t)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return tokens
+}
+
+// DeletePushToken deletes a push token of given chat
+func (d *Database) DeletePushToken(chatID, id int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`delete from push_tokens where chat_id = ? and id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(chatID, id); err != nil {
+			log.Printf("*** Failed to delete push token from local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// DeleteInvalidPushToken deletes given push token (rejected by its push service)
+func (d *Database) DeleteInvalidPushToken(platform, token string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`delete from push_tokens where platform = ? and token = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(platform, token); err != nil {
+			log.Printf("*** Failed to delete push token from local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// benchmarks of the queue with a database filled with synthetic reminders
+//
+//	$ go test -bench . ./db/
+
+const (
+	benchRows            = 100000 // number of rows in the queue while benchmarking
+	benchDueRows         = 1000   // number of deliverable rows among them
+	benchChatIDsToSpread = 1000
+	benchDBOptions       = "?_sync=OFF&_journal_mode=MEMORY"
+)
+
+var _bench = struct {
+	sync.Once
+	dir string
+	err error
+}{}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+
+	CloseDb()
+	if _bench.dir != "" {
+		os.RemoveAll(_bench.dir)
+	}
+
+	os.Exit(code)
+}
+
+// database filled with synthetic reminders (shared by all benchmarks)
+func benchDb(b *testing.B) *Database {
+	_bench.Do(func() {
+		if _bench.dir, _bench.err = ioutil.TempDir("", "reminder-bench"); _bench.err != nil {
+			return
+		}
+
+		d := OpenDb(filepath.Join(_bench.dir, "bench.sqlite") + benchDBOptions)
+		for i := 0; i < benchRows; i++ {
+			fireOn := time.Now().Add(24 * time.Hour)
+			if i < benchDueRows {
+				fireOn = time.Now()
+			}
+			if !d.Enqueue(int64(i%benchChatIDsToSpread), fmt.Sprintf("synthetic reminder #%d", i), fireOn) {
+				_bench.err = fmt.Errorf("failed to fill queue")
+				return
+			}
+		}
+	})
+	if _bench.err != nil {
+		b.Fatal(_bench.err)
+	}
+
+	return _db
+}
+
+func BenchmarkEnqueue(b *testing.B) {
+	d := benchDb(b)
+	fireOn := time.Now().Add(24 * time.Hour)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.Enqueue(int64(i%benchChatIDsToSpread), "benchmark", fireOn)
+	}
+}
+
+func BenchmarkDeliverableQueueItems(b *testing.B) {
+	d := benchDb(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.DeliverableQueueItems(DefaultMaxNumTries)
+	}
+}
+
+func BenchmarkUndeliveredQueueItems(b *testing.B) {
+	d := benchDb(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.UndeliveredQueueItems(int64(i % benchChatIDsToSpread))
+	}
+}
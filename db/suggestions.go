@@ -0,0 +1,53 @@
+package db
+
+import (
+	"log"
+	"time"
+)
+
+// SaveSuggestion records that a suggestion was made for given chat and message
+func (d *Database) SaveSuggestion(chatID int64, message string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into suggestions(chat_id, message) values(?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(chatID, message); err != nil {
+			log.Printf("*** Failed to save suggestion into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// SuggestedSince checks if a suggestion was made for given chat and message after given time
+func (d *Database) SuggestedSince(chatID int64, message string, since time.Time) bool {
+	result := false
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select count(*) from suggestions where chat_id = ? and message = ? and suggested_on >= ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var count int
+		if err := stmt.QueryRow(chatID, message, since.Unix()).Scan(&count); err != nil {
+			log.Printf("*** Failed to select suggestions from local database: %s\n", err.Error())
+		} else {
+			result = count > 0
+		}
+	}
+
+	d.RUnlock()
+
+	return result
+}
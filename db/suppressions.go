@@ -0,0 +1,168 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// kinds of suppressions
+const (
+	SuppressionKindVacation = "vacation"
+	SuppressionKindFocus    = "focus"
+)
+
+// modes of suppressions
+const (
+	SuppressionModeSkip   = "skip"   // do not deliver reminders in the window
+	SuppressionModeDefer  = "defer"  // deliver them after the window
+	SuppressionModeDigest = "digest" // deliver them after the window, combined into one message
+)
+
+// Suppression struct
+type Suppression struct {
+	ID        int64     `json:"id"`
+	ChatID    int64     `json:"chat_id"`
+	Kind      string    `json:"kind"`
+	Mode      string    `json:"mode"`
+	StartsOn  time.Time `json:"starts_on"`
+	EndsOn    time.Time `json:"ends_on"`
+	CreatedOn time.Time `json:"created_on"`
+}
+
+// SaveSuppression saves a new suppression window
+func (d *Database) SaveSuppression(s Suppression) (id int64, result bool) {
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into suppressions(chat_id, kind, mode, starts_on, ends_on) values(?, ?, ?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(s.ChatID, s.Kind, s.Mode, s.StartsOn.Unix(), s.EndsOn.Unix()); err != nil {
+			log.Printf("*** Failed to save suppression into local database: %s\n", err.Error())
+		} else {
+			id, _ = res.LastInsertId()
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return id, result
+}
+
+// Suppressions returns current and upcoming suppression windows of given chat and kind
+func (d *Database) Suppressions(chatID int64, kind string) []Suppression {
+	suppressions := []Suppression{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select
+		id,
+		chat_id,
+		kind,
+		mode,
+		starts_on,
+		ends_on,
+		created_on
+		from suppressions
+		where chat_id = ? and kind = ? and ends_on > ?
+		order by starts_on`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID, kind, time.Now().Unix()); err != nil {
+			log.Printf("*** Failed to select suppressions from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			suppressions = scanSuppressions(rows)
+		}
+	}
+
+	d.RUnlock()
+
+	return suppressions
+}
+
+// ActiveSuppression returns the suppression window of given chat which is in effect at given time
+//
+// if there are overlapping ones, the one which ends last is returned
+func (d *Database) ActiveSuppression(chatID int64, at time.Time) (suppression Suppression, exists bool) {
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select
+		id,
+		chat_id,
+		kind,
+		mode,
+		starts_on,
+		ends_on,
+		created_on
+		from suppressions
+		where chat_id = ? and starts_on <= ? and ends_on > ?
+		order by ends_on desc
+		limit 1`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(chatID, at.Unix(), at.Unix()); err != nil {
+			log.Printf("*** Failed to select suppression from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			if suppressions := scanSuppressions(rows); len(suppressions) > 0 {
+				suppression, exists = suppressions[0], true
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return suppression, exists
+}
+
+// DeleteSuppressions deletes current and upcoming suppression windows of given chat and kind
+func (d *Database) DeleteSuppressions(chatID int64, kind string) (num int64) {
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`delete from suppressions where chat_id = ? and kind = ? and ends_on > ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(chatID, kind, time.Now().Unix()); err != nil {
+			log.Printf("*** Failed to delete suppressions from local database: %s\n", err.Error())
+		} else {
+			num, _ = res.RowsAffected()
+		}
+	}
+
+	d.Unlock()
+
+	return num
+}
+
+func scanSuppressions(rows *sql.Rows) []Suppression {
+	suppressions := []Suppression{}
+
+	var s Suppression
+	var startsOn, endsOn, createdOn int64
+	for rows.Next() {
+		if err := rows.Scan(&s.ID, &s.ChatID, &s.Kind, &s.Mode, &startsOn, &endsOn, &createdOn); err != nil {
+			log.Printf("*** Failed to scan suppression: %s\n", err.Error())
+			continue
+		}
+		s.StartsOn = time.Unix(startsOn, 0)
+		s.EndsOn = time.Unix(endsOn, 0)
+		s.CreatedOn = time.Unix(createdOn, 0)
+
+		suppressions = append(suppressions, s)
+	}
+
+	return suppressions
+}
@@ -0,0 +1,270 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Topic struct (a named channel which chats can subscribe to, and admins or authorized publishers post to)
+type Topic struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedBy int64     `json:"created_by"` // user id of the admin
+	CreatedOn time.Time `json:"created_on"`
+}
+
+// TopicSubscription struct
+type TopicSubscription struct {
+	TopicID      int64     `json:"topic_id"`
+	ChatID       int64     `json:"chat_id"`
+	UserID       int64     `json:"user_id"` // user who subscribed
+	SubscribedOn time.Time `json:"subscribed_on"`
+}
+
+// SaveTopic saves a topic
+func (d *Database) SaveTopic(t Topic) (topicID int64, result bool) {
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into topics(name, created_by) values(?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(t.Name, t.CreatedBy); err != nil {
+			log.Printf("*** Failed to save topic into local database: %s\n", err.Error())
+		} else {
+			topicID, _ = res.LastInsertId()
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return topicID, result
+}
+
+// DeleteTopic deletes given topic (with its subscriptions and publishers)
+func (d *Database) DeleteTopic(topicID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if tx, err := d.db.Begin(); err != nil {
+		log.Printf("*** Failed to begin a transaction: %s\n", err.Error())
+	} else {
+		if res, err := tx.Exec(`delete from topics where id = ?`, topicID); err != nil {
+			log.Printf("*** Failed to delete topic from local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			if _, err := tx.Exec(`delete from topic_subscriptions where topic_id = ?`, topicID); err != nil {
+				log.Printf("*** Failed to delete topic subscriptions from local database: %s\n", err.Error())
+			} else if _, err := tx.Exec(`delete from topic_publishers where topic_id = ?`, topicID); err != nil {
+				log.Printf("*** Failed to delete topic publishers from local database: %s\n", err.Error())
+			} else {
+				result = true
+			}
+		}
+
+		if result {
+			if err := tx.Commit(); err != nil {
+				log.Printf("*** Failed to commit a transaction: %s\n", err.Error())
+				result = false
+			}
+		} else {
+			tx.Rollback()
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// Topics returns all topics
+func (d *Database) Topics() []Topic {
+	topics := []Topic{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select id, name, created_by, created_on from topics order by name, id`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(); err != nil {
+			log.Printf("*** Failed to select topics from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var t Topic
+			var createdOn int64
+			for rows.Next() {
+				if err := rows.Scan(&t.ID, &t.Name, &t.CreatedBy, &createdOn); err != nil {
+					log.Printf("*** Failed to scan topic: %s\n", err.Error())
+					continue
+				}
+				t.CreatedOn = time.Unix(createdOn, 0)
+
+				topics = append(topics, t)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return topics
+}
+
+// AddTopicPublisher authorizes given user to publish to given topic
+func (d *Database) AddTopicPublisher(topicID, userID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or replace into topic_publishers(topic_id, user_id) values(?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(topicID, userID); err != nil {
+			log.Printf("*** Failed to save topic publisher into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// RemoveTopicPublisher revokes the authorization of given user for given topic
+func (d *Database) RemoveTopicPublisher(topicID, userID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`delete from topic_publishers where topic_id = ? and user_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(topicID, userID); err != nil {
+			log.Printf("*** Failed to delete topic publisher from local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// IsTopicPublisher checks if given user is authorized to publish to given topic
+func (d *Database) IsTopicPublisher(topicID, userID int64) bool {
+	result := false
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select count(*) from topic_publishers where topic_id = ? and user_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var count int
+		if err := stmt.QueryRow(topicID, userID).Scan(&count); err != nil {
+			log.Printf("*** Failed to select topic publisher from local database: %s\n", err.Error())
+		} else {
+			result = count > 0
+		}
+	}
+
+	d.RUnlock()
+
+	return result
+}
+
+// SubscribeTopic subscribes given chat to given topic
+func (d *Database) SubscribeTopic(topicID, chatID, userID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert or replace into topic_subscriptions(topic_id, chat_id, user_id) values(?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(topicID, chatID, userID); err != nil {
+			log.Printf("*** Failed to save topic subscription into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// UnsubscribeTopic unsubscribes given chat from given topic
+func (d *Database) UnsubscribeTopic(topicID, chatID int64) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`delete from topic_subscriptions where topic_id = ? and chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if res, err := stmt.Exec(topicID, chatID); err != nil {
+			log.Printf("*** Failed to delete topic subscription from local database: %s\n", err.Error())
+		} else if num, _ := res.RowsAffected(); num > 0 {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// TopicSubscriptions returns subscriptions of given topic and/or chat (0 for any)
+func (d *Database) TopicSubscriptions(topicID, chatID int64) []TopicSubscription {
+	subscriptions := []TopicSubscription{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select topic_id, chat_id, user_id, subscribed_on from topic_subscriptions
+		where (? = 0 or topic_id = ?) and (? = 0 or chat_id = ?)
+		order by topic_id, chat_id`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(topicID, topicID, chatID, chatID); err != nil {
+			log.Printf("*** Failed to select topic subscriptions from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var s TopicSubscription
+			var subscribedOn int64
+			for rows.Next() {
+				if err := rows.Scan(&s.TopicID, &s.ChatID, &s.UserID, &subscribedOn); err != nil {
+					log.Printf("*** Failed to scan topic subscription: %s\n", err.Error())
+					continue
+				}
+				s.SubscribedOn = time.Unix(subscribedOn, 0)
+
+				subscriptions = append(subscriptions, s)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return subscriptions
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// sources of training data
+const (
+	TrainingSourceMisparse      = "misparse"       // reported by users
+	TrainingSourceLowConfidence = "low_confidence" // scored low by api.ai
+)
+
+// TrainingData struct (a query which was (possibly) misparsed by api.ai)
+type TrainingData struct {
+	ID         int64     `json:"id"`
+	ChatID     int64     `json:"chat_id"`
+	UserID     int64     `json:"user_id"`
+	Source     string    `json:"source"`
+	Query      string    `json:"query"`
+	Response   string    `json:"response"` // (JSON)
+	Score      float64   `json:"score"`
+	Correction string    `json:"correction,omitempty"`
+	CreatedOn  time.Time `json:"created_on"`
+}
+
+// SaveTrainingData saves a (possibly) misparsed query and the response of api.ai (in JSON)
+func (d *Database) SaveTrainingData(chatID, userID int64, source, query, response string, score float64) (id int64, result bool) {
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into training_data(chat_id, user_id, source, query, response, score) values(?, ?, ?, ?, ?, ?)`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(chatID, userID, source, query, response, score); err != nil {
+			log.Printf("*** Failed to save training data into local database: %s\n", err.Error())
+		} else {
+			id, _ = res.LastInsertId()
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return id, result
+}
+
+// SetTrainingDataCorrection saves the user's correction of given training data
+func (d *Database) SetTrainingDataCorrection(chatID, id int64, correction string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update training_data set correction = ? where id = ? and chat_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(correction, id, chatID); err != nil {
+			log.Printf("*** Failed to update training data in local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// TrainingDataSince returns training data collected since given time (oldest first)
+func (d *Database) TrainingDataSince(since time.Time) []TrainingData {
+	data := []TrainingData{}
+
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select
+		id,
+		chat_id,
+		user_id,
+		ifnull(source, '') as source,
+		query,
+		response,
+		ifnull(score, 0) as score,
+		ifnull(correction, '') as correction,
+		created_on
+		from training_data
+		where created_on >= ?
+		order by id`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if rows, err := stmt.Query(since.Unix()); err != nil {
+			log.Printf("*** Failed to select training data from local database: %s\n", err.Error())
+		} else {
+			defer rows.Close()
+
+			var t TrainingData
+			var createdOn int64
+			for rows.Next() {
+				if err := rows.Scan(&t.ID, &t.ChatID, &t.UserID, &t.Source, &t.Query, &t.Response, &t.Score, &t.Correction, &createdOn); err != nil {
+					log.Printf("*** Failed to scan training data: %s\n", err.Error())
+					continue
+				}
+				t.CreatedOn = time.Unix(createdOn, 0)
+				data = append(data, t)
+			}
+		}
+	}
+
+	d.RUnlock()
+
+	return data
+}
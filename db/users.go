@@ -0,0 +1,144 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// User struct
+type User struct {
+	UserID      int64     `json:"user_id"`
+	Username    string    `json:"username,omitempty"`
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name,omitempty"`
+	AllowedAs   string    `json:"allowed_as,omitempty"`
+	FirstSeenOn time.Time `json:"first_seen_on"`
+	LastSeenOn  time.Time `json:"last_seen_on"`
+}
+
+// DisplayName returns a name for displaying
+func (u User) DisplayName() string {
+	name := strings.TrimSpace(fmt.Sprintf("%s %s", u.FirstName, u.LastName))
+	if u.Username != "" {
+		return fmt.Sprintf("%s (@%s)", name, u.Username)
+	}
+	return name
+}
+
+// SaveUser saves the latest seen names of given user
+func (d *Database) SaveUser(userID int64, username, firstName, lastName string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`insert into users(user_id, username, first_name, last_name) values(?, nullif(?, ''), ?, nullif(?, ''))
+		on conflict(user_id) do update set
+			username = excluded.username,
+			first_name = excluded.first_name,
+			last_name = excluded.last_name,
+			last_seen_on = strftime('%s', 'now')`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		if _, err = stmt.Exec(userID, username, firstName, lastName); err != nil {
+			log.Printf("*** Failed to save user into local database: %s\n", err.Error())
+		} else {
+			result = true
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// User returns the user with given id
+func (d *Database) User(userID int64) (user User, exists bool) {
+	d.RLock()
+
+	if stmt, err := d.db.Prepare(`select
+		user_id,
+		ifnull(username, '') as username,
+		first_name,
+		ifnull(last_name, '') as last_name,
+		ifnull(allowed_as, '') as allowed_as,
+		first_seen_on,
+		last_seen_on
+		from users
+		where user_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var firstSeenOn, lastSeenOn int64
+		if err := stmt.QueryRow(userID).Scan(&user.UserID, &user.Username, &user.FirstName, &user.LastName, &user.AllowedAs, &firstSeenOn, &lastSeenOn); err == nil {
+			user.FirstSeenOn = time.Unix(firstSeenOn, 0)
+			user.LastSeenOn = time.Unix(lastSeenOn, 0)
+			exists = true
+		} else if err != sql.ErrNoRows {
+			log.Printf("*** Failed to select user from local database: %s\n", err.Error())
+		}
+	}
+
+	d.RUnlock()
+
+	return user, exists
+}
+
+// AllowUser marks given user as allowed by given entry of the config (eg. his username at that time)
+func (d *Database) AllowUser(userID int64, allowedAs string) bool {
+	result := false
+
+	d.Lock()
+
+	if stmt, err := d.db.Prepare(`update users set allowed_as = ? where user_id = ?`); err != nil {
+		log.Printf("*** Failed to prepare a statement: %s\n", err.Error())
+	} else {
+		defer stmt.Close()
+
+		var res sql.Result
+		if res, err = stmt.Exec(allowedAs, userID); err != nil {
+			log.Printf("*** Failed to update allowed_as in local database: %s\n", err.Error())
+		} else {
+			if num, _ := res.RowsAffected(); num > 0 {
+				result = true
+			}
+		}
+	}
+
+	d.Unlock()
+
+	return result
+}
+
+// RevokeUsersNotAllowedBy clears allowed marks which were not granted by any of given entries
+func (d *Database) RevokeUsersNotAllowedBy(entries []string) bool {
+	result := false
+
+	d.Lock()
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(entries)), ", ")
+	args := []interface{}{}
+	for _, entry := range entries {
+		args = append(args, entry)
+	}
+
+	query := `update users set allowed_as = null where allowed_as is not null`
+	if len(entries) > 0 {
+		query += fmt.Sprintf(` and allowed_as not in (%s)`, placeholders)
+	}
+
+	if _, err := d.db.Exec(query, args...); err != nil {
+		log.Printf("*** Failed to revoke allowed users in local database: %s\n", err.Error())
+	} else {
+		result = true
+	}
+
+	d.Unlock()
+
+	return result
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	commandDDay = "/dday"
+
+	ddayNotifyHour = 9 // countdowns will be sent at this hour
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageDDayRegisteredFormat = "%s D-day를 %s로 등록했습니다. (%s)"
+	messageDDayCountdownsFormat = "%s에 알려드립니다"
+	messageDDayNoCountdowns     = "남은 알림은 없습니다"
+	messageDDayIsPast           = "이미 지난 날짜입니다."
+	messageNoDDays              = "등록된 D-day가 없습니다."
+	messageDDayUsage            = "사용 예: \"수능 D-day 등록해줘 11월 14일\""
+	messageDDayItemFormat       = "%s: %s (%s)"
+	messageDDayCountdownFormat  = "[%s] %s (%s)"
+	messageDDayDateFormat       = "2006.1.2"
+)
+
+// days before the event when countdowns are sent
+var ddayCountdownDays = []int{30, 7, 1, 0}
+
+var (
+	ddayTokenRegex = regexp.MustCompile(`(?i)(d-?day|디데이)`)
+	ddayDateRegex  = regexp.MustCompile(`(?:(\d{4})년\s*)?(\d{1,2})월\s*(\d{1,2})일`)
+)
+
+// words for telling D-day registrations and queries from ordinary reminders which mention D-day
+var (
+	ddayRegisterWords = []string{"등록"}
+	ddayQueryWords    = []string{"남았", "며칠", "몇일", "얼마나"}
+)
+
+// check if given text is about D-day
+func isDDayText(txt string) bool {
+	if strings.HasPrefix(txt, commandDDay) {
+		return true
+	}
+
+	return ddayTokenRegex.MatchString(txt) && (containsAny(txt, ddayRegisterWords) || containsAny(txt, ddayQueryWords))
+}
+
+// process D-day registrations ("수능 D-day 등록해줘 11월 14일") and queries ("디데이 며칠 남았어?")
+//
+// (also "/dday 수능 11월 14일" for registration, and "/dday" for query)
+func processDDayText(chatID, userID int64, txt string) string {
+	isCommand := strings.HasPrefix(txt, commandDDay)
+	txt = strings.TrimSpace(strings.TrimPrefix(txt, commandDDay))
+
+	if containsAny(txt, ddayRegisterWords) || (isCommand && ddayDateRegex.MatchString(txt)) {
+		return registerDDay(chatID, userID, txt)
+	}
+
+	return listDDays(chatID)
+}
+
+// check if given text contains any of given words
+func containsAny(txt string, words []string) bool {
+	for _, word := range words {
+		if strings.Contains(txt, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// register a D-day event and schedule its countdowns
+func registerDDay(chatID, userID int64, txt string) string {
+	matches := ddayDateRegex.FindStringSubmatch(txt)
+	if matches == nil {
+		return messageDDayUsage
+	}
+
+	// name is in front of "D-day" (or the date)
+	end := len(txt)
+	if loc := ddayTokenRegex.FindStringIndex(txt); loc != nil {
+		end = loc[0]
+	} else if loc := ddayDateRegex.FindStringIndex(txt); loc != nil {
+		end = loc[0]
+	}
+	name := strings.TrimSpace(ddayDateRegex.ReplaceAllString(txt[:end], ""))
+	if name == "" {
+		name = "D-day"
+	}
+
+	today := truncateToDay(time.Now().In(_location))
+	year := today.Year()
+	if matches[1] != "" {
+		year, _ = strconv.Atoi(matches[1])
+	}
+	month, _ := strconv.Atoi(matches[2])
+	day, _ := strconv.Atoi(matches[3])
+
+	eventOn := time.Date(year, time.Month(month), day, 0, 0, 0, 0, _location)
+	if eventOn.Before(today) {
+		if matches[1] != "" {
+			return messageDDayIsPast
+		}
+		eventOn = eventOn.AddDate(1, 0, 0) // next year
+	}
+
+	if _, saved := db.SaveDDay(chatID, userID, name, eventOn); !saved {
+		return messageSaveFailed
+	}
+
+	// countdowns are ordinary reminders, so they can be listed or canceled
+	labels := []string{}
+	for _, days := range ddayCountdownDays {
+		fireOn := eventOn.AddDate(0, 0, -days).Add(ddayNotifyHour * time.Hour)
+		if fireOn.Before(time.Now()) {
+			continue
+		}
+
+		label := ddayLabel(days)
+		if _, enqueued := db.EnqueueItem(dbhelper.QueueItem{
+			ChatID:  chatID,
+			UserID:  userID,
+			Message: fmt.Sprintf(messageDDayCountdownFormat, label, name, eventOn.Format(messageDDayDateFormat)),
+			FireOn:  fireOn,
+		}); enqueued {
+			labels = append(labels, label)
+		}
+	}
+
+	countdowns := messageDDayNoCountdowns
+	if len(labels) > 0 {
+		countdowns = fmt.Sprintf(messageDDayCountdownsFormat, strings.Join(labels, ", "))
+	}
+
+	return fmt.Sprintf(messageDDayRegisteredFormat, name, eventOn.Format(messageDDayDateFormat), countdowns)
+}
+
+// list upcoming D-day events with the number of remaining days
+func listDDays(chatID int64) string {
+	today := truncateToDay(time.Now().In(_location))
+
+	ddays := db.UpcomingDDays(chatID, today)
+	if len(ddays) <= 0 {
+		return messageNoDDays
+	}
+
+	lines := []string{}
+	for _, d := range ddays {
+		lines = append(lines, fmt.Sprintf(messageDDayItemFormat, d.Name, ddayLabel(daysBetween(today, d.EventOn.In(_location))), d.EventOn.In(_location).Format(messageDDayDateFormat)))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// label for given number of remaining days (eg. "D-7", "D-day")
+func ddayLabel(days int) string {
+	if days == 0 {
+		return "D-day"
+	}
+	return fmt.Sprintf("D-%d", days)
+}
+
+// midnight of given time's day
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// number of calendar days from one day to another (not affected by DST)
+func daysBetween(from, to time.Time) int {
+	f := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	t := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+
+	return int(t.Sub(f).Hours() / 24)
+}
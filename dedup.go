@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	callbackMerge = "/merge"
+
+	dedupWindowMinutes = 30 // reminders with the same text within this window are duplicates
+	dedupMaxGroups     = 3  // number of merge buttons shown at once
+	dedupMaxItems      = 6  // (callback data of telegram is up to 64 bytes)
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageDuplicatesFormat = "\n중복된 것 같은 알림이 %d묶음 있습니다. 합치면 가장 이른 알림만 남깁니다."
+	messageMergeFormat      = "합치기: %s (%d개)"
+	messageMergedFormat     = "\"%s\" 알림 %d개를 %s 하나로 합쳤습니다."
+	messageMergeGone        = "이미 바뀐 알림들입니다. 다시 /list 해 주세요."
+)
+
+// find groups of near-duplicate reminders of given user (same text within the window, earliest first)
+//
+// (private ones of other members are not compared)
+func duplicateReminders(reminders []dbhelper.QueueItem, userID int64) (groups [][]dbhelper.QueueItem) {
+	sorted := []dbhelper.QueueItem{}
+	for _, r := range reminders {
+		if r.Kind == dbhelper.QueueKindReminder && !isOthersPrivate(r, userID) {
+			sorted = append(sorted, r)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].FireOn.Before(sorted[j].FireOn)
+	})
+
+	merged := map[int64]bool{}
+	for i, first := range sorted {
+		if merged[first.ID] {
+			continue
+		}
+
+		group := []dbhelper.QueueItem{first}
+		key := strings.Join(normalizeForMatching(first.Message), " ")
+		for _, r := range sorted[i+1:] {
+			if r.FireOn.Sub(first.FireOn) > dedupWindowMinutes*time.Minute {
+				break
+			}
+			if !merged[r.ID] && r.Recurrence == first.Recurrence && strings.Join(normalizeForMatching(r.Message), " ") == key {
+				group = append(group, r)
+				merged[r.ID] = true
+			}
+		}
+
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups
+}
+
+// append merge offers of duplicate reminders to given /list message
+func offerMerges(message string, reminders []dbhelper.QueueItem, userID int64, options map[string]interface{}) string {
+	groups := duplicateReminders(reminders, userID)
+	if len(groups) <= 0 {
+		return message
+	}
+
+	buttons := [][]bot.InlineKeyboardButton{}
+	for i, group := range groups {
+		if i >= dedupMaxGroups {
+			break
+		}
+
+		if len(group) > dedupMaxItems {
+			group = group[:dedupMaxItems]
+		}
+
+		ids := []string{}
+		for _, r := range group {
+			ids = append(ids, strconv.FormatInt(r.ID, 10))
+		}
+		data := fmt.Sprintf("%s %s", callbackMerge, strings.Join(ids, ","))
+		buttons = append(buttons, []bot.InlineKeyboardButton{
+			bot.InlineKeyboardButton{
+				Text:         fmt.Sprintf(messageMergeFormat, visibleReminderText(group[0]), len(group)),
+				CallbackData: &data,
+			},
+		})
+	}
+	options["reply_markup"] = bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+
+	return message + fmt.Sprintf(messageDuplicatesFormat, len(groups))
+}
+
+// process callback query for merging duplicate reminders: keep the first (earliest) one, and delete the rest
+func processMergeCallback(query bot.CallbackQuery, txt string) string {
+	chatID := query.Message.Chat.ID
+
+	items := []dbhelper.QueueItem{}
+	for _, param := range strings.Split(strings.TrimSpace(strings.TrimPrefix(txt, callbackMerge)), ",") {
+		queueID, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			log.Printf("*** Unprocessable callback query: %s", txt)
+			return messageError
+		}
+
+		q, exists := db.QueueItem(chatID, queueID)
+		if !exists || q.DeliveredOn.Unix() > 0 || isOthersPrivate(q, query.From.ID) {
+			return messageMergeGone
+		}
+		items = append(items, q)
+	}
+	if len(items) < 2 {
+		return messageMergeGone
+	}
+
+	for _, q := range items[1:] {
+		if db.DeleteQueueItem(chatID, q.ID) {
+			fireWebhooks(webhookEventCanceled, q, "")
+		}
+	}
+
+	return fmt.Sprintf(messageMergedFormat, visibleReminderText(items[0]), len(items), formatTimeFor(chatID, items[0].FireOn, reminderTimeFormat))
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageMentionFormat = "%s님, " // prefix for mentioning the creator of a reminder in groups
+)
+
+// ids of queue items being delivered, not to be delivered twice
+// (eg. when fetching linked pages takes longer than the interval of queue checks)
+var _deliveringItems = struct {
+	sync.Mutex
+	ids map[int64]bool
+}{
+	ids: map[int64]bool{},
+}
+
+// check if given queue item is being delivered
+func isDelivering(queueID int64) bool {
+	_deliveringItems.Lock()
+	defer _deliveringItems.Unlock()
+
+	return _deliveringItems.ids[queueID]
+}
+
+// mark given queue item as being delivered (false if it is already)
+func beginDelivery(queueID int64) bool {
+	_deliveringItems.Lock()
+	defer _deliveringItems.Unlock()
+
+	if _deliveringItems.ids[queueID] {
+		return false
+	}
+	_deliveringItems.ids[queueID] = true
+	return true
+}
+
+// unmark given queue item (after it is marked as delivered or failed)
+func endDelivery(queueID int64) {
+	_deliveringItems.Lock()
+	delete(_deliveringItems.ids, queueID)
+	_deliveringItems.Unlock()
+}
+
+// deliver a reminder and update its state
+func deliverQueueItem(client *bot.Bot, q dbhelper.QueueItem) {
+	if !beginDelivery(q.ID) {
+		return
+	}
+	defer endDelivery(q.ID)
+
+	// (it might have been delivered after it was fetched)
+	if current, exists := db.QueueItem(q.ChatID, q.ID); !exists || current.DeliveredOn.Unix() > 0 {
+		return
+	}
+
+	_varNumDeliveriesInFlight.Add(1)
+	defer _varNumDeliveriesInFlight.Add(-1)
+
+	if q.Broadcast {
+		broadcastQueueItem(client, q)
+		return
+	}
+
+	// (pushed to the companion apps instead, if the chat wants so)
+	if deliverWithPushOnly(client, q) {
+		return
+	}
+
+	ctx, span := startSpan(context.Background(), spanNameDelivery, q.ChatID,
+		attribute.Int64("queue.id", q.ID),
+		attribute.Int64("queue.delay_seconds", time.Now().Unix()-q.FireOn.Unix()),
+	)
+	defer span.End()
+
+	// send message
+	_, sendSpan := startSpan(ctx, spanNameSendMessage, q.ChatID)
+	var sent bot.APIResponseMessage
+	if q.Kind == dbhelper.QueueKindPoll {
+		sent = sendPoll(client, q)
+	} else if q.Kind == dbhelper.QueueKindGroupReport {
+		sent = sendGroupReports(client, q)
+	} else if isPrivateInGroup(q) {
+		sent = sendPrivateReminder(client, q)
+	} else if q.BusinessConnectionID != "" {
+		sent = sendBusinessReminder(client, q)
+	} else if q.Attachment != "" {
+		sent = sendAttachment(client, q)
+	} else {
+		message, options := deliveryMessage(client, q)
+		sent = client.SendMessage(deliveryChatID(q, options), message, options)
+	}
+	fellBack := false
+	if !sent.Ok && canFallBackToPrivateChat(q, telegramError(*sent.Description)) {
+		// the group cannot receive it, so deliver it to the creator instead
+		if fallback := sendToPrivateChatInstead(client, q, *sent.Description); fallback.Ok {
+			sent, fellBack = fallback, true
+		}
+	}
+	countTry := true
+	if !sent.Ok {
+		endSpan(sendSpan, false, *sent.Description)
+
+		err := telegramError(*sent.Description)
+
+		log.Printf("*** failed to send reminder (%s): %s", telegramErrorKind(err), *sent.Description)
+
+		_varNumDeliveryFailures.Add(1)
+
+		db.SetQueueItemError(q.ChatID, q.ID, telegramErrorCode(*sent.Description), *sent.Description)
+
+		switch e := err.(type) {
+		case ErrRateLimited:
+			// not a failure of this item: retry after the given time
+			countTry = false
+			if e.RetryAfter > 0 {
+				db.RescheduleQueueItem(q.ChatID, q.ID, time.Now().Add(e.RetryAfter))
+			}
+		default:
+			if isPermanentTelegramError(err) {
+				// (retries will not help)
+				countTry = false
+				db.SetQueueItemNumTries(q.ChatID, q.ID, maxNumTriesOrDefault())
+				defer handleDeliveryFailure(client, q, *sent.Description)
+			} else if isLastTry(q.NumTries + 1) {
+				// (after increasing num tries below, not to be overwritten)
+				defer handleDeliveryFailure(client, q, *sent.Description)
+			}
+		}
+	} else {
+		endSpan(sendSpan, true, "")
+
+		_varNumDelivered.Add(1)
+
+		// mark as delivered
+		_, markSpan := startSpan(ctx, spanNameMarkDelivered, q.ChatID)
+		marked := db.MarkQueueItemAsDelivered(q.ChatID, q.ID)
+		endSpan(markSpan, marked, "failed to mark as delivered")
+
+		if !marked {
+			log.Printf("*** failed to mark chat id: %d, queue id: %d", q.ChatID, q.ID)
+		}
+
+		recordDeliveryLatency(client, q)
+		markMergedQueueItems(client, q)
+
+		// (for resolving replies, reactions, edits, and pins of the message)
+		db.SaveDeliveredMessage(sent.Result.Chat.ID, int64(sent.Result.MessageID), q.ChatID, q.ID)
+
+		publishFiredReminder(q)
+
+		if q.Kind == dbhelper.QueueKindReminder {
+			fireWebhooks(webhookEventDelivered, q, "")
+		}
+
+		// (pushed in background, not to delay the delivery)
+		go pushAlongside(q)
+
+		// (voice note is sent in background, as synthesizing takes a while)
+		if shouldSendVoice(q) {
+			go sendVoiceNote(client, q, sent.Result.MessageID)
+		}
+
+		switch q.Kind {
+		case dbhelper.QueueKindPomodoro:
+			advancePomodoro(q)
+		case dbhelper.QueueKindMedication, dbhelper.QueueKindMedicationNag, dbhelper.QueueKindMedicationReport:
+			afterMedicationDelivery(q)
+		case dbhelper.QueueKindHabit, dbhelper.QueueKindHabitSummary:
+			afterHabitDelivery(q)
+		case dbhelper.QueueKindGroupNag:
+			scheduleGroupNag(q)
+		case dbhelper.QueueKindPoll, dbhelper.QueueKindPollResult:
+			// (results are enqueued when the poll is sent)
+		case dbhelper.QueueKindFocusDigest:
+			// (deferred reminders are marked as delivered with the digest)
+		case dbhelper.QueueKindGroupReport:
+			scheduleGroupReport(q.ChatID, q.UserID)
+		default:
+			if q.Recurrence != "" {
+				scheduleNextOccurrence(q)
+			} else {
+				suggestRecurrence(client, q)
+			}
+
+			if isGroupChatID(q.ChatID) && needsAck(q) && !fellBack {
+				scheduleGroupNag(q)
+			}
+		}
+	}
+
+	// increase num tries
+	if countTry && !db.IncreaseNumTries(q.ChatID, q.ID) {
+		log.Printf("*** failed to increase num tries for chat id: %d, queue id: %d", q.ChatID, q.ID)
+	}
+}
+
+// build message and options for delivering given reminder
+func deliveryMessage(client *bot.Bot, q dbhelper.QueueItem) (message string, options map[string]interface{}) {
+	message = fmt.Sprintf("%s", q.Message)
+	options = map[string]interface{}{}
+
+	// sanitized and tidied up text of the chat's own reminder (with the ones merged into it)
+	if q.Kind == dbhelper.QueueKindReminder && !q.Broadcast {
+		message = mergedMessage(q, reminderText(q.ChatID, sanitizedDeliveryText(q.ChatID, message)))
+	}
+
+	// controls for special kinds
+	switch q.Kind {
+	case dbhelper.QueueKindPomodoro:
+		options["reply_markup"] = pomodoroControls(false)
+	case dbhelper.QueueKindMedication, dbhelper.QueueKindMedicationNag, dbhelper.QueueKindMedicationReport:
+		var keyboard interface{}
+		if message, keyboard = medicationDeliveryMessage(q); keyboard != nil {
+			options["reply_markup"] = keyboard
+		}
+	case dbhelper.QueueKindHabit, dbhelper.QueueKindHabitSummary:
+		var keyboard interface{}
+		if message, keyboard = habitDeliveryMessage(q); keyboard != nil {
+			options["reply_markup"] = keyboard
+		}
+	case dbhelper.QueueKindPollResult:
+		message = pollResultMessage(client, q)
+	case dbhelper.QueueKindFocusDigest:
+		message = focusDigestMessage(q)
+	default:
+		if needsFollowUp(q) {
+			var keyboard bot.InlineKeyboardMarkup
+			message, keyboard = followUpMessage(q, followUpStepAsk)
+			options["reply_markup"] = keyboard
+		} else if needsAck(q) {
+			var keyboard interface{}
+			message, keyboard = ackDeliveryMessage(q)
+			options["reply_markup"] = keyboard
+		}
+	}
+
+	// titles of linked pages
+	if q.Kind == dbhelper.QueueKindReminder && len(urlsInMessage(q.Message)) > 0 {
+		message = unfurlMessage(message)
+
+		if shouldSummarize(q.ChatID) {
+			message = summarizeLinks(message)
+		}
+	}
+	if !db.ChatSettings(q.ChatID).LinkPreview {
+		options["disable_web_page_preview"] = true
+	}
+
+	decorated := q.Kind == dbhelper.QueueKindReminder && !q.Broadcast
+	mentioned := isGroupChatID(q.ChatID) && !isPrivateInGroup(q)
+
+	// too long for telegram (with the greeting, sign-off, and mention which are added below)
+	reserved := 0
+	if decorated {
+		reserved += utf16Len(decorateMessage(q.ChatID, ""))
+	}
+	if mentioned {
+		reserved += utf16Len(mentionPrefix(q))
+	}
+	message = trimLongMessage(q, message, reserved, options)
+
+	// numbered options instead of inline keyboards
+	if isSimpleMode(q.ChatID) {
+		message = simplifyMessage(q.ChatID, message, options)
+	}
+
+	// greeting and sign-off of the chat
+	if decorated {
+		message = decorateMessage(q.ChatID, message)
+	}
+
+	// mention the creator in groups (private ones are delivered to the creator's private chat)
+	if mentioned {
+		message = mentionCreator(client, q, message, options)
+	}
+
+	return message, options
+}
+
+// prefix for mentioning the creator of given queue item (empty if unknown)
+func mentionPrefix(q dbhelper.QueueItem) string {
+	if q.UserID == 0 {
+		return ""
+	}
+
+	if user, exists := db.User(q.UserID); exists {
+		return fmt.Sprintf(messageMentionFormat, user.FirstName)
+	}
+
+	return ""
+}
+
+// prefix given message with a mention of the creator of given queue item
+func mentionCreator(client *bot.Bot, q dbhelper.QueueItem, message string, options map[string]interface{}) string {
+	if q.UserID == 0 {
+		return message
+	}
+
+	if user, exists := db.User(q.UserID); exists {
+		prefix := fmt.Sprintf(messageMentionFormat, user.FirstName)
+
+		if isChatMember(client, q.ChatID, q.UserID) {
+			// text_mention works even for users without usernames
+			options["entities"] = []map[string]interface{}{
+				map[string]interface{}{
+					"type":   "text_mention",
+					"offset": 0,
+					"length": utf16Len(user.FirstName),
+					"user": map[string]interface{}{
+						"id":         q.UserID,
+						"first_name": user.FirstName,
+					},
+				},
+			}
+		}
+
+		message = prefix + message
+	}
+
+	return message
+}
+
+// check if given chat id is of a group (or supergroup/channel)
+func isGroupChatID(chatID int64) bool {
+	return chatID < 0
+}
+
+// check if given user is still a member of given chat
+func isChatMember(client *bot.Bot, chatID, userID int64) bool {
+	if member := client.GetChatMember(chatID, int(userID)); member.Ok {
+		switch member.Result.Status {
+		case bot.ChatMemberStatusLeft, bot.ChatMemberStatusKicked:
+			return false
+		}
+		return true
+	}
+
+	return false
+}
+
+// length of given string in UTF-16 code units (used for offsets/lengths of message entities)
+func utf16Len(str string) int {
+	return len(utf16.Encode([]rune(str)))
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	apiai "github.com/meinside/api.ai-go"
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	commandDoctor = "doctor"
+
+	doctorClockURL       = "https://api.telegram.org"
+	doctorMaxClockSkew   = 1 * time.Minute
+	doctorTimeoutSeconds = 10
+	doctorResultPass     = "PASS"
+	doctorResultFail     = "FAIL"
+	doctorResultSkip     = "SKIP"
+)
+
+// run `doctor` subcommand: check config, tokens, database, and time settings, then print a report
+//
+//	$ reminderbot doctor
+//
+// (exits with 1 if any of the checks failed)
+func runDoctor() {
+	failed := false
+	report := func(name, result, detail string) {
+		if result == doctorResultFail {
+			failed = true
+		}
+		if detail != "" {
+			fmt.Printf("[%s] %-10s %s\n", result, name, detail)
+		} else {
+			fmt.Printf("[%s] %s\n", result, name)
+		}
+	}
+
+	// config
+	conf, err := openConfig()
+	if err == nil {
+		err = conf.resolveSecrets()
+	}
+	if err == nil {
+		if problems := conf.validate(); len(problems) > 0 {
+			err = fmt.Errorf("%s", strings.Join(problems, "; "))
+		}
+	}
+	if err != nil {
+		report("config", doctorResultFail, fmt.Sprintf("%s (%s)", err, *_configFilepath))
+
+		for _, name := range []string{"telegram", "api.ai", "database", "time"} {
+			report(name, doctorResultSkip, "")
+		}
+		os.Exit(1)
+	}
+	report("config", doctorResultPass, *_configFilepath)
+
+	// telegram bot api token
+	if me := bot.NewClient(conf.TelegramAPIToken).GetMe(); me.Ok {
+		report("telegram", doctorResultPass, fmt.Sprintf("@%s", *me.Result.Username))
+	} else {
+		detail := "getMe failed"
+		if me.Description != nil {
+			detail += ": " + *me.Description
+		}
+		report("telegram", doctorResultFail, detail)
+	}
+
+	// api.ai access token
+	if response, err := apiai.NewClient(conf.ApiaiAccessToken).QueryText(apiai.QueryRequest{
+		Query:     []string{setupTestQuery},
+		SessionId: "doctor",
+		Language:  apiai.Korean,
+	}); err != nil {
+		report("api.ai", doctorResultFail, err.Error())
+	} else if response.Status.ErrorType != apiai.Success {
+		report("api.ai", doctorResultFail, fmt.Sprintf("%s (%s)", response.Status.ErrorType, response.Status.ErrorDetails))
+	} else {
+		report("api.ai", doctorResultPass, "")
+	}
+
+	// database
+	_dataDir = dataDirOf(conf)
+	dbFilepath := dataPath(dbFilename)
+	if result, detail := checkDatabase(dbFilepath); result == doctorResultPass {
+		report("database", result, dbFilepath)
+	} else {
+		report("database", result, fmt.Sprintf("%s (%s)", detail, dbFilepath))
+	}
+
+	// time and zone
+	result, detail := checkTime()
+	report("time", result, detail)
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// check if the database is intact and writable, and has all tables and columns of the current schema
+//
+// (nothing is written to it, as the bot might be running with it)
+func checkDatabase(dbFilepath string) (result, detail string) {
+	if _, err := os.Stat(dbFilepath); err != nil {
+		return doctorResultFail, err.Error()
+	}
+
+	if problems, err := dbhelper.CheckIntegrity(dbFilepath); err != nil || len(problems) > 0 {
+		return doctorResultFail, fmt.Sprintf("integrity check failed: %s", integrityDetail(problems, err))
+	}
+
+	// (opened for writing, but not written)
+	if f, err := os.OpenFile(dbFilepath, os.O_WRONLY, 0); err != nil {
+		return doctorResultFail, fmt.Sprintf("not writable: %s", err)
+	} else {
+		f.Close()
+	}
+
+	missing, err := dbhelper.MissingSchema(dbFilepath)
+	if err != nil {
+		return doctorResultFail, fmt.Sprintf("failed to check schema: %s", err)
+	}
+	if len(missing) > 0 {
+		return doctorResultFail, fmt.Sprintf("missing in schema (will be created when the bot starts): %s", strings.Join(missing, ", "))
+	}
+
+	return doctorResultPass, ""
+}
+
+// check the time zone and the clock (compared with the time of telegram servers)
+func checkTime() (result, detail string) {
+	if tz := os.Getenv("TZ"); tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			return doctorResultFail, fmt.Sprintf("invalid TZ: %s", err)
+		}
+	}
+
+	now := time.Now()
+	zone, offset := now.Zone()
+	detail = fmt.Sprintf("%s (UTC%+.1f)", zone, float64(offset)/3600)
+
+	client := http.Client{Timeout: doctorTimeoutSeconds * time.Second}
+	resp, err := client.Head(doctorClockURL)
+	if err != nil {
+		return doctorResultFail, fmt.Sprintf("%s, failed to check clock: %s", detail, err)
+	}
+	resp.Body.Close()
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return doctorResultFail, fmt.Sprintf("%s, failed to check clock: %s", detail, err)
+	}
+
+	skew := now.Sub(serverTime)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > doctorMaxClockSkew {
+		return doctorResultFail, fmt.Sprintf("%s, clock is off by %s", detail, skew.Round(time.Second))
+	}
+
+	return doctorResultPass, detail
+}
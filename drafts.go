@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	apiai "github.com/meinside/api.ai-go"
+	bot "github.com/meinside/telegram-bot-go"
+
+	aihelper "github.com/meinside/telegram-bot-reminder-api.ai/ai"
+)
+
+// expiring drafts: reminders which were started (with missing parameters, or waiting for the confirmation)
+// but not finished for a while are dropped with the session context of api.ai, optionally with a nudge
+
+// messages (can be overridden with messages.json)
+var (
+	messageDraftExpiredFormat = "아직 저장되지 않았어요. ('%s')\n알림이 필요하시면 처음부터 다시 말씀해 주세요."
+)
+
+// unfinished reminder of a chat
+type draft struct {
+	userID    int64
+	query     string // (the first query of the draft)
+	updatedOn time.Time
+}
+
+var _drafts = struct {
+	sync.Mutex
+	chats  map[int64]draft
+	resets map[int64]bool // chats whose session contexts should be reset with the next query
+}{
+	chats:  map[int64]draft{},
+	resets: map[int64]bool{},
+}
+
+// keep track of the draft of given chat with given response of api.ai
+func trackDraft(chatID, userID int64, query string, response apiai.QueryResponse) {
+	if _conf.DraftExpiryMinutes <= 0 {
+		return
+	}
+
+	unfinished := response.Result.ActionIncomplete || response.Result.Metadata.IntentName == aihelper.IntentNameMessage
+
+	_drafts.Lock()
+	defer _drafts.Unlock()
+
+	if !unfinished {
+		delete(_drafts.chats, chatID)
+		return
+	}
+
+	d, exists := _drafts.chats[chatID]
+	if !exists {
+		d = draft{userID: userID, query: query}
+	}
+	d.updatedOn = time.Now()
+	_drafts.chats[chatID] = d
+}
+
+// check if the session context of given chat should be reset (with the next query)
+func takeSessionReset(chatID int64) bool {
+	_drafts.Lock()
+	defer _drafts.Unlock()
+
+	reset := _drafts.resets[chatID]
+	delete(_drafts.resets, chatID)
+
+	return reset
+}
+
+// expire drafts which were not updated for the configured period
+func expireDrafts(client *bot.Bot) {
+	if _conf.DraftExpiryMinutes <= 0 {
+		return
+	}
+
+	expired := map[int64]draft{}
+
+	_drafts.Lock()
+	for chatID, d := range _drafts.chats {
+		if time.Since(d.updatedOn) >= time.Duration(_conf.DraftExpiryMinutes)*time.Minute {
+			expired[chatID] = d
+
+			delete(_drafts.chats, chatID)
+			_drafts.resets[chatID] = true
+		}
+	}
+	_drafts.Unlock()
+
+	for chatID, d := range expired {
+		// drop things kept until the confirmation
+		takePendingPoll(chatID, d.userID)
+		takePendingWindow(chatID, d.userID)
+		takePendingVisibility(chatID, d.userID)
+
+		if _isVerbose {
+			log.Printf("Expired draft of chat %d: %s", chatID, d.query)
+		}
+
+		if _conf.DraftNudge {
+			if sent := client.SendMessage(chatID, fmt.Sprintf(messageDraftExpiredFormat, d.query), map[string]interface{}{}); !sent.Ok {
+				log.Printf("*** failed to send nudge for expired draft: %s", *sent.Description)
+			}
+		}
+	}
+}
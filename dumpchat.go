@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	commandDumpChat = "/dumpchat"
+
+	dumpChatMaxDeliveries = 20
+	dumpChatMaxErrors     = 20
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageDumpChatUsage         = "사용법: /dumpchat <chat_id>"
+	messageDumpChatCaptionFormat = "[관리자] chat %d의 상태 (%s)"
+	messageDumpChatFailed        = "[관리자] 채팅 상태를 보내지 못했습니다."
+	messageDumpChatSentFormat    = "[관리자] chat %d의 설정, 대기 중인 알림, 최근 발송/오류 내역을 첨부했습니다."
+)
+
+// state of a chat for support
+type chatDump struct {
+	ChatID     int64                  `json:"chat_id"`
+	DumpedOn   time.Time              `json:"dumped_on"`
+	Settings   dbhelper.ChatSettings  `json:"settings"`
+	Aliases    []dbhelper.Alias       `json:"aliases"`
+	Vacations  []dbhelper.Suppression `json:"vacations"`
+	Pending    []dbhelper.QueueItem   `json:"pending"`
+	Deliveries []dbhelper.QueueItem   `json:"recent_deliveries"`
+	Errors     []dbhelper.QueueItem   `json:"recent_errors"`
+}
+
+// collect the state of given chat
+func dumpChat(chatID int64) chatDump {
+	return chatDump{
+		ChatID:     chatID,
+		DumpedOn:   time.Now(),
+		Settings:   db.ChatSettings(chatID),
+		Aliases:    db.Aliases(chatID),
+		Vacations:  db.Suppressions(chatID, dbhelper.SuppressionKindVacation),
+		Pending:    db.UndeliveredQueueItems(chatID),
+		Deliveries: db.SearchQueueItems(dbhelper.QueueFilter{ChatID: chatID, Delivered: true, Limit: dumpChatMaxDeliveries}),
+		Errors:     db.QueueItemsWithErrors(chatID, dumpChatMaxErrors),
+	}
+}
+
+// process /dumpchat command: send the state of a chat as a JSON attachment to the admin
+func processDumpChatCommand(b *bot.Bot, chatID, userID int64, txt string) string {
+	if !isAdmin(userID) {
+		return messageAnnounceAdminOnly
+	}
+
+	targetChatID, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(txt, commandDumpChat)), 10, 64)
+	if err != nil {
+		return messageDumpChatUsage
+	}
+
+	dump, err := json.MarshalIndent(dumpChat(targetChatID), "", "  ")
+	if err != nil {
+		log.Printf("*** Failed to marshal state of chat %d: %s", targetChatID, err)
+		return messageDumpChatFailed
+	}
+
+	keepExport(fmt.Sprintf("chat-%d.json", targetChatID), dump)
+
+	options := map[string]interface{}{
+		"caption": fmt.Sprintf(messageDumpChatCaptionFormat, targetChatID, time.Now().Format(reminderTimeFormat)),
+	}
+	if sent := b.SendDocument(chatID, bot.InputFileFromBytes(dump), options); !sent.Ok {
+		log.Printf("*** Failed to send state of chat %d: %s", targetChatID, *sent.Description)
+		return messageDumpChatFailed
+	}
+
+	db.Log(fmt.Sprintf("admin %d dumped the state of chat %d", userID, targetChatID))
+
+	return fmt.Sprintf(messageDumpChatSentFormat, targetChatID)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	commandEvent = "/event"
+
+	apiPathEvents = "/api/events/"
+
+	eventParamDelete = "삭제"
+
+	maxEventPayloadBytes = 64 * 1024
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageEventUsage = `사용법:
+/event : 등록된 이벤트 조회
+/event 이름 메시지 템플릿 : 이벤트 메시지 등록 (예: /event ci {{.repo}} 빌드 {{.status}})
+/event 이름 삭제 : 이벤트 메시지 삭제
+
+POST /api/events/이름 (JSON, /apikey로 발급받은 키 필요)
+?fire_on=2017-12-31T23:00:00%2B09:00 또는 ?delay=10m 으로 예약 가능`
+	messageEventListFormat    = "➤ %s: %s\n"
+	messageEventNone          = "등록된 이벤트가 없습니다."
+	messageEventSavedFormat   = "이벤트 '%s'의 메시지가 등록 되었습니다."
+	messageEventDeletedFormat = "이벤트 '%s'의 메시지가 삭제 되었습니다."
+	messageEventNotFound      = "등록된 이벤트가 아닙니다."
+	messageEventNameInvalid   = "이벤트 이름은 영문, 숫자, '-', '_'로만 지어 주세요."
+	messageEventTemplateError = "템플릿이 올바르지 않습니다: %s"
+)
+
+var eventNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// process /event command
+func processEventCommand(chatID int64, txt string) string {
+	if _conf.APIServerPort <= 0 {
+		return messageAPIDisabled
+	}
+
+	param := strings.TrimSpace(strings.TrimPrefix(txt, commandEvent))
+	if param == "" {
+		templates := db.EventTemplates(chatID)
+		if len(templates) <= 0 {
+			return messageEventNone + "\n\n" + messageEventUsage
+		}
+
+		message := ""
+		for _, t := range templates {
+			message += fmt.Sprintf(messageEventListFormat, t.Name, t.Template)
+		}
+		return message + "\n" + messageEventUsage
+	}
+
+	fields := strings.Fields(param)
+	name := fields[0]
+	if !eventNameRegex.MatchString(name) {
+		return messageEventNameInvalid
+	}
+	if len(fields) < 2 {
+		return messageEventUsage
+	}
+
+	// text after the name (keeping line breaks)
+	text := strings.TrimSpace(strings.TrimPrefix(param, name))
+	if text == eventParamDelete {
+		if db.DeleteEventTemplate(chatID, name) {
+			return fmt.Sprintf(messageEventDeletedFormat, name)
+		}
+		return messageEventNotFound
+	}
+
+	if _, err := template.New(name).Parse(text); err != nil {
+		return fmt.Sprintf(messageEventTemplateError, err)
+	}
+	if !db.SaveEventTemplate(chatID, name, text) {
+		return messageSaveFailed
+	}
+
+	return fmt.Sprintf(messageEventSavedFormat, name)
+}
+
+// render message of given event with its payload
+//
+// (without a registered template, 'message' value of the payload is used)
+func renderEventMessage(chatID int64, name string, payload map[string]interface{}) (string, error) {
+	tmpl, exists := db.EventTemplateFor(chatID, name)
+	if !exists {
+		if message, ok := payload["message"].(string); ok && strings.TrimSpace(message) != "" {
+			return message, nil
+		}
+		return "", fmt.Errorf("no template for event '%s' (and no 'message' in payload)", name)
+	}
+
+	return executeTemplate(name, tmpl, payload)
+}
+
+// execute given message template with given data
+func executeTemplate(name, text string, data interface{}) (string, error) {
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// send given message to given chat now (if fireOn is not in the future), or enqueue it
+//
+// (failed immediate ones are also enqueued for retrying)
+func sendOrEnqueue(chatID int64, message string, fireOn time.Time) (queueID int64, sent bool, err error) {
+	// (immediate ones are not checked again when delivered)
+	if message, err = sanitizeReminderText(chatID, message); err != nil {
+		return 0, false, err
+	}
+
+	if !fireOn.After(time.Now()) {
+		res := telegram.SendMessage(chatID, message, map[string]interface{}{})
+		if res.Ok {
+			return 0, true, nil
+		}
+
+		log.Printf("*** failed to send message to chat %d: %s (will be retried)", chatID, *res.Description)
+	}
+
+	item := dbhelper.QueueItem{
+		ChatID:  chatID,
+		Message: message,
+		FireOn:  fireOn,
+	}
+	if queueID, enqueued := db.EnqueueItem(item); enqueued {
+		item.ID = queueID
+		fireWebhooks(webhookEventCreated, item, "")
+
+		return queueID, false, nil
+	}
+
+	return 0, false, fmt.Errorf("failed to save reminder")
+}
+
+// POST: create a reminder (or send a notification immediately) with given event
+func handleEvent(w http.ResponseWriter, r *http.Request, chatID int64) {
+	if r.Method != http.MethodPost {
+		writeAPIResponse(w, http.StatusMethodNotAllowed, apiResponse{Error: "method not allowed"})
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, apiPathEvents)
+	if !eventNameRegex.MatchString(name) {
+		writeAPIResponse(w, http.StatusBadRequest, apiResponse{Error: "invalid event name"})
+		return
+	}
+
+	// payload (empty body is allowed)
+	payload := map[string]interface{}{}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxEventPayloadBytes))
+	if err != nil {
+		writeAPIResponse(w, http.StatusBadRequest, apiResponse{Error: fmt.Sprintf("failed to read request body: %s", err)})
+		return
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &payload); err != nil {
+			writeAPIResponse(w, http.StatusBadRequest, apiResponse{Error: fmt.Sprintf("invalid request body: %s", err)})
+			return
+		}
+	}
+
+	message, err := renderEventMessage(chatID, name, payload)
+	if err == nil {
+		message, err = sanitizeReminderText(chatID, message)
+	}
+	if err != nil {
+		writeAPIResponse(w, http.StatusBadRequest, apiResponse{Error: err.Error()})
+		return
+	}
+
+	// when to fire (now if not given)
+	fireOn := time.Now()
+	if param := r.URL.Query().Get("fire_on"); param != "" {
+		if fireOn, err = time.Parse(time.RFC3339, param); err != nil {
+			writeAPIResponse(w, http.StatusBadRequest, apiResponse{Error: fmt.Sprintf("invalid fire_on: %s", err)})
+			return
+		}
+	} else if param := r.URL.Query().Get("delay"); param != "" {
+		delay, err := time.ParseDuration(param)
+		if err != nil || delay < 0 {
+			writeAPIResponse(w, http.StatusBadRequest, apiResponse{Error: fmt.Sprintf("invalid delay: %s", param)})
+			return
+		}
+		fireOn = fireOn.Add(delay)
+	}
+
+	db.Log(fmt.Sprintf("received event '%s' for chat %d", name, chatID))
+
+	if queueID, sent, err := sendOrEnqueue(chatID, message, fireOn); err != nil {
+		writeAPIResponse(w, http.StatusInternalServerError, apiResponse{Error: err.Error()})
+	} else if sent {
+		writeAPIResponse(w, http.StatusOK, apiResponse{Ok: true})
+	} else {
+		writeAPIResponse(w, http.StatusCreated, apiResponse{Ok: true, Result: map[string]interface{}{"id": queueID}})
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	commandOnFail = "/onfail"
+
+	failurePolicyParamGiveUp = "giveup" // (policy for giving up is an empty string)
+
+	escalationTimeoutSeconds = 10
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageOnFailWhat          = "어떤 알림의 발송 실패시 처리 방법을 바꾸시겠습니까?"
+	messageOnFailHowFormat     = "\"%s\" 알림을 끝내 보내지 못하면 어떻게 할까요?"
+	messageOnFailGiveUp        = "그냥 포기하기"
+	messageOnFailRetryNextDay  = "다음 날 다시 보내기"
+	messageOnFailEscalate      = "이메일/웹훅으로 알리기"
+	messageOnFailSavedFormat   = "발송 실패시 처리 방법이 변경 되었습니다: %s"
+	messageOnFailNoEscalation  = "이메일/웹훅 설정이 되어있지 않습니다."
+	messageEscalationSubject   = "[Reminder Bot] 알림 발송 실패"
+	messageEscalationBodyFmt   = "다음 알림을 보내지 못했습니다.\r\n\r\n채팅: %d\r\n예약 시각: %s\r\n내용: %s\r\n오류: %s\r\n"
+	messageFailureAdminsFormat = "[관리자] 알림 %d(채팅 %d)의 발송 실패를 알리지 못했습니다."
+)
+
+// config for escalating failures through email
+type emailConfig struct {
+	SMTPServer string `json:"smtp_server"` // host:port
+	Username   string `json:"username,omitempty"`
+	Password   string `json:"password,omitempty"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+}
+
+// payload posted to the escalation webhook
+type escalationPayload struct {
+	QueueID int64     `json:"queue_id"`
+	ChatID  int64     `json:"chat_id"`
+	UserID  int64     `json:"user_id,omitempty"`
+	Message string    `json:"message"`
+	FireOn  time.Time `json:"fire_on"`
+	Error   string    `json:"error"`
+}
+
+var _escalationClient = &http.Client{Timeout: escalationTimeoutSeconds * time.Second}
+
+// displayable name of given failure policy
+func failurePolicyName(policy string) string {
+	switch policy {
+	case dbhelper.FailurePolicyRetryNextDay:
+		return messageOnFailRetryNextDay
+	case dbhelper.FailurePolicyEscalate:
+		return messageOnFailEscalate
+	case dbhelper.FailurePolicySMS:
+		return messageOnFailSMS
+	}
+	return messageOnFailGiveUp
+}
+
+// check if escalation channels are configured
+func canEscalate() bool {
+	return _conf.EscalationWebhookURL != "" || _conf.EscalationEmail != nil
+}
+
+// http status codes of telegram errors, guessed from the prefixes of their descriptions
+var telegramErrorCodes = map[string]int{
+	"Bad Request":       http.StatusBadRequest,
+	"Unauthorized":      http.StatusUnauthorized,
+	"Forbidden":         http.StatusForbidden,
+	"Not Found":         http.StatusNotFound,
+	"Conflict":          http.StatusConflict,
+	"Too Many Requests": http.StatusTooManyRequests,
+}
+
+// http status code of given telegram error description (0 if unknown)
+//
+// (eg. "Forbidden: bot was blocked by the user" => 403)
+func telegramErrorCode(description string) int {
+	if i := strings.Index(description, ":"); i > 0 {
+		if code, exists := telegramErrorCodes[description[:i]]; exists {
+			return code
+		}
+	}
+
+	return 0
+}
+
+// check if given number of tries was the last one
+func isLastTry(numTries int) bool {
+	return numTries >= maxNumTriesOrDefault()
+}
+
+// configured max number of tries (or the default one)
+func maxNumTriesOrDefault() int {
+	if _maxNumTries <= 0 {
+		return dbhelper.DefaultMaxNumTries
+	}
+	return _maxNumTries
+}
+
+// process /onfail command: show reminders for changing their failure policies
+func processOnFailCommand(chatID int64, options map[string]interface{}) string {
+	reminders := db.UndeliveredQueueItems(chatID)
+	if len(reminders) <= 0 {
+		return messageNoReminders
+	}
+
+	options["reply_markup"] = reminderSelectionKeyboard(reminders, commandOnFail)
+
+	return messageOnFailWhat
+}
+
+// process callback query for failure policies
+//
+//	"/onfail <id>"          : show policies for the reminder
+//	"/onfail <id> <policy>" : set the policy of the reminder
+func processOnFailCallback(query bot.CallbackQuery, txt string) (message string, keyboard interface{}) {
+	params := strings.Fields(strings.TrimPrefix(txt, commandOnFail))
+	if len(params) <= 0 {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError, nil
+	}
+
+	chatID := query.Message.Chat.ID
+	queueID, err := strconv.ParseInt(params[0], 10, 64)
+	if err != nil {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError, nil
+	}
+
+	if len(params) == 1 {
+		q, exists := db.QueueItem(chatID, queueID)
+		if !exists {
+			return messageError, nil
+		}
+
+		policies := []string{dbhelper.FailurePolicyGiveUp, dbhelper.FailurePolicyRetryNextDay, dbhelper.FailurePolicyEscalate}
+		if isSMSEnabled() {
+			policies = append(policies, dbhelper.FailurePolicySMS)
+		}
+
+		buttons := [][]bot.InlineKeyboardButton{}
+		for _, policy := range policies {
+			param := policy
+			if policy == dbhelper.FailurePolicyGiveUp {
+				param = failurePolicyParamGiveUp
+			}
+			data := fmt.Sprintf("%s %d %s", commandOnFail, queueID, param)
+			buttons = append(buttons, []bot.InlineKeyboardButton{
+				bot.InlineKeyboardButton{Text: failurePolicyName(policy), CallbackData: &data},
+			})
+		}
+		cancel := commandCancel
+		buttons = append(buttons, []bot.InlineKeyboardButton{
+			bot.InlineKeyboardButton{Text: messageCancel, CallbackData: &cancel},
+		})
+
+		return fmt.Sprintf(messageOnFailHowFormat, q.Message), bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+	}
+
+	policy := params[1]
+	switch policy {
+	case failurePolicyParamGiveUp:
+		policy = dbhelper.FailurePolicyGiveUp
+	case dbhelper.FailurePolicyRetryNextDay:
+	case dbhelper.FailurePolicyEscalate:
+		if !canEscalate() {
+			return messageOnFailNoEscalation, nil
+		}
+	case dbhelper.FailurePolicySMS:
+		if !isSMSEnabled() {
+			return messageSMSDisabled, nil
+		}
+		if !hasVerifiedPhoneNumber(chatID) {
+			return messageOnFailNoPhone, nil
+		}
+	default:
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError, nil
+	}
+
+	if db.SetQueueItemFailurePolicy(chatID, queueID, policy) {
+		return fmt.Sprintf(messageOnFailSavedFormat, failurePolicyName(policy)), nil
+	}
+
+	return messageError, nil
+}
+
+// handle given queue item which failed to be delivered after all retries, with its failure policy
+func handleDeliveryFailure(client *bot.Bot, q dbhelper.QueueItem, reason string) {
+	fireWebhooks(webhookEventFailed, q, reason)
+
+	retried := false
+	switch q.FailurePolicy {
+	case dbhelper.FailurePolicyRetryNextDay:
+		if db.RetryQueueItem(q.ChatID, q.ID, q.FireOn.AddDate(0, 0, 1)) {
+			db.Log(fmt.Sprintf("queue item %d of chat %d will be retried on the next day", q.ID, q.ChatID))
+
+			retried = true
+		}
+	case dbhelper.FailurePolicyEscalate:
+		if escalateFailure(q, reason) {
+			db.Log(fmt.Sprintf("escalated failure of queue item %d of chat %d", q.ID, q.ChatID))
+		} else {
+			notifyAdmins(client, fmt.Sprintf(messageFailureAdminsFormat, q.ID, q.ChatID))
+		}
+	case dbhelper.FailurePolicySMS:
+		if isSMSEnabled() && sendFailureSMS(q) {
+			db.Log(fmt.Sprintf("sent queue item %d of chat %d as SMS", q.ID, q.ChatID))
+		} else {
+			notifyAdmins(client, fmt.Sprintf(messageSMSFailureAdminFormat, q.ID, q.ChatID))
+		}
+	default:
+		db.Log(fmt.Sprintf("gave up delivering queue item %d of chat %d: %s", q.ID, q.ChatID, reason))
+	}
+
+	// (a failed occurrence should not end its recurring series)
+	if !retried {
+		scheduleNextOccurrence(q)
+	}
+}
+
+// notify the failure of given queue item through the configured channels
+func escalateFailure(q dbhelper.QueueItem, reason string) (result bool) {
+	if _conf.EscalationWebhookURL != "" {
+		if err := postEscalationWebhook(q, reason); err != nil {
+			log.Printf("*** failed to post escalation webhook: %s", err)
+		} else {
+			result = true
+		}
+	}
+
+	if _conf.EscalationEmail != nil {
+		if err := sendEscalationEmail(*_conf.EscalationEmail, q, reason); err != nil {
+			log.Printf("*** failed to send escalation email: %s", err)
+		} else {
+			result = true
+		}
+	}
+
+	return result
+}
+
+// post the failure to the escalation webhook
+func postEscalationWebhook(q dbhelper.QueueItem, reason string) error {
+	body, err := json.Marshal(escalationPayload{
+		QueueID: q.ID,
+		ChatID:  q.ChatID,
+		UserID:  q.UserID,
+		Message: q.Message,
+		FireOn:  q.FireOn,
+		Error:   reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	res, err := _escalationClient.Post(_conf.EscalationWebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("http status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// send the failure through email
+func sendEscalationEmail(conf emailConfig, q dbhelper.QueueItem, reason string) error {
+	var auth smtp.Auth
+	if conf.Username != "" {
+		host, _, _ := net.SplitHostPort(conf.SMTPServer)
+		auth = smtp.PlainAuth("", conf.Username, conf.Password, host)
+	}
+
+	body := fmt.Sprintf(messageEscalationBodyFmt, q.ChatID, q.FireOn.Format(reminderTimeFormat), q.Message, reason)
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s", conf.From, conf.To, messageEscalationSubject, body)
+
+	return smtp.SendMail(conf.SMTPServer, auth, conf.From, []string{conf.To}, []byte(msg))
+}
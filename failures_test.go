@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+func TestHandleDeliveryFailureOfRecurringReminder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reminder-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	_location = time.Local
+	db = dbhelper.OpenDb(filepath.Join(dir, dbFilename))
+
+	fireOn := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	for i, test := range []struct {
+		policy      string
+		rescheduled bool
+	}{
+		{dbhelper.FailurePolicyGiveUp, true},
+		{dbhelper.FailurePolicyRetryNextDay, false},
+	} {
+		chatID := int64(i + 1)
+
+		queueID, enqueued := db.EnqueueItem(dbhelper.QueueItem{
+			ChatID:        chatID,
+			Message:       "test",
+			FireOn:        fireOn,
+			Recurrence:    recurrenceDaily,
+			FailurePolicy: test.policy,
+		})
+		if !enqueued {
+			t.Fatalf("failed to enqueue item with policy '%s'", test.policy)
+		}
+		q, _ := db.QueueItem(chatID, queueID)
+
+		handleDeliveryFailure(nil, q, "test failure")
+
+		rescheduled := false
+		for _, item := range db.UndeliveredQueueItems(chatID) {
+			if item.ID != queueID && item.Recurrence == recurrenceDaily && item.FireOn.Unix() == fireOn.AddDate(0, 0, 1).Unix() {
+				rescheduled = true
+			}
+		}
+		if rescheduled != test.rescheduled {
+			t.Errorf("next occurrence with policy '%s': expected %t, got %t", test.policy, test.rescheduled, rescheduled)
+		}
+	}
+}
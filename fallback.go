@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// falling back to private chats: when a reminder cannot be delivered to its group (eg. the bot was kicked,
+// or has no rights to send messages), it is delivered to the creator's private chat instead (if they started the bot)
+
+// messages (can be overridden with messages.json)
+var (
+	messageFallbackNoteFormat = "(그룹 '%s'에 보내지 못해서 개인 대화로 보냅니다)"
+)
+
+// check if given queue item can fall back to the creator's private chat after failing with given error
+func canFallBackToPrivateChat(q dbhelper.QueueItem, err error) bool {
+	if q.Kind != dbhelper.QueueKindReminder || q.Broadcast || q.UserID == 0 || !isGroupChatID(q.ChatID) {
+		return false
+	}
+
+	switch err.(type) {
+	case ErrBlocked, ErrChatNotFound, ErrNoRights:
+		return hasPrivateChat(q.UserID)
+	}
+	return false
+}
+
+// deliver given queue item to the creator's private chat, with a note of the fallback
+func sendToPrivateChatInstead(client *bot.Bot, q dbhelper.QueueItem, reason string) bot.APIResponseMessage {
+	message, options := deliveryMessage(client, q)
+
+	// (callbacks of the keyboards work in the group only)
+	delete(options, "reply_markup")
+
+	message += "\n\n" + fmt.Sprintf(messageFallbackNoteFormat, chatNameOf(q.ChatID))
+
+	sent := client.SendMessage(q.UserID, message, options)
+	if sent.Ok {
+		db.Log(fmt.Sprintf("delivered queue item %d of chat %d to the private chat of user %d instead: %s", q.ID, q.ChatID, q.UserID, reason))
+	} else {
+		log.Printf("*** failed to fall back to the private chat of user %d: %s", q.UserID, *sent.Description)
+	}
+
+	return sent
+}
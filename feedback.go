@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	commandFeedback = "/feedback"
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageFeedbackUsage         = "사용법: /feedback 내용 (예: /feedback '다음 주 월요일'을 이번 주로 알아들었어요)"
+	messageFeedbackThanks        = "소중한 의견 감사합니다. 관리자에게 전달했습니다."
+	messageFeedbackSaveFailed    = "의견을 저장하지 못했습니다. 잠시 후 다시 시도해 주세요."
+	messageFeedbackAdminFormat   = "[관리자] 피드백 #%d (%s, user: %d, chat: %d):\n%s\n\n(이 메시지에 답장하면 사용자에게 전달됩니다)"
+	messageFeedbackAnswerFormat  = "보내주신 의견에 대한 관리자의 답변입니다:\n%s"
+	messageFeedbackAnswered      = "[관리자] 답변을 보냈습니다."
+	messageFeedbackAnswerFailed  = "[관리자] 답변을 보내지 못했습니다."
+	messageFeedbackUnknownSender = "(알 수 없음)"
+)
+
+// process /feedback command: save the feedback and forward it to admins
+func processFeedbackCommand(b *bot.Bot, chatID, userID int64, username, txt string) string {
+	feedback := strings.TrimSpace(strings.TrimPrefix(txt, commandFeedback))
+	if feedback == "" {
+		return messageFeedbackUsage
+	}
+
+	feedbackID, saved := db.SaveFeedback(chatID, userID, username, feedback)
+	if !saved {
+		return messageFeedbackSaveFailed
+	}
+
+	sender := username
+	if sender == "" {
+		sender = messageFeedbackUnknownSender
+	}
+	forwarded := fmt.Sprintf(messageFeedbackAdminFormat, feedbackID, sender, userID, chatID, feedback)
+	for _, adminID := range _adminUserIds {
+		// private chat id is the same as the user id
+		if sent := b.SendMessage(adminID, forwarded, map[string]interface{}{}); sent.Ok {
+			db.SaveFeedbackForward(feedbackID, adminID, int64(sent.Result.MessageID))
+		} else {
+			log.Printf("*** failed to forward feedback to admin %d: %s", adminID, *sent.Description)
+		}
+	}
+
+	return messageFeedbackThanks
+}
+
+// feedback which was replied to by an admin with given message
+func feedbackRepliedTo(message *bot.Message, userID int64) (feedback dbhelper.Feedback, isReply bool) {
+	if message.ReplyToMessage == nil || !isAdmin(userID) {
+		return feedback, false
+	}
+
+	return db.ForwardedFeedback(message.Chat.ID, int64(message.ReplyToMessage.MessageID))
+}
+
+// send the answer of an admin to the user who sent given feedback
+func processFeedbackReply(b *bot.Bot, feedback dbhelper.Feedback, answer string) string {
+	if sent := b.SendMessage(feedback.ChatID, fmt.Sprintf(messageFeedbackAnswerFormat, answer), map[string]interface{}{}); !sent.Ok {
+		log.Printf("*** failed to send answer of feedback %d: %s", feedback.ID, *sent.Description)
+		return messageFeedbackAnswerFailed
+	}
+
+	db.MarkFeedbackAsAnswered(feedback.ID)
+
+	return messageFeedbackAnswered
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+
+	bot "github.com/meinside/telegram-bot-go"
+)
+
+const (
+	commandFeed = "/feed"
+
+	feedFetchTimeoutSeconds = 30
+	feedMaxItemsPerCheck    = 5 // not to flood the chat with too many new entries at once
+	feedMaxPerChat          = 20
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageFeedUsage = `사용법:
+/feed https://blog.example.com/rss : 피드 등록
+"이 블로그 새 글 올라오면 알려줘 https://..." 처럼 말해도 등록됨
+/feed : 등록된 피드 조회 및 삭제`
+	messageFeedAddedFormat   = "피드 '%s'를 등록했습니다. 새 글이 올라오면 알려드리겠습니다."
+	messageFeedExists        = "이미 등록된 피드입니다."
+	messageFeedTooMany       = "더 이상 피드를 등록할 수 없습니다."
+	messageFeedFetchFailed   = "피드를 읽지 못했습니다. (공개된 http(s) 주소인지 확인해 주세요)"
+	messageFeedNone          = "등록된 피드가 없습니다."
+	messageFeedDeleteWhat    = "삭제할 피드를 선택해 주세요."
+	messageFeedDeleted       = "피드가 삭제 되었습니다."
+	messageFeedItemFormat    = "[%s] %s\n%s"
+	messageFeedListItemFmt   = "➤ %s (%s)"
+	messageFeedUntitledTitle = "(제목 없음)"
+)
+
+var (
+	feedURLRegex   = regexp.MustCompile(`https?://\S+`)
+	feedAskedWords = []string{"새 글", "새글", "올라오면"}
+)
+
+// check if given text is the feed command (not others starting with it, eg. "/feedback")
+func isFeedCommand(txt string) bool {
+	return txt == commandFeed || strings.HasPrefix(txt, commandFeed+" ")
+}
+
+// check if given text asks for watching a feed ("이 블로그 새 글 올라오면 알려줘 https://...")
+func isFeedText(txt string) bool {
+	if isFeedCommand(txt) {
+		return true
+	}
+
+	return feedURLRegex.MatchString(txt) && containsAny(txt, feedAskedWords)
+}
+
+// process feed command or text
+func processFeedText(chatID int64, txt string, options map[string]interface{}) string {
+	url := feedURLRegex.FindString(txt)
+	if url == "" {
+		if strings.TrimSpace(strings.TrimPrefix(txt, commandFeed)) != "" {
+			return messageFeedUsage
+		}
+
+		return listFeeds(chatID, options)
+	}
+
+	return addFeed(chatID, url)
+}
+
+// register given feed url for given chat
+func addFeed(chatID int64, url string) string {
+	if len(db.Feeds(chatID)) >= feedMaxPerChat {
+		return messageFeedTooMany
+	}
+
+	feed, err := fetchFeed(url)
+	if err != nil {
+		log.Printf("*** failed to fetch feed %s: %s", url, err)
+
+		// (details of errors are not shown, as they might tell about the network of the bot)
+		return messageFeedFetchFailed
+	}
+
+	feedID, saved := db.SaveFeed(chatID, url, feed.Title)
+	if !saved {
+		return messageFeedExists
+	}
+
+	// existing entries are not new
+	for _, item := range feed.Items {
+		db.SaveFeedItem(feedID, feedItemGUID(item))
+	}
+	db.MarkFeedChecked(feedID)
+
+	return fmt.Sprintf(messageFeedAddedFormat, feedTitle(feed.Title, url))
+}
+
+// list feeds of given chat with a keyboard for deleting them
+func listFeeds(chatID int64, options map[string]interface{}) string {
+	feeds := db.Feeds(chatID)
+	if len(feeds) <= 0 {
+		return messageFeedNone + "\n\n" + messageFeedUsage
+	}
+
+	keys := make(map[string]string)
+	for _, f := range feeds {
+		keys[fmt.Sprintf(messageFeedListItemFmt, feedTitle(f.Title, f.URL), f.URL)] = fmt.Sprintf("%s %d", commandFeed, f.ID)
+	}
+	buttons := bot.NewInlineKeyboardButtonsAsRowsWithCallbackData(keys)
+
+	cancel := commandCancel
+	buttons = append(buttons, []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{Text: messageCancel, CallbackData: &cancel},
+	})
+
+	options["reply_markup"] = bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+
+	return messageFeedDeleteWhat
+}
+
+// process callback query for deleting a feed
+func processFeedCallback(query bot.CallbackQuery, txt string) string {
+	if feedID, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(txt, commandFeed)), 10, 64); err == nil {
+		if db.DeleteFeed(query.Message.Chat.ID, feedID) {
+			return messageFeedDeleted
+		}
+		log.Printf("*** Failed to delete feed")
+	} else {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+	}
+
+	return messageError
+}
+
+// check feeds periodically
+func monitorFeeds(ticker *time.Ticker, client *bot.Bot) {
+	for range ticker.C {
+		checkFeeds(client)
+	}
+}
+
+// check all feeds and send their new entries
+func checkFeeds(client *bot.Bot) {
+	for _, f := range db.Feeds(0) {
+		feed, err := fetchFeed(f.URL)
+		if err != nil {
+			if _isVerbose {
+				log.Printf("*** failed to fetch feed %s: %s", f.URL, err)
+			}
+			continue
+		}
+
+		// oldest first
+		numSent := 0
+		for i := len(feed.Items) - 1; i >= 0; i-- {
+			item := feed.Items[i]
+
+			if !db.SaveFeedItem(f.ID, feedItemGUID(item)) {
+				continue // seen already
+			}
+			if numSent >= feedMaxItemsPerCheck {
+				continue // (marked as seen)
+			}
+
+			message := fmt.Sprintf(messageFeedItemFormat, feedTitle(feed.Title, f.URL), item.Title, item.Link)
+			if sent := client.SendMessage(f.ChatID, message, map[string]interface{}{}); sent.Ok {
+				numSent++
+			} else {
+				log.Printf("*** failed to send feed item to chat %d: %s", f.ChatID, *sent.Description)
+			}
+		}
+
+		db.MarkFeedChecked(f.ID)
+	}
+}
+
+var _feedClient = &http.Client{
+	Timeout:   feedFetchTimeoutSeconds * time.Second,
+	Transport: publicOnlyTransport(feedFetchTimeoutSeconds * time.Second),
+}
+
+// fetch and parse given feed url (on public addresses only)
+func fetchFeed(url string) (*gofeed.Feed, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), feedFetchTimeoutSeconds*time.Second)
+	defer cancel()
+
+	parser := gofeed.NewParser()
+	parser.Client = _feedClient
+
+	return parser.ParseURLWithContext(url, ctx)
+}
+
+// unique id of given feed item for deduplication
+func feedItemGUID(item *gofeed.Item) string {
+	if item.GUID != "" {
+		return item.GUID
+	}
+	if item.Link != "" {
+		return item.Link
+	}
+	return item.Title
+}
+
+// displayable title of a feed
+func feedTitle(title, url string) string {
+	if title != "" {
+		return title
+	}
+	if url != "" {
+		return url
+	}
+	return messageFeedUntitledTitle
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// focus sessions: ordinary reminders are deferred (as a suppression window of the chat) until the session ends,
+// then delivered together in the message of a digest item; urgent ones (eg. #긴급) and special kinds are not deferred
+
+const (
+	commandFocus = "/focus"
+
+	focusParamEnd = "끝"
+
+	focusMaxHours = 12
+)
+
+// categories of urgent reminders (delivered even in focus sessions)
+var focusUrgentCategories = []string{"긴급", "urgent"}
+
+// messages (can be overridden with messages.json)
+var (
+	messageFocusUsage          = "사용법: /focus 2시간 (또는 /focus 45분, /focus 끝)\n집중하는 동안의 알림은 끝난 뒤 한 번에 보내드립니다. (#긴급 알림은 바로 보냅니다)"
+	messageFocusTooLong        = "집중 시간은 12시간까지만 설정할 수 있습니다."
+	messageFocusStartedFormat  = "🎯 %s까지 집중 시간입니다. 그 동안의 알림은 끝난 뒤 한 번에 보내드립니다."
+	messageFocusAlreadyFormat  = "이미 %s까지 집중 시간입니다. ('/focus 끝'으로 끝낼 수 있습니다)"
+	messageFocusEnded          = "집중 시간을 끝냈습니다."
+	messageFocusNone           = "집중 시간이 아닙니다."
+	messageFocusDigestTitle    = "🎯 집중 시간이 끝났습니다."
+	messageFocusDigestFormat   = "🎯 집중 시간이 끝났습니다. 그 동안 미뤄둔 알림 %d건:"
+	messageFocusDigestReminder = "집중 시간 끝"
+	messageFocusTimeFormat     = "15:04"
+)
+
+// process /focus command
+func processFocusCommand(chatID, userID int64, txt string) string {
+	param := strings.TrimSpace(strings.TrimPrefix(txt, commandFocus))
+	if param == focusParamEnd {
+		return endFocusSession(chatID)
+	}
+
+	duration, _ := parseTimerDuration(param)
+	if duration <= 0 {
+		return messageFocusUsage
+	}
+	if duration > focusMaxHours*time.Hour {
+		return messageFocusTooLong
+	}
+
+	for _, s := range db.Suppressions(chatID, dbhelper.SuppressionKindFocus) {
+		if s.StartsOn.Before(time.Now()) {
+			return fmt.Sprintf(messageFocusAlreadyFormat, formatTimeFor(chatID, s.EndsOn, messageFocusTimeFormat))
+		}
+	}
+
+	now := time.Now()
+	session := dbhelper.Suppression{
+		ChatID:   chatID,
+		Kind:     dbhelper.SuppressionKindFocus,
+		Mode:     dbhelper.SuppressionModeDigest,
+		StartsOn: now,
+		EndsOn:   now.Add(duration),
+	}
+	sessionID, saved := db.SaveSuppression(session)
+	if !saved {
+		return messageSaveFailed
+	}
+
+	// digest is delivered when the session ends
+	if _, enqueued := db.EnqueueItem(dbhelper.QueueItem{
+		ChatID:  chatID,
+		UserID:  userID,
+		Message: messageFocusDigestReminder,
+		FireOn:  session.EndsOn,
+		Kind:    dbhelper.QueueKindFocusDigest,
+		RefID:   sessionID,
+	}); !enqueued {
+		db.DeleteSuppressions(chatID, dbhelper.SuppressionKindFocus)
+		return messageSaveFailed
+	}
+
+	return fmt.Sprintf(messageFocusStartedFormat, formatTimeFor(chatID, session.EndsOn, messageFocusTimeFormat))
+}
+
+// end the focus session of given chat now (deferred reminders and the digest are delivered right away)
+func endFocusSession(chatID int64) string {
+	sessions := db.Suppressions(chatID, dbhelper.SuppressionKindFocus)
+	if len(sessions) <= 0 || db.DeleteSuppressions(chatID, dbhelper.SuppressionKindFocus) <= 0 {
+		return messageFocusNone
+	}
+
+	now := time.Now()
+	for _, s := range sessions {
+		for _, q := range db.UndeliveredQueueItems(chatID) {
+			if q.FireOn.Equal(s.EndsOn) && (q.Kind == dbhelper.QueueKindFocusDigest || isDeferrableInFocus(q)) {
+				if !db.RescheduleQueueItem(chatID, q.ID, now) {
+					log.Printf("*** failed to reschedule queue item %d after focus session", q.ID)
+				}
+			}
+		}
+	}
+
+	return messageFocusEnded
+}
+
+// check if given item is deferred during focus sessions
+func isDeferrableInFocus(q dbhelper.QueueItem) bool {
+	if q.Kind != dbhelper.QueueKindReminder || q.Broadcast || isTimerPending(q.ID) {
+		return false
+	}
+
+	for _, category := range categoriesInMessage(q.Message) {
+		for _, urgent := range focusUrgentCategories {
+			if category == urgent {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// check if given item bypasses given suppression window
+func bypassesSuppression(q dbhelper.QueueItem, s dbhelper.Suppression) bool {
+	return s.Kind == dbhelper.SuppressionKindFocus && !isDeferrableInFocus(q)
+}
+
+// attach reminders deferred by focus sessions to the digests of the sessions (delivered in their messages)
+//
+// returns the items to be delivered now
+func collectFocusDigests(items []dbhelper.QueueItem) []dbhelper.QueueItem {
+	digests := map[string]int{} // key: chat id/fire time, value: index in items
+	for i, q := range items {
+		if q.Kind == dbhelper.QueueKindFocusDigest {
+			digests[fmt.Sprintf("%d/%d", q.ChatID, q.FireOn.Unix())] = i
+		}
+	}
+	if len(digests) <= 0 {
+		return items
+	}
+
+	deferred := map[int][]dbhelper.QueueItem{}
+	result := []dbhelper.QueueItem{}
+	for _, q := range items {
+		if isDeferrableInFocus(q) {
+			if i, exists := digests[fmt.Sprintf("%d/%d", q.ChatID, q.FireOn.Unix())]; exists {
+				deferred[i] = append(deferred[i], q)
+				continue
+			}
+		}
+		result = append(result, q)
+	}
+
+	for i, group := range deferred {
+		_mergedItems.Lock()
+		_mergedItems.items[items[i].ID] = group
+		_mergedItems.Unlock()
+	}
+
+	return result
+}
+
+// message of given focus digest, with the reminders deferred during the session
+func focusDigestMessage(q dbhelper.QueueItem) string {
+	_mergedItems.Lock()
+	deferred := _mergedItems.items[q.ID]
+	_mergedItems.Unlock()
+
+	if len(deferred) <= 0 {
+		return messageFocusDigestTitle
+	}
+
+	lines := []string{fmt.Sprintf(messageFocusDigestFormat, len(deferred))}
+	for _, m := range deferred {
+		lines = append(lines, fmt.Sprintf(messageMergedReminderFormat, reminderText(m.ChatID, sanitizedDeliveryText(m.ChatID, m.Message))))
+	}
+
+	return strings.Join(lines, "\n")
+}
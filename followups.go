@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	callbackFollowUp = "/followup"
+
+	// steps of the dialog
+	followUpStepAsk    = "ask"
+	followUpStepSnooze = "snooze"
+
+	// terminal actions of the dialog
+	followUpActionDone   = "done"
+	followUpActionSnooze = "snooze:" // + minutes
+	followUpActionSkip   = "skip"
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageFollowUpDoneFormat    = "✔ 완료! (🔥 %d회 연속)"
+	messageFollowUpSnoozedFormat = "⏰ %s에 다시 알려드리겠습니다."
+	messageFollowUpSkipped       = "다음엔 꼭!"
+)
+
+// an answer of a follow-up step: goes to another step, or ends with an action
+type followUpAnswer struct {
+	Label  string
+	Action string
+}
+
+// a step of the follow-up dialog
+type followUpStep struct {
+	Question string
+	Answers  [][]followUpAnswer // (rows of buttons)
+}
+
+// follow-up dialog asked after delivering reminders
+var followUpSteps = map[string]followUpStep{
+	followUpStepAsk: followUpStep{
+		Question: "했어요?",
+		Answers: [][]followUpAnswer{
+			[]followUpAnswer{
+				followUpAnswer{Label: "예", Action: followUpActionDone},
+				followUpAnswer{Label: "아니오", Action: followUpStepSnooze},
+			},
+		},
+	},
+	followUpStepSnooze: followUpStep{
+		Question: "언제 다시 알려드릴까요?",
+		Answers: [][]followUpAnswer{
+			[]followUpAnswer{
+				followUpAnswer{Label: "10분 뒤", Action: followUpActionSnooze + "10"},
+				followUpAnswer{Label: "30분 뒤", Action: followUpActionSnooze + "30"},
+			},
+			[]followUpAnswer{
+				followUpAnswer{Label: "1시간 뒤", Action: followUpActionSnooze + "60"},
+				followUpAnswer{Label: "내일 이 시각", Action: followUpActionSnooze + "1440"},
+			},
+			[]followUpAnswer{
+				followUpAnswer{Label: "괜찮아요", Action: followUpActionSkip},
+			},
+		},
+	},
+}
+
+// check if a follow-up question should be asked after delivering given queue item
+func needsFollowUp(q dbhelper.QueueItem) bool {
+	if q.Broadcast || q.Kind != dbhelper.QueueKindReminder {
+		return false
+	}
+
+	return db.ChatSettings(q.ChatID).FollowUp
+}
+
+// message and keyboard of given follow-up step for given reminder
+func followUpMessage(q dbhelper.QueueItem, stepName string) (message string, keyboard bot.InlineKeyboardMarkup) {
+	step := followUpSteps[stepName]
+
+	buttons := [][]bot.InlineKeyboardButton{}
+	for _, row := range step.Answers {
+		buttonsRow := []bot.InlineKeyboardButton{}
+		for _, answer := range row {
+			data := fmt.Sprintf("%s %d %s", callbackFollowUp, q.ID, answer.Action)
+			buttonsRow = append(buttonsRow, bot.InlineKeyboardButton{Text: answer.Label, CallbackData: &data})
+		}
+		buttons = append(buttons, buttonsRow)
+	}
+
+	return q.Message + "\n\n" + step.Question, bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// process callback query of follow-up dialogs
+func processFollowUpCallback(query bot.CallbackQuery, txt string) (message string, keyboard interface{}) {
+	params := strings.Fields(strings.TrimPrefix(txt, callbackFollowUp))
+	if len(params) != 2 {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError, nil
+	}
+	queueID, err := strconv.ParseInt(params[0], 10, 64)
+	if err != nil {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError, nil
+	}
+	action := params[1]
+
+	chatID := query.Message.Chat.ID
+	userID := int64(query.From.ID)
+
+	q, exists := db.QueueItem(chatID, queueID)
+	if !exists {
+		return messageError, nil
+	}
+
+	// next step
+	if _, isStep := followUpSteps[action]; isStep {
+		return followUpMessage(q, action)
+	}
+
+	// end of the dialog
+	switch {
+	case action == followUpActionDone:
+		db.SaveFollowUp(queueID, chatID, userID, q.Message, true)
+
+		// same as acknowledging it
+		db.AckQueueItem(chatID, queueID, userID)
+		db.DeleteQueueItemsByRef(chatID, dbhelper.QueueKindGroupNag, queueID)
+		completeIntegrationTasks(q)
+
+		message = q.Message + "\n\n" + fmt.Sprintf(messageFollowUpDoneFormat, db.FollowUpStreak(chatID, q.Message))
+		if chained := triggerChains(chatID, queueID); chained != "" {
+			message += "\n" + chained
+		}
+		return message, nil
+	case strings.HasPrefix(action, followUpActionSnooze):
+		minutes, err := strconv.Atoi(strings.TrimPrefix(action, followUpActionSnooze))
+		if err != nil || minutes <= 0 {
+			log.Printf("*** Unprocessable callback query: %s", txt)
+			return messageError, nil
+		}
+
+		db.SaveFollowUp(queueID, chatID, userID, q.Message, false)
+
+		fireOn := time.Now().Add(time.Duration(minutes) * time.Minute)
+		if _, enqueued := db.EnqueueItem(dbhelper.QueueItem{
+			ChatID:   q.ChatID,
+			UserID:   q.UserID,
+			Message:  q.Message,
+			FireOn:   fireOn,
+			Assignee: q.Assignee,
+			Private:  q.Private,
+		}); !enqueued {
+			return messageSaveFailed, nil
+		}
+
+		return q.Message + "\n\n" + fmt.Sprintf(messageFollowUpSnoozedFormat, formatTimeFor(chatID, fireOn, reminderTimeFormat)), nil
+	case action == followUpActionSkip:
+		db.SaveFollowUp(queueID, chatID, userID, q.Message, false)
+
+		return q.Message + "\n\n" + messageFollowUpSkipped, nil
+	}
+
+	log.Printf("*** Unprocessable callback query: %s", txt)
+
+	return messageError, nil
+}
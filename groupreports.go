@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// reports for group admins: groups which opted in (with /settings) get a daily morning report of the day's reminders
+// (who created what for when), sent to each admin of the group in their private chats, after the group's quiet hours
+
+const (
+	groupReportHour = 8 // (or the end of the group's quiet hours, if it is in them)
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageGroupReportTitle       = "그룹 관리자 일일 보고"
+	messageGroupReportTitleFormat = "📋 '%s'의 오늘 알림 %d건:"
+	messageGroupReportLineFormat  = "➤ %s %s: %s"
+	messageGroupReportNoneFormat  = "📋 '%s'에 오늘 예약된 알림이 없습니다."
+	messageGroupReportTimeFormat  = "15:04"
+)
+
+// time of the next report of given group
+func nextGroupReportTime(chatID int64) time.Time {
+	s := db.ChatSettings(chatID)
+
+	hour := groupReportHour
+	if isInQuietHours(time.Date(2000, 1, 1, hour, 0, 0, 0, time.Local), s.QuietFrom, s.QuietTo) {
+		hour = s.QuietTo
+	}
+
+	return nextTimeOfDay(hour, 0)
+}
+
+// schedule the next report of given group (if it opted in, and it is not scheduled yet)
+func scheduleGroupReport(chatID, userID int64) {
+	if !isGroupChatID(chatID) || !db.ChatSettings(chatID).GroupReport {
+		return
+	}
+
+	for _, q := range db.UndeliveredQueueItems(chatID) {
+		if q.Kind == dbhelper.QueueKindGroupReport {
+			return
+		}
+	}
+
+	if _, enqueued := db.EnqueueItem(dbhelper.QueueItem{
+		ChatID:  chatID,
+		UserID:  userID,
+		Message: messageGroupReportTitle, // (replaced with the report on delivery)
+		FireOn:  nextGroupReportTime(chatID),
+		Kind:    dbhelper.QueueKindGroupReport,
+	}); !enqueued {
+		log.Printf("*** failed to enqueue report of group %d", chatID)
+	}
+}
+
+// cancel the scheduled report of given group
+func cancelGroupReport(chatID int64) {
+	db.DeleteQueueItemsByRef(chatID, dbhelper.QueueKindGroupReport, 0)
+}
+
+// report of the reminders of given group scheduled on the day of given time
+func groupReport(chatID int64, day time.Time) string {
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	reminders := db.ScheduledQueueItems(chatID, from, from.AddDate(0, 0, 1))
+
+	title := chatNameOf(chatID)
+	if len(reminders) <= 0 {
+		return fmt.Sprintf(messageGroupReportNoneFormat, title)
+	}
+
+	lines := []string{fmt.Sprintf(messageGroupReportTitleFormat, title, len(reminders))}
+	for _, r := range reminders {
+		lines = append(lines, fmt.Sprintf(messageGroupReportLineFormat,
+			formatTimeFor(chatID, r.FireOn, messageGroupReportTimeFormat),
+			userDisplayName(r.UserID),
+			sanitizedDeliveryText(chatID, visibleReminderText(r)),
+		))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// send the report of given queue item to the admins of its group in their private chats
+//
+// (returns the result of the last successful send, or of the last failure if there was no success)
+func sendGroupReports(client *bot.Bot, q dbhelper.QueueItem) (sent bot.APIResponseMessage) {
+	admins := client.GetChatAdministrators(q.ChatID)
+	if !admins.Ok {
+		log.Printf("*** failed to get admins of group %d: %s", q.ChatID, *admins.Description)
+
+		sent.Description = admins.Description
+		return sent
+	}
+
+	report := groupReport(q.ChatID, q.FireOn)
+
+	description := "no admin to report to"
+	sent.Description = &description
+	for _, admin := range admins.Result {
+		if admin.User.IsBot {
+			continue
+		}
+
+		// (admins who have not started a private chat with the bot cannot receive it)
+		if result := client.SendMessage(int64(admin.User.ID), report, map[string]interface{}{}); result.Ok {
+			sent = result
+		} else {
+			log.Printf("*** failed to send report of group %d to admin %d: %s", q.ChatID, admin.User.ID, *result.Description)
+
+			if !sent.Ok {
+				sent = result
+			}
+		}
+	}
+
+	return sent
+}
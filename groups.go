@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	callbackTransfer  = "/transfer"
+	transferParamDrop = "drop"
+
+	greetingIntervalSeconds = 60 // not to greet twice for my_chat_member and new_chat_members
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageTransferOfferFormat   = "봇이 그룹 '%s'에서 내보내져서, 그 그룹에 예약하신 알림 %d개가 일시중지 되었습니다.\n개인 대화로 옮기시겠습니까?"
+	messageTransferMove          = "개인 대화로 옮기기"
+	messageTransferDrop          = "모두 취소하기"
+	messageTransferredFormat     = "알림 %d개를 개인 대화로 옮겼습니다."
+	messageTransferDroppedFormat = "알림 %d개를 취소했습니다."
+	messageUnknownGroup          = "(알 수 없는 그룹)"
+	messageGreetingGroup         = "안녕하세요! 그룹에 초대해 주셔서 감사합니다."
+)
+
+// chats greeted recently
+var _greetings = struct {
+	sync.Mutex
+	chats map[int64]time.Time
+}{
+	chats: map[int64]time.Time{},
+}
+
+// process changes of the bot's own membership in chats
+//
+// (in private chats, the bot is 'kicked' when the user blocks it)
+func processMyChatMember(b *bot.Bot, update bot.Update) {
+	member := update.MyChatMember
+	chatID := member.Chat.ID
+
+	switch member.NewChatMember.Status {
+	case bot.ChatMemberStatusLeft, bot.ChatMemberStatusKicked:
+		log.Printf("Removed from chat: %d", chatID)
+
+		db.Log(fmt.Sprintf("removed from chat: %d", chatID))
+
+		// clean up states of the chat
+		db.SetChatActive(chatID, false)
+		db.DeleteAPIKey(chatID)
+
+		// pause reminders of the chat (they cannot be delivered anymore)
+		if db.PauseQueueItems(chatID, dbhelper.PausedByBotRemoval) && isGroupChatID(chatID) {
+			offerTransfers(b, chatID, groupTitle(member.Chat))
+		}
+	default:
+		switch member.OldChatMember.Status {
+		case bot.ChatMemberStatusLeft, bot.ChatMemberStatusKicked:
+			log.Printf("Added to chat: %d", chatID)
+
+			db.SaveChat(chatID, member.Chat.Type, groupTitle(member.Chat))
+
+			// resume reminders which were not transferred yet
+			db.ResumeQueueItems(chatID, dbhelper.PausedByBotRemoval)
+
+			if isGroupChatID(chatID) {
+				greetGroup(b, chatID)
+			}
+		}
+	}
+}
+
+// process service messages (members joined/left, title changed, ...)
+func processServiceMessage(b *bot.Bot, message *bot.Message) {
+	for _, user := range message.NewChatMembers {
+		if int64(user.ID) == _botUserID {
+			// (older clients/servers send only this, not my_chat_member)
+			greetGroup(b, message.Chat.ID)
+		}
+	}
+
+	if _isVerbose {
+		log.Printf("Ignoring service message in chat: %d", message.Chat.ID)
+	}
+}
+
+// check if given message is a service message
+func isServiceMessage(message *bot.Message) bool {
+	return len(message.NewChatMembers) > 0 ||
+		message.LeftChatMember != nil ||
+		message.NewChatTitle != nil ||
+		message.NewChatPhoto != nil ||
+		message.DeleteChatPhoto ||
+		message.GroupChatCreated ||
+		message.SupergroupChatCreated ||
+		message.PinnedMessage != nil ||
+		message.MigrateToChatID != nil ||
+		message.MigrateFromChatID != nil
+}
+
+// send a greeting message to given group (only once in a while)
+func greetGroup(b *bot.Bot, chatID int64) {
+	_greetings.Lock()
+	greetedOn, exists := _greetings.chats[chatID]
+	if exists && time.Since(greetedOn) < greetingIntervalSeconds*time.Second {
+		_greetings.Unlock()
+		return
+	}
+	_greetings.chats[chatID] = time.Now()
+	_greetings.Unlock()
+
+	if sent := b.SendMessage(chatID, messageGreetingGroup+"\n\n"+messageUsage, map[string]interface{}{}); !sent.Ok {
+		log.Printf("*** failed to send greeting to group %d: %s", chatID, *sent.Description)
+	}
+}
+
+// offer the creators of paused reminders in given group to move them to their private chats
+func offerTransfers(b *bot.Bot, chatID int64, title string) {
+	// count paused reminders of each creator
+	counts := map[int64]int{}
+	for _, q := range db.UndeliveredQueueItems(chatID) {
+		if q.Paused == dbhelper.PausedByBotRemoval && q.UserID != 0 {
+			counts[q.UserID]++
+		}
+	}
+
+	for userID, count := range counts {
+		move := fmt.Sprintf("%s %d", callbackTransfer, chatID)
+		drop := fmt.Sprintf("%s %d %s", callbackTransfer, chatID, transferParamDrop)
+
+		options := map[string]interface{}{
+			"reply_markup": bot.InlineKeyboardMarkup{
+				InlineKeyboard: [][]bot.InlineKeyboardButton{
+					[]bot.InlineKeyboardButton{
+						bot.InlineKeyboardButton{
+							Text:         messageTransferMove,
+							CallbackData: &move,
+						},
+						bot.InlineKeyboardButton{
+							Text:         messageTransferDrop,
+							CallbackData: &drop,
+						},
+					},
+				},
+			},
+		}
+
+		// (will fail if the user has never started a private chat with the bot)
+		if sent := b.SendMessage(userID, fmt.Sprintf(messageTransferOfferFormat, title, count), options); !sent.Ok {
+			log.Printf("*** failed to offer transfer to user %d: %s", userID, *sent.Description)
+		}
+	}
+}
+
+// process callback query for transferring paused reminders
+func processTransferCallback(query bot.CallbackQuery, txt string) string {
+	params := strings.Fields(strings.TrimSpace(strings.TrimPrefix(txt, callbackTransfer)))
+	if len(params) <= 0 {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+
+	groupChatID, err := strconv.ParseInt(params[0], 10, 64)
+	if err != nil {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+	userID := int64(query.From.ID)
+
+	if len(params) > 1 && params[1] == transferParamDrop {
+		num := db.DeletePausedQueueItems(groupChatID, userID, dbhelper.PausedByBotRemoval)
+		return fmt.Sprintf(messageTransferDroppedFormat, num)
+	}
+
+	num := db.TransferPausedQueueItems(groupChatID, userID, query.Message.Chat.ID, dbhelper.PausedByBotRemoval)
+	return fmt.Sprintf(messageTransferredFormat, num)
+}
+
+// title of given group chat (empty for private chats)
+func groupTitle(chat bot.Chat) string {
+	if chat.Title != nil {
+		return *chat.Title
+	}
+	if isGroupChatID(chat.ID) {
+		return messageUnknownGroup
+	}
+	return ""
+}
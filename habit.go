@@ -0,0 +1,437 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	commandHabit = "/habit"
+
+	habitParamDone        = "done"
+	habitParamMiss        = "miss"
+	habitParamDelete      = "del"
+	habitParamLeaderboard = "순위"
+	habitParamJoin        = "참여"
+	habitParamLeave       = "해제"
+
+	habitDayFormat        = "2006-01-02"
+	habitSummaryDays      = 7
+	habitSummaryHour      = 21
+	habitDailyIndicator   = "매일"
+	habitMaxStreakToCount = 3650 // not to loop forever
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageHabitUsage = `사용법:
+/habit 운동 매일 저녁 8시 : 매일 저녁 8시에 운동 했는지 물어보기
+/habit : 등록된 습관 조회 및 삭제
+/habit 순위 : 그룹 채팅의 연속 기록 순위
+/habit 순위 참여 (또는 해제) : 순위에 참여 (또는 해제)`
+	messageHabitPromptFormat         = "✅ 오늘 %s 하셨나요?"
+	messageHabitDone                 = "했어요"
+	messageHabitMissed               = "못했어요"
+	messageHabitSavedFormat          = "✅ %s: 매일 %s에 물어보겠습니다."
+	messageHabitInvalidTime          = "시각을 알 수 없습니다. (예: /habit 운동 매일 저녁 8시)"
+	messageHabitNone                 = "등록된 습관이 없습니다."
+	messageHabitDeleteWhat           = "삭제할 습관을 선택해 주세요."
+	messageHabitDeleted              = "습관이 삭제 되었습니다."
+	messageHabitListItemFormat       = "➤ %s (매일 %s)"
+	messageHabitCheckedFormat        = "%s: %s (🔥 %d일 연속)"
+	messageHabitMissedFormat         = "%s: %s (다음엔 꼭!)"
+	messageHabitSummaryTitle         = "✅ 이번 주 습관 기록:"
+	messageHabitSummaryLineFormat    = "➤ %s%s: %d/%d일, 🔥 %d일 연속"
+	messageHabitSummaryNone          = "아직 습관 기록이 없습니다."
+	messageHabitLeaderboardTitle     = "🏆 습관 연속 기록 순위:"
+	messageHabitLeaderboardFormat    = "%d. %s: 🔥 %d일"
+	messageHabitLeaderboardEmpty     = "순위에 참여한 사람이 없습니다.\n'/habit 순위 참여'로 참여할 수 있습니다."
+	messageHabitLeaderboardGroupOnly = "순위는 그룹 채팅에서만 볼 수 있습니다."
+	messageHabitLeaderboardJoined    = "습관 순위에 참여했습니다."
+	messageHabitLeaderboardLeft      = "습관 순위 참여를 해제했습니다."
+)
+
+var (
+	habitClockRegex = regexp.MustCompile(`(?:(새벽|아침|오전|점심|오후|저녁|밤)\s*)?(\d{1,2})시\s*(?:(\d{1,2})분|(반))?`)
+	habitTimeRegex  = regexp.MustCompile(`([01]?\d|2[0-3]):([0-5]\d)`)
+)
+
+// process /habit command
+func processHabitCommand(chatID, userID int64, txt string, options map[string]interface{}) string {
+	params := strings.Fields(strings.TrimPrefix(txt, commandHabit))
+
+	if len(params) == 0 {
+		return listHabits(chatID, options)
+	}
+	if params[0] == habitParamLeaderboard {
+		if !isGroupChatID(chatID) {
+			return messageHabitLeaderboardGroupOnly
+		}
+
+		if len(params) > 1 {
+			switch params[1] {
+			case habitParamJoin:
+				if db.SetHabitLeaderboardMember(chatID, userID, true) {
+					return messageHabitLeaderboardJoined
+				}
+				return messageError
+			case habitParamLeave:
+				if db.SetHabitLeaderboardMember(chatID, userID, false) {
+					return messageHabitLeaderboardLeft
+				}
+				return messageError
+			}
+		}
+
+		return habitLeaderboard(chatID)
+	}
+	if len(params) < 2 {
+		return messageHabitUsage
+	}
+
+	hour, minute, ok := parseHabitClock(strings.Join(params[1:], " "))
+	if !ok {
+		return messageHabitInvalidTime
+	}
+
+	// name of the habit: words before '매일' or the time
+	name := params[0]
+	for _, param := range params[1:] {
+		if param == habitDailyIndicator || habitClockRegex.MatchString(param) || habitTimeRegex.MatchString(param) {
+			break
+		}
+		name += " " + param
+	}
+
+	h := dbhelper.Habit{
+		ChatID: chatID,
+		UserID: userID,
+		Name:   name,
+		Time:   fmt.Sprintf("%02d:%02d", hour, minute),
+	}
+	habitID, saved := db.SaveHabit(h)
+	if !saved {
+		return messageSaveFailed
+	}
+
+	if _, enqueued := db.EnqueueItem(dbhelper.QueueItem{
+		ChatID:     chatID,
+		UserID:     userID,
+		Message:    fmt.Sprintf(messageHabitPromptFormat, name),
+		FireOn:     nextTimeOfDay(hour, minute),
+		Recurrence: recurrenceDaily,
+		Kind:       dbhelper.QueueKindHabit,
+		RefID:      habitID,
+	}); !enqueued {
+		db.DeactivateHabit(chatID, habitID)
+
+		return messageSaveFailed
+	}
+
+	scheduleHabitSummary(chatID, userID)
+
+	return fmt.Sprintf(messageHabitSavedFormat, name, h.Time)
+}
+
+// parse time of a day from given text (eg. "저녁 8시", "오전 7시 반", "20:30")
+func parseHabitClock(txt string) (hour, minute int, ok bool) {
+	if matches := habitTimeRegex.FindStringSubmatch(txt); matches != nil {
+		hour, _ = strconv.Atoi(matches[1])
+		minute, _ = strconv.Atoi(matches[2])
+		return hour, minute, true
+	}
+
+	matches := habitClockRegex.FindStringSubmatch(txt)
+	if matches == nil {
+		return 0, 0, false
+	}
+
+	hour, _ = strconv.Atoi(matches[2])
+	if matches[3] != "" {
+		minute, _ = strconv.Atoi(matches[3])
+	} else if matches[4] != "" {
+		minute = 30
+	}
+
+	switch matches[1] {
+	case "오후", "저녁":
+		if hour < 12 {
+			hour += 12
+		}
+	case "밤":
+		if hour == 12 {
+			hour = 0
+		} else if hour >= 6 && hour < 12 {
+			hour += 12
+		}
+	case "점심":
+		if hour < 6 {
+			hour += 12
+		}
+	case "새벽", "아침", "오전":
+		if hour == 12 {
+			hour = 0
+		}
+	}
+
+	if hour > 23 || minute > 59 {
+		return 0, 0, false
+	}
+
+	return hour, minute, true
+}
+
+// schedule weekly streak summary of given chat (if not scheduled yet)
+func scheduleHabitSummary(chatID, userID int64) {
+	for _, q := range db.UndeliveredQueueItems(chatID) {
+		if q.Kind == dbhelper.QueueKindHabitSummary {
+			return
+		}
+	}
+
+	db.EnqueueItem(dbhelper.QueueItem{
+		ChatID:     chatID,
+		UserID:     userID,
+		Message:    messageHabitSummaryTitle, // (replaced with the summary on delivery)
+		FireOn:     nextTimeOfWeekday(lastWeekdayOf(chatID), habitSummaryHour),
+		Recurrence: recurrenceWeekly,
+		Kind:       dbhelper.QueueKindHabitSummary,
+	})
+}
+
+// list habits of given chat with a keyboard for deleting them
+func listHabits(chatID int64, options map[string]interface{}) string {
+	habits := db.Habits(chatID)
+	if len(habits) <= 0 {
+		return messageHabitNone + "\n\n" + messageHabitUsage
+	}
+
+	keys := make(map[string]string)
+	for _, h := range habits {
+		keys[fmt.Sprintf(messageHabitListItemFormat, h.Name, h.Time)] = fmt.Sprintf("%s %s %d", commandHabit, habitParamDelete, h.ID)
+	}
+	buttons := bot.NewInlineKeyboardButtonsAsRowsWithCallbackData(keys)
+
+	cancel := commandCancel
+	buttons = append(buttons, []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{Text: messageCancel, CallbackData: &cancel},
+	})
+
+	options["reply_markup"] = bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+
+	return messageHabitDeleteWhat
+}
+
+// users to be counted for the habits of given chat
+//
+// (members who opted in to the leaderboard in groups, or the creator of each habit)
+func habitMembers(chatID int64, h dbhelper.Habit) []int64 {
+	if isGroupChatID(chatID) {
+		if members := db.HabitLeaderboardMembers(chatID); len(members) > 0 {
+			return members
+		}
+	}
+
+	return []int64{h.UserID}
+}
+
+// current streak of given checks as of given day
+//
+// (streak is not broken until the day is over)
+func habitStreak(checks map[string]bool, today time.Time) int {
+	day := truncateToDay(today)
+	if !checks[day.Format(habitDayFormat)] {
+		day = day.AddDate(0, 0, -1)
+	}
+
+	streak := 0
+	for streak < habitMaxStreakToCount && checks[day.Format(habitDayFormat)] {
+		streak++
+		day = day.AddDate(0, 0, -1)
+	}
+
+	return streak
+}
+
+// weekly streak summary of given chat
+func habitSummary(chatID int64) string {
+	habits := db.Habits(chatID)
+	if len(habits) <= 0 {
+		return messageHabitSummaryNone
+	}
+
+	now := time.Now()
+	lines := []string{messageHabitSummaryTitle}
+	for _, h := range habits {
+		members := habitMembers(chatID, h)
+		for _, userID := range members {
+			checks := db.HabitChecks(h.ID, userID)
+
+			numDone := 0
+			for i := 0; i < habitSummaryDays; i++ {
+				if checks[truncateToDay(now).AddDate(0, 0, -i).Format(habitDayFormat)] {
+					numDone++
+				}
+			}
+
+			who := ""
+			if isGroupChatID(chatID) {
+				who = fmt.Sprintf(" (%s)", userDisplayName(userID))
+			}
+
+			lines = append(lines, fmt.Sprintf(messageHabitSummaryLineFormat, h.Name, who, numDone, habitSummaryDays, habitStreak(checks, now)))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// leaderboard of streaks among members of given group chat who opted in
+func habitLeaderboard(chatID int64) string {
+	members := db.HabitLeaderboardMembers(chatID)
+	if len(members) <= 0 {
+		return messageHabitLeaderboardEmpty
+	}
+
+	type score struct {
+		userID int64
+		streak int
+	}
+
+	now := time.Now()
+	habits := db.Habits(chatID)
+	scores := []score{}
+	for _, userID := range members {
+		s := score{userID: userID}
+		for _, h := range habits {
+			s.streak += habitStreak(db.HabitChecks(h.ID, userID), now)
+		}
+		scores = append(scores, s)
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].streak > scores[j].streak
+	})
+
+	lines := []string{messageHabitLeaderboardTitle}
+	for i, s := range scores {
+		lines = append(lines, fmt.Sprintf(messageHabitLeaderboardFormat, i+1, userDisplayName(s.userID), s.streak))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// message and keyboard for delivering habit queue items
+func habitDeliveryMessage(q dbhelper.QueueItem) (message string, keyboard interface{}) {
+	switch q.Kind {
+	case dbhelper.QueueKindHabit:
+		return q.Message, habitCheckKeyboard(q.RefID, q.FireOn)
+	case dbhelper.QueueKindHabitSummary:
+		return habitSummary(q.ChatID), nil
+	}
+
+	return q.Message, nil
+}
+
+// inline keyboard for checking a habit of given day
+func habitCheckKeyboard(habitID int64, day time.Time) bot.InlineKeyboardMarkup {
+	done := fmt.Sprintf("%s %s %d %s", commandHabit, habitParamDone, habitID, day.Format(habitDayFormat))
+	miss := fmt.Sprintf("%s %s %d %s", commandHabit, habitParamMiss, habitID, day.Format(habitDayFormat))
+
+	return bot.InlineKeyboardMarkup{
+		InlineKeyboard: [][]bot.InlineKeyboardButton{
+			[]bot.InlineKeyboardButton{
+				bot.InlineKeyboardButton{Text: messageHabitDone, CallbackData: &done},
+				bot.InlineKeyboardButton{Text: messageHabitMissed, CallbackData: &miss},
+			},
+		},
+	}
+}
+
+// schedule following items after delivering habit queue items
+func afterHabitDelivery(q dbhelper.QueueItem) {
+	switch q.Kind {
+	case dbhelper.QueueKindHabit:
+		if h, exists := db.Habit(q.RefID); exists && h.IsActive {
+			scheduleNextOccurrence(q)
+		}
+	case dbhelper.QueueKindHabitSummary:
+		if len(db.Habits(q.ChatID)) > 0 {
+			scheduleNextOccurrence(q)
+		}
+	}
+}
+
+// process callback query of habits
+func processHabitCallback(query bot.CallbackQuery, txt string) (message string, keyboard interface{}) {
+	params := strings.Fields(strings.TrimPrefix(txt, commandHabit))
+	if len(params) < 2 {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError, nil
+	}
+	habitID, err := strconv.ParseInt(params[1], 10, 64)
+	if err != nil {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError, nil
+	}
+
+	chatID := query.Message.Chat.ID
+	userID := int64(query.From.ID)
+
+	switch params[0] {
+	case habitParamDone, habitParamMiss:
+		if len(params) < 3 {
+			log.Printf("*** Unprocessable callback query: %s", txt)
+			return messageError, nil
+		}
+		day, err := time.ParseInLocation(habitDayFormat, params[2], time.Local)
+		if err != nil {
+			log.Printf("*** Unprocessable callback query: %s", txt)
+			return messageError, nil
+		}
+
+		h, exists := db.Habit(habitID)
+		if !exists || h.ChatID != chatID {
+			return messageError, nil
+		}
+
+		done := params[0] == habitParamDone
+		if !db.SaveHabitCheck(habitID, userID, params[2], done) {
+			return messageError, nil
+		}
+
+		line := fmt.Sprintf(messageHabitMissedFormat, userDisplayName(userID), messageHabitMissed)
+		if done {
+			line = fmt.Sprintf(messageHabitCheckedFormat, userDisplayName(userID), messageHabitDone, habitStreak(db.HabitChecks(habitID, userID), day))
+		}
+
+		// keep the buttons for other members in groups
+		if isGroupChatID(chatID) {
+			text := ""
+			if query.Message.Text != nil {
+				text = *query.Message.Text
+			}
+			return text + "\n" + line, habitCheckKeyboard(habitID, day)
+		}
+
+		return line, nil
+	case habitParamDelete:
+		if db.DeactivateHabit(chatID, habitID) {
+			db.DeleteQueueItemsByRef(chatID, dbhelper.QueueKindHabit, habitID)
+
+			return messageHabitDeleted, nil
+		}
+	default:
+		log.Printf("*** Unprocessable callback query: %s", txt)
+	}
+
+	return messageError, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	commandMove = "/move"
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageMove               = "이동"
+	messageMoveWhat           = "어떤 알림을 옮기시겠습니까?"
+	messageMoveWhere          = "어느 대화로 옮기시겠습니까?"
+	messageNoMovableReminders = "옮길 수 있는 알림이 없습니다. (직접 등록한 알림만 옮길 수 있습니다)"
+	messageNoChatsToMove      = "함께 있는 다른 대화가 없습니다."
+	messagePrivateChat        = "개인 대화"
+	messageMovedFormat        = "알림을 '%s'(으)로 옮겼습니다."
+	messageMovedInFormat      = "%s님이 알림을 옮겨 왔습니다:\n%s"
+)
+
+// process /move command: show reminders of the user which can be moved
+func processMoveCommand(chatID, userID int64, options map[string]interface{}) string {
+	reminders := []dbhelper.QueueItem{}
+	for _, r := range db.UndeliveredQueueItems(chatID) {
+		if r.UserID == userID && r.Kind == dbhelper.QueueKindReminder {
+			reminders = append(reminders, r)
+		}
+	}
+
+	if len(reminders) <= 0 {
+		return messageNoMovableReminders
+	}
+
+	options["reply_markup"] = reminderSelectionKeyboard(reminders, commandMove)
+
+	return messageMoveWhat
+}
+
+// chats which the bot shares with given user (except given chat)
+//
+// (only the groups where the user has been seen are asked for the membership, not all of them)
+func sharedChats(client *bot.Bot, userID, exceptChatID int64) []dbhelper.Chat {
+	chats := []dbhelper.Chat{}
+	for _, chat := range db.ActiveChatsSeenWith(userID) {
+		if chat.ChatID == exceptChatID {
+			continue
+		}
+
+		if chat.ChatID == userID || (isGroupChatID(chat.ChatID) && isChatMember(client, chat.ChatID, userID)) {
+			chats = append(chats, chat)
+		}
+	}
+
+	return chats
+}
+
+// displayable name of given chat
+func chatName(chat dbhelper.Chat) string {
+	if isGroupChatID(chat.ChatID) {
+		if chat.Title != "" {
+			return chat.Title
+		}
+		return messageUnknownGroup
+	}
+
+	return messagePrivateChat
+}
+
+// process callback query for moving a reminder: '/move <queue id>' for selecting a chat, '/move <queue id> <chat id>' for moving
+func processMoveCallback(client *bot.Bot, query bot.CallbackQuery, txt string) (message string, keyboard interface{}) {
+	params := strings.Fields(strings.TrimPrefix(txt, commandMove))
+	if len(params) <= 0 {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError, nil
+	}
+	queueID, err := strconv.ParseInt(params[0], 10, 64)
+	if err != nil {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError, nil
+	}
+
+	chatID := query.Message.Chat.ID
+	userID := int64(query.From.ID)
+
+	q, exists := db.QueueItem(chatID, queueID)
+	if !exists || q.UserID != userID {
+		return messageNoMovableReminders, nil
+	}
+
+	// select a chat
+	if len(params) == 1 {
+		chats := sharedChats(client, userID, chatID)
+		if len(chats) <= 0 {
+			return messageNoChatsToMove, nil
+		}
+
+		buttons := [][]bot.InlineKeyboardButton{}
+		for _, chat := range chats {
+			data := fmt.Sprintf("%s %d %d", commandMove, queueID, chat.ChatID)
+			buttons = append(buttons, []bot.InlineKeyboardButton{
+				bot.InlineKeyboardButton{Text: chatName(chat), CallbackData: &data},
+			})
+		}
+		cancel := commandCancel
+		buttons = append(buttons, []bot.InlineKeyboardButton{
+			bot.InlineKeyboardButton{Text: messageCancel, CallbackData: &cancel},
+		})
+
+		return messageMoveWhere, bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+	}
+
+	// move to the chat
+	toChatID, err := strconv.ParseInt(params[1], 10, 64)
+	if err != nil {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError, nil
+	}
+
+	var to *dbhelper.Chat
+	for _, chat := range sharedChats(client, userID, chatID) {
+		if chat.ChatID == toChatID {
+			to = &chat
+			break
+		}
+	}
+	if to == nil {
+		return messageNoChatsToMove, nil
+	}
+
+	if !db.MoveQueueItem(chatID, queueID, userID, toChatID) {
+		return messageError, nil
+	}
+
+	// notify the destination
+	if sent := client.SendMessage(toChatID, fmt.Sprintf(messageMovedInFormat, userDisplayName(userID), formatReminder(q)), map[string]interface{}{}); !sent.Ok {
+		log.Printf("*** failed to notify moved reminder to chat %d: %s", toChatID, *sent.Description)
+	}
+
+	return fmt.Sprintf(messageMovedFormat, chatName(*to)), nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"log"
+	"sync"
+
+	apiai "github.com/meinside/api.ai-go"
+)
+
+// hook for inspecting or modifying the parsed result of api.ai before it is processed
+//
+// returning false will stop the response from being processed any further
+// (then the speech of the response is sent as it is)
+//
+// (eg. for site-specific corrections of parameters, register one in an init() of a new file:
+//
+//	func init() {
+//		registerNLUHook("office-hours", func(chatID, userID int64, response *apiai.QueryResponse) bool {
+//			if response.Result.Parameters["time"] == "12:00:00" {
+//				response.Result.Parameters["time"] = "12:30:00"
+//			}
+//			return true
+//		})
+//	}
+//
+// )
+type nluHook func(chatID, userID int64, response *apiai.QueryResponse) bool
+
+type namedNLUHook struct {
+	name string
+	hook nluHook
+}
+
+// registered hooks, run in the order of registration
+var _nluHooks = struct {
+	sync.RWMutex
+	hooks []namedNLUHook
+}{}
+
+// register a hook with given name (for logging)
+func registerNLUHook(name string, hook nluHook) {
+	_nluHooks.Lock()
+	_nluHooks.hooks = append(_nluHooks.hooks, namedNLUHook{name: name, hook: hook})
+	_nluHooks.Unlock()
+}
+
+// run all registered hooks on given response
+//
+// returns false if any of them stopped processing the response
+func runNLUHooks(chatID, userID int64, response *apiai.QueryResponse) bool {
+	_nluHooks.RLock()
+	hooks := _nluHooks.hooks
+	_nluHooks.RUnlock()
+
+	for _, h := range hooks {
+		if !runNLUHook(h, chatID, userID, response) {
+			if _isVerbose {
+				log.Printf("NLU hook '%s' stopped processing response for chat: %d", h.name, chatID)
+			}
+
+			return false
+		}
+	}
+
+	return true
+}
+
+// run a hook, not to let its panic break the main flow
+func runNLUHook(h namedNLUHook, chatID, userID int64, response *apiai.QueryResponse) (result bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("*** NLU hook '%s' panicked: %v", h.name, r)
+
+			result = true
+		}
+	}()
+
+	return h.hook(chatID, userID, response)
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// importing reminders from the export files of other apps (uploaded to the bot):
+//
+// - Todoist: CSV files ('Export as a template'), tasks with dates are imported
+// - Google Tasks: Tasks.json of Google Takeout, unfinished tasks with due dates are imported
+//
+// (Google Keep exports have no reminder times, so they are not importable)
+//
+// imported items are shown first, and saved after the confirmation
+
+const (
+	callbackImport = "/import"
+
+	importParamYes = "yes"
+	importParamNo  = "no"
+
+	importMaxFileBytes     = 1024 * 1024
+	importMaxItems         = 200
+	importNumPreviewItems  = 10
+	importPendingMinutes   = 10
+	importDefaultHour      = 9 // for dates without times
+	importTimeoutSeconds   = 30
+	importTodoistTypeTask  = "task"
+	importGoogleTasksKind  = "tasks#taskLists"
+	importGoogleTaskDone   = "completed"
+	importGoogleKeepMarker = `"textContent"`
+)
+
+// date(/time) layouts of Todoist exports
+var importTodoistDateLayouts = []string{
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"Jan 2 2006 15:04",
+	"Jan 2 2006",
+	"2 Jan 2006 15:04",
+	"2 Jan 2006",
+}
+
+// messages (can be overridden with messages.json)
+var (
+	messageImportPreviewFormat = "%s에서 알림 %d개를 가져옵니다:"
+	messageImportSkippedFormat = "(날짜가 없거나, 지났거나, 반복 일정인 %d개는 제외)"
+	messageImportMoreFormat    = "... 외 %d개"
+	messageImportLineFormat    = "➤ %s (%s)"
+	messageImportYes           = "가져오기"
+	messageImportNo            = "취소"
+	messageImportDoneFormat    = "알림 %d개를 가져왔습니다. (/list 로 확인할 수 있습니다)"
+	messageImportCanceled      = "가져오기를 취소했습니다."
+	messageImportExpired       = "가져올 내용이 없거나 시간이 지났습니다. 파일을 다시 보내 주세요."
+	messageImportNothingFormat = "%s 파일에 가져올 수 있는 알림이 없습니다. (날짜가 있는 앞으로의 할 일만 가져옵니다)"
+	messageImportUnknown       = "가져올 수 없는 파일입니다. Todoist의 CSV 파일이나, Google Takeout의 Tasks.json 파일을 보내 주세요."
+	messageImportKeep          = "Google Keep 내보내기 파일에는 알림 시각이 들어있지 않아 가져올 수 없습니다."
+	messageImportTooLarge      = "파일이 너무 큽니다. (1MB 이하)"
+	messageImportFailed        = "파일을 받지 못했습니다. 잠시 후 다시 보내 주세요."
+	messageImportTodoist       = "Todoist"
+	messageImportGoogleTasks   = "Google Tasks"
+)
+
+var _importClient = &http.Client{Timeout: importTimeoutSeconds * time.Second}
+
+// item parsed from an export file
+type importedItem struct {
+	title  string
+	fireOn time.Time
+}
+
+// items waiting for the confirmation
+type pendingImport struct {
+	userID    int64
+	items     []importedItem
+	expiresOn time.Time
+}
+
+var _pendingImports = struct {
+	sync.Mutex
+	chats map[int64]pendingImport
+}{
+	chats: map[int64]pendingImport{},
+}
+
+// process an uploaded file: parse it, then show a preview with buttons for confirming the import
+func processImportFile(b *bot.Bot, chatID, userID int64, document bot.Document, options map[string]interface{}) string {
+	if document.FileSize != nil && *document.FileSize > importMaxFileBytes {
+		return messageImportTooLarge
+	}
+
+	data, err := downloadFile(b, document.FileID, importMaxFileBytes)
+	if err != nil {
+		log.Printf("*** failed to download file for importing: %s", err)
+		return messageImportFailed
+	}
+
+	source, items, numSkipped, err := parseImportFile(data)
+	if err != nil {
+		return err.Error()
+	}
+	if len(items) <= 0 {
+		return fmt.Sprintf(messageImportNothingFormat, source)
+	}
+
+	_pendingImports.Lock()
+	_pendingImports.chats[chatID] = pendingImport{
+		userID:    userID,
+		items:     items,
+		expiresOn: time.Now().Add(importPendingMinutes * time.Minute),
+	}
+	_pendingImports.Unlock()
+
+	yes := fmt.Sprintf("%s %s", callbackImport, importParamYes)
+	no := fmt.Sprintf("%s %s", callbackImport, importParamNo)
+	options["reply_markup"] = bot.InlineKeyboardMarkup{
+		InlineKeyboard: [][]bot.InlineKeyboardButton{
+			[]bot.InlineKeyboardButton{
+				bot.InlineKeyboardButton{Text: messageImportYes, CallbackData: &yes},
+				bot.InlineKeyboardButton{Text: messageImportNo, CallbackData: &no},
+			},
+		},
+	}
+
+	return importPreview(chatID, source, items, numSkipped)
+}
+
+// preview of items to be imported
+func importPreview(chatID int64, source string, items []importedItem, numSkipped int) string {
+	lines := []string{fmt.Sprintf(messageImportPreviewFormat, source, len(items))}
+	for i, item := range items {
+		if i >= importNumPreviewItems {
+			lines = append(lines, fmt.Sprintf(messageImportMoreFormat, len(items)-i))
+			break
+		}
+		lines = append(lines, fmt.Sprintf(messageImportLineFormat, item.title, formatTimeFor(chatID, item.fireOn, reminderTimeFormat)))
+	}
+	if numSkipped > 0 {
+		lines = append(lines, fmt.Sprintf(messageImportSkippedFormat, numSkipped))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// download the file with given id (at most maxBytes)
+func downloadFile(b *bot.Bot, fileID string, maxBytes int64) ([]byte, error) {
+	file := b.GetFile(fileID)
+	if !file.Ok {
+		return nil, fmt.Errorf("failed to get file: %s", *file.Description)
+	}
+
+	resp, err := _importClient.Get(b.GetFileURL(*file.Result))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http status: %s", resp.Status)
+	}
+
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxBytes))
+}
+
+// parse given export file (detected by its contents)
+func parseImportFile(data []byte) (source string, items []importedItem, numSkipped int, err error) {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		if bytes.Contains(trimmed, []byte(importGoogleKeepMarker)) {
+			return "", nil, 0, errors.New(messageImportKeep)
+		}
+
+		items, numSkipped, err = parseGoogleTasks(trimmed)
+		return messageImportGoogleTasks, items, numSkipped, err
+	}
+
+	items, numSkipped, err = parseTodoistCSV(trimmed)
+	return messageImportTodoist, items, numSkipped, err
+}
+
+// parse Tasks.json of Google Takeout
+//
+// (due dates of Google Tasks have no times, so they are imported at importDefaultHour)
+func parseGoogleTasks(data []byte) (items []importedItem, numSkipped int, err error) {
+	var export struct {
+		Kind  string `json:"kind"`
+		Items []struct {
+			Title string `json:"title"`
+			Items []struct {
+				Title  string `json:"title"`
+				Status string `json:"status"`
+				Due    string `json:"due"`
+			} `json:"items"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &export); err != nil || export.Kind != importGoogleTasksKind {
+		return nil, 0, errors.New(messageImportUnknown)
+	}
+
+	for _, list := range export.Items {
+		for _, task := range list.Items {
+			if task.Status == importGoogleTaskDone {
+				continue
+			}
+
+			due, err := time.Parse(time.RFC3339, task.Due)
+			if err != nil {
+				numSkipped++
+				continue
+			}
+			due = due.UTC()
+
+			items, numSkipped = appendImportedItem(items, numSkipped, task.Title, time.Date(due.Year(), due.Month(), due.Day(), importDefaultHour, 0, 0, 0, _location))
+		}
+	}
+
+	return items, numSkipped, nil
+}
+
+// parse a CSV file of Todoist (exported as a template)
+//
+// (natural language dates like 'every day' cannot be parsed, so they are skipped)
+func parseTodoistCSV(data []byte) (items []importedItem, numSkipped int, err error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil || len(records) <= 0 {
+		return nil, 0, errors.New(messageImportUnknown)
+	}
+
+	// indices of columns
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	typeColumn, hasType := columns["TYPE"]
+	contentColumn, hasContent := columns["CONTENT"]
+	dateColumn, hasDate := columns["DATE"]
+	if !hasType || !hasContent || !hasDate {
+		return nil, 0, errors.New(messageImportUnknown)
+	}
+
+	for _, record := range records[1:] {
+		if len(record) <= typeColumn || len(record) <= contentColumn || len(record) <= dateColumn || record[typeColumn] != importTodoistTypeTask {
+			continue
+		}
+
+		fireOn, parsed := parseTodoistDate(strings.TrimSpace(record[dateColumn]))
+		if !parsed {
+			numSkipped++
+			continue
+		}
+
+		items, numSkipped = appendImportedItem(items, numSkipped, record[contentColumn], fireOn)
+	}
+
+	return items, numSkipped, nil
+}
+
+// parse the date of a Todoist task
+func parseTodoistDate(date string) (time.Time, bool) {
+	for _, layout := range importTodoistDateLayouts {
+		if t, err := time.ParseInLocation(layout, date, _location); err == nil {
+			if !strings.Contains(layout, "15:04") {
+				t = t.Add(importDefaultHour * time.Hour)
+			}
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// append an item with given title and time (skipped if it is not importable)
+func appendImportedItem(items []importedItem, numSkipped int, title string, fireOn time.Time) ([]importedItem, int) {
+	title = strings.TrimSpace(title)
+	if title == "" || fireOn.Before(time.Now()) || len(items) >= importMaxItems {
+		return items, numSkipped + 1
+	}
+
+	return append(items, importedItem{title: title, fireOn: fireOn}), numSkipped
+}
+
+// process callback query for confirming an import: '/import <yes|no>'
+func processImportCallback(query bot.CallbackQuery, txt string) string {
+	chatID := query.Message.Chat.ID
+	userID := int64(query.From.ID)
+
+	_pendingImports.Lock()
+	pending, exists := _pendingImports.chats[chatID]
+	if exists && pending.userID == userID {
+		delete(_pendingImports.chats, chatID)
+	}
+	_pendingImports.Unlock()
+
+	if !exists || pending.userID != userID || time.Now().After(pending.expiresOn) {
+		return messageImportExpired
+	}
+
+	switch strings.TrimSpace(strings.TrimPrefix(txt, callbackImport)) {
+	case importParamNo:
+		return messageImportCanceled
+	case importParamYes:
+		numImported := 0
+		for _, item := range pending.items {
+			sanitized, err := sanitizeReminderText(chatID, item.title)
+			if err != nil {
+				continue
+			}
+
+			q := dbhelper.QueueItem{
+				ChatID:  chatID,
+				UserID:  userID,
+				Message: sanitized,
+				FireOn:  item.fireOn,
+			}
+			if queueID, enqueued := db.EnqueueItem(q); enqueued {
+				q.ID = queueID
+				fireWebhooks(webhookEventCreated, q, "")
+
+				numImported++
+			}
+		}
+
+		db.Log(fmt.Sprintf("imported %d reminders into chat %d", numImported, chatID))
+
+		return fmt.Sprintf(messageImportDoneFormat, numImported)
+	}
+
+	log.Printf("*** Unprocessable callback query: %s", txt)
+
+	return messageError
+}
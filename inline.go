@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// search-as-you-type cancel with inline queries (eg. "@botname cancel 회")
+//
+// (inline mode should be enabled for the bot with @BotFather)
+
+const (
+	inlineQueryCancel       = "cancel"
+	inlineQueryCancelKorean = "취소"
+	callbackInlineCancel    = "/icancel"
+
+	inlineQueryMaxResults = 20
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageInlineCancelTitleFormat   = "취소: %s"
+	messageInlineCancelConfirmFormat = "'%s' (%s) 알림을 취소할까요?"
+	messageInlineCancelButton        = "취소하기"
+	messageInlineCanceledFormat      = "'%s' 알림이 취소 되었습니다."
+	messageInlineNotYours            = "직접 만든 알림만 취소할 수 있습니다."
+	messageInlineGone                = "이미 없어진 알림입니다."
+)
+
+// process inline query: list pending reminders of the user which match the query
+func processInlineQuery(b *bot.Bot, query bot.InlineQuery) {
+	userID, username := saveUser(&query.From)
+	if !isAllowedUser(userID, username) {
+		log.Printf("*** Id not allowed for inline query: %d (%s)", userID, username)
+		return
+	}
+
+	keyword, ok := inlineCancelKeyword(query.Query)
+	if !ok {
+		return
+	}
+
+	results := []interface{}{}
+	for _, r := range matchingReminders(userID, keyword) {
+		data := fmt.Sprintf("%s %d %d", callbackInlineCancel, r.ChatID, r.ID)
+		when := formatTimeFor(r.ChatID, r.FireOn, reminderTimeFormat)
+
+		results = append(results, map[string]interface{}{
+			"type":        "article",
+			"id":          strconv.FormatInt(r.ID, 10),
+			"title":       fmt.Sprintf(messageInlineCancelTitleFormat, reminderText(r.ChatID, r.Message)),
+			"description": when,
+			"input_message_content": map[string]interface{}{
+				"message_text": fmt.Sprintf(messageInlineCancelConfirmFormat, reminderText(r.ChatID, r.Message), when),
+			},
+			"reply_markup": bot.InlineKeyboardMarkup{
+				InlineKeyboard: [][]bot.InlineKeyboardButton{
+					[]bot.InlineKeyboardButton{
+						bot.InlineKeyboardButton{
+							Text:         messageInlineCancelButton,
+							CallbackData: &data,
+						},
+					},
+				},
+			},
+		})
+	}
+
+	if answered := b.AnswerInlineQuery(query.ID, results, map[string]interface{}{
+		"cache_time":  0,    // (results change as reminders are canceled)
+		"is_personal": true, // (results are different for each user)
+	}); !answered.Ok {
+		log.Printf("*** failed to answer inline query: %s", *answered.Description)
+	}
+}
+
+// keyword of given inline query for canceling (eg. "cancel 회" => "회")
+func inlineCancelKeyword(query string) (keyword string, ok bool) {
+	query = strings.TrimSpace(query)
+	for _, prefix := range []string{inlineQueryCancel, inlineQueryCancelKorean} {
+		if query == prefix || strings.HasPrefix(query, prefix+" ") {
+			return strings.TrimSpace(strings.TrimPrefix(query, prefix)), true
+		}
+	}
+
+	return "", false
+}
+
+// pending reminders of given user which match given keyword (all of them if it is empty)
+func matchingReminders(userID int64, keyword string) []dbhelper.QueueItem {
+	reminders := db.UndeliveredQueueItemsOfUser(userID)
+
+	if keyword != "" {
+		type scored struct {
+			reminder dbhelper.QueueItem
+			score    float64
+		}
+
+		matches := []scored{}
+		for _, r := range reminders {
+			score := textSimilarity(keyword, r.Message)
+			if strings.Contains(r.Message, keyword) {
+				score += 1 // (substrings come first while typing)
+			}
+			if score >= cancelMatchMinimum {
+				matches = append(matches, scored{reminder: r, score: score})
+			}
+		}
+		sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+		reminders = []dbhelper.QueueItem{}
+		for _, m := range matches {
+			reminders = append(reminders, m.reminder)
+		}
+	}
+
+	if len(reminders) > inlineQueryMaxResults {
+		reminders = reminders[:inlineQueryMaxResults]
+	}
+
+	return reminders
+}
+
+// process callback query from a message sent via inline mode (which has no chat of its own)
+func processInlineCallbackQuery(b *bot.Bot, query bot.CallbackQuery) bool {
+	message := messageError
+	if query.Data != nil && strings.HasPrefix(*query.Data, callbackInlineCancel) {
+		message = processInlineCancelCallback(query, *query.Data)
+	} else {
+		log.Printf("*** Unprocessable inline callback query: %+v", query)
+	}
+
+	answer, _ := trimText(message, telegramMaxCallbackAnswerLength)
+	if answered := b.AnswerCallbackQuery(query.ID, map[string]interface{}{"text": answer}); !answered.Ok {
+		log.Printf("*** Failed to answer callback query: %+v", query)
+		return false
+	}
+
+	if query.InlineMessageID == nil {
+		return false
+	}
+	if edited := b.EditMessageText(message, map[string]interface{}{
+		"inline_message_id": *query.InlineMessageID,
+	}); !edited.Ok {
+		log.Printf("*** Failed to edit inline message text: %s", *edited.Description)
+		return false
+	}
+
+	return true
+}
+
+// process callback query for canceling a reminder chosen with inline query
+func processInlineCancelCallback(query bot.CallbackQuery, txt string) string {
+	params := strings.Fields(strings.TrimPrefix(txt, callbackInlineCancel))
+	if len(params) != 2 {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+	chatID, err1 := strconv.ParseInt(params[0], 10, 64)
+	queueID, err2 := strconv.ParseInt(params[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+
+	q, exists := db.QueueItem(chatID, queueID)
+	if !exists || q.DeliveredOn.Unix() > 0 {
+		return messageInlineGone
+	}
+
+	// (the confirmation message can be pressed by anyone in the chat it was sent to)
+	userID := int64(query.From.ID)
+	if q.UserID != userID && q.ChatID != userID {
+		return messageInlineNotYours
+	}
+
+	if !db.DeleteQueueItem(chatID, queueID) {
+		return messageError
+	}
+	fireWebhooks(webhookEventCanceled, q, "")
+
+	return fmt.Sprintf(messageInlineCanceledFormat, reminderText(chatID, q.Message))
+}
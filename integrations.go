@@ -0,0 +1,555 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// integrations with task apps (Todoist, TickTick): chats connect their accounts with OAuth (/sync),
+// then reminders of the chats are mirrored to them as tasks, which are completed when the reminders are acknowledged
+// (and deleted when canceled)
+//
+// (access tokens are saved encrypted with token_key, and OAuth callbacks are served by the REST API server)
+
+const (
+	commandSync = "/sync"
+
+	syncParamOff = "off"
+
+	integrationTodoist  = "todoist"
+	integrationTickTick = "ticktick"
+
+	integrationTimeoutSeconds  = 10
+	integrationMaxBodyBytes    = 64 * 1024
+	integrationStateMinutes    = 10        // OAuth flows should be finished in this time
+	integrationStateLength     = 16        // in bytes
+	apiPathOAuthCallbackPrefix = "/oauth/" // + provider
+
+	todoistAuthorizeURL = "https://todoist.com/oauth/authorize"
+	todoistTokenURL     = "https://todoist.com/oauth/access_token"
+	todoistTasksURL     = "https://api.todoist.com/rest/v2/tasks"
+	todoistScope        = "data:read_write"
+
+	tickTickAuthorizeURL = "https://ticktick.com/oauth/authorize"
+	tickTickTokenURL     = "https://ticktick.com/oauth/token"
+	tickTickTaskURL      = "https://api.ticktick.com/open/v1/task"
+	tickTickProjectURL   = "https://api.ticktick.com/open/v1/project"
+	tickTickScope        = "tasks:write tasks:read"
+)
+
+// config for integrations with task apps
+type integrationsConfig struct {
+	RedirectBaseURL string          `json:"redirect_base_url"` // public url of the REST API server (eg. "https://bot.example.com"), for OAuth callbacks
+	TokenKey        string          `json:"token_key"`         // passphrase for encrypting access tokens in the database
+	Todoist         *oauthAppConfig `json:"todoist,omitempty"`
+	TickTick        *oauthAppConfig `json:"ticktick,omitempty"`
+}
+
+// config of an OAuth app
+type oauthAppConfig struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+// messages (can be overridden with messages.json)
+var (
+	messageSyncDisabled        = "연동할 수 있는 할 일 앱이 없습니다."
+	messageSyncTitle           = "알림을 할 일 앱에도 등록하고, 완료하면 함께 완료 처리합니다:"
+	messageSyncLineFormat      = "➤ %s: %s"
+	messageSyncConnected       = "연결됨"
+	messageSyncNotConnected    = "연결 안 됨"
+	messageSyncConnectFormat   = "%s 연결하기"
+	messageSyncDisconnectFmt   = "%s 연결 해제"
+	messageSyncDisconnected    = "연결을 해제했습니다."
+	messageSyncConnectedFormat = "%s 계정이 연결되었습니다. 이제 이 대화의 알림이 %s에도 등록됩니다."
+	messageSyncPageDone        = "연결되었습니다. 텔레그램으로 돌아가 주세요."
+	messageSyncPageFailed      = "연결하지 못했습니다. 텔레그램에서 /sync 로 다시 시도해 주세요."
+)
+
+var _integrationClient = &http.Client{Timeout: integrationTimeoutSeconds * time.Second}
+
+// OAuth flow waiting for its callback
+type oauthState struct {
+	chatID    int64
+	userID    int64
+	provider  string
+	expiresOn time.Time
+}
+
+var _oauthStates = struct {
+	sync.Mutex
+	states map[string]oauthState
+}{
+	states: map[string]oauthState{},
+}
+
+// decrypted access tokens (not to derive keys every time)
+var _integrationTokens = struct {
+	sync.Mutex
+	tokens map[string]string // key: encrypted token
+}{
+	tokens: map[string]string{},
+}
+
+// validate integrations config values
+func (c integrationsConfig) validate(apiServerPort int) (problems []string) {
+	if u, err := url.Parse(c.RedirectBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems = append(problems, fmt.Sprintf("redirect_base_url of integrations is malformed: %s", c.RedirectBaseURL))
+	}
+	if c.TokenKey == "" {
+		problems = append(problems, "token_key of integrations is empty")
+	}
+	if apiServerPort <= 0 {
+		problems = append(problems, "api_server_port is needed for integrations (for OAuth callbacks)")
+	}
+	for provider, app := range map[string]*oauthAppConfig{integrationTodoist: c.Todoist, integrationTickTick: c.TickTick} {
+		if app != nil && (app.ClientID == "" || app.ClientSecret == "") {
+			problems = append(problems, fmt.Sprintf("client_id and client_secret of %s should not be empty", provider))
+		}
+	}
+
+	return problems
+}
+
+// OAuth app of given provider (nil if not configured)
+func integrationApp(provider string) *oauthAppConfig {
+	if _conf.Integrations == nil {
+		return nil
+	}
+
+	switch provider {
+	case integrationTodoist:
+		return _conf.Integrations.Todoist
+	case integrationTickTick:
+		return _conf.Integrations.TickTick
+	}
+	return nil
+}
+
+// configured providers
+func integrationProviders() (providers []string) {
+	for _, provider := range []string{integrationTodoist, integrationTickTick} {
+		if integrationApp(provider) != nil {
+			providers = append(providers, provider)
+		}
+	}
+	return providers
+}
+
+// displayable name of given provider
+func integrationName(provider string) string {
+	switch provider {
+	case integrationTodoist:
+		return "Todoist"
+	case integrationTickTick:
+		return "TickTick"
+	}
+	return provider
+}
+
+// process /sync command: show integrations of the chat with buttons for connecting/disconnecting them
+func processSyncCommand(chatID, userID int64, options map[string]interface{}) string {
+	providers := integrationProviders()
+	if len(providers) <= 0 {
+		return messageSyncDisabled
+	}
+
+	connected := map[string]bool{}
+	for _, i := range db.Integrations(chatID) {
+		connected[i.Provider] = true
+	}
+
+	lines := []string{messageSyncTitle}
+	buttons := [][]bot.InlineKeyboardButton{}
+	for _, provider := range providers {
+		name := integrationName(provider)
+
+		if connected[provider] {
+			lines = append(lines, fmt.Sprintf(messageSyncLineFormat, name, messageSyncConnected))
+
+			data := fmt.Sprintf("%s %s %s", commandSync, syncParamOff, provider)
+			buttons = append(buttons, []bot.InlineKeyboardButton{
+				bot.InlineKeyboardButton{Text: fmt.Sprintf(messageSyncDisconnectFmt, name), CallbackData: &data},
+			})
+		} else {
+			lines = append(lines, fmt.Sprintf(messageSyncLineFormat, name, messageSyncNotConnected))
+
+			authURL, err := integrationAuthURL(chatID, userID, provider)
+			if err != nil {
+				log.Printf("*** failed to build authorization url of %s: %s", provider, err)
+				continue
+			}
+			buttons = append(buttons, []bot.InlineKeyboardButton{
+				bot.InlineKeyboardButton{Text: fmt.Sprintf(messageSyncConnectFormat, name), URL: &authURL},
+			})
+		}
+	}
+	options["reply_markup"] = bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+
+	return strings.Join(lines, "\n")
+}
+
+// process callback query for disconnecting an integration: '/sync off <provider>'
+func processSyncCallback(query bot.CallbackQuery, txt string) string {
+	params := strings.Fields(strings.TrimPrefix(txt, commandSync))
+	if len(params) != 2 || params[0] != syncParamOff {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+
+	if !db.DeleteIntegration(query.Message.Chat.ID, params[1]) {
+		return messageError
+	}
+
+	return messageSyncDisconnected
+}
+
+// url of the OAuth callback of given provider
+func integrationRedirectURL(provider string) string {
+	return strings.TrimSuffix(_conf.Integrations.RedirectBaseURL, "/") + apiPathOAuthCallbackPrefix + provider
+}
+
+// url for authorizing given provider (with a new state for given chat and user)
+func integrationAuthURL(chatID, userID int64, provider string) (string, error) {
+	random := make([]byte, integrationStateLength)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	state := hex.EncodeToString(random)
+
+	_oauthStates.Lock()
+	for s, pending := range _oauthStates.states {
+		if time.Now().After(pending.expiresOn) {
+			delete(_oauthStates.states, s)
+		}
+	}
+	_oauthStates.states[state] = oauthState{
+		chatID:    chatID,
+		userID:    userID,
+		provider:  provider,
+		expiresOn: time.Now().Add(integrationStateMinutes * time.Minute),
+	}
+	_oauthStates.Unlock()
+
+	app := integrationApp(provider)
+	params := url.Values{}
+	params.Set("client_id", app.ClientID)
+	params.Set("state", state)
+
+	switch provider {
+	case integrationTodoist:
+		params.Set("scope", todoistScope)
+		return todoistAuthorizeURL + "?" + params.Encode(), nil
+	case integrationTickTick:
+		params.Set("scope", tickTickScope)
+		params.Set("redirect_uri", integrationRedirectURL(provider))
+		params.Set("response_type", "code")
+		return tickTickAuthorizeURL + "?" + params.Encode(), nil
+	}
+	return "", fmt.Errorf("unknown provider: %s", provider)
+}
+
+// handle OAuth callbacks: exchange the code for an access token, and save it (encrypted) for the chat
+func handleOAuthCallback(w http.ResponseWriter, r *http.Request) {
+	provider := strings.TrimPrefix(r.URL.Path, apiPathOAuthCallbackPrefix)
+	state, code := r.URL.Query().Get("state"), r.URL.Query().Get("code")
+
+	_oauthStates.Lock()
+	pending, exists := _oauthStates.states[state]
+	delete(_oauthStates.states, state)
+	_oauthStates.Unlock()
+
+	if !exists || pending.provider != provider || time.Now().After(pending.expiresOn) || code == "" {
+		http.Error(w, messageSyncPageFailed, http.StatusBadRequest)
+		return
+	}
+
+	token, err := exchangeOAuthCode(provider, code)
+	if err == nil {
+		var encrypted string
+		if encrypted, err = encryptSecret(token, _conf.Integrations.TokenKey); err == nil {
+			if !db.SaveIntegration(dbhelper.Integration{
+				ChatID:   pending.chatID,
+				Provider: provider,
+				UserID:   pending.userID,
+				Token:    encrypted,
+			}) {
+				err = fmt.Errorf("failed to save integration")
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("*** failed to connect %s for chat %d: %s", provider, pending.chatID, err)
+
+		http.Error(w, messageSyncPageFailed, http.StatusInternalServerError)
+		return
+	}
+
+	name := integrationName(provider)
+	if sent := telegram.SendMessage(pending.chatID, fmt.Sprintf(messageSyncConnectedFormat, name, name), map[string]interface{}{}); !sent.Ok {
+		log.Printf("*** failed to notify connection of %s: %s", provider, *sent.Description)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, messageSyncPageDone)
+}
+
+// exchange given authorization code for an access token
+func exchangeOAuthCode(provider, code string) (string, error) {
+	app := integrationApp(provider)
+	if app == nil {
+		return "", fmt.Errorf("unknown provider: %s", provider)
+	}
+
+	form := url.Values{}
+	form.Set("code", code)
+
+	var req *http.Request
+	var err error
+	switch provider {
+	case integrationTodoist:
+		form.Set("client_id", app.ClientID)
+		form.Set("client_secret", app.ClientSecret)
+		req, err = http.NewRequest("POST", todoistTokenURL, strings.NewReader(form.Encode()))
+	case integrationTickTick:
+		form.Set("grant_type", "authorization_code")
+		form.Set("scope", tickTickScope)
+		form.Set("redirect_uri", integrationRedirectURL(provider))
+		if req, err = http.NewRequest("POST", tickTickTokenURL, strings.NewReader(form.Encode())); err == nil {
+			req.SetBasicAuth(app.ClientID, app.ClientSecret)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	body, err := doIntegrationRequest(req)
+	if err != nil {
+		return "", err
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil || token.AccessToken == "" {
+		return "", fmt.Errorf("no access token in response: %s", string(body))
+	}
+
+	return token.AccessToken, nil
+}
+
+// send given request, returning its response body
+func doIntegrationRequest(req *http.Request) ([]byte, error) {
+	resp, err := _integrationClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, integrationMaxBodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("http status: %s (%s)", resp.Status, string(body))
+	}
+
+	return body, nil
+}
+
+// send a request to the api of a task app with given access token (and json body)
+func integrationAPIRequest(method, endpoint, token string, body interface{}) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return doIntegrationRequest(req)
+}
+
+// decrypted access token of given integration
+func integrationToken(i dbhelper.Integration) (string, error) {
+	_integrationTokens.Lock()
+	defer _integrationTokens.Unlock()
+
+	if token, exists := _integrationTokens.tokens[i.Token]; exists {
+		return token, nil
+	}
+
+	token, err := decryptSecret(i.Token, _conf.Integrations.TokenKey)
+	if err != nil {
+		return "", err
+	}
+	_integrationTokens.tokens[i.Token] = token
+
+	return token, nil
+}
+
+// mirror given event of a queue item to the task apps connected to its chat (in background)
+func syncIntegrations(event string, q dbhelper.QueueItem) {
+	if _conf.Integrations == nil || q.Kind != dbhelper.QueueKindReminder || q.Broadcast {
+		return
+	}
+	if event != webhookEventCreated && event != webhookEventCanceled {
+		return
+	}
+
+	integrations := db.Integrations(q.ChatID)
+	if len(integrations) <= 0 {
+		return
+	}
+
+	go func() {
+		switch event {
+		case webhookEventCreated:
+			for _, i := range integrations {
+				if err := createIntegrationTask(i, q); err != nil {
+					log.Printf("*** failed to create %s task for queue item %d: %s", i.Provider, q.ID, err)
+				}
+			}
+		case webhookEventCanceled:
+			finishIntegrationTasks(q, false)
+		}
+	}()
+}
+
+// complete the tasks mirrored from given (acknowledged) queue item (in background)
+func completeIntegrationTasks(q dbhelper.QueueItem) {
+	if _conf.Integrations == nil {
+		return
+	}
+
+	go finishIntegrationTasks(q, true)
+}
+
+// create a task for given queue item in the task app of given integration
+func createIntegrationTask(i dbhelper.Integration, q dbhelper.QueueItem) error {
+	token, err := integrationToken(i)
+	if err != nil {
+		return err
+	}
+
+	var taskID string
+	switch i.Provider {
+	case integrationTodoist:
+		body, err := integrationAPIRequest("POST", todoistTasksURL, token, map[string]interface{}{
+			"content":      q.Message,
+			"due_datetime": q.FireOn.UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+
+		var task struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(body, &task); err != nil {
+			return err
+		}
+		taskID = task.ID
+	case integrationTickTick:
+		body, err := integrationAPIRequest("POST", tickTickTaskURL, token, map[string]interface{}{
+			"title":    q.Message,
+			"dueDate":  q.FireOn.Format("2006-01-02T15:04:05-0700"),
+			"timeZone": _location.String(),
+		})
+		if err != nil {
+			return err
+		}
+
+		var task struct {
+			ID        string `json:"id"`
+			ProjectID string `json:"projectId"`
+		}
+		if err := json.Unmarshal(body, &task); err != nil {
+			return err
+		}
+		taskID = task.ProjectID + "/" + task.ID // (tasks of TickTick are addressed with their projects)
+	default:
+		return fmt.Errorf("unknown provider: %s", i.Provider)
+	}
+
+	if !db.SaveIntegrationTask(q.ChatID, q.ID, i.Provider, taskID) {
+		return fmt.Errorf("failed to save task id: %s", taskID)
+	}
+
+	return nil
+}
+
+// complete (or delete) the tasks mirrored from given queue item
+func finishIntegrationTasks(q dbhelper.QueueItem, complete bool) {
+	tasks := db.IntegrationTasks(q.ChatID, q.ID)
+	if len(tasks) <= 0 {
+		return
+	}
+
+	integrations := map[string]dbhelper.Integration{}
+	for _, i := range db.Integrations(q.ChatID) {
+		integrations[i.Provider] = i
+	}
+
+	for _, t := range tasks {
+		i, exists := integrations[t.Provider]
+		if !exists {
+			continue // (disconnected)
+		}
+
+		token, err := integrationToken(i)
+		if err == nil {
+			switch {
+			case t.Provider == integrationTodoist && complete:
+				_, err = integrationAPIRequest("POST", fmt.Sprintf("%s/%s/close", todoistTasksURL, t.TaskID), token, nil)
+			case t.Provider == integrationTodoist:
+				_, err = integrationAPIRequest("DELETE", fmt.Sprintf("%s/%s", todoistTasksURL, t.TaskID), token, nil)
+			case t.Provider == integrationTickTick && complete:
+				_, err = integrationAPIRequest("POST", tickTickTaskPath(t.TaskID)+"/complete", token, nil)
+			case t.Provider == integrationTickTick:
+				_, err = integrationAPIRequest("DELETE", tickTickTaskPath(t.TaskID), token, nil)
+			}
+		}
+		if err != nil {
+			log.Printf("*** failed to finish %s task %s of queue item %d: %s", t.Provider, t.TaskID, q.ID, err)
+		}
+	}
+
+	db.DeleteIntegrationTasks(q.ChatID, q.ID)
+}
+
+// url of the TickTick task with given id ("<project id>/<task id>")
+func tickTickTaskPath(taskID string) string {
+	projectID, id := taskID, ""
+	if i := strings.Index(taskID, "/"); i >= 0 {
+		projectID, id = taskID[:i], taskID[i+1:]
+	}
+
+	return fmt.Sprintf("%s/%s/task/%s", tickTickProjectURL, projectID, id)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// integrity of the database: checked on startup before it is opened, and repaired if possible
+// (broken indexes are rebuilt, or the database is replaced with the newest intact backup);
+// admins are alerted with the results, and the bot exits when it could not be repaired
+// (instead of panicking in the middle of operations)
+
+const (
+	integrityMaxProblemsShown = 3
+
+	corruptedFileSuffixFormat = ".corrupted-20060102-150405"
+)
+
+// files of sqlite which belong to the database file (journals)
+var databaseFileSuffixes = []string{"-journal", "-wal", "-shm"}
+
+// messages (can be overridden with messages.json)
+var (
+	messageIntegrityReindexedFormat = "[관리자] DB의 인덱스가 손상되어 다시 만들었습니다: %s"
+	messageIntegrityRestoredFormat  = "[관리자] DB가 손상되어 백업(%s)으로 복구했습니다. 백업 이후의 변경 사항은 없어졌으며(대화 기록으로 알림을 되살리려면 rebuild 명령을 사용), 손상된 DB는 %s에 남겨 두었습니다: %s"
+	messageIntegrityFailedFormat    = "[관리자] DB가 손상되었지만 복구하지 못해 봇을 종료합니다: %s"
+	messageIntegrityOpenFailed      = "[관리자] DB를 열지 못해 봇을 종료합니다: %s"
+	messageIntegritySchemaFormat    = "[관리자] DB 스키마에 빠진 부분이 있어 새로 만듭니다: %s"
+)
+
+// check the integrity of the database file at given path, and repair it if needed
+//
+// (returns an alert for admins, empty if nothing was wrong)
+func checkDatabaseIntegrity(dbFilepath string) (alert string, ok bool) {
+	// (new database)
+	if _, err := os.Stat(dbFilepath); os.IsNotExist(err) {
+		return "", true
+	}
+
+	problems, err := dbhelper.CheckIntegrity(dbFilepath)
+	if err == nil && len(problems) <= 0 {
+		return "", true
+	}
+	detail := integrityDetail(problems, err)
+
+	log.Printf("*** database failed integrity check: %s", detail)
+
+	// broken indexes can be rebuilt
+	if err == nil {
+		if err := dbhelper.Reindex(dbFilepath); err != nil {
+			log.Printf("*** failed to reindex database: %s", err)
+		} else if problems, err := dbhelper.CheckIntegrity(dbFilepath); err == nil && len(problems) <= 0 {
+			return fmt.Sprintf(messageIntegrityReindexedFormat, detail), true
+		}
+	}
+
+	// or replaced with the newest intact backup
+	for _, backup := range backupFiles() {
+		if problems, err := dbhelper.CheckIntegrity(backup); err != nil || len(problems) > 0 {
+			log.Printf("*** backup %s is not intact either: %s", backup, integrityDetail(problems, err))
+			continue
+		}
+
+		kept, err := restoreDatabaseFrom(backup, dbFilepath)
+		if err != nil {
+			log.Printf("*** failed to restore database from backup %s: %s", backup, err)
+			break
+		}
+
+		return fmt.Sprintf(messageIntegrityRestoredFormat, filepath.Base(backup), kept, detail), true
+	}
+
+	return fmt.Sprintf(messageIntegrityFailedFormat, detail), false
+}
+
+// summary of given problems of integrity check
+func integrityDetail(problems []string, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	if len(problems) > integrityMaxProblemsShown {
+		return fmt.Sprintf("%s (+%d)", strings.Join(problems[:integrityMaxProblemsShown], "; "), len(problems)-integrityMaxProblemsShown)
+	}
+	return strings.Join(problems, "; ")
+}
+
+// replace the database file with given backup, keeping the current one (and its journals) with a suffix
+func restoreDatabaseFrom(backup, dbFilepath string) (kept string, err error) {
+	kept = dbFilepath + time.Now().Format(corruptedFileSuffixFormat)
+	if err = os.Rename(dbFilepath, kept); err != nil {
+		return "", err
+	}
+
+	// (journals should not be applied to the restored one)
+	for _, suffix := range databaseFileSuffixes {
+		if _, err := os.Stat(dbFilepath + suffix); err == nil {
+			if err := os.Rename(dbFilepath+suffix, kept+suffix); err != nil {
+				log.Printf("*** failed to keep %s: %s", dbFilepath+suffix, err)
+			}
+		}
+	}
+
+	if err = copyFile(backup, dbFilepath); err != nil {
+		os.Rename(kept, dbFilepath)
+		return "", err
+	}
+
+	return kept, nil
+}
+
+// copy file at given path to given destination
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(to, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// open the database at given path, after checking (and repairing) its integrity
+//
+// (admins are alerted with the results, and it exits when the database is not usable)
+func openDatabase(dbFilepath string) *dbhelper.Database {
+	alert, ok := checkDatabaseIntegrity(dbFilepath)
+	if alert != "" {
+		notifyAdmins(telegram, alert)
+	}
+	if !ok {
+		fmt.Fprintf(os.Stderr, "*** Database is corrupted and could not be repaired: %s\n", dbFilepath)
+		os.Exit(1)
+	}
+
+	// missing tables, columns, and indexes are created while opening it,
+	// so they should be checked before (except for a new database)
+	missing := []string{}
+	if _, err := os.Stat(dbFilepath); err == nil {
+		if missing, err = dbhelper.MissingSchema(dbFilepath); err != nil {
+			log.Printf("*** failed to check schema of database: %s", err)
+		} else if len(missing) > 0 {
+			log.Printf("*** missing in schema of database: %s", strings.Join(missing, ", "))
+
+			notifyAdmins(telegram, fmt.Sprintf(messageIntegritySchemaFormat, strings.Join(missing, ", ")))
+		}
+	}
+
+	var database *dbhelper.Database
+	if err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%v", r)
+			}
+		}()
+		database = dbhelper.OpenDb(dbFilepath)
+		return nil
+	}(); err != nil {
+		notifyAdmins(telegram, fmt.Sprintf(messageIntegrityOpenFailed, err))
+
+		fmt.Fprintf(os.Stderr, "*** Failed to open database: %s\n", err)
+		os.Exit(1)
+	}
+
+	if alert != "" {
+		database.LogError(alert)
+	}
+	if len(missing) > 0 {
+		database.LogError(fmt.Sprintf("missing in schema: %s", strings.Join(missing, ", ")))
+	}
+
+	return database
+}
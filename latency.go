@@ -0,0 +1,144 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	commandStats = "/stats"
+
+	latencyWarningCooldown = 1 * time.Hour // admins will not be warned again within this
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageStatsTitle             = "[관리자] 알림 발송 지연 (예약 시각 → 실제 발송):"
+	messageStatsLatencyFormat     = "➤ 최근 %s: %d건, p50 %s / p95 %s / p99 %s"
+	messageStatsNoDeliveries      = "➤ 최근 %s: 발송된 알림 없음"
+	messageStatsLastHour          = "1시간"
+	messageStatsLastDay           = "24시간"
+	messageLatencyWarningFormat   = "[관리자] 알림이 예약 시각보다 %s 늦게 발송되었습니다 (기준: %s, chat id: %d, queue id: %d)\n감시 주기(monitor_interval_seconds)나 전송 속도 제한을 확인해 주세요."
+	messageStatsQueriesFormat     = "➤ DB 쿼리: %d건, 평균 %s / 최대 %s"
+	messageStatsSlowQueriesFormat = "➤ 느린 쿼리: %d건, 잠금 대기(busy): %d건"
+)
+
+// last time admins were warned about latency
+var _latencyWarnedOn = struct {
+	sync.Mutex
+	time time.Time
+}{}
+
+func init() {
+	expvar.Publish("delivery_latency", expvar.Func(func() interface{} {
+		if db == nil {
+			return nil
+		}
+
+		p50, p95, p99 := latencyPercentiles(db.DeliveryLatencies(time.Now().Add(-24 * time.Hour)))
+		return map[string]int64{
+			"p50_ms": p50.Nanoseconds() / int64(time.Millisecond),
+			"p95_ms": p95.Nanoseconds() / int64(time.Millisecond),
+			"p99_ms": p99.Nanoseconds() / int64(time.Millisecond),
+		}
+	}))
+}
+
+// record the delivery latency of given queue item, and warn admins if it is over the threshold
+func recordDeliveryLatency(client *bot.Bot, q dbhelper.QueueItem) {
+	latency := time.Since(q.FireOn)
+	if latency < 0 {
+		latency = 0
+	}
+
+	db.SetQueueItemLatency(q.ChatID, q.ID, latency)
+
+	threshold := time.Duration(_conf.LatencyWarningSeconds) * time.Second
+	if threshold <= 0 || latency <= threshold {
+		return
+	}
+
+	_latencyWarnedOn.Lock()
+	warn := time.Since(_latencyWarnedOn.time) > latencyWarningCooldown
+	if warn {
+		_latencyWarnedOn.time = time.Now()
+	}
+	_latencyWarnedOn.Unlock()
+
+	if warn {
+		notifyAdmins(client, fmt.Sprintf(messageLatencyWarningFormat, latency.Round(time.Second), threshold, q.ChatID, q.ID))
+	}
+}
+
+// p50, p95, and p99 of given latencies
+func latencyPercentiles(latencies []time.Duration) (p50, p95, p99 time.Duration) {
+	if len(latencies) <= 0 {
+		return 0, 0, 0
+	}
+
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	percentile := func(p int) time.Duration {
+		index := (len(sorted)*p+99)/100 - 1 // (nearest-rank)
+		if index < 0 {
+			index = 0
+		}
+		return sorted[index]
+	}
+
+	return percentile(50), percentile(95), percentile(99)
+}
+
+// process /stats command: show delivery latencies to admins
+func processStatsCommand(userID int64) string {
+	if !isAdmin(userID) {
+		return messageAnnounceAdminOnly
+	}
+
+	lines := []string{messageStatsTitle}
+	for _, window := range []struct {
+		title    string
+		duration time.Duration
+	}{
+		{messageStatsLastHour, time.Hour},
+		{messageStatsLastDay, 24 * time.Hour},
+	} {
+		latencies := db.DeliveryLatencies(time.Now().Add(-window.duration))
+		if len(latencies) <= 0 {
+			lines = append(lines, fmt.Sprintf(messageStatsNoDeliveries, window.title))
+			continue
+		}
+
+		p50, p95, p99 := latencyPercentiles(latencies)
+		lines = append(lines, fmt.Sprintf(messageStatsLatencyFormat, window.title, len(latencies), formatLatency(p50), formatLatency(p95), formatLatency(p99)))
+	}
+
+	// database metrics
+	metrics := db.Metrics()
+	var average time.Duration
+	if metrics.NumQueries > 0 {
+		average = metrics.TotalDuration / time.Duration(metrics.NumQueries)
+	}
+	lines = append(lines, fmt.Sprintf(messageStatsQueriesFormat, metrics.NumQueries, formatLatency(average), formatLatency(metrics.MaxDuration)))
+	lines = append(lines, fmt.Sprintf(messageStatsSlowQueriesFormat, metrics.NumSlowQueries, metrics.NumLockWaits))
+
+	return strings.Join(lines, "\n")
+}
+
+// displayable latency
+func formatLatency(latency time.Duration) string {
+	if latency < time.Second {
+		return latency.Round(time.Millisecond).String()
+	}
+	return latency.Round(100 * time.Millisecond).String()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	llmDefaultEndpoint  = "https://api.openai.com/v1/chat/completions"
+	llmTimeoutSeconds   = 30
+	llmMaxTokens        = 300
+	llmDefaultModelName = "gpt-4o-mini"
+)
+
+// config for LLM backend (OpenAI-compatible chat completions API)
+type llmConfig struct {
+	Endpoint string `json:"endpoint,omitempty"` // (default: OpenAI)
+	APIKey   string `json:"api_key"`
+	Model    string `json:"model,omitempty"`
+}
+
+// request body of chat completions API
+type llmRequest struct {
+	Model     string       `json:"model"`
+	Messages  []llmMessage `json:"messages"`
+	MaxTokens int          `json:"max_tokens,omitempty"`
+}
+
+type llmMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// response body of chat completions API
+type llmResponse struct {
+	Choices []struct {
+		Message llmMessage `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+var _llmClient = &http.Client{Timeout: llmTimeoutSeconds * time.Second}
+
+// validate LLM config values
+func (c llmConfig) validate() (problems []string) {
+	if c.Endpoint != "" {
+		if u, err := url.Parse(c.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("endpoint of llm is malformed: %s", c.Endpoint))
+		}
+	}
+	if c.APIKey == "" {
+		problems = append(problems, "api_key of llm is empty")
+	}
+
+	return problems
+}
+
+// check if LLM backend is configured
+func isLLMEnabled() bool {
+	return _conf.LLM != nil
+}
+
+// get a completion of given prompts from the LLM backend
+func llmComplete(system, prompt string) (string, error) {
+	if !isLLMEnabled() {
+		return "", fmt.Errorf("llm is not configured")
+	}
+
+	endpoint, model := _conf.LLM.Endpoint, _conf.LLM.Model
+	if endpoint == "" {
+		endpoint = llmDefaultEndpoint
+	}
+	if model == "" {
+		model = llmDefaultModelName
+	}
+
+	body, _ := json.Marshal(llmRequest{
+		Model: model,
+		Messages: []llmMessage{
+			llmMessage{Role: "system", Content: system},
+			llmMessage{Role: "user", Content: prompt},
+		},
+		MaxTokens: llmMaxTokens,
+	})
+
+	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+_conf.LLM.APIKey)
+
+	resp, err := _llmClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var res llmResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", fmt.Errorf("failed to decode llm response (%s): %s", resp.Status, err)
+	}
+	if res.Error != nil {
+		return "", fmt.Errorf("llm error: %s", res.Error.Message)
+	}
+	if len(res.Choices) <= 0 {
+		return "", fmt.Errorf("llm returned no choices (%s)", resp.Status)
+	}
+
+	return res.Choices[0].Message.Content, nil
+}
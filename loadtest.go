@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// (benchmarks of the queue are in db/queue_bench_test.go: `go test -bench . ./db/`)
+
+const (
+	loadTestTimeoutSeconds  = 600
+	loadTestDBFilename      = "loadtest.sqlite"
+	loadTestDBOptions       = "?_sync=OFF&_journal_mode=MEMORY"
+	loadTestTelegramToken   = "0123456789:loadtest"
+	loadTestChatIDsToSpread = 1000
+)
+
+// transport which redirects all requests to the mock server
+type redirectTransport struct {
+	target *url.URL
+	base   http.RoundTripper
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	redirected := req.Clone(req.Context())
+	redirected.URL.Scheme = t.target.Scheme
+	redirected.URL.Host = t.target.Host
+	redirected.Host = t.target.Host
+
+	return t.base.RoundTrip(redirected)
+}
+
+// fill the queue with given number of reminders and measure how fast they are delivered to a mock Telegram server
+func runLoadTest(numItems int) {
+	dir, err := ioutil.TempDir("", "reminder-loadtest")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	_location, _ = time.LoadLocation("Local")
+
+	log.Printf("> Running load test with %d reminders...", numItems)
+
+	db = dbhelper.OpenDb(filepath.Join(dir, loadTestDBFilename) + loadTestDBOptions)
+	fillQueue(numItems, time.Now())
+
+	var numSent int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if strings.HasSuffix(r.URL.Path, "/sendMessage") {
+			sent := atomic.AddInt64(&numSent, 1)
+			fmt.Fprintf(w, `{"ok":true,"result":{"message_id":%d,"date":%d,"chat":{"id":1,"type":"private"}}}`, sent, time.Now().Unix())
+		} else {
+			fmt.Fprint(w, `{"ok":true,"result":true}`)
+		}
+	}))
+	defer server.Close()
+
+	// redirect requests to Telegram to the mock server
+	target, _ := url.Parse(server.URL)
+	http.DefaultTransport = redirectTransport{target: target, base: http.DefaultTransport}
+
+	client := bot.NewClient(loadTestTelegramToken)
+
+	started := time.Now()
+	processQueue(client)
+
+	// wait for all deliveries to finish
+	deadline := started.Add(loadTestTimeoutSeconds * time.Second)
+	for _varNumDelivered.Value()+_varNumDeliveryFailures.Value() < int64(numItems) ||
+		_varNumDeliveriesInFlight.Value() > 0 {
+		if time.Now().After(deadline) {
+			log.Printf("*** load test timed out with %d deliveries in flight", _varNumDeliveriesInFlight.Value())
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	elapsed := time.Since(started)
+
+	numDelivered := numItems - len(db.DeliverableQueueItems(_maxNumTries))
+
+	log.Printf("> Sent %d messages, %d reminders marked as delivered in %s (%.1f reminders/sec)",
+		atomic.LoadInt64(&numSent),
+		numDelivered,
+		elapsed,
+		float64(numDelivered)/elapsed.Seconds(),
+	)
+
+	dbhelper.CloseDb()
+}
+
+// fill the queue with given number of synthetic reminders
+func fillQueue(num int, fireOn time.Time) {
+	for i := 0; i < num; i++ {
+		if !db.Enqueue(int64(i%loadTestChatIDsToSpread), fmt.Sprintf("synthetic reminder #%d", i), fireOn) {
+			panic("failed to fill queue")
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+const (
+	lockFileSuffix = ".lock"
+)
+
+// lock file of the database, held while this process is running
+var _lockFile *os.File
+
+// lock given database file exclusively, not to let another instance poll the same queue and send reminders twice
+//
+// (advisory lock on a separate file, so it is released automatically when the process exits)
+func lockDatabaseFile(dbFilepath string) error {
+	lockFilepath := dbFilepath + lockFileSuffix
+
+	file, err := os.OpenFile(lockFilepath, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+
+		if err == syscall.EWOULDBLOCK {
+			return fmt.Errorf("another instance is already running with the database: %s (locked: %s)", dbFilepath, lockFilepath)
+		}
+		return err
+	}
+
+	// write pid for finding the running instance
+	if err := file.Truncate(0); err == nil {
+		fmt.Fprintf(file, "%d\n", os.Getpid())
+	}
+
+	_lockFile = file
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	callbackFullText = "/fulltext"
+
+	telegramMaxMessageLength        = 4096 // (in UTF-16 code units)
+	telegramMaxCallbackAnswerLength = 200
+	longTextTrimmedLength           = 3000 // (leaves room for greetings, sign-offs, and mentions)
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageFullText         = "전체 보기"
+	messageFullTextSent     = "전체 내용을 보냈습니다."
+	messageFullTextGone     = "이미 없어진 알림입니다."
+	messageTrimmedSuffix    = "…"
+	messageFullTextFilename = "reminder-%d.txt"
+)
+
+// trim given text to given length (in UTF-16 code units), preferably at a line break or space
+func trimText(text string, maxLength int) (trimmed string, wasTrimmed bool) {
+	encoded := utf16.Encode([]rune(text))
+	if len(encoded) <= maxLength {
+		return text, false
+	}
+
+	// (not to break surrogate pairs: cut before a high surrogate)
+	cut := maxLength
+	if cut > 0 && encoded[cut-1] >= 0xD800 && encoded[cut-1] < 0xDC00 {
+		cut--
+	}
+	trimmed = string(utf16.Decode(encoded[:cut]))
+
+	if i := strings.LastIndexAny(trimmed, "\n "); i > len(trimmed)*9/10 {
+		trimmed = trimmed[:i]
+	}
+
+	return trimmed, true
+}
+
+// trim given delivery message if it is too long for telegram, with a button for getting the full text
+//
+// (reserved: length of the texts which will be added to it later, eg. greetings, sign-offs, and mentions)
+func trimLongMessage(q dbhelper.QueueItem, message string, reserved int, options map[string]interface{}) string {
+	if utf16Len(message)+reserved <= telegramMaxMessageLength {
+		return message
+	}
+
+	length := longTextTrimmedLength
+	if available := telegramMaxMessageLength - reserved - utf16Len(messageTrimmedSuffix); available < length {
+		length = available
+	}
+	if length < 0 {
+		length = 0
+	}
+	trimmed, _ := trimText(message, length)
+
+	// (not for other kinds of items, as they have their own keyboards)
+	if q.Kind == dbhelper.QueueKindReminder && options["reply_markup"] == nil {
+		data := fmt.Sprintf("%s %d", callbackFullText, q.ID)
+		options["reply_markup"] = bot.InlineKeyboardMarkup{
+			InlineKeyboard: [][]bot.InlineKeyboardButton{
+				[]bot.InlineKeyboardButton{
+					bot.InlineKeyboardButton{
+						Text:         messageFullText,
+						CallbackData: &data,
+					},
+				},
+			},
+		}
+	}
+
+	return trimmed + messageTrimmedSuffix
+}
+
+// process callback query for the full text of a trimmed reminder:
+// send it as a reply (or a text file if it is still too long), and keep the trimmed one without the button
+func processFullTextCallback(b *bot.Bot, query bot.CallbackQuery, txt string) string {
+	chatID := query.Message.Chat.ID
+
+	queueID, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(txt, callbackFullText)), 10, 64)
+	if err != nil {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+	q, exists := db.QueueItem(chatID, queueID)
+	if !exists {
+		return messageFullTextGone
+	}
+
+	options := map[string]interface{}{
+		"reply_to_message_id": query.Message.MessageID,
+	}
+
+	var sent bot.APIResponseMessage
+	if utf16Len(q.Message) <= telegramMaxMessageLength {
+		sent = b.SendMessage(chatID, q.Message, options)
+	} else {
+		options["caption"] = fmt.Sprintf(messageFullTextFilename, q.ID)
+		sent = b.SendDocument(chatID, bot.InputFileFromBytes([]byte(q.Message)), options)
+	}
+	if !sent.Ok {
+		log.Printf("*** failed to send full text of queue item %d: %s", q.ID, *sent.Description)
+		return messageError
+	}
+
+	if query.Message.Text != nil {
+		return *query.Message.Text
+	}
+	return messageFullTextSent
+}
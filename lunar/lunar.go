@@ -0,0 +1,148 @@
+// Package lunar converts dates between the (Korean) lunar calendar and the solar calendar
+package lunar
+
+import (
+	"fmt"
+	"time"
+)
+
+// supported range of lunar years
+const (
+	MinYear = 1900
+	MaxYear = 2049
+)
+
+// lunar year data from 1900 to 2049
+//
+//	bits 0-3   : leap month of the year (0 if none)
+//	bits 4-15  : number of days of each month, from the 1st(bit 15) to the 12th(bit 4) (1: 30 days, 0: 29 days)
+//	bit 16     : number of days of the leap month (1: 30 days, 0: 29 days)
+//
+// (computed for UTC+8 and adjusted for KST where known (1997, 2023, 2027), but a few new moons near midnight
+// can still differ by a day from KASI tables)
+var lunarYears = []int{
+	0x04bd8, 0x04ae0, 0x0a570, 0x054d5, 0x0d260, 0x0d950, 0x16554, 0x056a0, 0x09ad0, 0x055d2, // 1900-1909
+	0x04ae0, 0x0a5b6, 0x0a4d0, 0x0d250, 0x1d255, 0x0b540, 0x0d6a0, 0x0ada2, 0x095b0, 0x14977, // 1910-1919
+	0x04970, 0x0a4b0, 0x0b4b5, 0x06a50, 0x06d40, 0x1ab54, 0x02b60, 0x09570, 0x052f2, 0x04970, // 1920-1929
+	0x06566, 0x0d4a0, 0x0ea50, 0x16a95, 0x05ad0, 0x02b60, 0x186e3, 0x092e0, 0x1c8d7, 0x0c950, // 1930-1939
+	0x0d4a0, 0x1d8a6, 0x0b550, 0x056a0, 0x1a5b4, 0x025d0, 0x092d0, 0x0d2b2, 0x0a950, 0x0b557, // 1940-1949
+	0x06ca0, 0x0b550, 0x15355, 0x04da0, 0x0a5b0, 0x14573, 0x052b0, 0x0a9a8, 0x0e950, 0x06aa0, // 1950-1959
+	0x0aea6, 0x0ab50, 0x04b60, 0x0aae4, 0x0a570, 0x05260, 0x0f263, 0x0d950, 0x05b57, 0x056a0, // 1960-1969
+	0x096d0, 0x04dd5, 0x04ad0, 0x0a4d0, 0x0d4d4, 0x0d250, 0x0d558, 0x0b540, 0x0b6a0, 0x195a6, // 1970-1979
+	0x095b0, 0x049b0, 0x0a974, 0x0a4b0, 0x0b27a, 0x06a50, 0x06d40, 0x0af46, 0x0ab60, 0x09570, // 1980-1989
+	0x04af5, 0x04970, 0x064b0, 0x074a3, 0x0ea50, 0x06b58, 0x05ad0, 0x02b60, 0x096d5, 0x092e0, // 1990-1999
+	0x0c960, 0x0d954, 0x0d4a0, 0x0da50, 0x07552, 0x056a0, 0x0abb7, 0x025d0, 0x092d0, 0x0cab5, // 2000-2009
+	0x0a950, 0x0b4a0, 0x0baa4, 0x0ad50, 0x055d9, 0x04ba0, 0x0a5b0, 0x15176, 0x052b0, 0x0a930, // 2010-2019
+	0x07954, 0x06aa0, 0x0ad50, 0x06b52, 0x04b60, 0x0a6e6, 0x0a4f0, 0x05260, 0x0ea65, 0x0d530, // 2020-2029
+	0x05aa0, 0x076a3, 0x096d0, 0x04afb, 0x04ad0, 0x0a4d0, 0x1d0b6, 0x0d250, 0x0d520, 0x0dd45, // 2030-2039
+	0x0b5a0, 0x056d0, 0x055b2, 0x049b0, 0x0a577, 0x0a4b0, 0x0aa50, 0x1b255, 0x06d20, 0x0ada0, // 2040-2049
+}
+
+// solar date of lunar 1900-01-01
+var baseDate = time.Date(1900, time.January, 31, 0, 0, 0, 0, time.UTC)
+
+// LeapMonth returns the leap month of given lunar year (0 if none)
+func LeapMonth(year int) int {
+	return lunarYears[year-MinYear] & 0xf
+}
+
+// DaysInMonth returns the number of days in given lunar month
+func DaysInMonth(year, month int, leap bool) int {
+	data := lunarYears[year-MinYear]
+
+	if leap {
+		if data&0x10000 != 0 {
+			return 30
+		}
+		return 29
+	}
+
+	if data&(0x10000>>uint(month)) != 0 {
+		return 30
+	}
+	return 29
+}
+
+// number of days in given lunar year
+func daysInYear(year int) (days int) {
+	for month := 1; month <= 12; month++ {
+		days += DaysInMonth(year, month, false)
+	}
+	if LeapMonth(year) > 0 {
+		days += DaysInMonth(year, LeapMonth(year), true)
+	}
+
+	return days
+}
+
+// ToSolar converts given lunar date to the solar date (in given location)
+func ToSolar(year, month, day int, leap bool, loc *time.Location) (time.Time, error) {
+	if year < MinYear || year > MaxYear {
+		return time.Time{}, fmt.Errorf("lunar year out of range (%d-%d): %d", MinYear, MaxYear, year)
+	}
+	if month < 1 || month > 12 {
+		return time.Time{}, fmt.Errorf("invalid lunar month: %d", month)
+	}
+	if leap && LeapMonth(year) != month {
+		return time.Time{}, fmt.Errorf("no leap month %d in lunar year %d", month, year)
+	}
+	if day < 1 || day > DaysInMonth(year, month, leap) {
+		return time.Time{}, fmt.Errorf("invalid day of lunar month %d/%d: %d", year, month, day)
+	}
+
+	offset := 0
+	for y := MinYear; y < year; y++ {
+		offset += daysInYear(y)
+	}
+	for m := 1; m < month; m++ {
+		offset += DaysInMonth(year, m, false)
+		if LeapMonth(year) == m {
+			offset += DaysInMonth(year, m, true)
+		}
+	}
+	if leap {
+		offset += DaysInMonth(year, month, false)
+	}
+	offset += day - 1
+
+	solar := baseDate.AddDate(0, 0, offset)
+
+	return time.Date(solar.Year(), solar.Month(), solar.Day(), 0, 0, 0, 0, loc), nil
+}
+
+// FromSolar converts the date of given time to the lunar date
+func FromSolar(t time.Time) (year, month, day int, leap bool, err error) {
+	offset := int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Sub(baseDate).Hours() / 24)
+	if offset < 0 {
+		return 0, 0, 0, false, fmt.Errorf("date out of range: %s", t.Format("2006-01-02"))
+	}
+
+	for year = MinYear; year <= MaxYear; year++ {
+		days := daysInYear(year)
+		if offset < days {
+			break
+		}
+		offset -= days
+	}
+	if year > MaxYear {
+		return 0, 0, 0, false, fmt.Errorf("date out of range: %s", t.Format("2006-01-02"))
+	}
+
+	for month = 1; month <= 12; month++ {
+		if days := DaysInMonth(year, month, false); offset < days {
+			return year, month, offset + 1, false, nil
+		} else {
+			offset -= days
+		}
+
+		if LeapMonth(year) == month {
+			if days := DaysInMonth(year, month, true); offset < days {
+				return year, month, offset + 1, true, nil
+			} else {
+				offset -= days
+			}
+		}
+	}
+
+	return 0, 0, 0, false, fmt.Errorf("failed to convert date: %s", t.Format("2006-01-02"))
+}
@@ -0,0 +1,109 @@
+package lunar
+
+import (
+	"testing"
+	"time"
+)
+
+// 설날 (1/1) and 추석 (8/15) of KASI
+var kasiHolidays = []struct {
+	year             int
+	seollal, chuseok string
+}{
+	{1997, "1997-02-08", "1997-09-16"}, // (new year a day later than in China)
+	{2000, "2000-02-05", "2000-09-12"},
+	{2010, "2010-02-14", "2010-09-22"},
+	{2012, "2012-01-23", "2012-09-30"}, // (leap 3rd month)
+	{2017, "2017-01-28", "2017-10-04"},
+	{2020, "2020-01-25", "2020-10-01"}, // (leap 4th month)
+	{2023, "2023-01-22", "2023-09-29"}, // (leap 2nd month)
+	{2024, "2024-02-10", "2024-09-17"},
+	{2025, "2025-01-29", "2025-10-06"}, // (leap 6th month)
+	{2026, "2026-02-17", "2026-09-25"},
+	{2027, "2027-02-07", "2027-09-15"}, // (new year a day later than in China)
+}
+
+func TestToSolar(t *testing.T) {
+	loc := time.FixedZone("KST", 9*60*60)
+
+	for _, test := range kasiHolidays {
+		for _, holiday := range []struct {
+			month, day int
+			expected   string
+		}{
+			{1, 1, test.seollal},
+			{8, 15, test.chuseok},
+		} {
+			if solar, err := ToSolar(test.year, holiday.month, holiday.day, false, loc); err != nil {
+				t.Errorf("lunar %d-%d-%d: %s", test.year, holiday.month, holiday.day, err)
+			} else if solar.Format("2006-01-02") != holiday.expected {
+				t.Errorf("lunar %d-%d-%d: expected %s, got %s", test.year, holiday.month, holiday.day, holiday.expected, solar.Format("2006-01-02"))
+			}
+		}
+	}
+
+	// leap months
+	for _, test := range []struct {
+		year, month, day int
+		expected         string
+	}{
+		{2020, 4, 1, "2020-05-23"},
+		{2023, 2, 1, "2023-03-22"},
+		{2023, 2, 29, "2023-04-19"},
+	} {
+		if solar, err := ToSolar(test.year, test.month, test.day, true, loc); err != nil {
+			t.Errorf("lunar %d-leap %d-%d: %s", test.year, test.month, test.day, err)
+		} else if solar.Format("2006-01-02") != test.expected {
+			t.Errorf("lunar %d-leap %d-%d: expected %s, got %s", test.year, test.month, test.day, test.expected, solar.Format("2006-01-02"))
+		}
+	}
+
+	// no such leap month
+	if _, err := ToSolar(2024, 2, 1, true, loc); err == nil {
+		t.Errorf("lunar 2024-leap 2-1 should not exist")
+	}
+}
+
+func TestFromSolar(t *testing.T) {
+	loc := time.FixedZone("KST", 9*60*60)
+
+	for _, test := range kasiHolidays {
+		for _, holiday := range []struct {
+			date       string
+			month, day int
+		}{
+			{test.seollal, 1, 1},
+			{test.chuseok, 8, 15},
+		} {
+			date, _ := time.ParseInLocation("2006-01-02", holiday.date, loc)
+			if year, month, day, leap, err := FromSolar(date); err != nil {
+				t.Errorf("solar %s: %s", holiday.date, err)
+			} else if year != test.year || month != holiday.month || day != holiday.day || leap {
+				t.Errorf("solar %s: expected %d-%d-%d, got %d-%d-%d (leap: %t)", holiday.date, test.year, holiday.month, holiday.day, year, month, day, leap)
+			}
+		}
+	}
+
+	// the day before 설날 is the last day of the previous lunar year
+	date := time.Date(1997, 2, 7, 12, 0, 0, 0, loc)
+	if year, month, day, _, err := FromSolar(date); err != nil || year != 1996 || month != 12 || day != 30 {
+		t.Errorf("solar 1997-02-07: expected 1996-12-30, got %d-%d-%d (%v)", year, month, day, err)
+	}
+
+	// leap months
+	for _, test := range []struct {
+		date             string
+		year, month, day int
+	}{
+		{"2020-05-23", 2020, 4, 1},
+		{"2023-03-22", 2023, 2, 1},
+		{"2023-04-19", 2023, 2, 29},
+	} {
+		date, _ := time.ParseInLocation("2006-01-02", test.date, loc)
+		if year, month, day, leap, err := FromSolar(date); err != nil {
+			t.Errorf("solar %s: %s", test.date, err)
+		} else if year != test.year || month != test.month || day != test.day || !leap {
+			t.Errorf("solar %s: expected %d-leap %d-%d, got %d-%d-%d (leap: %t)", test.date, test.year, test.month, test.day, year, month, day, leap)
+		}
+	}
+}
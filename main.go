@@ -1,14 +1,21 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
 	apiai "github.com/meinside/api.ai-go"
 	bot "github.com/meinside/telegram-bot-go"
 
@@ -19,12 +26,17 @@ import (
 const (
 	dbFilename     = "db.sqlite"
 	configFilename = "config.json"
+	backupDirname  = "backups"
 
 	commandStart         = "/start"
 	commandListReminders = "/list"
 	commandCancel        = "/cancel"
 	commandHelp          = "/help"
+	commandAPIKey        = "/apikey"
+)
 
+// messages (can be overridden with messages.json)
+var (
 	messageCancel           = "취소"
 	messageCommandCanceled  = "명령이 취소 되었습니다."
 	messageReminderCanceled = "알림이 취소 되었습니다."
@@ -41,11 +53,41 @@ const (
 * 사용 예:
 "내일 저녁 9시에 뉴스 보라고 보내줘"
 "12월 31일 오후 11시에 신년 타종행사 보라고 알려줘"
+"매일 아침 8시에 약 먹으라고 알려줘" (매일, 매주, 매달 반복)
+"끝나면 30분 뒤에 정리하라고 알려줘" (마지막 알림을 완료하면 이어서)
+"내일 점심 투표 올려줘: 김치찌개, 피자, 초밥"
+"회의 알림 지워줘" (비슷한 알림을 찾아서 취소)
 
 * 기타 명령어:
 /list : 예약된 알림 조회
-/cancel : 예약된 알림 취소
+/cancel : 예약된 알림 취소 (/cancel 2 : /list의 2번 알림 취소)
+(받은 알림에 "완료", "+1h", "반복 매주"로 답장하면 그 알림을 완료/다시 알림/반복으로 바꿈)
+/pause : 예약된 알림 일시중지
+/resume : 일시중지된 알림 다시 시작
+/move : 알림을 다른 대화(개인 대화, 그룹)로 옮기기
+/vacation : 휴가 기간 동안 알림 보류
+/onfail : 알림 발송 실패시 처리 방법 설정
+/settings : 채팅별 설정 (링크 미리보기, 완료 확인 질문, 인사말 등)
+/timer : 타이머 (예: /timer 10분 라면)
+/remind : cron 표현식으로 반복 알림 (예: /remind cron "0 9 * * 1-5" 출근 준비)
+/focus : 집중 시간 동안 알림 미루기 (예: /focus 2시간)
+/pomodoro : 뽀모도로 (25분 집중 / 5분 휴식 반복)
+/med : 복용약 알림 및 복용 기록
+/habit : 습관 기록 및 연속 기록 순위
+/feed : 블로그 등의 새 글 알림 (RSS/Atom)
+/watch : 웹 페이지가 바뀌면 알림
+/price : 환율, 코인, 주가가 기준을 넘으면 알림
+/countdowns : 명절, 공휴일 등 공개 일정의 D-day 알림 받기
+/topic : 토픽 구독 (/publish <토픽> 2006-01-02 15:04 내용 : 발행 권한이 있는 사용자가 구독자 모두에게 보내기)
+/dday : D-day 조회 ("수능 D-day 등록해줘 11월 14일"로 등록)
+/alias : 명령어 단축어 설정 (예: /alias ㄹ /list)
+/feedback : 잘못 알아들은 날짜 등 의견 보내기
 /help : 본 사용법 확인
+/apikey : REST API 키 발급
+/sync : 할 일 앱(Todoist, TickTick) 연동
+/phone : 발송 실패시 문자로 받을 번호 등록
+/push : 푸시 알림을 받는 기기 관리
+/event : 외부 이벤트(웹훅) 메시지 설정
 
 * 문의:
 https://github.com/meinside/telegram-bot-reminder-api.ai
@@ -67,22 +109,72 @@ var _monitorIntervalSeconds int
 var _telegramIntervalSeconds int
 var _restrictUsers bool
 var _allowedUserIds []string
+var _adminUserIds []int64
+var _dataDir string
+var _backupDir string
+var _paydayOfMonth int
 
 var _isVerbose bool
+var _botUserID int64
+
+// command line flags
+var _configFilepath = flag.String("config", configFilename, "path of the config file")
+var _dataDirFlag = flag.String("data-dir", "", "directory for the database, logs, and backups (overrides 'data_dir' of the config)")
+var _loadTestItems = flag.Int("loadtest", 0, "run a load test with given number of synthetic reminders, then exit")
 
 type config struct {
-	TelegramAPIToken        string   `json:"telegram_api_token"`
-	ApiaiAccessToken        string   `json:"apiai_access_token"`
-	MonitorIntervalSeconds  int      `json:"monitor_interval_seconds"`
-	TelegramIntervalSeconds int      `json:"telegram_interval_seconds"`
-	MaxNumTries             int      `json:"max_num_tries"`
-	RestrictUsers           bool     `json:"restrict_users,omitempty"`
-	AllowedUserIds          []string `json:"allowed_user_ids"`
-	IsVerbose               bool     `json:"is_verbose,omitempty"`
+	TelegramAPIToken           string              `json:"telegram_api_token"`
+	ApiaiAccessToken           string              `json:"apiai_access_token"`
+	MonitorIntervalSeconds     int                 `json:"monitor_interval_seconds"`
+	TelegramIntervalSeconds    int                 `json:"telegram_interval_seconds"`
+	MaxNumTries                int                 `json:"max_num_tries"`
+	RestrictUsers              bool                `json:"restrict_users,omitempty"`
+	AllowedUserIds             []string            `json:"allowed_user_ids"`
+	AdminUserIds               []int64             `json:"admin_user_ids,omitempty"`
+	APIServerPort              int                 `json:"api_server_port,omitempty"`
+	OTLPEndpoint               string              `json:"otlp_endpoint,omitempty"`
+	AdminServerAddr            string              `json:"admin_server_addr,omitempty"`
+	AdminServerToken           string              `json:"admin_server_token,omitempty"`
+	DataDir                    string              `json:"data_dir,omitempty"`
+	LogFilename                string              `json:"log_filename,omitempty"`
+	MessagesFilename           string              `json:"messages_filename,omitempty"`
+	BackupDir                  string              `json:"backup_dir,omitempty"`
+	BackupIntervalHours        int                 `json:"backup_interval_hours,omitempty"`
+	NumBackupsToKeep           int                 `json:"num_backups_to_keep,omitempty"`
+	PaydayOfMonth              int                 `json:"payday_of_month,omitempty"`
+	EscalationWebhookURL       string              `json:"escalation_webhook_url,omitempty"`
+	EscalationEmail            *emailConfig        `json:"escalation_email,omitempty"`
+	MQTT                       *mqttConfig         `json:"mqtt,omitempty"`
+	FeedIntervalMinutes        int                 `json:"feed_interval_minutes,omitempty"`
+	PriceAlertIntervalMinutes  int                 `json:"price_alert_interval_minutes,omitempty"`
+	LLM                        *llmConfig          `json:"llm,omitempty"`
+	PollWindowMinutes          int                 `json:"poll_window_minutes,omitempty"`
+	Webhooks                   []webhookConfig     `json:"webhooks,omitempty"`
+	TTS                        *ttsConfig          `json:"tts,omitempty"`
+	Integrations               *integrationsConfig `json:"integrations,omitempty"`                  // task apps (Todoist, TickTick) which reminders are mirrored to
+	Push                       *pushConfig         `json:"push,omitempty"`                          // pushes to companion apps (FCM, APNs)
+	SMS                        *smsConfig          `json:"sms,omitempty"`                           // SMS fallback of failed reminders (Twilio)
+	BlobStorage                *blobStorageConfig  `json:"blob_storage,omitempty"`                  // storage of attachments and exports (local directory or S3-compatible)
+	RemoteBackup               *remoteBackupConfig `json:"remote_backup,omitempty"`                 // encrypted copies of backups (S3-compatible or WebDAV)
+	Sanitization               *sanitizationConfig `json:"sanitization,omitempty"`                  // filtering of reminder texts (control characters, links, words, and length)
+	ContentRules               []contentRule       `json:"content_rules,omitempty"`                 // operator-defined rules for blocking or flagging reminder texts
+	Aliases                    map[string]string   `json:"aliases,omitempty"`                       // global aliases of commands (eg. {"취소": "/cancel"})
+	Redis                      *redisConfig        `json:"redis,omitempty"`                         // index due queue items in redis for near-real-time firing
+	CollisionPolicy            string              `json:"collision_policy,omitempty"`              // "merge" or "stagger" for reminders of a chat with the same fire time
+	CollisionSpacingSeconds    int                 `json:"collision_spacing_seconds,omitempty"`     // spacing of staggered reminders (default: 30)
+	QueueShards                int                 `json:"queue_shards,omitempty"`                  // number of worker loops for delivering queue items (0 for a goroutine per item)
+	ShardMessagesPerSecond     float64             `json:"shard_messages_per_second,omitempty"`     // rate limit of each shard (default: 25)
+	LatencyWarningSeconds      int                 `json:"latency_warning_seconds,omitempty"`       // admins are warned when a reminder is delivered later than this (0 for not warning)
+	SlowQueryMilliseconds      int                 `json:"slow_query_milliseconds,omitempty"`       // queries slower than this are logged (0 for not logging)
+	LowConfidenceScore         float64             `json:"low_confidence_score,omitempty"`          // queries scored lower than this by api.ai are saved as training data (0 for not saving)
+	DraftExpiryMinutes         int                 `json:"draft_expiry_minutes,omitempty"`          // unfinished reminders are dropped after this long (0 for not dropping)
+	DraftNudge                 bool                `json:"draft_nudge,omitempty"`                   // tell users when their unfinished reminders are dropped
+	BroadcastMessagesPerSecond float64             `json:"broadcast_messages_per_second,omitempty"` // rate limit of broadcasts, shared by all of them (default: 25)
+	IsVerbose                  bool                `json:"is_verbose,omitempty"`
 }
 
 func openConfig() (conf config, err error) {
-	file, err := ioutil.ReadFile(configFilename)
+	file, err := ioutil.ReadFile(*_configFilepath)
 	if err == nil {
 		err := json.Unmarshal(file, &conf)
 		if err == nil {
@@ -95,11 +187,27 @@ func openConfig() (conf config, err error) {
 	return config{}, err
 }
 
-func init() {
+// read config and initialize clients
+func setup() {
 	var err error
-	if _conf, err = openConfig(); err != nil {
+	if _conf, err = openConfig(); err != nil && os.IsNotExist(err) {
+		_conf, err = runFirstRunSetup(*_configFilepath)
+	}
+	if err == nil {
+		err = _conf.resolveSecrets()
+	}
+	if err != nil {
 		panic(err)
 	} else {
+		// validate config values before using them
+		if problems := _conf.validate(); len(problems) > 0 {
+			fmt.Fprintf(os.Stderr, "*** Found %d problem(s) in config file: %s\n", len(problems), *_configFilepath)
+			for _, problem := range problems {
+				fmt.Fprintf(os.Stderr, "  - %s\n", problem)
+			}
+			os.Exit(1)
+		}
+
 		if _conf.MonitorIntervalSeconds <= 0 {
 			_conf.MonitorIntervalSeconds = 10
 		}
@@ -117,6 +225,22 @@ func init() {
 
 		_restrictUsers = _conf.RestrictUsers
 		_allowedUserIds = _conf.AllowedUserIds
+		_adminUserIds = _conf.AdminUserIds
+
+		if _conf.PaydayOfMonth <= 0 {
+			_conf.PaydayOfMonth = 25
+		}
+		_paydayOfMonth = _conf.PaydayOfMonth
+
+		if _conf.FeedIntervalMinutes <= 0 {
+			_conf.FeedIntervalMinutes = 30
+		}
+		if _conf.PriceAlertIntervalMinutes <= 0 {
+			_conf.PriceAlertIntervalMinutes = priceAlertDefaultIntervalMinutes
+		}
+		if _conf.PollWindowMinutes <= 0 {
+			_conf.PollWindowMinutes = pollDefaultWindowMinutes
+		}
 
 		telegram = bot.NewClient(_conf.TelegramAPIToken)
 		telegram.Verbose = _conf.IsVerbose
@@ -124,33 +248,109 @@ func init() {
 		ai = apiai.NewClient(_conf.ApiaiAccessToken)
 		ai.Verbose = _conf.IsVerbose
 
-		db = dbhelper.OpenDb(dbFilename)
+		// data directory
+		_dataDir = dataDirOf(_conf)
+		if err := os.MkdirAll(_dataDir, 0700); err != nil {
+			panic(err)
+		}
+
+		// log file
+		if _conf.LogFilename != "" {
+			logFilepath := dataPath(_conf.LogFilename)
+			if err := os.MkdirAll(filepath.Dir(logFilepath), 0700); err != nil {
+				panic(err)
+			}
+			if file, err := os.OpenFile(logFilepath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600); err == nil {
+				log.SetOutput(io.MultiWriter(os.Stderr, file))
+			} else {
+				panic(err)
+			}
+		}
+
+		// backup directory
+		if _conf.BackupDir != "" {
+			_backupDir = dataPath(_conf.BackupDir)
+		} else {
+			_backupDir = dataPath(backupDirname)
+		}
+		if err := os.MkdirAll(_backupDir, 0700); err != nil {
+			panic(err)
+		}
+
+		// messages
+		if _conf.MessagesFilename != "" {
+			setupMessages(dataPath(_conf.MessagesFilename))
+		} else {
+			setupMessages(dataPath(messagesFilename))
+		}
+
+		// only one instance can use the database at a time
+		if err := lockDatabaseFile(dataPath(dbFilename)); err != nil {
+			fmt.Fprintf(os.Stderr, "*** Failed to lock database: %s\n", err)
+			os.Exit(1)
+		}
+
+		dbhelper.SetSlowQueryThreshold(time.Duration(_conf.SlowQueryMilliseconds) * time.Millisecond)
+		db = openDatabase(dataPath(dbFilename))
+
+		// revoke users who were allowed by removed entries of the config
+		if _restrictUsers {
+			db.RevokeUsersNotAllowedBy(_allowedUserIds)
+		}
 
 		_location, _ = time.LoadLocation("Local")
 		_isVerbose = _conf.IsVerbose
 	}
 }
 
-// check if given Telegram id is allowed or not
-func isAllowedID(id string) bool {
-	if _restrictUsers == false {
-		return true
+// data directory of given config (can be overridden with the command line flag)
+func dataDirOf(conf config) string {
+	if *_dataDirFlag != "" {
+		return *_dataDirFlag
+	}
+	if conf.DataDir != "" {
+		return conf.DataDir
 	}
+	return "."
+}
 
-	for _, v := range _allowedUserIds {
-		if v == id {
-			return true
-		}
+// returns given path relative to the data directory (if it is not an absolute path)
+func dataPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
 	}
 
-	return false
+	return filepath.Join(_dataDir, path)
+}
+
+// send given message to all admins
+func notifyAdmins(client *bot.Bot, message string) {
+	for _, id := range _adminUserIds {
+		// private chat id is the same as the user id
+		if sent := client.SendMessage(id, message, map[string]interface{}{}); !sent.Ok {
+			log.Printf("*** failed to notify admin %d: %s", id, *sent.Description)
+		}
+	}
 }
 
+// deliver queued reminders when their times come
+//
+// (Telegram's native scheduled messages (schedule_date) are available only for user accounts, not for bots,
+// and unknown parameters are silently ignored by the Bot API, so reminders are always kept in the internal queue)
 func monitorQueue(monitor *time.Ticker, client *bot.Bot) {
 	for {
 		select {
 		case <-monitor.C:
-			processQueue(client)
+			expireDrafts(client)
+
+			// (in background, not to delay deliveries)
+			go prefetchLinkSummaries()
+
+			if isRedisQueueEnabled() {
+				syncRedisQueue()
+			} else {
+				processQueue(client)
+			}
 		}
 	}
 }
@@ -162,41 +362,95 @@ func processQueue(client *bot.Bot) {
 		log.Printf("Checking queue: %d items...", len(queue))
 	}
 
+	dispatchQueueItems(client, queue)
+}
+
+// deliver given deliverable queue items (with shards if configured)
+func dispatchQueueItems(client *bot.Bot, queue []dbhelper.QueueItem) {
+	now := time.Now()
+	deliverable := []dbhelper.QueueItem{}
 	for _, q := range queue {
-		go func(q dbhelper.QueueItem) {
-			// send message
-			message := fmt.Sprintf("%s", q.Message)
-			options := map[string]interface{}{}
-			if sent := client.SendMessage(q.ChatID, message, options); !sent.Ok {
-				log.Printf("*** failed to send reminder: %s", *sent.Description)
-			} else {
-				// mark as delivered
-				if !db.MarkQueueItemAsDelivered(q.ChatID, q.ID) {
-					log.Printf("*** failed to mark chat id: %d, queue id: %d", q.ChatID, q.ID)
-				}
+		// check suppression windows (eg. vacations) of the chat
+		if !q.Broadcast {
+			if s, suppressed := db.ActiveSuppression(q.ChatID, now); suppressed && !bypassesSuppression(q, s) {
+				suppressQueueItem(q, s)
+				continue
 			}
+		}
 
-			// increase num tries
-			if !db.IncreaseNumTries(q.ChatID, q.ID) {
-				log.Printf("*** failed to increase num tries for chat id: %d, queue id: %d", q.ChatID, q.ID)
-			}
-		}(q)
+		// timers are delivered by themselves
+		if isTimerPending(q.ID) {
+			continue
+		}
+
+		// (still being delivered since the last check)
+		if isDelivering(q.ID) {
+			continue
+		}
+
+		deliverable = append(deliverable, q)
+	}
+
+	// deliver reminders deferred by focus sessions in their digests
+	deliverable = collectFocusDigests(deliverable)
+
+	// merge or stagger reminders with the same fire time
+	deliverable = avoidCollisions(deliverable)
+
+	sharded := []dbhelper.QueueItem{}
+	for _, q := range deliverable {
+		// (broadcasts take long, so they are not sent through shards)
+		if isSharded() && !q.Broadcast {
+			sharded = append(sharded, q)
+			continue
+		}
+
+		go deliverQueueItem(client, q)
+	}
+
+	if len(sharded) > 0 {
+		dispatchToShards(sharded)
 	}
 }
 
 func processUpdate(b *bot.Bot, update bot.Update, err error) {
 	if err == nil {
 		if update.HasMessage() {
-			username := *update.Message.From.Username
+			userID, username := saveUser(update.Message.From)
 
-			if !isAllowedID(username) {
-				log.Printf("*** Id not allowed: %s", username)
+			if !isAllowedUser(userID, username) {
+				log.Printf("*** Id not allowed: %d (%s)", userID, username)
 
 				return
 			}
 
 			chatID := update.Message.Chat.ID
 
+			db.SaveChat(chatID, update.Message.Chat.Type, groupTitle(update.Message.Chat))
+
+			if isServiceMessage(update.Message) {
+				processServiceMessage(b, update.Message)
+				return
+			}
+
+			// ignore non-text messages between members of groups (except captioned photos, for attachments)
+			if !update.Message.HasText() && !isCaptionedPhotoMessage(update.Message) && isGroupChatID(chatID) {
+				return
+			}
+
+			ctx, span := startSpan(context.Background(), spanNameUpdate, chatID, attribute.Int("update.id", update.UpdateID))
+			defer span.End()
+
+			if !checkAbuse(b, userID, username, chatID) {
+				return
+			}
+
+			// numbered answers in simple mode
+			simple := isSimpleMode(chatID)
+			if simple && processSimpleChoice(b, update.Message) {
+				return
+			}
+
 			// 'is typing...'
 			b.SendChatAction(chatID, bot.ChatActionTyping)
 
@@ -224,70 +478,203 @@ func processUpdate(b *bot.Bot, update bot.Update, err error) {
 				},
 			}
 
-			if update.Message.HasText() { // text
-				txt := *update.Message.Text
+			// photos with reminders in their captions
+			photoError := ""
+			if isPhotoMessage(update.Message) {
+				var caption string
+				if caption, photoError = processPhotoMessage(b, chatID, userID, update.Message); photoError == "" {
+					update.Message.Text = &caption
+				}
+			}
+
+			if photoError != "" {
+				message = photoError
+			} else if update.Message.HasText() { // text
+				txt := expandAlias(chatID, *update.Message.Text)
 
-				if strings.HasPrefix(txt, commandStart) { // /start
+				if feedback, isReply := feedbackRepliedTo(update.Message, userID); isReply { // admin's answer to a feedback
+					message = processFeedbackReply(b, feedback, txt)
+				} else if q, isReply := reminderRepliedTo(update.Message, txt); isReply { // command in reply to a delivered reminder
+					message = processReplyCommand(q, userID, txt)
+				} else if strings.HasPrefix(txt, commandStart) { // /start
 					message = messageUsage
 				} else if strings.HasPrefix(txt, commandListReminders) {
-					reminders := db.UndeliveredQueueItems(chatID)
+					reminders := listedReminders(chatID)
 					if len(reminders) > 0 {
-						for _, r := range reminders {
-							message += fmt.Sprintf("➤ %s (%s)\n", r.Message, r.FireOn.Format("2006.1.2 15:04"))
-						}
+						message = formatReminderList(reminders, time.Now(), db.ChatSettings(chatID))
+
+						message = offerMerges(message, reminders, userID, options)
+						addCloneButton(options)
 					} else {
 						message = messageNoReminders
 					}
 				} else if strings.HasPrefix(txt, commandCancel) {
 					reminders := db.UndeliveredQueueItems(chatID)
-					if len(reminders) > 0 {
+					if canceled, ok := cancelNthReminder(chatID, strings.TrimSpace(strings.TrimPrefix(txt, commandCancel))); ok {
+						message = canceled
+					} else if len(reminders) > 0 {
 						// inline keyboards
-						keys := make(map[string]string)
-						for _, r := range reminders {
-							keys[fmt.Sprintf("➤ %s (%s)", r.Message, r.FireOn.Format("2006.1.2 15:04"))] = fmt.Sprintf("%s %d", commandCancel, r.ID)
-						}
-						buttons := bot.NewInlineKeyboardButtonsAsRowsWithCallbackData(keys)
-
-						// add a button for canceling command
-						cancel := commandCancel
-						buttons = append(buttons, []bot.InlineKeyboardButton{
-							bot.InlineKeyboardButton{
-								Text:         messageCancel,
-								CallbackData: &cancel,
-							},
-						})
-
-						// options
-						options["reply_markup"] = bot.InlineKeyboardMarkup{
-							InlineKeyboard: buttons,
-						}
+						options["reply_markup"] = reminderSelectionKeyboard(reminders, commandCancel)
 
 						message = messageCancelWhat
 					} else {
 						message = messageNoReminders
 					}
+				} else if strings.HasPrefix(txt, commandPause) || txt == messagePause {
+					message = processPauseCommand(chatID, options)
+				} else if strings.HasPrefix(txt, commandResume) || txt == messageResume {
+					message = processResumeCommand(chatID, options)
+				} else if strings.HasPrefix(txt, commandMove) || txt == messageMove {
+					message = processMoveCommand(chatID, userID, options)
+				} else if strings.HasPrefix(txt, commandVacation) {
+					message = processVacationCommand(chatID, txt, options)
+				} else if strings.HasPrefix(txt, commandSettings) {
+					message = processSettingsCommand(b, chatID, userID, txt, options)
+				} else if strings.HasPrefix(txt, commandBusiness) {
+					message = processBusinessCommand(userID, options)
+				} else if strings.HasPrefix(txt, commandSync) {
+					message = processSyncCommand(chatID, userID, options)
+				} else if strings.HasPrefix(txt, commandPush) {
+					message = processPushCommand(chatID, update.Message.Chat.Type == "private", txt, options)
+				} else if strings.HasPrefix(txt, commandPhone) {
+					message = processPhoneCommand(chatID, userID, txt)
+				} else if strings.HasPrefix(txt, commandOnFail) {
+					message = processOnFailCommand(chatID, options)
+				} else if strings.HasPrefix(txt, commandHabit) {
+					message = processHabitCommand(chatID, userID, txt, options)
+				} else if strings.HasPrefix(txt, commandMedication) {
+					message = processMedicationCommand(chatID, userID, txt, options)
+				} else if strings.HasPrefix(txt, commandPomodoro) {
+					message = processPomodoroCommand(chatID, userID, txt, options)
+				} else if strings.HasPrefix(txt, commandFocus) {
+					message = processFocusCommand(chatID, userID, txt)
+				} else if strings.HasPrefix(txt, commandTimer) {
+					message = processTimerCommand(b, chatID, userID, txt, options)
+				} else if strings.HasPrefix(txt, commandRemind) {
+					message = processRemindCommand(chatID, userID, txt)
+				} else if isCloneTimeText(chatID, userID, txt) {
+					message = processCloneTimeText(chatID, userID, txt)
+				} else if isMisparseCorrectionText(chatID, userID) {
+					message = processMisparseCorrection(chatID, userID, txt)
+				} else if isFeedText(txt) {
+					message = processFeedText(chatID, txt, options)
+				} else if isPageWatchText(txt) {
+					message = processPageWatchText(chatID, txt, options)
+				} else if isPriceAlertText(txt) {
+					message = processPriceAlertText(chatID, userID, txt, options)
+				} else if isCancelText(txt) {
+					message = processCancelText(chatID, userID, txt, options)
+				} else if isChainText(txt) {
+					message = processChainText(chatID, userID, txt)
+				} else if isPublicEventText(txt) {
+					message = processPublicEventText(chatID, userID, txt, options)
+				} else if isDDayText(txt) {
+					message = processDDayText(chatID, userID, txt)
 				} else if strings.HasPrefix(txt, commandHelp) {
 					message = messageUsage
+				} else if strings.HasPrefix(txt, commandStats) {
+					message = processStatsCommand(userID)
+				} else if strings.HasPrefix(txt, commandAnnounce) {
+					message = processAnnounceCommand(chatID, userID, txt)
+				} else if strings.HasPrefix(txt, commandTopic) {
+					message = processTopicCommand(chatID, userID, txt, options)
+				} else if strings.HasPrefix(txt, commandPublish) {
+					message = processPublishCommand(chatID, userID, txt)
+				} else if strings.HasPrefix(txt, commandRaw) {
+					message = processRawCommand(chatID, userID, txt)
+				} else if strings.HasPrefix(txt, commandQueue) {
+					message = processQueueCommand(userID, txt, options)
+				} else if strings.HasPrefix(txt, commandDumpChat) {
+					message = processDumpChatCommand(b, chatID, userID, txt)
+				} else if strings.HasPrefix(txt, commandReplay) {
+					message = processReplayCommand(userID, txt)
+				} else if strings.HasPrefix(txt, commandTrainExport) {
+					message = processTrainExportCommand(b, chatID, userID, txt)
+				} else if strings.HasPrefix(txt, commandReview) {
+					message = processReviewCommand(userID, options)
+				} else if strings.HasPrefix(txt, commandEvent) {
+					message = processEventCommand(chatID, txt)
+				} else if strings.HasPrefix(txt, commandFeedback) {
+					message = processFeedbackCommand(b, chatID, userID, username, txt)
+				} else if strings.HasPrefix(txt, commandAlias) {
+					message = processAliasCommand(chatID, txt)
+				} else if strings.HasPrefix(txt, commandAPIKey) {
+					param := strings.TrimSpace(strings.TrimPrefix(txt, commandAPIKey))
+					message = processAPIKeyCommand(chatID, update.Message.Chat.Type == "private", param)
 				} else {
+					// keep poll options until the reminder is confirmed
+					if query, pollOptions, isPoll := parsePollText(txt); isPoll {
+						setPendingPoll(chatID, userID, pollOptions)
+						txt = query
+					}
+
+					// keep the delivery window until the reminder is confirmed
+					if query, length, isWindow := parseWindowText(txt); isWindow {
+						setPendingWindow(chatID, userID, length)
+						txt = query
+					}
+
+					// keep the recurrence until the reminder is confirmed
+					query, recurrence, isRecurring := parseRecurrenceText(txt)
+					if isRecurring {
+						setPendingRecurrence(chatID, userID, recurrence)
+						txt = query
+					}
+
 					// send query to api.ai
-					if response, err := ai.QueryText(apiai.QueryRequest{
-						Query:     []string{txt},
-						SessionId: sessionIDFor(chatID),
-						Language:  apiai.Korean,
-					}); err == nil {
+					_, querySpan := startSpan(ctx, spanNameQuery, chatID)
+					response, err := ai.QueryText(apiai.QueryRequest{
+						Query:         []string{txt},
+						SessionId:     sessionIDFor(chatID),
+						Language:      apiai.Korean,
+						ResetContexts: takeSessionReset(chatID), // (after its draft expired)
+					})
+					if err == nil {
 						if response.Status.ErrorType == apiai.Success {
+							endSpan(querySpan, true, "")
+
+							recordNLUSuccess(userID)
+							recordLowConfidenceQuery(chatID, userID, txt, response)
+							trackDraft(chatID, userID, txt, response)
+
 							if response.Result.ActionIncomplete {
 								message = response.Result.Fulfillment.Speech
 							} else {
-								message = processQueryResponse(chatID, response)
+								message = processQueryResponse(ctx, chatID, userID, response)
+							}
+							updatePendingRecurrence(chatID, userID, isRecurring, response.Result.Metadata.IntentName, response.Result.ActionIncomplete)
+
+							// bigger confirmation prompt in simple mode
+							if simple && response.Result.Metadata.IntentName == aihelper.IntentNameMessage && !response.Result.ActionIncomplete {
+								message = message + "\n\n" + messageSimpleConfirmQuestion
+							}
+
+							// buttons for choosing visibility (in groups) and reporting misparses on confirmations
+							if response.Result.Metadata.IntentName == aihelper.IntentNameMessage && !response.Result.ActionIncomplete {
+								addVisibilityButton(chatID, userID, options)
+								addMisparseButton(chatID, userID, txt, response, options)
 							}
 						} else {
+							endSpan(querySpan, false, string(response.Status.ErrorType))
+
 							message = fmt.Sprintf(messageAPIAIDetailedErrorFormat, response.Status.ErrorType, response.Status.ErrorDetails)
+
+							updatePendingRecurrence(chatID, userID, isRecurring, "", false)
+
+							if isUserCausedNLUError(string(response.Status.ErrorType)) {
+								recordNLUError(b, userID, username, chatID)
+							}
 						}
 					} else {
+						endSpan(querySpan, false, err.Error())
+
 						message = fmt.Sprintf(messageAPIAIErrorFormat, err)
+
+						updatePendingRecurrence(chatID, userID, isRecurring, "", false)
 					}
 				}
+			} else if update.Message.Document != nil { // export files of other apps
+				message = processImportFile(b, chatID, userID, *update.Message.Document, options)
 			} else {
 				message = messageTextNeeded
 			}
@@ -296,11 +683,25 @@ func processUpdate(b *bot.Bot, update bot.Update, err error) {
 			if len(message) <= 0 {
 				message = messageError
 			}
+			if simple {
+				if message == messageUsage {
+					message = messageUsageSimple
+				}
+				message = simplifyMessage(chatID, message, options)
+			}
 			if sent := b.SendMessage(chatID, message, options); !sent.Ok {
 				log.Printf("*** failed to send message: %s", *sent.Description)
 			}
 		} else if update.HasCallbackQuery() {
 			processCallbackQuery(b, update)
+		} else if update.InlineQuery != nil {
+			processInlineQuery(b, *update.InlineQuery)
+		} else if update.MyChatMember != nil {
+			processMyChatMember(b, update)
+		} else if update.BusinessConnection != nil {
+			processBusinessConnection(b, *update.BusinessConnection)
+		} else if update.BusinessMessage != nil {
+			processBusinessMessage(b, update.BusinessMessage)
 		}
 	} else {
 		log.Printf("*** error while receiving update (%s)", err.Error())
@@ -313,35 +714,25 @@ func processCallbackQuery(b *bot.Bot, update bot.Update) bool {
 	result := false
 
 	query := *update.CallbackQuery
-	txt := *query.Data
 
-	var message = messageError
-	if strings.HasPrefix(txt, commandCancel) {
-		if txt == commandCancel {
-			message = messageCommandCanceled
-		} else {
-			cancelParam := strings.TrimSpace(strings.Replace(txt, commandCancel, "", 1))
-			if queueID, err := strconv.Atoi(cancelParam); err == nil {
-				if db.DeleteQueueItem(query.Message.Chat.ID, int64(queueID)) {
-					message = messageReminderCanceled
-				} else {
-					log.Printf("*** Failed to delete reminder")
-				}
-			} else {
-				log.Printf("*** Unprocessable callback query: %s", txt)
-			}
-		}
-	} else {
-		log.Printf("*** Unprocessable callback query: %s", txt)
+	// (messages sent via inline mode have no chats of their own)
+	if query.Message == nil {
+		return processInlineCallbackQuery(b, query)
 	}
 
+	message, keyboard := dispatchCallback(b, query, *query.Data)
+
 	// answer callback query
-	if apiResult := b.AnswerCallbackQuery(query.ID, map[string]interface{}{"text": message}); apiResult.Ok {
+	answer, _ := trimText(message, telegramMaxCallbackAnswerLength)
+	if apiResult := b.AnswerCallbackQuery(query.ID, map[string]interface{}{"text": answer}); apiResult.Ok {
 		// edit message and remove inline keyboards
 		options := map[string]interface{}{
 			"chat_id":    query.Message.Chat.ID,
 			"message_id": query.Message.MessageID,
 		}
+		if keyboard != nil {
+			options["reply_markup"] = keyboard
+		}
 		if apiResult := b.EditMessageText(message, options); apiResult.Ok {
 			result = true
 		} else {
@@ -358,38 +749,180 @@ func processCallbackQuery(b *bot.Bot, update bot.Update) bool {
 	return result
 }
 
+// process given callback data of a query, returning the resulting message
+// (and a keyboard for callbacks with following steps)
+func dispatchCallback(b *bot.Bot, query bot.CallbackQuery, txt string) (message string, keyboard interface{}) {
+	message = messageError
+	if strings.HasPrefix(txt, commandCancel) {
+		if txt == commandCancel {
+			message = messageCommandCanceled
+		} else {
+			cancelParam := strings.TrimSpace(strings.Replace(txt, commandCancel, "", 1))
+			if queueID, err := strconv.Atoi(cancelParam); err == nil {
+				q, exists := db.QueueItem(query.Message.Chat.ID, int64(queueID))
+				if db.DeleteQueueItem(query.Message.Chat.ID, int64(queueID)) {
+					// (with the text of the reminder, for rebuilding reminders from chat exports)
+					if exists {
+						message = fmt.Sprintf(messageCanceledFormat, reminderText(q.ChatID, q.Message))
+					} else {
+						message = messageReminderCanceled
+					}
+
+					fireWebhooks(webhookEventCanceled, q, "")
+				} else {
+					log.Printf("*** Failed to delete reminder")
+				}
+			} else {
+				log.Printf("*** Unprocessable callback query: %s", txt)
+			}
+		}
+	} else if strings.HasPrefix(txt, commandPause) {
+		message = processPauseCallback(query, txt)
+	} else if strings.HasPrefix(txt, commandResume) {
+		message = processResumeCallback(query, txt)
+	} else if strings.HasPrefix(txt, commandVacation) {
+		message = processVacationCallback(query, txt)
+	} else if strings.HasPrefix(txt, callbackRecur) {
+		message = processRecurCallback(query, txt)
+	} else if strings.HasPrefix(txt, callbackTransfer) {
+		message = processTransferCallback(query, txt)
+	} else if strings.HasPrefix(txt, commandOnFail) {
+		message, keyboard = processOnFailCallback(query, txt)
+	} else if isFeedCommand(txt) {
+		message = processFeedCallback(query, txt)
+	} else if strings.HasPrefix(txt, commandWatch) {
+		message = processPageWatchCallback(query, txt)
+	} else if strings.HasPrefix(txt, commandPrice) {
+		message = processPriceAlertCallback(query, txt)
+	} else if strings.HasPrefix(txt, commandCountdowns) {
+		message, keyboard = processPublicEventCallback(query, txt)
+	} else if strings.HasPrefix(txt, commandTopic) {
+		message, keyboard = processTopicCallback(query, txt)
+	} else if strings.HasPrefix(txt, commandTimer) {
+		message, keyboard = processTimerCallback(b, query, txt)
+	} else if strings.HasPrefix(txt, commandPomodoro) {
+		message, keyboard = processPomodoroCallback(query, txt)
+	} else if strings.HasPrefix(txt, commandMedication) {
+		message = processMedicationCallback(query, txt)
+	} else if strings.HasPrefix(txt, commandHabit) {
+		message, keyboard = processHabitCallback(query, txt)
+	} else if strings.HasPrefix(txt, commandMove) {
+		message, keyboard = processMoveCallback(b, query, txt)
+	} else if strings.HasPrefix(txt, commandSettings) {
+		message, keyboard = processSettingsCallback(query, txt)
+	} else if strings.HasPrefix(txt, callbackFollowUp) {
+		message, keyboard = processFollowUpCallback(query, txt)
+	} else if strings.HasPrefix(txt, callbackAck) {
+		message = processAckCallback(query, txt)
+	} else if strings.HasPrefix(txt, commandQueue) {
+		message = processQueueCallback(query, txt)
+	} else if strings.HasPrefix(txt, commandReview) {
+		message = processReviewCallback(query, txt)
+	} else if strings.HasPrefix(txt, callbackMerge) {
+		message = processMergeCallback(query, txt)
+	} else if strings.HasPrefix(txt, callbackFullText) {
+		message = processFullTextCallback(b, query, txt)
+	} else if strings.HasPrefix(txt, callbackCategory) {
+		message = processCategoryCallback(b, query, txt)
+	} else if strings.HasPrefix(txt, callbackMisparse) {
+		message = processMisparseCallback(query)
+	} else if strings.HasPrefix(txt, callbackVisibility) {
+		message, keyboard = processVisibilityCallback(query, txt)
+	} else if strings.HasPrefix(txt, commandBusiness) {
+		message, keyboard = processBusinessCallback(query, txt)
+	} else if strings.HasPrefix(txt, callbackClone) {
+		message, keyboard = processCloneCallback(query, txt)
+	} else if strings.HasPrefix(txt, callbackImport) {
+		message = processImportCallback(query, txt)
+	} else if strings.HasPrefix(txt, commandSync) {
+		message = processSyncCallback(query, txt)
+	} else if strings.HasPrefix(txt, commandPush) {
+		message, keyboard = processPushCallback(query, txt)
+	} else {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+	}
+
+	return message, keyboard
+}
+
 func sessionIDFor(chatID int64) string {
 	return fmt.Sprintf("ss_%d", chatID)
 }
 
-func processQueryResponse(chatID int64, response apiai.QueryResponse) string {
+func processQueryResponse(ctx context.Context, chatID, userID int64, response apiai.QueryResponse) string {
+	// let registered hooks correct the parameters
+	proceed := runNLUHooks(chatID, userID, &response)
+
 	var message = response.Result.Fulfillment.Speech
+	if !proceed {
+		return message
+	}
 
 	// if confirmed yes,
 	if response.Result.Metadata.IntentName == aihelper.IntentNameMessageConfirmedYes {
 		params := response.Result.Parameters
 
-		// check params
+		// check params (date is optional with anchor or time only)
 		if msg, ok := params["message"]; ok {
-			if dt, ok := params["date"]; ok {
-				if tm, ok := params["time"]; ok {
-					// parse date & time
-					if when, err := time.ParseInLocation(
-						"2006-01-02 15:04:05",
-						fmt.Sprintf("%s %s", dt, tm),
-						_location,
-					); err == nil {
-						if when.Unix() >= time.Now().Unix() {
-							// save it to DB
-							if !db.Enqueue(chatID, msg.(string), when) {
-								message = messageSaveFailed
-							}
-						} else {
-							message = when.Format(messageTimeIsPastFormat)
+			sanitized, err := sanitizeReminderText(chatID, fmt.Sprintf("%s", msg))
+			if err != nil {
+				return err.Error()
+			}
+			msg = sanitized
+
+			if tm, ok := params["time"]; ok {
+				dt, _ := params["date"].(string)
+				anchor, _ := params["anchor"].(string)
+				if recurrence, exists := takePendingRecurrence(chatID, userID); exists && anchor == "" {
+					anchor = recurrence
+				}
+
+				// parse date & time (with anchor)
+				if when, recurrence, err := resolveSchedule(dt, fmt.Sprintf("%s", tm), anchor); err == nil {
+					// round it with the preference of the chat
+					requested := when
+					if when.Unix() >= time.Now().Unix() {
+						when = roundTime(chatID, when)
+					}
+
+					// pick a time in the delivery window (if requested)
+					when = fitInWindow(chatID, userID, when)
+
+					if when.Unix() >= time.Now().Unix() {
+						// save it to DB
+						_, enqueueSpan := startSpan(ctx, spanNameEnqueue, chatID)
+						item := dbhelper.QueueItem{
+							ChatID:     chatID,
+							UserID:     userID,
+							Message:    msg.(string),
+							FireOn:     when,
+							Recurrence: recurrence,
+							Assignee:   assigneeFromMessage(msg.(string)),
+						}
+						attachPendingPoll(&item)
+						attachPendingAttachment(&item)
+						item.Private = takePendingVisibility(chatID, userID)
+						queueID, enqueued := db.EnqueueItem(item)
+						endSpan(enqueueSpan, enqueued, messageSaveFailed)
+
+						if enqueued {
+							item.ID = queueID
+							fireWebhooks(webhookEventCreated, item, "")
+							flagQueueItemIfNeeded(item)
+						}
+
+						if !enqueued {
+							message = messageSaveFailed
+						} else if isSimpleMode(chatID) {
+							message = simpleConfirmationMessage(chatID, msg.(string), when, recurrence)
+						} else if recurrence != "" || dt == "" || anchor != "" || !when.Equal(requested) {
+							message = confirmationMessage(chatID, msg.(string), when, recurrence)
 						}
 					} else {
-						message = messageTimeParseError
+						message = formatTimeFor(chatID, when, messageTimeIsPastFormat)
 					}
+				} else {
+					message = messageTimeParseError
 				}
 			}
 		}
@@ -399,17 +932,125 @@ func processQueryResponse(chatID int64, response apiai.QueryResponse) string {
 }
 
 func main() {
+	flag.Parse()
+
+	if *_encryptFlag {
+		runEncrypt()
+		return
+	}
+
+	if *_loadTestItems > 0 {
+		runLoadTest(*_loadTestItems)
+		return
+	}
+
+	if flag.Arg(0) == commandMigrate {
+		runMigrate(flag.Args()[1:])
+		return
+	}
+
+	if flag.Arg(0) == subcommandReplay {
+		runReplay(flag.Args()[1:])
+		return
+	}
+
+	if flag.Arg(0) == commandDoctor {
+		runDoctor()
+		return
+	}
+
+	if flag.Arg(0) == subcommandRestore {
+		runRestore(flag.Args()[1:])
+		return
+	}
+
+	if flag.Arg(0) == subcommandRebuild {
+		runRebuild(flag.Args()[1:])
+		return
+	}
+
+	setup()
+
 	// get info about this bot
 	if me := telegram.GetMe(); me.Ok {
+		_botUserID = int64(me.Result.ID)
+
 		// delete webhook (getting updates will not work when wehbook is set up)
 		if unhooked := telegram.DeleteWebhook(); unhooked.Ok {
+			// setup tracing
+			if _conf.OTLPEndpoint != "" {
+				if err := setupTracing(_conf.OTLPEndpoint); err != nil {
+					log.Printf("*** failed to setup tracing: %s", err)
+				}
+			}
+
 			// monitor queue
+			if _conf.QueueShards > 0 {
+				startQueueShards(telegram, _conf.QueueShards, _conf.ShardMessagesPerSecond)
+			}
+			if _conf.Redis != nil {
+				if err := startRedisQueue(*_conf.Redis, telegram); err != nil {
+					log.Printf("*** failed to start redis queue, falling back to the database: %s", err)
+				}
+			}
+			resumeBroadcasts(telegram)
+
+			// setup pushes to companion apps
+			if _conf.Push != nil {
+				setupNotifiers(*_conf.Push)
+			}
+
+			// setup storage of attachments and exports
+			setupBlobStore(_conf.BlobStorage)
+
 			log.Printf("> Starting monitoring queue...")
 			go monitorQueue(
 				time.NewTicker(time.Duration(_monitorIntervalSeconds)*time.Second),
 				telegram,
 			)
 
+			// start REST API server
+			if _conf.APIServerPort > 0 {
+				go startAPIServer(_conf.APIServerPort)
+			}
+
+			// backup database periodically
+			if _conf.BackupIntervalHours > 0 {
+				go monitorBackup(time.NewTicker(time.Duration(_conf.BackupIntervalHours) * time.Hour))
+			}
+
+			// start admin server
+			if _conf.AdminServerAddr != "" {
+				go startAdminServer(_conf.AdminServerAddr, _conf.AdminServerToken)
+			}
+
+			// check feeds periodically
+			log.Printf("> Starting monitoring feeds...")
+			go monitorFeeds(
+				time.NewTicker(time.Duration(_conf.FeedIntervalMinutes)*time.Minute),
+				telegram,
+			)
+
+			// check watched pages periodically
+			go monitorPageWatches(
+				time.NewTicker(pageWatchCheckIntervalSeconds*time.Second),
+				telegram,
+			)
+
+			// check prices of price alerts periodically
+			go monitorPriceAlerts(time.NewTicker(time.Duration(_conf.PriceAlertIntervalMinutes) * time.Minute))
+
+			// fan out countdowns of public events
+			go monitorPublicEvents(time.NewTicker(publicEventCheckIntervalSeconds * time.Second))
+
+			// collect garbage blobs
+			go monitorBlobs(time.NewTicker(blobGCIntervalMinutes * time.Minute))
+
+			// connect to mqtt broker
+			if _conf.MQTT != nil {
+				startMQTT(*_conf.MQTT)
+			}
+
 			// setup api.ai agent
 			log.Printf("> Setting up agent...")
 			aihelper.SetupAgent(ai, db)
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	commandMedication = "/med"
+
+	medicationParamTaken  = "taken"
+	medicationParamDelete = "del"
+	medicationParamReport = "기록"
+
+	medicationNagIntervalMinutes = 15 // nag again after this while a dose is not taken
+	medicationNagMaxMinutes      = 90 // stop nagging after this from the due time
+	medicationReportDays         = 7
+	medicationReportHour         = 21
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageMedicationUsage = `사용법:
+/med 혈압약 08:00,20:00 : 매일 08시, 20시에 복용 알림
+/med 감기약 09:00,13:00,19:00 5일 : 5일 동안만 복용 알림
+/med 기록 : 최근 7일 복용 기록
+/med : 등록된 약 조회 및 삭제`
+	messageMedicationDoseFormat       = "💊 %s 드실 시간입니다."
+	messageMedicationNagFormat        = "💊 %s 아직 안 드셨나요? (%s 복용분)"
+	messageMedicationTaken            = "💊 복용 완료"
+	messageMedicationTakenFormat      = "💊 %s 복용 완료! (%s)"
+	messageMedicationAlreadyTaken     = "이미 복용 완료한 약입니다."
+	messageMedicationSavedFormat      = "💊 %s: 매일 %s에 알려드리겠습니다.%s"
+	messageMedicationUntilFormat      = " (%s까지)"
+	messageMedicationNone             = "등록된 약이 없습니다."
+	messageMedicationDeleteWhat       = "삭제할 약을 선택해 주세요."
+	messageMedicationDeleted          = "약이 삭제 되었습니다."
+	messageMedicationListItemFormat   = "➤ %s (%s)"
+	messageMedicationInvalidTimes     = "복용 시각이 올바르지 않습니다. (예: 08:00,20:00)"
+	messageMedicationReportTitle      = "💊 최근 7일 복용 기록:"
+	messageMedicationReportLineFormat = "➤ %s: %d/%d회 (%d%%)"
+	messageMedicationReportNone       = "아직 복용 기록이 없습니다."
+)
+
+var (
+	medicationTimeRegex = regexp.MustCompile(`^([01]?\d|2[0-3]):([0-5]\d)$`)
+	medicationDaysRegex = regexp.MustCompile(`^(\d+)일$`)
+)
+
+// process /med command
+func processMedicationCommand(chatID, userID int64, txt string, options map[string]interface{}) string {
+	params := strings.Fields(strings.TrimPrefix(txt, commandMedication))
+
+	if len(params) == 0 {
+		return listMedications(chatID, options)
+	}
+	if len(params) == 1 && params[0] == medicationParamReport {
+		return medicationReport(chatID)
+	}
+	if len(params) < 2 {
+		return messageMedicationUsage
+	}
+
+	m := dbhelper.Medication{
+		ChatID: chatID,
+		UserID: userID,
+		Name:   params[0],
+	}
+	for _, param := range params[1:] {
+		if matches := medicationDaysRegex.FindStringSubmatch(param); matches != nil {
+			days, _ := strconv.Atoi(matches[1])
+			m.EndsOn = truncateToDay(time.Now()).AddDate(0, 0, days)
+			continue
+		}
+
+		for _, t := range strings.Split(param, ",") {
+			if t == "" {
+				continue
+			}
+			if !medicationTimeRegex.MatchString(t) {
+				return messageMedicationInvalidTimes
+			}
+			m.Times = append(m.Times, t)
+		}
+	}
+	if len(m.Times) <= 0 {
+		return messageMedicationInvalidTimes
+	}
+
+	medicationID, saved := db.SaveMedication(m)
+	if !saved {
+		return messageSaveFailed
+	}
+
+	// dose times are daily recurring reminders
+	for _, t := range m.Times {
+		matches := medicationTimeRegex.FindStringSubmatch(t)
+		hour, _ := strconv.Atoi(matches[1])
+		minute, _ := strconv.Atoi(matches[2])
+
+		fireOn := nextTimeOfDay(hour, minute)
+		if !m.EndsOn.IsZero() && fireOn.After(m.EndsOn) {
+			continue
+		}
+
+		db.EnqueueItem(dbhelper.QueueItem{
+			ChatID:     chatID,
+			UserID:     userID,
+			Message:    fmt.Sprintf(messageMedicationDoseFormat, m.Name),
+			FireOn:     fireOn,
+			Recurrence: recurrenceDaily,
+			Kind:       dbhelper.QueueKindMedication,
+			RefID:      medicationID,
+		})
+	}
+
+	scheduleMedicationReport(chatID, userID)
+
+	until := ""
+	if !m.EndsOn.IsZero() {
+		until = fmt.Sprintf(messageMedicationUntilFormat, m.EndsOn.AddDate(0, 0, -1).Format(messageDDayDateFormat))
+	}
+
+	return fmt.Sprintf(messageMedicationSavedFormat, m.Name, strings.Join(m.Times, ", "), until)
+}
+
+// schedule weekly adherence report of given chat (if not scheduled yet)
+func scheduleMedicationReport(chatID, userID int64) {
+	for _, q := range db.UndeliveredQueueItems(chatID) {
+		if q.Kind == dbhelper.QueueKindMedicationReport {
+			return
+		}
+	}
+
+	db.EnqueueItem(dbhelper.QueueItem{
+		ChatID:     chatID,
+		UserID:     userID,
+		Message:    messageMedicationReportTitle, // (replaced with the report on delivery)
+		FireOn:     nextTimeOfWeekday(lastWeekdayOf(chatID), medicationReportHour),
+		Recurrence: recurrenceWeekly,
+		Kind:       dbhelper.QueueKindMedicationReport,
+	})
+}
+
+// list medications of given chat with a keyboard for deleting them
+func listMedications(chatID int64, options map[string]interface{}) string {
+	medications := db.Medications(chatID)
+	if len(medications) <= 0 {
+		return messageMedicationNone + "\n\n" + messageMedicationUsage
+	}
+
+	keys := make(map[string]string)
+	for _, m := range medications {
+		keys[fmt.Sprintf(messageMedicationListItemFormat, m.Name, strings.Join(m.Times, ", "))] = fmt.Sprintf("%s %s %d", commandMedication, medicationParamDelete, m.ID)
+	}
+	buttons := bot.NewInlineKeyboardButtonsAsRowsWithCallbackData(keys)
+
+	cancel := commandCancel
+	buttons = append(buttons, []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{Text: messageCancel, CallbackData: &cancel},
+	})
+
+	options["reply_markup"] = bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+
+	return messageMedicationDeleteWhat
+}
+
+// weekly adherence report of given chat
+func medicationReport(chatID int64) string {
+	adherences := db.Adherences(chatID, truncateToDay(time.Now()).AddDate(0, 0, -(medicationReportDays-1)))
+	if len(adherences) <= 0 {
+		return messageMedicationReportNone
+	}
+
+	lines := []string{messageMedicationReportTitle}
+	for _, a := range adherences {
+		lines = append(lines, fmt.Sprintf(messageMedicationReportLineFormat, a.Name, a.NumTaken, a.NumDoses, a.NumTaken*100/a.NumDoses))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// message and keyboard for delivering medication queue items
+func medicationDeliveryMessage(q dbhelper.QueueItem) (message string, keyboard interface{}) {
+	switch q.Kind {
+	case dbhelper.QueueKindMedication:
+		if doseID, saved := db.SaveDose(q.RefID, q.ChatID, q.FireOn); saved {
+			return q.Message, medicationTakenKeyboard(doseID)
+		}
+	case dbhelper.QueueKindMedicationNag:
+		return q.Message, medicationTakenKeyboard(q.RefID)
+	case dbhelper.QueueKindMedicationReport:
+		return medicationReport(q.ChatID), nil
+	}
+
+	return q.Message, nil
+}
+
+// inline keyboard for marking a dose as taken
+func medicationTakenKeyboard(doseID int64) bot.InlineKeyboardMarkup {
+	taken := fmt.Sprintf("%s %s %d", commandMedication, medicationParamTaken, doseID)
+
+	return bot.InlineKeyboardMarkup{
+		InlineKeyboard: [][]bot.InlineKeyboardButton{
+			[]bot.InlineKeyboardButton{
+				bot.InlineKeyboardButton{Text: messageMedicationTaken, CallbackData: &taken},
+			},
+		},
+	}
+}
+
+// schedule following items after delivering medication queue items
+func afterMedicationDelivery(q dbhelper.QueueItem) {
+	switch q.Kind {
+	case dbhelper.QueueKindMedication:
+		m, exists := db.Medication(q.RefID)
+		if !exists || !m.IsActive {
+			return
+		}
+
+		// nag until taken
+		if doseID, saved := db.SaveDose(q.RefID, q.ChatID, q.FireOn); saved {
+			enqueueMedicationNag(q, m, doseID, q.FireOn)
+		}
+
+		// next dose (in the period)
+		if next, err := nextOccurrenceAfterNow(q.Recurrence, scheduledTimeOf(q)); err == nil && (m.EndsOn.IsZero() || next.Before(m.EndsOn)) {
+			scheduleNextOccurrence(q)
+		} else if !hasPendingMedicationItems(q.ChatID, m.ID) {
+			// the period is over
+			db.DeactivateMedication(q.ChatID, m.ID)
+		}
+	case dbhelper.QueueKindMedicationNag:
+		dose, exists := db.Dose(q.ChatID, q.RefID)
+		if !exists || !dose.TakenOn.IsZero() {
+			return
+		}
+		if m, exists := db.Medication(dose.MedicationID); exists && m.IsActive {
+			enqueueMedicationNag(q, m, dose.ID, dose.DueOn)
+		}
+	case dbhelper.QueueKindMedicationReport:
+		if len(db.Medications(q.ChatID)) > 0 {
+			scheduleNextOccurrence(q)
+		}
+	}
+}
+
+// check if there are pending dose reminders of given medication
+func hasPendingMedicationItems(chatID, medicationID int64) bool {
+	for _, q := range db.UndeliveredQueueItems(chatID) {
+		if q.Kind == dbhelper.QueueKindMedication && q.RefID == medicationID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// enqueue a nag for given dose (if it is not too late)
+func enqueueMedicationNag(q dbhelper.QueueItem, m dbhelper.Medication, doseID int64, dueOn time.Time) {
+	fireOn := time.Now().Add(medicationNagIntervalMinutes * time.Minute)
+	if fireOn.After(dueOn.Add(medicationNagMaxMinutes * time.Minute)) {
+		return
+	}
+
+	db.EnqueueItem(dbhelper.QueueItem{
+		ChatID:  q.ChatID,
+		UserID:  q.UserID,
+		Message: fmt.Sprintf(messageMedicationNagFormat, m.Name, clockOf(q.ChatID, dueOn)),
+		FireOn:  fireOn,
+		Kind:    dbhelper.QueueKindMedicationNag,
+		RefID:   doseID,
+	})
+}
+
+// process callback query of medications
+func processMedicationCallback(query bot.CallbackQuery, txt string) string {
+	params := strings.Fields(strings.TrimPrefix(txt, commandMedication))
+	if len(params) != 2 {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+	id, err := strconv.ParseInt(params[1], 10, 64)
+	if err != nil {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+
+	chatID := query.Message.Chat.ID
+
+	switch params[0] {
+	case medicationParamTaken:
+		dose, exists := db.Dose(chatID, id)
+		if !exists {
+			return messageError
+		}
+		if !db.TakeDose(chatID, id) {
+			return messageMedicationAlreadyTaken
+		}
+
+		// stop nagging
+		db.DeleteQueueItemsByRef(chatID, dbhelper.QueueKindMedicationNag, id)
+
+		name := ""
+		if m, exists := db.Medication(dose.MedicationID); exists {
+			name = m.Name
+		}
+		return fmt.Sprintf(messageMedicationTakenFormat, name, clockOf(chatID, time.Now()))
+	case medicationParamDelete:
+		if db.DeactivateMedication(chatID, id) {
+			db.DeleteQueueItemsByRef(chatID, dbhelper.QueueKindMedication, id)
+
+			return messageMedicationDeleted
+		}
+	default:
+		log.Printf("*** Unprocessable callback query: %s", txt)
+	}
+
+	return messageError
+}
@@ -0,0 +1,580 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+)
+
+const (
+	messagesFilename = "messages.json"
+)
+
+// overridable messages, keyed by their names
+//
+// (eg. {"messageUsage": "...", "messageNoReminders": "..."} in messages.json)
+var _messages = map[string]*string{
+	"messageAPIAIDetailedErrorFormat":   &messageAPIAIDetailedErrorFormat,
+	"messageAPIAIErrorFormat":           &messageAPIAIErrorFormat,
+	"messageAPIDisabled":                &messageAPIDisabled,
+	"messageAPIKeyFormat":               &messageAPIKeyFormat,
+	"messageAPIKeyNotFound":             &messageAPIKeyNotFound,
+	"messageAPIKeyPrivateOnly":          &messageAPIKeyPrivateOnly,
+	"messageAPIKeyRevoked":              &messageAPIKeyRevoked,
+	"messageAckButton":                  &messageAckButton,
+	"messageAckedFormat":                &messageAckedFormat,
+	"messageAdminBannedFormat":          &messageAdminBannedFormat,
+	"messageAliasDeletedFormat":         &messageAliasDeletedFormat,
+	"messageAliasGlobalSuffix":          &messageAliasGlobalSuffix,
+	"messageAliasInvalid":               &messageAliasInvalid,
+	"messageAliasItemFormat":            &messageAliasItemFormat,
+	"messageAliasListTitle":             &messageAliasListTitle,
+	"messageAliasNone":                  &messageAliasNone,
+	"messageAliasNotFound":              &messageAliasNotFound,
+	"messageAliasSavedFormat":           &messageAliasSavedFormat,
+	"messageAliasUsage":                 &messageAliasUsage,
+	"messageAlreadyAckedFormat":         &messageAlreadyAckedFormat,
+	"messageAnnounceAdminOnly":          &messageAnnounceAdminOnly,
+	"messageAnnounceDoneFormat":         &messageAnnounceDoneFormat,
+	"messageAnnounceScheduledFormat":    &messageAnnounceScheduledFormat,
+	"messageAnnounceTimeParseError":     &messageAnnounceTimeParseError,
+	"messageAnnounceUsage":              &messageAnnounceUsage,
+	"messageAnnouncementFormat":         &messageAnnouncementFormat,
+	"messageAssigneeFormat":             &messageAssigneeFormat,
+	"messageAttachmentCaptionNeeded":    &messageAttachmentCaptionNeeded,
+	"messageAttachmentMissing":          &messageAttachmentMissing,
+	"messageAttachmentSaveFailed":       &messageAttachmentSaveFailed,
+	"messageBannedFormat":               &messageBannedFormat,
+	"messageBusinessAllow":              &messageBusinessAllow,
+	"messageBusinessAllowed":            &messageBusinessAllowed,
+	"messageBusinessCannotReply":        &messageBusinessCannotReply,
+	"messageBusinessConnectedFormat":    &messageBusinessConnectedFormat,
+	"messageBusinessDisallow":           &messageBusinessDisallow,
+	"messageBusinessDisallowed":         &messageBusinessDisallowed,
+	"messageBusinessDisconnected":       &messageBusinessDisconnected,
+	"messageBusinessLineFormat":         &messageBusinessLineFormat,
+	"messageBusinessListTitle":          &messageBusinessListTitle,
+	"messageBusinessNone":               &messageBusinessNone,
+	"messageBusinessNotAllowed":         &messageBusinessNotAllowed,
+	"messageBusinessRemindUsage":        &messageBusinessRemindUsage,
+	"messageBusinessScheduledFormat":    &messageBusinessScheduledFormat,
+	"messageBusinessTimeFormat":         &messageBusinessTimeFormat,
+	"messageBusinessUnknownCustomer":    &messageBusinessUnknownCustomer,
+	"messageCancel":                     &messageCancel,
+	"messageCancelCandidateFormat":      &messageCancelCandidateFormat,
+	"messageCancelCandidates":           &messageCancelCandidates,
+	"messageCancelMatchedFormat":        &messageCancelMatchedFormat,
+	"messageCancelNoMatchFormat":        &messageCancelNoMatchFormat,
+	"messageCancelNoSuchNumber":         &messageCancelNoSuchNumber,
+	"messageCancelWhat":                 &messageCancelWhat,
+	"messageCanceledFormat":             &messageCanceledFormat,
+	"messageCategoryNoRoutes":           &messageCategoryNoRoutes,
+	"messageCategoryNotShared":          &messageCategoryNotShared,
+	"messageCategoryRemoved":            &messageCategoryRemoved,
+	"messageCategoryRouteFormat":        &messageCategoryRouteFormat,
+	"messageCategorySavedFormat":        &messageCategorySavedFormat,
+	"messageCategoryThisChat":           &messageCategoryThisChat,
+	"messageCategoryTooLong":            &messageCategoryTooLong,
+	"messageCategoryUsage":              &messageCategoryUsage,
+	"messageCategoryWhereFormat":        &messageCategoryWhereFormat,
+	"messageChainNoParent":              &messageChainNoParent,
+	"messageChainSavedFormat":           &messageChainSavedFormat,
+	"messageChainTriggeredFormat":       &messageChainTriggeredFormat,
+	"messageClone":                      &messageClone,
+	"messageCloneGone":                  &messageCloneGone,
+	"messageCloneNextDay":               &messageCloneNextDay,
+	"messageCloneNextMonth":             &messageCloneNextMonth,
+	"messageCloneNextWeek":              &messageCloneNextWeek,
+	"messageCloneTimeIsPast":            &messageCloneTimeIsPast,
+	"messageCloneWhat":                  &messageCloneWhat,
+	"messageCloneWhenFormat":            &messageCloneWhenFormat,
+	"messageCommandCanceled":            &messageCommandCanceled,
+	"messageContentRejected":            &messageContentRejected,
+	"messageCronInvalidFormat":          &messageCronInvalidFormat,
+	"messageCronScheduledFormat":        &messageCronScheduledFormat,
+	"messageCronUsage":                  &messageCronUsage,
+	"messageDDayCountdownFormat":        &messageDDayCountdownFormat,
+	"messageDDayCountdownsFormat":       &messageDDayCountdownsFormat,
+	"messageDDayDateFormat":             &messageDDayDateFormat,
+	"messageDDayIsPast":                 &messageDDayIsPast,
+	"messageDDayItemFormat":             &messageDDayItemFormat,
+	"messageDDayNoCountdowns":           &messageDDayNoCountdowns,
+	"messageDDayRegisteredFormat":       &messageDDayRegisteredFormat,
+	"messageDDayUsage":                  &messageDDayUsage,
+	"messageDateFormatTitle":            &messageDateFormatTitle,
+	"messageDecorationNotAllowed":       &messageDecorationNotAllowed,
+	"messageDecorationTooLongFormat":    &messageDecorationTooLongFormat,
+	"messageDraftExpiredFormat":         &messageDraftExpiredFormat,
+	"messageDumpChatCaptionFormat":      &messageDumpChatCaptionFormat,
+	"messageDumpChatFailed":             &messageDumpChatFailed,
+	"messageDumpChatSentFormat":         &messageDumpChatSentFormat,
+	"messageDumpChatUsage":              &messageDumpChatUsage,
+	"messageDuplicatesFormat":           &messageDuplicatesFormat,
+	"messageError":                      &messageError,
+	"messageEscalationBodyFmt":          &messageEscalationBodyFmt,
+	"messageEscalationSubject":          &messageEscalationSubject,
+	"messageEventDeletedFormat":         &messageEventDeletedFormat,
+	"messageEventListFormat":            &messageEventListFormat,
+	"messageEventNameInvalid":           &messageEventNameInvalid,
+	"messageEventNone":                  &messageEventNone,
+	"messageEventNotFound":              &messageEventNotFound,
+	"messageEventSavedFormat":           &messageEventSavedFormat,
+	"messageEventTemplateError":         &messageEventTemplateError,
+	"messageEventUsage":                 &messageEventUsage,
+	"messageFailureAdminsFormat":        &messageFailureAdminsFormat,
+	"messageFallbackNoteFormat":         &messageFallbackNoteFormat,
+	"messageFeedAddedFormat":            &messageFeedAddedFormat,
+	"messageFeedDeleteWhat":             &messageFeedDeleteWhat,
+	"messageFeedDeleted":                &messageFeedDeleted,
+	"messageFeedExists":                 &messageFeedExists,
+	"messageFeedFetchFailed":            &messageFeedFetchFailed,
+	"messageFeedItemFormat":             &messageFeedItemFormat,
+	"messageFeedListItemFmt":            &messageFeedListItemFmt,
+	"messageFeedNone":                   &messageFeedNone,
+	"messageFeedTooMany":                &messageFeedTooMany,
+	"messageFeedUntitledTitle":          &messageFeedUntitledTitle,
+	"messageFeedUsage":                  &messageFeedUsage,
+	"messageFeedbackAdminFormat":        &messageFeedbackAdminFormat,
+	"messageFeedbackAnswerFailed":       &messageFeedbackAnswerFailed,
+	"messageFeedbackAnswerFormat":       &messageFeedbackAnswerFormat,
+	"messageFeedbackAnswered":           &messageFeedbackAnswered,
+	"messageFeedbackSaveFailed":         &messageFeedbackSaveFailed,
+	"messageFeedbackThanks":             &messageFeedbackThanks,
+	"messageFeedbackUnknownSender":      &messageFeedbackUnknownSender,
+	"messageFeedbackUsage":              &messageFeedbackUsage,
+	"messageFocusAlreadyFormat":         &messageFocusAlreadyFormat,
+	"messageFocusDigestFormat":          &messageFocusDigestFormat,
+	"messageFocusDigestReminder":        &messageFocusDigestReminder,
+	"messageFocusDigestTitle":           &messageFocusDigestTitle,
+	"messageFocusEnded":                 &messageFocusEnded,
+	"messageFocusNone":                  &messageFocusNone,
+	"messageFocusStartedFormat":         &messageFocusStartedFormat,
+	"messageFocusTimeFormat":            &messageFocusTimeFormat,
+	"messageFocusTooLong":               &messageFocusTooLong,
+	"messageFocusUsage":                 &messageFocusUsage,
+	"messageFollowUpDoneFormat":         &messageFollowUpDoneFormat,
+	"messageFollowUpSkipped":            &messageFollowUpSkipped,
+	"messageFollowUpSnoozedFormat":      &messageFollowUpSnoozedFormat,
+	"messageFollowUpTitle":              &messageFollowUpTitle,
+	"messageFullText":                   &messageFullText,
+	"messageFullTextFilename":           &messageFullTextFilename,
+	"messageFullTextGone":               &messageFullTextGone,
+	"messageFullTextSent":               &messageFullTextSent,
+	"messageGreetingGroup":              &messageGreetingGroup,
+	"messageGreetingTitle":              &messageGreetingTitle,
+	"messageGroupNagFormat":             &messageGroupNagFormat,
+	"messageGroupReportLineFormat":      &messageGroupReportLineFormat,
+	"messageGroupReportNoneFormat":      &messageGroupReportNoneFormat,
+	"messageGroupReportSettingTitle":    &messageGroupReportSettingTitle,
+	"messageGroupReportTimeFormat":      &messageGroupReportTimeFormat,
+	"messageGroupReportTitle":           &messageGroupReportTitle,
+	"messageGroupReportTitleFormat":     &messageGroupReportTitleFormat,
+	"messageHabitCheckedFormat":         &messageHabitCheckedFormat,
+	"messageHabitDeleteWhat":            &messageHabitDeleteWhat,
+	"messageHabitDeleted":               &messageHabitDeleted,
+	"messageHabitDone":                  &messageHabitDone,
+	"messageHabitInvalidTime":           &messageHabitInvalidTime,
+	"messageHabitLeaderboardEmpty":      &messageHabitLeaderboardEmpty,
+	"messageHabitLeaderboardFormat":     &messageHabitLeaderboardFormat,
+	"messageHabitLeaderboardGroupOnly":  &messageHabitLeaderboardGroupOnly,
+	"messageHabitLeaderboardJoined":     &messageHabitLeaderboardJoined,
+	"messageHabitLeaderboardLeft":       &messageHabitLeaderboardLeft,
+	"messageHabitLeaderboardTitle":      &messageHabitLeaderboardTitle,
+	"messageHabitListItemFormat":        &messageHabitListItemFormat,
+	"messageHabitMissed":                &messageHabitMissed,
+	"messageHabitMissedFormat":          &messageHabitMissedFormat,
+	"messageHabitNone":                  &messageHabitNone,
+	"messageHabitPromptFormat":          &messageHabitPromptFormat,
+	"messageHabitSavedFormat":           &messageHabitSavedFormat,
+	"messageHabitSummaryLineFormat":     &messageHabitSummaryLineFormat,
+	"messageHabitSummaryNone":           &messageHabitSummaryNone,
+	"messageHabitSummaryTitle":          &messageHabitSummaryTitle,
+	"messageHabitUsage":                 &messageHabitUsage,
+	"messageHour12":                     &messageHour12,
+	"messageHour12Title":                &messageHour12Title,
+	"messageHour24":                     &messageHour24,
+	"messageImportCanceled":             &messageImportCanceled,
+	"messageImportDoneFormat":           &messageImportDoneFormat,
+	"messageImportExpired":              &messageImportExpired,
+	"messageImportFailed":               &messageImportFailed,
+	"messageImportGoogleTasks":          &messageImportGoogleTasks,
+	"messageImportKeep":                 &messageImportKeep,
+	"messageImportLineFormat":           &messageImportLineFormat,
+	"messageImportMoreFormat":           &messageImportMoreFormat,
+	"messageImportNo":                   &messageImportNo,
+	"messageImportNothingFormat":        &messageImportNothingFormat,
+	"messageImportPreviewFormat":        &messageImportPreviewFormat,
+	"messageImportSkippedFormat":        &messageImportSkippedFormat,
+	"messageImportTodoist":              &messageImportTodoist,
+	"messageImportTooLarge":             &messageImportTooLarge,
+	"messageImportUnknown":              &messageImportUnknown,
+	"messageImportYes":                  &messageImportYes,
+	"messageInlineCancelButton":         &messageInlineCancelButton,
+	"messageInlineCancelConfirmFormat":  &messageInlineCancelConfirmFormat,
+	"messageInlineCancelTitleFormat":    &messageInlineCancelTitleFormat,
+	"messageInlineCanceledFormat":       &messageInlineCanceledFormat,
+	"messageInlineGone":                 &messageInlineGone,
+	"messageInlineNotYours":             &messageInlineNotYours,
+	"messageIntegrityFailedFormat":      &messageIntegrityFailedFormat,
+	"messageIntegrityOpenFailed":        &messageIntegrityOpenFailed,
+	"messageIntegrityReindexedFormat":   &messageIntegrityReindexedFormat,
+	"messageIntegrityRestoredFormat":    &messageIntegrityRestoredFormat,
+	"messageIntegritySchemaFormat":      &messageIntegritySchemaFormat,
+	"messageLatencyWarningFormat":       &messageLatencyWarningFormat,
+	"messageLinkPreviewTitle":           &messageLinkPreviewTitle,
+	"messageLinkSummaryFormat":          &messageLinkSummaryFormat,
+	"messageLinkTitleFormat":            &messageLinkTitleFormat,
+	"messageListGroupFormat":            &messageListGroupFormat,
+	"messageListHint":                   &messageListHint,
+	"messageListItemFormat":             &messageListItemFormat,
+	"messageListLater":                  &messageListLater,
+	"messageListNextWeek":               &messageListNextWeek,
+	"messageListThisWeek":               &messageListThisWeek,
+	"messageListToday":                  &messageListToday,
+	"messageListTomorrow":               &messageListTomorrow,
+	"messageMedicationAlreadyTaken":     &messageMedicationAlreadyTaken,
+	"messageMedicationDeleteWhat":       &messageMedicationDeleteWhat,
+	"messageMedicationDeleted":          &messageMedicationDeleted,
+	"messageMedicationDoseFormat":       &messageMedicationDoseFormat,
+	"messageMedicationInvalidTimes":     &messageMedicationInvalidTimes,
+	"messageMedicationListItemFormat":   &messageMedicationListItemFormat,
+	"messageMedicationNagFormat":        &messageMedicationNagFormat,
+	"messageMedicationNone":             &messageMedicationNone,
+	"messageMedicationReportLineFormat": &messageMedicationReportLineFormat,
+	"messageMedicationReportNone":       &messageMedicationReportNone,
+	"messageMedicationReportTitle":      &messageMedicationReportTitle,
+	"messageMedicationSavedFormat":      &messageMedicationSavedFormat,
+	"messageMedicationTaken":            &messageMedicationTaken,
+	"messageMedicationTakenFormat":      &messageMedicationTakenFormat,
+	"messageMedicationUntilFormat":      &messageMedicationUntilFormat,
+	"messageMedicationUsage":            &messageMedicationUsage,
+	"messageMentionFormat":              &messageMentionFormat,
+	"messageMergeFormat":                &messageMergeFormat,
+	"messageMergeGone":                  &messageMergeGone,
+	"messageMergedFormat":               &messageMergedFormat,
+	"messageMergedReminderFormat":       &messageMergedReminderFormat,
+	"messageMisparse":                   &messageMisparse,
+	"messageMisparseAskFormat":          &messageMisparseAskFormat,
+	"messageMisparseCorrectionSaved":    &messageMisparseCorrectionSaved,
+	"messageMisparseExpired":            &messageMisparseExpired,
+	"messageMisparseSaveFailed":         &messageMisparseSaveFailed,
+	"messageMove":                       &messageMove,
+	"messageMoveWhat":                   &messageMoveWhat,
+	"messageMoveWhere":                  &messageMoveWhere,
+	"messageMovedFormat":                &messageMovedFormat,
+	"messageMovedInFormat":              &messageMovedInFormat,
+	"messageNoActiveReminders":          &messageNoActiveReminders,
+	"messageNoChatsToMove":              &messageNoChatsToMove,
+	"messageNoDDays":                    &messageNoDDays,
+	"messageNoMovableReminders":         &messageNoMovableReminders,
+	"messageNoPausedReminders":          &messageNoPausedReminders,
+	"messageNoReminders":                &messageNoReminders,
+	"messageOnFailEscalate":             &messageOnFailEscalate,
+	"messageOnFailGiveUp":               &messageOnFailGiveUp,
+	"messageOnFailHowFormat":            &messageOnFailHowFormat,
+	"messageOnFailNoEscalation":         &messageOnFailNoEscalation,
+	"messageOnFailNoPhone":              &messageOnFailNoPhone,
+	"messageOnFailRetryNextDay":         &messageOnFailRetryNextDay,
+	"messageOnFailSMS":                  &messageOnFailSMS,
+	"messageOnFailSavedFormat":          &messageOnFailSavedFormat,
+	"messageOnFailWhat":                 &messageOnFailWhat,
+	"messagePageWatchAddedFormat":       &messagePageWatchAddedFormat,
+	"messagePageWatchBadInterval":       &messagePageWatchBadInterval,
+	"messagePageWatchChangedFormat":     &messagePageWatchChangedFormat,
+	"messagePageWatchDeleteWhat":        &messagePageWatchDeleteWhat,
+	"messagePageWatchDeleted":           &messagePageWatchDeleted,
+	"messagePageWatchExists":            &messagePageWatchExists,
+	"messagePageWatchFailingFormat":     &messagePageWatchFailingFormat,
+	"messagePageWatchFetchFailed":       &messagePageWatchFetchFailed,
+	"messagePageWatchHoursFormat":       &messagePageWatchHoursFormat,
+	"messagePageWatchListItemFormat":    &messagePageWatchListItemFormat,
+	"messagePageWatchMinutesFormat":     &messagePageWatchMinutesFormat,
+	"messagePageWatchNoMatch":           &messagePageWatchNoMatch,
+	"messagePageWatchNone":              &messagePageWatchNone,
+	"messagePageWatchTooMany":           &messagePageWatchTooMany,
+	"messagePageWatchUsage":             &messagePageWatchUsage,
+	"messagePause":                      &messagePause,
+	"messagePauseWhat":                  &messagePauseWhat,
+	"messagePhoneCodeExpired":           &messagePhoneCodeExpired,
+	"messagePhoneCodeFailed":            &messagePhoneCodeFailed,
+	"messagePhoneCodeSMSFormat":         &messagePhoneCodeSMSFormat,
+	"messagePhoneCodeSentFormat":        &messagePhoneCodeSentFormat,
+	"messagePhoneCodeTooSoon":           &messagePhoneCodeTooSoon,
+	"messagePhoneDeleted":               &messagePhoneDeleted,
+	"messagePhoneInvalid":               &messagePhoneInvalid,
+	"messagePhoneNone":                  &messagePhoneNone,
+	"messagePhoneNotVerified":           &messagePhoneNotVerified,
+	"messagePhoneNotYours":              &messagePhoneNotYours,
+	"messagePhoneNothingToVerify":       &messagePhoneNothingToVerify,
+	"messagePhoneStatusFormat":          &messagePhoneStatusFormat,
+	"messagePhoneUsage":                 &messagePhoneUsage,
+	"messagePhoneVerified":              &messagePhoneVerified,
+	"messagePhoneVerifiedDone":          &messagePhoneVerifiedDone,
+	"messagePhoneWrongCode":             &messagePhoneWrongCode,
+	"messagePollNoVotes":                &messagePollNoVotes,
+	"messagePollResultLineFormat":       &messagePollResultLineFormat,
+	"messagePollResultTitleFormat":      &messagePollResultTitleFormat,
+	"messagePollResultUnavailable":      &messagePollResultUnavailable,
+	"messagePollResultWinner":           &messagePollResultWinner,
+	"messagePomodoroBreakDoneFmt":       &messagePomodoroBreakDoneFmt,
+	"messagePomodoroInvalidMinutes":     &messagePomodoroInvalidMinutes,
+	"messagePomodoroNotRunning":         &messagePomodoroNotRunning,
+	"messagePomodoroPause":              &messagePomodoroPause,
+	"messagePomodoroPausedFormat":       &messagePomodoroPausedFormat,
+	"messagePomodoroPhaseBreak":         &messagePomodoroPhaseBreak,
+	"messagePomodoroPhaseWork":          &messagePomodoroPhaseWork,
+	"messagePomodoroResume":             &messagePomodoroResume,
+	"messagePomodoroResumedFormat":      &messagePomodoroResumedFormat,
+	"messagePomodoroStartedFormat":      &messagePomodoroStartedFormat,
+	"messagePomodoroStatsLineFmt":       &messagePomodoroStatsLineFmt,
+	"messagePomodoroStatsTitle":         &messagePomodoroStatsTitle,
+	"messagePomodoroStatusFormat":       &messagePomodoroStatusFormat,
+	"messagePomodoroStop":               &messagePomodoroStop,
+	"messagePomodoroStoppedFormat":      &messagePomodoroStoppedFormat,
+	"messagePomodoroUsage":              &messagePomodoroUsage,
+	"messagePomodoroWorkDoneFormat":     &messagePomodoroWorkDoneFormat,
+	"messagePriceAlertAbove":            &messagePriceAlertAbove,
+	"messagePriceAlertAddedFormat":      &messagePriceAlertAddedFormat,
+	"messagePriceAlertBelow":            &messagePriceAlertBelow,
+	"messagePriceAlertDeleteWhat":       &messagePriceAlertDeleteWhat,
+	"messagePriceAlertDeleted":          &messagePriceAlertDeleted,
+	"messagePriceAlertFetchFailed":      &messagePriceAlertFetchFailed,
+	"messagePriceAlertFiredAbove":       &messagePriceAlertFiredAbove,
+	"messagePriceAlertFiredBelow":       &messagePriceAlertFiredBelow,
+	"messagePriceAlertFiredFormat":      &messagePriceAlertFiredFormat,
+	"messagePriceAlertListItemFmt":      &messagePriceAlertListItemFmt,
+	"messagePriceAlertNone":             &messagePriceAlertNone,
+	"messagePriceAlertTooMany":          &messagePriceAlertTooMany,
+	"messagePriceAlertUnknownSource":    &messagePriceAlertUnknownSource,
+	"messagePriceAlertUsage":            &messagePriceAlertUsage,
+	"messagePrivateChat":                &messagePrivateChat,
+	"messagePublicEventAddedFormat":     &messagePublicEventAddedFormat,
+	"messagePublicEventAdminUsage":      &messagePublicEventAdminUsage,
+	"messagePublicEventButtonFormat":    &messagePublicEventButtonFormat,
+	"messagePublicEventDeletedFormat":   &messagePublicEventDeletedFormat,
+	"messagePublicEventIsPast":          &messagePublicEventIsPast,
+	"messagePublicEventNotFound":        &messagePublicEventNotFound,
+	"messagePublicEventSubscribedFmt":   &messagePublicEventSubscribedFmt,
+	"messagePublicEventSubscribedMark":  &messagePublicEventSubscribedMark,
+	"messagePublicEventUnsubscribed":    &messagePublicEventUnsubscribed,
+	"messagePublicEventsNone":           &messagePublicEventsNone,
+	"messagePublicEventsTitle":          &messagePublicEventsTitle,
+	"messagePublishNotAllowed":          &messagePublishNotAllowed,
+	"messagePublishScheduledFmt":        &messagePublishScheduledFmt,
+	"messagePublishUsage":               &messagePublishUsage,
+	"messagePushDeviceFormat":           &messagePushDeviceFormat,
+	"messagePushDevicesFormat":          &messagePushDevicesFormat,
+	"messagePushDisabled":               &messagePushDisabled,
+	"messagePushInvalidToken":           &messagePushInvalidToken,
+	"messagePushModeAlongside":          &messagePushModeAlongside,
+	"messagePushModeButtonFormat":       &messagePushModeButtonFormat,
+	"messagePushModeChangedFormat":      &messagePushModeChangedFormat,
+	"messagePushModeOnly":               &messagePushModeOnly,
+	"messagePushPlatformAPNs":           &messagePushPlatformAPNs,
+	"messagePushPlatformFCM":            &messagePushPlatformFCM,
+	"messagePushPrivateOnly":            &messagePushPrivateOnly,
+	"messagePushRegisteredFormat":       &messagePushRegisteredFormat,
+	"messagePushRemoveFormat":           &messagePushRemoveFormat,
+	"messagePushRemoved":                &messagePushRemoved,
+	"messagePushTitle":                  &messagePushTitle,
+	"messagePushTooManyDevices":         &messagePushTooManyDevices,
+	"messagePushUnknownPlatform":        &messagePushUnknownPlatform,
+	"messagePushUnnamedDevice":          &messagePushUnnamedDevice,
+	"messagePushUsage":                  &messagePushUsage,
+	"messageQueueDropFormat":            &messageQueueDropFormat,
+	"messageQueueDropped":               &messageQueueDropped,
+	"messageQueueEmpty":                 &messageQueueEmpty,
+	"messageQueueItemGone":              &messageQueueItemGone,
+	"messageQueueMore":                  &messageQueueMore,
+	"messageQueueRetried":               &messageQueueRetried,
+	"messageQueueRetryFormat":           &messageQueueRetryFormat,
+	"messageQueueUsage":                 &messageQueueUsage,
+	"messageQuietHoursFormat":           &messageQuietHoursFormat,
+	"messageQuietHoursInvalid":          &messageQuietHoursInvalid,
+	"messageQuietHoursTitle":            &messageQuietHoursTitle,
+	"messageRawUsage":                   &messageRawUsage,
+	"messageRecurrenceCreatedFormat":    &messageRecurrenceCreatedFormat,
+	"messageRecurrenceExists":           &messageRecurrenceExists,
+	"messageRelativeDaysFmt":            &messageRelativeDaysFmt,
+	"messageRelativeHoursFmt":           &messageRelativeHoursFmt,
+	"messageRelativeMinutesFmt":         &messageRelativeMinutesFmt,
+	"messageRelativeSoon":               &messageRelativeSoon,
+	"messageReminderCanceled":           &messageReminderCanceled,
+	"messageReminderPaused":             &messageReminderPaused,
+	"messageReminderResumed":            &messageReminderResumed,
+	"messageReminderScheduledFormat":    &messageReminderScheduledFormat,
+	"messageReplayFailedFormat":         &messageReplayFailedFormat,
+	"messageReplayNothing":              &messageReplayNothing,
+	"messageReplaySummaryFormat":        &messageReplaySummaryFormat,
+	"messageReplayUsage":                &messageReplayUsage,
+	"messageReplyAlreadyDoneFormat":     &messageReplyAlreadyDoneFormat,
+	"messageReplyDoneFormat":            &messageReplyDoneFormat,
+	"messageReplyRepeatUsage":           &messageReplyRepeatUsage,
+	"messageResume":                     &messageResume,
+	"messageResumeWhat":                 &messageResumeWhat,
+	"messageReviewAlreadyReviewed":      &messageReviewAlreadyReviewed,
+	"messageReviewApproveFormat":        &messageReviewApproveFormat,
+	"messageReviewApproved":             &messageReviewApproved,
+	"messageReviewEmpty":                &messageReviewEmpty,
+	"messageReviewMoreFormat":           &messageReviewMoreFormat,
+	"messageReviewRemoveFormat":         &messageReviewRemoveFormat,
+	"messageReviewRemoved":              &messageReviewRemoved,
+	"messageRoundingHour":               &messageRoundingHour,
+	"messageRoundingMinutesFormat":      &messageRoundingMinutesFormat,
+	"messageRoundingTitle":              &messageRoundingTitle,
+	"messageSMSDisabled":                &messageSMSDisabled,
+	"messageSMSFailureAdminFormat":      &messageSMSFailureAdminFormat,
+	"messageSMSFailureFormat":           &messageSMSFailureFormat,
+	"messageSanitizeBlockedDomain":      &messageSanitizeBlockedDomain,
+	"messageSanitizeBlockedWord":        &messageSanitizeBlockedWord,
+	"messageSanitizeEmpty":              &messageSanitizeEmpty,
+	"messageSanitizeFilteredFormat":     &messageSanitizeFilteredFormat,
+	"messageSanitizeLinkInGroup":        &messageSanitizeLinkInGroup,
+	"messageSanitizeTooLongFormat":      &messageSanitizeTooLongFormat,
+	"messageSaveFailed":                 &messageSaveFailed,
+	"messageSendingBackFile":            &messageSendingBackFile,
+	"messageSettingFormat":              &messageSettingFormat,
+	"messageSettingOff":                 &messageSettingOff,
+	"messageSettingOn":                  &messageSettingOn,
+	"messageSettingSaved":               &messageSettingSaved,
+	"messageSettings":                   &messageSettings,
+	"messageSignOffTitle":               &messageSignOffTitle,
+	"messageSimpleChoiceFormat":         &messageSimpleChoiceFormat,
+	"messageSimpleChoiceHint":           &messageSimpleChoiceHint,
+	"messageSimpleConfirmFormat":        &messageSimpleConfirmFormat,
+	"messageSimpleConfirmQuestion":      &messageSimpleConfirmQuestion,
+	"messageSimpleModeTitle":            &messageSimpleModeTitle,
+	"messageStatsLastDay":               &messageStatsLastDay,
+	"messageStatsLastHour":              &messageStatsLastHour,
+	"messageStatsLatencyFormat":         &messageStatsLatencyFormat,
+	"messageStatsNoDeliveries":          &messageStatsNoDeliveries,
+	"messageStatsQueriesFormat":         &messageStatsQueriesFormat,
+	"messageStatsSlowQueriesFormat":     &messageStatsSlowQueriesFormat,
+	"messageStatsTitle":                 &messageStatsTitle,
+	"messageSuggestDeclined":            &messageSuggestDeclined,
+	"messageSuggestNo":                  &messageSuggestNo,
+	"messageSuggestRecurrenceFormat":    &messageSuggestRecurrenceFormat,
+	"messageSuggestYes":                 &messageSuggestYes,
+	"messageSummarizeTitle":             &messageSummarizeTitle,
+	"messageSyncConnectFormat":          &messageSyncConnectFormat,
+	"messageSyncConnected":              &messageSyncConnected,
+	"messageSyncConnectedFormat":        &messageSyncConnectedFormat,
+	"messageSyncDisabled":               &messageSyncDisabled,
+	"messageSyncDisconnectFmt":          &messageSyncDisconnectFmt,
+	"messageSyncDisconnected":           &messageSyncDisconnected,
+	"messageSyncLineFormat":             &messageSyncLineFormat,
+	"messageSyncNotConnected":           &messageSyncNotConnected,
+	"messageSyncPageDone":               &messageSyncPageDone,
+	"messageSyncPageFailed":             &messageSyncPageFailed,
+	"messageSyncTitle":                  &messageSyncTitle,
+	"messageTextNeeded":                 &messageTextNeeded,
+	"messageThrottled":                  &messageThrottled,
+	"messageTidyTextTitle":              &messageTidyTextTitle,
+	"messageTimeAM":                     &messageTimeAM,
+	"messageTimeIsPastFormat":           &messageTimeIsPastFormat,
+	"messageTimePM":                     &messageTimePM,
+	"messageTimeParseError":             &messageTimeParseError,
+	"messageTimerAlreadyFinished":       &messageTimerAlreadyFinished,
+	"messageTimerCancel":                &messageTimerCancel,
+	"messageTimerCanceledFormat":        &messageTimerCanceledFormat,
+	"messageTimerDoneFormat":            &messageTimerDoneFormat,
+	"messageTimerFiredFormat":           &messageTimerFiredFormat,
+	"messageTimerLiveFormat":            &messageTimerLiveFormat,
+	"messageTimerSetFormat":             &messageTimerSetFormat,
+	"messageTimerShowCountdown":         &messageTimerShowCountdown,
+	"messageTimerTooLong":               &messageTimerTooLong,
+	"messageTimerUsage":                 &messageTimerUsage,
+	"messageTopicAddedFormat":           &messageTopicAddedFormat,
+	"messageTopicAdminUsage":            &messageTopicAdminUsage,
+	"messageTopicAllowedFormat":         &messageTopicAllowedFormat,
+	"messageTopicButtonFormat":          &messageTopicButtonFormat,
+	"messageTopicDeletedFormat":         &messageTopicDeletedFormat,
+	"messageTopicDeniedFormat":          &messageTopicDeniedFormat,
+	"messageTopicNotFound":              &messageTopicNotFound,
+	"messageTopicPostDoneFormat":        &messageTopicPostDoneFormat,
+	"messageTopicPostFormat":            &messageTopicPostFormat,
+	"messageTopicPostTopicDeleted":      &messageTopicPostTopicDeleted,
+	"messageTopicSubscribedFmt":         &messageTopicSubscribedFmt,
+	"messageTopicSubscribedMark":        &messageTopicSubscribedMark,
+	"messageTopicUnsubscribed":          &messageTopicUnsubscribed,
+	"messageTopicsNone":                 &messageTopicsNone,
+	"messageTopicsTitle":                &messageTopicsTitle,
+	"messageTrainExportCaptionFormat":   &messageTrainExportCaptionFormat,
+	"messageTrainExportEmpty":           &messageTrainExportEmpty,
+	"messageTrainExportFailed":          &messageTrainExportFailed,
+	"messageTrainExportSentFormat":      &messageTrainExportSentFormat,
+	"messageTrainExportUsage":           &messageTrainExportUsage,
+	"messageTransferDrop":               &messageTransferDrop,
+	"messageTransferDroppedFormat":      &messageTransferDroppedFormat,
+	"messageTransferMove":               &messageTransferMove,
+	"messageTransferOfferFormat":        &messageTransferOfferFormat,
+	"messageTransferredFormat":          &messageTransferredFormat,
+	"messageTrimmedSuffix":              &messageTrimmedSuffix,
+	"messageUnknownGroup":               &messageUnknownGroup,
+	"messageUsage":                      &messageUsage,
+	"messageUsageSimple":                &messageUsageSimple,
+	"messageVacationCanceled":           &messageVacationCanceled,
+	"messageVacationDefer":              &messageVacationDefer,
+	"messageVacationHowFormat":          &messageVacationHowFormat,
+	"messageVacationListFormat":         &messageVacationListFormat,
+	"messageVacationNone":               &messageVacationNone,
+	"messageVacationParseError":         &messageVacationParseError,
+	"messageVacationSavedFormat":        &messageVacationSavedFormat,
+	"messageVacationSkip":               &messageVacationSkip,
+	"messageVacationTimeFormat":         &messageVacationTimeFormat,
+	"messageVacationUsage":              &messageVacationUsage,
+	"messageVisibilityHiddenFormat":     &messageVisibilityHiddenFormat,
+	"messageVisibilityNoPrivateChat":    &messageVisibilityNoPrivateChat,
+	"messageVisibilityNotice":           &messageVisibilityNotice,
+	"messageVisibilityPrivate":          &messageVisibilityPrivate,
+	"messageVisibilityPrivateChosen":    &messageVisibilityPrivateChosen,
+	"messageVisibilityPublic":           &messageVisibilityPublic,
+	"messageVisibilityPublicChosen":     &messageVisibilityPublicChosen,
+	"messageVoiceTitle":                 &messageVoiceTitle,
+	"messageWeekStartFormat":            &messageWeekStartFormat,
+	"messageWeekStartTitle":             &messageWeekStartTitle,
+}
+
+// override built-in messages with the ones in given json file
+//
+// (keys missing in the file keep their default values)
+func loadMessages(path string) error {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	overrides := map[string]string{}
+	if err := json.Unmarshal(file, &overrides); err != nil {
+		return err
+	}
+
+	unknown := []string{}
+	for name, message := range overrides {
+		if ptr, exists := _messages[name]; exists {
+			*ptr = message
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		log.Printf("*** unknown messages in %s: %v", path, unknown)
+	}
+
+	if _conf.IsVerbose {
+		log.Printf("Loaded %d message(s) from: %s", len(overrides)-len(unknown), path)
+	}
+
+	return nil
+}
+
+// load messages.json if it exists
+func setupMessages(path string) {
+	if err := loadMessages(path); err != nil && !os.IsNotExist(err) {
+		panic(fmt.Sprintf("failed to load messages from %s: %s", path, err))
+	}
+}
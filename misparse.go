@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	apiai "github.com/meinside/api.ai-go"
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// reporting misparses: confirmation messages have a button for telling that the query was misunderstood,
+// then the query, the response of api.ai, and the user's correction are saved as training data
+
+const (
+	callbackMisparse = "/misparse"
+
+	misparsePendingMinutes = 10 // confirmations (and typed corrections) will be waited for this long
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageMisparse                = "잘못 이해했어요"
+	messageMisparseAskFormat       = "'%s'(을)를 잘못 이해해서 죄송합니다.\n원래 어떤 뜻이었는지 입력해 주시면 개선에 참고하겠습니다. (예: '내일 오후 3시에 회의')"
+	messageMisparseExpired         = "시간이 많이 지난 메시지입니다. 다시 말씀해 주세요."
+	messageMisparseSaveFailed      = "기록하지 못했습니다. 잠시 후 다시 시도해 주세요."
+	messageMisparseCorrectionSaved = "알려주셔서 감사합니다. 다시 말씀해 주시면 알림을 등록해 드리겠습니다."
+)
+
+// query (and its response) which was confirmed for a user in a chat
+type misparseCandidate struct {
+	query     string
+	response  string // (JSON)
+	score     float64
+	expiresOn time.Time
+}
+
+// reported misparse waiting for a typed correction
+type pendingCorrection struct {
+	trainingDataID int64
+	expiresOn      time.Time
+}
+
+// (key: chat id/user id)
+var _misparses = struct {
+	sync.Mutex
+	candidates  map[string]misparseCandidate
+	corrections map[string]pendingCorrection
+}{
+	candidates:  map[string]misparseCandidate{},
+	corrections: map[string]pendingCorrection{},
+}
+
+// remember the query of given confirmation, and add a button for reporting a misparse of it
+func addMisparseButton(chatID, userID int64, query string, response apiai.QueryResponse, options map[string]interface{}) {
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("*** Failed to marshal api.ai response: %s", err)
+		return
+	}
+
+	_misparses.Lock()
+	_misparses.candidates[pendingKey(chatID, userID)] = misparseCandidate{
+		query:     query,
+		response:  string(encoded),
+		score:     float64(response.Result.Score),
+		expiresOn: time.Now().Add(misparsePendingMinutes * time.Minute),
+	}
+	_misparses.Unlock()
+
+	data := callbackMisparse
+	row := []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{
+			Text:         messageMisparse,
+			CallbackData: &data,
+		},
+	}
+	if markup, ok := options["reply_markup"].(bot.InlineKeyboardMarkup); ok {
+		markup.InlineKeyboard = append(markup.InlineKeyboard, row)
+		options["reply_markup"] = markup
+	} else {
+		options["reply_markup"] = bot.InlineKeyboardMarkup{InlineKeyboard: [][]bot.InlineKeyboardButton{row}}
+	}
+}
+
+// process callback query for reporting a misparse: save it and wait for a correction
+//
+// (only the user whose query was confirmed can report it)
+func processMisparseCallback(query bot.CallbackQuery) string {
+	chatID := query.Message.Chat.ID
+	userID := int64(query.From.ID)
+	key := pendingKey(chatID, userID)
+
+	_misparses.Lock()
+	candidate, exists := _misparses.candidates[key]
+	delete(_misparses.candidates, key)
+	_misparses.Unlock()
+
+	if !exists || time.Now().After(candidate.expiresOn) {
+		return messageMisparseExpired
+	}
+
+	id, saved := db.SaveTrainingData(chatID, userID, dbhelper.TrainingSourceMisparse, candidate.query, candidate.response, candidate.score)
+	if !saved {
+		return messageMisparseSaveFailed
+	}
+
+	_misparses.Lock()
+	_misparses.corrections[key] = pendingCorrection{
+		trainingDataID: id,
+		expiresOn:      time.Now().Add(misparsePendingMinutes * time.Minute),
+	}
+	_misparses.Unlock()
+
+	return fmt.Sprintf(messageMisparseAskFormat, candidate.query)
+}
+
+// check if a correction of a reported misparse is waited for from given user in given chat
+func isMisparseCorrectionText(chatID, userID int64) bool {
+	_misparses.Lock()
+	pending, exists := _misparses.corrections[pendingKey(chatID, userID)]
+	_misparses.Unlock()
+
+	return exists && time.Now().Before(pending.expiresOn)
+}
+
+// save typed correction of the reported misparse
+func processMisparseCorrection(chatID, userID int64, txt string) string {
+	key := pendingKey(chatID, userID)
+
+	_misparses.Lock()
+	pending, exists := _misparses.corrections[key]
+	delete(_misparses.corrections, key)
+	_misparses.Unlock()
+
+	if !exists {
+		return messageMisparseExpired
+	}
+
+	if !db.SetTrainingDataCorrection(chatID, pending.trainingDataID, txt) {
+		return messageMisparseSaveFailed
+	}
+
+	return messageMisparseCorrectionSaved
+}
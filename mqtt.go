@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	mqttDefaultClientID = "telegram-bot-reminder-api.ai"
+	mqttQoS             = 1
+	mqttTimeoutSeconds  = 10
+	mqttDefaultTemplate = "{{.topic}}: {{.payload}}"
+)
+
+// config for MQTT integration
+type mqttConfig struct {
+	Broker        string                   `json:"broker"` // eg. "tcp://localhost:1883"
+	ClientID      string                   `json:"client_id,omitempty"`
+	Username      string                   `json:"username,omitempty"`
+	Password      string                   `json:"password,omitempty"`
+	Subscriptions []mqttSubscriptionConfig `json:"subscriptions,omitempty"`
+	Publications  []mqttPublicationConfig  `json:"publications,omitempty"`
+}
+
+// messages of subscribed topics will be sent to the bound chat
+type mqttSubscriptionConfig struct {
+	Topic    string `json:"topic"` // wildcards(+, #) can be used
+	ChatID   int64  `json:"chat_id"`
+	Match    string `json:"match,omitempty"`    // regex for filtering payloads
+	Template string `json:"template,omitempty"` // with .topic, .payload, and .json (if payload is a json object)
+	Delay    string `json:"delay,omitempty"`    // eg. "10m" (immediate if empty)
+}
+
+// fired reminders of the chat (matching the regex) will be published to the topic
+type mqttPublicationConfig struct {
+	ChatID int64  `json:"chat_id"`
+	Match  string `json:"match,omitempty"` // regex for filtering reminder messages
+	Topic  string `json:"topic"`
+}
+
+// payload published for fired reminders
+type mqttFiredPayload struct {
+	QueueID int64     `json:"queue_id"`
+	ChatID  int64     `json:"chat_id"`
+	Message string    `json:"message"`
+	FireOn  time.Time `json:"fire_on"`
+}
+
+var _mqttClient mqtt.Client
+
+// validate MQTT config values
+func (c mqttConfig) validate() (problems []string) {
+	if c.Broker == "" {
+		problems = append(problems, "broker of mqtt is empty")
+	}
+	for _, s := range c.Subscriptions {
+		if s.Topic == "" || s.ChatID == 0 {
+			problems = append(problems, fmt.Sprintf("topic and chat_id of mqtt subscriptions should not be empty: %+v", s))
+		}
+		if _, err := regexp.Compile(s.Match); err != nil {
+			problems = append(problems, fmt.Sprintf("match of mqtt subscription '%s' is malformed: %s", s.Topic, err))
+		}
+		if s.Delay != "" {
+			if _, err := time.ParseDuration(s.Delay); err != nil {
+				problems = append(problems, fmt.Sprintf("delay of mqtt subscription '%s' is malformed: %s", s.Topic, err))
+			}
+		}
+	}
+	for _, p := range c.Publications {
+		if p.Topic == "" || p.ChatID == 0 {
+			problems = append(problems, fmt.Sprintf("topic and chat_id of mqtt publications should not be empty: %+v", p))
+		}
+		if _, err := regexp.Compile(p.Match); err != nil {
+			problems = append(problems, fmt.Sprintf("match of mqtt publication '%s' is malformed: %s", p.Topic, err))
+		}
+	}
+
+	return problems
+}
+
+// connect to the MQTT broker and subscribe to the configured topics
+func startMQTT(conf mqttConfig) {
+	clientID := conf.ClientID
+	if clientID == "" {
+		clientID = mqttDefaultClientID
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(conf.Broker).
+		SetClientID(clientID).
+		SetUsername(conf.Username).
+		SetPassword(conf.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOnConnectHandler(func(client mqtt.Client) {
+			// (re)subscribe on every (re)connection
+			for _, s := range conf.Subscriptions {
+				subscribeMQTT(client, s)
+			}
+		}).
+		SetConnectionLostHandler(func(client mqtt.Client, err error) {
+			log.Printf("*** lost connection to mqtt broker: %s", err)
+		})
+
+	_mqttClient = mqtt.NewClient(opts)
+
+	log.Printf("> Connecting to mqtt broker: %s", conf.Broker)
+
+	if token := _mqttClient.Connect(); !token.WaitTimeout(mqttTimeoutSeconds * time.Second) {
+		log.Printf("*** could not connect to mqtt broker yet (will keep retrying in background)")
+	} else if token.Error() != nil {
+		log.Printf("*** failed to connect to mqtt broker: %s", token.Error())
+
+		db.LogError(fmt.Sprintf("failed to connect to mqtt broker: %s", token.Error()))
+	}
+}
+
+// subscribe to given topic
+func subscribeMQTT(client mqtt.Client, s mqttSubscriptionConfig) {
+	match := regexp.MustCompile(s.Match) // (validated)
+	tmpl := s.Template
+	if tmpl == "" {
+		tmpl = mqttDefaultTemplate
+	}
+	delay, _ := time.ParseDuration(s.Delay) // (validated)
+
+	token := client.Subscribe(s.Topic, mqttQoS, func(_ mqtt.Client, msg mqtt.Message) {
+		payload := string(msg.Payload())
+		if !match.MatchString(payload) {
+			return
+		}
+
+		data := map[string]interface{}{
+			"topic":   msg.Topic(),
+			"payload": payload,
+		}
+		var obj map[string]interface{}
+		if err := json.Unmarshal(msg.Payload(), &obj); err == nil {
+			data["json"] = obj
+		}
+
+		message, err := executeTemplate(s.Topic, tmpl, data)
+		if err != nil {
+			log.Printf("*** failed to render mqtt message of topic %s: %s", msg.Topic(), err)
+			return
+		}
+
+		if _, _, err := sendOrEnqueue(s.ChatID, message, time.Now().Add(delay)); err != nil {
+			log.Printf("*** failed to notify mqtt message of topic %s: %s", msg.Topic(), err)
+		}
+	})
+	if token.WaitTimeout(mqttTimeoutSeconds*time.Second) && token.Error() != nil {
+		log.Printf("*** failed to subscribe to mqtt topic %s: %s", s.Topic, token.Error())
+	}
+}
+
+// publish given (fired) reminder to the matching topics
+func publishFiredReminder(q dbhelper.QueueItem) {
+	if _mqttClient == nil || !_mqttClient.IsConnected() {
+		return
+	}
+
+	for _, p := range _conf.MQTT.Publications {
+		if p.ChatID != q.ChatID || !regexp.MustCompile(p.Match).MatchString(q.Message) {
+			continue
+		}
+
+		payload, _ := json.Marshal(mqttFiredPayload{
+			QueueID: q.ID,
+			ChatID:  q.ChatID,
+			Message: q.Message,
+			FireOn:  q.FireOn,
+		})
+
+		if token := _mqttClient.Publish(p.Topic, mqttQoS, false, payload); token.WaitTimeout(mqttTimeoutSeconds*time.Second) && token.Error() != nil {
+			log.Printf("*** failed to publish to mqtt topic %s: %s", p.Topic, token.Error())
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// watching web pages for changes ("이 페이지 바뀌면 알려줘 https://...")
+//
+// contents of the page (or its part matching a CSS selector) are hashed and compared on each check,
+// and checks that fail are retried with longer intervals
+
+const (
+	commandWatch = "/watch"
+
+	pageWatchCheckIntervalSeconds = 60
+	pageWatchDefaultMinutes       = 60
+	pageWatchMinMinutes           = 5
+	pageWatchMaxMinutes           = 24 * 60
+	pageWatchMaxBackoffMinutes    = 24 * 60
+	pageWatchNotifyFailures       = 5 // chats are told (once) about this many consecutive failures
+	pageWatchMaxPerChat           = 10
+	pageWatchMaxBytes             = 2 * 1024 * 1024
+	pageWatchMaxSnippetLength     = 200
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messagePageWatchUsage = `사용법:
+/watch https://example.com/notice : 페이지가 바뀌면 알림
+/watch https://example.com/notice 30m #list : 30분마다, '#list' 부분(CSS 선택자)이 바뀌면 알림
+"이 페이지 바뀌면 알려줘 https://..." 처럼 말해도 등록됨
+/watch : 등록된 페이지 조회 및 삭제`
+	messagePageWatchAddedFormat    = "'%s' 페이지를 %s마다 확인해서, 바뀌면 알려드리겠습니다."
+	messagePageWatchExists         = "이미 등록된 페이지입니다."
+	messagePageWatchTooMany        = "더 이상 페이지를 등록할 수 없습니다."
+	messagePageWatchFetchFailed    = "페이지를 읽지 못했습니다. (공개된 http(s) 주소인지 확인해 주세요)"
+	messagePageWatchNoMatch        = "선택자에 해당하는 내용이 없습니다."
+	messagePageWatchBadInterval    = "확인 간격은 5분에서 24시간 사이로 지정해 주세요."
+	messagePageWatchNone           = "등록된 페이지가 없습니다."
+	messagePageWatchDeleteWhat     = "삭제할 페이지를 선택해 주세요."
+	messagePageWatchDeleted        = "페이지가 삭제 되었습니다."
+	messagePageWatchListItemFormat = "➤ %s (%s마다)"
+	messagePageWatchChangedFormat  = "🔔 페이지가 바뀌었습니다: %s\n%s\n\n%s"
+	messagePageWatchFailingFormat  = "'%s' 페이지를 %d번 연속으로 확인하지 못했습니다.\n(계속 확인하지만, 간격을 늘려서 확인합니다)"
+	messagePageWatchMinutesFormat  = "%d분"
+	messagePageWatchHoursFormat    = "%d시간"
+)
+
+var (
+	pageWatchAskedWords          = []string{"바뀌면", "바뀌었으면", "변경되면", "업데이트되면"}
+	pageWatchIntervalRegex       = regexp.MustCompile(`^(\d+)([mh])$`)
+	pageWatchKoreanIntervalRegex = regexp.MustCompile(`(\d+)\s*(분|시간)\s*마다`)
+)
+
+// check if given text asks for watching a page ("이 페이지 바뀌면 알려줘 https://...")
+func isPageWatchText(txt string) bool {
+	if strings.HasPrefix(txt, commandWatch) {
+		return true
+	}
+
+	return urlRegex.MatchString(txt) && containsAny(txt, pageWatchAskedWords)
+}
+
+// process watch command or text
+func processPageWatchText(chatID int64, txt string, options map[string]interface{}) string {
+	url := urlRegex.FindString(txt)
+	if url == "" {
+		if strings.TrimSpace(strings.TrimPrefix(txt, commandWatch)) != "" {
+			return messagePageWatchUsage
+		}
+
+		return listPageWatches(chatID, options)
+	}
+
+	minutes := pageWatchDefaultMinutes
+	selector := ""
+	if strings.HasPrefix(txt, commandWatch) {
+		// "/watch <url> [interval] [selector]"
+		params := strings.Fields(strings.TrimSpace(strings.TrimPrefix(txt, commandWatch)))[1:]
+		if len(params) > 0 {
+			if matches := pageWatchIntervalRegex.FindStringSubmatch(params[0]); matches != nil {
+				minutes = intervalMinutes(matches[1], matches[2] == "h")
+				params = params[1:]
+			}
+		}
+		selector = strings.Join(params, " ")
+	} else if matches := pageWatchKoreanIntervalRegex.FindStringSubmatch(txt); matches != nil {
+		// "30분마다", "2시간마다"
+		minutes = intervalMinutes(matches[1], matches[2] == "시간")
+	}
+
+	return addPageWatch(chatID, url, selector, minutes)
+}
+
+// minutes of given interval
+func intervalMinutes(number string, hours bool) int {
+	n, _ := strconv.Atoi(number)
+	if hours {
+		return n * 60
+	}
+	return n
+}
+
+// register given page (or its part matching given selector) for given chat
+func addPageWatch(chatID int64, url, selector string, minutes int) string {
+	if minutes < pageWatchMinMinutes || minutes > pageWatchMaxMinutes {
+		return messagePageWatchBadInterval
+	}
+	if len(db.PageWatches(chatID)) >= pageWatchMaxPerChat {
+		return messagePageWatchTooMany
+	}
+
+	title, content, err := fetchPageContent(url, selector)
+	if err != nil {
+		log.Printf("*** failed to fetch page %s for watching: %s", url, err)
+
+		// (details of errors are not shown, as they might tell about the network of the bot)
+		if err == errPageWatchNoMatch {
+			return messagePageWatchNoMatch
+		}
+		return messagePageWatchFetchFailed
+	}
+
+	if _, saved := db.SavePageWatch(dbhelper.PageWatch{
+		ChatID:          chatID,
+		URL:             url,
+		Selector:        selector,
+		Title:           title,
+		IntervalMinutes: minutes,
+		ContentHash:     hashPageContent(content),
+		NextCheckOn:     time.Now().Add(time.Duration(minutes) * time.Minute),
+	}); !saved {
+		return messagePageWatchExists
+	}
+
+	return fmt.Sprintf(messagePageWatchAddedFormat, pageWatchTitle(title, url), describeMinutes(minutes))
+}
+
+// list page watches of given chat with a keyboard for deleting them
+func listPageWatches(chatID int64, options map[string]interface{}) string {
+	watches := db.PageWatches(chatID)
+	if len(watches) <= 0 {
+		return messagePageWatchNone + "\n\n" + messagePageWatchUsage
+	}
+
+	keys := make(map[string]string)
+	for _, w := range watches {
+		keys[fmt.Sprintf(messagePageWatchListItemFormat, pageWatchTitle(w.Title, w.URL), describeMinutes(w.IntervalMinutes))] = fmt.Sprintf("%s %d", commandWatch, w.ID)
+	}
+	buttons := bot.NewInlineKeyboardButtonsAsRowsWithCallbackData(keys)
+
+	cancel := commandCancel
+	buttons = append(buttons, []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{Text: messageCancel, CallbackData: &cancel},
+	})
+
+	options["reply_markup"] = bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+
+	return messagePageWatchDeleteWhat
+}
+
+// process callback query for deleting a page watch
+func processPageWatchCallback(query bot.CallbackQuery, txt string) string {
+	if watchID, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(txt, commandWatch)), 10, 64); err == nil {
+		if db.DeletePageWatch(query.Message.Chat.ID, watchID) {
+			return messagePageWatchDeleted
+		}
+		log.Printf("*** Failed to delete page watch")
+	} else {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+	}
+
+	return messageError
+}
+
+// check page watches periodically
+func monitorPageWatches(ticker *time.Ticker, client *bot.Bot) {
+	for range ticker.C {
+		checkPageWatches(client)
+	}
+}
+
+// check due page watches and notify chats of changed ones
+func checkPageWatches(client *bot.Bot) {
+	now := time.Now()
+
+	for _, w := range db.DuePageWatches(now) {
+		_, content, err := fetchPageContent(w.URL, w.Selector)
+		if err != nil {
+			numFailures := w.NumFailures + 1
+
+			// (back off exponentially)
+			backoff := w.IntervalMinutes << uint(numFailures)
+			if backoff <= 0 || backoff > pageWatchMaxBackoffMinutes {
+				backoff = pageWatchMaxBackoffMinutes
+			}
+			db.UpdatePageWatchFailed(w.ID, now.Add(time.Duration(backoff)*time.Minute))
+
+			if _isVerbose {
+				log.Printf("*** failed to check page %s (%d times): %s", w.URL, numFailures, err)
+			}
+
+			if numFailures == pageWatchNotifyFailures {
+				message := fmt.Sprintf(messagePageWatchFailingFormat, pageWatchTitle(w.Title, w.URL), numFailures)
+				if sent := client.SendMessage(w.ChatID, message, map[string]interface{}{"disable_web_page_preview": true}); !sent.Ok {
+					log.Printf("*** failed to send page watch failure to chat %d: %s", w.ChatID, *sent.Description)
+				}
+			}
+			continue
+		}
+
+		hash := hashPageContent(content)
+		changed := w.ContentHash != "" && hash != w.ContentHash
+
+		db.UpdatePageWatchChecked(w.ID, hash, changed, now.Add(time.Duration(w.IntervalMinutes)*time.Minute))
+
+		if changed {
+			message := fmt.Sprintf(messagePageWatchChangedFormat, pageWatchTitle(w.Title, w.URL), w.URL, pageContentSnippet(content))
+			if sent := client.SendMessage(w.ChatID, message, map[string]interface{}{"disable_web_page_preview": true}); !sent.Ok {
+				log.Printf("*** failed to send page change to chat %d: %s", w.ChatID, *sent.Description)
+			}
+		}
+	}
+}
+
+// error for pages which have no part matching the selector
+var errPageWatchNoMatch = errors.New("no part matching the selector")
+
+// fetch given page and return its title and (whitespace-normalized) text of the part matching given selector
+//
+// (like other fetched pages, only the ones on public addresses are fetched)
+func fetchPageContent(url, selector string) (title, content string, err error) {
+	body, err := fetchPage(url, pageWatchMaxBytes)
+	if err != nil {
+		return "", "", err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	title = strings.Join(strings.Fields(doc.Find("title").First().Text()), " ")
+
+	// (not visible, and often changes on every request)
+	doc.Find("script, style, noscript").Remove()
+
+	if selector == "" {
+		selector = "body"
+	}
+	selection := doc.Find(selector)
+	if selection.Length() <= 0 {
+		return title, "", errPageWatchNoMatch
+	}
+
+	return title, strings.Join(strings.Fields(selection.Text()), " "), nil
+}
+
+// hash of given page content
+func hashPageContent(content string) string {
+	hash := sha256.Sum256([]byte(content))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// beginning of given page content
+func pageContentSnippet(content string) string {
+	if runes := []rune(content); len(runes) > pageWatchMaxSnippetLength {
+		return string(runes[:pageWatchMaxSnippetLength]) + "…"
+	}
+	return content
+}
+
+// displayable title of a watched page
+func pageWatchTitle(title, url string) string {
+	if title != "" {
+		return title
+	}
+	return url
+}
+
+// describe given minutes ("30분", "2시간")
+func describeMinutes(minutes int) string {
+	if minutes%60 == 0 {
+		return fmt.Sprintf(messagePageWatchHoursFormat, minutes/60)
+	}
+	return fmt.Sprintf(messagePageWatchMinutesFormat, minutes)
+}
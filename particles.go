@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// trailing imperative/quotative endings of extracted reminder texts, and what to replace them with
+//
+// (checked in order, so longer ones come first)
+var imperativeEndings = []struct {
+	ending      string
+	replacement string
+}{
+	{"해주세요", "하기"},
+	{"해 주세요", "하기"},
+	{"하라고요", "하기"},
+	{"하라구요", "하기"},
+	{"하세요", "하기"},
+	{"하라고", "하기"},
+	{"하라구", "하기"},
+	{"하라는", "하기"},
+	{"하기로", "하기"},
+	{"해 줘", "하기"},
+	{"해줘", "하기"},
+	{"해요", "하기"},
+	{"해라", "하기"},
+	{"하라", "하기"},
+	{"하자", "하기"},
+	{"할 것", "하기"},
+	{"라고요", "기"},
+	{"라구요", "기"},
+	{"세요", "기"},
+	{"라고", "기"},
+	{"라구", "기"},
+	{"라는", "기"},
+}
+
+// tidy up the trailing imperative particles of given reminder text, for reading naturally in lists
+//
+// (eg. "뉴스 보라고" => "뉴스 보기", "책 읽으라고" => "책 읽기", "운동하세요" => "운동하기")
+func tidyReminderText(text string) string {
+	trimmed := strings.TrimRight(text, " .!~")
+
+	for _, e := range imperativeEndings {
+		if !strings.HasSuffix(trimmed, e.ending) {
+			continue
+		}
+
+		stem := strings.TrimSuffix(trimmed, e.ending)
+		if strings.TrimSpace(stem) == "" {
+			return text // (nothing left to be tidied up)
+		}
+
+		// (eg. "읽으" => "읽")
+		if e.replacement == "기" && strings.HasSuffix(stem, "으") {
+			if r, _ := utf8.DecodeLastRuneInString(strings.TrimSuffix(stem, "으")); hasFinalConsonant(r) {
+				stem = strings.TrimSuffix(stem, "으")
+			}
+		}
+
+		// (only when the stem ends with a hangul syllable, eg. not "PR 리뷰 세요")
+		if r, _ := utf8.DecodeLastRuneInString(stem); !isHangulSyllable(r) {
+			return text
+		}
+
+		return stem + e.replacement
+	}
+
+	return text
+}
+
+// displayable text of given reminder text, with the preference of given chat
+//
+// (original texts are kept as they are, so turning the setting off restores them)
+func reminderText(chatID int64, text string) string {
+	if db.ChatSettings(chatID).TidyText {
+		return tidyReminderText(text)
+	}
+	return text
+}
+
+// check if given rune is a composed hangul syllable
+func isHangulSyllable(r rune) bool {
+	return r >= 0xAC00 && r <= 0xD7A3
+}
+
+// check if given hangul syllable has a final consonant (batchim)
+func hasFinalConsonant(r rune) bool {
+	return isHangulSyllable(r) && (r-0xAC00)%28 != 0
+}
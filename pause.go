@@ -0,0 +1,100 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	commandPause  = "/pause"
+	commandResume = "/resume"
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messagePause             = "일시중지"
+	messageResume            = "다시 시작"
+	messagePauseWhat         = "어떤 알림을 일시중지 하시겠습니까?"
+	messageResumeWhat        = "어떤 알림을 다시 시작 하시겠습니까?"
+	messageNoActiveReminders = "일시중지할 알림이 없습니다."
+	messageNoPausedReminders = "일시중지된 알림이 없습니다."
+	messageReminderPaused    = "알림이 일시중지 되었습니다."
+	messageReminderResumed   = "알림이 다시 시작 되었습니다."
+)
+
+// process /pause command: show reminders which can be paused
+func processPauseCommand(chatID int64, options map[string]interface{}) string {
+	reminders := []dbhelper.QueueItem{}
+	for _, r := range db.UndeliveredQueueItems(chatID) {
+		if r.Paused == dbhelper.NotPaused {
+			reminders = append(reminders, r)
+		}
+	}
+
+	if len(reminders) <= 0 {
+		return messageNoActiveReminders
+	}
+
+	options["reply_markup"] = reminderSelectionKeyboard(reminders, commandPause)
+
+	return messagePauseWhat
+}
+
+// process /resume command: show reminders paused by the user
+func processResumeCommand(chatID int64, options map[string]interface{}) string {
+	reminders := []dbhelper.QueueItem{}
+	for _, r := range db.UndeliveredQueueItems(chatID) {
+		if r.Paused == dbhelper.PausedByUser {
+			reminders = append(reminders, r)
+		}
+	}
+
+	if len(reminders) <= 0 {
+		return messageNoPausedReminders
+	}
+
+	options["reply_markup"] = reminderSelectionKeyboard(reminders, commandResume)
+
+	return messageResumeWhat
+}
+
+// process callback query for pausing a reminder
+func processPauseCallback(query bot.CallbackQuery, txt string) string {
+	if queueID, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(txt, commandPause)), 10, 64); err == nil {
+		if db.SetQueueItemPaused(query.Message.Chat.ID, queueID, dbhelper.PausedByUser) {
+			return messageReminderPaused
+		}
+		log.Printf("*** Failed to pause reminder")
+	} else {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+	}
+
+	return messageError
+}
+
+// process callback query for resuming a reminder
+func processResumeCallback(query bot.CallbackQuery, txt string) string {
+	if queueID, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(txt, commandResume)), 10, 64); err == nil {
+		if db.SetQueueItemPaused(query.Message.Chat.ID, queueID, dbhelper.NotPaused) {
+			// recurring reminders resume from their next occurrence
+			if q, exists := db.QueueItem(query.Message.Chat.ID, queueID); exists && q.Recurrence != "" && q.FireOn.Before(time.Now()) {
+				if next, err := nextOccurrenceAfterNow(q.Recurrence, scheduledTimeOf(q)); err == nil {
+					db.ScheduleQueueItem(q.ChatID, q.ID, next)
+				}
+			}
+
+			return messageReminderResumed
+		}
+		log.Printf("*** Failed to resume reminder")
+	} else {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+	}
+
+	return messageError
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	pollMinOptions           = 2
+	pollMaxOptions           = 10 // (limit of Telegram)
+	pollPendingMinutes       = 30 // options are kept this long while api.ai asks for the rest
+	pollDefaultWindowMinutes = 60
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messagePollResultTitleFormat = "📊 투표 결과: %s"
+	messagePollResultLineFormat  = "➤ %s: %d표"
+	messagePollResultWinner      = " 🏆"
+	messagePollNoVotes           = "(투표한 사람이 없습니다)"
+	messagePollResultUnavailable = "📊 투표 결과를 가져오지 못했습니다: %s"
+)
+
+// eg. "내일 점심 투표 올려줘: 김치찌개, 피자, 초밥"
+var pollTextRegex = regexp.MustCompile(`^(.*투표.*?)\s*[:：]\s*(.+)$`)
+
+// poll options waiting for the reminder to be confirmed
+type pendingPoll struct {
+	options   []string
+	expiresOn time.Time
+}
+
+// poll options of reminders not confirmed yet (key: chat id/user id)
+var _pendingPolls = struct {
+	sync.Mutex
+	polls map[string]pendingPoll
+}{
+	polls: map[string]pendingPoll{},
+}
+
+// split given text into the query (for api.ai) and poll options
+func parsePollText(txt string) (query string, options []string, ok bool) {
+	matches := pollTextRegex.FindStringSubmatch(strings.TrimSpace(txt))
+	if matches == nil {
+		return txt, nil, false
+	}
+
+	for _, option := range strings.FieldsFunc(matches[2], func(r rune) bool {
+		return r == ',' || r == '，' || r == '/'
+	}) {
+		if option = strings.TrimSpace(option); option != "" {
+			options = append(options, option)
+		}
+	}
+	if len(options) < pollMinOptions || len(options) > pollMaxOptions {
+		return txt, nil, false
+	}
+
+	return matches[1], options, true
+}
+
+// keep given poll options until the reminder of given user in given chat is confirmed
+func setPendingPoll(chatID, userID int64, options []string) {
+	_pendingPolls.Lock()
+	_pendingPolls.polls[pendingKey(chatID, userID)] = pendingPoll{
+		options:   options,
+		expiresOn: time.Now().Add(pollPendingMinutes * time.Minute),
+	}
+	_pendingPolls.Unlock()
+}
+
+// take pending poll options of given user in given chat
+func takePendingPoll(chatID, userID int64) (options []string, exists bool) {
+	key := pendingKey(chatID, userID)
+
+	_pendingPolls.Lock()
+	defer _pendingPolls.Unlock()
+
+	pending, exists := _pendingPolls.polls[key]
+	if !exists {
+		return nil, false
+	}
+	delete(_pendingPolls.polls, key)
+
+	if time.Now().After(pending.expiresOn) {
+		return nil, false
+	}
+
+	return pending.options, true
+}
+
+// make given queue item a poll, if there are pending poll options of its creator in its chat
+func attachPendingPoll(item *dbhelper.QueueItem) {
+	options, exists := takePendingPoll(item.ChatID, item.UserID)
+	if !exists {
+		return
+	}
+
+	if pollID, saved := db.SavePoll(dbhelper.Poll{
+		ChatID:   item.ChatID,
+		Question: item.Message,
+		Options:  options,
+	}); saved {
+		item.Kind = dbhelper.QueueKindPoll
+		item.RefID = pollID
+	}
+}
+
+// send a native poll for given queue item
+func sendPoll(client *bot.Bot, q dbhelper.QueueItem) (sent bot.APIResponseMessage) {
+	p, exists := db.Poll(q.RefID)
+	if !exists {
+		// (send it as an ordinary message)
+		message, options := deliveryMessage(client, q)
+		return client.SendMessage(q.ChatID, message, options)
+	}
+
+	if sent = client.SendPoll(q.ChatID, p.Question, p.Options, map[string]interface{}{
+		"is_anonymous": false,
+	}); sent.Ok {
+		closesOn := time.Now().Add(time.Duration(_conf.PollWindowMinutes) * time.Minute)
+
+		db.SetPollSent(p.ID, sent.Result.MessageID, closesOn)
+
+		if _, enqueued := db.EnqueueItem(dbhelper.QueueItem{
+			ChatID:  q.ChatID,
+			UserID:  q.UserID,
+			Message: fmt.Sprintf(messagePollResultTitleFormat, p.Question), // (replaced with the results on delivery)
+			FireOn:  closesOn,
+			Kind:    dbhelper.QueueKindPollResult,
+			RefID:   p.ID,
+		}); !enqueued {
+			log.Printf("*** failed to enqueue results of poll %d", p.ID)
+		}
+	}
+
+	return sent
+}
+
+// close the poll of given queue item and build a message of its results
+func pollResultMessage(client *bot.Bot, q dbhelper.QueueItem) string {
+	p, exists := db.Poll(q.RefID)
+	if !exists || p.MessageID == 0 {
+		return q.Message
+	}
+
+	stopped := client.StopPoll(p.ChatID, p.MessageID, map[string]interface{}{})
+	if !stopped.Ok || stopped.Result == nil {
+		reason := ""
+		if stopped.Description != nil {
+			reason = *stopped.Description
+		}
+		return fmt.Sprintf(messagePollResultUnavailable, reason)
+	}
+
+	max := 0
+	for _, option := range stopped.Result.Options {
+		if option.VoterCount > max {
+			max = option.VoterCount
+		}
+	}
+
+	lines := []string{fmt.Sprintf(messagePollResultTitleFormat, p.Question)}
+	for _, option := range stopped.Result.Options {
+		line := fmt.Sprintf(messagePollResultLineFormat, option.Text, option.VoterCount)
+		if max > 0 && option.VoterCount == max {
+			line += messagePollResultWinner
+		}
+		lines = append(lines, line)
+	}
+	if max == 0 {
+		lines = append(lines, messagePollNoVotes)
+	}
+
+	return strings.Join(lines, "\n")
+}
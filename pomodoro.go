@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	commandPomodoro = "/pomodoro"
+
+	pomodoroParamPause  = "pause"
+	pomodoroParamResume = "resume"
+	pomodoroParamStop   = "stop"
+	pomodoroParamStats  = "기록"
+
+	pomodoroDefaultWorkMinutes  = 25
+	pomodoroDefaultBreakMinutes = 5
+	pomodoroMaxMinutes          = 180
+	pomodoroStatsDays           = 7
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messagePomodoroUsage = `사용법:
+/pomodoro : 뽀모도로 시작 (25분 집중 / 5분 휴식)
+/pomodoro 50 10 : 50분 집중 / 10분 휴식으로 시작
+/pomodoro 기록 : 최근 완료한 뽀모도로 수`
+	messagePomodoroStartedFormat  = "🍅 뽀모도로를 시작합니다. %d분 동안 집중해 보세요! (%s까지)"
+	messagePomodoroWorkDoneFormat = "🍅 집중 시간 끝! %d분 쉬세요."
+	messagePomodoroBreakDoneFmt   = "☕ 휴식 끝! %d분 동안 다시 집중해 보세요."
+	messagePomodoroStatusFormat   = "🍅 뽀모도로 진행 중: %s (%s)"
+	messagePomodoroPausedFormat   = "⏸ 뽀모도로 일시중지: %s %s 남음"
+	messagePomodoroResumedFormat  = "▶️ 뽀모도로 다시 시작: %s (%s까지)"
+	messagePomodoroStoppedFormat  = "⏹ 뽀모도로를 마쳤습니다. 오늘 완료한 뽀모도로: %d개"
+	messagePomodoroNotRunning     = "진행 중인 뽀모도로가 없습니다."
+	messagePomodoroInvalidMinutes = "집중/휴식 시간은 1분에서 180분 사이로 지정해 주세요."
+	messagePomodoroStatsTitle     = "최근 완료한 뽀모도로:"
+	messagePomodoroStatsLineFmt   = "➤ %s: %s %d개"
+	messagePomodoroPause          = "⏸ 일시중지"
+	messagePomodoroResume         = "▶️ 다시 시작"
+	messagePomodoroStop           = "⏹ 그만하기"
+	messagePomodoroPhaseWork      = "집중"
+	messagePomodoroPhaseBreak     = "휴식"
+)
+
+// process /pomodoro command
+func processPomodoroCommand(chatID, userID int64, txt string, options map[string]interface{}) string {
+	params := strings.Fields(strings.TrimPrefix(txt, commandPomodoro))
+
+	if len(params) == 1 && params[0] == pomodoroParamStats {
+		return pomodoroStats(chatID)
+	}
+
+	// show status of the running one
+	if p, exists := db.Pomodoro(chatID); exists && len(params) == 0 {
+		options["reply_markup"] = pomodoroControls(p.Paused)
+
+		return pomodoroStatus(p)
+	}
+
+	workMinutes, breakMinutes := pomodoroDefaultWorkMinutes, pomodoroDefaultBreakMinutes
+	if len(params) > 0 {
+		var err1, err2 error
+		workMinutes, err1 = strconv.Atoi(params[0])
+		if len(params) > 1 {
+			breakMinutes, err2 = strconv.Atoi(params[1])
+		}
+		if err1 != nil || err2 != nil {
+			return messagePomodoroUsage
+		}
+		if workMinutes < 1 || workMinutes > pomodoroMaxMinutes || breakMinutes < 1 || breakMinutes > pomodoroMaxMinutes {
+			return messagePomodoroInvalidMinutes
+		}
+	}
+
+	// (replaces the running one)
+	stopPomodoro(chatID)
+
+	p := dbhelper.Pomodoro{
+		ChatID:       chatID,
+		UserID:       userID,
+		WorkMinutes:  workMinutes,
+		BreakMinutes: breakMinutes,
+	}
+	fireOn, started := startPomodoroPhase(&p, dbhelper.PomodoroPhaseWork, time.Duration(workMinutes)*time.Minute)
+	if !started {
+		return messageSaveFailed
+	}
+
+	options["reply_markup"] = pomodoroControls(false)
+
+	return fmt.Sprintf(messagePomodoroStartedFormat, workMinutes, clockOf(chatID, fireOn))
+}
+
+// enqueue the end of given phase and save the session
+func startPomodoroPhase(p *dbhelper.Pomodoro, phase string, duration time.Duration) (fireOn time.Time, result bool) {
+	message := fmt.Sprintf(messagePomodoroWorkDoneFormat, p.BreakMinutes)
+	if phase == dbhelper.PomodoroPhaseBreak {
+		message = fmt.Sprintf(messagePomodoroBreakDoneFmt, p.WorkMinutes)
+	}
+
+	fireOn = time.Now().Add(duration)
+	queueID, enqueued := db.EnqueueItem(dbhelper.QueueItem{
+		ChatID:  p.ChatID,
+		UserID:  p.UserID,
+		Message: message,
+		FireOn:  fireOn,
+		Kind:    dbhelper.QueueKindPomodoro,
+	})
+	if !enqueued {
+		return fireOn, false
+	}
+
+	p.Phase = phase
+	p.Paused = false
+	p.QueueID = queueID
+	p.RemainingSeconds = 0
+
+	return fireOn, db.SavePomodoro(*p)
+}
+
+// move to the next phase when a phase of given chat is over (called after delivering its queue item)
+func advancePomodoro(q dbhelper.QueueItem) {
+	p, exists := db.Pomodoro(q.ChatID)
+	if !exists || p.QueueID != q.ID {
+		return // stopped or stale
+	}
+
+	next, minutes := dbhelper.PomodoroPhaseWork, p.WorkMinutes
+	if p.Phase == dbhelper.PomodoroPhaseWork {
+		db.SavePomodoroCompletion(q.ChatID)
+
+		next, minutes = dbhelper.PomodoroPhaseBreak, p.BreakMinutes
+	}
+
+	if _, started := startPomodoroPhase(&p, next, time.Duration(minutes)*time.Minute); !started {
+		log.Printf("*** failed to start next pomodoro phase of chat %d", q.ChatID)
+	}
+}
+
+// stop the pomodoro of given chat, returns false if there was none
+func stopPomodoro(chatID int64) bool {
+	p, exists := db.Pomodoro(chatID)
+	if !exists {
+		return false
+	}
+
+	if p.QueueID > 0 {
+		db.DeleteQueueItem(chatID, p.QueueID)
+	}
+
+	return db.DeletePomodoro(chatID)
+}
+
+// process callback query of pomodoro controls
+func processPomodoroCallback(query bot.CallbackQuery, txt string) (message string, keyboard interface{}) {
+	chatID := query.Message.Chat.ID
+	param := strings.TrimSpace(strings.TrimPrefix(txt, commandPomodoro))
+
+	p, exists := db.Pomodoro(chatID)
+	if !exists {
+		return messagePomodoroNotRunning, nil
+	}
+
+	switch param {
+	case pomodoroParamPause:
+		if p.Paused {
+			break
+		}
+
+		q, exists := db.QueueItem(chatID, p.QueueID)
+		if !exists {
+			return messagePomodoroNotRunning, nil
+		}
+		remaining := time.Until(q.FireOn)
+		if remaining < 0 {
+			remaining = 0
+		}
+		db.DeleteQueueItem(chatID, p.QueueID)
+
+		p.Paused = true
+		p.QueueID = 0
+		p.RemainingSeconds = int(remaining.Seconds())
+		if !db.SavePomodoro(p) {
+			return messageError, nil
+		}
+
+		return fmt.Sprintf(messagePomodoroPausedFormat, pomodoroPhaseName(p.Phase), formatTimerDuration(remaining)), pomodoroControls(true)
+	case pomodoroParamResume:
+		if !p.Paused {
+			break
+		}
+
+		fireOn, started := startPomodoroPhase(&p, p.Phase, time.Duration(p.RemainingSeconds)*time.Second)
+		if !started {
+			return messageError, nil
+		}
+
+		return fmt.Sprintf(messagePomodoroResumedFormat, pomodoroPhaseName(p.Phase), clockOf(chatID, fireOn)), pomodoroControls(false)
+	case pomodoroParamStop:
+		stopPomodoro(chatID)
+
+		today := time.Now().Format("2006-01-02")
+		return fmt.Sprintf(messagePomodoroStoppedFormat, db.PomodoroCompletionsByDay(chatID, truncateToDay(time.Now()))[today]), nil
+	default:
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError, nil
+	}
+
+	// nothing changed
+	return pomodoroStatus(p), pomodoroControls(p.Paused)
+}
+
+// status of given pomodoro
+func pomodoroStatus(p dbhelper.Pomodoro) string {
+	if p.Paused {
+		return fmt.Sprintf(messagePomodoroPausedFormat, pomodoroPhaseName(p.Phase), formatTimerDuration(time.Duration(p.RemainingSeconds)*time.Second))
+	}
+
+	remaining := time.Duration(0)
+	if q, exists := db.QueueItem(p.ChatID, p.QueueID); exists {
+		remaining = time.Until(q.FireOn)
+	}
+	return fmt.Sprintf(messagePomodoroStatusFormat, pomodoroPhaseName(p.Phase), formatCountdown(remaining))
+}
+
+// inline keyboard for controlling pomodoros
+func pomodoroControls(paused bool) bot.InlineKeyboardMarkup {
+	toggle, toggleText := fmt.Sprintf("%s %s", commandPomodoro, pomodoroParamPause), messagePomodoroPause
+	if paused {
+		toggle, toggleText = fmt.Sprintf("%s %s", commandPomodoro, pomodoroParamResume), messagePomodoroResume
+	}
+	stop := fmt.Sprintf("%s %s", commandPomodoro, pomodoroParamStop)
+
+	return bot.InlineKeyboardMarkup{
+		InlineKeyboard: [][]bot.InlineKeyboardButton{
+			[]bot.InlineKeyboardButton{
+				bot.InlineKeyboardButton{Text: toggleText, CallbackData: &toggle},
+				bot.InlineKeyboardButton{Text: messagePomodoroStop, CallbackData: &stop},
+			},
+		},
+	}
+}
+
+// numbers of completed pomodoros of recent days
+func pomodoroStats(chatID int64) string {
+	today := truncateToDay(time.Now())
+	counts := db.PomodoroCompletionsByDay(chatID, today.AddDate(0, 0, -(pomodoroStatsDays-1)))
+
+	lines := []string{messagePomodoroStatsTitle}
+	for i := pomodoroStatsDays - 1; i >= 0; i-- {
+		day := today.AddDate(0, 0, -i)
+		count := counts[day.Format("2006-01-02")]
+
+		lines = append(lines, fmt.Sprintf(messagePomodoroStatsLineFmt, day.Format("1/2"), strings.Repeat("🍅", count), count))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// displayable name of given phase
+func pomodoroPhaseName(phase string) string {
+	if phase == dbhelper.PomodoroPhaseBreak {
+		return messagePomodoroPhaseBreak
+	}
+	return messagePomodoroPhaseWork
+}
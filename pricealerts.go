@@ -0,0 +1,311 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// threshold alerts of prices ("달러 환율 1300 넘으면 알려줘")
+//
+// prices are polled from the data sources periodically,
+// and when one crosses the threshold, an alert is enqueued (and delivered like other reminders)
+
+const (
+	commandPrice = "/price"
+
+	priceAlertMaxPerChat             = 20
+	priceAlertDefaultIntervalMinutes = 5
+)
+
+// a price which can be alerted
+type priceTarget struct {
+	source string
+	symbol string
+	name   string
+}
+
+// messages (can be overridden with messages.json)
+var (
+	messagePriceAlertUsage = `사용법:
+"달러 환율 1300 넘으면 알려줘", "비트코인 1억 밑으로 떨어지면 알려줘" 처럼 말하면 등록됨
+/price <fx|crypto|stock> <종목> <'>' 또는 '<'> <값> : 직접 등록 (예: /price stock 005930.KS > 80000)
+/price : 등록된 알림 조회 및 삭제`
+	messagePriceAlertAddedFormat   = "%s이(가) %s %s 알려드리겠습니다. (현재: %s)"
+	messagePriceAlertAbove         = "이상이 되면"
+	messagePriceAlertBelow         = "이하가 되면"
+	messagePriceAlertFiredFormat   = "📈 %s이(가) %s %s. (기준: %s)"
+	messagePriceAlertFiredAbove    = "올랐습니다"
+	messagePriceAlertFiredBelow    = "내려갔습니다"
+	messagePriceAlertFetchFailed   = "가격을 가져오지 못했습니다: %s"
+	messagePriceAlertUnknownSource = "지원하지 않는 데이터입니다. (fx, crypto, stock 중 하나)"
+	messagePriceAlertTooMany       = "더 이상 가격 알림을 등록할 수 없습니다."
+	messagePriceAlertNone          = "등록된 가격 알림이 없습니다."
+	messagePriceAlertDeleteWhat    = "삭제할 가격 알림을 선택해 주세요."
+	messagePriceAlertDeleted       = "가격 알림이 삭제 되었습니다."
+	messagePriceAlertListItemFmt   = "➤ %s %s %s"
+)
+
+// names of prices in texts (checked in order)
+var priceAliases = []struct {
+	words  []string
+	target priceTarget
+}{
+	{[]string{"달러", "USD"}, priceTarget{priceSourceFX, "USD/KRW", "달러 환율"}},
+	{[]string{"엔화", "엔 환율", "JPY"}, priceTarget{priceSourceFX, "100JPY/KRW", "엔화 환율(100엔)"}},
+	{[]string{"유로", "EUR"}, priceTarget{priceSourceFX, "EUR/KRW", "유로 환율"}},
+	{[]string{"위안", "CNY"}, priceTarget{priceSourceFX, "CNY/KRW", "위안 환율"}},
+	{[]string{"비트코인", "BTC"}, priceTarget{priceSourceCrypto, "BTC", "비트코인"}},
+	{[]string{"이더리움", "ETH"}, priceTarget{priceSourceCrypto, "ETH", "이더리움"}},
+	{[]string{"리플", "XRP"}, priceTarget{priceSourceCrypto, "XRP", "리플"}},
+	{[]string{"솔라나", "SOL"}, priceTarget{priceSourceCrypto, "SOL", "솔라나"}},
+	{[]string{"삼성전자"}, priceTarget{priceSourceStock, "005930.KS", "삼성전자"}},
+	{[]string{"하이닉스"}, priceTarget{priceSourceStock, "000660.KS", "SK하이닉스"}},
+	{[]string{"애플"}, priceTarget{priceSourceStock, "AAPL", "애플"}},
+	{[]string{"테슬라"}, priceTarget{priceSourceStock, "TSLA", "테슬라"}},
+	{[]string{"엔비디아"}, priceTarget{priceSourceStock, "NVDA", "엔비디아"}},
+	{[]string{"마이크로소프트"}, priceTarget{priceSourceStock, "MSFT", "마이크로소프트"}},
+}
+
+var (
+	priceThresholdRegex = regexp.MustCompile(`([0-9][0-9,]*(?:\.[0-9]+)?)\s*(만|억)?\s*(?:원|달러|불)?\s*(?:을|를|이|가)?\s*(넘으면|넘어가면|넘어서면|돌파하면|이상이면|이상|오르면|올라가면|밑으로|아래로|이하로|이하면|이하|미만이면|떨어지면|내려가면)`)
+	priceBelowWords     = []string{"밑으로", "아래로", "이하로", "이하면", "이하", "미만이면", "떨어지면", "내려가면"}
+)
+
+// check if given text asks for a price alert ("달러 환율 1300 넘으면 알려줘")
+func isPriceAlertText(txt string) bool {
+	if strings.HasPrefix(txt, commandPrice) {
+		return true
+	}
+
+	_, found := priceTargetIn(txt)
+	return found && priceThresholdRegex.MatchString(txt)
+}
+
+// process price command or text
+func processPriceAlertText(chatID, userID int64, txt string, options map[string]interface{}) string {
+	if strings.HasPrefix(txt, commandPrice) {
+		params := strings.Fields(strings.TrimPrefix(txt, commandPrice))
+		switch len(params) {
+		case 0:
+			return listPriceAlerts(chatID, options)
+		case 4:
+			// "/price <source> <symbol> <'>'|'<'> <value>"
+			threshold, err := strconv.ParseFloat(strings.ReplaceAll(params[3], ",", ""), 64)
+			if err != nil || (params[2] != ">" && params[2] != "<") {
+				return messagePriceAlertUsage
+			}
+
+			symbol := strings.ToUpper(params[1])
+			return addPriceAlert(chatID, userID, priceTarget{source: params[0], symbol: symbol, name: symbol}, params[2] == ">", threshold)
+		}
+
+		return messagePriceAlertUsage
+	}
+
+	target, _ := priceTargetIn(txt)
+	matches := priceThresholdRegex.FindStringSubmatch(txt)
+	if matches == nil {
+		return messagePriceAlertUsage
+	}
+	threshold, err := strconv.ParseFloat(strings.ReplaceAll(matches[1], ",", ""), 64)
+	if err != nil {
+		return messagePriceAlertUsage
+	}
+	switch matches[2] {
+	case "만":
+		threshold *= 10000
+	case "억":
+		threshold *= 100000000
+	}
+
+	return addPriceAlert(chatID, userID, target, !containsAny(matches[3], priceBelowWords), threshold)
+}
+
+// price mentioned in given text
+func priceTargetIn(txt string) (priceTarget, bool) {
+	upper := strings.ToUpper(txt)
+	for _, alias := range priceAliases {
+		if containsAny(upper, alias.words) {
+			return alias.target, true
+		}
+	}
+	return priceTarget{}, false
+}
+
+// register a price alert for given chat
+func addPriceAlert(chatID, userID int64, target priceTarget, above bool, threshold float64) string {
+	source, exists := _priceSources[target.source]
+	if !exists {
+		return messagePriceAlertUnknownSource
+	}
+	if len(db.PriceAlerts(chatID)) >= priceAlertMaxPerChat {
+		return messagePriceAlertTooMany
+	}
+
+	// (current price is remembered, so that the alert fires only when it crosses the threshold)
+	current, err := source.price(target.symbol)
+	if err != nil {
+		return fmt.Sprintf(messagePriceAlertFetchFailed, err)
+	}
+
+	if _, saved := db.SavePriceAlert(dbhelper.PriceAlert{
+		ChatID:    chatID,
+		UserID:    userID,
+		Source:    target.source,
+		Symbol:    target.symbol,
+		Name:      target.name,
+		Above:     above,
+		Threshold: threshold,
+		LastValue: current,
+	}); !saved {
+		return messageError
+	}
+
+	return fmt.Sprintf(messagePriceAlertAddedFormat, target.name, formatPrice(threshold), priceDirection(above), formatPrice(current))
+}
+
+// list price alerts of given chat with a keyboard for deleting them
+func listPriceAlerts(chatID int64, options map[string]interface{}) string {
+	alerts := db.PriceAlerts(chatID)
+	if len(alerts) <= 0 {
+		return messagePriceAlertNone + "\n\n" + messagePriceAlertUsage
+	}
+
+	keys := make(map[string]string)
+	for _, a := range alerts {
+		keys[fmt.Sprintf(messagePriceAlertListItemFmt, a.Name, formatPrice(a.Threshold), priceDirection(a.Above))] = fmt.Sprintf("%s %d", commandPrice, a.ID)
+	}
+	buttons := bot.NewInlineKeyboardButtonsAsRowsWithCallbackData(keys)
+
+	cancel := commandCancel
+	buttons = append(buttons, []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{Text: messageCancel, CallbackData: &cancel},
+	})
+
+	options["reply_markup"] = bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+
+	return messagePriceAlertDeleteWhat
+}
+
+// process callback query for deleting a price alert
+func processPriceAlertCallback(query bot.CallbackQuery, txt string) string {
+	if alertID, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(txt, commandPrice)), 10, 64); err == nil {
+		if db.DeletePriceAlert(query.Message.Chat.ID, alertID) {
+			return messagePriceAlertDeleted
+		}
+		log.Printf("*** Failed to delete price alert")
+	} else {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+	}
+
+	return messageError
+}
+
+// check price alerts periodically
+func monitorPriceAlerts(ticker *time.Ticker) {
+	for range ticker.C {
+		checkPriceAlerts()
+	}
+}
+
+// check all price alerts, and enqueue the ones whose prices crossed their thresholds
+func checkPriceAlerts() {
+	// (each price is fetched only once per check)
+	prices := map[string]float64{}
+	failed := map[string]bool{}
+
+	for _, a := range db.PriceAlerts(0) {
+		key := a.Source + " " + a.Symbol
+		if failed[key] {
+			continue
+		}
+
+		value, fetched := prices[key]
+		if !fetched {
+			source, exists := _priceSources[a.Source]
+			if !exists {
+				continue
+			}
+
+			var err error
+			if value, err = source.price(a.Symbol); err != nil {
+				if _isVerbose {
+					log.Printf("*** failed to fetch price of %s: %s", key, err)
+				}
+
+				failed[key] = true
+				continue
+			}
+			prices[key] = value
+		}
+
+		if !priceCrossed(a, value) {
+			if value != a.LastValue {
+				db.UpdatePriceAlertValue(a.ID, value)
+			}
+			continue
+		}
+
+		direction := messagePriceAlertFiredAbove
+		if !a.Above {
+			direction = messagePriceAlertFiredBelow
+		}
+		item := dbhelper.QueueItem{
+			ChatID:  a.ChatID,
+			UserID:  a.UserID,
+			Message: fmt.Sprintf(messagePriceAlertFiredFormat, a.Name, formatPrice(value), direction, formatPrice(a.Threshold)),
+			FireOn:  time.Now(),
+		}
+		if queueID, enqueued := db.EnqueueItem(item); enqueued {
+			item.ID = queueID
+			fireWebhooks(webhookEventCreated, item, "")
+
+			// (alerts are fired only once)
+			db.DeletePriceAlert(a.ChatID, a.ID)
+		}
+	}
+}
+
+// check if the price of given alert crossed its threshold
+func priceCrossed(a dbhelper.PriceAlert, value float64) bool {
+	if a.Above {
+		return a.LastValue < a.Threshold && value >= a.Threshold
+	}
+	return a.LastValue > a.Threshold && value <= a.Threshold
+}
+
+// direction of a price alert
+func priceDirection(above bool) string {
+	if above {
+		return messagePriceAlertAbove
+	}
+	return messagePriceAlertBelow
+}
+
+// format given price with thousands separators (eg. "1,234.56")
+func formatPrice(value float64) string {
+	formatted := strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+
+	integer, fraction := formatted, ""
+	if i := strings.Index(formatted, "."); i >= 0 {
+		integer, fraction = formatted[:i], formatted[i:]
+	}
+	sign := ""
+	if strings.HasPrefix(integer, "-") {
+		sign, integer = "-", integer[1:]
+	}
+	for i := len(integer) - 3; i > 0; i -= 3 {
+		integer = integer[:i] + "," + integer[i:]
+	}
+
+	return sign + integer + fraction
+}
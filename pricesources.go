@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// data sources of prices (public APIs which need no keys)
+
+const (
+	priceSourceFX     = "fx"     // exchange rates (eg. "USD/KRW", "100JPY/KRW")
+	priceSourceCrypto = "crypto" // crypto currencies in KRW (eg. "BTC")
+	priceSourceStock  = "stock"  // stocks (eg. "AAPL", "005930.KS")
+
+	priceTimeoutSeconds = 10
+	priceMaxBytes       = 512 * 1024
+
+	exchangeRateURLFormat = "https://open.er-api.com/v6/latest/%s"                 // (base currency)
+	upbitTickerURLFormat  = "https://api.upbit.com/v1/ticker?markets=%s"           // (market)
+	yahooChartURLFormat   = "https://query1.finance.yahoo.com/v8/finance/chart/%s" // (symbol)
+)
+
+// data source of prices
+type priceSource interface {
+	price(symbol string) (float64, error)
+}
+
+// data sources (key: name)
+var _priceSources = map[string]priceSource{
+	priceSourceFX:     exchangeRateSource{},
+	priceSourceCrypto: upbitSource{},
+	priceSourceStock:  yahooFinanceSource{},
+}
+
+var _priceClient = &http.Client{Timeout: priceTimeoutSeconds * time.Second}
+
+// fetch given url and decode its json response into given value
+func fetchPriceJSON(endpoint string, v interface{}) error {
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "telegram-bot-reminder-api.ai")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := _priceClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("http status: %s", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, priceMaxBytes))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// exchange rates (from open.er-api.com)
+type exchangeRateSource struct{}
+
+var exchangeRateSymbolRegex = regexp.MustCompile(`^([0-9]*)([A-Z]{3})/([A-Z]{3})$`)
+
+func (s exchangeRateSource) price(symbol string) (float64, error) {
+	matches := exchangeRateSymbolRegex.FindStringSubmatch(symbol)
+	if matches == nil {
+		return 0, fmt.Errorf("malformed symbol: %s", symbol)
+	}
+	amount := 1.0
+	if matches[1] != "" {
+		amount, _ = strconv.ParseFloat(matches[1], 64)
+	}
+
+	var rates struct {
+		Result string             `json:"result"`
+		Rates  map[string]float64 `json:"rates"`
+	}
+	if err := fetchPriceJSON(fmt.Sprintf(exchangeRateURLFormat, matches[2]), &rates); err != nil {
+		return 0, err
+	}
+
+	rate, exists := rates.Rates[matches[3]]
+	if rates.Result != "success" || !exists {
+		return 0, fmt.Errorf("no exchange rate for %s", symbol)
+	}
+
+	return rate * amount, nil
+}
+
+// crypto currencies (from Upbit, in KRW)
+type upbitSource struct{}
+
+func (s upbitSource) price(symbol string) (float64, error) {
+	var tickers []struct {
+		Market     string  `json:"market"`
+		TradePrice float64 `json:"trade_price"`
+	}
+	if err := fetchPriceJSON(fmt.Sprintf(upbitTickerURLFormat, url.QueryEscape("KRW-"+strings.ToUpper(symbol))), &tickers); err != nil {
+		return 0, err
+	}
+	if len(tickers) <= 0 {
+		return 0, fmt.Errorf("no ticker for %s", symbol)
+	}
+
+	return tickers[0].TradePrice, nil
+}
+
+// stocks (from Yahoo Finance, in the currency of the market)
+type yahooFinanceSource struct{}
+
+func (s yahooFinanceSource) price(symbol string) (float64, error) {
+	var chart struct {
+		Chart struct {
+			Result []struct {
+				Meta struct {
+					RegularMarketPrice float64 `json:"regularMarketPrice"`
+				} `json:"meta"`
+			} `json:"result"`
+		} `json:"chart"`
+	}
+	if err := fetchPriceJSON(fmt.Sprintf(yahooChartURLFormat, url.PathEscape(symbol)), &chart); err != nil {
+		return 0, err
+	}
+	if len(chart.Chart.Result) <= 0 || chart.Chart.Result[0].Meta.RegularMarketPrice <= 0 {
+		return 0, fmt.Errorf("no price for %s", symbol)
+	}
+
+	return chart.Chart.Result[0].Meta.RegularMarketPrice, nil
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// public events: admins curate events (holidays, releases, deadlines, ...),
+// chats subscribe to them with one tap ("설날 D-7 알림 받기"),
+// and countdowns are fanned out to all subscribers (as ordinary reminders) at the offsets of each event
+
+const (
+	commandCountdowns = "/countdowns"
+
+	publicEventParamAdd         = "add"
+	publicEventParamDelete      = "del"
+	publicEventParamSubscribe   = "sub"
+	publicEventParamUnsubscribe = "unsub"
+
+	publicEventDateFormat           = "2006-01-02"
+	publicEventCheckIntervalSeconds = 60
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messagePublicEventsNone          = "예정된 공개 일정이 없습니다."
+	messagePublicEventsTitle         = "공개 일정 (눌러서 알림 받기/그만 받기):"
+	messagePublicEventButtonFormat   = "%s%s %s (%s)"
+	messagePublicEventSubscribedMark = "✅ "
+	messagePublicEventSubscribedFmt  = "'%s' 알림을 받습니다. (%s에 알려드립니다)"
+	messagePublicEventUnsubscribed   = "'%s' 알림을 그만 받습니다."
+	messagePublicEventNotFound       = "해당하는 공개 일정이 없습니다. (/countdowns 로 확인할 수 있습니다)"
+	messagePublicEventAdminUsage     = `사용법 (관리자):
+/countdowns add 2027-02-06 [7,1,0] 설날 : 공개 일정 등록 (D-7, D-1, D-day에 알림)
+/countdowns del <id> : 공개 일정 삭제`
+	messagePublicEventAddedFormat   = "[관리자] 공개 일정을 등록했습니다: %d. %s (%s, %s)"
+	messagePublicEventDeletedFormat = "[관리자] 공개 일정 %d을(를) 삭제했습니다."
+	messagePublicEventIsPast        = "이미 지난 날짜입니다."
+)
+
+var (
+	publicEventOffsetsRegex   = regexp.MustCompile(`^\d+(,\d+)*$`)
+	publicEventSubscribeWords = []string{"알림 받기", "알림받기", "알림 받을래", "알림 신청"}
+)
+
+// check if given text is about public events
+func isPublicEventText(txt string) bool {
+	if strings.HasPrefix(txt, commandCountdowns) {
+		return true
+	}
+
+	if !containsAny(txt, publicEventSubscribeWords) {
+		return false
+	}
+
+	_, found := publicEventIn(txt)
+	return found
+}
+
+// process public event command or text
+//
+//	"/countdowns"                                   : list upcoming events with buttons for subscribing
+//	"/countdowns add <yyyy-mm-dd> [offsets] <name>" : add an event (admins only)
+//	"/countdowns del <id>"                          : delete an event (admins only)
+//	"설날 D-7 알림 받기"                             : subscribe to an event
+func processPublicEventText(chatID, userID int64, txt string, options map[string]interface{}) string {
+	if !strings.HasPrefix(txt, commandCountdowns) {
+		e, _ := publicEventIn(txt)
+
+		return subscribePublicEvent(chatID, userID, e)
+	}
+
+	params := strings.Fields(strings.TrimPrefix(txt, commandCountdowns))
+	if len(params) <= 0 {
+		message, keyboard := publicEventList(chatID)
+		if keyboard != nil {
+			options["reply_markup"] = keyboard
+		}
+		return message
+	}
+
+	if !isAdmin(userID) {
+		return messageAnnounceAdminOnly
+	}
+
+	switch params[0] {
+	case publicEventParamAdd:
+		return addPublicEvent(userID, params[1:])
+	case publicEventParamDelete:
+		if len(params) == 2 {
+			if eventID, err := strconv.ParseInt(params[1], 10, 64); err == nil {
+				if db.DeletePublicEvent(eventID) {
+					return fmt.Sprintf(messagePublicEventDeletedFormat, eventID)
+				}
+				return messagePublicEventNotFound
+			}
+		}
+	}
+
+	return messagePublicEventAdminUsage
+}
+
+// add a public event with given params: <yyyy-mm-dd> [offsets] <name>
+func addPublicEvent(userID int64, params []string) string {
+	if len(params) < 2 {
+		return messagePublicEventAdminUsage
+	}
+
+	eventOn, err := time.ParseInLocation(publicEventDateFormat, params[0], _location)
+	if err != nil {
+		return messagePublicEventAdminUsage
+	}
+	if eventOn.Before(truncateToDay(time.Now().In(_location))) {
+		return messagePublicEventIsPast
+	}
+
+	offsets := append([]int{}, ddayCountdownDays...)
+	if publicEventOffsetsRegex.MatchString(params[1]) && len(params) > 2 {
+		offsets = []int{}
+		for _, offset := range strings.Split(params[1], ",") {
+			days, _ := strconv.Atoi(offset)
+			offsets = append(offsets, days)
+		}
+		params = params[1:]
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(offsets)))
+
+	e := dbhelper.PublicEvent{
+		Name:      strings.Join(params[1:], " "),
+		EventOn:   eventOn,
+		Offsets:   offsets,
+		CreatedBy: userID,
+	}
+	eventID, saved := db.SavePublicEvent(e)
+	if !saved {
+		return messageSaveFailed
+	}
+
+	return fmt.Sprintf(messagePublicEventAddedFormat, eventID, e.Name, eventOn.Format(publicEventDateFormat), publicEventCountdowns(e))
+}
+
+// upcoming public events with buttons for (un)subscribing
+func publicEventList(chatID int64) (message string, keyboard interface{}) {
+	events := db.PublicEvents(truncateToDay(time.Now().In(_location)))
+	if len(events) <= 0 {
+		return messagePublicEventsNone, nil
+	}
+
+	subscribed := subscribedPublicEventIDs(chatID)
+
+	buttons := [][]bot.InlineKeyboardButton{}
+	for _, e := range events {
+		mark, param := "", publicEventParamSubscribe
+		if subscribed[e.ID] {
+			mark, param = messagePublicEventSubscribedMark, publicEventParamUnsubscribe
+		}
+
+		data := fmt.Sprintf("%s %s %d", commandCountdowns, param, e.ID)
+		buttons = append(buttons, []bot.InlineKeyboardButton{
+			bot.InlineKeyboardButton{
+				Text:         fmt.Sprintf(messagePublicEventButtonFormat, mark, e.Name, formatTimeFor(chatID, e.EventOn, messageDDayDateFormat), publicEventCountdowns(e)),
+				CallbackData: &data,
+			},
+		})
+	}
+	cancel := commandCancel
+	buttons = append(buttons, []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{Text: messageCancel, CallbackData: &cancel},
+	})
+
+	return messagePublicEventsTitle, bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// process callback query for (un)subscribing: '/countdowns <sub|unsub> <id>'
+func processPublicEventCallback(query bot.CallbackQuery, txt string) (message string, keyboard interface{}) {
+	chatID := query.Message.Chat.ID
+	userID := int64(query.From.ID)
+
+	params := strings.Fields(strings.TrimPrefix(txt, commandCountdowns))
+	if len(params) == 2 {
+		if eventID, err := strconv.ParseInt(params[1], 10, 64); err == nil {
+			e, exists := upcomingPublicEvent(eventID)
+			if !exists {
+				return messagePublicEventNotFound, nil
+			}
+
+			switch params[0] {
+			case publicEventParamSubscribe:
+				message = subscribePublicEvent(chatID, userID, e)
+			case publicEventParamUnsubscribe:
+				db.UnsubscribePublicEvent(e.ID, chatID)
+				message = fmt.Sprintf(messagePublicEventUnsubscribed, e.Name)
+			}
+
+			if message != "" {
+				_, keyboard = publicEventList(chatID)
+
+				return message, keyboard
+			}
+		}
+	}
+
+	log.Printf("*** Unprocessable callback query: %s", txt)
+
+	return messageError, nil
+}
+
+// subscribe given chat to given public event
+func subscribePublicEvent(chatID, userID int64, e dbhelper.PublicEvent) string {
+	if !db.SubscribePublicEvent(e.ID, chatID, userID) {
+		return messageSaveFailed
+	}
+
+	return fmt.Sprintf(messagePublicEventSubscribedFmt, e.Name, publicEventCountdowns(e))
+}
+
+// upcoming public event whose name is in given text
+func publicEventIn(txt string) (dbhelper.PublicEvent, bool) {
+	for _, e := range db.PublicEvents(truncateToDay(time.Now().In(_location))) {
+		if strings.Contains(txt, e.Name) {
+			return e, true
+		}
+	}
+	return dbhelper.PublicEvent{}, false
+}
+
+// upcoming public event with given id
+func upcomingPublicEvent(eventID int64) (dbhelper.PublicEvent, bool) {
+	for _, e := range db.PublicEvents(truncateToDay(time.Now().In(_location))) {
+		if e.ID == eventID {
+			return e, true
+		}
+	}
+	return dbhelper.PublicEvent{}, false
+}
+
+// ids of public events which given chat subscribed to
+func subscribedPublicEventIDs(chatID int64) map[int64]bool {
+	ids := map[int64]bool{}
+	for _, s := range db.PublicEventSubscriptions(0, chatID) {
+		ids[s.EventID] = true
+	}
+	return ids
+}
+
+// labels of countdowns of given public event (eg. "D-7, D-1, D-day")
+func publicEventCountdowns(e dbhelper.PublicEvent) string {
+	labels := []string{}
+	for _, days := range e.Offsets {
+		labels = append(labels, ddayLabel(days))
+	}
+	return strings.Join(labels, ", ")
+}
+
+// check public events periodically
+func monitorPublicEvents(ticker *time.Ticker) {
+	for range ticker.C {
+		fanOutPublicEvents()
+	}
+}
+
+// enqueue countdowns of public events which are due, for all of their subscribers
+//
+// (countdowns missed while the bot was not running are sent if it is still the same day)
+func fanOutPublicEvents() {
+	now := time.Now().In(_location)
+
+	for _, e := range db.PublicEvents(truncateToDay(now)) {
+		for _, days := range e.Offsets {
+			fireOn := e.EventOn.In(_location).AddDate(0, 0, -days).Add(ddayNotifyHour * time.Hour)
+			if now.Before(fireOn) || !now.Before(truncateToDay(fireOn).AddDate(0, 0, 1)) {
+				continue
+			}
+			if !db.MarkPublicEventFannedOut(e.ID, days) {
+				continue // (fanned out already)
+			}
+
+			numEnqueued := 0
+			for _, s := range db.PublicEventSubscriptions(e.ID, 0) {
+				if _, enqueued := db.EnqueueItem(dbhelper.QueueItem{
+					ChatID:  s.ChatID,
+					UserID:  s.UserID,
+					Message: fmt.Sprintf(messageDDayCountdownFormat, ddayLabel(days), e.Name, formatTimeFor(s.ChatID, e.EventOn, messageDDayDateFormat)),
+					FireOn:  now,
+				}); enqueued {
+					numEnqueued++
+				}
+			}
+
+			db.Log(fmt.Sprintf("fanned out %s of public event %d to %d chats", ddayLabel(days), e.ID, numEnqueued))
+		}
+	}
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// pushes to companion apps (or PWAs):
+//
+// apps register their push tokens with the REST API (or users do it with /push command),
+// then reminders of the chat are pushed to them alongside telegram messages
+// (or instead of them, when 'push only' is chosen and any of the devices receives the push)
+
+const (
+	commandPush = "/push"
+
+	pushParamMode   = "mode"
+	pushParamRemove = "remove"
+
+	apiPathPushTokens = "/api/push/tokens"
+
+	pushMaxTokensPerChat = 10
+	pushMaxTokenLength   = 4096
+	pushMaxNameLength    = 64
+)
+
+// config for pushes
+type pushConfig struct {
+	FCM  *fcmConfig  `json:"fcm,omitempty"`
+	APNs *apnsConfig `json:"apns,omitempty"`
+}
+
+// notification pushed to devices
+type pushNotification struct {
+	Title   string
+	Body    string
+	ChatID  int64
+	QueueID int64
+}
+
+// notifier delivers notifications to the devices of a push platform
+type notifier interface {
+	notify(token string, n pushNotification) error
+}
+
+// returned by notifiers for tokens which are no longer valid (they will be deleted)
+var errInvalidPushToken = errors.New("invalid push token")
+
+// notifiers of configured push platforms (key: platform)
+var _notifiers = map[string]notifier{}
+
+// request body for registering a push token
+type apiPushTokenRequest struct {
+	Platform string `json:"platform"` // "fcm" or "apns"
+	Token    string `json:"token"`
+	Name     string `json:"name,omitempty"`
+}
+
+// messages (can be overridden with messages.json)
+var (
+	messagePushDisabled          = "푸시 알림이 설정되어 있지 않습니다."
+	messagePushPrivateOnly       = "푸시 알림은 개인 대화에서만 등록할 수 있습니다."
+	messagePushUsage             = "등록된 기기가 없습니다. 앱에서 등록하거나, '/push <fcm|apns> <토큰> [이름]'으로 직접 등록할 수 있습니다."
+	messagePushDevicesFormat     = "푸시 알림을 받는 기기 (%s):"
+	messagePushDeviceFormat      = "%d. %s (%s)"
+	messagePushUnknownPlatform   = "지원하지 않는 플랫폼입니다."
+	messagePushInvalidToken      = "토큰이 올바르지 않습니다."
+	messagePushTooManyDevices    = "더 이상 기기를 등록할 수 없습니다. (최대 10개)"
+	messagePushRegisteredFormat  = "기기가 등록되었습니다: %s"
+	messagePushRemoved           = "기기를 삭제했습니다."
+	messagePushModeAlongside     = "텔레그램과 함께"
+	messagePushModeOnly          = "푸시로만"
+	messagePushModeButtonFormat  = "받는 방법: %s"
+	messagePushRemoveFormat      = "삭제: %d"
+	messagePushTitle             = "알림"
+	messagePushUnnamedDevice     = "이름 없음"
+	messagePushPlatformFCM       = "Android/웹"
+	messagePushPlatformAPNs      = "iOS"
+	messagePushModeChangedFormat = "이제 알림을 %s 받습니다."
+)
+
+// setup notifiers of configured push platforms
+func setupNotifiers(c pushConfig) {
+	if c.FCM != nil {
+		if n, err := newFCMNotifier(*c.FCM); err == nil {
+			_notifiers[dbhelper.PushPlatformFCM] = n
+		} else {
+			log.Printf("*** failed to setup fcm notifier: %s", err)
+		}
+	}
+	if c.APNs != nil {
+		if n, err := newAPNsNotifier(*c.APNs); err == nil {
+			_notifiers[dbhelper.PushPlatformAPNs] = n
+		} else {
+			log.Printf("*** failed to setup apns notifier: %s", err)
+		}
+	}
+}
+
+// check if any push platform is available
+func isPushEnabled() bool {
+	return len(_notifiers) > 0
+}
+
+// process /push command
+//
+//	"/push"                           : list registered devices
+//	"/push <fcm|apns> <token> [name]" : register a device
+func processPushCommand(chatID int64, isPrivate bool, txt string, options map[string]interface{}) string {
+	if !isPushEnabled() {
+		return messagePushDisabled
+	}
+	if !isPrivate {
+		return messagePushPrivateOnly
+	}
+
+	params := strings.Fields(strings.TrimPrefix(txt, commandPush))
+	if len(params) >= 2 {
+		name := strings.Join(params[2:], " ")
+		if err := registerPushToken(chatID, params[0], params[1], name); err != nil {
+			return err.Error()
+		}
+		return fmt.Sprintf(messagePushRegisteredFormat, pushDeviceName(params[0], name))
+	}
+
+	message, keyboard := pushDevices(chatID)
+	if keyboard != nil {
+		options["reply_markup"] = keyboard
+	}
+
+	return message
+}
+
+// list of registered devices of given chat, with buttons for managing them
+func pushDevices(chatID int64) (message string, keyboard interface{}) {
+	tokens := db.PushTokens(chatID)
+	if len(tokens) <= 0 {
+		return messagePushUsage, nil
+	}
+
+	mode := pushModeName(db.ChatSettings(chatID).PushOnly)
+	lines := []string{fmt.Sprintf(messagePushDevicesFormat, mode)}
+
+	modeData := fmt.Sprintf("%s %s", commandPush, pushParamMode)
+	buttons := [][]bot.InlineKeyboardButton{
+		[]bot.InlineKeyboardButton{
+			bot.InlineKeyboardButton{Text: fmt.Sprintf(messagePushModeButtonFormat, mode), CallbackData: &modeData},
+		},
+	}
+	for i, t := range tokens {
+		lines = append(lines, fmt.Sprintf(messagePushDeviceFormat, i+1, pushDeviceName(t.Platform, t.Name), formatTimeFor(chatID, t.RegisteredOn, reminderTimeFormat)))
+
+		removeData := fmt.Sprintf("%s %s %d", commandPush, pushParamRemove, t.ID)
+		buttons = append(buttons, []bot.InlineKeyboardButton{
+			bot.InlineKeyboardButton{Text: fmt.Sprintf(messagePushRemoveFormat, i+1), CallbackData: &removeData},
+		})
+	}
+	cancel := commandCancel
+	buttons = append(buttons, []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{Text: messageCancel, CallbackData: &cancel},
+	})
+
+	return strings.Join(lines, "\n"), bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// process callback query for managing devices
+//
+//	"/push mode"        : toggle whether reminders are delivered only with pushes
+//	"/push remove <id>" : remove a device
+func processPushCallback(query bot.CallbackQuery, txt string) (message string, keyboard interface{}) {
+	chatID := query.Message.Chat.ID
+
+	params := strings.Fields(strings.TrimPrefix(txt, commandPush))
+	if len(params) > 0 {
+		switch params[0] {
+		case pushParamMode:
+			s := db.ChatSettings(chatID)
+			s.PushOnly = !s.PushOnly
+			if !db.SaveChatSettings(s) {
+				return messageError, nil
+			}
+
+			_, keyboard = pushDevices(chatID)
+
+			return fmt.Sprintf(messagePushModeChangedFormat, pushModeName(s.PushOnly)), keyboard
+		case pushParamRemove:
+			if len(params) == 2 {
+				if id, err := strconv.ParseInt(params[1], 10, 64); err == nil && db.DeletePushToken(chatID, id) {
+					_, keyboard = pushDevices(chatID)
+
+					return messagePushRemoved, keyboard
+				}
+			}
+		}
+	}
+
+	log.Printf("*** Unprocessable callback query: %s", txt)
+
+	return messageError, nil
+}
+
+// validate and save a push token for given chat
+func registerPushToken(chatID int64, platform, token, name string) error {
+	if _, exists := _notifiers[platform]; !exists {
+		return errors.New(messagePushUnknownPlatform)
+	}
+	if token == "" || len(token) > pushMaxTokenLength || strings.ContainsAny(token, " \t\r\n") {
+		return errors.New(messagePushInvalidToken)
+	}
+	if runes := []rune(name); len(runes) > pushMaxNameLength {
+		name = string(runes[:pushMaxNameLength])
+	}
+
+	registered := false
+	tokens := db.PushTokens(chatID)
+	for _, t := range tokens {
+		if t.Platform == platform && t.Token == token {
+			registered = true
+			break
+		}
+	}
+	if !registered && len(tokens) >= pushMaxTokensPerChat {
+		return errors.New(messagePushTooManyDevices)
+	}
+
+	if !db.SavePushToken(chatID, platform, token, name) {
+		return errors.New(messageError)
+	}
+
+	return nil
+}
+
+// displayed name of a device
+func pushDeviceName(platform, name string) string {
+	platformName := messagePushPlatformFCM
+	if platform == dbhelper.PushPlatformAPNs {
+		platformName = messagePushPlatformAPNs
+	}
+	if name == "" {
+		name = messagePushUnnamedDevice
+	}
+
+	return fmt.Sprintf("%s, %s", name, platformName)
+}
+
+// name of the delivery mode
+func pushModeName(pushOnly bool) string {
+	if pushOnly {
+		return messagePushModeOnly
+	}
+	return messagePushModeAlongside
+}
+
+// check if given queue item can be pushed to the companion apps
+func isPushable(q dbhelper.QueueItem) bool {
+	return isPushEnabled() && q.Kind == dbhelper.QueueKindReminder && !q.Broadcast && q.BusinessConnectionID == "" && !isGroupChatID(q.ChatID)
+}
+
+// push given reminder to all devices of its chat, and return the number of devices which received it
+func pushQueueItem(q dbhelper.QueueItem) (numPushed int) {
+	n := pushNotification{
+		Title:   messagePushTitle,
+		Body:    sanitizedDeliveryText(q.ChatID, visibleReminderText(q)),
+		ChatID:  q.ChatID,
+		QueueID: q.ID,
+	}
+
+	for _, t := range db.PushTokens(q.ChatID) {
+		notifier, exists := _notifiers[t.Platform]
+		if !exists {
+			continue
+		}
+
+		if err := notifier.notify(t.Token, n); err == nil {
+			numPushed++
+		} else if err == errInvalidPushToken {
+			log.Printf("deleting invalid %s push token of chat %d", t.Platform, t.ChatID)
+
+			db.DeleteInvalidPushToken(t.Platform, t.Token)
+		} else {
+			log.Printf("*** failed to push queue item %d to %s: %s", q.ID, t.Platform, err)
+		}
+	}
+
+	return numPushed
+}
+
+// push given reminder alongside its telegram message (unless the chat receives only pushes)
+func pushAlongside(q dbhelper.QueueItem) {
+	if isPushable(q) && !db.ChatSettings(q.ChatID).PushOnly {
+		pushQueueItem(q)
+	}
+}
+
+// deliver given reminder only with pushes (if the chat wants so), and return true if any device received it
+//
+// (when no device receives it, it is delivered with telegram as usual)
+func deliverWithPushOnly(client *bot.Bot, q dbhelper.QueueItem) bool {
+	if !isPushable(q) || !db.ChatSettings(q.ChatID).PushOnly || pushQueueItem(q) <= 0 {
+		return false
+	}
+
+	_varNumDelivered.Add(1)
+
+	if !db.MarkQueueItemAsDelivered(q.ChatID, q.ID) {
+		log.Printf("*** failed to mark chat id: %d, queue id: %d", q.ChatID, q.ID)
+	}
+	if !db.IncreaseNumTries(q.ChatID, q.ID) {
+		log.Printf("*** failed to increase num tries for chat id: %d, queue id: %d", q.ChatID, q.ID)
+	}
+
+	recordDeliveryLatency(client, q)
+	markMergedQueueItems(client, q)
+	publishFiredReminder(q)
+	fireWebhooks(webhookEventDelivered, q, "")
+
+	if q.Recurrence != "" {
+		scheduleNextOccurrence(q)
+	}
+
+	return true
+}
+
+// GET: list push tokens, POST: register a push token
+func handlePushTokens(w http.ResponseWriter, r *http.Request, chatID int64) {
+	if !isPushEnabled() {
+		writeAPIResponse(w, http.StatusNotFound, apiResponse{Error: "push is not enabled"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeAPIResponse(w, http.StatusOK, apiResponse{Ok: true, Result: db.PushTokens(chatID)})
+	case http.MethodPost:
+		var req apiPushTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeAPIResponse(w, http.StatusBadRequest, apiResponse{Error: fmt.Sprintf("invalid request body: %s", err)})
+			return
+		}
+		if _, exists := _notifiers[req.Platform]; !exists {
+			writeAPIResponse(w, http.StatusBadRequest, apiResponse{Error: fmt.Sprintf("unsupported platform: %s", req.Platform)})
+			return
+		}
+
+		if err := registerPushToken(chatID, req.Platform, req.Token, strings.TrimSpace(req.Name)); err != nil {
+			writeAPIResponse(w, http.StatusBadRequest, apiResponse{Error: err.Error()})
+			return
+		}
+
+		writeAPIResponse(w, http.StatusCreated, apiResponse{Ok: true})
+	default:
+		writeAPIResponse(w, http.StatusMethodNotAllowed, apiResponse{Error: "method not allowed"})
+	}
+}
+
+// DELETE: unregister a push token
+func handlePushToken(w http.ResponseWriter, r *http.Request, chatID int64) {
+	if r.Method != http.MethodDelete {
+		writeAPIResponse(w, http.StatusMethodNotAllowed, apiResponse{Error: "method not allowed"})
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, apiPathPushTokens+"/"), 10, 64)
+	if err != nil {
+		writeAPIResponse(w, http.StatusBadRequest, apiResponse{Error: "invalid push token id"})
+		return
+	}
+
+	if db.DeletePushToken(chatID, id) {
+		writeAPIResponse(w, http.StatusOK, apiResponse{Ok: true})
+	} else {
+		writeAPIResponse(w, http.StatusNotFound, apiResponse{Error: "no such push token"})
+	}
+}
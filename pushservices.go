@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// notifiers of push platforms (authenticated with signed JWTs)
+
+const (
+	pushTimeoutSeconds = 10
+
+	fcmScope           = "https://www.googleapis.com/auth/firebase.messaging"
+	fcmDefaultTokenURI = "https://oauth2.googleapis.com/token"
+	fcmSendURLFormat   = "https://fcm.googleapis.com/v1/projects/%s/messages:send" // (project id)
+	fcmGrantType       = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+	apnsProductionURL   = "https://api.push.apple.com/3/device/"
+	apnsSandboxURL      = "https://api.sandbox.push.apple.com/3/device/"
+	apnsJWTValidMinutes = 50 // (should be refreshed within an hour)
+)
+
+// config for Firebase Cloud Messaging
+type fcmConfig struct {
+	ServiceAccountFile string `json:"service_account_file"` // service account key (json) of the firebase project
+}
+
+// config for Apple Push Notification service
+type apnsConfig struct {
+	KeyFile string `json:"key_file"` // signing key (.p8) for token-based authentication
+	KeyID   string `json:"key_id"`
+	TeamID  string `json:"team_id"`
+	Topic   string `json:"topic"` // bundle id of the app
+	Sandbox bool   `json:"sandbox,omitempty"`
+}
+
+var _pushClient = &http.Client{Timeout: pushTimeoutSeconds * time.Second}
+
+// validate push config values
+func (c pushConfig) validate() (problems []string) {
+	if c.FCM == nil && c.APNs == nil {
+		problems = append(problems, "fcm or apns of push should be configured")
+	}
+	if c.FCM != nil && c.FCM.ServiceAccountFile == "" {
+		problems = append(problems, "service_account_file of push.fcm should not be empty")
+	}
+	if c.APNs != nil && (c.APNs.KeyFile == "" || c.APNs.KeyID == "" || c.APNs.TeamID == "" || c.APNs.Topic == "") {
+		problems = append(problems, "key_file, key_id, team_id, and topic of push.apns should not be empty")
+	}
+
+	return problems
+}
+
+// JWT signed with given function
+func signedJWT(header, claims map[string]interface{}, sign func(digest []byte) ([]byte, error)) (string, error) {
+	encodedHeader, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	encodedClaims, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(encodedHeader) + "." + base64.RawURLEncoding.EncodeToString(encodedClaims)
+	digest := sha256.Sum256([]byte(unsigned))
+
+	signature, err := sign(digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// PKCS#8 private key in given PEM data
+func parsePKCS8PrivateKey(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+
+	return x509.ParsePKCS8PrivateKey(block.Bytes)
+}
+
+// error from the response of a push service
+func pushResponseError(resp *http.Response) error {
+	message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("http status: %s (%s)", resp.Status, strings.TrimSpace(string(message)))
+}
+
+// notifier for Firebase Cloud Messaging (HTTP v1 API)
+type fcmNotifier struct {
+	projectID   string
+	clientEmail string
+	tokenURI    string
+	key         *rsa.PrivateKey
+
+	sync.Mutex
+	accessToken string
+	expiresOn   time.Time
+}
+
+func newFCMNotifier(c fcmConfig) (*fcmNotifier, error) {
+	data, err := ioutil.ReadFile(c.ServiceAccountFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var account struct {
+		ProjectID   string `json:"project_id"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, err
+	}
+	if account.ProjectID == "" || account.ClientEmail == "" {
+		return nil, errors.New("project_id or client_email is missing in the service account file")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = fcmDefaultTokenURI
+	}
+
+	parsed, err := parsePKCS8PrivateKey([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %s", err)
+	}
+	key, isRSA := parsed.(*rsa.PrivateKey)
+	if !isRSA {
+		return nil, errors.New("private key is not a RSA key")
+	}
+
+	return &fcmNotifier{
+		projectID:   account.ProjectID,
+		clientEmail: account.ClientEmail,
+		tokenURI:    account.TokenURI,
+		key:         key,
+	}, nil
+}
+
+// access token for the API (cached until it expires)
+func (n *fcmNotifier) token() (string, error) {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.accessToken != "" && time.Now().Before(n.expiresOn) {
+		return n.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := signedJWT(
+		map[string]interface{}{"alg": "RS256", "typ": "JWT"},
+		map[string]interface{}{
+			"iss":   n.clientEmail,
+			"scope": fcmScope,
+			"aud":   n.tokenURI,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+		},
+		func(digest []byte) ([]byte, error) {
+			return rsa.SignPKCS1v15(rand.Reader, n.key, crypto.SHA256, digest)
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := _pushClient.PostForm(n.tokenURI, url.Values{
+		"grant_type": {fcmGrantType},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", pushResponseError(resp)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+
+	// (refreshed a minute earlier)
+	n.accessToken = token.AccessToken
+	n.expiresOn = now.Add(time.Duration(token.ExpiresIn-60) * time.Second)
+
+	return n.accessToken, nil
+}
+
+func (n *fcmNotifier) notify(token string, notification pushNotification) error {
+	accessToken, err := n.token()
+	if err != nil {
+		return fmt.Errorf("failed to get access token: %s", err)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token": token,
+			"notification": map[string]string{
+				"title": notification.Title,
+				"body":  notification.Body,
+			},
+			"data": map[string]string{ // (values should be strings)
+				"chat_id":  strconv.FormatInt(notification.ChatID, 10),
+				"queue_id": strconv.FormatInt(notification.QueueID, 10),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf(fcmSendURLFormat, url.PathEscape(n.projectID)), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := _pushClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound: // UNREGISTERED
+		return errInvalidPushToken
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return pushResponseError(resp)
+	}
+
+	return nil
+}
+
+// notifier for Apple Push Notification service (token-based)
+type apnsNotifier struct {
+	keyID   string
+	teamID  string
+	topic   string
+	baseURL string
+	key     *ecdsa.PrivateKey
+
+	sync.Mutex
+	jwt       string
+	expiresOn time.Time
+}
+
+func newAPNsNotifier(c apnsConfig) (*apnsNotifier, error) {
+	data, err := ioutil.ReadFile(c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := parsePKCS8PrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %s", err)
+	}
+	key, isECDSA := parsed.(*ecdsa.PrivateKey)
+	if !isECDSA {
+		return nil, errors.New("private key is not an ECDSA key")
+	}
+
+	baseURL := apnsProductionURL
+	if c.Sandbox {
+		baseURL = apnsSandboxURL
+	}
+
+	return &apnsNotifier{
+		keyID:   c.KeyID,
+		teamID:  c.TeamID,
+		topic:   c.Topic,
+		baseURL: baseURL,
+		key:     key,
+	}, nil
+}
+
+// provider token for the API (cached until it needs refreshing)
+func (n *apnsNotifier) token() (string, error) {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.jwt != "" && time.Now().Before(n.expiresOn) {
+		return n.jwt, nil
+	}
+
+	now := time.Now()
+	signed, err := signedJWT(
+		map[string]interface{}{"alg": "ES256", "kid": n.keyID},
+		map[string]interface{}{"iss": n.teamID, "iat": now.Unix()},
+		func(digest []byte) ([]byte, error) {
+			r, s, err := ecdsa.Sign(rand.Reader, n.key, digest)
+			if err != nil {
+				return nil, err
+			}
+
+			// (r and s, each padded to 32 bytes)
+			signature := make([]byte, 64)
+			rBytes, sBytes := r.Bytes(), s.Bytes()
+			copy(signature[32-len(rBytes):32], rBytes)
+			copy(signature[64-len(sBytes):], sBytes)
+
+			return signature, nil
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	n.jwt = signed
+	n.expiresOn = now.Add(apnsJWTValidMinutes * time.Minute)
+
+	return n.jwt, nil
+}
+
+func (n *apnsNotifier) notify(token string, notification pushNotification) error {
+	jwt, err := n.token()
+	if err != nil {
+		return fmt.Errorf("failed to sign provider token: %s", err)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{
+				"title": notification.Title,
+				"body":  notification.Body,
+			},
+			"sound": "default",
+		},
+		"chat_id":  notification.ChatID,
+		"queue_id": notification.QueueID,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", n.baseURL+url.PathEscape(token), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+jwt)
+	req.Header.Set("apns-topic", n.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+
+	// (HTTP/2 is negotiated by the default transport)
+	resp, err := _pushClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var reason struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 1024)).Decode(&reason)
+	if resp.StatusCode == http.StatusGone || reason.Reason == "BadDeviceToken" || reason.Reason == "Unregistered" {
+		return errInvalidPushToken
+	}
+
+	return fmt.Errorf("http status: %s (%s)", resp.Status, reason.Reason)
+}
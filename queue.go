@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	commandQueue = "/queue"
+
+	queueParamAll   = "all"
+	queueParamRetry = "retry"
+	queueParamDrop  = "drop"
+
+	queueInspectMaxItems = 10
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageQueueUsage       = "사용법: /queue <chat_id|all>"
+	messageQueueEmpty       = "대기 중인 알림이 없습니다."
+	messageQueueMore        = "(더 있음)"
+	messageQueueRetried     = "[관리자] 알림을 지금 다시 보냅니다."
+	messageQueueDropped     = "[관리자] 알림을 삭제했습니다."
+	messageQueueItemGone    = "[관리자] 알림이 이미 없습니다."
+	messageQueueRetryFormat = "↻ #%d"
+	messageQueueDropFormat  = "✕ #%d"
+)
+
+// states of queue items (for inspection)
+const (
+	queueStateDelivered = "delivered"
+	queueStatePaused    = "paused"
+	queueStateFailed    = "failed"
+	queueStateDue       = "due"
+	queueStatePending   = "pending"
+)
+
+// state of given queue item
+func queueItemState(q dbhelper.QueueItem, maxNumTries int) string {
+	switch {
+	case q.DeliveredOn.Unix() > 0:
+		return queueStateDelivered
+	case q.Paused != dbhelper.NotPaused:
+		return queueStatePaused
+	case q.NumTries >= maxNumTries:
+		return queueStateFailed
+	case !q.FireOn.After(time.Now()):
+		return queueStateDue
+	}
+
+	return queueStatePending
+}
+
+// text of the last delivery error of given queue item
+func lastErrorText(q dbhelper.QueueItem) string {
+	text := q.LastError
+	if q.LastErrorCode != 0 {
+		text = fmt.Sprintf("[%d] %s", q.LastErrorCode, text)
+	}
+	if q.LastErrorOn.Unix() > 0 {
+		text = fmt.Sprintf("%s (%s)", text, q.LastErrorOn.Format(reminderTimeFormat))
+	}
+
+	return text
+}
+
+// process /queue command: show raw rows of undelivered queue items for admins
+func processQueueCommand(userID int64, txt string, options map[string]interface{}) string {
+	if !isAdmin(userID) {
+		return messageAnnounceAdminOnly
+	}
+
+	param := strings.TrimSpace(strings.TrimPrefix(txt, commandQueue))
+	if param == "" {
+		return messageQueueUsage
+	}
+
+	var chatID int64
+	if param != queueParamAll {
+		var err error
+		if chatID, err = strconv.ParseInt(param, 10, 64); err != nil {
+			return messageQueueUsage
+		}
+	}
+
+	maxNumTries := _maxNumTries
+	if maxNumTries <= 0 {
+		maxNumTries = dbhelper.DefaultMaxNumTries
+	}
+
+	items := db.SearchQueueItems(dbhelper.QueueFilter{ChatID: chatID, Limit: queueInspectMaxItems + 1})
+	if len(items) <= 0 {
+		return messageQueueEmpty
+	}
+	more := len(items) > queueInspectMaxItems
+	if more {
+		items = items[:queueInspectMaxItems]
+	}
+
+	lines := []string{}
+	buttons := [][]bot.InlineKeyboardButton{}
+	for _, q := range items {
+		lines = append(lines, fmt.Sprintf("#%d chat:%d tries:%d/%d fire:%s %s",
+			q.ID, q.ChatID, q.NumTries, maxNumTries, q.FireOn.Format(reminderTimeFormat), queueItemState(q, maxNumTries)))
+		lines = append(lines, "  "+q.Message)
+		if q.LastError != "" {
+			lines = append(lines, "  ⚠ "+lastErrorText(q))
+		}
+
+		retry := fmt.Sprintf("%s %s %d %d", commandQueue, queueParamRetry, q.ChatID, q.ID)
+		drop := fmt.Sprintf("%s %s %d %d", commandQueue, queueParamDrop, q.ChatID, q.ID)
+		buttons = append(buttons, []bot.InlineKeyboardButton{
+			bot.InlineKeyboardButton{Text: fmt.Sprintf(messageQueueRetryFormat, q.ID), CallbackData: &retry},
+			bot.InlineKeyboardButton{Text: fmt.Sprintf(messageQueueDropFormat, q.ID), CallbackData: &drop},
+		})
+	}
+	if more {
+		lines = append(lines, messageQueueMore)
+	}
+
+	options["reply_markup"] = bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+
+	return strings.Join(lines, "\n")
+}
+
+// process callback query of /queue actions
+func processQueueCallback(query bot.CallbackQuery, txt string) string {
+	if !isAdmin(int64(query.From.ID)) {
+		return messageAnnounceAdminOnly
+	}
+
+	params := strings.Fields(strings.TrimPrefix(txt, commandQueue))
+	if len(params) != 3 {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+	chatID, err1 := strconv.ParseInt(params[1], 10, 64)
+	queueID, err2 := strconv.ParseInt(params[2], 10, 64)
+	if err1 != nil || err2 != nil {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+
+	q, exists := db.QueueItem(chatID, queueID)
+	if !exists {
+		return messageQueueItemGone
+	}
+
+	switch params[0] {
+	case queueParamRetry:
+		if db.RetryQueueItem(chatID, queueID, time.Now()) && db.SetQueueItemPaused(chatID, queueID, dbhelper.NotPaused) {
+			db.Log(fmt.Sprintf("admin %d retried queue item %d", query.From.ID, queueID))
+
+			return messageQueueRetried
+		}
+	case queueParamDrop:
+		if db.DeleteQueueItem(chatID, queueID) {
+			fireWebhooks(webhookEventCanceled, q, "")
+
+			db.Log(fmt.Sprintf("admin %d dropped queue item %d", query.From.ID, queueID))
+
+			return messageQueueDropped
+		}
+	default:
+		log.Printf("*** Unprocessable callback query: %s", txt)
+	}
+
+	return messageError
+}
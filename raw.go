@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	commandRaw = "/raw"
+
+	rawOpEnqueue = "enqueue"
+	rawOpList    = "list"
+	rawOpGet     = "get"
+	rawOpCancel  = "cancel"
+
+	rawTimeFormat = "2006-01-02 15:04"
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageRawUsage = `사용법: /raw {"op":"enqueue","msg":"내용","at":"2006-01-02 15:04"}
+
+op: enqueue(msg, at, [recur], [chat]), list([chat]), get(id, [chat]), cancel(id, [chat])`
+)
+
+// request of /raw command
+type rawRequest struct {
+	Op    string `json:"op"`
+	Msg   string `json:"msg,omitempty"`
+	At    string `json:"at,omitempty"`    // "2006-01-02 15:04" or RFC3339
+	Recur string `json:"recur,omitempty"` // recurrence rule (eg. "daily")
+	ID    int64  `json:"id,omitempty"`
+	Chat  int64  `json:"chat,omitempty"` // (default: current chat)
+}
+
+// required fields of each op
+var rawRequiredFields = map[string][]string{
+	rawOpEnqueue: []string{"msg", "at"},
+	rawOpList:    []string{},
+	rawOpGet:     []string{"id"},
+	rawOpCancel:  []string{"id"},
+}
+
+// process /raw command: answer given json request with a json response
+func processRawCommand(chatID, userID int64, txt string) string {
+	if !isAdmin(userID) {
+		return messageAnnounceAdminOnly
+	}
+
+	payload := strings.TrimSpace(strings.TrimPrefix(txt, commandRaw))
+	if payload == "" {
+		return messageRawUsage
+	}
+
+	var response apiResponse
+	if req, err := parseRawRequest(payload); err != nil {
+		response = apiResponse{Ok: false, Error: err.Error()}
+	} else {
+		if req.Chat == 0 {
+			req.Chat = chatID
+		}
+
+		if result, err := executeRawRequest(req, userID); err != nil {
+			response = apiResponse{Ok: false, Error: err.Error()}
+		} else {
+			response = apiResponse{Ok: true, Result: result}
+		}
+	}
+
+	bytes, _ := json.MarshalIndent(response, "", "  ")
+
+	return string(bytes)
+}
+
+// parse and validate given json payload
+func parseRawRequest(payload string) (req rawRequest, err error) {
+	decoder := json.NewDecoder(bytes.NewBufferString(payload))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&req); err != nil {
+		return req, fmt.Errorf("malformed request: %s", err)
+	}
+
+	required, exists := rawRequiredFields[req.Op]
+	if !exists {
+		return req, fmt.Errorf("unknown op: '%s'", req.Op)
+	}
+	for _, field := range required {
+		switch {
+		case field == "msg" && strings.TrimSpace(req.Msg) == "",
+			field == "at" && req.At == "",
+			field == "id" && req.ID <= 0:
+			return req, fmt.Errorf("'%s' is required for op '%s'", field, req.Op)
+		}
+	}
+
+	return req, nil
+}
+
+// execute given request
+func executeRawRequest(req rawRequest, userID int64) (result interface{}, err error) {
+	switch req.Op {
+	case rawOpEnqueue:
+		at, err := time.ParseInLocation(rawTimeFormat, req.At, _location)
+		if err != nil {
+			if at, err = time.Parse(time.RFC3339, req.At); err != nil {
+				return nil, fmt.Errorf("malformed 'at': %s", req.At)
+			}
+		}
+		if at.Before(time.Now()) {
+			return nil, fmt.Errorf("'at' is in the past: %s", req.At)
+		}
+		if req.Recur != "" {
+			if _, err := nextOccurrence(req.Recur, at); err != nil {
+				return nil, fmt.Errorf("malformed 'recur': %s", err)
+			}
+		}
+
+		msg, err := sanitizeReminderText(req.Chat, req.Msg)
+		if err != nil {
+			return nil, fmt.Errorf("rejected 'msg': %s", err)
+		}
+
+		item := dbhelper.QueueItem{
+			ChatID:     req.Chat,
+			UserID:     userID,
+			Message:    msg,
+			FireOn:     at,
+			Recurrence: req.Recur,
+		}
+		queueID, enqueued := db.EnqueueItem(item)
+		if !enqueued {
+			return nil, fmt.Errorf("failed to enqueue")
+		}
+		item.ID = queueID
+
+		fireWebhooks(webhookEventCreated, item, "")
+		flagQueueItemIfNeeded(item)
+
+		return item, nil
+	case rawOpList:
+		return db.UndeliveredQueueItems(req.Chat), nil
+	case rawOpGet:
+		if item, exists := db.QueueItem(req.Chat, req.ID); exists {
+			return item, nil
+		}
+		return nil, fmt.Errorf("no such item: %d", req.ID)
+	case rawOpCancel:
+		item, exists := db.QueueItem(req.Chat, req.ID)
+		if !exists {
+			return nil, fmt.Errorf("no such item: %d", req.ID)
+		}
+		if db.DeleteQueueItem(req.Chat, req.ID) {
+			fireWebhooks(webhookEventCanceled, item, "")
+
+			return map[string]int64{"id": req.ID}, nil
+		}
+		return nil, fmt.Errorf("failed to cancel: %d", req.ID)
+	}
+
+	return nil, fmt.Errorf("unknown op: '%s'", req.Op)
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// rebuild: reminders which were confirmed by the bot but lost (eg. after corruption of the database) are reconstructed
+// from a chat export of Telegram Desktop (JSON), by parsing the confirmation messages of the bot in it
+//
+//	$ reminderbot rebuild --export result.json [--dry-run]
+//
+// (only future reminders are rebuilt; ones which are already in the queue, or canceled later in the chat are skipped)
+
+const (
+	subcommandRebuild = "rebuild"
+
+	rebuildAPIAITimeLayout = "2006-01-02 15:04:05"
+)
+
+// speeches of the confirmed intent of api.ai (see ai/apiai.go)
+var rebuildAPIAIConfirmedRegex = regexp.MustCompile(`(?s)^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})에 "(.+)"(?:를 보내드리겠습니다|라고 알려드리겠습니다)\.$`)
+
+// chat export of Telegram Desktop (result.json)
+type chatExport struct {
+	ID       int64               `json:"id"`
+	Type     string              `json:"type"` // "personal_chat", "private_group", "private_supergroup", ...
+	Messages []chatExportMessage `json:"messages"`
+}
+
+// a message in the chat export
+type chatExportMessage struct {
+	Type         string          `json:"type"` // "message" or "service"
+	Date         string          `json:"date"` // (local time of the exporter)
+	DateUnixtime string          `json:"date_unixtime,omitempty"`
+	FromID       string          `json:"from_id,omitempty"` // eg. "user123456789"
+	Text         json.RawMessage `json:"text"`              // string, or array of strings and text entities
+}
+
+// a reminder found in the chat export
+type rebuiltReminder struct {
+	userID      int64
+	message     string
+	fireOn      time.Time
+	recurrence  string
+	confirmedOn time.Time
+}
+
+// run `rebuild` subcommand: enqueue reminders confirmed in given chat export, then exit
+//
+// (they will be delivered when the bot starts)
+func runRebuild(args []string) {
+	flags := flag.NewFlagSet(subcommandRebuild, flag.ExitOnError)
+	exportPath := flags.String("export", "", "path of the chat export of Telegram Desktop (JSON)")
+	chatID := flags.Int64("chat", 0, "chat id of the reminders (default: from the chat export)")
+	botID := flags.Int64("bot", 0, "user id of this bot (default: from telegram)")
+	dryRun := flags.Bool("dry-run", false, "only print the reminders to be rebuilt")
+	flags.Parse(args)
+
+	if *exportPath == "" {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	setup()
+
+	data, err := ioutil.ReadFile(*exportPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "*** failed to read chat export: %s\n", err)
+		os.Exit(1)
+	}
+	var export chatExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		fmt.Fprintf(os.Stderr, "*** failed to parse chat export: %s\n", err)
+		os.Exit(1)
+	}
+
+	if *chatID == 0 {
+		*chatID = chatIDOfExport(export)
+	}
+	if *botID == 0 {
+		if me := telegram.GetMe(); me.Ok {
+			*botID = int64(me.Result.ID)
+		} else {
+			fmt.Fprintf(os.Stderr, "*** failed to get the user id of this bot (give it with --bot)\n")
+			os.Exit(1)
+		}
+	}
+
+	reminders, numUnmatchedCancels := rebuildReminders(export, *botID)
+
+	numRebuilt := 0
+	existing := db.UndeliveredQueueItems(*chatID)
+	for _, r := range reminders {
+		schedule := r.fireOn.Format(reminderTimeFormat)
+		if r.recurrence != "" {
+			schedule += " (" + r.recurrence + ")"
+		}
+
+		if isInQueue(existing, r) {
+			fmt.Printf("%s  %q  exists\n", schedule, r.message)
+			continue
+		}
+		if *dryRun {
+			fmt.Printf("%s  %q\n", schedule, r.message)
+			continue
+		}
+
+		if queueID, enqueued := db.EnqueueItem(dbhelper.QueueItem{
+			ChatID:     *chatID,
+			UserID:     r.userID,
+			Message:    r.message,
+			FireOn:     r.fireOn,
+			Recurrence: r.recurrence,
+		}); enqueued {
+			fmt.Printf("%s  %q  rebuilt (%d)\n", schedule, r.message, queueID)
+			numRebuilt++
+		} else {
+			fmt.Printf("%s  %q  FAILED\n", schedule, r.message)
+		}
+	}
+
+	if numUnmatchedCancels > 0 {
+		fmt.Printf("*** %d cancel(s) without the texts of reminders could not be matched: check the reminders above\n", numUnmatchedCancels)
+	}
+
+	if *dryRun {
+		fmt.Printf("Found %d confirmed reminder(s) of chat %d\n", len(reminders), *chatID)
+		return
+	}
+
+	if numRebuilt > 0 {
+		db.Log(fmt.Sprintf("rebuilt %d reminder(s) of chat %d from chat export", numRebuilt, *chatID))
+	}
+	fmt.Printf("Rebuilt %d of %d confirmed reminder(s) of chat %d\n", numRebuilt, len(reminders), *chatID)
+}
+
+// chat id of given chat export (ids of groups are exported without their prefixes)
+func chatIDOfExport(export chatExport) int64 {
+	switch export.Type {
+	case "private_group":
+		return -export.ID
+	case "private_supergroup", "public_supergroup", "private_channel", "public_channel":
+		return -1000000000000 - export.ID
+	}
+	return export.ID
+}
+
+// future reminders confirmed by given bot in given chat export
+//
+// (also returns the number of cancels which could not be matched with reminders, eg. canceled with buttons by older versions)
+func rebuildReminders(export chatExport, botID int64) (reminders []rebuiltReminder, numUnmatchedCancels int) {
+	now := time.Now()
+	botFromID := fmt.Sprintf("user%d", botID)
+
+	reminders = []rebuiltReminder{}
+	var userID int64
+	for _, m := range export.Messages {
+		if m.Type != "message" {
+			continue
+		}
+
+		if m.FromID != botFromID {
+			// (the last user who talked to the bot)
+			if id, err := strconv.ParseInt(strings.TrimPrefix(m.FromID, "user"), 10, 64); err == nil {
+				userID = id
+			}
+			continue
+		}
+
+		text := chatExportText(m.Text)
+		confirmedOn := chatExportDate(m)
+
+		// canceled later
+		if canceled, ok := parseCancelConfirmation(text); ok {
+			kept := []rebuiltReminder{}
+			for _, r := range reminders {
+				if r.message != canceled && tidyReminderText(r.message) != canceled {
+					kept = append(kept, r)
+				}
+			}
+			reminders = kept
+			continue
+		}
+		if text == messageReminderCanceled {
+			numUnmatchedCancels++
+			continue
+		}
+
+		if r, ok := parseConfirmation(text, confirmedOn); ok && (r.recurrence != "" || r.fireOn.After(now)) {
+			r.userID = userID
+			reminders = append(reminders, r)
+		}
+	}
+
+	return reminders, numUnmatchedCancels
+}
+
+// plain text of a message in the chat export
+func chatExportText(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+
+	var parts []interface{}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return ""
+	}
+	for _, part := range parts {
+		switch p := part.(type) {
+		case string:
+			text += p
+		case map[string]interface{}:
+			if t, ok := p["text"].(string); ok {
+				text += t
+			}
+		}
+	}
+	return text
+}
+
+// sent time of a message in the chat export
+func chatExportDate(m chatExportMessage) time.Time {
+	if unix, err := strconv.ParseInt(m.DateUnixtime, 10, 64); err == nil {
+		return time.Unix(unix, 0).In(_location)
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04:05", m.Date, _location); err == nil {
+		return t
+	}
+	return time.Now()
+}
+
+// parse given confirmation message of the bot (in any of the date/time formats of chats)
+func parseConfirmation(text string, confirmedOn time.Time) (r rebuiltReminder, ok bool) {
+	r.confirmedOn = confirmedOn
+
+	// confirmed by api.ai
+	if matches := rebuildAPIAIConfirmedRegex.FindStringSubmatch(text); matches != nil {
+		if t, err := time.ParseInLocation(rebuildAPIAITimeLayout, matches[1], _location); err == nil {
+			r.fireOn, r.message = t, matches[2]
+			return r, true
+		}
+	}
+
+	// reminder at a time
+	if parts := strings.SplitN(messageReminderScheduledFormat, "%s", 2); len(parts) == 2 && strings.HasSuffix(text, parts[1]) {
+		rest := strings.TrimSuffix(text, parts[1])
+		for n := 1; n < len(rest); n++ {
+			if t, parsed := parseChatTime(parts[0], rest[:n]); parsed {
+				r.fireOn, r.message = t, rest[n:]
+				return r, true
+			}
+		}
+	}
+
+	// recurring reminder
+	if matches := formatRegex(messageRecurrenceCreatedFormat).FindStringSubmatch(text); matches != nil {
+		if r.recurrence, r.fireOn, ok = parseRecurrenceDescription(matches[1], confirmedOn); ok {
+			r.message = matches[2]
+			return r, true
+		}
+	}
+
+	// cron
+	if matches := formatRegex(messageCronScheduledFormat).FindStringSubmatch(text); matches != nil {
+		if r.recurrence, r.fireOn, ok = parseRecurrenceDescription(fmt.Sprintf("cron '%s'", matches[1]), confirmedOn); ok {
+			r.message = matches[2]
+			return r, true
+		}
+	}
+
+	// in simple mode
+	if matches := formatRegex(messageSimpleConfirmFormat).FindStringSubmatch(text); matches != nil {
+		r.message = matches[2]
+		if t, parsed := parseChatTime(reminderTimeFormat, matches[1]); parsed {
+			r.fireOn = t
+			return r, true
+		}
+		if r.recurrence, r.fireOn, ok = parseRecurrenceDescription(matches[1], confirmedOn); ok {
+			return r, true
+		}
+	}
+
+	return r, false
+}
+
+// parse given message of the bot for a canceled reminder
+//
+// (canceled with /cancel and a text, a number in /list, or a button)
+func parseCancelConfirmation(text string) (message string, ok bool) {
+	for _, format := range []string{messageCancelMatchedFormat, messageCanceledFormat} {
+		if matches := formatRegex(format).FindStringSubmatch(text); matches != nil {
+			return matches[1], true
+		}
+	}
+	return "", false
+}
+
+// regular expression which matches texts formatted with given format (of %s only)
+func formatRegex(format string) *regexp.Regexp {
+	parts := strings.Split(format, "%s")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile(`(?s)^` + strings.Join(parts, `(.+?)`) + `$`)
+}
+
+// parse given time formatted with given layout, in any of the date/time formats of chats
+func parseChatTime(layout, value string) (time.Time, bool) {
+	for _, format := range settingDateFormats {
+		for _, hour12 := range []bool{false, true} {
+			l := chatLayout(dbhelper.ChatSettings{DateFormat: format, Hour12: hour12}, layout)
+			v := value
+			if hour12 {
+				v = strings.NewReplacer(messageTimeAM, "AM", messageTimePM, "PM").Replace(v)
+			}
+
+			if t, err := time.ParseInLocation(l, v, _location); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+var (
+	rebuildWeekdayRegex    = regexp.MustCompile(`^(매주|격주) (\S+)$`)
+	rebuildDayOfMonthRegex = regexp.MustCompile(`^매월 (\d{1,2})일$`)
+	rebuildLunarRegex      = regexp.MustCompile(`^매년 음력 (\d{1,2})월 (\d{1,2})일$`)
+	rebuildCronRegex       = regexp.MustCompile(`^cron '(.+)'$`)
+)
+
+// parse given description of recurrence (reverse of describeRecurrence),
+// and return its rule and next occurrence after now (from the time it was confirmed)
+func parseRecurrenceDescription(description string, confirmedOn time.Time) (rule string, next time.Time, ok bool) {
+	if matches := rebuildCronRegex.FindStringSubmatch(description); matches != nil {
+		c, err := parseCronSpec(matches[1])
+		if err != nil {
+			return "", next, false
+		}
+		if next, err = c.next(time.Now()); err != nil {
+			return "", next, false
+		}
+		return cronRecurrence(matches[1]), next, true
+	}
+
+	// (messages of AM/PM can be overridden, so it is compiled here)
+	clockRegex := regexp.MustCompile(`^(.*?)\s*((?:` + regexp.QuoteMeta(messageTimeAM) + `|` + regexp.QuoteMeta(messageTimePM) + `) )?(\d{1,2}:\d{2})$`)
+	matches := clockRegex.FindStringSubmatch(description)
+	if matches == nil {
+		return "", next, false
+	}
+	clock, parsed := parseChatTime(defaultClockLayout, matches[2]+matches[3])
+	if !parsed {
+		return "", next, false
+	}
+
+	y, m, d := confirmedOn.Date()
+	first := time.Date(y, m, d, clock.Hour(), clock.Minute(), 0, 0, _location)
+
+	var err error
+	switch schedule := matches[1]; {
+	case schedule == "매일":
+		rule = recurrenceDaily
+	case schedule == "매월 말일":
+		rule = fmt.Sprintf("%s:%s", recurrenceMonthly, recurrenceLastDay)
+		first = dayOfMonthClamped(y, m, 31, first)
+	case rebuildWeekdayRegex.MatchString(schedule):
+		weekday := rebuildWeekdayRegex.FindStringSubmatch(schedule)
+		index := -1
+		for i, name := range weekdayNames {
+			if name == weekday[2] {
+				index = i
+			}
+		}
+		if index < 0 {
+			return "", next, false
+		}
+
+		rule = recurrenceWeekly
+		if weekday[1] == "격주" {
+			rule = recurrenceBiweekly
+		}
+		first = first.AddDate(0, 0, (index-int(first.Weekday())+7)%7)
+	case rebuildDayOfMonthRegex.MatchString(schedule):
+		day, _ := strconv.Atoi(rebuildDayOfMonthRegex.FindStringSubmatch(schedule)[1])
+		rule = fmt.Sprintf("%s:%d", recurrenceMonthly, day)
+		first = dayOfMonthClamped(y, m, day, first)
+	case rebuildLunarRegex.MatchString(schedule):
+		lunarDate := rebuildLunarRegex.FindStringSubmatch(schedule)
+		month, _ := strconv.Atoi(lunarDate[1])
+		day, _ := strconv.Atoi(lunarDate[2])
+		rule = lunarRecurrence(month, day)
+		if first, err = nextOccurrence(rule, first.AddDate(-1, 0, 0)); err != nil {
+			return "", next, false
+		}
+	default:
+		return "", next, false
+	}
+
+	if next, err = nextOccurrenceAfterNow(rule, first); err != nil {
+		return "", next, false
+	}
+	return rule, next, true
+}
+
+// check if given reminder is already in given queue items
+func isInQueue(items []dbhelper.QueueItem, r rebuiltReminder) bool {
+	for _, q := range items {
+		if q.Message == r.message && q.Recurrence == r.recurrence && (r.recurrence != "" || q.FireOn.Equal(r.fireOn)) {
+			return true
+		}
+	}
+	return false
+}
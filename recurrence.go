@@ -0,0 +1,365 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	aihelper "github.com/meinside/telegram-bot-reminder-api.ai/ai"
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+	"github.com/meinside/telegram-bot-reminder-api.ai/lunar"
+)
+
+// recurrence rules
+//
+//	"daily"      : every day
+//	"weekly"       : every week, on the same weekday
+//	"biweekly"     : every other week, on the same weekday
+//	"monthly:N"    : every month, on the N-th day (or the last day of shorter months)
+//	"monthly:last" : every month, on the last day
+//	"lunar:M-D"    : every year, on the D-th day of the M-th lunar month (or its last day if it has only 29 days)
+//	"cron:SPEC"    : at the times matching the cron spec (eg. "cron:0 9 * * 1-5")
+const (
+	recurrenceDaily    = "daily"
+	recurrenceWeekly   = "weekly"
+	recurrenceBiweekly = "biweekly"
+	recurrenceMonthly  = "monthly"
+	recurrenceLunar    = "lunar"
+	recurrenceCron     = "cron"
+
+	recurrenceLastDay = "last"
+
+	recurringIndicator = "🔁 "
+
+	recurrencePendingMinutes = 10 // recurrences will be waited for the reminder to be confirmed this long
+)
+
+// words of recurrences in texts, which are not anchors of api.ai (eg. "매일 아침 8시에 약 먹으라고 알려줘")
+var recurrenceWords = map[string]string{
+	"매일":  recurrenceDaily,
+	"날마다": recurrenceDaily,
+	"매주":  recurrenceWeekly,
+	"주마다": recurrenceWeekly,
+	"매달":  recurrenceMonthly,
+	"매월":  recurrenceMonthly,
+	"달마다": recurrenceMonthly,
+}
+
+var recurrenceTextRegex = regexp.MustCompile(`(?:^|\s)(매일|날마다|매주|주마다|매달|매월|달마다)(?:\s+|$)`)
+
+// anchors of api.ai which start with the words above (eg. "매월 말일", "매달 월급날")
+var recurrenceAnchorSuffixes = []string{"말일", "마지막", "초", "1일", "월급날"}
+
+// recurrence waiting for the reminder to be confirmed
+type pendingRecurrence struct {
+	name       string
+	incomplete bool // (api.ai is asking for missing parameters)
+	expiresOn  time.Time
+}
+
+// recurrences of reminders not confirmed yet (key: chat id/user id)
+var _pendingRecurrences = struct {
+	sync.Mutex
+	recurrences map[string]pendingRecurrence
+}{
+	recurrences: map[string]pendingRecurrence{},
+}
+
+// recurrence rule for every month on the same day as given time
+func monthlyRecurrence(t time.Time) string {
+	return fmt.Sprintf("%s:%d", recurrenceMonthly, t.Day())
+}
+
+// korean names of weekdays
+var weekdayNames = []string{"일요일", "월요일", "화요일", "수요일", "목요일", "금요일", "토요일"}
+
+// displayable description of given recurrence rule at given time (eg. "매주 화요일 15:04"), in the time format of given chat
+func describeRecurrence(chatID int64, rule string, t time.Time) string {
+	name, param := splitRecurrence(rule)
+	clock := clockOf(chatID, t)
+
+	switch name {
+	case recurrenceDaily:
+		return fmt.Sprintf("매일 %s", clock)
+	case recurrenceWeekly:
+		return fmt.Sprintf("매주 %s %s", weekdayNames[t.Weekday()], clock)
+	case recurrenceBiweekly:
+		return fmt.Sprintf("격주 %s %s", weekdayNames[t.Weekday()], clock)
+	case recurrenceMonthly:
+		if param == recurrenceLastDay {
+			return fmt.Sprintf("매월 말일 %s", clock)
+		}
+		return fmt.Sprintf("매월 %s일 %s", param, clock)
+	case recurrenceLunar:
+		if month, day, err := lunarDateParam(param); err == nil {
+			return fmt.Sprintf("매년 음력 %d월 %d일 %s", month, day, clock)
+		}
+	case recurrenceCron:
+		return fmt.Sprintf("cron '%s'", param)
+	}
+
+	return rule
+}
+
+// remove the word of recurrence from given text (for api.ai), and return the name of the recurrence
+//
+// (eg. "매일 아침 8시에 약 먹으라고 알려줘" => "아침 8시에 약 먹으라고 알려줘", "daily")
+func parseRecurrenceText(txt string) (query, name string, ok bool) {
+	loc := recurrenceTextRegex.FindStringSubmatchIndex(txt)
+	if loc == nil {
+		return txt, "", false
+	}
+
+	// (left for api.ai)
+	rest := txt[loc[1]:]
+	for _, suffix := range recurrenceAnchorSuffixes {
+		if strings.HasPrefix(rest, suffix) {
+			return txt, "", false
+		}
+	}
+
+	name = recurrenceWords[txt[loc[2]:loc[3]]]
+	query = strings.TrimSpace(txt[:loc[0]] + " " + rest)
+
+	return query, name, true
+}
+
+// keep given recurrence until the reminder of given user in given chat is confirmed
+func setPendingRecurrence(chatID, userID int64, name string) {
+	_pendingRecurrences.Lock()
+	_pendingRecurrences.recurrences[pendingKey(chatID, userID)] = pendingRecurrence{
+		name:      name,
+		expiresOn: time.Now().Add(recurrencePendingMinutes * time.Minute),
+	}
+	_pendingRecurrences.Unlock()
+}
+
+// update the pending recurrence of given user in given chat with the intent of api.ai's response
+//
+// (kept only while its reminder is being asked for confirmation or missing parameters,
+// so that it is not applied to other reminders)
+func updatePendingRecurrence(chatID, userID int64, recurring bool, intentName string, incomplete bool) {
+	key := pendingKey(chatID, userID)
+
+	_pendingRecurrences.Lock()
+	defer _pendingRecurrences.Unlock()
+
+	pending, exists := _pendingRecurrences.recurrences[key]
+	if !exists {
+		return
+	}
+
+	if intentName == aihelper.IntentNameMessage && (recurring || pending.incomplete) {
+		pending.incomplete = incomplete
+		_pendingRecurrences.recurrences[key] = pending
+	} else {
+		delete(_pendingRecurrences.recurrences, key)
+	}
+}
+
+// take the pending recurrence of given user in given chat
+func takePendingRecurrence(chatID, userID int64) (name string, exists bool) {
+	key := pendingKey(chatID, userID)
+
+	_pendingRecurrences.Lock()
+	defer _pendingRecurrences.Unlock()
+
+	pending, exists := _pendingRecurrences.recurrences[key]
+	if !exists {
+		return "", false
+	}
+	delete(_pendingRecurrences.recurrences, key)
+
+	if time.Now().After(pending.expiresOn) {
+		return "", false
+	}
+
+	return pending.name, true
+}
+
+// the first time of given hour and minute from now on
+func nextTimeOfDay(hour, minute int) time.Time {
+	now := time.Now()
+	t := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	if t.Before(now) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// the first time of given weekday and hour from now on
+func nextTimeOfWeekday(weekday time.Weekday, hour int) time.Time {
+	now := time.Now()
+	t := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	t = t.AddDate(0, 0, (int(weekday)-int(t.Weekday())+7)%7)
+	if t.Before(now) {
+		t = t.AddDate(0, 0, 7)
+	}
+	return t
+}
+
+// split given rule into its name and parameter
+func splitRecurrence(rule string) (name, param string) {
+	if i := strings.Index(rule, ":"); i >= 0 {
+		return rule[:i], rule[i+1:]
+	}
+	return rule, ""
+}
+
+// the occurrence of given rule right after given time
+func nextOccurrence(rule string, last time.Time) (time.Time, error) {
+	name, param := splitRecurrence(rule)
+
+	switch name {
+	case recurrenceDaily:
+		return last.AddDate(0, 0, 1), nil
+	case recurrenceWeekly:
+		return last.AddDate(0, 0, 7), nil
+	case recurrenceBiweekly:
+		return last.AddDate(0, 0, 14), nil
+	case recurrenceMonthly:
+		day, err := dayOfMonthParam(param)
+		if err != nil {
+			return last, fmt.Errorf("%s: %s", err, rule)
+		}
+		return dayOfMonthClamped(last.Year(), last.Month()+1, day, last), nil
+	case recurrenceLunar:
+		month, day, err := lunarDateParam(param)
+		if err != nil {
+			return last, fmt.Errorf("%s: %s", err, rule)
+		}
+		year, _, _, _, err := lunar.FromSolar(last)
+		if err != nil {
+			return last, err
+		}
+		return lunarDateClamped(year+1, month, day, last)
+	case recurrenceCron:
+		c, err := parseCronSpec(param)
+		if err != nil {
+			return last, err
+		}
+		return c.next(last)
+	}
+
+	return last, fmt.Errorf("unknown recurrence rule: %s", rule)
+}
+
+// day of month from given parameter of monthly rule ("last" => 31, to be clamped)
+func dayOfMonthParam(param string) (int, error) {
+	if param == recurrenceLastDay {
+		return 31, nil
+	}
+
+	day, err := strconv.Atoi(param)
+	if err != nil || day < 1 || day > 31 {
+		return 0, fmt.Errorf("invalid day of month")
+	}
+	return day, nil
+}
+
+// month and day from given parameter of lunar rule (eg. "8-15")
+func lunarDateParam(param string) (month, day int, err error) {
+	if _, err = fmt.Sscanf(param, "%d-%d", &month, &day); err != nil || month < 1 || month > 12 || day < 1 || day > 30 {
+		return 0, 0, fmt.Errorf("invalid lunar date")
+	}
+	return month, day, nil
+}
+
+// recurrence rule for every year on given lunar month and day
+func lunarRecurrence(month, day int) string {
+	return fmt.Sprintf("%s:%d-%d", recurrenceLunar, month, day)
+}
+
+// solar date of given (non-leap) lunar date with the time of given clock, clamped to the last day of the lunar month
+func lunarDateClamped(year, month, day int, clock time.Time) (time.Time, error) {
+	if year < lunar.MinYear || year > lunar.MaxYear {
+		return clock, fmt.Errorf("lunar year out of range: %d", year)
+	}
+	if days := lunar.DaysInMonth(year, month, false); day > days {
+		day = days
+	}
+
+	solar, err := lunar.ToSolar(year, month, day, false, clock.Location())
+	if err != nil {
+		return clock, err
+	}
+
+	return time.Date(solar.Year(), solar.Month(), solar.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, clock.Location()), nil
+}
+
+// the first occurrence of given rule after now
+func nextOccurrenceAfterNow(rule string, last time.Time) (next time.Time, err error) {
+	now := time.Now()
+
+	next = last
+	if name, _ := splitRecurrence(rule); name == recurrenceCron && next.Before(now) { // (no need to go through missed ones)
+		next = now
+	}
+	for !next.After(now) {
+		if next, err = nextOccurrence(rule, next); err != nil {
+			return next, err
+		}
+	}
+
+	return next, nil
+}
+
+// given day of given month with the time of given clock, clamped to the last day of the month
+//
+// (month overflows are normalized, eg. 13th month of 2017 => 1st month of 2018)
+func dayOfMonthClamped(year int, month time.Month, day int, clock time.Time) time.Time {
+	firstDay := time.Date(year, month, 1, clock.Hour(), clock.Minute(), clock.Second(), 0, clock.Location())
+	lastDay := firstDay.AddDate(0, 1, -1).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+
+	return firstDay.AddDate(0, 0, day-1)
+}
+
+// scheduled time of given queue item
+// (its fire time can be deferred, eg. by vacations, retries, or staggering, so next occurrences are calculated from this)
+func scheduledTimeOf(q dbhelper.QueueItem) time.Time {
+	if q.ScheduledOn.IsZero() || q.ScheduledOn.Unix() <= 0 {
+		return q.FireOn
+	}
+	return q.ScheduledOn
+}
+
+// enqueue the next occurrence of given (delivered or skipped) recurring reminder
+func scheduleNextOccurrence(q dbhelper.QueueItem) {
+	if q.Recurrence == "" {
+		return
+	}
+
+	next, err := nextOccurrenceAfterNow(q.Recurrence, scheduledTimeOf(q))
+	if err != nil {
+		log.Printf("*** failed to calculate next occurrence of queue item %d: %s", q.ID, err)
+		return
+	}
+
+	// (with all the fields given when enqueued, but not the states of its delivery)
+	if _, enqueued := db.EnqueueItem(dbhelper.QueueItem{
+		ChatID:        q.ChatID,
+		UserID:        q.UserID,
+		Message:       q.Message,
+		FireOn:        next,
+		ScheduledOn:   next,
+		Broadcast:     q.Broadcast,
+		Recurrence:    q.Recurrence,
+		FailurePolicy: q.FailurePolicy,
+		Kind:          q.Kind,
+		RefID:         q.RefID,
+		Assignee:      q.Assignee,
+		Private:       q.Private,
+
+		BusinessConnectionID: q.BusinessConnectionID,
+		Attachment:           q.Attachment,
+	}); !enqueued {
+		log.Printf("*** failed to enqueue next occurrence of queue item %d", q.ID)
+	}
+}
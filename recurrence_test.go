@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextOccurrence(t *testing.T) {
+	_location = time.FixedZone("KST", 9*60*60)
+
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, _location)
+	}
+
+	for _, test := range []struct {
+		rule     string
+		last     time.Time
+		expected time.Time
+	}{
+		{recurrenceDaily, at(2023, 2, 28, 9, 0), at(2023, 3, 1, 9, 0)},
+		{recurrenceWeekly, at(2023, 12, 28, 9, 0), at(2024, 1, 4, 9, 0)},
+		{recurrenceBiweekly, at(2023, 1, 2, 9, 0), at(2023, 1, 16, 9, 0)},
+
+		// monthly: clamped to the last day of shorter months, but back to the day in longer ones
+		{"monthly:15", at(2023, 1, 15, 9, 0), at(2023, 2, 15, 9, 0)},
+		{"monthly:31", at(2023, 1, 31, 9, 0), at(2023, 2, 28, 9, 0)},
+		{"monthly:31", at(2023, 2, 28, 9, 0), at(2023, 3, 31, 9, 0)},
+		{"monthly:31", at(2023, 3, 31, 9, 0), at(2023, 4, 30, 9, 0)},
+		{"monthly:31", at(2024, 1, 31, 9, 0), at(2024, 2, 29, 9, 0)},
+		{"monthly:30", at(2024, 2, 29, 9, 0), at(2024, 3, 30, 9, 0)},
+		{"monthly:last", at(2023, 1, 31, 9, 0), at(2023, 2, 28, 9, 0)},
+		{"monthly:last", at(2023, 2, 28, 9, 0), at(2023, 3, 31, 9, 0)},
+		{"monthly:31", at(2023, 12, 31, 9, 0), at(2024, 1, 31, 9, 0)},
+
+		// cron
+		{"cron:0 9 * * 1-5", at(2023, 1, 6, 9, 0), at(2023, 1, 9, 9, 0)},
+	} {
+		if next, err := nextOccurrence(test.rule, test.last); err != nil {
+			t.Errorf("next occurrence of '%s' after %s: %s", test.rule, test.last, err)
+		} else if !next.Equal(test.expected) {
+			t.Errorf("next occurrence of '%s' after %s: expected %s, got %s", test.rule, test.last, test.expected, next)
+		}
+	}
+
+	for _, rule := range []string{"monthly:0", "monthly:32", "monthly:", "cron:0 0 31 2 *", "yearly"} {
+		if next, err := nextOccurrence(rule, time.Now()); err == nil {
+			t.Errorf("next occurrence of '%s' should fail, but got %s", rule, next)
+		}
+	}
+}
+
+func TestDayOfMonthClamped(t *testing.T) {
+	loc := time.FixedZone("KST", 9*60*60)
+	clock := time.Date(2000, 1, 1, 8, 30, 15, 0, loc)
+
+	for _, test := range []struct {
+		year     int
+		month    time.Month
+		day      int
+		expected time.Time
+	}{
+		{2023, time.January, 31, time.Date(2023, 1, 31, 8, 30, 15, 0, loc)},
+		{2023, time.February, 31, time.Date(2023, 2, 28, 8, 30, 15, 0, loc)},
+		{2024, time.February, 31, time.Date(2024, 2, 29, 8, 30, 15, 0, loc)},
+		{2023, time.March, 31, time.Date(2023, 3, 31, 8, 30, 15, 0, loc)},
+		{2023, time.April, 31, time.Date(2023, 4, 30, 8, 30, 15, 0, loc)},
+		{2023, time.February, 15, time.Date(2023, 2, 15, 8, 30, 15, 0, loc)},
+
+		// overflowed months are normalized
+		{2023, 13, 31, time.Date(2024, 1, 31, 8, 30, 15, 0, loc)},
+		{2023, 14, 30, time.Date(2024, 2, 29, 8, 30, 15, 0, loc)},
+	} {
+		if clamped := dayOfMonthClamped(test.year, test.month, test.day, clock); !clamped.Equal(test.expected) {
+			t.Errorf("day %d of %d-%d: expected %s, got %s", test.day, test.year, test.month, test.expected, clamped)
+		}
+	}
+}
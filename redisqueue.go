@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// redis-backed queue: queue items which are due soon are indexed in a sorted set (scored by their fire times),
+// and popped from it every second for near-real-time firing
+//
+// the database is still the source of truth (and history), so popped items are checked with it before delivery,
+// and claimed with a key in redis, so that multiple senders can share the same sorted set
+
+const (
+	redisDefaultKey            = "reminder:queue"
+	redisClaimKeyPrefix        = "reminder:claim:"
+	redisPollIntervalMillis    = 1000
+	redisMinClaimSeconds       = 60
+	redisSyncLookaheadMultiple = 2 // items due within (monitor interval * this) are indexed on each sync
+)
+
+// config for redis-backed queue
+type redisConfig struct {
+	Addr     string `json:"addr"` // eg. "localhost:6379"
+	Password string `json:"password,omitempty"`
+	DB       int    `json:"db,omitempty"`
+	Key      string `json:"key,omitempty"` // key of the sorted set (default: "reminder:queue")
+}
+
+// validate redis config
+func (c redisConfig) validate() (problems []string) {
+	if c.Addr == "" {
+		problems = append(problems, "addr of redis is empty")
+	}
+	if c.DB < 0 {
+		problems = append(problems, fmt.Sprintf("db of redis should not be negative: %d", c.DB))
+	}
+
+	return problems
+}
+
+var _redis *redis.Client
+
+// check if the redis-backed queue is enabled
+func isRedisQueueEnabled() bool {
+	return _redis != nil
+}
+
+// connect to redis and start popping due items from it
+func startRedisQueue(conf redisConfig, client *bot.Bot) error {
+	_redis = redis.NewClient(&redis.Options{
+		Addr:     conf.Addr,
+		Password: conf.Password,
+		DB:       conf.DB,
+	})
+	if err := _redis.Ping().Err(); err != nil {
+		_redis = nil
+		return err
+	}
+
+	log.Printf("> Starting redis queue: %s", conf.Addr)
+
+	syncRedisQueue()
+	go monitorRedisQueue(time.NewTicker(redisPollIntervalMillis*time.Millisecond), client)
+
+	return nil
+}
+
+// key of the sorted set
+func redisQueueKey() string {
+	if _conf.Redis != nil && _conf.Redis.Key != "" {
+		return _conf.Redis.Key
+	}
+	return redisDefaultKey
+}
+
+// member of the sorted set for given queue item
+func redisQueueMember(q dbhelper.QueueItem) string {
+	return fmt.Sprintf("%d:%d", q.ChatID, q.ID)
+}
+
+// index queue items which are due soon (called periodically instead of processing the queue directly)
+func syncRedisQueue() {
+	lookahead := time.Duration(_monitorIntervalSeconds*redisSyncLookaheadMultiple) * time.Second
+	items := db.DeliverableQueueItemsUntil(_maxNumTries, time.Now().Add(lookahead))
+	if len(items) <= 0 {
+		return
+	}
+
+	members := []redis.Z{}
+	for _, q := range items {
+		members = append(members, redis.Z{
+			Score:  float64(q.FireOn.Unix()),
+			Member: redisQueueMember(q),
+		})
+	}
+	if err := _redis.ZAdd(redisQueueKey(), members...).Err(); err != nil {
+		log.Printf("*** failed to index queue items in redis: %s", err)
+	}
+
+	if _isVerbose {
+		log.Printf("Indexed %d queue items in redis", len(items))
+	}
+}
+
+// pop due items from redis periodically
+func monitorRedisQueue(monitor *time.Ticker, client *bot.Bot) {
+	for {
+		select {
+		case <-monitor.C:
+			if items := popDueRedisItems(); len(items) > 0 {
+				dispatchQueueItems(client, items)
+			}
+		}
+	}
+}
+
+// pop and claim due items from redis, and return the ones which are still deliverable
+func popDueRedisItems() (items []dbhelper.QueueItem) {
+	key := redisQueueKey()
+	now := time.Now()
+
+	members, err := _redis.ZRangeByScore(key, redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.Unix(), 10),
+	}).Result()
+	if err != nil {
+		log.Printf("*** failed to get due items from redis: %s", err)
+		return nil
+	}
+
+	maxNumTries := maxNumTriesOrDefault()
+
+	claimTTL := time.Duration(_monitorIntervalSeconds*redisSyncLookaheadMultiple) * time.Second
+	if claimTTL < redisMinClaimSeconds*time.Second {
+		claimTTL = redisMinClaimSeconds * time.Second
+	}
+
+	for _, member := range members {
+		// (only one sender can remove it)
+		if removed, err := _redis.ZRem(key, member).Result(); err != nil || removed <= 0 {
+			continue
+		}
+		// (not to be delivered again while being delivered, even if it was indexed again)
+		if claimed, err := _redis.SetNX(redisClaimKeyPrefix+member, now.Unix(), claimTTL).Result(); err != nil || !claimed {
+			continue
+		}
+
+		chatID, queueID, ok := parseRedisQueueMember(member)
+		if !ok {
+			log.Printf("*** malformed member in redis queue: %s", member)
+			continue
+		}
+
+		// check with the database (it may have been delivered, deleted, paused, or rescheduled)
+		q, exists := db.QueueItem(chatID, queueID)
+		if !exists || q.DeliveredOn.Unix() > 0 || q.Paused != dbhelper.NotPaused || q.NumTries >= maxNumTries || q.FireOn.After(now) {
+			continue
+		}
+
+		items = append(items, q)
+	}
+
+	return items
+}
+
+// chat id and queue id from given member of the sorted set
+func parseRedisQueueMember(member string) (chatID, queueID int64, ok bool) {
+	parts := strings.SplitN(member, ":", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+
+	var err error
+	if chatID, err = strconv.ParseInt(parts[0], 10, 64); err != nil {
+		return 0, 0, false
+	}
+	if queueID, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+		return 0, 0, false
+	}
+
+	return chatID, queueID, true
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	reminderTimeFormat = "2006.1.2 15:04"
+
+	pausedIndicator = "⏸ "
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageListToday          = "오늘"
+	messageListTomorrow       = "내일"
+	messageListThisWeek       = "이번 주"
+	messageListNextWeek       = "다음 주"
+	messageListLater          = "나중에"
+	messageListGroupFormat    = "[%s]"
+	messageListItemFormat     = "%d. %s%s (%s, %s)"
+	messageListHint           = "(번호로 취소: /cancel 2)"
+	messageRelativeSoon       = "곧"
+	messageRelativeMinutesFmt = "%d분 후"
+	messageRelativeHoursFmt   = "%d시간 후"
+	messageRelativeDaysFmt    = "%d일 후"
+	messageCancelNoSuchNumber = "%d번 알림이 없습니다. /list 로 번호를 확인해 주세요."
+	messageCanceledFormat     = "'%s' 알림이 취소 되었습니다."
+)
+
+// korean short names of weekdays
+var shortWeekdayNames = []string{"일", "월", "화", "수", "목", "금", "토"}
+
+// format given reminder for listing
+func formatReminder(r dbhelper.QueueItem) string {
+	indicator := ""
+	if r.Paused != dbhelper.NotPaused {
+		indicator = pausedIndicator
+	}
+	if r.Recurrence != "" {
+		indicator += recurringIndicator
+	}
+
+	return fmt.Sprintf("➤ %s%s (%s)", indicator, visibleReminderText(r), formatTimeFor(r.ChatID, r.FireOn, reminderTimeFormat))
+}
+
+// inline keyboard for selecting one of given reminders with given command
+func reminderSelectionKeyboard(reminders []dbhelper.QueueItem, command string) bot.InlineKeyboardMarkup {
+	keys := make(map[string]string)
+	for _, r := range reminders {
+		keys[formatReminder(r)] = fmt.Sprintf("%s %d", command, r.ID)
+	}
+	buttons := bot.NewInlineKeyboardButtonsAsRowsWithCallbackData(keys)
+
+	// add a button for canceling command
+	cancel := commandCancel
+	buttons = append(buttons, []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{
+			Text:         messageCancel,
+			CallbackData: &cancel,
+		},
+	})
+
+	return bot.InlineKeyboardMarkup{
+		InlineKeyboard: buttons,
+	}
+}
+
+// undelivered reminders of given chat, in order of their fire times (numbers in /list follow this order)
+func listedReminders(chatID int64) []dbhelper.QueueItem {
+	reminders := db.UndeliveredQueueItems(chatID)
+	sort.SliceStable(reminders, func(i, j int) bool {
+		if reminders[i].FireOn.Equal(reminders[j].FireOn) {
+			return reminders[i].ID < reminders[j].ID
+		}
+		return reminders[i].FireOn.Before(reminders[j].FireOn)
+	})
+
+	return reminders
+}
+
+// format given reminders for /list: numbered, grouped by day, with relative times
+//
+// eg.
+//
+//	[오늘]
+//	1. 회의 (15:00, 3시간 후)
+//	[이번 주]
+//	2. 🔁 운동 (10.24 (금) 07:00, 3일 후)
+//
+// (dates and weeks follow given settings)
+func formatReminderList(reminders []dbhelper.QueueItem, now time.Time, s dbhelper.ChatSettings) string {
+	lines := []string{}
+
+	lastGroup := ""
+	for i, r := range reminders {
+		group := dayGroupOf(r.FireOn, now, time.Weekday(s.WeekStart))
+		if group != lastGroup {
+			lines = append(lines, fmt.Sprintf(messageListGroupFormat, group))
+			lastGroup = group
+		}
+
+		indicator := ""
+		if r.Paused != dbhelper.NotPaused {
+			indicator = pausedIndicator
+		}
+		if r.Recurrence != "" {
+			indicator += recurringIndicator
+		}
+		if r.Attachment != "" {
+			indicator += attachmentIndicator
+		}
+
+		when := formatTimeWith(s, r.FireOn, defaultClockLayout)
+		if group != messageListToday && group != messageListTomorrow {
+			when = fmt.Sprintf("%s (%s) %s", shortDateOf(s, r.FireOn), shortWeekdayNames[r.FireOn.Weekday()], when)
+		}
+
+		lines = append(lines, fmt.Sprintf(messageListItemFormat, i+1, indicator, visibleReminderText(r), when, relativeTime(r.FireOn, now)))
+	}
+	lines = append(lines, "", messageListHint)
+
+	return strings.Join(lines, "\n")
+}
+
+// group of given time for /list (with weeks starting on given weekday)
+func dayGroupOf(t, now time.Time, weekStart time.Weekday) string {
+	days := daysBetween(now, t)
+
+	daysToNextWeek := 7 - (int(now.Weekday())-int(weekStart)+7)%7
+
+	switch {
+	case days <= 0:
+		return messageListToday
+	case days == 1:
+		return messageListTomorrow
+	case days < daysToNextWeek:
+		return messageListThisWeek
+	case days < daysToNextWeek+7:
+		return messageListNextWeek
+	}
+	return messageListLater
+}
+
+// relative time of given time from now (eg. "3시간 후")
+func relativeTime(t, now time.Time) string {
+	d := t.Sub(now)
+	switch {
+	case d < time.Minute:
+		return messageRelativeSoon
+	case d < time.Hour:
+		return fmt.Sprintf(messageRelativeMinutesFmt, int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf(messageRelativeHoursFmt, int(d.Hours()))
+	}
+	return fmt.Sprintf(messageRelativeDaysFmt, daysBetween(now, t))
+}
+
+// cancel the reminder of given number in /list
+func cancelNthReminder(chatID int64, param string) (message string, ok bool) {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		return "", false
+	}
+
+	reminders := listedReminders(chatID)
+	if n < 1 || n > len(reminders) {
+		return fmt.Sprintf(messageCancelNoSuchNumber, n), true
+	}
+
+	q := reminders[n-1]
+	if !db.DeleteQueueItem(chatID, q.ID) {
+		return messageError, true
+	}
+	fireWebhooks(webhookEventCanceled, q, "")
+
+	return fmt.Sprintf(messageCanceledFormat, visibleReminderText(q)), true
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// replay of failed deliveries: queue items which ran out of tries (dead letters) are rescheduled
+// for immediate delivery with their numbers of tries reset
+
+const (
+	commandReplay    = "/replay" // admin command
+	subcommandReplay = "replay"
+
+	replayParamAll   = "all"
+	replayDateFormat = "2006.1.2"
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageReplayUsage         = "사용법: /replay <chat_id|all> [시작일 [종료일]] (예: /replay all 2024.1.1 2024.1.31)"
+	messageReplayNothing       = "[관리자] 다시 보낼 (발송에 실패한) 알림이 없습니다."
+	messageReplaySummaryFormat = "[관리자] 발송에 실패한 알림 %d건 중 %d건을 지금 다시 보냅니다."
+	messageReplayFailedFormat  = "(다시 예약하지 못한 알림: %s)"
+)
+
+// filter of failed queue items to replay
+type replayFilter struct {
+	ChatID int64     // 0 for all chats
+	From   time.Time // zero for no limit
+	Until  time.Time // (exclusive) zero for no limit
+}
+
+// result of a replay
+type replayResult struct {
+	NumFailed   int
+	Replayed    []int64
+	NotReplayed []int64
+}
+
+// parse the date range of a replay (both dates are inclusive)
+func parseReplayDates(from, until string) (filter replayFilter, err error) {
+	if from != "" {
+		if filter.From, err = time.ParseInLocation(replayDateFormat, from, _location); err != nil {
+			return filter, fmt.Errorf("malformed date: %s", from)
+		}
+	}
+	if until != "" {
+		if filter.Until, err = time.ParseInLocation(replayDateFormat, until, _location); err != nil {
+			return filter, fmt.Errorf("malformed date: %s", until)
+		}
+		filter.Until = filter.Until.AddDate(0, 0, 1)
+	}
+
+	return filter, nil
+}
+
+// reschedule failed queue items which match given filter for immediate delivery
+func replayFailedQueueItems(filter replayFilter) (result replayResult) {
+	items := db.FailedQueueItems(maxNumTriesOrDefault(), filter.ChatID, filter.From, filter.Until)
+	result.NumFailed = len(items)
+
+	now := time.Now()
+	for _, q := range items {
+		if db.RetryQueueItem(q.ChatID, q.ID, now) {
+			result.Replayed = append(result.Replayed, q.ID)
+		} else {
+			result.NotReplayed = append(result.NotReplayed, q.ID)
+		}
+	}
+
+	if len(result.Replayed) > 0 {
+		db.Log(fmt.Sprintf("replayed %d failed queue item(s): %v", len(result.Replayed), result.Replayed))
+	}
+
+	return result
+}
+
+// process /replay command: re-drive failed deliveries for admins
+func processReplayCommand(userID int64, txt string) string {
+	if !isAdmin(userID) {
+		return messageAnnounceAdminOnly
+	}
+
+	params := strings.Fields(strings.TrimPrefix(txt, commandReplay))
+	if len(params) < 1 || len(params) > 3 {
+		return messageReplayUsage
+	}
+
+	var from, until string
+	if len(params) > 1 {
+		from = params[1]
+	}
+	if len(params) > 2 {
+		until = params[2]
+	}
+	filter, err := parseReplayDates(from, until)
+	if err != nil {
+		return messageReplayUsage
+	}
+	if params[0] != replayParamAll {
+		if filter.ChatID, err = strconv.ParseInt(params[0], 10, 64); err != nil {
+			return messageReplayUsage
+		}
+	}
+
+	result := replayFailedQueueItems(filter)
+	if result.NumFailed <= 0 {
+		return messageReplayNothing
+	}
+
+	message := fmt.Sprintf(messageReplaySummaryFormat, result.NumFailed, len(result.Replayed))
+	if len(result.NotReplayed) > 0 {
+		message += "\n" + fmt.Sprintf(messageReplayFailedFormat, strings.Trim(fmt.Sprint(result.NotReplayed), "[]"))
+	}
+
+	return message
+}
+
+// run `replay` subcommand: reschedule failed deliveries, then exit
+//
+// (they will be delivered when the bot starts)
+//
+//	$ reminderbot replay --chat 12345 --from 2024.1.1 --until 2024.1.31
+func runReplay(args []string) {
+	flags := flag.NewFlagSet(subcommandReplay, flag.ExitOnError)
+	chatID := flags.Int64("chat", 0, "chat id of failed reminders (0 for all chats)")
+	from := flags.String("from", "", "replay reminders scheduled on or after this date (eg. '2024.1.1')")
+	until := flags.String("until", "", "replay reminders scheduled on or before this date (eg. '2024.1.31')")
+	flags.Parse(args)
+
+	setup()
+
+	filter, err := parseReplayDates(*from, *until)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "*** %s\n", err)
+		os.Exit(1)
+	}
+	filter.ChatID = *chatID
+
+	result := replayFailedQueueItems(filter)
+	for _, id := range result.Replayed {
+		fmt.Printf("%8d  replayed\n", id)
+	}
+	for _, id := range result.NotReplayed {
+		fmt.Printf("%8d  FAILED\n", id)
+	}
+	fmt.Printf("Replayed %d of %d failed reminder(s)\n", len(result.Replayed), result.NumFailed)
+
+	if len(result.NotReplayed) > 0 {
+		os.Exit(1)
+	}
+}
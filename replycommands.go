@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// commands in replies: delivered reminders can be handled by replying to them with short commands
+// (eg. "완료", "+1h", "반복 매주"), instead of inline keyboards on every message
+
+const (
+	replyCommandDone   = "완료"
+	replyCommandRepeat = "반복"
+)
+
+// eg. "+1h", "+30m", "+2d", "+1시간", "+30분", "+2일"
+var replySnoozeRegex = regexp.MustCompile(`^\+\s*(\d+)\s*(h|m|d|시간|분|일)$`)
+
+// recurrence rules of "반복 ..."
+var replyRepeatRules = map[string]func(t time.Time) string{
+	"매일": func(t time.Time) string { return recurrenceDaily },
+	"매주": func(t time.Time) string { return recurrenceWeekly },
+	"격주": func(t time.Time) string { return recurrenceBiweekly },
+	"매월": monthlyRecurrence,
+}
+
+// messages (can be overridden with messages.json)
+var (
+	messageReplyAlreadyDoneFormat = "이미 %s님이 완료한 알림입니다."
+	messageReplyDoneFormat        = "✔ %s님이 완료했습니다. (%s)"
+	messageReplyRepeatUsage       = "'반복 매일', '반복 매주', '반복 격주', '반복 매월' 중 하나로 답장해 주세요."
+)
+
+// check if given text is a command for replying to delivered reminders
+func isReplyCommand(txt string) bool {
+	txt = strings.TrimSpace(txt)
+
+	return txt == replyCommandDone ||
+		replySnoozeRegex.MatchString(txt) ||
+		txt == replyCommandRepeat || strings.HasPrefix(txt, replyCommandRepeat+" ")
+}
+
+// delivered reminder which was replied to with a command in given message
+func reminderRepliedTo(message *bot.Message, txt string) (q dbhelper.QueueItem, isReply bool) {
+	if message.ReplyToMessage == nil || !isReplyCommand(txt) {
+		return q, false
+	}
+
+	if q, isReply = db.DeliveredQueueItem(message.Chat.ID, int64(message.ReplyToMessage.MessageID)); isReply && q.Kind != dbhelper.QueueKindReminder {
+		return q, false // (eg. polls, digests)
+	}
+
+	return q, isReply
+}
+
+// process a command replied to given delivered reminder
+func processReplyCommand(q dbhelper.QueueItem, userID int64, txt string) string {
+	txt = strings.TrimSpace(txt)
+
+	switch {
+	case txt == replyCommandDone:
+		if !db.AckQueueItem(q.ChatID, q.ID, userID) {
+			if acked, exists := db.QueueItem(q.ChatID, q.ID); exists && acked.AckedBy != 0 {
+				return fmt.Sprintf(messageReplyAlreadyDoneFormat, userDisplayName(acked.AckedBy))
+			}
+			return messageError
+		}
+
+		// stop nagging
+		db.DeleteQueueItemsByRef(q.ChatID, dbhelper.QueueKindGroupNag, q.ID)
+
+		completeIntegrationTasks(q)
+
+		message := fmt.Sprintf(messageReplyDoneFormat, userDisplayName(userID), clockOf(q.ChatID, time.Now()))
+
+		// schedule chained reminders
+		if chained := triggerChains(q.ChatID, q.ID); chained != "" {
+			message += "\n" + chained
+		}
+
+		return message
+	case replySnoozeRegex.MatchString(txt):
+		matches := replySnoozeRegex.FindStringSubmatch(txt)
+		num, _ := strconv.Atoi(matches[1])
+
+		var duration time.Duration
+		switch matches[2] {
+		case "h", "시간":
+			duration = time.Duration(num) * time.Hour
+		case "m", "분":
+			duration = time.Duration(num) * time.Minute
+		case "d", "일":
+			duration = time.Duration(num) * 24 * time.Hour
+		}
+
+		return cloneQueueItem(q, userID, time.Now().Add(duration))
+	default: // "반복 ..."
+		rule, exists := replyRepeatRules[strings.TrimSpace(strings.TrimPrefix(txt, replyCommandRepeat))]
+		if !exists {
+			return messageReplyRepeatUsage
+		}
+
+		return recurQueueItem(q, rule(q.FireOn))
+	}
+}
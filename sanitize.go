@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sanitization of reminder texts: applied before they are saved, and again before they are delivered,
+// so that the bot cannot be used for scheduling spam into group chats
+
+const (
+	sanitizeDefaultMaxLength = 1000 // in runes
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageSanitizeTooLongFormat  = "알림 내용이 너무 깁니다. (%d자 이하로 줄여 주세요)"
+	messageSanitizeEmpty          = "알림 내용이 비어 있습니다."
+	messageSanitizeLinkInGroup    = "그룹 채팅방에서는 링크가 포함된 알림을 등록할 수 없습니다."
+	messageSanitizeBlockedDomain  = "등록할 수 없는 링크가 포함되어 있습니다."
+	messageSanitizeBlockedWord    = "등록할 수 없는 단어가 포함되어 있습니다."
+	messageSanitizeFilteredFormat = "(필터링된 알림입니다: %s)"
+)
+
+// config for sanitizing reminder texts
+type sanitizationConfig struct {
+	MaxLength         int      `json:"max_length,omitempty"`           // max number of characters (default: 1000)
+	BlockURLsInGroups bool     `json:"block_urls_in_groups,omitempty"` // reject reminders with links in group chats
+	BlockedDomains    []string `json:"blocked_domains,omitempty"`      // reject reminders with links to these domains (and their subdomains)
+	BlockedWords      []string `json:"blocked_words,omitempty"`        // reject reminders containing these words (case-insensitive)
+}
+
+// validate sanitization config
+func (c sanitizationConfig) validate() (problems []string) {
+	if c.MaxLength < 0 {
+		problems = append(problems, fmt.Sprintf("max_length of sanitization should not be negative: %d", c.MaxLength))
+	}
+	for _, domain := range c.BlockedDomains {
+		if strings.TrimSpace(domain) == "" || strings.Contains(domain, "/") {
+			problems = append(problems, fmt.Sprintf("malformed blocked domain of sanitization: '%s'", domain))
+		}
+	}
+
+	return problems
+}
+
+// invisible characters which are often used for hiding links or reversing texts
+func isInvisibleRune(r rune) bool {
+	return (r >= 0x200B && r <= 0x200F) || // zero-width spaces and marks
+		(r >= 0x202A && r <= 0x202E) || // bidi embeddings and overrides
+		(r >= 0x2066 && r <= 0x2069) || // bidi isolates
+		r == 0xFEFF // byte order mark
+}
+
+// strip control and invisible characters (except newlines and tabs)
+func stripControlCharacters(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if r == utf8.RuneError || unicode.IsControl(r) || isInvisibleRune(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// check if given host is (a subdomain of) one of the blocked domains
+func isBlockedHost(host string, domains []string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, domain := range domains {
+		domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitize given reminder text for given chat
+//
+// returns an error (with a displayable reason) if it should not be saved nor delivered
+func sanitizeReminderText(chatID int64, text string) (string, error) {
+	text = strings.TrimSpace(stripControlCharacters(text))
+	if text == "" {
+		return text, errors.New(messageSanitizeEmpty)
+	}
+
+	var conf sanitizationConfig
+	if _conf.Sanitization != nil {
+		conf = *_conf.Sanitization
+	}
+
+	maxLength := conf.MaxLength
+	if maxLength <= 0 {
+		maxLength = sanitizeDefaultMaxLength
+	}
+	if utf8.RuneCountInString(text) > maxLength {
+		return text, fmt.Errorf(messageSanitizeTooLongFormat, maxLength)
+	}
+
+	for _, link := range urlRegex.FindAllString(text, -1) {
+		if conf.BlockURLsInGroups && isGroupChatID(chatID) {
+			return text, errors.New(messageSanitizeLinkInGroup)
+		}
+		if parsed, err := url.Parse(link); err == nil && isBlockedHost(parsed.Hostname(), conf.BlockedDomains) {
+			return text, errors.New(messageSanitizeBlockedDomain)
+		}
+	}
+
+	lowered := strings.ToLower(text)
+	for _, word := range conf.BlockedWords {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" && strings.Contains(lowered, word) {
+			return text, errors.New(messageSanitizeBlockedWord)
+		}
+	}
+
+	if _, matched := matchingContentRule(text, contentActionBlock); matched {
+		return text, errors.New(messageContentRejected)
+	}
+
+	return text, nil
+}
+
+// sanitized reminder text of given chat for delivery
+//
+// (filters were possibly changed after it was saved, so it is checked again)
+func sanitizedDeliveryText(chatID int64, message string) string {
+	sanitized, err := sanitizeReminderText(chatID, message)
+	if err != nil {
+		return fmt.Sprintf(messageSanitizeFilteredFormat, err)
+	}
+	return sanitized
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+// prefixes of secret references in config values
+//
+//	"env:VAR_NAME"          : read from the environment variable
+//	"keyring:user"          : read from the OS keyring (service: telegram-bot-reminder-api.ai)
+//	"keyring:service/user"  : read from the OS keyring with given service
+//	"enc:base64..."         : decrypt with a passphrase (generate one with -encrypt flag)
+const (
+	secretPrefixEnv       = "env:"
+	secretPrefixKeyring   = "keyring:"
+	secretPrefixEncrypted = "enc:"
+
+	secretKeyringService = "telegram-bot-reminder-api.ai"
+
+	// passphrase for encrypted values will be read from this environment variable, or prompted
+	secretPassphraseEnv = "REMINDER_BOT_PASSPHRASE"
+
+	secretSaltLength = 16
+	secretKeyLength  = 32 // AES-256
+)
+
+// command line flags for encrypting secrets
+var _encryptFlag = flag.Bool("encrypt", false, "encrypt a secret (read from stdin) with a passphrase for the config file, then exit")
+
+// cached passphrase, so that it is prompted only once
+var _passphrase string
+
+// resolve secret references in the config
+func (c *config) resolveSecrets() (err error) {
+	if c.TelegramAPIToken, err = resolveSecret(c.TelegramAPIToken); err != nil {
+		return fmt.Errorf("failed to resolve telegram_api_token: %s", err)
+	}
+	if c.ApiaiAccessToken, err = resolveSecret(c.ApiaiAccessToken); err != nil {
+		return fmt.Errorf("failed to resolve apiai_access_token: %s", err)
+	}
+	if c.MQTT != nil {
+		if c.MQTT.Password, err = resolveSecret(c.MQTT.Password); err != nil {
+			return fmt.Errorf("failed to resolve password of mqtt: %s", err)
+		}
+	}
+	if c.LLM != nil {
+		if c.LLM.APIKey, err = resolveSecret(c.LLM.APIKey); err != nil {
+			return fmt.Errorf("failed to resolve api_key of llm: %s", err)
+		}
+	}
+	if c.Redis != nil {
+		if c.Redis.Password, err = resolveSecret(c.Redis.Password); err != nil {
+			return fmt.Errorf("failed to resolve password of redis: %s", err)
+		}
+	}
+	if c.TTS != nil {
+		if c.TTS.APIKey, err = resolveSecret(c.TTS.APIKey); err != nil {
+			return fmt.Errorf("failed to resolve api_key of tts: %s", err)
+		}
+	}
+	for i := range c.Webhooks {
+		if c.Webhooks[i].Secret, err = resolveSecret(c.Webhooks[i].Secret); err != nil {
+			return fmt.Errorf("failed to resolve secret of webhook %s: %s", c.Webhooks[i].URL, err)
+		}
+	}
+	if c.EscalationEmail != nil {
+		if c.EscalationEmail.Password, err = resolveSecret(c.EscalationEmail.Password); err != nil {
+			return fmt.Errorf("failed to resolve password of escalation_email: %s", err)
+		}
+	}
+	if c.SMS != nil {
+		if c.SMS.AuthToken, err = resolveSecret(c.SMS.AuthToken); err != nil {
+			return fmt.Errorf("failed to resolve auth_token of sms: %s", err)
+		}
+	}
+	if c.BlobStorage != nil && c.BlobStorage.S3 != nil {
+		if c.BlobStorage.S3.SecretAccessKey, err = resolveSecret(c.BlobStorage.S3.SecretAccessKey); err != nil {
+			return fmt.Errorf("failed to resolve secret_access_key of blob_storage: %s", err)
+		}
+	}
+	if c.RemoteBackup != nil {
+		if c.RemoteBackup.Passphrase, err = resolveSecret(c.RemoteBackup.Passphrase); err != nil {
+			return fmt.Errorf("failed to resolve passphrase of remote_backup: %s", err)
+		}
+		if c.RemoteBackup.S3 != nil {
+			if c.RemoteBackup.S3.SecretAccessKey, err = resolveSecret(c.RemoteBackup.S3.SecretAccessKey); err != nil {
+				return fmt.Errorf("failed to resolve secret_access_key of remote_backup: %s", err)
+			}
+		}
+		if c.RemoteBackup.WebDAV != nil {
+			if c.RemoteBackup.WebDAV.Password, err = resolveSecret(c.RemoteBackup.WebDAV.Password); err != nil {
+				return fmt.Errorf("failed to resolve password of remote_backup: %s", err)
+			}
+		}
+	}
+	if c.Integrations != nil {
+		if c.Integrations.TokenKey, err = resolveSecret(c.Integrations.TokenKey); err != nil {
+			return fmt.Errorf("failed to resolve token_key of integrations: %s", err)
+		}
+		for _, app := range []*oauthAppConfig{c.Integrations.Todoist, c.Integrations.TickTick} {
+			if app == nil {
+				continue
+			}
+			if app.ClientSecret, err = resolveSecret(app.ClientSecret); err != nil {
+				return fmt.Errorf("failed to resolve client_secret of integrations: %s", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolve given value if it is a secret reference, or return it as it is
+func resolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, secretPrefixEnv):
+		name := strings.TrimPrefix(value, secretPrefixEnv)
+		if secret, exists := os.LookupEnv(name); exists {
+			return secret, nil
+		}
+		return "", fmt.Errorf("no such environment variable: %s", name)
+	case strings.HasPrefix(value, secretPrefixKeyring):
+		service, user := secretKeyringService, strings.TrimPrefix(value, secretPrefixKeyring)
+		if i := strings.Index(user, "/"); i >= 0 {
+			service, user = user[:i], user[i+1:]
+		}
+		return keyring.Get(service, user)
+	case strings.HasPrefix(value, secretPrefixEncrypted):
+		passphrase, err := readPassphrase()
+		if err != nil {
+			return "", err
+		}
+		return decryptSecret(strings.TrimPrefix(value, secretPrefixEncrypted), passphrase)
+	}
+
+	return value, nil
+}
+
+// read passphrase from the environment variable or terminal
+func readPassphrase() (string, error) {
+	if _passphrase != "" {
+		return _passphrase, nil
+	}
+
+	if passphrase, exists := os.LookupEnv(secretPassphraseEnv); exists {
+		_passphrase = passphrase
+	} else {
+		if !term.IsTerminal(int(syscall.Stdin)) {
+			return "", fmt.Errorf("passphrase is needed, but %s is not set and stdin is not a terminal", secretPassphraseEnv)
+		}
+
+		fmt.Fprint(os.Stderr, "Passphrase: ")
+		bytes, err := term.ReadPassword(int(syscall.Stdin))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		_passphrase = string(bytes)
+	}
+
+	return _passphrase, nil
+}
+
+// derive a key from given passphrase and salt
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, secretKeyLength)
+}
+
+// encrypt given secret with AES-GCM (result: base64 of salt + nonce + sealed)
+func encryptSecret(secret, passphrase string) (string, error) {
+	salt := make([]byte, secretSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nil, nonce, []byte(secret), nil)
+
+	return base64.StdEncoding.EncodeToString(append(append(salt, nonce...), sealed...)), nil
+}
+
+// decrypt given value which was encrypted with encryptSecret()
+func decryptSecret(encoded, passphrase string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < secretSaltLength {
+		return "", fmt.Errorf("encrypted value is too short")
+	}
+
+	key, err := deriveKey(passphrase, data[:secretSaltLength])
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	data = data[secretSaltLength:]
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted value is too short")
+	}
+
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt (wrong passphrase?)")
+	}
+
+	return string(plain), nil
+}
+
+// read a secret from stdin and print its encrypted value for the config file
+func runEncrypt() {
+	fmt.Fprint(os.Stderr, "Secret to encrypt: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "*** failed to read secret: %s\n", err)
+		os.Exit(1)
+	}
+
+	passphrase, err := readPassphrase()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "*** failed to read passphrase: %s\n", err)
+		os.Exit(1)
+	}
+
+	encrypted, err := encryptSecret(strings.TrimSpace(line), passphrase)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "*** failed to encrypt: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println(secretPrefixEncrypted + encrypted)
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	commandSettings = "/settings"
+
+	settingLinkPreview = "link_preview"
+	settingSummarize   = "summarize"
+	settingFollowUp    = "follow_up"
+	settingRounding    = "rounding"
+	settingVoice       = "voice"
+	settingSimpleMode  = "simple_mode"
+	settingTidyText    = "tidy_text"
+	settingGroupReport = "group_report"
+	settingDateFormat  = "date_format"
+	settingWeekStart   = "week_start"
+	settingHour12      = "hour12"
+
+	settingParamGreeting = "인사"
+	settingParamSignOff  = "맺음"
+	settingParamQuiet    = "조용"
+
+	settingDecorationMaxLength = 30 // max length of greetings and sign-offs
+)
+
+// units of rounding requested times (in minutes, cycled with the button)
+var settingRoundingUnits = []int{0, 5, 10, 15, 30, 60}
+
+// messages (can be overridden with messages.json)
+var (
+	messageSettings                = "설정을 바꾸려면 눌러 주세요.\n(알림 앞뒤에 붙일 인사말은 '/settings 인사 여보세요~', '/settings 맺음 좋은 하루 되세요!'처럼 바꿀 수 있고, 내용 없이 보내면 지워집니다.\n'6시에서 8시 사이에'처럼 시간대로 예약한 알림이 피할 조용한 시간은 '/settings 조용 23-7'처럼 정할 수 있습니다.\n'#업무'처럼 분류된 알림을 다른 대화로 보내려면 '/settings 분류 #업무'를 보내 주세요)"
+	messageSettingOn               = "켜짐"
+	messageSettingOff              = "꺼짐"
+	messageSettingFormat           = "%s: %s"
+	messageSettingSaved            = "설정이 저장 되었습니다."
+	messageLinkPreviewTitle        = "링크 미리보기"
+	messageSummarizeTitle          = "링크 요약"
+	messageFollowUpTitle           = "완료 확인 질문"
+	messageRoundingTitle           = "요청 시각 맞춤"
+	messageRoundingMinutesFormat   = "%d분 단위"
+	messageRoundingHour            = "정각"
+	messageVoiceTitle              = "음성으로도 보내기"
+	messageTidyTextTitle           = "알림 문구 다듬기 (보라고 → 보기)"
+	messageGroupReportSettingTitle = "관리자에게 매일 아침 보고"
+	messageGreetingTitle           = "인사"
+	messageSignOffTitle            = "맺음"
+	messageDecorationTooLongFormat = "%d자 이내로 입력해 주세요."
+	messageDecorationNotAllowed    = "링크나 멘션은 넣을 수 없습니다."
+	messageQuietHoursTitle         = "조용한 시간"
+	messageQuietHoursFormat        = "%d시 ~ %d시"
+	messageQuietHoursInvalid       = "'/settings 조용 23-7'처럼 시작과 끝 시각(0~23)을 입력해 주세요."
+)
+
+// process /settings command: show settings of the chat with buttons for toggling them,
+// or set the greeting/sign-off of delivered reminders (and routes of categories)
+func processSettingsCommand(client *bot.Bot, chatID, userID int64, txt string, options map[string]interface{}) string {
+	s := db.ChatSettings(chatID)
+
+	params := strings.TrimSpace(strings.TrimPrefix(txt, commandSettings))
+	if params == settingParamCategory || strings.HasPrefix(params, settingParamCategory+" ") {
+		return processCategorySetting(client, chatID, userID, strings.TrimPrefix(params, settingParamCategory), options)
+	}
+	if params == settingParamQuiet || strings.HasPrefix(params, settingParamQuiet+" ") {
+		from, to, ok := parseQuietHours(strings.TrimSpace(strings.TrimPrefix(params, settingParamQuiet)))
+		if !ok {
+			return messageQuietHoursInvalid
+		}
+		s.QuietFrom, s.QuietTo = from, to
+		if !db.SaveChatSettings(s) {
+			return messageError
+		}
+
+		return messageSettingSaved
+	}
+
+	for _, param := range []string{settingParamGreeting, settingParamSignOff} {
+		if params != param && !strings.HasPrefix(params, param+" ") {
+			continue
+		}
+
+		decoration, errMessage := sanitizeDecoration(strings.TrimPrefix(params, param))
+		if errMessage != "" {
+			return errMessage
+		}
+		if param == settingParamGreeting {
+			s.Greeting = decoration
+		} else {
+			s.SignOff = decoration
+		}
+		if !db.SaveChatSettings(s) {
+			return messageError
+		}
+
+		return messageSettingSaved
+	}
+
+	options["reply_markup"] = settingsKeyboard(s)
+
+	lines := []string{messageSettings}
+	if s.Greeting != "" {
+		lines = append(lines, fmt.Sprintf(messageSettingFormat, messageGreetingTitle, s.Greeting))
+	}
+	if s.SignOff != "" {
+		lines = append(lines, fmt.Sprintf(messageSettingFormat, messageSignOffTitle, s.SignOff))
+	}
+	if s.QuietFrom != s.QuietTo {
+		lines = append(lines, fmt.Sprintf(messageSettingFormat, messageQuietHoursTitle, fmt.Sprintf(messageQuietHoursFormat, s.QuietFrom, s.QuietTo)))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// parse quiet hours (eg. "23-7"; empty for no quiet hours)
+func parseQuietHours(param string) (from, to int, ok bool) {
+	if param == "" {
+		return 0, 0, true
+	}
+
+	hours := strings.FieldsFunc(param, func(r rune) bool {
+		return r == '-' || r == '~' || r == ' '
+	})
+	if len(hours) != 2 {
+		return 0, 0, false
+	}
+
+	var err1, err2 error
+	from, err1 = strconv.Atoi(strings.TrimSuffix(hours[0], "시"))
+	to, err2 = strconv.Atoi(strings.TrimSuffix(hours[1], "시"))
+	if err1 != nil || err2 != nil || from < 0 || from > 23 || to < 0 || to > 23 {
+		return 0, 0, false
+	}
+
+	return from, to, true
+}
+
+// sanitize given greeting/sign-off (returns a non-empty error message if it is not acceptable)
+func sanitizeDecoration(text string) (decoration, errMessage string) {
+	// remove control characters, and squeeze spaces and line breaks
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, text)
+	decoration = strings.Join(strings.Fields(text), " ")
+
+	if utf8.RuneCountInString(decoration) > settingDecorationMaxLength {
+		return "", fmt.Sprintf(messageDecorationTooLongFormat, settingDecorationMaxLength)
+	}
+	if len(urlsInMessage(decoration)) > 0 || strings.Contains(decoration, "@") {
+		return "", messageDecorationNotAllowed
+	}
+
+	return decoration, ""
+}
+
+// decorate given message with the greeting and sign-off of given chat
+func decorateMessage(chatID int64, message string) string {
+	s := db.ChatSettings(chatID)
+	if s.Greeting != "" {
+		message = s.Greeting + "\n" + message
+	}
+	if s.SignOff != "" {
+		message = message + "\n" + s.SignOff
+	}
+
+	return message
+}
+
+// inline keyboard for toggling given settings
+func settingsKeyboard(s dbhelper.ChatSettings) bot.InlineKeyboardMarkup {
+	linkPreview := fmt.Sprintf("%s %s", commandSettings, settingLinkPreview)
+	buttons := [][]bot.InlineKeyboardButton{
+		[]bot.InlineKeyboardButton{
+			bot.InlineKeyboardButton{
+				Text:         fmt.Sprintf(messageSettingFormat, messageLinkPreviewTitle, onOff(s.LinkPreview)),
+				CallbackData: &linkPreview,
+			},
+		},
+	}
+
+	followUp := fmt.Sprintf("%s %s", commandSettings, settingFollowUp)
+	buttons = append(buttons, []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{
+			Text:         fmt.Sprintf(messageSettingFormat, messageFollowUpTitle, onOff(s.FollowUp)),
+			CallbackData: &followUp,
+		},
+	})
+
+	simpleMode := fmt.Sprintf("%s %s", commandSettings, settingSimpleMode)
+	buttons = append(buttons, []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{
+			Text:         fmt.Sprintf(messageSettingFormat, messageSimpleModeTitle, onOff(s.SimpleMode)),
+			CallbackData: &simpleMode,
+		},
+	})
+
+	tidyText := fmt.Sprintf("%s %s", commandSettings, settingTidyText)
+	buttons = append(buttons, []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{
+			Text:         fmt.Sprintf(messageSettingFormat, messageTidyTextTitle, onOff(s.TidyText)),
+			CallbackData: &tidyText,
+		},
+	})
+
+	rounding := fmt.Sprintf("%s %s", commandSettings, settingRounding)
+	buttons = append(buttons, []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{
+			Text:         fmt.Sprintf(messageSettingFormat, messageRoundingTitle, describeRounding(s.RoundMinutes)),
+			CallbackData: &rounding,
+		},
+	})
+
+	dateFormat := fmt.Sprintf("%s %s", commandSettings, settingDateFormat)
+	weekStart := fmt.Sprintf("%s %s", commandSettings, settingWeekStart)
+	buttons = append(buttons, []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{
+			Text:         fmt.Sprintf(messageSettingFormat, messageDateFormatTitle, describeDateFormat(s.DateFormat)),
+			CallbackData: &dateFormat,
+		},
+		bot.InlineKeyboardButton{
+			Text:         fmt.Sprintf(messageSettingFormat, messageWeekStartTitle, fmt.Sprintf(messageWeekStartFormat, shortWeekdayNames[time.Weekday(s.WeekStart)])),
+			CallbackData: &weekStart,
+		},
+	})
+
+	hour12 := fmt.Sprintf("%s %s", commandSettings, settingHour12)
+	buttons = append(buttons, []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{
+			Text:         fmt.Sprintf(messageSettingFormat, messageHour12Title, describeHour12(s.Hour12)),
+			CallbackData: &hour12,
+		},
+	})
+
+	// (only in groups)
+	if isGroupChatID(s.ChatID) {
+		groupReport := fmt.Sprintf("%s %s", commandSettings, settingGroupReport)
+		buttons = append(buttons, []bot.InlineKeyboardButton{
+			bot.InlineKeyboardButton{
+				Text:         fmt.Sprintf(messageSettingFormat, messageGroupReportSettingTitle, onOff(s.GroupReport)),
+				CallbackData: &groupReport,
+			},
+		})
+	}
+
+	// (only when LLM backend is configured)
+	if isLLMEnabled() {
+		summarize := fmt.Sprintf("%s %s", commandSettings, settingSummarize)
+		buttons = append(buttons, []bot.InlineKeyboardButton{
+			bot.InlineKeyboardButton{
+				Text:         fmt.Sprintf(messageSettingFormat, messageSummarizeTitle, onOff(s.Summarize)),
+				CallbackData: &summarize,
+			},
+		})
+	}
+
+	// (only when TTS backend is configured)
+	if isTTSEnabled() {
+		voice := fmt.Sprintf("%s %s", commandSettings, settingVoice)
+		buttons = append(buttons, []bot.InlineKeyboardButton{
+			bot.InlineKeyboardButton{
+				Text:         fmt.Sprintf(messageSettingFormat, messageVoiceTitle, onOff(s.Voice)),
+				CallbackData: &voice,
+			},
+		})
+	}
+
+	return bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// displayable on/off of given value
+func onOff(value bool) string {
+	if value {
+		return messageSettingOn
+	}
+	return messageSettingOff
+}
+
+// displayable unit of rounding
+func describeRounding(minutes int) string {
+	switch {
+	case minutes <= 0:
+		return messageSettingOff
+	case minutes == 60:
+		return messageRoundingHour
+	}
+	return fmt.Sprintf(messageRoundingMinutesFormat, minutes)
+}
+
+// next unit of rounding (for cycling with the button)
+func nextRounding(minutes int) int {
+	for i, unit := range settingRoundingUnits {
+		if unit == minutes && i+1 < len(settingRoundingUnits) {
+			return settingRoundingUnits[i+1]
+		}
+	}
+	return settingRoundingUnits[0]
+}
+
+// round given requested time to the rounding unit of given chat
+//
+// (eg. 14:58 => 15:00 with 5-minute unit, but not to be a past time)
+func roundTime(chatID int64, when time.Time) time.Time {
+	minutes := db.ChatSettings(chatID).RoundMinutes
+	if minutes <= 0 {
+		return when
+	}
+
+	unit := time.Duration(minutes) * time.Minute
+
+	// (truncate from the start of the day, for time zones with non-hour offsets)
+	midnight := time.Date(when.Year(), when.Month(), when.Day(), 0, 0, 0, 0, when.Location())
+	rounded := midnight.Add(when.Sub(midnight).Round(unit))
+	if rounded.Before(time.Now()) {
+		rounded = rounded.Add(unit)
+	}
+
+	return rounded
+}
+
+// process callback query for toggling a setting
+func processSettingsCallback(query bot.CallbackQuery, txt string) (message string, keyboard interface{}) {
+	chatID := query.Message.Chat.ID
+
+	s := db.ChatSettings(chatID)
+	param := strings.TrimSpace(strings.TrimPrefix(txt, commandSettings))
+	switch param {
+	case settingLinkPreview:
+		s.LinkPreview = !s.LinkPreview
+	case settingSummarize:
+		s.Summarize = !s.Summarize
+	case settingFollowUp:
+		s.FollowUp = !s.FollowUp
+	case settingRounding:
+		s.RoundMinutes = nextRounding(s.RoundMinutes)
+	case settingVoice:
+		s.Voice = !s.Voice
+	case settingSimpleMode:
+		s.SimpleMode = !s.SimpleMode
+	case settingTidyText:
+		s.TidyText = !s.TidyText
+	case settingGroupReport:
+		s.GroupReport = !s.GroupReport
+	case settingDateFormat:
+		s.DateFormat = nextDateFormat(s.DateFormat)
+	case settingWeekStart:
+		s.WeekStart = int(nextWeekStart(time.Weekday(s.WeekStart)))
+	case settingHour12:
+		s.Hour12 = !s.Hour12
+	default:
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError, nil
+	}
+
+	if !db.SaveChatSettings(s) {
+		return messageError, nil
+	}
+
+	if param == settingWeekStart {
+		rescheduleWeeklyReports(chatID)
+	}
+
+	if s.GroupReport {
+		scheduleGroupReport(chatID, int64(query.From.ID))
+	} else {
+		cancelGroupReport(chatID)
+	}
+
+	return messageSettingSaved, settingsKeyboard(s)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	apiai "github.com/meinside/api.ai-go"
+	bot "github.com/meinside/telegram-bot-go"
+)
+
+const (
+	setupDefaultMonitorIntervalSeconds  = 60
+	setupDefaultTelegramIntervalSeconds = 10
+	setupDefaultMaxNumTries             = 3
+
+	setupTestQuery = "안녕"
+)
+
+// command line flags for the first-run setup
+var _telegramTokenFlag = flag.String("telegram-token", "", "telegram bot api token (for creating a new config file)")
+var _apiaiTokenFlag = flag.String("apiai-token", "", "api.ai developer access token (for creating a new config file)")
+
+// create a new config file at given path with tokens from flags or stdin
+func runFirstRunSetup(path string) (conf config, err error) {
+	fmt.Printf("> Config file not found: %s\n", path)
+	fmt.Printf("> Starting first-run setup...\n")
+
+	reader := bufio.NewReader(os.Stdin)
+
+	// telegram bot api token
+	var me bot.APIResponseUser
+	if conf.TelegramAPIToken, err = askToken(reader, *_telegramTokenFlag, "Telegram bot API token", func(token string) error {
+		if me = bot.NewClient(token).GetMe(); !me.Ok {
+			return fmt.Errorf("getMe failed")
+		}
+		return nil
+	}); err != nil {
+		return config{}, err
+	}
+	fmt.Printf("> Bot: @%s (%s)\n", *me.Result.Username, me.Result.FirstName)
+
+	// api.ai access token
+	if conf.ApiaiAccessToken, err = askToken(reader, *_apiaiTokenFlag, "api.ai developer access token", func(token string) error {
+		response, err := apiai.NewClient(token).QueryText(apiai.QueryRequest{
+			Query:     []string{setupTestQuery},
+			SessionId: "setup",
+			Language:  apiai.Korean,
+		})
+		if err != nil {
+			return err
+		}
+		if response.Status.ErrorType != apiai.Success {
+			return fmt.Errorf("%s (%s)", response.Status.ErrorType, response.Status.ErrorDetails)
+		}
+		return nil
+	}); err != nil {
+		return config{}, err
+	}
+
+	conf.MonitorIntervalSeconds = setupDefaultMonitorIntervalSeconds
+	conf.TelegramIntervalSeconds = setupDefaultTelegramIntervalSeconds
+	conf.MaxNumTries = setupDefaultMaxNumTries
+
+	// write config file
+	var bytes []byte
+	if bytes, err = json.MarshalIndent(conf, "", "\t"); err == nil {
+		if err = ioutil.WriteFile(path, bytes, 0600); err == nil {
+			fmt.Printf("> Saved config file: %s\n", path)
+		}
+	}
+
+	return conf, err
+}
+
+// get a token from given flag value or stdin, and validate it
+//
+// keeps asking until a valid one is entered, unless the token was given as a flag
+func askToken(reader *bufio.Reader, flagValue, name string, validate func(token string) error) (string, error) {
+	if flagValue != "" {
+		if err := validate(flagValue); err != nil {
+			return "", fmt.Errorf("invalid %s: %s", name, err)
+		}
+		return flagValue, nil
+	}
+
+	for {
+		fmt.Printf("%s: ", name)
+
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %s", name, err)
+		}
+
+		token := strings.TrimSpace(line)
+		if token == "" {
+			continue
+		}
+
+		if err := validate(token); err != nil {
+			fmt.Printf("*** invalid %s: %s\n", name, err)
+			continue
+		}
+
+		return token, nil
+	}
+}
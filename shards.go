@@ -0,0 +1,128 @@
+package main
+
+import (
+	"expvar"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// queue sharding: for large deployments, deliver queue items with a fixed number of worker loops,
+// each with its own rate limiter
+//
+// items of a chat always go to the same shard, so they are delivered in order of their fire times
+
+const (
+	shardQueueSize                = 1000 // items waiting in each shard (overflowed ones will be retried in the next check)
+	shardDefaultMessagesPerSecond = 25
+)
+
+// a worker loop of delivering queue items
+type queueShard struct {
+	items   chan dbhelper.QueueItem
+	limiter *time.Ticker
+}
+
+var _queueShards []queueShard
+
+// ids of queue items which are waiting in (or being delivered by) shards, not to be dispatched twice
+var _shardedItems = struct {
+	sync.Mutex
+	ids map[int64]bool
+}{
+	ids: map[int64]bool{},
+}
+
+func init() {
+	expvar.Publish("queue_shards", expvar.Func(func() interface{} {
+		lengths := []int{}
+		for _, shard := range _queueShards {
+			lengths = append(lengths, len(shard.items))
+		}
+		return lengths
+	}))
+}
+
+// start given number of shards with given rate limit (messages per second for each shard)
+func startQueueShards(client *bot.Bot, numShards int, messagesPerSecond float64) {
+	if messagesPerSecond <= 0 {
+		messagesPerSecond = shardDefaultMessagesPerSecond
+	}
+	interval := time.Duration(float64(time.Second) / messagesPerSecond)
+
+	log.Printf("> Starting %d queue shards (%.1f messages/sec each)...", numShards, messagesPerSecond)
+
+	for i := 0; i < numShards; i++ {
+		shard := queueShard{
+			items:   make(chan dbhelper.QueueItem, shardQueueSize),
+			limiter: time.NewTicker(interval),
+		}
+		_queueShards = append(_queueShards, shard)
+
+		go runQueueShard(client, shard)
+	}
+}
+
+// deliver queue items of given shard one by one
+func runQueueShard(client *bot.Bot, shard queueShard) {
+	for q := range shard.items {
+		<-shard.limiter.C
+
+		deliverQueueItem(client, q)
+
+		_shardedItems.Lock()
+		delete(_shardedItems.ids, q.ID)
+		_shardedItems.Unlock()
+	}
+}
+
+// check if queue items are delivered with shards
+func isSharded() bool {
+	return len(_queueShards) > 0
+}
+
+// dispatch given queue items to their shards (in order of their fire times)
+func dispatchToShards(items []dbhelper.QueueItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].FireOn.Equal(items[j].FireOn) {
+			return items[i].ID < items[j].ID
+		}
+		return items[i].FireOn.Before(items[j].FireOn)
+	})
+
+	overflowed := map[int64]bool{} // (chats whose items could not be dispatched, for keeping the order)
+	for _, q := range items {
+		if overflowed[q.ChatID] {
+			continue
+		}
+
+		_shardedItems.Lock()
+		if _shardedItems.ids[q.ID] {
+			_shardedItems.Unlock()
+			continue
+		}
+		_shardedItems.ids[q.ID] = true
+		_shardedItems.Unlock()
+
+		select {
+		case _queueShards[shardOf(q.ChatID)].items <- q:
+		default:
+			// (shard is full: will be dispatched again in the next check)
+			overflowed[q.ChatID] = true
+
+			_shardedItems.Lock()
+			delete(_shardedItems.ids, q.ID)
+			_shardedItems.Unlock()
+		}
+	}
+}
+
+// index of the shard for given chat
+func shardOf(chatID int64) int {
+	return int(uint64(chatID) % uint64(len(_queueShards)))
+}
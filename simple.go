@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+)
+
+// simple mode: for clients or screen readers which handle inline keyboards poorly,
+// inline keyboards are replaced with numbered options which can be answered with numbers
+const (
+	simpleChoicesTTLMinutes = 60 // numbered options will be forgotten after this
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageSimpleModeTitle       = "간단 모드 (버튼 대신 번호로 답하기)"
+	messageSimpleChoiceFormat    = "%d. %s"
+	messageSimpleChoiceHint      = "(번호로 답해 주세요)"
+	messageSimpleConfirmFormat   = "✅ 알림을 등록했습니다.\n\n🕒 %s\n📝 %s"
+	messageSimpleConfirmQuestion = "👉 맞으면 '네', 아니면 '아니오'라고 답해 주세요."
+	messageUsageSimple           = `사용 예: "내일 저녁 9시에 뉴스 보라고 알려줘"
+
+/list : 알림 보기
+/cancel : 알림 취소
+/settings : 설정
+/help : 도움말`
+)
+
+// numbered options waiting for answers
+type simpleChoices struct {
+	text    string   // text of the message with options (for callbacks which need it)
+	data    []string // callback data of each option
+	savedOn time.Time
+}
+
+var _simpleChoices = struct {
+	sync.Mutex
+	chats map[int64]simpleChoices
+}{
+	chats: map[int64]simpleChoices{},
+}
+
+// check if given chat is in simple mode
+func isSimpleMode(chatID int64) bool {
+	return db.ChatSettings(chatID).SimpleMode
+}
+
+// replace the inline keyboard in given options with numbered options appended to the message
+func simplifyMessage(chatID int64, message string, options map[string]interface{}) string {
+	var markup bot.InlineKeyboardMarkup
+	switch keyboard := options["reply_markup"].(type) {
+	case bot.InlineKeyboardMarkup:
+		markup = keyboard
+	case *bot.InlineKeyboardMarkup:
+		markup = *keyboard
+	default:
+		return message
+	}
+	delete(options, "reply_markup")
+
+	lines := []string{}
+	data := []string{}
+	for _, row := range markup.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData == nil {
+				continue
+			}
+
+			data = append(data, *button.CallbackData)
+			lines = append(lines, fmt.Sprintf(messageSimpleChoiceFormat, len(data), button.Text))
+		}
+	}
+	if len(data) <= 0 {
+		return message
+	}
+
+	_simpleChoices.Lock()
+	_simpleChoices.chats[chatID] = simpleChoices{text: message, data: data, savedOn: time.Now()}
+	_simpleChoices.Unlock()
+
+	return message + "\n\n" + strings.Join(lines, "\n") + "\n" + messageSimpleChoiceHint
+}
+
+// take the callback data of given numbered answer (and the text of the message with options)
+func takeSimpleChoice(chatID int64, txt string) (text, data string, exists bool) {
+	num, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(txt), ".")))
+	if err != nil {
+		return "", "", false
+	}
+
+	_simpleChoices.Lock()
+	defer _simpleChoices.Unlock()
+
+	choices, exists := _simpleChoices.chats[chatID]
+	if !exists || time.Since(choices.savedOn) > simpleChoicesTTLMinutes*time.Minute {
+		delete(_simpleChoices.chats, chatID)
+		return "", "", false
+	}
+	if num < 1 || num > len(choices.data) {
+		return "", "", false
+	}
+	delete(_simpleChoices.chats, chatID)
+
+	return choices.text, choices.data[num-1], true
+}
+
+// process a numbered answer in simple mode as a callback query
+//
+// returns false if given message is not an answer to numbered options
+func processSimpleChoice(b *bot.Bot, message *bot.Message) bool {
+	if message.Text == nil || message.From == nil {
+		return false
+	}
+	chatID := message.Chat.ID
+
+	text, data, exists := takeSimpleChoice(chatID, *message.Text)
+	if !exists {
+		return false
+	}
+
+	// (as if the button of the message with options was pressed)
+	original := *message
+	original.Text = &text
+	result, keyboard := dispatchCallback(b, bot.CallbackQuery{
+		From:    *message.From,
+		Message: &original,
+		Data:    &data,
+	}, data)
+
+	options := map[string]interface{}{}
+	if keyboard != nil {
+		options["reply_markup"] = keyboard
+	}
+	result = simplifyMessage(chatID, result, options)
+
+	if sent := b.SendMessage(chatID, result, options); !sent.Ok {
+		log.Printf("*** failed to send result of numbered answer: %s", *sent.Description)
+	}
+
+	return true
+}
+
+// bigger confirmation of a registered reminder for simple mode
+func simpleConfirmationMessage(chatID int64, message string, when time.Time, recurrence string) string {
+	schedule := formatTimeFor(chatID, when, reminderTimeFormat)
+	if recurrence != "" {
+		schedule = describeRecurrence(chatID, recurrence, when)
+	}
+
+	return fmt.Sprintf(messageSimpleConfirmFormat, schedule, message)
+}
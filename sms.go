@@ -0,0 +1,277 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// SMS fallback: chats register (and verify) a phone number with /phone,
+// then reminders with the 'SMS' failure policy (/onfail) are sent as SMS when telegram delivery fails after all retries
+//
+// (sent through Twilio, or a compatible API with 'endpoint')
+
+const (
+	commandPhone = "/phone"
+
+	phoneParamDelete = "삭제"
+
+	smsDefaultEndpoint          = "https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json" // (account sid)
+	smsTimeoutSeconds           = 10
+	smsVerificationCodeDigits   = 6
+	smsVerificationMinutes      = 10 // codes expire after this
+	smsVerificationMaxAttempts  = 5
+	smsVerificationResendSecond = 60 // codes cannot be resent more often than this
+	smsMaxBodyLength            = 300
+)
+
+// phone numbers in E.164 format
+var phoneNumberRegex = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// config for sending SMS
+type smsConfig struct {
+	Endpoint   string `json:"endpoint,omitempty"` // (default: Twilio)
+	AccountSID string `json:"account_sid"`
+	AuthToken  string `json:"auth_token"`
+	From       string `json:"from"` // sender phone number
+}
+
+// messages (can be overridden with messages.json)
+var (
+	messageSMSDisabled           = "SMS 발송이 설정되어 있지 않습니다."
+	messagePhoneUsage            = "알림을 텔레그램으로 끝내 보내지 못했을 때 문자로 받을 번호를 '/phone +821012345678'처럼 등록해 주세요. (국가 번호 포함)"
+	messagePhoneStatusFormat     = "등록된 번호: %s (%s)\n지우려면: /phone 삭제"
+	messagePhoneVerified         = "인증됨"
+	messagePhoneNotVerified      = "인증 대기 중"
+	messagePhoneInvalid          = "번호 형식이 올바르지 않습니다. '+821012345678'처럼 국가 번호를 포함해 주세요."
+	messagePhoneCodeSentFormat   = "%s(으)로 인증 번호를 보냈습니다. 받은 번호를 '/phone 123456'처럼 보내 주세요. (%d분 안에)"
+	messagePhoneCodeSMSFormat    = "[Reminder Bot] 인증 번호: %s"
+	messagePhoneCodeTooSoon      = "잠시 후에 다시 요청해 주세요."
+	messagePhoneCodeFailed       = "인증 번호를 보내지 못했습니다. 번호를 확인해 주세요."
+	messagePhoneVerifiedDone     = "번호가 인증되었습니다. 이제 /onfail 에서 '문자로 보내기'를 고를 수 있습니다."
+	messagePhoneWrongCode        = "인증 번호가 맞지 않습니다."
+	messagePhoneCodeExpired      = "인증 번호가 만료되었습니다. 번호를 다시 등록해 주세요."
+	messagePhoneNothingToVerify  = "인증할 번호가 없습니다. 먼저 번호를 등록해 주세요."
+	messagePhoneNotYours         = "번호를 등록한 사용자만 인증할 수 있습니다."
+	messagePhoneDeleted          = "등록된 번호를 지웠습니다."
+	messagePhoneNone             = "등록된 번호가 없습니다."
+	messageOnFailSMS             = "문자로 보내기"
+	messageOnFailNoPhone         = "인증된 번호가 없습니다. /phone 으로 먼저 등록해 주세요."
+	messageSMSFailureFormat      = "[Reminder Bot] %s (%s, 텔레그램으로 보내지 못해 문자로 보냅니다)"
+	messageSMSFailureAdminFormat = "[관리자] 알림 %d(채팅 %d)을 문자로도 보내지 못했습니다."
+)
+
+var _smsClient = &http.Client{Timeout: smsTimeoutSeconds * time.Second}
+
+// validate SMS config values
+func (c smsConfig) validate() (problems []string) {
+	if c.Endpoint != "" {
+		if u, err := url.Parse(c.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("endpoint of sms is malformed: %s", c.Endpoint))
+		}
+	}
+	if c.AccountSID == "" || c.AuthToken == "" {
+		problems = append(problems, "account_sid and auth_token of sms should not be empty")
+	}
+	if !phoneNumberRegex.MatchString(c.From) {
+		problems = append(problems, fmt.Sprintf("from of sms should be a phone number in E.164 format: %s", c.From))
+	}
+
+	return problems
+}
+
+// check if SMS is configured
+func isSMSEnabled() bool {
+	return _conf.SMS != nil
+}
+
+// check if given chat has a verified phone number
+func hasVerifiedPhoneNumber(chatID int64) bool {
+	p, exists := db.PhoneNumber(chatID)
+	return exists && p.IsVerified
+}
+
+// process /phone command
+//
+//	"/phone"               : show the registered phone number
+//	"/phone +821012345678" : register a phone number (a verification code is sent to it)
+//	"/phone 123456"        : verify the registered phone number with the code
+//	"/phone 삭제"           : delete the registered phone number
+func processPhoneCommand(chatID, userID int64, txt string) string {
+	if !isSMSEnabled() {
+		return messageSMSDisabled
+	}
+
+	param := strings.Join(strings.Fields(strings.TrimPrefix(txt, commandPhone)), "")
+	switch {
+	case param == "":
+		if p, exists := db.PhoneNumber(chatID); exists {
+			status := messagePhoneNotVerified
+			if p.IsVerified {
+				status = messagePhoneVerified
+			}
+			return fmt.Sprintf(messagePhoneStatusFormat, maskPhoneNumber(p.Number), status)
+		}
+		return messagePhoneUsage
+	case param == phoneParamDelete:
+		if db.DeletePhoneNumber(chatID) {
+			return messagePhoneDeleted
+		}
+		return messagePhoneNone
+	case strings.HasPrefix(param, "+"):
+		return registerPhoneNumber(chatID, userID, strings.ReplaceAll(param, "-", ""))
+	}
+
+	return verifyPhoneNumber(chatID, userID, param)
+}
+
+// register given phone number for given chat, and send a verification code to it
+func registerPhoneNumber(chatID, userID int64, number string) string {
+	if !phoneNumberRegex.MatchString(number) {
+		return messagePhoneInvalid
+	}
+	if p, exists := db.PhoneNumber(chatID); exists && !p.IsVerified && time.Since(p.CodeSentOn) < smsVerificationResendSecond*time.Second {
+		return messagePhoneCodeTooSoon
+	}
+
+	code, err := generateVerificationCode()
+	if err != nil {
+		log.Printf("*** failed to generate verification code: %s", err)
+		return messageError
+	}
+	if !db.SavePhoneNumber(chatID, userID, number, hashVerificationCode(chatID, code)) {
+		return messageError
+	}
+
+	if err := sendSMS(number, fmt.Sprintf(messagePhoneCodeSMSFormat, code)); err != nil {
+		log.Printf("*** failed to send verification code to %s: %s", maskPhoneNumber(number), err)
+
+		db.DeletePhoneNumber(chatID)
+
+		return messagePhoneCodeFailed
+	}
+
+	return fmt.Sprintf(messagePhoneCodeSentFormat, maskPhoneNumber(number), smsVerificationMinutes)
+}
+
+// verify the phone number of given chat with given code
+func verifyPhoneNumber(chatID, userID int64, code string) string {
+	p, exists := db.PhoneNumber(chatID)
+	if !exists || p.IsVerified {
+		return messagePhoneNothingToVerify
+	}
+	if p.UserID != userID {
+		return messagePhoneNotYours
+	}
+	if time.Since(p.CodeSentOn) > smsVerificationMinutes*time.Minute || p.NumAttempts >= smsVerificationMaxAttempts {
+		return messagePhoneCodeExpired
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashVerificationCode(chatID, code)), []byte(p.CodeHash)) != 1 {
+		db.IncreasePhoneVerificationAttempts(chatID)
+		return messagePhoneWrongCode
+	}
+
+	if !db.VerifyPhoneNumber(chatID) {
+		return messageError
+	}
+
+	return messagePhoneVerifiedDone
+}
+
+// generate a random verification code (of smsVerificationCodeDigits digits)
+func generateVerificationCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < smsVerificationCodeDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*d", smsVerificationCodeDigits, n.Int64()), nil
+}
+
+// hash given verification code for storing/comparing
+func hashVerificationCode(chatID int64, code string) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", chatID, code)))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// masked phone number for displaying (eg. "+8210****5678")
+func maskPhoneNumber(number string) string {
+	if len(number) <= 8 {
+		return number
+	}
+	return number[:len(number)-8] + "****" + number[len(number)-4:]
+}
+
+// send given queue item as an SMS to the verified phone number of its chat
+func sendFailureSMS(q dbhelper.QueueItem) bool {
+	p, exists := db.PhoneNumber(q.ChatID)
+	if !exists || !p.IsVerified {
+		log.Printf("*** no verified phone number for SMS fallback of chat %d", q.ChatID)
+		return false
+	}
+
+	body := fmt.Sprintf(messageSMSFailureFormat, visibleReminderText(q), formatTimeFor(q.ChatID, q.FireOn, reminderTimeFormat))
+	if runes := []rune(body); len(runes) > smsMaxBodyLength {
+		body = string(runes[:smsMaxBodyLength-1]) + "…"
+	}
+
+	if err := sendSMS(p.Number, body); err != nil {
+		log.Printf("*** failed to send SMS fallback of queue item %d: %s", q.ID, err)
+		return false
+	}
+
+	return true
+}
+
+// send an SMS with given body to given phone number
+func sendSMS(to, body string) error {
+	endpoint := _conf.SMS.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf(smsDefaultEndpoint, url.PathEscape(_conf.SMS.AccountSID))
+	}
+
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", _conf.SMS.From)
+	form.Set("Body", body)
+
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(_conf.SMS.AccountSID, _conf.SMS.AuthToken)
+
+	resp, err := _smsClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("http status: %s (%s)", resp.Status, string(message))
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	callbackRecur   = "/recur"
+	recurParamNever = "no"
+
+	suggestionWeeklyLookbackDays   = 35 // look back 5 weeks for weekly patterns
+	suggestionDailyLookbackDays    = 7  // look back a week for daily patterns
+	suggestionMinWeeklyOccurrences = 3
+	suggestionMinDailyOccurrences  = 5
+	suggestionTimeToleranceMinutes = 30 // reminders fired around the same time of day
+	suggestionCooloffDays          = 30 // do not suggest the same thing again during this period
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageSuggestRecurrenceFormat = "\"%s\" 알림을 자주 등록하시네요.\n%s에 반복되는 알림으로 만들어 드릴까요?"
+	messageSuggestYes              = "반복 알림 만들기"
+	messageSuggestNo               = "괜찮아요"
+	messageSuggestDeclined         = "알겠습니다."
+	messageRecurrenceCreatedFormat = "%s에 \"%s\" 알림을 보내드리겠습니다."
+	messageRecurrenceExists        = "이미 반복되는 알림이 있습니다."
+)
+
+// suggest making given (just delivered) reminder a recurring one, if it has a pattern
+func suggestRecurrence(client *bot.Bot, q dbhelper.QueueItem) {
+	if q.Recurrence != "" || q.Broadcast || isPrivateInGroup(q) || q.BusinessConnectionID != "" {
+		return
+	}
+
+	now := time.Now()
+	if db.SuggestedSince(q.ChatID, q.Message, now.AddDate(0, 0, -suggestionCooloffDays)) {
+		return
+	}
+
+	rule, detected := detectRecurrence(q, db.DeliveredQueueItemsWithMessage(q.ChatID, q.Message, now.AddDate(0, 0, -suggestionWeeklyLookbackDays)))
+	if !detected {
+		return
+	}
+
+	db.SaveSuggestion(q.ChatID, q.Message)
+
+	yes := fmt.Sprintf("%s %d %s", callbackRecur, q.ID, rule)
+	no := fmt.Sprintf("%s %d %s", callbackRecur, q.ID, recurParamNever)
+	options := map[string]interface{}{
+		"reply_markup": bot.InlineKeyboardMarkup{
+			InlineKeyboard: [][]bot.InlineKeyboardButton{
+				[]bot.InlineKeyboardButton{
+					bot.InlineKeyboardButton{Text: messageSuggestYes, CallbackData: &yes},
+					bot.InlineKeyboardButton{Text: messageSuggestNo, CallbackData: &no},
+				},
+			},
+		},
+	}
+
+	message := fmt.Sprintf(messageSuggestRecurrenceFormat, q.Message, describeRecurrence(q.ChatID, rule, q.FireOn))
+	if sent := client.SendMessage(q.ChatID, message, options); !sent.Ok {
+		log.Printf("*** failed to send suggestion: %s", *sent.Description)
+	}
+}
+
+// detect a daily or weekly pattern of given reminder from its history
+func detectRecurrence(q dbhelper.QueueItem, history []dbhelper.QueueItem) (rule string, detected bool) {
+	dailyDates, weeklyDates := map[string]bool{}, map[string]bool{}
+	dailySince := q.FireOn.AddDate(0, 0, -suggestionDailyLookbackDays)
+
+	for _, h := range history {
+		if !isAroundSameTimeOfDay(h.FireOn, q.FireOn) {
+			continue
+		}
+
+		date := h.FireOn.Format("2006-01-02")
+		if h.FireOn.After(dailySince) {
+			dailyDates[date] = true
+		}
+		if h.FireOn.Weekday() == q.FireOn.Weekday() {
+			weeklyDates[date] = true
+		}
+	}
+
+	if len(dailyDates) >= suggestionMinDailyOccurrences {
+		return recurrenceDaily, true
+	}
+	if len(weeklyDates) >= suggestionMinWeeklyOccurrences {
+		return recurrenceWeekly, true
+	}
+
+	return "", false
+}
+
+// check if given times are around the same time of day
+func isAroundSameTimeOfDay(t1, t2 time.Time) bool {
+	minutes1 := t1.Hour()*60 + t1.Minute()
+	minutes2 := t2.Hour()*60 + t2.Minute()
+
+	diff := minutes1 - minutes2
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > 12*60 { // (around midnight)
+		diff = 24*60 - diff
+	}
+
+	return diff <= suggestionTimeToleranceMinutes
+}
+
+// process callback query for making a reminder recurring
+func processRecurCallback(query bot.CallbackQuery, txt string) string {
+	params := strings.Fields(strings.TrimPrefix(txt, callbackRecur))
+	if len(params) != 2 {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+	if params[1] == recurParamNever {
+		return messageSuggestDeclined
+	}
+
+	chatID := query.Message.Chat.ID
+	queueID, err := strconv.ParseInt(params[0], 10, 64)
+	if err != nil {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+
+	q, exists := db.QueueItem(chatID, queueID)
+	if !exists {
+		return messageError
+	}
+
+	return recurQueueItem(q, params[1])
+}
+
+// make given (delivered) queue item recur with given rule
+func recurQueueItem(q dbhelper.QueueItem, rule string) string {
+	// check duplicates
+	for _, r := range db.UndeliveredQueueItems(q.ChatID) {
+		if r.Message == q.Message && r.Recurrence != "" {
+			return messageRecurrenceExists
+		}
+	}
+
+	q.Recurrence = rule
+	scheduleNextOccurrence(q)
+
+	return fmt.Sprintf(messageRecurrenceCreatedFormat, describeRecurrence(q.ChatID, q.Recurrence, q.FireOn), q.Message)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	summaryMaxPageBytes    = 2 * 1024 * 1024
+	summaryMaxTextRunes    = 6000 // of article text sent to the LLM
+	summaryPrefetchMinutes = 10   // summaries of reminders firing in this period are generated beforehand
+	summaryRetryMinutes    = 30   // pages which failed to be summarized are retried after this period
+
+	summarySystemPrompt = "You summarize web articles for reminder messages. Answer with exactly two sentences in Korean, without any preface."
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageLinkSummaryFormat = "📝 %s"
+)
+
+var (
+	htmlNoiseRegex = regexp.MustCompile(`(?is)<(script|style|noscript|head|nav|header|footer|aside)[^>]*>.*?</(script|style|noscript|head|nav|header|footer|aside)>`)
+	htmlTagRegex   = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// check if linked pages of given chat's reminders should be summarized
+func shouldSummarize(chatID int64) bool {
+	return isLLMEnabled() && db.ChatSettings(chatID).Summarize
+}
+
+// urls whose summaries are being generated (or failed recently), not to be summarized twice
+var _summarizingURLs = struct {
+	sync.Mutex
+	urls   map[string]bool
+	failed map[string]time.Time
+}{
+	urls:   map[string]bool{},
+	failed: map[string]time.Time{},
+}
+
+// append (already generated) summaries of the pages linked in given message
+//
+// (summaries are generated before the fire time, not to delay the delivery)
+func summarizeLinks(message string) string {
+	lines := []string{message}
+	for _, url := range urlsInMessage(message) {
+		if summary, exists := db.LinkSummary(url); exists && summary != "" {
+			lines = append(lines, fmt.Sprintf(messageLinkSummaryFormat, summary))
+		} else if !exists {
+			// (for the next ones, eg. recurring reminders)
+			go summarizeInBackground(url)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// generate summaries of the pages linked in reminders which will fire soon
+func prefetchLinkSummaries() {
+	if !isLLMEnabled() {
+		return
+	}
+
+	for _, q := range db.DeliverableQueueItemsUntil(_maxNumTries, time.Now().Add(summaryPrefetchMinutes*time.Minute)) {
+		if q.Kind != dbhelper.QueueKindReminder || len(urlsInMessage(q.Message)) <= 0 || !shouldSummarize(q.ChatID) {
+			continue
+		}
+
+		for _, url := range urlsInMessage(q.Message) {
+			if _, exists := db.LinkSummary(url); !exists {
+				go summarizeInBackground(url)
+			}
+		}
+	}
+}
+
+// generate the summary of given page, if it is not being generated (or failed recently)
+func summarizeInBackground(url string) {
+	_summarizingURLs.Lock()
+	if failedOn, exists := _summarizingURLs.failed[url]; _summarizingURLs.urls[url] || exists && time.Since(failedOn) < summaryRetryMinutes*time.Minute {
+		_summarizingURLs.Unlock()
+		return
+	}
+	_summarizingURLs.urls[url] = true
+	_summarizingURLs.Unlock()
+
+	summary := linkSummary(url)
+
+	_summarizingURLs.Lock()
+	delete(_summarizingURLs.urls, url)
+	if summary == "" {
+		_summarizingURLs.failed[url] = time.Now()
+	} else {
+		delete(_summarizingURLs.failed, url)
+	}
+	_summarizingURLs.Unlock()
+}
+
+// two-sentence summary of given page (cached per url, empty if it failed)
+func linkSummary(url string) string {
+	if summary, exists := db.LinkSummary(url); exists {
+		return summary
+	}
+
+	body, err := fetchPage(url, summaryMaxPageBytes)
+	if err != nil {
+		log.Printf("*** failed to fetch page for summary: %s (%s)", url, err)
+		return ""
+	}
+
+	text := articleText(string(body))
+	if text == "" {
+		return ""
+	}
+
+	summary, err := llmComplete(summarySystemPrompt, text)
+	if err != nil {
+		log.Printf("*** failed to summarize page: %s (%s)", url, err)
+		return ""
+	}
+	summary = strings.Join(strings.Fields(summary), " ")
+
+	db.SaveLinkSummary(url, summary)
+
+	return summary
+}
+
+// readable text of given html page (roughly, without tags and scripts)
+func articleText(page string) string {
+	text := htmlNoiseRegex.ReplaceAllString(page, " ")
+	text = htmlTagRegex.ReplaceAllString(text, " ")
+	text = strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+
+	if runes := []rune(text); len(runes) > summaryMaxTextRunes {
+		text = string(runes[:summaryMaxTextRunes])
+	}
+
+	return text
+}
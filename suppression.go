@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// skip or defer given reminder which falls in given suppression window
+func suppressQueueItem(q dbhelper.QueueItem, s dbhelper.Suppression) {
+	switch s.Mode {
+	case dbhelper.SuppressionModeDefer, dbhelper.SuppressionModeDigest:
+		if _isVerbose {
+			log.Printf("Deferring queue item %d until %s (%s)", q.ID, s.EndsOn.Format(reminderTimeFormat), s.Kind)
+		}
+
+		if !db.RescheduleQueueItem(q.ChatID, q.ID, s.EndsOn) {
+			log.Printf("*** failed to defer queue item %d", q.ID)
+		}
+	default:
+		skipQueueItem(q, s.Kind)
+	}
+}
+
+// mark given reminder as done without delivering it
+func skipQueueItem(q dbhelper.QueueItem, reason string) {
+	if _isVerbose {
+		log.Printf("Skipping queue item %d (%s)", q.ID, reason)
+	}
+
+	if db.MarkQueueItemAsDelivered(q.ChatID, q.ID) {
+		db.Log(fmt.Sprintf("skipped queue item %d of chat %d (%s)", q.ID, q.ChatID, reason))
+
+		scheduleNextOccurrence(q)
+	} else {
+		log.Printf("*** failed to skip queue item %d", q.ID)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// typed errors of telegram bot api, classified from the descriptions of failed responses,
+// so that callers can decide what to do with each kind of errors
+//
+// (eg. retry later when rate limited, give up when blocked)
+
+// ErrRateLimited is for "Too Many Requests" errors
+type ErrRateLimited struct {
+	RetryAfter  time.Duration
+	Description string
+}
+
+func (e ErrRateLimited) Error() string { return e.Description }
+
+// ErrBlocked is for errors from chats where the bot was blocked or kicked
+type ErrBlocked struct {
+	Description string
+}
+
+func (e ErrBlocked) Error() string { return e.Description }
+
+// ErrChatNotFound is for errors from chats which do not exist (anymore)
+type ErrChatNotFound struct {
+	Description string
+}
+
+func (e ErrChatNotFound) Error() string { return e.Description }
+
+// ErrNoRights is for errors from groups where the bot is not allowed to send messages
+type ErrNoRights struct {
+	Description string
+}
+
+func (e ErrNoRights) Error() string { return e.Description }
+
+// ErrNetwork is for errors which did not come from telegram (eg. timeouts, connection failures)
+type ErrNetwork struct {
+	Description string
+}
+
+func (e ErrNetwork) Error() string { return e.Description }
+
+// ErrTelegram is for other errors from telegram
+type ErrTelegram struct {
+	Code        int // http status code (0 if unknown)
+	Description string
+}
+
+func (e ErrTelegram) Error() string { return e.Description }
+
+// eg. "Too Many Requests: retry after 35"
+var retryAfterRegex = regexp.MustCompile(`retry after (\d+)`)
+
+// typed error from given description of a failed response
+func telegramError(description string) error {
+	code := telegramErrorCode(description)
+	lowered := strings.ToLower(description)
+
+	switch {
+	case code == 0:
+		// (all errors from telegram have prefixes like "Bad Request: ")
+		return ErrNetwork{Description: description}
+	case code == 429:
+		retryAfter := time.Duration(0)
+		if matches := retryAfterRegex.FindStringSubmatch(description); matches != nil {
+			seconds, _ := strconv.Atoi(matches[1])
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return ErrRateLimited{RetryAfter: retryAfter, Description: description}
+	case code == 403 && (strings.Contains(lowered, "blocked") ||
+		strings.Contains(lowered, "kicked") ||
+		strings.Contains(lowered, "deactivated") ||
+		strings.Contains(lowered, "not a member")):
+		return ErrBlocked{Description: description}
+	case strings.Contains(lowered, "chat not found"):
+		return ErrChatNotFound{Description: description}
+	case strings.Contains(lowered, "business connection") || strings.Contains(lowered, "business_connection_invalid"):
+		// (disconnected, or not allowed by the owner anymore)
+		return ErrBlocked{Description: description}
+	case strings.Contains(lowered, "not enough rights") ||
+		strings.Contains(lowered, "have no rights") ||
+		strings.Contains(lowered, "chat_write_forbidden"):
+		// (not permanent, as admins can give the rights back)
+		return ErrNoRights{Description: description}
+	}
+
+	return ErrTelegram{Code: code, Description: description}
+}
+
+// check if given error will not go away with retries
+func isPermanentTelegramError(err error) bool {
+	switch err.(type) {
+	case ErrBlocked, ErrChatNotFound:
+		return true
+	}
+	return false
+}
+
+// displayable kind of given error (for logs)
+func telegramErrorKind(err error) string {
+	switch e := err.(type) {
+	case ErrRateLimited:
+		return fmt.Sprintf("rate limited (retry after %s)", e.RetryAfter)
+	case ErrBlocked:
+		return "blocked"
+	case ErrChatNotFound:
+		return "chat not found"
+	case ErrNoRights:
+		return "no rights"
+	case ErrNetwork:
+		return "network"
+	case ErrTelegram:
+		return fmt.Sprintf("telegram (%d)", e.Code)
+	}
+	return "unknown"
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	commandTimer = "/timer"
+
+	timerParamLive   = "live"
+	timerFiredPrefix = "⏰ "
+
+	timerMaxHours            = 24
+	timerLiveMaxMinutes      = 60 // live countdown is shown only for timers shorter than this
+	timerLiveIntervalSeconds = 5  // (not to hit the rate limit of editing messages)
+	timerDefaultLabel        = "타이머"
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageTimerUsage           = "사용법: /timer 10분 라면 (또는 /timer 1시간 30분, /timer 90초)"
+	messageTimerTooLong         = "타이머는 24시간까지만 설정할 수 있습니다."
+	messageTimerSetFormat       = "⏱ %s: %s 뒤에 알려드리겠습니다."
+	messageTimerFiredFormat     = timerFiredPrefix + "%s: 시간이 다 되었습니다! (%s)"
+	messageTimerLiveFormat      = "⏱ %s: %s 남음"
+	messageTimerDoneFormat      = "⏱ %s: 끝"
+	messageTimerCanceledFormat  = "⏱ %s: 취소 되었습니다."
+	messageTimerShowCountdown   = "카운트다운 보기"
+	messageTimerCancel          = "타이머 취소"
+	messageTimerAlreadyFinished = "이미 끝난 타이머입니다."
+)
+
+var timerDurationRegex = regexp.MustCompile(`^\s*(\d+)\s*(시간|분|초)`)
+
+// in-memory timers for second-level precision (queue items are also delivered by monitorQueue after restarts)
+var _timers = struct {
+	sync.Mutex
+	timers map[int64]*time.Timer
+}{
+	timers: map[int64]*time.Timer{},
+}
+
+// process /timer command (without api.ai)
+func processTimerCommand(client *bot.Bot, chatID, userID int64, txt string, options map[string]interface{}) string {
+	duration, label := parseTimerDuration(strings.TrimPrefix(txt, commandTimer))
+	if duration <= 0 {
+		return messageTimerUsage
+	}
+	if duration > timerMaxHours*time.Hour {
+		return messageTimerTooLong
+	}
+	if label == "" {
+		label = timerDefaultLabel
+	}
+
+	fireOn := time.Now().Add(duration)
+	queueID, enqueued := db.EnqueueItem(dbhelper.QueueItem{
+		ChatID:  chatID,
+		UserID:  userID,
+		Message: fmt.Sprintf(messageTimerFiredFormat, label, formatTimerDuration(duration)),
+		FireOn:  fireOn,
+	})
+	if !enqueued {
+		return messageSaveFailed
+	}
+
+	_timers.Lock()
+	_timers.timers[queueID] = time.AfterFunc(duration, func() {
+		fireTimer(client, chatID, queueID)
+	})
+	_timers.Unlock()
+
+	// buttons for live countdown and canceling
+	cancel := fmt.Sprintf("%s %d", commandCancel, queueID)
+	buttons := []bot.InlineKeyboardButton{}
+	if duration <= timerLiveMaxMinutes*time.Minute {
+		live := fmt.Sprintf("%s %s %d", commandTimer, timerParamLive, queueID)
+		buttons = append(buttons, bot.InlineKeyboardButton{Text: messageTimerShowCountdown, CallbackData: &live})
+	}
+	buttons = append(buttons, bot.InlineKeyboardButton{Text: messageTimerCancel, CallbackData: &cancel})
+	options["reply_markup"] = bot.InlineKeyboardMarkup{
+		InlineKeyboard: [][]bot.InlineKeyboardButton{buttons},
+	}
+
+	return fmt.Sprintf(messageTimerSetFormat, label, formatTimerDuration(duration))
+}
+
+// parse leading duration of given text (eg. "1시간 30분 라면" => 1h30m, "라면")
+func parseTimerDuration(txt string) (duration time.Duration, rest string) {
+	rest = txt
+	for {
+		matches := timerDurationRegex.FindStringSubmatch(rest)
+		if matches == nil {
+			break
+		}
+
+		num, _ := strconv.Atoi(matches[1])
+		switch matches[2] {
+		case "시간":
+			duration += time.Duration(num) * time.Hour
+		case "분":
+			duration += time.Duration(num) * time.Minute
+		case "초":
+			duration += time.Duration(num) * time.Second
+		}
+
+		rest = rest[len(matches[0]):]
+	}
+
+	// also accept go-style durations (eg. "1h30m")
+	if duration <= 0 {
+		if fields := strings.Fields(txt); len(fields) > 0 {
+			if d, err := time.ParseDuration(fields[0]); err == nil {
+				return d, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(txt), fields[0]))
+			}
+		}
+	}
+
+	return duration, strings.TrimSpace(rest)
+}
+
+// format given duration in korean (eg. "1시간 30분")
+func formatTimerDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+
+	parts := []string{}
+	if h > 0 {
+		parts = append(parts, fmt.Sprintf("%d시간", h))
+	}
+	if m > 0 {
+		parts = append(parts, fmt.Sprintf("%d분", m))
+	}
+	if s > 0 || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%d초", s))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// check if given queue item is waiting for its in-memory timer
+func isTimerPending(queueID int64) bool {
+	_timers.Lock()
+	defer _timers.Unlock()
+
+	_, exists := _timers.timers[queueID]
+	return exists
+}
+
+// deliver the queue item of a timer right on time
+func fireTimer(client *bot.Bot, chatID, queueID int64) {
+	// (kept pending until it is delivered, not to be delivered by queue checks too)
+	defer func() {
+		_timers.Lock()
+		delete(_timers.timers, queueID)
+		_timers.Unlock()
+	}()
+
+	// check if it is canceled or delivered already
+	q, exists := db.QueueItem(chatID, queueID)
+	if !exists || q.DeliveredOn.Unix() > 0 || q.Paused != dbhelper.NotPaused {
+		return
+	}
+
+	deliverQueueItem(client, q)
+}
+
+// process callback query for showing live countdown of a timer
+func processTimerCallback(client *bot.Bot, query bot.CallbackQuery, txt string) (message string, keyboard interface{}) {
+	params := strings.Fields(strings.TrimPrefix(txt, commandTimer))
+	if len(params) != 2 || params[0] != timerParamLive {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError, nil
+	}
+	queueID, err := strconv.ParseInt(params[1], 10, 64)
+	if err != nil {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError, nil
+	}
+
+	chatID := query.Message.Chat.ID
+	q, exists := db.QueueItem(chatID, queueID)
+	if !exists || q.DeliveredOn.Unix() > 0 {
+		return messageTimerAlreadyFinished, nil
+	}
+
+	label := timerLabel(q.Message)
+	go runLiveCountdown(client, chatID, query.Message.MessageID, queueID, label, q.FireOn)
+
+	return fmt.Sprintf(messageTimerLiveFormat, label, formatCountdown(time.Until(q.FireOn))), timerCancelKeyboard(queueID)
+}
+
+// inline keyboard for canceling a timer
+func timerCancelKeyboard(queueID int64) bot.InlineKeyboardMarkup {
+	cancel := fmt.Sprintf("%s %d", commandCancel, queueID)
+
+	return bot.InlineKeyboardMarkup{
+		InlineKeyboard: [][]bot.InlineKeyboardButton{
+			[]bot.InlineKeyboardButton{
+				bot.InlineKeyboardButton{Text: messageTimerCancel, CallbackData: &cancel},
+			},
+		},
+	}
+}
+
+// edit given message with the remaining time until the timer fires (or is canceled)
+func runLiveCountdown(client *bot.Bot, chatID int64, messageID int, queueID int64, label string, fireOn time.Time) {
+	ticker := time.NewTicker(timerLiveIntervalSeconds * time.Second)
+	defer ticker.Stop()
+
+	options := map[string]interface{}{
+		"chat_id":    chatID,
+		"message_id": messageID,
+	}
+
+	for range ticker.C {
+		message := ""
+		if q, exists := db.QueueItem(chatID, queueID); !exists {
+			message = fmt.Sprintf(messageTimerCanceledFormat, label)
+		} else if remaining := time.Until(fireOn); remaining <= 0 || q.DeliveredOn.Unix() > 0 {
+			message = fmt.Sprintf(messageTimerDoneFormat, label)
+		} else {
+			// (keep the cancel button)
+			options["reply_markup"] = timerCancelKeyboard(queueID)
+			if edited := client.EditMessageText(fmt.Sprintf(messageTimerLiveFormat, label, formatCountdown(remaining)), options); !edited.Ok {
+				log.Printf("*** failed to edit countdown message: %s", *edited.Description)
+			}
+			continue
+		}
+
+		delete(options, "reply_markup")
+		client.EditMessageText(message, options)
+		return
+	}
+}
+
+// format remaining time as a countdown (eg. "09:58", "1:02:03")
+func formatCountdown(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	d = d.Round(time.Second)
+
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
+
+// label of a timer from its message (eg. "⏰ 라면: ..." => "라면")
+func timerLabel(message string) string {
+	label := strings.TrimPrefix(message, timerFiredPrefix)
+	if i := strings.Index(label, ":"); i > 0 {
+		return label[:i]
+	}
+	return timerDefaultLabel
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// topics: named channels which chats subscribe to,
+// and messages published to them by admins or authorized publishers are broadcasted
+// to all subscribers at their fire times (with the status of each subscriber saved by the broadcast engine)
+
+const (
+	commandTopic   = "/topic"
+	commandPublish = "/publish"
+
+	topicParamAdd         = "add"
+	topicParamDelete      = "del"
+	topicParamAllow       = "allow"
+	topicParamDeny        = "deny"
+	topicParamSubscribe   = "sub"
+	topicParamUnsubscribe = "unsub"
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageTopicsNone          = "구독할 수 있는 토픽이 없습니다."
+	messageTopicsTitle         = "토픽 (눌러서 구독/구독 해지):"
+	messageTopicButtonFormat   = "%s%s (구독 %d)"
+	messageTopicSubscribedMark = "✅ "
+	messageTopicSubscribedFmt  = "'%s' 토픽을 구독합니다."
+	messageTopicUnsubscribed   = "'%s' 토픽 구독을 해지했습니다."
+	messageTopicNotFound       = "해당하는 토픽이 없습니다. (/topic 으로 확인할 수 있습니다)"
+	messageTopicAdminUsage     = `사용법 (관리자):
+/topic add <이름> : 토픽 등록 (이름은 띄어쓰기 없이)
+/topic del <id> : 토픽 삭제
+/topic allow <id> <user id> : 토픽에 발행할 수 있는 사용자 추가
+/topic deny <id> <user id> : 발행 권한 해제`
+	messageTopicAddedFormat      = "[관리자] 토픽을 등록했습니다: %d. %s"
+	messageTopicDeletedFormat    = "[관리자] 토픽 %d을(를) 삭제했습니다."
+	messageTopicAllowedFormat    = "[관리자] 사용자 %d이(가) '%s' 토픽에 발행할 수 있습니다."
+	messageTopicDeniedFormat     = "[관리자] 사용자 %d의 '%s' 토픽 발행 권한을 해제했습니다."
+	messagePublishUsage          = "사용법: /publish <토픽> 2006-01-02 15:04 발행할 내용"
+	messagePublishNotAllowed     = "이 토픽에 발행할 권한이 없습니다."
+	messagePublishScheduledFmt   = "%s에 '%s' 토픽의 구독자 모두에게 보냅니다. (현재 구독 %d)"
+	messageTopicPostFormat       = "[%s] %s"
+	messageTopicPostDoneFormat   = "'%s' 토픽 발송 완료: 성공 %d, 실패 %d"
+	messageTopicPostTopicDeleted = "토픽이 삭제되어 예약된 글을 보내지 않았습니다: %s"
+)
+
+// process /topic command
+//
+//	"/topic"                         : list topics with buttons for subscribing
+//	"/topic add <name>"              : add a topic (admins only)
+//	"/topic del <id>"                : delete a topic (admins only)
+//	"/topic <allow|deny> <id> <uid>" : (de)authorize a publisher of a topic (admins only)
+func processTopicCommand(chatID, userID int64, txt string, options map[string]interface{}) string {
+	params := strings.Fields(strings.TrimPrefix(txt, commandTopic))
+	if len(params) <= 0 {
+		message, keyboard := topicList(chatID)
+		if keyboard != nil {
+			options["reply_markup"] = keyboard
+		}
+		return message
+	}
+
+	if !isAdmin(userID) {
+		return messageAnnounceAdminOnly
+	}
+
+	switch params[0] {
+	case topicParamAdd:
+		if len(params) == 2 {
+			topicID, saved := db.SaveTopic(dbhelper.Topic{Name: params[1], CreatedBy: userID})
+			if !saved {
+				return messageSaveFailed
+			}
+			return fmt.Sprintf(messageTopicAddedFormat, topicID, params[1])
+		}
+	case topicParamDelete:
+		if len(params) == 2 {
+			if topicID, err := strconv.ParseInt(params[1], 10, 64); err == nil {
+				if db.DeleteTopic(topicID) {
+					return fmt.Sprintf(messageTopicDeletedFormat, topicID)
+				}
+				return messageTopicNotFound
+			}
+		}
+	case topicParamAllow, topicParamDeny:
+		if len(params) == 3 {
+			topicID, err1 := strconv.ParseInt(params[1], 10, 64)
+			publisherID, err2 := strconv.ParseInt(params[2], 10, 64)
+			if err1 == nil && err2 == nil {
+				t, exists := topicWithID(topicID)
+				if !exists {
+					return messageTopicNotFound
+				}
+
+				if params[0] == topicParamAllow {
+					if !db.AddTopicPublisher(t.ID, publisherID) {
+						return messageSaveFailed
+					}
+					return fmt.Sprintf(messageTopicAllowedFormat, publisherID, t.Name)
+				}
+
+				db.RemoveTopicPublisher(t.ID, publisherID)
+				return fmt.Sprintf(messageTopicDeniedFormat, publisherID, t.Name)
+			}
+		}
+	}
+
+	return messageTopicAdminUsage
+}
+
+// process /publish command: schedule a message to all subscribers of a topic
+//
+//	"/publish <topic> <yyyy-mm-dd> <hh:mm> <message>"
+func processPublishCommand(chatID, userID int64, txt string) string {
+	params := strings.Fields(strings.TrimPrefix(txt, commandPublish))
+	if len(params) < 4 {
+		return messagePublishUsage
+	}
+
+	t, exists := topicNamed(params[0])
+	if !exists {
+		return messageTopicNotFound
+	}
+	if !isAdmin(userID) && !db.IsTopicPublisher(t.ID, userID) {
+		return messagePublishNotAllowed
+	}
+
+	when, err := time.ParseInLocation(announceTimeFormat, params[1]+" "+params[2], _location)
+	if err != nil {
+		return messageAnnounceTimeParseError
+	}
+	if when.Before(time.Now()) {
+		return when.Format(messageTimeIsPastFormat)
+	}
+
+	// text after date and time (keeping line breaks)
+	text := strings.TrimSpace(txt[strings.Index(txt, params[2])+len(params[2]):])
+
+	if _, enqueued := db.EnqueueItem(dbhelper.QueueItem{
+		ChatID:    chatID,
+		UserID:    userID,
+		Message:   text,
+		FireOn:    when,
+		Broadcast: true,
+		Kind:      dbhelper.QueueKindTopicPost,
+		RefID:     t.ID,
+	}); !enqueued {
+		return messageSaveFailed
+	}
+
+	db.Log(fmt.Sprintf("user %d published to topic %d at %s", userID, t.ID, when.Format(announceTimeFormat)))
+
+	return fmt.Sprintf(messagePublishScheduledFmt, formatTimeFor(chatID, when, reminderTimeFormat), t.Name, len(db.TopicSubscriptions(t.ID, 0)))
+}
+
+// topics with buttons for (un)subscribing
+func topicList(chatID int64) (message string, keyboard interface{}) {
+	topics := db.Topics()
+	if len(topics) <= 0 {
+		return messageTopicsNone, nil
+	}
+
+	subscribed := map[int64]bool{}
+	for _, s := range db.TopicSubscriptions(0, chatID) {
+		subscribed[s.TopicID] = true
+	}
+
+	buttons := [][]bot.InlineKeyboardButton{}
+	for _, t := range topics {
+		mark, param := "", topicParamSubscribe
+		if subscribed[t.ID] {
+			mark, param = messageTopicSubscribedMark, topicParamUnsubscribe
+		}
+
+		data := fmt.Sprintf("%s %s %d", commandTopic, param, t.ID)
+		buttons = append(buttons, []bot.InlineKeyboardButton{
+			bot.InlineKeyboardButton{
+				Text:         fmt.Sprintf(messageTopicButtonFormat, mark, t.Name, len(db.TopicSubscriptions(t.ID, 0))),
+				CallbackData: &data,
+			},
+		})
+	}
+	cancel := commandCancel
+	buttons = append(buttons, []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{Text: messageCancel, CallbackData: &cancel},
+	})
+
+	return messageTopicsTitle, bot.InlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// process callback query for (un)subscribing: '/topic <sub|unsub> <id>'
+func processTopicCallback(query bot.CallbackQuery, txt string) (message string, keyboard interface{}) {
+	chatID := query.Message.Chat.ID
+	userID := int64(query.From.ID)
+
+	params := strings.Fields(strings.TrimPrefix(txt, commandTopic))
+	if len(params) == 2 {
+		if topicID, err := strconv.ParseInt(params[1], 10, 64); err == nil {
+			t, exists := topicWithID(topicID)
+			if !exists {
+				return messageTopicNotFound, nil
+			}
+
+			switch params[0] {
+			case topicParamSubscribe:
+				if db.SubscribeTopic(t.ID, chatID, userID) {
+					message = fmt.Sprintf(messageTopicSubscribedFmt, t.Name)
+				} else {
+					message = messageSaveFailed
+				}
+			case topicParamUnsubscribe:
+				db.UnsubscribeTopic(t.ID, chatID)
+				message = fmt.Sprintf(messageTopicUnsubscribed, t.Name)
+			}
+
+			if message != "" {
+				_, keyboard = topicList(chatID)
+
+				return message, keyboard
+			}
+		}
+	}
+
+	log.Printf("*** Unprocessable callback query: %s", txt)
+
+	return messageError, nil
+}
+
+// topic with given id
+func topicWithID(topicID int64) (dbhelper.Topic, bool) {
+	for _, t := range db.Topics() {
+		if t.ID == topicID {
+			return t, true
+		}
+	}
+	return dbhelper.Topic{}, false
+}
+
+// topic with given name (case insensitive)
+func topicNamed(name string) (dbhelper.Topic, bool) {
+	for _, t := range db.Topics() {
+		if strings.EqualFold(t.Name, name) {
+			return t, true
+		}
+	}
+	return dbhelper.Topic{}, false
+}
+
+// fan out given topic post to all subscribers of its topic (with the broadcast engine)
+func broadcastTopicPost(client *bot.Bot, q dbhelper.QueueItem) {
+	t, exists := topicWithID(q.RefID)
+	if !exists {
+		log.Printf("*** topic %d of queue item %d does not exist", q.RefID, q.ID)
+
+		// (nothing to broadcast)
+		db.MarkQueueItemAsDelivered(q.ChatID, q.ID)
+
+		if sent := client.SendMessage(q.ChatID, fmt.Sprintf(messageTopicPostTopicDeleted, q.Message), map[string]interface{}{}); !sent.Ok {
+			log.Printf("*** failed to notify publisher of queue item %d: %s", q.ID, *sent.Description)
+		}
+		return
+	}
+
+	chatIDs := []int64{}
+	for _, s := range db.TopicSubscriptions(t.ID, 0) {
+		chatIDs = append(chatIDs, s.ChatID)
+	}
+
+	if !startBroadcast(client, q, fmt.Sprintf(messageTopicPostFormat, t.Name, q.Message), chatIDs) {
+		log.Printf("*** failed to start broadcasting topic post %d", q.ID)
+
+		// (retried in the next check, until the max number of tries)
+		db.IncreaseNumTries(q.ChatID, q.ID)
+	}
+}
+
+// report the stats of a finished topic post to its publisher
+func reportTopicPost(client *bot.Bot, q dbhelper.QueueItem, stats dbhelper.BroadcastStats) {
+	name := strconv.FormatInt(q.RefID, 10)
+	if t, exists := topicWithID(q.RefID); exists {
+		name = t.Name
+	}
+
+	if sent := client.SendMessage(q.ChatID, fmt.Sprintf(messageTopicPostDoneFormat, name, stats.NumSent, stats.NumFailed), map[string]interface{}{}); !sent.Ok {
+		log.Printf("*** failed to report topic post %d to its publisher: %s", q.ID, *sent.Description)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracingServiceName = "telegram-bot-reminder-api.ai"
+
+	// span names
+	spanNameUpdate        = "update"
+	spanNameQuery         = "apiai.query"
+	spanNameEnqueue       = "db.enqueue"
+	spanNameDelivery      = "delivery"
+	spanNameSendMessage   = "telegram.send_message"
+	spanNameMarkDelivered = "db.mark_delivered"
+)
+
+// it does nothing until a tracer provider is set up with setupTracing()
+var tracer = otel.Tracer(tracingServiceName)
+
+// setup tracer provider which exports spans to given OTLP (http) endpoint
+func setupTracing(endpoint string) error {
+	exporter, err := otlptracehttp.New(
+		context.Background(),
+		otlptracehttp.WithEndpoint(endpoint),
+		otlptracehttp.WithInsecure(),
+	)
+	if err != nil {
+		return err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(tracingServiceName),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	log.Printf("> Exporting traces to: %s", endpoint)
+
+	return nil
+}
+
+// start a new span with given chat id
+func startSpan(ctx context.Context, name string, chatID int64, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(append(attrs, attribute.Int64("chat.id", chatID))...))
+}
+
+// end given span, marking it as failed if it was not successful
+func endSpan(span trace.Span, success bool, description string) {
+	if !success {
+		span.SetStatus(codes.Error, description)
+	}
+	span.End()
+}
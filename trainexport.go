@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	apiai "github.com/meinside/api.ai-go"
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// exporting training data: misparses reported by users and queries scored low by api.ai
+// are exported as training phrases of api.ai/Dialogflow (JSON), or as a sheet for reviewing them (CSV)
+
+const (
+	commandTrainExport = "/trainexport"
+
+	trainExportFormatJSON  = "json"
+	trainExportFormatCSV   = "csv"
+	trainExportDefaultDays = 30
+	trainExportLanguage    = "ko"
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageTrainExportUsage         = "사용법: /trainexport <json|csv> [최근 일수 (기본값: 30)]"
+	messageTrainExportEmpty         = "[관리자] 내보낼 학습 데이터가 없습니다."
+	messageTrainExportFailed        = "[관리자] 학습 데이터를 내보내지 못했습니다."
+	messageTrainExportCaptionFormat = "[관리자] 최근 %d일 동안 모인 학습 데이터 %d건 (%s)"
+	messageTrainExportSentFormat    = "[관리자] 학습 데이터 %d건을 첨부했습니다."
+)
+
+// training phrase of api.ai/Dialogflow ("usersays" of intents)
+type trainingPhrase struct {
+	Data       []trainingPhrasePart `json:"data"`
+	IsTemplate bool                 `json:"isTemplate"`
+	Count      int                  `json:"count"`
+	Lang       string               `json:"lang"`
+}
+
+// part of a training phrase
+type trainingPhrasePart struct {
+	Text        string `json:"text"`
+	UserDefined bool   `json:"userDefined"`
+}
+
+// save given query if api.ai was not confident of it
+func recordLowConfidenceQuery(chatID, userID int64, query string, response apiai.QueryResponse) {
+	score := float64(response.Result.Score)
+	if _conf.LowConfidenceScore <= 0 || score >= _conf.LowConfidenceScore {
+		return
+	}
+
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("*** Failed to marshal api.ai response: %s", err)
+		return
+	}
+	db.SaveTrainingData(chatID, userID, dbhelper.TrainingSourceLowConfidence, query, string(encoded), score)
+}
+
+// name of the intent detected in given (JSON) response of api.ai
+func detectedIntentName(response string) string {
+	var decoded apiai.QueryResponse
+	if err := json.Unmarshal([]byte(response), &decoded); err != nil {
+		return ""
+	}
+	return decoded.Result.Metadata.IntentName
+}
+
+// training phrases grouped by the intents detected at the time
+//
+// (operators are supposed to move them to the right intents before importing)
+func trainingPhrasesJSON(data []dbhelper.TrainingData) ([]byte, error) {
+	phrases := map[string][]trainingPhrase{}
+	for _, t := range data {
+		intent := detectedIntentName(t.Response)
+		if intent == "" {
+			intent = "unknown"
+		}
+
+		for _, text := range []string{t.Query, t.Correction} {
+			if text == "" {
+				continue
+			}
+			phrases[intent] = append(phrases[intent], trainingPhrase{
+				Data: []trainingPhrasePart{trainingPhrasePart{Text: text}},
+				Lang: trainExportLanguage,
+			})
+		}
+	}
+
+	return json.MarshalIndent(phrases, "", "  ")
+}
+
+// training data as a sheet for reviewing
+func trainingDataCSV(data []dbhelper.TrainingData) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	w.Write([]string{"id", "source", "score", "query", "detected_intent", "correction", "chat_id", "user_id", "created_on"})
+	for _, t := range data {
+		w.Write([]string{
+			strconv.FormatInt(t.ID, 10),
+			t.Source,
+			strconv.FormatFloat(t.Score, 'f', 3, 64),
+			t.Query,
+			detectedIntentName(t.Response),
+			t.Correction,
+			strconv.FormatInt(t.ChatID, 10),
+			strconv.FormatInt(t.UserID, 10),
+			t.CreatedOn.Format(time.RFC3339),
+		})
+	}
+	w.Flush()
+
+	return buf.Bytes(), w.Error()
+}
+
+// process /trainexport command: send collected training data as an attachment to the admin
+func processTrainExportCommand(b *bot.Bot, chatID, userID int64, txt string) string {
+	if !isAdmin(userID) {
+		return messageAnnounceAdminOnly
+	}
+
+	params := strings.Fields(strings.TrimPrefix(txt, commandTrainExport))
+	if len(params) < 1 || len(params) > 2 {
+		return messageTrainExportUsage
+	}
+	days := trainExportDefaultDays
+	if len(params) > 1 {
+		var err error
+		if days, err = strconv.Atoi(params[1]); err != nil || days <= 0 {
+			return messageTrainExportUsage
+		}
+	}
+
+	data := db.TrainingDataSince(time.Now().AddDate(0, 0, -days))
+	if len(data) <= 0 {
+		return messageTrainExportEmpty
+	}
+
+	var exported []byte
+	var err error
+	switch params[0] {
+	case trainExportFormatJSON:
+		exported, err = trainingPhrasesJSON(data)
+	case trainExportFormatCSV:
+		exported, err = trainingDataCSV(data)
+	default:
+		return messageTrainExportUsage
+	}
+	if err != nil {
+		log.Printf("*** Failed to export training data: %s", err)
+		return messageTrainExportFailed
+	}
+
+	keepExport("training."+params[0], exported)
+
+	options := map[string]interface{}{
+		"caption": fmt.Sprintf(messageTrainExportCaptionFormat, days, len(data), params[0]),
+	}
+	if sent := b.SendDocument(chatID, bot.InputFileFromBytes(exported), options); !sent.Ok {
+		log.Printf("*** Failed to send training data: %s", *sent.Description)
+		return messageTrainExportFailed
+	}
+
+	return fmt.Sprintf(messageTrainExportSentFormat, len(data))
+}
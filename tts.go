@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	ttsDefaultEndpoint  = "https://api.openai.com/v1/audio/speech"
+	ttsTimeoutSeconds   = 30
+	ttsDefaultModelName = "tts-1"
+	ttsDefaultVoice     = "alloy"
+	ttsResponseFormat   = "opus" // (voice notes of telegram should be ogg/opus)
+	ttsMaxInputLength   = 1000   // longer texts will be cut
+	ttsMaxAudioBytes    = 20 * 1024 * 1024
+)
+
+// config for TTS backend (OpenAI-compatible speech API)
+type ttsConfig struct {
+	Endpoint string `json:"endpoint,omitempty"` // (default: OpenAI)
+	APIKey   string `json:"api_key"`
+	Model    string `json:"model,omitempty"`
+	Voice    string `json:"voice,omitempty"`
+}
+
+// request body of speech API
+type ttsRequest struct {
+	Model          string `json:"model"`
+	Input          string `json:"input"`
+	Voice          string `json:"voice"`
+	ResponseFormat string `json:"response_format"`
+}
+
+var _ttsClient = &http.Client{Timeout: ttsTimeoutSeconds * time.Second}
+
+// validate TTS config values
+func (c ttsConfig) validate() (problems []string) {
+	if c.Endpoint != "" {
+		if u, err := url.Parse(c.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("endpoint of tts is malformed: %s", c.Endpoint))
+		}
+	}
+	if c.APIKey == "" {
+		problems = append(problems, "api_key of tts is empty")
+	}
+
+	return problems
+}
+
+// check if TTS backend is configured
+func isTTSEnabled() bool {
+	return _conf.TTS != nil
+}
+
+// check if given reminder should also be delivered as a voice note
+func shouldSendVoice(q dbhelper.QueueItem) bool {
+	return isTTSEnabled() && q.Kind == dbhelper.QueueKindReminder && !isPrivateInGroup(q) && db.ChatSettings(q.ChatID).Voice
+}
+
+// synthesize speech of given text with the TTS backend
+func ttsSynthesize(text string) ([]byte, error) {
+	if !isTTSEnabled() {
+		return nil, fmt.Errorf("tts is not configured")
+	}
+
+	endpoint, model, voice := _conf.TTS.Endpoint, _conf.TTS.Model, _conf.TTS.Voice
+	if endpoint == "" {
+		endpoint = ttsDefaultEndpoint
+	}
+	if model == "" {
+		model = ttsDefaultModelName
+	}
+	if voice == "" {
+		voice = ttsDefaultVoice
+	}
+	if runes := []rune(text); len(runes) > ttsMaxInputLength {
+		text = string(runes[:ttsMaxInputLength])
+	}
+
+	body, _ := json.Marshal(ttsRequest{
+		Model:          model,
+		Input:          text,
+		Voice:          voice,
+		ResponseFormat: ttsResponseFormat,
+	})
+
+	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+_conf.TTS.APIKey)
+
+	resp, err := _ttsClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tts error: %s", resp.Status)
+	}
+
+	return ioutil.ReadAll(io.LimitReader(resp.Body, ttsMaxAudioBytes))
+}
+
+// send given reminder as a voice note, replying to the delivered text message
+func sendVoiceNote(client *bot.Bot, q dbhelper.QueueItem, messageID int) {
+	audio, err := ttsSynthesize(q.Message)
+	if err != nil {
+		log.Printf("*** failed to synthesize voice of queue item %d: %s", q.ID, err)
+		return
+	}
+
+	if sent := client.SendVoice(q.ChatID, bot.InputFileFromBytes(audio), map[string]interface{}{
+		"reply_to_message_id": messageID,
+	}); !sent.Ok {
+		log.Printf("*** failed to send voice of queue item %d: %s", q.ID, *sent.Description)
+	}
+}
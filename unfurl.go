@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	unfurlTimeoutSeconds = 5
+	unfurlMaxBytes       = 512 * 1024 // titles are in the head, so the rest is not needed
+	unfurlMaxURLs        = 3
+	unfurlMaxTitleLength = 100
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageLinkTitleFormat = "🔗 %s"
+)
+
+var (
+	urlRegex     = regexp.MustCompile(`https?://[^\s<>"]+`)
+	ogTitleRegex = regexp.MustCompile(`(?is)<meta[^>]+property=["']og:title["'][^>]+content=["']([^"']+)["']`)
+	titleRegex   = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+var _unfurlClient = &http.Client{
+	Timeout:   unfurlTimeoutSeconds * time.Second,
+	Transport: publicOnlyTransport(unfurlTimeoutSeconds * time.Second),
+}
+
+// http transport which connects to public addresses only
+//
+// (addresses are checked when dialing, so redirects and dns rebinding cannot reach internal hosts)
+func publicOnlyTransport(timeout time.Duration) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("not a public address: %s", host)
+			}
+			return nil
+		},
+	}
+
+	// (no proxies, as they would connect to the addresses instead)
+	return &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: timeout,
+	}
+}
+
+// check if given ip is a public one (not loopback, private, link-local, or multicast)
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// urls in given message
+func urlsInMessage(message string) []string {
+	return urlRegex.FindAllString(message, unfurlMaxURLs)
+}
+
+// append titles of the pages linked in given message
+func unfurlMessage(message string) string {
+	lines := []string{message}
+	for _, url := range urlsInMessage(message) {
+		if title := fetchPageTitle(url); title != "" {
+			lines = append(lines, fmt.Sprintf(messageLinkTitleFormat, title))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// fetch the title of given page (empty if it failed)
+func fetchPageTitle(url string) string {
+	body, err := fetchPage(url, unfurlMaxBytes)
+	if err != nil {
+		if _isVerbose {
+			log.Printf("Failed to fetch page title of %s: %s", url, err)
+		}
+		return ""
+	}
+
+	title := ""
+	if matches := ogTitleRegex.FindSubmatch(body); matches != nil {
+		title = string(matches[1])
+	} else if matches := titleRegex.FindSubmatch(body); matches != nil {
+		title = string(matches[1])
+	}
+	title = strings.Join(strings.Fields(html.UnescapeString(title)), " ")
+
+	if runes := []rune(title); len(runes) > unfurlMaxTitleLength {
+		title = string(runes[:unfurlMaxTitleLength]) + "…"
+	}
+
+	return title
+}
+
+// fetch (at most given bytes of) given html page
+func fetchPage(url string, maxBytes int64) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "telegram-bot-reminder-api.ai")
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := _unfurlClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http status: %s", resp.Status)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !strings.Contains(contentType, "html") {
+		return nil, fmt.Errorf("not a html page: %s", contentType)
+	}
+
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxBytes))
+}
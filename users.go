@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	bot "github.com/meinside/telegram-bot-go"
+)
+
+// save the latest seen names of given user, and return his id and username
+func saveUser(user *bot.User) (userID int64, username string) {
+	userID = int64(user.ID)
+
+	var lastName string
+	if user.Username != nil {
+		username = *user.Username
+	}
+	if user.LastName != nil {
+		lastName = *user.LastName
+	}
+
+	db.SaveUser(userID, username, user.FirstName, lastName)
+
+	return userID, username
+}
+
+// check if given Telegram user is allowed or not
+//
+// entries of allowed_user_ids can be numeric user ids or usernames.
+// users allowed by their usernames are remembered by their ids,
+// so they are still allowed after changing their usernames.
+func isAllowedUser(userID int64, username string) bool {
+	if _restrictUsers == false {
+		return true
+	}
+
+	id := strconv.FormatInt(userID, 10)
+	for _, v := range _allowedUserIds {
+		if v == id {
+			return true
+		}
+		if username != "" && v == username {
+			db.AllowUser(userID, v)
+			return true
+		}
+	}
+
+	// allowed by a (still valid) username before
+	if user, exists := db.User(userID); exists && user.AllowedAs != "" {
+		return true
+	}
+
+	return false
+}
+
+// returns a displayable name of given user id
+func userDisplayName(userID int64) string {
+	if user, exists := db.User(userID); exists {
+		return user.DisplayName()
+	}
+
+	return fmt.Sprintf("%d", userID)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+const (
+	commandVacation = "/vacation"
+
+	vacationParamOff = "취소"
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageVacationUsage = `사용법:
+/vacation 7월 1일부터 7월 10일까지 : 휴가 기간 등록
+/vacation 취소 : 등록된 휴가 취소`
+	messageVacationHowFormat   = "%s ~ %s 동안의 알림을 어떻게 할까요?"
+	messageVacationSkip        = "보내지 않기"
+	messageVacationDefer       = "휴가 끝난 뒤 보내기"
+	messageVacationSavedFormat = "%s ~ %s 휴가가 등록 되었습니다. (%s)"
+	messageVacationListFormat  = "➤ %s ~ %s (%s)\n"
+	messageVacationCanceled    = "휴가가 취소 되었습니다."
+	messageVacationNone        = "등록된 휴가가 없습니다."
+	messageVacationParseError  = "휴가 기간이 올바르지 않습니다."
+	messageVacationTimeFormat  = "1월 2일"
+)
+
+// eg. "7월 1일부터 7월 10일까지", "2017년 12월 30일부터 2018년 1월 2일까지"
+var vacationRegex = regexp.MustCompile(`(?:(\d{4})년\s*)?(\d{1,2})월\s*(\d{1,2})일\s*부터\s*(?:(\d{4})년\s*)?(\d{1,2})월\s*(\d{1,2})일\s*까지`)
+
+// process /vacation command
+func processVacationCommand(chatID int64, txt string, options map[string]interface{}) string {
+	param := strings.TrimSpace(strings.TrimPrefix(txt, commandVacation))
+
+	if param == "" {
+		vacations := db.Suppressions(chatID, dbhelper.SuppressionKindVacation)
+		if len(vacations) <= 0 {
+			return messageVacationNone + "\n\n" + messageVacationUsage
+		}
+
+		message := ""
+		for _, v := range vacations {
+			message += fmt.Sprintf(messageVacationListFormat, v.StartsOn.Format(messageVacationTimeFormat), lastDayOf(v).Format(messageVacationTimeFormat), vacationModeName(v.Mode))
+		}
+		return message + "\n" + messageVacationUsage
+	}
+
+	if param == vacationParamOff {
+		if db.DeleteSuppressions(chatID, dbhelper.SuppressionKindVacation) > 0 {
+			return messageVacationCanceled
+		}
+		return messageVacationNone
+	}
+
+	startsOn, endsOn, err := parseVacation(param, time.Now())
+	if err != nil {
+		return messageVacationParseError
+	}
+
+	// ask how to handle reminders in the window
+	skip := fmt.Sprintf("%s %s %d %d", commandVacation, dbhelper.SuppressionModeSkip, startsOn.Unix(), endsOn.Unix())
+	deferred := fmt.Sprintf("%s %s %d %d", commandVacation, dbhelper.SuppressionModeDefer, startsOn.Unix(), endsOn.Unix())
+	cancel := commandCancel
+	options["reply_markup"] = bot.InlineKeyboardMarkup{
+		InlineKeyboard: [][]bot.InlineKeyboardButton{
+			[]bot.InlineKeyboardButton{
+				bot.InlineKeyboardButton{Text: messageVacationSkip, CallbackData: &skip},
+				bot.InlineKeyboardButton{Text: messageVacationDefer, CallbackData: &deferred},
+			},
+			[]bot.InlineKeyboardButton{
+				bot.InlineKeyboardButton{Text: messageCancel, CallbackData: &cancel},
+			},
+		},
+	}
+
+	return fmt.Sprintf(messageVacationHowFormat, startsOn.Format(messageVacationTimeFormat), endsOn.Add(-time.Second).Format(messageVacationTimeFormat))
+}
+
+// process callback query for saving a vacation
+func processVacationCallback(query bot.CallbackQuery, txt string) string {
+	params := strings.Fields(strings.TrimPrefix(txt, commandVacation))
+	if len(params) != 3 {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+
+	startsOn, err1 := strconv.ParseInt(params[1], 10, 64)
+	endsOn, err2 := strconv.ParseInt(params[2], 10, 64)
+	if err1 != nil || err2 != nil {
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError
+	}
+
+	vacation := dbhelper.Suppression{
+		ChatID:   query.Message.Chat.ID,
+		Kind:     dbhelper.SuppressionKindVacation,
+		Mode:     params[0],
+		StartsOn: time.Unix(startsOn, 0),
+		EndsOn:   time.Unix(endsOn, 0),
+	}
+	if _, saved := db.SaveSuppression(vacation); !saved {
+		return messageError
+	}
+
+	return fmt.Sprintf(messageVacationSavedFormat, vacation.StartsOn.Format(messageVacationTimeFormat), lastDayOf(vacation).Format(messageVacationTimeFormat), vacationModeName(vacation.Mode))
+}
+
+// parse vacation period from given text
+//
+// returns the beginning of the first day, and the beginning of the day after the last day
+func parseVacation(txt string, now time.Time) (startsOn, endsOn time.Time, err error) {
+	matches := vacationRegex.FindStringSubmatch(txt)
+	if matches == nil {
+		return startsOn, endsOn, fmt.Errorf("no vacation period in: %s", txt)
+	}
+
+	nums := make([]int, len(matches))
+	for i, m := range matches[1:] {
+		nums[i+1], _ = strconv.Atoi(m) // (empty years become 0)
+	}
+
+	startYear, endYear := nums[1], nums[4]
+	if startYear == 0 {
+		startYear = now.Year()
+	}
+	if endYear == 0 {
+		endYear = startYear
+	}
+
+	startsOn = time.Date(startYear, time.Month(nums[2]), nums[3], 0, 0, 0, 0, _location)
+	endsOn = time.Date(endYear, time.Month(nums[5]), nums[6], 0, 0, 0, 0, _location).AddDate(0, 0, 1)
+
+	// (eg. "12월 30일부터 1월 2일까지")
+	if !endsOn.After(startsOn) && matches[4] == "" {
+		endsOn = endsOn.AddDate(1, 0, 0)
+	}
+	// already passed this year
+	if !endsOn.After(now) && matches[1] == "" {
+		startsOn, endsOn = startsOn.AddDate(1, 0, 0), endsOn.AddDate(1, 0, 0)
+	}
+
+	if !endsOn.After(startsOn) || !endsOn.After(now) ||
+		startsOn.Day() != nums[3] || endsOn.AddDate(0, 0, -1).Day() != nums[6] { // (eg. "2월 30일")
+		return startsOn, endsOn, fmt.Errorf("invalid vacation period: %s", txt)
+	}
+
+	return startsOn, endsOn, nil
+}
+
+// last day of given suppression window
+func lastDayOf(s dbhelper.Suppression) time.Time {
+	return s.EndsOn.Add(-time.Second)
+}
+
+// displayable name of given vacation mode
+func vacationModeName(mode string) string {
+	if mode == dbhelper.SuppressionModeDefer {
+		return messageVacationDefer
+	}
+	return messageVacationSkip
+}
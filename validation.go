@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	maxMonitorIntervalSeconds  = 3600
+	maxTelegramIntervalSeconds = 60
+	maxMaxNumTries             = 100
+)
+
+var (
+	telegramTokenRegex    = regexp.MustCompile(`^\d+:[A-Za-z0-9_-]+$`)
+	telegramUsernameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{4,31}$`)
+	telegramUserIDRegex   = regexp.MustCompile(`^[1-9]\d*$`)
+)
+
+// validate config values, returning all the problems found
+func (c config) validate() (problems []string) {
+	// tokens
+	if strings.TrimSpace(c.TelegramAPIToken) == "" {
+		problems = append(problems, "telegram_api_token is empty")
+	} else if !telegramTokenRegex.MatchString(c.TelegramAPIToken) {
+		problems = append(problems, "telegram_api_token is malformed (should look like '0123456789:abcdefg...')")
+	}
+	if strings.TrimSpace(c.ApiaiAccessToken) == "" {
+		problems = append(problems, "apiai_access_token is empty")
+	}
+
+	// intervals (0 means default)
+	if c.MonitorIntervalSeconds < 0 || c.MonitorIntervalSeconds > maxMonitorIntervalSeconds {
+		problems = append(problems, fmt.Sprintf("monitor_interval_seconds should be between 1 and %d (or 0 for default): %d", maxMonitorIntervalSeconds, c.MonitorIntervalSeconds))
+	}
+	if c.TelegramIntervalSeconds < 0 || c.TelegramIntervalSeconds > maxTelegramIntervalSeconds {
+		problems = append(problems, fmt.Sprintf("telegram_interval_seconds should be between 1 and %d (or 0 for default): %d", maxTelegramIntervalSeconds, c.TelegramIntervalSeconds))
+	}
+	if c.MaxNumTries < 0 || c.MaxNumTries > maxMaxNumTries {
+		problems = append(problems, fmt.Sprintf("max_num_tries should be between 1 and %d (or 0 for default): %d", maxMaxNumTries, c.MaxNumTries))
+	}
+	if c.BackupIntervalHours < 0 {
+		problems = append(problems, fmt.Sprintf("backup_interval_hours should not be negative: %d", c.BackupIntervalHours))
+	}
+	if c.NumBackupsToKeep < 0 {
+		problems = append(problems, fmt.Sprintf("num_backups_to_keep should not be negative: %d", c.NumBackupsToKeep))
+	}
+	if c.FeedIntervalMinutes < 0 {
+		problems = append(problems, fmt.Sprintf("feed_interval_minutes should not be negative: %d", c.FeedIntervalMinutes))
+	}
+	if c.PriceAlertIntervalMinutes < 0 {
+		problems = append(problems, fmt.Sprintf("price_alert_interval_minutes should not be negative: %d", c.PriceAlertIntervalMinutes))
+	}
+	if c.CollisionPolicy != "" && c.CollisionPolicy != collisionPolicyMerge && c.CollisionPolicy != collisionPolicyStagger {
+		problems = append(problems, fmt.Sprintf("collision_policy should be one of '%s' and '%s': %s", collisionPolicyMerge, collisionPolicyStagger, c.CollisionPolicy))
+	}
+	if c.CollisionSpacingSeconds < 0 {
+		problems = append(problems, fmt.Sprintf("collision_spacing_seconds should not be negative: %d", c.CollisionSpacingSeconds))
+	}
+	if c.QueueShards < 0 {
+		problems = append(problems, fmt.Sprintf("queue_shards should not be negative: %d", c.QueueShards))
+	}
+	if c.ShardMessagesPerSecond < 0 {
+		problems = append(problems, fmt.Sprintf("shard_messages_per_second should not be negative: %f", c.ShardMessagesPerSecond))
+	}
+	if c.LatencyWarningSeconds < 0 {
+		problems = append(problems, fmt.Sprintf("latency_warning_seconds should not be negative: %d", c.LatencyWarningSeconds))
+	}
+	if c.LowConfidenceScore < 0 || c.LowConfidenceScore > 1 {
+		problems = append(problems, fmt.Sprintf("low_confidence_score should be between 0 and 1: %f", c.LowConfidenceScore))
+	}
+	if c.BroadcastMessagesPerSecond < 0 {
+		problems = append(problems, fmt.Sprintf("broadcast_messages_per_second should not be negative: %f", c.BroadcastMessagesPerSecond))
+	}
+	if c.DraftExpiryMinutes < 0 {
+		problems = append(problems, fmt.Sprintf("draft_expiry_minutes should not be negative: %d", c.DraftExpiryMinutes))
+	}
+	if c.SlowQueryMilliseconds < 0 {
+		problems = append(problems, fmt.Sprintf("slow_query_milliseconds should not be negative: %d", c.SlowQueryMilliseconds))
+	}
+	if c.PollWindowMinutes < 0 {
+		problems = append(problems, fmt.Sprintf("poll_window_minutes should not be negative: %d", c.PollWindowMinutes))
+	}
+	if c.PaydayOfMonth < 0 || c.PaydayOfMonth > 31 {
+		problems = append(problems, fmt.Sprintf("payday_of_month should be between 1 and 31 (or 0 for default): %d", c.PaydayOfMonth))
+	}
+
+	// users
+	if c.RestrictUsers && len(c.AllowedUserIds) <= 0 {
+		problems = append(problems, "restrict_users is set, but allowed_user_ids is empty (nobody can use the bot)")
+	}
+	for _, id := range c.AllowedUserIds {
+		if strings.HasPrefix(id, "@") {
+			problems = append(problems, fmt.Sprintf("allowed_user_ids should not start with '@': %s", id))
+		} else if !telegramUserIDRegex.MatchString(id) && !telegramUsernameRegex.MatchString(id) {
+			problems = append(problems, fmt.Sprintf("allowed_user_ids has a malformed user id or username: '%s'", id))
+		}
+	}
+	for _, id := range c.AdminUserIds {
+		if id <= 0 {
+			problems = append(problems, fmt.Sprintf("admin_user_ids has an invalid user id: %d", id))
+		}
+	}
+
+	// escalations
+	if c.EscalationWebhookURL != "" {
+		if u, err := url.Parse(c.EscalationWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("escalation_webhook_url is malformed: %s", c.EscalationWebhookURL))
+		}
+	}
+	if c.EscalationEmail != nil {
+		if _, _, err := net.SplitHostPort(c.EscalationEmail.SMTPServer); err != nil {
+			problems = append(problems, fmt.Sprintf("smtp_server of escalation_email is malformed (should look like 'smtp.example.com:587'): %s", err))
+		}
+		if c.EscalationEmail.From == "" || c.EscalationEmail.To == "" {
+			problems = append(problems, "from and to of escalation_email should not be empty")
+		}
+	}
+
+	// mqtt
+	if c.MQTT != nil {
+		problems = append(problems, c.MQTT.validate()...)
+	}
+
+	// aliases
+	for alias, command := range c.Aliases {
+		if strings.ContainsAny(alias, " \n") || !strings.HasPrefix(command, "/") {
+			problems = append(problems, fmt.Sprintf("aliases should be words without spaces, and their commands should start with '/': '%s' => '%s'", alias, command))
+		}
+	}
+
+	// webhooks
+	for _, hook := range c.Webhooks {
+		problems = append(problems, hook.validate()...)
+	}
+
+	// llm
+	if c.LLM != nil {
+		problems = append(problems, c.LLM.validate()...)
+	}
+
+	// redis
+	if c.Redis != nil {
+		problems = append(problems, c.Redis.validate()...)
+	}
+
+	// contents
+	for _, rule := range c.ContentRules {
+		problems = append(problems, rule.validate()...)
+	}
+	if c.Sanitization != nil {
+		problems = append(problems, c.Sanitization.validate()...)
+	}
+
+	// tts
+	if c.TTS != nil {
+		problems = append(problems, c.TTS.validate()...)
+	}
+
+	// integrations, push, sms, and storages
+	if c.Integrations != nil {
+		problems = append(problems, c.Integrations.validate(c.APIServerPort)...)
+	}
+	if c.Push != nil {
+		problems = append(problems, c.Push.validate()...)
+	}
+	if c.SMS != nil {
+		problems = append(problems, c.SMS.validate()...)
+	}
+	if c.BlobStorage != nil {
+		problems = append(problems, c.BlobStorage.validate()...)
+	}
+	if c.RemoteBackup != nil {
+		problems = append(problems, c.RemoteBackup.validate(c.BackupIntervalHours)...)
+	}
+
+	// servers
+	if c.APIServerPort < 0 || c.APIServerPort > 65535 {
+		problems = append(problems, fmt.Sprintf("api_server_port is out of range: %d", c.APIServerPort))
+	}
+	if c.AdminServerAddr != "" {
+		if _, port, err := net.SplitHostPort(c.AdminServerAddr); err != nil {
+			problems = append(problems, fmt.Sprintf("admin_server_addr is malformed (should look like '127.0.0.1:6060'): %s", err))
+		} else if c.APIServerPort > 0 && port == fmt.Sprintf("%d", c.APIServerPort) {
+			problems = append(problems, fmt.Sprintf("admin_server_addr and api_server_port use the same port: %s", port))
+		}
+		if c.AdminServerToken == "" {
+			problems = append(problems, "admin_server_addr is set, but admin_server_token is empty")
+		}
+	} else if c.AdminServerToken != "" {
+		problems = append(problems, "admin_server_token is set, but admin_server_addr is empty")
+	}
+
+	return problems
+}
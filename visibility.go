@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	bot "github.com/meinside/telegram-bot-go"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// visibility of reminders in groups: a member can choose (with the button on the confirmation) to keep a reminder private,
+// then its content is delivered to the member's private chat, and only a generic notice with a mention appears in the group
+
+const (
+	callbackVisibility = "/visibility"
+
+	visibilityPrivate = "private"
+	visibilityPublic  = "public"
+
+	visibilityPendingMinutes = 10 // choices will be kept this long for the reminder to be confirmed
+)
+
+// messages (can be overridden with messages.json)
+var (
+	messageVisibilityPrivate       = "🔒 나만 보기"
+	messageVisibilityPublic        = "👥 모두 보기"
+	messageVisibilityPrivateChosen = "🔒 알림 내용은 개인 대화로 보내고, 그룹에는 알림이 있다고만 알려드립니다."
+	messageVisibilityPublicChosen  = "👥 알림 내용을 그룹에 보내드립니다."
+	messageVisibilityNoPrivateChat = "봇과 개인 대화를 먼저 시작해 주세요. (개인 대화로 알림 내용을 보내드려야 합니다)"
+	messageVisibilityNotice        = "알림이 있어요. (내용은 개인 대화로 보냈습니다)"
+	messageVisibilityHiddenFormat  = "🔒 %s님의 비공개 알림"
+)
+
+// visibilities chosen for reminders not confirmed yet (key: chat id/user id)
+var _pendingVisibilities = struct {
+	sync.Mutex
+	choices map[string]time.Time // (expiration of the private choice)
+}{
+	choices: map[string]time.Time{},
+}
+
+// key of pending states of a user in a chat (eg. visibilities)
+func pendingKey(chatID, userID int64) string {
+	return fmt.Sprintf("%d/%d", chatID, userID)
+}
+
+// check if given queue item is a private reminder of a group
+func isPrivateInGroup(q dbhelper.QueueItem) bool {
+	return q.Private && q.UserID != 0 && q.Kind == dbhelper.QueueKindReminder && !q.Broadcast && isGroupChatID(q.ChatID)
+}
+
+// check if given queue item is a private reminder of a group, created by someone other than given user
+func isOthersPrivate(q dbhelper.QueueItem, userID int64) bool {
+	return isPrivateInGroup(q) && q.UserID != userID
+}
+
+// text of given reminder for displaying in its chat (private ones of groups are hidden)
+func visibleReminderText(r dbhelper.QueueItem) string {
+	if isPrivateInGroup(r) {
+		return fmt.Sprintf(messageVisibilityHiddenFormat, userDisplayName(r.UserID))
+	}
+	return reminderText(r.ChatID, r.Message)
+}
+
+// check if given user has a private chat with the bot
+func hasPrivateChat(userID int64) bool {
+	for _, chat := range db.ActiveChats() {
+		if chat.ChatID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// inline keyboard row for choosing visibility of the reminder being confirmed
+func visibilityButtons(private bool) []bot.InlineKeyboardButton {
+	text, data := messageVisibilityPrivate, fmt.Sprintf("%s %s", callbackVisibility, visibilityPrivate)
+	if private {
+		text, data = messageVisibilityPublic, fmt.Sprintf("%s %s", callbackVisibility, visibilityPublic)
+	}
+
+	return []bot.InlineKeyboardButton{
+		bot.InlineKeyboardButton{Text: text, CallbackData: &data},
+	}
+}
+
+// add a button for choosing visibility of the reminder being confirmed (only in groups)
+func addVisibilityButton(chatID, userID int64, options map[string]interface{}) {
+	if !isGroupChatID(chatID) {
+		return
+	}
+
+	// (public by default)
+	_pendingVisibilities.Lock()
+	delete(_pendingVisibilities.choices, pendingKey(chatID, userID))
+	_pendingVisibilities.Unlock()
+
+	row := visibilityButtons(false)
+	if markup, ok := options["reply_markup"].(bot.InlineKeyboardMarkup); ok {
+		markup.InlineKeyboard = append(markup.InlineKeyboard, row)
+		options["reply_markup"] = markup
+	} else {
+		options["reply_markup"] = bot.InlineKeyboardMarkup{InlineKeyboard: [][]bot.InlineKeyboardButton{row}}
+	}
+}
+
+// process callback query for choosing visibility: '/visibility <private|public>'
+func processVisibilityCallback(query bot.CallbackQuery, txt string) (message string, keyboard interface{}) {
+	chatID := query.Message.Chat.ID
+	userID := int64(query.From.ID)
+
+	text := ""
+	if query.Message.Text != nil {
+		text = *query.Message.Text
+	}
+
+	// (drop the notice of the previous choice)
+	for _, notice := range []string{messageVisibilityPrivateChosen, messageVisibilityPublicChosen} {
+		text = strings.TrimSuffix(text, "\n"+notice)
+	}
+
+	var private bool
+	switch strings.TrimSpace(strings.TrimPrefix(txt, callbackVisibility)) {
+	case visibilityPrivate:
+		if !hasPrivateChat(userID) {
+			return messageVisibilityNoPrivateChat, nil
+		}
+		private = true
+	case visibilityPublic:
+		private = false
+	default:
+		log.Printf("*** Unprocessable callback query: %s", txt)
+		return messageError, nil
+	}
+
+	_pendingVisibilities.Lock()
+	if private {
+		_pendingVisibilities.choices[pendingKey(chatID, userID)] = time.Now().Add(visibilityPendingMinutes * time.Minute)
+	} else {
+		delete(_pendingVisibilities.choices, pendingKey(chatID, userID))
+	}
+	_pendingVisibilities.Unlock()
+
+	// (keep the misparse button too)
+	data := callbackMisparse
+	keyboard = bot.InlineKeyboardMarkup{
+		InlineKeyboard: [][]bot.InlineKeyboardButton{
+			visibilityButtons(private),
+			[]bot.InlineKeyboardButton{
+				bot.InlineKeyboardButton{Text: messageMisparse, CallbackData: &data},
+			},
+		},
+	}
+
+	if private {
+		return text + "\n" + messageVisibilityPrivateChosen, keyboard
+	}
+	return text + "\n" + messageVisibilityPublicChosen, keyboard
+}
+
+// take the visibility chosen for the reminder of given user in given chat (true for private)
+func takePendingVisibility(chatID, userID int64) bool {
+	key := pendingKey(chatID, userID)
+
+	_pendingVisibilities.Lock()
+	defer _pendingVisibilities.Unlock()
+
+	expiresOn, exists := _pendingVisibilities.choices[key]
+	delete(_pendingVisibilities.choices, key)
+
+	return exists && time.Now().Before(expiresOn)
+}
+
+// deliver given private reminder: its content to the creator's private chat, and a notice to the group
+//
+// (returns the result of sending the content)
+func sendPrivateReminder(client *bot.Bot, q dbhelper.QueueItem) (sent bot.APIResponseMessage) {
+	message, options := deliveryMessage(client, q)
+
+	// (callbacks of the keyboards work in the group only; replies like '완료' work in the private chat)
+	delete(options, "reply_markup")
+
+	if sent = client.SendMessage(q.UserID, message, options); !sent.Ok {
+		return sent
+	}
+
+	noticeOptions := map[string]interface{}{}
+	notice := mentionCreator(client, q, messageVisibilityNotice, noticeOptions)
+	if result := client.SendMessage(q.ChatID, notice, noticeOptions); result.Ok {
+		db.SaveDeliveredMessage(result.Result.Chat.ID, int64(result.Result.MessageID), q.ChatID, q.ID)
+	} else {
+		log.Printf("*** failed to send notice of private reminder %d to group %d: %s", q.ID, q.ChatID, *result.Description)
+	}
+
+	return sent
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	dbhelper "github.com/meinside/telegram-bot-reminder-api.ai/db"
+)
+
+// lifecycle events of reminders
+const (
+	webhookEventCreated   = "created"
+	webhookEventDelivered = "delivered"
+	webhookEventCanceled  = "canceled"
+	webhookEventFailed    = "failed"
+
+	webhookTimeoutSeconds = 10
+
+	webhookHeaderEvent     = "X-Reminder-Event"
+	webhookHeaderSignature = "X-Reminder-Signature" // "sha256=" + hex of HMAC-SHA256 of the body with the secret
+)
+
+var webhookEvents = []string{webhookEventCreated, webhookEventDelivered, webhookEventCanceled, webhookEventFailed}
+
+// config for an outgoing webhook
+type webhookConfig struct {
+	URL     string   `json:"url"`
+	Secret  string   `json:"secret,omitempty"`   // for signing payloads
+	Events  []string `json:"events,omitempty"`   // (all events if empty)
+	ChatIDs []int64  `json:"chat_ids,omitempty"` // (all chats if empty)
+}
+
+// payload of webhooks
+type webhookPayload struct {
+	Event   string    `json:"event"`
+	QueueID int64     `json:"queue_id"`
+	ChatID  int64     `json:"chat_id"`
+	UserID  int64     `json:"user_id,omitempty"`
+	Message string    `json:"message"`
+	FireOn  time.Time `json:"fire_on"`
+	Time    time.Time `json:"time"`
+	Error   string    `json:"error,omitempty"`
+}
+
+var _webhookClient = &http.Client{Timeout: webhookTimeoutSeconds * time.Second}
+
+// validate webhook configs
+func (c webhookConfig) validate() (problems []string) {
+	if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems = append(problems, fmt.Sprintf("url of webhook is malformed: %s", c.URL))
+	}
+	for _, event := range c.Events {
+		if !containsString(webhookEvents, event) {
+			problems = append(problems, fmt.Sprintf("event of webhook '%s' is unknown: %s (should be one of %v)", c.URL, event, webhookEvents))
+		}
+	}
+
+	return problems
+}
+
+// check if given webhook wants given event of given chat
+func (c webhookConfig) wants(event string, chatID int64) bool {
+	if len(c.Events) > 0 && !containsString(c.Events, event) {
+		return false
+	}
+	if len(c.ChatIDs) > 0 {
+		for _, id := range c.ChatIDs {
+			if id == chatID {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// post given event of given reminder to the matching webhooks (in background)
+func fireWebhooks(event string, q dbhelper.QueueItem, reason string) {
+	// (also mirrored to the task apps connected to the chat)
+	syncIntegrations(event, q)
+
+	if len(_conf.Webhooks) <= 0 {
+		return
+	}
+
+	body, err := json.Marshal(webhookPayload{
+		Event:   event,
+		QueueID: q.ID,
+		ChatID:  q.ChatID,
+		UserID:  q.UserID,
+		Message: q.Message,
+		FireOn:  q.FireOn,
+		Time:    time.Now(),
+		Error:   reason,
+	})
+	if err != nil {
+		log.Printf("*** failed to marshal webhook payload: %s", err)
+		return
+	}
+
+	for _, hook := range _conf.Webhooks {
+		if hook.wants(event, q.ChatID) {
+			go func(hook webhookConfig) {
+				if err := postWebhook(hook, event, body); err != nil {
+					log.Printf("*** failed to post webhook %s (%s of queue item %d): %s", hook.URL, event, q.ID, err)
+				}
+			}(hook)
+		}
+	}
+}
+
+// post given body to the webhook
+func postWebhook(hook webhookConfig, event string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookHeaderEvent, event)
+	if hook.Secret != "" {
+		req.Header.Set(webhookHeaderSignature, "sha256="+signWebhook(hook.Secret, body))
+	}
+
+	res, err := _webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("http status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// hex of HMAC-SHA256 of given body with the secret
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// check if given slice has given string
+func containsString(slice []string, str string) bool {
+	for _, s := range slice {
+		if s == str {
+			return true
+		}
+	}
+
+	return false
+}
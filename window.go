@@ -0,0 +1,139 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// delivery windows: "6시에서 8시 사이에 ..." will be delivered at the earliest moment in the window,
+// which is not in the quiet hours of the chat, and not too close to other reminders of the chat
+
+const (
+	windowPendingMinutes   = 10 // windows will be waited for the reminder to be confirmed this long
+	windowStepMinutes      = 5  // candidate times in the window are checked with this interval
+	windowCollisionMinutes = 5  // other reminders closer than this are considered colliding
+)
+
+// eg. "6시에서 8시 사이에", "18시~20시 사이"
+var windowTextRegex = regexp.MustCompile(`(\d{1,2})\s*시\s*(?:에서|부터|~|-)\s*(\d{1,2})\s*시\s*사이(?:에)?`)
+
+// length of the window waiting for the reminder to be confirmed
+type pendingWindow struct {
+	length    time.Duration
+	expiresOn time.Time
+}
+
+// windows of reminders not confirmed yet (key: chat id/user id)
+var _pendingWindows = struct {
+	sync.Mutex
+	windows map[string]pendingWindow
+}{
+	windows: map[string]pendingWindow{},
+}
+
+// replace the window in given text with its start time (for api.ai), and return the length of the window
+//
+// (eg. "저녁 6시에서 8시 사이에 운동" => "저녁 6시에 운동", 2 hours)
+func parseWindowText(txt string) (query string, length time.Duration, ok bool) {
+	matches := windowTextRegex.FindStringSubmatch(txt)
+	if matches == nil {
+		return txt, 0, false
+	}
+
+	from, _ := strconv.Atoi(matches[1])
+	to, _ := strconv.Atoi(matches[2])
+
+	// (hours of 12-hour clock, eg. "11시에서 1시 사이" => 2 hours)
+	hours := to - from
+	if hours <= 0 {
+		hours += 12
+	}
+	if hours > 12 {
+		hours -= 12
+	}
+	if hours <= 0 {
+		return txt, 0, false
+	}
+
+	query = windowTextRegex.ReplaceAllString(txt, "${1}시에")
+
+	return query, time.Duration(hours) * time.Hour, true
+}
+
+// keep given window until the reminder of given user in given chat is confirmed
+func setPendingWindow(chatID, userID int64, length time.Duration) {
+	_pendingWindows.Lock()
+	_pendingWindows.windows[pendingKey(chatID, userID)] = pendingWindow{
+		length:    length,
+		expiresOn: time.Now().Add(windowPendingMinutes * time.Minute),
+	}
+	_pendingWindows.Unlock()
+}
+
+// take the pending window of given user in given chat
+func takePendingWindow(chatID, userID int64) (length time.Duration, exists bool) {
+	key := pendingKey(chatID, userID)
+
+	_pendingWindows.Lock()
+	defer _pendingWindows.Unlock()
+
+	pending, exists := _pendingWindows.windows[key]
+	if !exists {
+		return 0, false
+	}
+	delete(_pendingWindows.windows, key)
+
+	if time.Now().After(pending.expiresOn) {
+		return 0, false
+	}
+
+	return pending.length, true
+}
+
+// pick the time for a reminder of given user in given chat in the pending window starting from given time
+//
+// (returns given time as it is if there is no pending window, or no time in the window is suitable)
+func fitInWindow(chatID, userID int64, from time.Time) time.Time {
+	length, exists := takePendingWindow(chatID, userID)
+	if !exists {
+		return from
+	}
+
+	s := db.ChatSettings(chatID)
+	others := db.UndeliveredQueueItems(chatID)
+
+	now := time.Now()
+	for t := from; !t.After(from.Add(length)); t = t.Add(windowStepMinutes * time.Minute) {
+		if t.Before(now) || isInQuietHours(t, s.QuietFrom, s.QuietTo) {
+			continue
+		}
+
+		colliding := false
+		for _, o := range others {
+			if gap := o.FireOn.Sub(t); gap > -windowCollisionMinutes*time.Minute && gap < windowCollisionMinutes*time.Minute {
+				colliding = true
+				break
+			}
+		}
+		if !colliding {
+			return t
+		}
+	}
+
+	return from
+}
+
+// check if given time is in the quiet hours (from, to: hours of day, both the same for no quiet hours)
+func isInQuietHours(t time.Time, from, to int) bool {
+	if from == to {
+		return false
+	}
+
+	hour := t.Hour()
+	if from < to {
+		return hour >= from && hour < to
+	}
+	return hour >= from || hour < to // (eg. 23 ~ 7)
+}